@@ -24,6 +24,10 @@ package app
 import (
 	"net/http"
 
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
 	"github.com/openyurtio/openyurt/pkg/controller/certificates"
 	daemonpodupdater "github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater"
 	poolcoordinatorcertmanager "github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/cert"
@@ -62,13 +66,21 @@ func startYurtCSRApproverController(ctx ControllerContext) (http.Handler, bool,
 }
 
 func startDaemonPodUpdaterController(ctx ControllerContext) (http.Handler, bool, error) {
+	// the maintenance windows are read from NodePools of apps.openyurt.io/v1beta1
+	dynamicClient, err := dynamic.NewForConfig(ctx.ClientBuilder.ConfigOrDie("daemonPodUpdater-controller"))
+	if err != nil {
+		return nil, false, err
+	}
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, ctx.ResyncPeriod())
 	daemonPodUpdaterCtrl := daemonpodupdater.NewController(
 		ctx.ClientBuilder.ClientOrDie("daemonPodUpdater-controller"),
 		ctx.InformerFactory.Apps().V1().DaemonSets(),
 		ctx.InformerFactory.Core().V1().Nodes(),
 		ctx.InformerFactory.Core().V1().Pods(),
+		dynamicInformerFactory.ForResource(appsv1beta1.GroupVersion.WithResource("nodepools")),
 	)
 
+	dynamicInformerFactory.Start(ctx.Stop)
 	go daemonPodUpdaterCtrl.Run(2, ctx.Stop)
 	return nil, true, nil
 }
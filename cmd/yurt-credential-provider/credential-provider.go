@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurthub/credentialprovider"
+)
+
+// yurt-credential-provider is the kubelet image credential provider plugin, it's configured by
+// --image-credential-provider-config of kubelet and gets the registry credentials from yurthub.
+func main() {
+	socket := filepath.Join("/var/lib/yurthub", credentialprovider.CredentialProviderSocket)
+	cmd := &cobra.Command{
+		Use:     "yurt-credential-provider",
+		Short:   "yurt-credential-provider gets registry credentials for kubelet from yurthub",
+		Version: fmt.Sprintf("%#v", projectinfo.Get()),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return credentialprovider.RunPlugin(socket, os.Stdin, os.Stdout)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().StringVar(&socket, "yurthub-socket", socket, "The unix socket of yurthub which serves the registry credentials, it's in the root dir of yurthub.")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package options
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"github.com/openyurtio/openyurt/pkg/controller/gateway/config"
@@ -28,23 +31,27 @@ type GatewayControllerOptions struct {
 
 func NewGatewayControllerOptions() *GatewayControllerOptions {
 	return &GatewayControllerOptions{
-		&config.GatewayControllerConfiguration{},
+		&config.GatewayControllerConfiguration{
+			EndpointHealthTimeout: time.Minute,
+		},
 	}
 }
 
-// AddFlags adds flags related to nodepool for yurt-manager to the specified FlagSet.
+// AddFlags adds flags related to gateway for yurt-manager to the specified FlagSet.
 func (g *GatewayControllerOptions) AddFlags(fs *pflag.FlagSet) {
 	if g == nil {
 		return
 	}
 
+	fs.DurationVar(&g.EndpointHealthTimeout, "gateway-endpoint-health-timeout", g.EndpointHealthTimeout, "The duration after which the gateway endpoint is regarded as unhealthy and a standby endpoint is elected, if its health is not reported by raven agent. 0 means no timeout.")
 }
 
-// ApplyTo fills up nodepool config with options.
+// ApplyTo fills up gateway config with options.
 func (g *GatewayControllerOptions) ApplyTo(cfg *config.GatewayControllerConfiguration) error {
 	if g == nil {
 		return nil
 	}
+	cfg.EndpointHealthTimeout = g.EndpointHealthTimeout
 
 	return nil
 }
@@ -55,5 +62,8 @@ func (g *GatewayControllerOptions) Validate() []error {
 		return nil
 	}
 	var errs []error
+	if g.EndpointHealthTimeout < 0 {
+		errs = append(errs, fmt.Errorf("gateway-endpoint-health-timeout %v must not be negative", g.EndpointHealthTimeout))
+	}
 	return errs
 }
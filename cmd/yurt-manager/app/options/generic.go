@@ -16,7 +16,11 @@ limitations under the License.
 package options
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/openyurtio/openyurt/pkg/controller/apis/config"
 )
@@ -46,6 +50,11 @@ func (o *GenericOptions) Validate() []error {
 	}
 
 	errs := []error{}
+	for controller, group := range o.LeaderElectionGroups {
+		if msgs := validation.IsDNS1123Label(group); len(msgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid leader election group %q of controller %s, %s", group, controller, strings.Join(msgs, ",")))
+		}
+	}
 	return errs
 }
 
@@ -61,6 +70,7 @@ func (o *GenericOptions) ApplyTo(cfg *config.GenericConfiguration) error {
 	cfg.HealthProbeAddr = o.HealthProbeAddr
 	cfg.EnableLeaderElection = o.EnableLeaderElection
 	cfg.LeaderElectionNamespace = o.LeaderElectionNamespace
+	cfg.LeaderElectionGroups = o.LeaderElectionGroups
 	cfg.RestConfigQPS = o.RestConfigQPS
 	cfg.RestConfigBurst = o.RestConfigBurst
 
@@ -78,6 +88,7 @@ func (o *GenericOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.HealthProbeAddr, "health-probe-addr", o.HealthProbeAddr, "The address the healthz/readyz endpoint binds to.")
 	fs.BoolVar(&o.EnableLeaderElection, "enable-leader-election", o.EnableLeaderElection, "Whether you need to enable leader election.")
 	fs.StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", o.LeaderElectionNamespace, "This determines the namespace in which the leader election configmap/leases will be created, it will use in-cluster namespace if empty.")
+	fs.StringToStringVar(&o.LeaderElectionGroups, "leader-election-groups", o.LeaderElectionGroups, "A set of controller=group pairs, controllers in the same group are elected by the lease yurt-manager-<group> independently, so a slow controller doesn't block the others behind one leader. Controllers not specified are elected by the lease of yurt-manager, e.g. nodepool=pool,nodepoolquota=pool.")
 
 	fs.IntVar(&o.RestConfigQPS, "rest-config-qps", o.RestConfigQPS, "rest-config-qps.")
 	fs.IntVar(&o.RestConfigBurst, "rest-config-burst", o.RestConfigBurst, "rest-config-burst.")
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/openyurtio/openyurt/pkg/controller/nodepoolingress/config"
+)
+
+type NodePoolIngressControllerOptions struct {
+	*config.NodePoolIngressControllerConfiguration
+}
+
+func NewNodePoolIngressControllerOptions() *NodePoolIngressControllerOptions {
+	return &NodePoolIngressControllerOptions{
+		&config.NodePoolIngressControllerConfiguration{
+			GatewayClassName: "openyurt-nodepool",
+		},
+	}
+}
+
+// AddFlags adds flags related to nodepool ingress for yurt-manager to the specified FlagSet.
+func (n *NodePoolIngressControllerOptions) AddFlags(fs *pflag.FlagSet) {
+	if n == nil {
+		return
+	}
+
+	fs.StringVar(&n.GatewayClassName, "nodepool-ingress-gateway-class", n.GatewayClassName, "The name of GatewayClass whose Gateways are provisioned in nodepools.")
+	fs.StringVar(&n.DataPlaneImage, "nodepool-ingress-image", n.DataPlaneImage, "The image of ingress data plane which is deployed into nodepools, it must serve the routes in the file specified by --routes-config. nodepool ingress controller is disabled if it's not specified.")
+}
+
+// ApplyTo fills up nodepool ingress config with options.
+func (n *NodePoolIngressControllerOptions) ApplyTo(cfg *config.NodePoolIngressControllerConfiguration) error {
+	if n == nil {
+		return nil
+	}
+	cfg.GatewayClassName = n.GatewayClassName
+	cfg.DataPlaneImage = n.DataPlaneImage
+
+	return nil
+}
+
+// Validate checks validation of NodePoolIngressControllerOptions.
+func (n *NodePoolIngressControllerOptions) Validate() []error {
+	if n == nil {
+		return nil
+	}
+	var errs []error
+	if len(n.GatewayClassName) == 0 {
+		errs = append(errs, fmt.Errorf("nodepool-ingress-gateway-class should not be empty"))
+	}
+	return errs
+}
@@ -24,18 +24,20 @@ import (
 
 // YurtManagerOptions is the main context object for the yurt-manager.
 type YurtManagerOptions struct {
-	Generic            *GenericOptions
-	NodePoolController *NodePoolControllerOptions
-	GatewayController  *GatewayControllerOptions
+	Generic                   *GenericOptions
+	NodePoolController        *NodePoolControllerOptions
+	GatewayController         *GatewayControllerOptions
+	NodePoolIngressController *NodePoolIngressControllerOptions
 }
 
 // NewYurtManagerOptions creates a new YurtManagerOptions with a default config.
 func NewYurtManagerOptions() (*YurtManagerOptions, error) {
 
 	s := YurtManagerOptions{
-		Generic:            NewGenericOptions(),
-		NodePoolController: NewNodePoolControllerOptions(),
-		GatewayController:  NewGatewayControllerOptions(),
+		Generic:                   NewGenericOptions(),
+		NodePoolController:        NewNodePoolControllerOptions(),
+		GatewayController:         NewGatewayControllerOptions(),
+		NodePoolIngressController: NewNodePoolIngressControllerOptions(),
 	}
 
 	return &s, nil
@@ -46,6 +48,7 @@ func (y *YurtManagerOptions) Flags() cliflag.NamedFlagSets {
 	y.Generic.AddFlags(fss.FlagSet("generic"))
 	y.NodePoolController.AddFlags(fss.FlagSet("nodepool controller"))
 	y.GatewayController.AddFlags(fss.FlagSet("gateway controller"))
+	y.NodePoolIngressController.AddFlags(fss.FlagSet("nodepool ingress controller"))
 
 	// Please Add Other controller flags @kadisi
 
@@ -58,6 +61,7 @@ func (y *YurtManagerOptions) Validate() error {
 	errs = append(errs, y.Generic.Validate()...)
 	errs = append(errs, y.NodePoolController.Validate()...)
 	errs = append(errs, y.GatewayController.Validate()...)
+	errs = append(errs, y.NodePoolIngressController.Validate()...)
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -69,6 +73,12 @@ func (y *YurtManagerOptions) ApplyTo(c *config.Config) error {
 	if err := y.NodePoolController.ApplyTo(&c.ComponentConfig.NodePoolController); err != nil {
 		return err
 	}
+	if err := y.GatewayController.ApplyTo(&c.ComponentConfig.GatewayController); err != nil {
+		return err
+	}
+	if err := y.NodePoolIngressController.ApplyTo(&c.ComponentConfig.NodePoolIngressController); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/delta"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+// NewApplyDeltaCmd generates a new apply-delta command
+func NewApplyDeltaCmd() *cobra.Command {
+	o := delta.NewApplyDeltaOptions()
+	cmd := &cobra.Command{
+		Use:   "apply-delta --base --delta --base-digest --target-digest",
+		Short: "reconstruct a file on the node from the current version and a bsdiff delta, and verify its digest",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("node-servant version: %#v\n", projectinfo.Get())
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				klog.Infof("FLAG: --%s=%q", flag.Name, flag.Value)
+			})
+
+			o.Complete()
+			if err := o.Validate(); err != nil {
+				klog.Fatalf("validate options: %v", err)
+			}
+
+			d := delta.NewDeltaApplierWithOptions(o)
+			if err := d.Do(); err != nil {
+				klog.Fatalf("fail to apply delta: %s", err)
+			}
+			klog.Info("apply delta success")
+		},
+		Args: cobra.NoArgs,
+	}
+	o.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeletconfig
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apiserver/pkg/server"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/kubeletconfig"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+// NewReconcileKubeletCmd generates a new reconcile-kubelet command
+func NewReconcileKubeletCmd() *cobra.Command {
+	o := kubeletconfig.NewReconcileKubeletOptions()
+	cmd := &cobra.Command{
+		Use:   "reconcile-kubelet",
+		Short: "keep kubelet pointed at yurthub continuously, and switch kubelet back to the cloud during yurthub maintenance",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("node-servant version: %#v\n", projectinfo.Get())
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				klog.Infof("FLAG: --%s=%q", flag.Name, flag.Value)
+			})
+
+			o.Complete()
+			if err := o.Validate(); err != nil {
+				klog.Fatalf("validate options: %v", err)
+			}
+
+			r := kubeletconfig.NewKubeletReconcilerWithOptions(o)
+			r.Run(server.SetupSignalHandler())
+			klog.Info("stop reconciling kubelet config")
+		},
+		Args: cobra.NoArgs,
+	}
+	o.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -26,8 +26,12 @@ import (
 
 	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/config"
 	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/convert"
+	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/delta"
+	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/kubeletconfig"
 	preflightconvert "github.com/openyurtio/openyurt/cmd/yurt-node-servant/preflight-convert"
+	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/pullimage"
 	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/revert"
+	"github.com/openyurtio/openyurt/cmd/yurt-node-servant/upgrade"
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
 )
 
@@ -40,7 +44,7 @@ func main() {
 	version := fmt.Sprintf("%#v", projectinfo.Get())
 	rootCmd := &cobra.Command{
 		Use:     "node-servant",
-		Short:   "node-servant do preflight-convert/convert/revert specific node",
+		Short:   "node-servant do preflight-convert/convert/revert/upgrade/pull-image/apply-delta/reconcile-kubelet specific node",
 		Version: version,
 	}
 	rootCmd.PersistentFlags().String("kubeconfig", "", "The path to the kubeconfig file")
@@ -48,6 +52,10 @@ func main() {
 	rootCmd.AddCommand(revert.NewRevertCmd())
 	rootCmd.AddCommand(preflightconvert.NewxPreflightConvertCmd())
 	rootCmd.AddCommand(config.NewConfigCmd())
+	rootCmd.AddCommand(upgrade.NewUpgradeCmd())
+	rootCmd.AddCommand(pullimage.NewPullImageCmd())
+	rootCmd.AddCommand(delta.NewApplyDeltaCmd())
+	rootCmd.AddCommand(kubeletconfig.NewReconcileKubeletCmd())
 
 	if err := rootCmd.Execute(); err != nil { // run command
 		os.Exit(1)
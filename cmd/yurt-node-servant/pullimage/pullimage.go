@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/pullimage"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+// NewPullImageCmd generates a new pull-image command
+func NewPullImageCmd() *cobra.Command {
+	o := pullimage.NewPullImageOptions()
+	cmd := &cobra.Command{
+		Use:   "pull-image --images",
+		Short: "pull and verify the images on the node ahead of upgrades",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("node-servant version: %#v\n", projectinfo.Get())
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				klog.Infof("FLAG: --%s=%q", flag.Name, flag.Value)
+			})
+
+			if err := o.Complete(); err != nil {
+				klog.Fatalf("complete options: %v", err)
+			}
+			if err := o.Validate(); err != nil {
+				klog.Fatalf("validate options: %v", err)
+			}
+
+			p := pullimage.NewPullerWithOptions(o)
+			if err := p.Do(); err != nil {
+				klog.Fatalf("fail to pull images: %s", err)
+			}
+			klog.Info("pull images success")
+		},
+		Args: cobra.NoArgs,
+	}
+	o.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/upgrade"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+// NewUpgradeCmd generates a new upgrade command
+func NewUpgradeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade the yurt components on the node",
+	}
+	cmd.AddCommand(newUpgradeYurthubCmd())
+
+	return cmd
+}
+
+func newUpgradeYurthubCmd() *cobra.Command {
+	o := upgrade.NewUpgradeOptions()
+	cmd := &cobra.Command{
+		Use:   "yurthub --yurthub-image",
+		Short: "upgrade yurthub and roll back automatically if the new yurthub is unhealthy in probation",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("node-servant version: %#v\n", projectinfo.Get())
+			cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+				klog.Infof("FLAG: --%s=%q", flag.Name, flag.Value)
+			})
+
+			if err := o.Validate(); err != nil {
+				klog.Fatalf("validate options: %v", err)
+			}
+
+			u := upgrade.NewUpgraderWithOptions(o)
+			if err := u.Do(); err != nil {
+				klog.Fatalf("fail to upgrade yurthub: %s", err)
+			}
+			klog.Info("upgrade success")
+		},
+		Args: cobra.NoArgs,
+	}
+	o.AddFlags(cmd.Flags())
+
+	return cmd
+}
@@ -27,13 +27,21 @@ import (
 
 // Config is the main context object for yurttunel-agent
 type Config struct {
-	NodeName         string
-	NodeIP           string
-	TunnelServerAddr string
-	Client           kubernetes.Interface
-	AgentIdentifiers string
-	AgentMetaAddr    string
-	CertDir          string
+	NodeName string
+	NodeIP   string
+	// TunnelServerAddrs are the addresses of tunnel servers, the agent fails over
+	// to the next one if the current server is unhealthy.
+	TunnelServerAddrs []string
+	Client            kubernetes.Interface
+	AgentIdentifiers  string
+	AgentMetaAddr     string
+	CertDir           string
+	UDPRelayAddr      string
+	// EnableAccessPolicy specifies whether the dial requests are checked by tunnel access policies
+	EnableAccessPolicy bool
+	// MaxUploadBandwidth and MaxDownloadBandwidth are the bandwidth limits of tunnel in bytes per second
+	MaxUploadBandwidth   int
+	MaxDownloadBandwidth int
 }
 
 type completedConfig struct {
@@ -39,22 +39,27 @@ const defaultKubeconfig = "/etc/kubernetes/kubelet.conf"
 
 // AgentOptions has the information that required by the yurttunel-agent
 type AgentOptions struct {
-	NodeName         string
-	NodeIP           string
-	TunnelServerAddr string
-	ApiserverAddr    string
-	KubeConfig       string
-	Version          bool
-	AgentIdentifiers string
-	MetaHost         string
-	MetaPort         string
-	CertDir          string
+	NodeName             string
+	NodeIP               string
+	TunnelServerAddr     string
+	ApiserverAddr        string
+	KubeConfig           string
+	Version              bool
+	AgentIdentifiers     string
+	MetaHost             string
+	MetaPort             string
+	CertDir              string
+	EnableUDPRelay       bool
+	EnableAccessPolicy   bool
+	MaxUploadBandwidth   int
+	MaxDownloadBandwidth int
 }
 
 // NewAgentOptions creates a new AgentOptions with a default config.
 func NewAgentOptions() *AgentOptions {
 	o := &AgentOptions{
-		MetaPort: constants.YurttunnelAgentMetaPort,
+		MetaPort:       constants.YurttunnelAgentMetaPort,
+		EnableUDPRelay: true,
 	}
 
 	return o
@@ -80,6 +85,12 @@ func (o *AgentOptions) Validate() error {
 		o.MetaHost = utilip.MustGetLoopbackIP(utilnet.IsIPv6String(o.NodeIP))
 	}
 
+	for _, addr := range splitTunnelServerAddrs(o.TunnelServerAddr) {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("--tunnelserver-addr %s is invalid, %v", addr, err)
+		}
+	}
+
 	if !agentIdentifiersAreValid(o.AgentIdentifiers) {
 		return errors.New("--agent-identifiers are invalid, format should be host={node-name}")
 	}
@@ -92,13 +103,28 @@ func (o *AgentOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.Version, "version", o.Version, "print the version information.")
 	fs.StringVar(&o.NodeName, "node-name", o.NodeName, "The name of the edge node.")
 	fs.StringVar(&o.NodeIP, "node-ip", o.NodeIP, "The host IP of the edge node.")
-	fs.StringVar(&o.TunnelServerAddr, "tunnelserver-addr", o.TunnelServerAddr, fmt.Sprintf("The address of %s", projectinfo.GetServerName()))
+	fs.StringVar(&o.TunnelServerAddr, "tunnelserver-addr", o.TunnelServerAddr, fmt.Sprintf("The comma separated addresses of %s, the agent fails over to the next address if the current one is unhealthy.", projectinfo.GetServerName()))
 	fs.StringVar(&o.ApiserverAddr, "apiserver-addr", o.ApiserverAddr, "A reachable address of the apiserver.")
 	fs.StringVar(&o.KubeConfig, "kube-config", o.KubeConfig, "Path to the kubeconfig file.")
 	fs.StringVar(&o.AgentIdentifiers, "agent-identifiers", o.AgentIdentifiers, "The identifiers of the agent, which will be used by the server when choosing agent.")
 	fs.StringVar(&o.MetaHost, "meta-host", o.MetaHost, "The ip address on which listen for --meta-port port.")
 	fs.StringVar(&o.MetaPort, "meta-port", o.MetaPort, "The port on which to serve HTTP requests like profling, metrics")
 	fs.StringVar(&o.CertDir, "cert-dir", o.CertDir, "The directory of certificate stored at.")
+	fs.IntVar(&o.MaxUploadBandwidth, "max-upload-bandwidth", o.MaxUploadBandwidth, "The maximum bandwidth in bytes per second for the traffic sent to tunnel server, no limit if it is 0.")
+	fs.IntVar(&o.MaxDownloadBandwidth, "max-download-bandwidth", o.MaxDownloadBandwidth, "The maximum bandwidth in bytes per second for the traffic received from tunnel server, no limit if it is 0.")
+	fs.BoolVar(&o.EnableUDPRelay, "enable-udp-relay", o.EnableUDPRelay, "If allow udp datagrams forwarded by tunnel server to be relayed to the edge addresses.")
+	fs.BoolVar(&o.EnableAccessPolicy, "enable-access-policy", o.EnableAccessPolicy, "If only allow the tunnel server to dial the destinations which are whitelisted by TunnelAccessPolicy, the agent should be allowed to list tunnelaccesspolicies and get its node.")
+}
+
+// splitTunnelServerAddrs splits the comma separated addresses, the empty addresses are ignored.
+func splitTunnelServerAddrs(addrs string) []string {
+	var result []string
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			result = append(result, addr)
+		}
+	}
+	return result
 }
 
 // agentIdentifiersIsValid verify agent identifiers are valid or not.
@@ -130,12 +156,18 @@ func agentIdentifiersAreValid(agentIdentifiers string) bool {
 func (o *AgentOptions) Config() (*config.Config, error) {
 	var err error
 	c := &config.Config{
-		NodeName:         o.NodeName,
-		NodeIP:           o.NodeIP,
-		TunnelServerAddr: o.TunnelServerAddr,
-		AgentIdentifiers: o.AgentIdentifiers,
-		AgentMetaAddr:    net.JoinHostPort(o.MetaHost, o.MetaPort),
-		CertDir:          o.CertDir,
+		NodeName:             o.NodeName,
+		NodeIP:               o.NodeIP,
+		TunnelServerAddrs:    splitTunnelServerAddrs(o.TunnelServerAddr),
+		AgentIdentifiers:     o.AgentIdentifiers,
+		AgentMetaAddr:        net.JoinHostPort(o.MetaHost, o.MetaPort),
+		CertDir:              o.CertDir,
+		EnableAccessPolicy:   o.EnableAccessPolicy,
+		MaxUploadBandwidth:   o.MaxUploadBandwidth,
+		MaxDownloadBandwidth: o.MaxDownloadBandwidth,
+	}
+	if o.EnableUDPRelay {
+		c.UDPRelayAddr = net.JoinHostPort(o.MetaHost, constants.YurttunnelAgentUDPRelayPort)
 	}
 
 	if len(c.AgentIdentifiers) == 0 {
@@ -16,7 +16,10 @@ limitations under the License.
 
 package options
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestAgentIdentifiersAreValid(t *testing.T) {
 	testcases := map[string]struct {
@@ -51,3 +54,32 @@ func TestAgentIdentifiersAreValid(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitTunnelServerAddrs(t *testing.T) {
+	testcases := map[string]struct {
+		addrs  string
+		result []string
+	}{
+		"empty addresses": {
+			"",
+			nil,
+		},
+
+		"single address": {
+			"1.2.3.4:10262",
+			[]string{"1.2.3.4:10262"},
+		},
+
+		"multiple addresses with spaces": {
+			"1.2.3.4:10262, tunnel.example.com:10262,",
+			[]string{"1.2.3.4:10262", "tunnel.example.com:10262"},
+		},
+	}
+
+	for k, tc := range testcases {
+		result := splitTunnelServerAddrs(tc.addrs)
+		if !reflect.DeepEqual(result, tc.result) {
+			t.Errorf("%s: expect addresses %v, but got %v", k, tc.result, result)
+		}
+	}
+}
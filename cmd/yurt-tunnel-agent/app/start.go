@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -33,9 +35,11 @@ import (
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
 	"github.com/openyurtio/openyurt/pkg/util/certmanager"
 	certfactory "github.com/openyurtio/openyurt/pkg/util/certmanager/factory"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/agent"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/server/serveraddr"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/portforward"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
 )
 
@@ -75,19 +79,22 @@ func NewYurttunnelAgentCommand(stopCh <-chan struct{}) *cobra.Command {
 // Run starts the yurttunel-agent
 func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 	var (
-		tunnelServerAddr string
-		err              error
-		agentCertMgr     certificate.Manager
+		wg                sync.WaitGroup
+		tunnelServerAddrs []string
+		err               error
+		agentCertMgr      certificate.Manager
 	)
 
-	// 1. get the address of the yurttunnel-server
-	tunnelServerAddr = cfg.TunnelServerAddr
-	if tunnelServerAddr == "" {
-		if tunnelServerAddr, err = serveraddr.GetTunnelServerAddr(cfg.Client); err != nil {
+	// 1. get the addresses of the yurttunnel-server
+	tunnelServerAddrs = cfg.TunnelServerAddrs
+	if len(tunnelServerAddrs) == 0 {
+		tunnelServerAddr, err := serveraddr.GetTunnelServerAddr(cfg.Client)
+		if err != nil {
 			return err
 		}
+		tunnelServerAddrs = []string{tunnelServerAddr}
 	}
-	klog.Infof("%s address: %s", projectinfo.GetServerName(), tunnelServerAddr)
+	klog.Infof("%s addresses: %s", projectinfo.GetServerName(), strings.Join(tunnelServerAddrs, ","))
 
 	// 2. create a certificate manager
 	// As yurttunnel-agent will run on the edge node with Host network mode,
@@ -121,20 +128,40 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 	}, stopCh)
 	klog.Infof("certificate %s ok", projectinfo.GetAgentName())
 
-	// 3. generate a TLS configuration for securing the connection to server
+	// 3. generate a TLS configuration for securing the connection to server, the server name
+	// is set by the agent for each server address.
 	tlsCfg, err := certmanager.GenTLSConfigUseCertMgrAndCA(agentCertMgr,
-		tunnelServerAddr, constants.YurttunnelCAFile)
+		tunnelServerAddrs[0], constants.YurttunnelCAFile)
 	if err != nil {
 		return err
 	}
 
-	// 4. start the yurttunnel-agent
-	ta := agent.NewTunnelAgent(tlsCfg, tunnelServerAddr, cfg.NodeName, cfg.AgentIdentifiers)
+	// 4. start the yurttunnel-agent, the dial requests are checked by access policies if enabled
+	var accessChecker *accesspolicy.NodeChecker
+	var checkTarget func(protocol, addr string) error
+	if cfg.EnableAccessPolicy {
+		accessEnforcer := accesspolicy.NewEnforcer(cfg.Client)
+		accessChecker = accesspolicy.NewNodeChecker(accessEnforcer, cfg.Client, cfg.NodeName)
+		checkTarget = accessChecker.Check
+		wg.Add(2)
+		go accessEnforcer.Run(stopCh, &wg)
+		go accessChecker.Run(stopCh, &wg)
+	}
+	ta := agent.NewTunnelAgent(tlsCfg, tunnelServerAddrs, cfg.NodeName, cfg.AgentIdentifiers,
+		cfg.MaxUploadBandwidth, cfg.MaxDownloadBandwidth, accessChecker)
 	ta.Run(stopCh)
 
 	// 5. start meta server
 	util.RunMetaServer(cfg.AgentMetaAddr)
 
+	// 6. start udp relay for the udp ports forwarded by the server
+	if cfg.UDPRelayAddr != "" {
+		if err := portforward.RunUDPRelay(cfg.UDPRelayAddr, checkTarget, stopCh); err != nil {
+			return err
+		}
+	}
+
 	<-stopCh
+	wg.Wait()
 	return nil
 }
@@ -20,6 +20,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net"
+	"time"
 
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -32,8 +33,13 @@ import (
 // Config is the main context object for yurttunel-server
 type Config struct {
 	EgressSelectorEnabled       bool
+	EgressSelectorMode          string
+	EgressSelectorUDSFile       string
 	EnableIptables              bool
 	EnableDNSController         bool
+	EnablePortForwarding        bool
+	EnableAccessPolicy          bool
+	EnableScrapeConfig          bool
 	IptablesSyncPeriod          int
 	IPFamily                    iptables.Protocol
 	DNSSyncPeriod               int
@@ -44,11 +50,20 @@ type Config struct {
 	ListenAddrForMaster         string
 	ListenInsecureAddrForMaster string
 	ListenMetaAddr              string
+	ListenHostForPortForwarding string
+	UDPSourcesForPortForwarding []*net.IPNet
+	ListenAddrForL7Proxy        string
+	ListenAddrForSSHJump        string
+	ListenAddrForEgressSelector string
 	RootCert                    *x509.CertPool
 	Client                      kubernetes.Interface
 	SharedInformerFactory       informers.SharedInformerFactory
 	ServerCount                 int
 	ProxyStrategy               string
+	MaxStreamsPerAgent          int
+	StreamWaitTimeout           time.Duration
+	StreamBandwidthLimits       map[string]int
+	PeerAddr                    string
 	InterceptorServerUDSFile    string
 }
 
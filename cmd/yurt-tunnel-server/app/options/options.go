@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	"k8s.io/klog/v2"
 	utilnet "k8s.io/utils/net"
@@ -35,28 +36,44 @@ import (
 	"github.com/openyurtio/openyurt/pkg/util/iptables"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
 	kubeutil "github.com/openyurtio/openyurt/pkg/yurttunnel/kubernetes"
+	tunnelserver "github.com/openyurtio/openyurt/pkg/yurttunnel/server"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/portforward"
 )
 
 // ServerOptions has the information that required by the yurttunel-server
 type ServerOptions struct {
-	KubeConfig             string
-	BindAddr               string
-	InsecureBindAddr       string
-	CertDNSNames           string
-	CertIPs                string
-	CertDir                string
-	Version                bool
-	EnableIptables         bool
-	EnableDNSController    bool
-	EgressSelectorEnabled  bool
-	IptablesSyncPeriod     int
-	DNSSyncPeriod          int
-	TunnelAgentConnectPort string
-	SecurePort             string
-	InsecurePort           string
-	MetaPort               string
-	ServerCount            int
-	ProxyStrategy          string
+	KubeConfig               string
+	BindAddr                 string
+	InsecureBindAddr         string
+	CertDNSNames             string
+	CertIPs                  string
+	CertDir                  string
+	Version                  bool
+	EnableIptables           bool
+	EnableDNSController      bool
+	EnablePortForwarding     bool
+	PortForwardingBindAddr   string
+	PortForwardingUDPSources []string
+	EnableAccessPolicy       bool
+	EnableScrapeConfig       bool
+	EgressSelectorEnabled    bool
+	EgressSelectorMode       string
+	EgressSelectorPort       string
+	EgressSelectorUDSFile    string
+	IptablesSyncPeriod       int
+	DNSSyncPeriod            int
+	TunnelAgentConnectPort   string
+	SecurePort               string
+	InsecurePort             string
+	MetaPort                 string
+	L7ProxyPort              string
+	SSHJumpPort              string
+	PeerAddr                 string
+	ServerCount              int
+	ProxyStrategy            string
+	MaxStreamsPerAgent       int
+	StreamWaitTimeout        time.Duration
+	StreamBandwidthLimits    map[string]int
 }
 
 // NewServerOptions creates a new ServerOptions
@@ -65,6 +82,7 @@ func NewServerOptions() *ServerOptions {
 		BindAddr:               "0.0.0.0",
 		EnableIptables:         true,
 		EnableDNSController:    true,
+		PortForwardingBindAddr: "127.0.0.1",
 		IptablesSyncPeriod:     60,
 		DNSSyncPeriod:          1800,
 		ServerCount:            1,
@@ -72,7 +90,11 @@ func NewServerOptions() *ServerOptions {
 		SecurePort:             constants.YurttunnelServerMasterPort,
 		InsecurePort:           constants.YurttunnelServerMasterInsecurePort,
 		MetaPort:               constants.YurttunnelServerMetaPort,
+		EgressSelectorMode:     constants.EgressSelectorModeHTTPConnect,
+		EgressSelectorPort:     constants.YurttunnelServerEgressSelectorPort,
 		ProxyStrategy:          string(server.ProxyStrategyDestHost),
+		MaxStreamsPerAgent:     100,
+		StreamWaitTimeout:      5 * time.Second,
 	}
 	return o
 }
@@ -83,6 +105,35 @@ func (o *ServerOptions) Validate() error {
 		return fmt.Errorf("%s's bind address can't be empty",
 			projectinfo.GetServerName())
 	}
+	if len(o.PeerAddr) != 0 {
+		if _, _, err := net.SplitHostPort(o.PeerAddr); err != nil {
+			return fmt.Errorf("peer address %s is invalid, %w", o.PeerAddr, err)
+		}
+	}
+	if o.EgressSelectorEnabled {
+		switch o.EgressSelectorMode {
+		case constants.EgressSelectorModeHTTPConnect, constants.EgressSelectorModeGRPC:
+		default:
+			return fmt.Errorf("egress selector mode %s is not supported, only %s and %s are supported",
+				o.EgressSelectorMode, constants.EgressSelectorModeHTTPConnect, constants.EgressSelectorModeGRPC)
+		}
+	}
+	if o.EnablePortForwarding && net.ParseIP(o.PortForwardingBindAddr) == nil {
+		return fmt.Errorf("port forwarding bind address %q is invalid", o.PortForwardingBindAddr)
+	}
+	for _, source := range o.PortForwardingUDPSources {
+		if _, _, err := net.ParseCIDR(source); err != nil {
+			return fmt.Errorf("allowed source %s of port forwarding udp datagrams is invalid, %w", source, err)
+		}
+	}
+	for class, limit := range o.StreamBandwidthLimits {
+		if !sets.NewString(tunnelserver.StreamClasses...).Has(class) {
+			return fmt.Errorf("stream class %s is not supported, only %v are supported", class, tunnelserver.StreamClasses)
+		}
+		if limit < 0 {
+			return fmt.Errorf("bandwidth limit of stream class %s must not be negative", class)
+		}
+	}
 	if len(o.InsecureBindAddr) == 0 {
 		o.InsecureBindAddr = utilip.MustGetLoopbackIP(utilnet.IsIPv6String(o.BindAddr))
 	}
@@ -100,15 +151,29 @@ func (o *ServerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.CertDir, "cert-dir", o.CertDir, "The directory of certificate stored at.")
 	fs.BoolVar(&o.EnableIptables, "enable-iptables", o.EnableIptables, "If allow iptable manager to set the dnat rule.")
 	fs.BoolVar(&o.EnableDNSController, "enable-dns-controller", o.EnableDNSController, "If allow DNS controller to set the dns rules.")
-	fs.BoolVar(&o.EgressSelectorEnabled, "egress-selector-enable", o.EgressSelectorEnabled, "If the apiserver egress selector has been enabled.")
+	fs.BoolVar(&o.EnablePortForwarding, "enable-port-forwarding", o.EnablePortForwarding, fmt.Sprintf("If allow port forward manager to forward tcp/udp ports to edge addresses configured in port-forwarding-rules. The tcp ports are served with mTLS, and only the client certificates signed by cluster CA with O=%s are accepted.", portforward.PortForwardingGroup))
+	fs.StringVar(&o.PortForwardingBindAddr, "port-forwarding-bind-address", o.PortForwardingBindAddr, "The ip address on which to listen for the ports of port-forwarding-rules.")
+	fs.StringSliceVar(&o.PortForwardingUDPSources, "port-forwarding-udp-allowed-sources", o.PortForwardingUDPSources, "The CIDRs of clients which are allowed to send datagrams to the udp ports of port-forwarding-rules, because udp clients can't be authenticated. The udp rules are not served if not set.")
+	fs.BoolVar(&o.EnableAccessPolicy, "enable-access-policy", o.EnableAccessPolicy, "If only allow the streams to the destinations on edge nodes which are whitelisted by TunnelAccessPolicy.")
+	fs.BoolVar(&o.EnableScrapeConfig, "enable-scrape-config", o.EnableScrapeConfig, "If allow scrape config manager to maintain the proxy ports for metrics-server and prometheus to scrape the kubelets and host network exporters of edge nodes.")
+	fs.BoolVar(&o.EgressSelectorEnabled, "egress-selector-enable", o.EgressSelectorEnabled, "If the apiserver egress selector has been enabled. If set, the streams from the egress selector are served with the konnectivity proxy protocol on --egress-selector-port or --egress-selector-uds-file.")
+	fs.StringVar(&o.EgressSelectorMode, "egress-selector-mode", o.EgressSelectorMode, fmt.Sprintf("The proxy protocol of the apiserver egress selector, %s or %s.", constants.EgressSelectorModeHTTPConnect, constants.EgressSelectorModeGRPC))
+	fs.StringVar(&o.EgressSelectorPort, "egress-selector-port", o.EgressSelectorPort, fmt.Sprintf("The port on which to serve the apiserver egress selector with mTLS, kube-apiserver must present a client certificate with CN=%s and O=%s.", constants.YurtTunnelProxyClientCSRCN, constants.YurtTunnelCSROrg))
+	fs.StringVar(&o.EgressSelectorUDSFile, "egress-selector-uds-file", o.EgressSelectorUDSFile, "The unix socket on which to serve the apiserver egress selector running on the same host, --egress-selector-port is ignored if set.")
 	fs.IntVar(&o.IptablesSyncPeriod, "iptables-sync-period", o.IptablesSyncPeriod, "The synchronization period of the iptable manager.")
 	fs.IntVar(&o.DNSSyncPeriod, "dns-sync-period", o.DNSSyncPeriod, "The synchronization period of the DNS controller.")
+	fs.StringVar(&o.SSHJumpPort, "ssh-jump-port", o.SSHJumpPort, "The port on which to serve CONNECT requests for opening ssh sessions to the nodes in nodepools configured in ssh-jump-nodepools, ssh jump is disabled if not set.")
+	fs.StringVar(&o.PeerAddr, "peer-addr", o.PeerAddr, "The address(e.g. {pod-ip}:10261) on which to serve requests forwarded by other tunnel servers. If set, every agent only connects to one tunnel server which is recorded in the shared registry, and the requests for the agent are forwarded to that tunnel server, --server-count is ignored.")
 	fs.IntVar(&o.ServerCount, "server-count", o.ServerCount, "The number of proxy server instances, should be 1 unless it is an HA server.")
 	fs.StringVar(&o.ProxyStrategy, "proxy-strategy", o.ProxyStrategy, "The strategy of proxying requests from tunnel server to agent.")
+	fs.IntVar(&o.MaxStreamsPerAgent, "max-streams-per-agent", o.MaxStreamsPerAgent, "The maximum number of concurrent streams proxied to one tunnel agent, 0 means no limit.")
+	fs.DurationVar(&o.StreamWaitTimeout, "stream-wait-timeout", o.StreamWaitTimeout, "The duration for a new stream to wait for a free slot when the tunnel agent reaches --max-streams-per-agent.")
+	fs.StringToIntVar(&o.StreamBandwidthLimits, "stream-bandwidth-limits", o.StreamBandwidthLimits, fmt.Sprintf("The bandwidth limits in bytes per second of the streams from the cloud to one tunnel agent by class(%s), e.g. logs=262144,exec=1048576. The limit of a class to an agent is shared equally by the active streams of the class in each direction, and each stream is shaped by its own share, 0 or unset means no limit.", strings.Join(tunnelserver.StreamClasses, ", ")))
 	fs.StringVar(&o.TunnelAgentConnectPort, "tunnel-agent-connect-port", o.TunnelAgentConnectPort, "The port on which to serve tcp packets from tunnel agent")
 	fs.StringVar(&o.SecurePort, "secure-port", o.SecurePort, "The port on which to serve HTTPS requests from cloud clients like prometheus")
 	fs.StringVar(&o.InsecurePort, "insecure-port", o.InsecurePort, "The port on which to serve HTTP requests from cloud clients like metrics-server")
 	fs.StringVar(&o.MetaPort, "meta-port", o.MetaPort, "The port on which to serve HTTP requests like profling, metrics")
+	fs.StringVar(&o.L7ProxyPort, "l7-proxy-port", o.L7ProxyPort, "The port on which to serve HTTPS requests for the edge http services configured in l7-proxy-routes, l7 proxy is disabled if not set.")
 }
 
 func (o *ServerOptions) Config() (*config.Config, error) {
@@ -117,6 +182,9 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 		EgressSelectorEnabled: o.EgressSelectorEnabled,
 		EnableIptables:        o.EnableIptables,
 		EnableDNSController:   o.EnableDNSController,
+		EnablePortForwarding:  o.EnablePortForwarding,
+		EnableAccessPolicy:    o.EnableAccessPolicy,
+		EnableScrapeConfig:    o.EnableScrapeConfig,
 		IptablesSyncPeriod:    o.IptablesSyncPeriod,
 		DNSSyncPeriod:         o.DNSSyncPeriod,
 		CertDNSNames:          make([]string, 0),
@@ -124,6 +192,10 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 		CertDir:               o.CertDir,
 		ServerCount:           o.ServerCount,
 		ProxyStrategy:         o.ProxyStrategy,
+		MaxStreamsPerAgent:    o.MaxStreamsPerAgent,
+		StreamWaitTimeout:     o.StreamWaitTimeout,
+		StreamBandwidthLimits: o.StreamBandwidthLimits,
+		PeerAddr:              o.PeerAddr,
 	}
 
 	if o.CertDNSNames != "" {
@@ -141,6 +213,14 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 		}
 	}
 
+	// the peer tunnel servers verify the serving certificate by ip of peer address
+	if o.PeerAddr != "" {
+		host, _, _ := net.SplitHostPort(o.PeerAddr)
+		if ip := net.ParseIP(host); ip != nil {
+			cfg.CertIPs = append(cfg.CertIPs, ip)
+		}
+	}
+
 	if utilnet.IsIPv6String(o.BindAddr) {
 		cfg.IPFamily = iptables.ProtocolIpv6
 	} else {
@@ -150,6 +230,25 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 	cfg.ListenAddrForMaster = net.JoinHostPort(o.BindAddr, o.SecurePort)
 	cfg.ListenInsecureAddrForMaster = net.JoinHostPort(o.InsecureBindAddr, o.InsecurePort)
 	cfg.ListenMetaAddr = net.JoinHostPort(o.InsecureBindAddr, o.MetaPort)
+	if len(o.L7ProxyPort) != 0 {
+		cfg.ListenAddrForL7Proxy = net.JoinHostPort(o.BindAddr, o.L7ProxyPort)
+	}
+	if o.EgressSelectorEnabled {
+		cfg.EgressSelectorMode = o.EgressSelectorMode
+		cfg.ListenAddrForEgressSelector = net.JoinHostPort(o.BindAddr, o.EgressSelectorPort)
+		cfg.EgressSelectorUDSFile = o.EgressSelectorUDSFile
+	}
+	if len(o.SSHJumpPort) != 0 {
+		cfg.ListenAddrForSSHJump = net.JoinHostPort(o.BindAddr, o.SSHJumpPort)
+	}
+	cfg.ListenHostForPortForwarding = o.PortForwardingBindAddr
+	for _, source := range o.PortForwardingUDPSources {
+		_, cidr, err := net.ParseCIDR(source)
+		if err != nil {
+			return nil, err
+		}
+		cfg.UDPSourcesForPortForwarding = append(cfg.UDPSourcesForPortForwarding, cidr)
+	}
 	cfg.RootCert, err = certmanager.GenRootCertPool(o.KubeConfig, constants.YurttunnelCAFile)
 	if err != nil {
 		return nil, fmt.Errorf("fail to generate the rootCertPool: %w", err)
@@ -37,6 +37,8 @@ import (
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
 	"github.com/openyurtio/openyurt/pkg/util/certmanager"
 	certfactory "github.com/openyurtio/openyurt/pkg/util/certmanager/factory"
+	"github.com/openyurtio/openyurt/pkg/util/ip"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/handlerwrapper/initializer"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/handlerwrapper/wraphandler"
@@ -45,6 +47,10 @@ import (
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/server/serveraddr"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/dns"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/iptables"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/l7proxy"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/portforward"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/scrapeconfig"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/trafficforward/sshjump"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
 )
 
@@ -115,6 +121,13 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 		wg.Add(1)
 		go iptablesMgr.Run(stopCh, &wg)
 	}
+	// 1.1. start the scrape config manager, the scrape ports of edge nodes are proxied by
+	// the iptables manager and dns controller
+	if cfg.EnableScrapeConfig {
+		scrapeConfigMgr := scrapeconfig.NewScrapeConfigManager(cfg.Client, cfg.SharedInformerFactory.Core().V1().Nodes())
+		wg.Add(1)
+		go scrapeConfigMgr.Run(stopCh, &wg)
+	}
 
 	// 2. create a certificate manager for the tunnel server
 	certManagerFactory := certfactory.NewCertManagerFactory(cfg.Client)
@@ -187,9 +200,23 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 		return err
 	}
 
-	// 7. start the server
+	// 7. start the server, the destinations of streams are checked by access policies if enabled
+	var accessEnforcer accesspolicy.Enforcer
+	if cfg.EnableAccessPolicy {
+		accessEnforcer = accesspolicy.NewEnforcer(cfg.Client)
+		wg.Add(1)
+		go accessEnforcer.Run(stopCh, &wg)
+	}
+	var egressSelector *server.EgressSelectorConfig
+	if cfg.EgressSelectorEnabled {
+		egressSelector = &server.EgressSelectorConfig{
+			Mode:       cfg.EgressSelectorMode,
+			ListenAddr: cfg.ListenAddrForEgressSelector,
+			UDSFile:    cfg.EgressSelectorUDSFile,
+		}
+	}
 	ts := server.NewTunnelServer(
-		cfg.EgressSelectorEnabled,
+		egressSelector,
 		cfg.InterceptorServerUDSFile,
 		cfg.ListenAddrForMaster,
 		cfg.ListenInsecureAddrForMaster,
@@ -198,11 +225,44 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 		tlsCfg,
 		proxyClientTlsCfg,
 		wrappers,
-		cfg.ProxyStrategy)
+		cfg.ProxyStrategy,
+		cfg.MaxStreamsPerAgent,
+		cfg.StreamWaitTimeout,
+		cfg.StreamBandwidthLimits,
+		cfg.PeerAddr,
+		cfg.Client,
+		accessEnforcer,
+		cfg.SharedInformerFactory.Core().V1().Nodes().Lister())
 	if err := ts.Run(); err != nil {
 		return err
 	}
 
+	// 7.1. start the l7 proxy for edge http services
+	if len(cfg.ListenAddrForL7Proxy) != 0 {
+		l7Proxy := l7proxy.NewL7Proxy(cfg.Client, cfg.ListenAddrForL7Proxy, cfg.InterceptorServerUDSFile, tlsCfg)
+		wg.Add(1)
+		go l7Proxy.Run(stopCh, &wg)
+	}
+
+	// 7.2. start the port forward manager, udp datagrams are relayed by the tunnel agent
+	if cfg.EnablePortForwarding {
+		portForwardMgr := portforward.NewPortForwardManager(cfg.Client,
+			cfg.ListenHostForPortForwarding,
+			cfg.InterceptorServerUDSFile,
+			net.JoinHostPort(ip.MustGetLoopbackIP(cfg.IsIPv6()), constants.YurttunnelAgentUDPRelayPort),
+			tlsCfg,
+			cfg.UDPSourcesForPortForwarding)
+		wg.Add(1)
+		go portForwardMgr.Run(stopCh, &wg)
+	}
+
+	// 7.3. start the ssh jump for break-glass maintenance of edge nodes
+	if len(cfg.ListenAddrForSSHJump) != 0 {
+		sshJump := sshjump.NewSSHJump(cfg.Client, cfg.ListenAddrForSSHJump, cfg.InterceptorServerUDSFile, tlsCfg)
+		wg.Add(1)
+		go sshJump.Run(stopCh, &wg)
+	}
+
 	// 8. start meta server
 	util.RunMetaServer(cfg.ListenMetaAddr)
 
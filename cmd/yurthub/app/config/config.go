@@ -20,7 +20,9 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -47,14 +49,32 @@ import (
 	ipUtils "github.com/openyurtio/openyurt/pkg/util/ip"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/certificate"
+	"github.com/openyurtio/openyurt/pkg/yurthub/certificate/localca"
 	"github.com/openyurtio/openyurt/pkg/yurthub/certificate/token"
+	"github.com/openyurtio/openyurt/pkg/yurthub/compat"
+	"github.com/openyurtio/openyurt/pkg/yurthub/credentialprovider"
+	"github.com/openyurtio/openyurt/pkg/yurthub/egress"
+	"github.com/openyurtio/openyurt/pkg/yurthub/events"
 	"github.com/openyurtio/openyurt/pkg/yurthub/filter"
 	"github.com/openyurtio/openyurt/pkg/yurthub/filter/manager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/imagegc"
+	"github.com/openyurtio/openyurt/pkg/yurthub/interceptor"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/meta"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/serializer"
 	"github.com/openyurtio/openyurt/pkg/yurthub/network"
+	"github.com/openyurtio/openyurt/pkg/yurthub/nodeproblem"
+	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator/election"
+	"github.com/openyurtio/openyurt/pkg/yurthub/siteview"
+	"github.com/openyurtio/openyurt/pkg/yurthub/standby"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/bolt"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/encryption"
+	"github.com/openyurtio/openyurt/pkg/yurthub/timesync"
+	"github.com/openyurtio/openyurt/pkg/yurthub/trafficprofile"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
+	"github.com/openyurtio/openyurt/pkg/yurthub/webhooks"
 	yurtcorev1alpha1 "github.com/openyurtio/yurt-app-manager-api/pkg/yurtappmanager/apis/apps/v1alpha1"
 	yurtclientset "github.com/openyurtio/yurt-app-manager-api/pkg/yurtappmanager/client/clientset/versioned"
 	"github.com/openyurtio/yurt-app-manager-api/pkg/yurtappmanager/client/clientset/versioned/fake"
@@ -62,6 +82,8 @@ import (
 	yurtv1alpha1 "github.com/openyurtio/yurt-app-manager-api/pkg/yurtappmanager/client/informers/externalversions/apps/v1alpha1"
 )
 
+const compatSelfTestTimeout = 5 * time.Second
+
 // YurtHubConfiguration represents configuration of yurthub
 type YurtHubConfiguration struct {
 	LBMode                          string
@@ -85,12 +107,21 @@ type YurtHubConfiguration struct {
 	CoordinatorServer               *url.URL
 	MinRequestTimeout               time.Duration
 	TenantNs                        string
+	TenantNamespaces                []string
+	TenantComponents                map[string]string
+	TenantTokenExpiration           time.Duration
+	TenantTokenDir                  string
 	NetworkMgr                      *network.NetworkManager
 	CertManager                     certificate.YurtCertificateManager
 	YurtHubServerServing            *apiserver.DeprecatedInsecureServingInfo
 	YurtHubProxyServerServing       *apiserver.DeprecatedInsecureServingInfo
 	YurtHubDummyProxyServerServing  *apiserver.DeprecatedInsecureServingInfo
 	YurtHubSecureProxyServerServing *apiserver.SecureServingInfo
+	DualStackDummyProxyServing      *apiserver.DeprecatedInsecureServingInfo
+	DualStackSecureProxyServing     *apiserver.SecureServingInfo
+	RedirectSecureProxyServing      *apiserver.SecureServingInfo
+	SiteViewServing                 *apiserver.SecureServingInfo
+	CredentialProviderServing       *apiserver.DeprecatedInsecureServingInfo
 	YurtHubProxyServerAddr          string
 	ProxiedClient                   kubernetes.Interface
 	DiskCachePath                   string
@@ -100,11 +131,34 @@ type YurtHubConfiguration struct {
 	CoordinatorStoragePrefix        string
 	CoordinatorStorageAddr          string // ip:port
 	CoordinatorClient               kubernetes.Interface
+	CoordinatorDelegates            int
+	EnableHardwareDiscovery         bool
+	ResourceMetricsCacheDir         string
+	KubeletResourceMetricsURL       string
+	CredentialProvider              *credentialprovider.Provider
+	EventAggregator                 *events.Aggregator
+	WebhookMatcher                  *webhooks.Matcher
+	NodeProblemRelay                *nodeproblem.Relay
+	Interceptors                    *interceptor.Chain
+	StandbyManager                  *standby.Manager
+	ImageGCManager                  *imagegc.Manager
+	TimeSyncMonitor                 *timesync.Monitor
+	PoolElection                    *election.Service
+	SiteView                        *siteview.Service
+	TrafficProfiles                 *trafficprofile.Manager
+	CacheKeyring                    *encryption.Keyring
+	EgressGateway                   *egress.Gateway
+	BlockCertOnClockSkew            bool
 	LeaderElection                  componentbaseconfig.LeaderElectionConfiguration
 }
 
 // Complete converts *options.YurtHubOptions to *YurtHubConfiguration
 func Complete(options *options.YurtHubOptions) (*YurtHubConfiguration, error) {
+	activeCluster, dormantCluster, err := applyActiveCluster(options)
+	if err != nil {
+		return nil, err
+	}
+
 	us, err := parseRemoteServers(options.ServerAddr)
 	if err != nil {
 		return nil, err
@@ -118,12 +172,33 @@ func Complete(options *options.YurtHubOptions) (*YurtHubConfiguration, error) {
 		}
 	}
 
-	storageManager, err := disk.NewDiskStorage(options.DiskCachePath)
+	certMgr, err := createCertManager(options, us)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCompatibilityShims(options, certMgr); err != nil {
+		return nil, err
+	}
+
+	storageManager, err := newStorageManager(options)
 	if err != nil {
 		klog.Errorf("could not create storage manager, %v", err)
 		return nil, err
 	}
-	storageWrapper := cachemanager.NewStorageWrapper(storageManager)
+	var cacheKeyring *encryption.Keyring
+	if len(options.CacheEncryptionResources) != 0 {
+		if cacheKeyring, err = newCacheKeyring(options); err != nil {
+			klog.Errorf("could not load keys for encrypting cache, %v", err)
+			return nil, err
+		}
+		klog.Infof("%s are encrypted in local cache", strings.Join(options.CacheEncryptionResources, ","))
+		storageManager = encryption.NewEncryptedStorage(storageManager, cacheKeyring, options.CacheEncryptionResources)
+	}
+	cacheCompression, err := compression.ParseCodec(options.CacheCompression)
+	if err != nil {
+		return nil, err
+	}
+	storageWrapper := cachemanager.NewStorageWrapperWithCompression(storageManager, cacheCompression)
 	serializerManager := serializer.NewSerializerManager()
 	restMapperManager, err := meta.NewRESTMapperManager(options.DiskCachePath)
 	if err != nil {
@@ -165,6 +240,10 @@ func Complete(options *options.YurtHubOptions) (*YurtHubConfiguration, error) {
 		FilterManager:             filterManager,
 		MinRequestTimeout:         options.MinRequestTimeout,
 		TenantNs:                  tenantNs,
+		TenantNamespaces:          util.ParseTenantNamespacesFromOrgs(options.YurtHubCertOrganizations),
+		TenantComponents:          options.TenantComponents,
+		TenantTokenExpiration:     options.TenantTokenExpiration,
+		TenantTokenDir:            filepath.Join(options.RootDir, "tenants"),
 		YurtHubProxyServerAddr:    fmt.Sprintf("%s:%d", options.YurtHubProxyHost, options.YurtHubProxyPort),
 		ProxiedClient:             proxiedClient,
 		DiskCachePath:             options.DiskCachePath,
@@ -173,18 +252,17 @@ func Complete(options *options.YurtHubOptions) (*YurtHubConfiguration, error) {
 		CoordinatorServerURL:      coordinatorServerURL,
 		CoordinatorStoragePrefix:  options.CoordinatorStoragePrefix,
 		CoordinatorStorageAddr:    options.CoordinatorStorageAddr,
+		CoordinatorDelegates:      options.CoordinatorDelegates,
+		EnableHardwareDiscovery:   options.EnableHardwareDiscovery,
 		LeaderElection:            options.LeaderElection,
-	}
-
-	certMgr, err := createCertManager(options, us)
-	if err != nil {
-		return nil, err
+		BlockCertOnClockSkew:      options.BlockCertOnClockSkew,
+		CacheKeyring:              cacheKeyring,
 	}
 	cfg.CertManager = certMgr
 
 	if options.EnableDummyIf {
 		klog.V(2).Infof("create dummy network interface %s(%s) and init iptables manager", options.HubAgentDummyIfName, options.HubAgentDummyIfIP)
-		networkMgr, err := network.NewNetworkManager(options)
+		networkMgr, err := network.NewNetworkManager(options, sharedFactory)
 		if err != nil {
 			return nil, fmt.Errorf("could not create network manager, %w", err)
 		}
@@ -195,9 +273,201 @@ func Complete(options *options.YurtHubOptions) (*YurtHubConfiguration, error) {
 		return nil, err
 	}
 
+	if options.EnableMetricsAPICache {
+		cfg.ResourceMetricsCacheDir = filepath.Join(options.RootDir, "resource-metrics")
+		cfg.KubeletResourceMetricsURL = options.KubeletResourceMetricsURL
+	}
+
+	if options.EnableEventAggregation && cfg.WorkingMode == util.WorkingModeEdge {
+		cfg.WebhookMatcher = webhooks.NewMatcher(filepath.Join(options.RootDir, "webhooks"))
+		cfg.EventAggregator = events.NewAggregator(filepath.Join(options.RootDir, "events"), cfg.WebhookMatcher)
+	}
+
+	if cfg.WorkingMode == util.WorkingModeEdge {
+		cfg.NodeProblemRelay = nodeproblem.NewRelay(options.NodeName)
+	}
+
+	if options.EnableCoordinator && cfg.WorkingMode == util.WorkingModeEdge {
+		cfg.PoolElection = election.NewService()
+	}
+
+	if len(options.SiteViewBindAddr) != 0 && cfg.WorkingMode == util.WorkingModeEdge {
+		var localEvents func() []*corev1.Event
+		if cfg.EventAggregator != nil {
+			localEvents = cfg.EventAggregator.Events
+		}
+		cfg.SiteView, err = siteview.NewService(options.SiteViewCredentialsFile, storageWrapper, localEvents,
+			options.LeaderElection.ResourceNamespace, options.LeaderElection.ResourceName)
+		if err != nil {
+			return nil, err
+		}
+		if err := prepareSiteViewServing(options, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.WorkingMode == util.WorkingModeEdge {
+		cfg.TrafficProfiles, err = trafficprofile.NewManager(options.TrafficProfile, options.TrafficProfilesFile, options.NodeName, storageWrapper)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(options.EgressGatewayBindAddr) != 0 && cfg.WorkingMode == util.WorkingModeEdge {
+		credentials, err := egress.LoadCredentials(options.EgressGatewayCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.EgressGateway = egress.NewGateway(options.EgressGatewayBindAddr, options.NodeName, storageWrapper, credentials)
+	}
+
+	if options.EnableTimeSyncMonitor {
+		cfg.TimeSyncMonitor = timesync.NewMonitor(options.NodeName, options.ClockSkewThreshold)
+	}
+
+	if options.ImageGCHighThreshold != 0 && cfg.WorkingMode == util.WorkingModeEdge {
+		runtime, err := imagegc.NewCRIRuntime(options.ImageGCCRISocket)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ImageGCManager = imagegc.NewManager(runtime, options.ImageFsPath,
+			options.ImageGCHighThreshold, options.ImageGCLowThreshold, storageWrapper)
+	}
+
+	if len(options.Interceptors) != 0 {
+		cfg.Interceptors, err = interceptor.NewChain(options.Interceptors, options.InterceptorTimeout, options.InterceptorFailurePolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(options.RegistryCredentialSecrets) != 0 {
+		cfg.CredentialProvider = credentialprovider.NewProvider(proxiedClient, options.RegistryCredentialSecrets, filepath.Join(options.RootDir, "registry-credentials"))
+		if cfg.CredentialProviderServing, err = prepareCredentialProviderServing(filepath.Join(options.RootDir, credentialprovider.CredentialProviderSocket)); err != nil {
+			return nil, err
+		}
+	}
+
+	if dormantCluster != nil {
+		dormantServers, err := parseRemoteServers(dormantCluster.ServerAddr)
+		if err != nil {
+			return nil, err
+		}
+		dormantOptions := *options
+		dormantOptions.ServerAddr = dormantCluster.ServerAddr
+		dormantOptions.JoinToken = dormantCluster.JoinToken
+		dormantOptions.CACertHashes = dormantCluster.CACertHashes
+		dormantOptions.CAFile = dormantCluster.CAFile
+		dormantOptions.RootDir = dormantCluster.RootDir
+		// switchover state is always stored in the root dir of primary cluster
+		stateDir := dormantCluster.RootDir
+		if activeCluster == standby.ClusterPrimary {
+			stateDir = options.RootDir
+		}
+		cfg.StandbyManager = standby.NewManager(options.NodeName, stateDir, activeCluster, proxiedClient, func() (certificate.YurtCertificateManager, error) {
+			return newCertManager(&dormantOptions, dormantServers)
+		})
+	}
+
 	return cfg, nil
 }
 
+// applyActiveCluster re-homes options to the standby cluster if the node has been switched over to it,
+// and returns the active cluster and the dormant cluster which the node can be switched over to.
+// The files of standby cluster are stored in the standby dir of primary root dir, and the cache of
+// standby cluster is separated from primary cluster.
+func applyActiveCluster(options *options.YurtHubOptions) (string, *standby.Cluster, error) {
+	if len(options.StandbyServerAddr) == 0 {
+		return "", nil, nil
+	}
+
+	active, err := standby.LoadActiveCluster(options.RootDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load active cluster, %w", err)
+	}
+	primaryCluster := &standby.Cluster{
+		ServerAddr:   options.ServerAddr,
+		JoinToken:    options.JoinToken,
+		CACertHashes: options.CACertHashes,
+		CAFile:       options.CAFile,
+		RootDir:      options.RootDir,
+	}
+	standbyCluster := &standby.Cluster{
+		ServerAddr:   options.StandbyServerAddr,
+		JoinToken:    options.StandbyJoinToken,
+		CACertHashes: options.StandbyCACertHashes,
+		RootDir:      filepath.Join(options.RootDir, standby.StandbyDirName),
+	}
+	if active == standby.ClusterPrimary {
+		return active, standbyCluster, nil
+	}
+
+	klog.Infof("node %s has been switched over to standby cluster %s", options.NodeName, standbyCluster.ServerAddr)
+	options.ServerAddr = standbyCluster.ServerAddr
+	options.JoinToken = standbyCluster.JoinToken
+	options.CACertHashes = standbyCluster.CACertHashes
+	options.CAFile = standbyCluster.CAFile
+	options.RootDir = standbyCluster.RootDir
+	options.DiskCachePath = options.DiskCachePath + "-" + standby.StandbyDirName
+	if len(options.SecretsTmpfsPath) != 0 {
+		options.SecretsTmpfsPath = options.SecretsTmpfsPath + "-" + standby.StandbyDirName
+	}
+	return active, primaryCluster, nil
+}
+
+// applyCompatibilityShims runs the compatibility self-test against the cloud control plane, and adjusts
+// options for the lightweight distributions like k3s and k0s. The self-test is skipped if the cloud is
+// unreachable, e.g. yurthub is restarted while the node is offline.
+func applyCompatibilityShims(options *options.YurtHubOptions, certMgr certificate.YurtCertificateManager) error {
+	client := options.ClientForTest
+	if client == nil {
+		restCfg, err := clientcmd.BuildConfigFromFlags("", certMgr.GetHubConfFile())
+		if err != nil {
+			return err
+		}
+		restCfg.Timeout = compatSelfTestTimeout
+		client, err = kubernetes.NewForConfig(restCfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := compat.SelfTest(client)
+	if err != nil {
+		klog.Warningf("skip compatibility self-test, %v", err)
+		return nil
+	}
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+	if options.EnableNodePool && !report.NodePoolsServed {
+		klog.Warningf("nodepools are not served by %s control plane, disable list/watch nodepools", report.Distribution)
+		options.EnableNodePool = false
+	}
+	return nil
+}
+
+// newStorageManager creates the storage of StorageBackend, secrets are cached in tmpfs instead of disk if SecretsTmpfsPath is set.
+func newStorageManager(o *options.YurtHubOptions) (storage.Store, error) {
+	if o.StorageBackend == options.StorageBackendBolt {
+		return bolt.NewBoltStorage(o.DiskCachePath)
+	}
+	if len(o.SecretsTmpfsPath) == 0 {
+		return disk.NewDiskStorage(o.DiskCachePath)
+	}
+	klog.Infof("secrets are cached in memory at %s", o.SecretsTmpfsPath)
+	return disk.NewStorageWithSecretsInMemory(o.DiskCachePath, o.SecretsTmpfsPath)
+}
+
+// newCacheKeyring loads the keys for encrypting cached objects from key file or tpm.
+func newCacheKeyring(o *options.YurtHubOptions) (*encryption.Keyring, error) {
+	if len(o.CacheEncryptionTPMHandles) != 0 {
+		return encryption.NewKeyring(encryption.NewTPMProvider(o.CacheEncryptionTPMHandles, o.CacheEncryptionTPMPCRs))
+	}
+	return encryption.NewKeyring(encryption.NewKeyFileProvider(o.CacheEncryptionKeyFile))
+}
+
 func parseRemoteServers(serverAddr string) ([]*url.URL, error) {
 	if serverAddr == "" {
 		return make([]*url.URL, 0), fmt.Errorf("--server-addr should be set for hub agent")
@@ -331,19 +601,24 @@ func serviceTopologyFilterEnabled(options *options.YurtHubOptions) bool {
 	return true
 }
 
-func createCertManager(options *options.YurtHubOptions, remoteServers []*url.URL) (certificate.YurtCertificateManager, error) {
-	// use dummy ip and bind ip as cert IP SANs
-	certIPs := ipUtils.RemoveDupIPs([]net.IP{
+func newCertManager(options *options.YurtHubOptions, remoteServers []*url.URL) (certificate.YurtCertificateManager, error) {
+	// use dummy ips and bind ip as cert IP SANs
+	certIPs := []net.IP{
 		net.ParseIP(options.HubAgentDummyIfIP),
 		net.ParseIP(options.YurtHubHost),
 		net.ParseIP(options.YurtHubProxyHost),
-	})
+	}
+	for _, ip := range options.HubAgentDummyIfIPs {
+		certIPs = append(certIPs, net.ParseIP(ip))
+	}
+	certIPs = ipUtils.RemoveDupIPs(certIPs)
 
 	cfg := &token.CertificateManagerConfiguration{
 		RootDir:                  options.RootDir,
 		NodeName:                 options.NodeName,
 		JoinToken:                options.JoinToken,
 		CaCertHashes:             options.CACertHashes,
+		CAFile:                   options.CAFile,
 		YurtHubCertOrganizations: options.YurtHubCertOrganizations,
 		CertIPs:                  certIPs,
 		RemoteServers:            remoteServers,
@@ -353,6 +628,14 @@ func createCertManager(options *options.YurtHubOptions, remoteServers []*url.URL
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cert manager for yurthub, %v", err)
 	}
+	return certManager, nil
+}
+
+func createCertManager(options *options.YurtHubOptions, remoteServers []*url.URL) (certificate.YurtCertificateManager, error) {
+	certManager, err := newCertManager(options, remoteServers)
+	if err != nil {
+		return nil, err
+	}
 
 	certManager.Start()
 	err = wait.PollImmediate(5*time.Second, 4*time.Minute, func() (bool, error) {
@@ -369,6 +652,51 @@ func createCertManager(options *options.YurtHubOptions, remoteServers []*url.URL
 	return certManager, nil
 }
 
+// prepareCredentialProviderServing prepares the serving of registry credentials on the unix socket,
+// which is only accessible by root on the node.
+func prepareCredentialProviderServing(socket string) (*apiserver.DeprecatedInsecureServingInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0755); err != nil {
+		return nil, err
+	}
+	// remove the socket left by the former yurthub
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s, %w", socket, err)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &apiserver.DeprecatedInsecureServingInfo{Listener: listener, Name: "credential-provider"}, nil
+}
+
+// prepareSiteViewServing prepares the https serving of site view on the bind address, which has been
+// validated as ip:port.
+func prepareSiteViewServing(options *options.YurtHubOptions, cfg *YurtHubConfiguration) error {
+	host, portStr, err := net.SplitHostPort(options.SiteViewBindAddr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+	return (&apiserveroptions.SecureServingOptions{
+		BindAddress: net.ParseIP(host),
+		BindPort:    port,
+		BindNetwork: "tcp",
+		ServerCert: apiserveroptions.GeneratableKeyCert{
+			CertKey: apiserveroptions.CertKey{
+				CertFile: options.SiteViewTLSCertFile,
+				KeyFile:  options.SiteViewTLSKeyFile,
+			},
+		},
+	}).ApplyTo(&cfg.SiteViewServing)
+}
+
 func prepareServerServing(options *options.YurtHubOptions, certMgr certificate.YurtCertificateManager, cfg *YurtHubConfiguration) error {
 	if err := (&apiserveroptions.DeprecatedInsecureServingOptions{
 		BindAddress: net.ParseIP(options.YurtHubHost),
@@ -406,21 +734,86 @@ func prepareServerServing(options *options.YurtHubOptions, certMgr certificate.Y
 		return err
 	}
 
+	applySecureServing := func(host string, serving **apiserver.SecureServingInfo) error {
+		if err := (&apiserveroptions.SecureServingOptions{
+			BindAddress: net.ParseIP(host),
+			BindPort:    options.YurtHubProxySecurePort,
+			BindNetwork: "tcp",
+			ServerCert: apiserveroptions.GeneratableKeyCert{
+				CertKey: apiserveroptions.CertKey{
+					CertFile: serverCertPath,
+					KeyFile:  serverCertPath,
+				},
+			},
+		}).ApplyTo(serving); err != nil {
+			return err
+		}
+		(*serving).ClientCA = caBundleProvider
+		(*serving).DisableHTTP2 = true
+		return nil
+	}
+	if err := applySecureServing(yurtHubSecureProxyHost, &cfg.YurtHubSecureProxyServerServing); err != nil {
+		return err
+	}
+
+	// the proxy servers are served on the secondary dummy ip too for dual-stack
+	if options.EnableDummyIf && len(options.HubAgentDummyIfIPs) > 1 {
+		if err := (&apiserveroptions.DeprecatedInsecureServingOptions{
+			BindAddress: net.ParseIP(options.HubAgentDummyIfIPs[1]),
+			BindPort:    options.YurtHubProxyPort,
+			BindNetwork: "tcp",
+		}).ApplyTo(&cfg.DualStackDummyProxyServing); err != nil {
+			return err
+		}
+		if err := applySecureServing(options.HubAgentDummyIfIPs[1], &cfg.DualStackSecureProxyServing); err != nil {
+			return err
+		}
+	}
+
+	if options.RedirectsHTTPSByBPF() {
+		return prepareRedirectServing(options, caBundleProvider, cfg)
+	}
+	return nil
+}
+
+// prepareRedirectServing prepares the secure proxy server which the https connections to kube-apiserver
+// are redirected to in ebpf firewall mode with --bpf-redirect-https. The clients verify the serving cert with the addresses of
+// kube-apiserver, so the cert is signed by the node-local CA, which is trusted only by the clients
+// configured to trust it, rather than the cluster CA trusted by all clients of the cluster.
+func prepareRedirectServing(options *options.YurtHubOptions, caBundleProvider dynamiccertificates.CAContentProvider, cfg *YurtHubConfiguration) error {
+	var ips []net.IP
+	var dnsNames []string
+	for _, server := range strings.Split(options.ServerAddr, ",") {
+		u, err := url.Parse(strings.TrimSpace(server))
+		if err != nil || u.Scheme != "https" {
+			continue
+		}
+		if ip := net.ParseIP(u.Hostname()); ip != nil {
+			ips = append(ips, ip)
+		} else if len(u.Hostname()) != 0 {
+			dnsNames = append(dnsNames, u.Hostname())
+		}
+	}
+	files, err := localca.EnsureServingCert(filepath.Join(options.RootDir, "pki", "redirect"), ipUtils.RemoveDupIPs(ips), dnsNames)
+	if err != nil {
+		return fmt.Errorf("failed to ensure serving cert for redirected connections, %w", err)
+	}
+	klog.Infof("https connections to kube-apiserver are redirected to hub agent, only the clients trusting %s accept them", files.CAFile)
+
 	if err := (&apiserveroptions.SecureServingOptions{
-		BindAddress: net.ParseIP(yurtHubSecureProxyHost),
-		BindPort:    options.YurtHubProxySecurePort,
+		BindAddress: net.ParseIP(options.RedirectTargetIP()),
+		BindPort:    options.YurtHubProxyRedirectSecurePort,
 		BindNetwork: "tcp",
 		ServerCert: apiserveroptions.GeneratableKeyCert{
 			CertKey: apiserveroptions.CertKey{
-				CertFile: serverCertPath,
-				KeyFile:  serverCertPath,
+				CertFile: files.CertFile,
+				KeyFile:  files.CertFile,
 			},
 		},
-	}).ApplyTo(&cfg.YurtHubSecureProxyServerServing); err != nil {
+	}).ApplyTo(&cfg.RedirectSecureProxyServing); err != nil {
 		return err
 	}
-	cfg.YurtHubSecureProxyServerServing.ClientCA = caBundleProvider
-	cfg.YurtHubSecureProxyServerServing.DisableHTTP2 = true
-
+	cfg.RedirectSecureProxyServing.ClientCA = caBundleProvider
+	cfg.RedirectSecureProxyServing.DisableHTTP2 = true
 	return nil
 }
@@ -19,6 +19,9 @@ package config
 import (
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+
 	"github.com/openyurtio/openyurt/cmd/yurthub/app/options"
 	"github.com/openyurtio/openyurt/pkg/yurthub/certificate/token/testdata"
 )
@@ -30,6 +33,11 @@ func TestComplete(t *testing.T) {
 		t.Errorf("failed to create cert fake client, %v", err)
 		return
 	}
+	// resources served by the cloud for compatibility self-test
+	for _, gv := range []string{"coordination.k8s.io/v1", "certificates.k8s.io/v1"} {
+		discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+		discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: gv})
+	}
 	options.ClientForTest = client
 	options.ServerAddr = "https://127.0.0.1:6443"
 	options.JoinToken = "123456.abcdef1234567890"
@@ -19,11 +19,15 @@ package options
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	componentbaseconfig "k8s.io/component-base/config"
@@ -31,7 +35,11 @@ import (
 	utilnet "k8s.io/utils/net"
 
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurthub/interceptor"
+	"github.com/openyurtio/openyurt/pkg/yurthub/resourcemetrics"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/encryption"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
 
@@ -42,84 +50,165 @@ const (
 	ExclusiveCIDR     = "169.254.31.0/24"
 )
 
+// the modes of programming the rules for accessing hub agent through the dummy interface
+const (
+	FirewallModeAuto           = "auto"
+	FirewallModeIptables       = "iptables"
+	FirewallModeIptablesLegacy = "iptables-legacy"
+	FirewallModeIptablesNft    = "iptables-nft"
+	FirewallModeNftables       = "nftables"
+	// FirewallModeEBPF redirects the connections to kube-apiserver on the node to hub agent by
+	// a cgroup/connect4 bpf program instead of programming iptables or nftables rules.
+	FirewallModeEBPF = "ebpf"
+)
+
+// the backends of storage which yurthub caches the resources in
+const (
+	StorageBackendDisk = "disk"
+	StorageBackendBolt = "bolt"
+)
+
 // YurtHubOptions is the main settings for the yurthub
 type YurtHubOptions struct {
-	ServerAddr                string
-	YurtHubHost               string // YurtHub server host (e.g.: expose metrics API)
-	YurtHubProxyHost          string // YurtHub proxy server host
-	YurtHubPort               int
-	YurtHubProxyPort          int
-	YurtHubProxySecurePort    int
-	GCFrequency               int
-	YurtHubCertOrganizations  []string
-	NodeName                  string
-	NodePoolName              string
-	LBMode                    string
-	HeartbeatFailedRetry      int
-	HeartbeatHealthyThreshold int
-	HeartbeatTimeoutSeconds   int
-	HeartbeatIntervalSeconds  int
-	MaxRequestInFlight        int
-	JoinToken                 string
-	RootDir                   string
-	Version                   bool
-	EnableProfiling           bool
-	EnableDummyIf             bool
-	EnableIptables            bool
-	HubAgentDummyIfIP         string
-	HubAgentDummyIfName       string
-	DiskCachePath             string
-	AccessServerThroughHub    bool
-	EnableResourceFilter      bool
-	DisabledResourceFilters   []string
-	WorkingMode               string
-	KubeletHealthGracePeriod  time.Duration
-	EnableNodePool            bool
-	MinRequestTimeout         time.Duration
-	CACertHashes              []string
-	UnsafeSkipCAVerification  bool
-	ClientForTest             kubernetes.Interface
-	EnableCoordinator         bool
-	CoordinatorServerAddr     string
-	CoordinatorStoragePrefix  string
-	CoordinatorStorageAddr    string
-	LeaderElection            componentbaseconfig.LeaderElectionConfiguration
+	ServerAddr                     string
+	YurtHubHost                    string // YurtHub server host (e.g.: expose metrics API)
+	YurtHubProxyHost               string // YurtHub proxy server host
+	YurtHubPort                    int
+	YurtHubProxyPort               int
+	YurtHubProxySecurePort         int
+	YurtHubProxyRedirectSecurePort int
+	GCFrequency                    int
+	YurtHubCertOrganizations       []string
+	TenantComponents               map[string]string
+	TenantTokenExpiration          time.Duration
+	NodeName                       string
+	NodePoolName                   string
+	LBMode                         string
+	HeartbeatFailedRetry           int
+	HeartbeatHealthyThreshold      int
+	HeartbeatTimeoutSeconds        int
+	HeartbeatIntervalSeconds       int
+	MaxRequestInFlight             int
+	JoinToken                      string
+	RootDir                        string
+	Version                        bool
+	EnableProfiling                bool
+	EnableDummyIf                  bool
+	EnableIptables                 bool
+	FirewallMode                   string
+	BPFCgroupPath                  string
+	BPFRedirectHTTPS               bool
+	HubAgentDummyIfIP              string
+	HubAgentDummyIfIPs             []string
+	HubAgentDummyIfName            string
+	DiskCachePath                  string
+	AccessServerThroughHub         bool
+	EnableResourceFilter           bool
+	DisabledResourceFilters        []string
+	WorkingMode                    string
+	KubeletHealthGracePeriod       time.Duration
+	EnableNodePool                 bool
+	MinRequestTimeout              time.Duration
+	CACertHashes                   []string
+	UnsafeSkipCAVerification       bool
+	CAFile                         string
+	ClientForTest                  kubernetes.Interface
+	EnableCoordinator              bool
+	CoordinatorServerAddr          string
+	CoordinatorStoragePrefix       string
+	CoordinatorStorageAddr         string
+	CoordinatorDelegates           int
+	EnableHardwareDiscovery        bool
+	EnableMetricsAPICache          bool
+	KubeletResourceMetricsURL      string
+	EnableDNSCache                 bool
+	DNSCacheUpstream               string
+	DNSClusterDomain               string
+	RegistryCredentialSecrets      []string
+	EnableEventAggregation         bool
+	Interceptors                   []string
+	InterceptorTimeout             time.Duration
+	InterceptorFailurePolicy       string
+	StandbyServerAddr              string
+	StandbyJoinToken               string
+	StandbyCACertHashes            []string
+	SecretsTmpfsPath               string
+	ImageGCHighThreshold           int
+	ImageGCLowThreshold            int
+	ImageGCCRISocket               string
+	ImageFsPath                    string
+	EnableTimeSyncMonitor          bool
+	ClockSkewThreshold             time.Duration
+	BlockCertOnClockSkew           bool
+	StorageBackend                 string
+	CacheCompression               string
+	CacheEncryptionResources       []string
+	CacheEncryptionKeyFile         string
+	CacheEncryptionTPMHandles      []string
+	CacheEncryptionTPMPCRs         []int
+	SiteViewBindAddr               string
+	SiteViewCredentialsFile        string
+	SiteViewTLSCertFile            string
+	SiteViewTLSKeyFile             string
+	TrafficProfile                 string
+	TrafficProfilesFile            string
+	EgressGatewayBindAddr          string
+	EgressGatewayCredentialsFile   string
+	LeaderElection                 componentbaseconfig.LeaderElectionConfiguration
 }
 
 // NewYurtHubOptions creates a new YurtHubOptions with a default config.
 func NewYurtHubOptions() *YurtHubOptions {
 	o := &YurtHubOptions{
-		YurtHubHost:               "127.0.0.1",
-		YurtHubProxyHost:          "127.0.0.1",
-		YurtHubProxyPort:          util.YurtHubProxyPort,
-		YurtHubPort:               util.YurtHubPort,
-		YurtHubProxySecurePort:    util.YurtHubProxySecurePort,
-		GCFrequency:               120,
-		YurtHubCertOrganizations:  make([]string, 0),
-		LBMode:                    "rr",
-		HeartbeatFailedRetry:      3,
-		HeartbeatHealthyThreshold: 2,
-		HeartbeatTimeoutSeconds:   2,
-		HeartbeatIntervalSeconds:  10,
-		MaxRequestInFlight:        250,
-		RootDir:                   filepath.Join("/var/lib/", projectinfo.GetHubName()),
-		EnableProfiling:           true,
-		EnableDummyIf:             true,
-		EnableIptables:            true,
-		HubAgentDummyIfName:       fmt.Sprintf("%s-dummy0", projectinfo.GetHubName()),
-		DiskCachePath:             disk.CacheBaseDir,
-		AccessServerThroughHub:    true,
-		EnableResourceFilter:      true,
-		DisabledResourceFilters:   make([]string, 0),
-		WorkingMode:               string(util.WorkingModeEdge),
-		KubeletHealthGracePeriod:  time.Second * 40,
-		EnableNodePool:            true,
-		MinRequestTimeout:         time.Second * 1800,
-		CACertHashes:              make([]string, 0),
-		UnsafeSkipCAVerification:  true,
-		CoordinatorServerAddr:     fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorAPIServerSvcName, util.DefaultPoolCoordinatorAPIServerSvcPort),
-		CoordinatorStorageAddr:    fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorEtcdSvcName, util.DefaultPoolCoordinatorEtcdSvcPort),
-		CoordinatorStoragePrefix:  "/registry",
+		YurtHubHost:                    "127.0.0.1",
+		YurtHubProxyHost:               "127.0.0.1",
+		YurtHubProxyPort:               util.YurtHubProxyPort,
+		YurtHubPort:                    util.YurtHubPort,
+		YurtHubProxySecurePort:         util.YurtHubProxySecurePort,
+		YurtHubProxyRedirectSecurePort: util.YurtHubProxyRedirectSecurePort,
+		GCFrequency:                    120,
+		YurtHubCertOrganizations:       make([]string, 0),
+		LBMode:                         "rr",
+		HeartbeatFailedRetry:           3,
+		HeartbeatHealthyThreshold:      2,
+		HeartbeatTimeoutSeconds:        2,
+		HeartbeatIntervalSeconds:       10,
+		MaxRequestInFlight:             250,
+		RootDir:                        filepath.Join("/var/lib/", projectinfo.GetHubName()),
+		EnableProfiling:                true,
+		EnableDummyIf:                  true,
+		EnableIptables:                 true,
+		FirewallMode:                   FirewallModeAuto,
+		BPFCgroupPath:                  "/sys/fs/cgroup",
+		HubAgentDummyIfName:            fmt.Sprintf("%s-dummy0", projectinfo.GetHubName()),
+		DiskCachePath:                  disk.CacheBaseDir,
+		StorageBackend:                 StorageBackendDisk,
+		CacheCompression:               string(compression.None),
+		AccessServerThroughHub:         true,
+		EnableResourceFilter:           true,
+		DisabledResourceFilters:        make([]string, 0),
+		WorkingMode:                    string(util.WorkingModeEdge),
+		KubeletHealthGracePeriod:       time.Second * 40,
+		EnableNodePool:                 true,
+		MinRequestTimeout:              time.Second * 1800,
+		CACertHashes:                   make([]string, 0),
+		ImageGCLowThreshold:            80,
+		ImageGCCRISocket:               "unix:///run/containerd/containerd.sock",
+		ImageFsPath:                    "/var/lib/containerd",
+		CacheEncryptionTPMPCRs:         []int{7},
+		UnsafeSkipCAVerification:       true,
+		CoordinatorServerAddr:          fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorAPIServerSvcName, util.DefaultPoolCoordinatorAPIServerSvcPort),
+		CoordinatorStorageAddr:         fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorEtcdSvcName, util.DefaultPoolCoordinatorEtcdSvcPort),
+		CoordinatorStoragePrefix:       "/registry",
+		CoordinatorDelegates:           1,
+		EnableMetricsAPICache:          true,
+		KubeletResourceMetricsURL:      resourcemetrics.DefaultKubeletResourceMetricsURL,
+		DNSClusterDomain:               "cluster.local",
+		EnableEventAggregation:         true,
+		InterceptorTimeout:             interceptor.DefaultExecTimeout,
+		InterceptorFailurePolicy:       interceptor.FailurePolicyIgnore,
+		EnableTimeSyncMonitor:          true,
+		ClockSkewThreshold:             time.Minute,
 		LeaderElection: componentbaseconfig.LeaderElectionConfiguration{
 			LeaderElect:       true,
 			LeaseDuration:     metav1.Duration{Duration: 15 * time.Second},
@@ -159,10 +248,167 @@ func (options *YurtHubOptions) Validate() error {
 		return fmt.Errorf("dummy ip %s is not invalid, %w", options.HubAgentDummyIfIP, err)
 	}
 
-	if len(options.CACertHashes) == 0 && !options.UnsafeSkipCAVerification {
+	switch options.FirewallMode {
+	case FirewallModeAuto, FirewallModeIptables, FirewallModeIptablesLegacy, FirewallModeIptablesNft, FirewallModeNftables:
+	case FirewallModeEBPF:
+		hasIPv4 := false
+		for _, ip := range options.HubAgentDummyIfIPs {
+			hasIPv4 = hasIPv4 || utilnet.IsIPv4String(ip)
+		}
+		if !options.EnableDummyIf || !hasIPv4 {
+			return fmt.Errorf("connections are redirected to the ipv4 address of dummy interface in firewall mode %s, ipv4 dummy interface should be enabled", options.FirewallMode)
+		}
+	default:
+		return fmt.Errorf("firewall mode %s is not supported", options.FirewallMode)
+	}
+
+	if options.EnableDNSCache && !options.EnableDummyIf {
+		return fmt.Errorf("dns cache listens on the dummy interface, dummy interface should be enabled")
+	}
+
+	if len(options.KubeletResourceMetricsURL) != 0 {
+		u, err := url.Parse(options.KubeletResourceMetricsURL)
+		if err != nil {
+			return fmt.Errorf("kubelet resource metrics url %s is invalid, %w", options.KubeletResourceMetricsURL, err)
+		}
+		if ip := net.ParseIP(u.Hostname()); u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("kubelet resource metrics url %s should be on the loopback address", options.KubeletResourceMetricsURL)
+		}
+	}
+
+	for _, secret := range options.RegistryCredentialSecrets {
+		if parts := strings.Split(secret, "/"); len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return fmt.Errorf("registry credential secret %s should be in format namespace/name", secret)
+		}
+	}
+
+	if len(options.DNSCacheUpstream) != 0 {
+		host := options.DNSCacheUpstream
+		if h, _, err := net.SplitHostPort(options.DNSCacheUpstream); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("dns cache upstream %s is not an ip address", options.DNSCacheUpstream)
+		}
+	}
+
+	if options.EnableCoordinator && options.CoordinatorDelegates < 1 {
+		return fmt.Errorf("coordinator delegates %d should be at least 1", options.CoordinatorDelegates)
+	}
+
+	tenantNss := sets.NewString(util.ParseTenantNamespacesFromOrgs(options.YurtHubCertOrganizations)...)
+	for comp, ns := range options.TenantComponents {
+		if !tenantNss.Has(ns) {
+			return fmt.Errorf("tenant %s of component %s is not in hub cert organizations", ns, comp)
+		}
+	}
+
+	// the minimum expiration accepted by TokenRequest API is 10 minutes
+	if options.TenantTokenExpiration != 0 && options.TenantTokenExpiration < 10*time.Minute {
+		return fmt.Errorf("tenant token expiration %v should be at least 10m", options.TenantTokenExpiration)
+	}
+
+	if len(options.CACertHashes) == 0 && !options.UnsafeSkipCAVerification && len(options.CAFile) == 0 {
 		return fmt.Errorf("set --discovery-token-unsafe-skip-ca-verification flag as true or pass CACertHashes to continue")
 	}
 
+	if options.InterceptorFailurePolicy != interceptor.FailurePolicyIgnore && options.InterceptorFailurePolicy != interceptor.FailurePolicyFail {
+		return fmt.Errorf("interceptor failure policy %s is not supported", options.InterceptorFailurePolicy)
+	}
+
+	if options.BlockCertOnClockSkew && !options.EnableTimeSyncMonitor {
+		return fmt.Errorf("clock skew is detected by time sync monitor, time sync monitor should be enabled")
+	}
+
+	if options.ImageGCHighThreshold != 0 {
+		if options.ImageGCHighThreshold < 0 || options.ImageGCHighThreshold > 100 {
+			return fmt.Errorf("image gc high threshold %d should be in (0, 100]", options.ImageGCHighThreshold)
+		}
+		if options.ImageGCLowThreshold < 0 || options.ImageGCLowThreshold >= options.ImageGCHighThreshold {
+			return fmt.Errorf("image gc low threshold %d should be in [0, %d)", options.ImageGCLowThreshold, options.ImageGCHighThreshold)
+		}
+	}
+
+	switch options.StorageBackend {
+	case StorageBackendDisk:
+	case StorageBackendBolt:
+		if len(options.SecretsTmpfsPath) != 0 {
+			return fmt.Errorf("secrets can't be cached in tmpfs with storage backend %s", options.StorageBackend)
+		}
+	default:
+		return fmt.Errorf("storage backend %s is not supported", options.StorageBackend)
+	}
+
+	if _, err := compression.ParseCodec(options.CacheCompression); err != nil {
+		return err
+	}
+
+	if len(options.CacheEncryptionResources) != 0 {
+		if (len(options.CacheEncryptionKeyFile) == 0) == (len(options.CacheEncryptionTPMHandles) == 0) {
+			return fmt.Errorf("one of cache encryption key file and tpm handles should be set for encrypting cached %s", strings.Join(options.CacheEncryptionResources, ","))
+		}
+		for _, handle := range options.CacheEncryptionTPMHandles {
+			if _, err := encryption.ParseTPMHandle(handle); err != nil {
+				return err
+			}
+		}
+		if len(options.CacheEncryptionTPMHandles) != 0 && len(options.CacheEncryptionTPMPCRs) == 0 {
+			return fmt.Errorf("tpm pcrs should be set for unsealing cache encryption keys")
+		}
+		for _, pcr := range options.CacheEncryptionTPMPCRs {
+			if pcr < 0 || pcr > 23 {
+				return fmt.Errorf("tpm pcr %d should be in [0, 23]", pcr)
+			}
+		}
+	}
+
+	if len(options.SiteViewBindAddr) != 0 {
+		if !options.EnableCoordinator {
+			return fmt.Errorf("site view is served by the leader yurthub of pool, pool coordinator should be enabled")
+		}
+		if len(options.SiteViewCredentialsFile) == 0 {
+			return fmt.Errorf("site view credentials file should be set for serving site view")
+		}
+		if len(options.SiteViewTLSCertFile) == 0 || len(options.SiteViewTLSKeyFile) == 0 {
+			return fmt.Errorf("site view tls cert file and private key file should be set for serving site view")
+		}
+		host, port, err := net.SplitHostPort(options.SiteViewBindAddr)
+		if err != nil {
+			return fmt.Errorf("site view bind address %s is invalid, %w", options.SiteViewBindAddr, err)
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("host of site view bind address %s is not an ip address", options.SiteViewBindAddr)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("port of site view bind address %s is invalid, %w", options.SiteViewBindAddr, err)
+		}
+	}
+
+	if len(options.EgressGatewayBindAddr) != 0 {
+		host, port, err := net.SplitHostPort(options.EgressGatewayBindAddr)
+		if err != nil {
+			return fmt.Errorf("egress gateway bind address %s is invalid, %w", options.EgressGatewayBindAddr, err)
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("host of egress gateway bind address %s is not an ip address", options.EgressGatewayBindAddr)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return fmt.Errorf("port of egress gateway bind address %s is invalid, %w", options.EgressGatewayBindAddr, err)
+		}
+		if len(options.EgressGatewayCredentialsFile) == 0 {
+			return fmt.Errorf("credentials file of egress gateway is required, so only the authenticated pods can use it")
+		}
+	}
+
+	if len(options.StandbyServerAddr) != 0 {
+		if len(options.StandbyJoinToken) == 0 {
+			return fmt.Errorf("bootstrap token of standby cluster is empty")
+		}
+		if len(options.StandbyCACertHashes) == 0 && !options.UnsafeSkipCAVerification {
+			return fmt.Errorf("set --discovery-token-unsafe-skip-ca-verification flag as true or pass StandbyCACertHashes to continue")
+		}
+	}
+
 	return nil
 }
 
@@ -173,8 +419,11 @@ func (o *YurtHubOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.YurtHubProxyHost, "bind-proxy-address", o.YurtHubProxyHost, "the IP address of YurtHub Proxy Server")
 	fs.IntVar(&o.YurtHubProxyPort, "proxy-port", o.YurtHubProxyPort, "the port on which to proxy HTTP requests to kube-apiserver")
 	fs.IntVar(&o.YurtHubProxySecurePort, "proxy-secure-port", o.YurtHubProxySecurePort, "the port on which to proxy HTTPS requests to kube-apiserver")
+	fs.IntVar(&o.YurtHubProxyRedirectSecurePort, "proxy-redirect-secure-port", o.YurtHubProxyRedirectSecurePort, "the port on which to proxy the HTTPS connections to kube-apiserver which are redirected in ebpf firewall mode with --bpf-redirect-https. It's served with a cert signed by the node-local CA <root-dir>/pki/redirect/local-ca.crt instead of the cluster CA, and only the clients configured to trust the node-local CA accept the redirected connections.")
 	fs.StringVar(&o.ServerAddr, "server-addr", o.ServerAddr, "the address of Kubernetes kube-apiserver,the format is: \"server1,server2,...\"")
 	fs.StringSliceVar(&o.YurtHubCertOrganizations, "hub-cert-organizations", o.YurtHubCertOrganizations, "Organizations that will be added into hub's apiserver client certificate, the format is: certOrg1,certOrg2,...")
+	fs.StringToStringVar(&o.TenantComponents, "tenant-components", o.TenantComponents, "A set of component=namespace pairs, the requests of component are sent to kube-apiserver with the service account token of its tenant namespace, the namespaces should be specified as tenants by hub-cert-organizations in format openyurt:tenant:namespace. Components not specified belong to the first tenant, e.g. kube-proxy=tenant-a,coredns=tenant-b.")
+	fs.DurationVar(&o.TenantTokenExpiration, "tenant-token-expiration", o.TenantTokenExpiration, "The expiration of tenant tokens requested by TokenRequest API, the tokens are rotated before expiration and projected into <root-dir>/tenants/<namespace>/token for local components. The tokens of service account secrets are used if it's zero. The hub identity should be allowed to create serviceaccounts/token of the default service accounts in tenant namespaces(yurtHub.tenantTokenRequest of the openyurt chart) before it's set.")
 	fs.IntVar(&o.GCFrequency, "gc-frequency", o.GCFrequency, "the frequency to gc cache in storage(unit: minute).")
 	fs.StringVar(&o.NodeName, "node-name", o.NodeName, "the name of node that runs hub agent")
 	fs.StringVar(&o.LBMode, "lb-mode", o.LBMode, "the mode of load balancer to connect remote servers(rr, priority)")
@@ -189,9 +438,26 @@ func (o *YurtHubOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.EnableProfiling, "profiling", o.EnableProfiling, "enable profiling via web interface host:port/debug/pprof/")
 	fs.BoolVar(&o.EnableDummyIf, "enable-dummy-if", o.EnableDummyIf, "enable dummy interface or not")
 	fs.BoolVar(&o.EnableIptables, "enable-iptables", o.EnableIptables, "enable iptables manager to setup rules for accessing hub agent")
-	fs.StringVar(&o.HubAgentDummyIfIP, "dummy-if-ip", o.HubAgentDummyIfIP, "the ip address of dummy interface that used for container connect hub agent(exclusive ips: 169.254.31.0/24, 169.254.1.1/32)")
+	fs.StringVar(&o.FirewallMode, "firewall-mode", o.FirewallMode, "the mode of setting up rules for accessing hub agent(auto, iptables, iptables-legacy, iptables-nft, nftables). auto uses the iptables backend(legacy or nft) which kube-proxy rules are in, both for iptables and ipvs mode kube-proxy, and native nftables if iptables is not installed. nftables inserts the rules at the head of filter chains on input and output hooks of all nftables tables, e.g. the tables of firewalld. ebpf redirects the ipv4 connections to kube-apiserver on the node to hub agent by a cgroup bpf program, which requires kernel 5.7+ and cgroup v2.")
+	fs.StringVar(&o.BPFCgroupPath, "bpf-cgroup-path", o.BPFCgroupPath, "the path of cgroup v2 which the redirecting bpf program is attached to in ebpf firewall mode, it should be the root of host cgroup v2 for redirecting the connections of all processes on the node. If hub agent runs in a pod, mount the host cgroup v2 into the pod and set it to the mount path, because /sys/fs/cgroup of the pod is only the cgroup of the pod.")
+	fs.BoolVar(&o.BPFRedirectHTTPS, "bpf-redirect-https", o.BPFRedirectHTTPS, "redirect the https connections to kube-apiserver to --proxy-redirect-secure-port in ebpf firewall mode. The redirected connections are served with a cert signed by the node-local CA <root-dir>/pki/redirect/local-ca.crt, so only enable it when all redirected clients on the node are configured to trust the node-local CA, otherwise they fail to verify the server. By default only the http connections are redirected.")
+	fs.StringVar(&o.HubAgentDummyIfIP, "dummy-if-ip", o.HubAgentDummyIfIP, "the ip address of dummy interface that used for container connect hub agent(exclusive ips: 169.254.31.0/24, 169.254.1.1/32), an ipv4 and an ipv6 address separated by comma can be specified for dual-stack, e.g. 169.254.2.1,fd00::2:1, and the first one is the primary address.")
 	fs.StringVar(&o.HubAgentDummyIfName, "dummy-if-name", o.HubAgentDummyIfName, "the name of dummy interface that is used for hub agent")
 	fs.StringVar(&o.DiskCachePath, "disk-cache-path", o.DiskCachePath, "the path for kubernetes to storage metadata")
+	fs.StringVar(&o.StorageBackend, "storage-backend", o.StorageBackend, "the backend of storage which the resources are cached in under disk cache path(disk, bolt). disk caches each resource in a file, bolt caches all resources in a single BoltDB file and writes them in transactions, which avoids lots of small files on flash media.")
+	fs.StringVar(&o.CacheCompression, "cache-compression", o.CacheCompression, "the compression of objects cached in local storage(none, gzip, zstd). zstd is faster and gzip compresses slightly better. the cache is not rewritten when the compression is changed, the objects are compressed when they're updated, and both compressed and uncompressed objects can be read.")
+	fs.StringSliceVar(&o.CacheEncryptionResources, "cache-encryption-resources", o.CacheEncryptionResources, "the resources(like secrets,configmaps) whose objects are encrypted in local cache with envelope encryption, each object is encrypted by a random data key which is encrypted by the key from cache encryption key file or tpm. the cached objects are not encrypted if not set, and the objects which have been encrypted can't be read after encryption is disabled.")
+	fs.StringVar(&o.CacheEncryptionKeyFile, "cache-encryption-key-file", o.CacheEncryptionKeyFile, "the file of keys for encrypting cached objects, each line is a key in format of <id>:<base64 encoded 32 bytes key>, the key in the first line encrypts objects and all keys decrypt them. the file is reloaded every minute, and a key is rotated by adding the new key in the first line, the objects are re-encrypted by the new key when they're read, and then the old key can be removed.")
+	fs.StringSliceVar(&o.CacheEncryptionTPMHandles, "cache-encryption-tpm-handles", o.CacheEncryptionTPMHandles, fmt.Sprintf("the persistent handles(like 0x81010002) of tpm which the keys for encrypting cached objects are sealed in, the key of the first handle encrypts objects and all keys decrypt them. a key is rotated by sealing the new key in a new handle and adding it as the first handle. the keys should be sealed with the policy of --cache-encryption-tpm-pcrs, and %s should be mounted into yurthub.", encryption.TPMDevice))
+	fs.IntSliceVar(&o.CacheEncryptionTPMPCRs, "cache-encryption-tpm-pcrs", o.CacheEncryptionTPMPCRs, "the sha256 pcrs of tpm whose values are required by the policy of sealed cache encryption keys, so the keys are only unsealed when the node boots into the measured state.")
+	fs.StringVar(&o.SiteViewBindAddr, "site-view-bind-address", o.SiteViewBindAddr, "the address(ip:port) on which the leader yurthub of pool serves a read-only snapshot of nodes, pods and events in the pool from pool coordinator and local cache, for browsers on / and kubectl on the paths of kube-apiserver. It's served over https with basic auth, and should be bound on the site-local network only. Site view is disabled if not set.")
+	fs.StringVar(&o.TrafficProfile, "traffic-profile", o.TrafficProfile, "the traffic profile(lan, metered, satellite or a profile in traffic profiles file) which shapes the requests of local components when nodepool of the node doesn't select one by spec.trafficProfile. requests are not shaped if not set.")
+	fs.StringVar(&o.TrafficProfilesFile, "traffic-profiles-file", o.TrafficProfilesFile, "the yaml file of named traffic profiles, which bundle request timeout, watch timeout, watch bookmarks, list page size and retry budget, and can be overridden for components. profiles in the file take precedence over the built-in profiles with same names.")
+	fs.StringVar(&o.EgressGatewayBindAddr, "egress-gateway-bind-address", o.EgressGatewayBindAddr, "the address(ip:port) on which yurthub serves a SOCKS5 egress gateway for the cloud-bound traffic of pods, the pods are directed through it by proxy env like ALL_PROXY=socks5h://$(HOST_IP):<port>. The destinations and bandwidth of the gateway are restricted by spec.egress of nodepool, all traffic is rejected until the node is cached or if the nodepool has no egress policy. Egress gateway is disabled if not set.")
+	fs.StringVar(&o.EgressGatewayCredentialsFile, "egress-gateway-credentials-file", o.EgressGatewayCredentialsFile, "the file of credentials which the pods authenticate to egress gateway with, one username:password per line, like ALL_PROXY=socks5h://<username>:<password>@$(HOST_IP):<port>. It's required if egress gateway is enabled.")
+	fs.StringVar(&o.SiteViewCredentialsFile, "site-view-credentials-file", o.SiteViewCredentialsFile, "the file of basic auth credentials for site view, which is in format of username:password.")
+	fs.StringVar(&o.SiteViewTLSCertFile, "site-view-tls-cert-file", o.SiteViewTLSCertFile, "the file of x509 certificate for serving site view over https, the certificate should be trusted by the browsers and kubectl on the site.")
+	fs.StringVar(&o.SiteViewTLSKeyFile, "site-view-tls-private-key-file", o.SiteViewTLSKeyFile, "the file of x509 private key matching --site-view-tls-cert-file.")
 	fs.BoolVar(&o.AccessServerThroughHub, "access-server-through-hub", o.AccessServerThroughHub, "enable pods access kube-apiserver through yurthub or not")
 	fs.BoolVar(&o.EnableResourceFilter, "enable-resource-filter", o.EnableResourceFilter, "enable to filter response that comes back from reverse proxy")
 	fs.StringSliceVar(&o.DisabledResourceFilters, "disabled-resource-filters", o.DisabledResourceFilters, "disable resource filters to handle response")
@@ -201,11 +467,35 @@ func (o *YurtHubOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.EnableNodePool, "enable-node-pool", o.EnableNodePool, "enable list/watch nodepools resource or not for filters(only used for testing)")
 	fs.DurationVar(&o.MinRequestTimeout, "min-request-timeout", o.MinRequestTimeout, "An optional field indicating at least how long a proxy handler must keep a request open before timing it out. Currently only honored by the local watch request handler(use request parameter timeoutSeconds firstly), which picks a randomized value above this number as the connection timeout, to spread out load.")
 	fs.StringSliceVar(&o.CACertHashes, "discovery-token-ca-cert-hash", o.CACertHashes, "For token-based discovery, validate that the root CA public key matches this hash (format: \"<type>:<value>\").")
+	fs.StringVar(&o.CAFile, "ca-file", o.CAFile, "the CA file of cloud kube-apiservers for bootstrapping hub agent instead of the cluster-info configmap, which is not published by lightweight distributions like k3s and k0s. The CA files written by the agents of k3s, rke2 and k0s on the node are used if cluster-info is not found.")
 	fs.BoolVar(&o.UnsafeSkipCAVerification, "discovery-token-unsafe-skip-ca-verification", o.UnsafeSkipCAVerification, "For token-based discovery, allow joining without --discovery-token-ca-cert-hash pinning.")
 	fs.BoolVar(&o.EnableCoordinator, "enable-coordinator", o.EnableCoordinator, "make yurthub aware of the pool coordinator")
 	fs.StringVar(&o.CoordinatorServerAddr, "coordinator-server-addr", o.CoordinatorServerAddr, "Coordinator APIServer address in format https://host:port")
 	fs.StringVar(&o.CoordinatorStoragePrefix, "coordinator-storage-prefix", o.CoordinatorStoragePrefix, "Pool-Coordinator etcd storage prefix, same as etcd-prefix of Kube-APIServer")
 	fs.StringVar(&o.CoordinatorStorageAddr, "coordinator-storage-addr", o.CoordinatorStorageAddr, "Address of Pool-Coordinator etcd, in the format host:port")
+	fs.IntVar(&o.CoordinatorDelegates, "coordinator-delegates", o.CoordinatorDelegates, "The number of yurthubs in the pool which are elected to delegate node leases to cloud, including the leader yurthub. More delegates can be used for larger pools.")
+	fs.BoolVar(&o.EnableMetricsAPICache, "enable-metrics-api-cache", o.EnableMetricsAPICache, "enable caching the node and pod metrics of metrics.k8s.io, the cached metrics are served when the cloud is unreachable so that kubectl top and the autoscaling on the edge keep working.")
+	fs.StringVar(&o.KubeletResourceMetricsURL, "kubelet-resource-metrics-url", o.KubeletResourceMetricsURL, "the resource metrics endpoint of kubelet on the local node, which is scraped with the node certificate for refreshing the cached metrics of node and its pods when the cloud is unreachable. kubelet authorizes the scrape by yurthub when the cloud is unreachable. Set it to empty for serving the metrics frozen at the time of disconnect.")
+	fs.BoolVar(&o.EnableDNSCache, "enable-dns-cache", o.EnableDNSCache, "enable the node-local dns cache on the dummy interface ip, the queries are forwarded to the cloud dns server, and the cached answers and services are used when it's unreachable. Pods use the cache if kubelet is started with --cluster-dns=<dummy-if-ip>.")
+	fs.StringVar(&o.DNSCacheUpstream, "dns-cache-upstream", o.DNSCacheUpstream, "the address(ip or ip:port) of the cloud dns server which the dns cache forwards queries to, the cluster ip of kube-system/kube-dns service is used if not set.")
+	fs.StringVar(&o.DNSClusterDomain, "dns-cluster-domain", o.DNSClusterDomain, "the cluster domain which the dns cache resolves service names in by cached services when the cloud dns server is unreachable.")
+	fs.StringSliceVar(&o.RegistryCredentialSecrets, "registry-credential-secrets", o.RegistryCredentialSecrets, "the docker config secrets(namespace/name) which yurthub serves registry credentials from for the kubelet image credential provider plugin yurt-credential-provider on the unix socket <root-dir>/credential-provider.sock, the credentials are cached on the node so private images can be pulled when the cloud is unreachable.")
+	fs.StringVar(&o.SecretsTmpfsPath, "secrets-tmpfs-path", o.SecretsTmpfsPath, "the dir on tmpfs which secrets are cached in instead of disk cache path, secrets are held only in memory and served to kubelet from it when the cloud is unreachable, they are lost after the node reboots. The pages of tmpfs can be swapped to disk, so swap should be disabled on the node. Secrets are cached on disk if not set.")
+	fs.IntVar(&o.ImageGCHighThreshold, "image-gc-high-threshold", o.ImageGCHighThreshold, "the percent of image fs usage after which yurthub collects the unused images instead of kubelet, kubelet image gc should be disabled by --image-gc-high-threshold=100 on the node. The images of pods cached for kubelet are protected while the cloud is unreachable, and a catch-up gc runs once the cloud is reachable again. Kubelet still removes unused images when it evicts pods under disk pressure, and the images of cached pods are not protected in this case. Image gc of yurthub is disabled if set to 0.")
+	fs.IntVar(&o.ImageGCLowThreshold, "image-gc-low-threshold", o.ImageGCLowThreshold, "the percent of image fs usage which yurthub collects the unused images to.")
+	fs.StringVar(&o.ImageGCCRISocket, "image-gc-cri-socket", o.ImageGCCRISocket, "the cri socket of container runtime which yurthub collects images from through the cri api, e.g. unix:///run/containerd/containerd.sock for containerd, unix:///var/run/crio/crio.sock for cri-o and unix:///var/run/dockershim.sock for docker, the socket should be mounted into yurthub.")
+	fs.StringVar(&o.ImageFsPath, "image-fs-path", o.ImageFsPath, "the path on the file system which images of container runtime are stored in.")
+	fs.BoolVar(&o.EnableTimeSyncMonitor, "enable-time-sync-monitor", o.EnableTimeSyncMonitor, "enable monitoring the ntp status and the clock offset of node versus kube-apiserver, the status is exposed by metrics and the ClockSynchronized condition of node.")
+	fs.DurationVar(&o.ClockSkewThreshold, "clock-skew-threshold", o.ClockSkewThreshold, "the clock of node is skewed if its offset versus kube-apiserver or ntp exceeds the threshold.")
+	fs.BoolVar(&o.BlockCertOnClockSkew, "block-cert-requests-on-clock-skew", o.BlockCertOnClockSkew, "reject the certificate signing requests of local components while the clock of node is skewed, because the certificates issued are not valid for the node.")
+	fs.BoolVar(&o.EnableEventAggregation, "enable-event-aggregation", o.EnableEventAggregation, "enable aggregating the events which are created by local components when the cloud is unreachable, the events are saved on the node and uploaded in batches when the cloud is reachable again.")
+	fs.StringSliceVar(&o.Interceptors, "interceptors", o.Interceptors, "the interceptors which run in order for the requests of yurthub proxy, an interceptor is the name of an interceptor registered in yurthub, or name=path for the interceptor which runs the executable at path as subprocess. The failures of interceptors are handled by --interceptor-failure-policy.")
+	fs.DurationVar(&o.InterceptorTimeout, "interceptor-timeout", o.InterceptorTimeout, "the timeout for the subprocess of interceptor to decide a request.")
+	fs.StringVar(&o.InterceptorFailurePolicy, "interceptor-failure-policy", o.InterceptorFailurePolicy, "how the failures of interceptors are handled, Ignore lets the requests go through unchanged, Fail rejects the requests, which is used when the interceptors must not be bypassed(like authentication).")
+	fs.StringVar(&o.StandbyServerAddr, "standby-server-addr", o.StandbyServerAddr, "the address of the kube-apiservers of the standby disaster-recovery cluster, yurthub keeps the credentials of the standby cluster ready, and the node can be re-homed to it by POST /v1/switchover from localhost or the openyurt.io/switchover-cluster annotation of node without re-imaging the node.")
+	fs.StringVar(&o.StandbyJoinToken, "standby-join-token", o.StandbyJoinToken, "the Join token for bootstrapping hub agent in the standby cluster.")
+	fs.StringSliceVar(&o.StandbyCACertHashes, "standby-discovery-token-ca-cert-hash", o.StandbyCACertHashes, "For token-based discovery of the standby cluster, validate that the root CA public key matches this hash (format: \"<type>:<value>\").")
+	fs.BoolVar(&o.EnableHardwareDiscovery, "enable-hardware-discovery", o.EnableHardwareDiscovery, "enable detecting hardware(gpu, npu, modem, disk and architecture) of the node and reporting it by node annotation, the report is converted into node labels by yurt-manager.")
 	bindFlags(&o.LeaderElection, fs)
 }
 
@@ -237,7 +527,32 @@ func bindFlags(l *componentbaseconfig.LeaderElectionConfiguration, fs *pflag.Fla
 		"leader election.")
 }
 
-// verifyDummyIP verify the specified ip is valid or not and set the default ip if empty
+// RedirectsByBPF returns whether the connections to kube-apiserver on the node are redirected to
+// hub agent by bpf program.
+func (o *YurtHubOptions) RedirectsByBPF() bool {
+	return o.EnableIptables && o.FirewallMode == FirewallModeEBPF
+}
+
+// RedirectsHTTPSByBPF returns whether the https connections to kube-apiserver on the node are also
+// redirected to hub agent by bpf program.
+func (o *YurtHubOptions) RedirectsHTTPSByBPF() bool {
+	return o.RedirectsByBPF() && o.BPFRedirectHTTPS
+}
+
+// RedirectTargetIP returns the ipv4 address of dummy interface which the connections to kube-apiserver
+// are redirected to in ebpf firewall mode.
+func (o *YurtHubOptions) RedirectTargetIP() string {
+	for _, ip := range o.HubAgentDummyIfIPs {
+		if utilnet.IsIPv4String(ip) {
+			return ip
+		}
+	}
+	return ""
+}
+
+// verifyDummyIP verify the specified ips are valid or not and set the default ip if empty, an ipv4
+// and an ipv6 address can be specified for dual-stack, and the first one is the primary dummy ip.
+
 func (o *YurtHubOptions) verifyDummyIP() error {
 	if o.HubAgentDummyIfIP == "" {
 		if utilnet.IsIPv6String(o.YurtHubHost) {
@@ -245,11 +560,27 @@ func (o *YurtHubOptions) verifyDummyIP() error {
 		} else {
 			o.HubAgentDummyIfIP = DefaultDummyIfIP4
 		}
+		o.HubAgentDummyIfIPs = []string{o.HubAgentDummyIfIP}
 		klog.Infof("dummy ip not set, will use %s as default", o.HubAgentDummyIfIP)
 		return nil
 	}
 
-	dummyIP := o.HubAgentDummyIfIP
+	dummyIPs := strings.Split(o.HubAgentDummyIfIP, ",")
+	for i := range dummyIPs {
+		dummyIPs[i] = strings.TrimSpace(dummyIPs[i])
+		if err := verifyDummyIP(dummyIPs[i]); err != nil {
+			return err
+		}
+	}
+	if len(dummyIPs) > 2 || (len(dummyIPs) == 2 && utilnet.IsIPv6String(dummyIPs[0]) == utilnet.IsIPv6String(dummyIPs[1])) {
+		return fmt.Errorf("dummy ips %s should be an ipv4 and an ipv6 address for dual-stack", o.HubAgentDummyIfIP)
+	}
+	o.HubAgentDummyIfIP = dummyIPs[0]
+	o.HubAgentDummyIfIPs = dummyIPs
+	return nil
+}
+
+func verifyDummyIP(dummyIP string) error {
 	dip := net.ParseIP(dummyIP)
 	if dip == nil {
 		return fmt.Errorf("dummy ip %s is invalid", dummyIP)
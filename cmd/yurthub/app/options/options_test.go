@@ -29,43 +29,63 @@ import (
 	componentbaseconfig "k8s.io/component-base/config"
 
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurthub/interceptor"
+	"github.com/openyurtio/openyurt/pkg/yurthub/resourcemetrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
 
 func TestNewYurtHubOptions(t *testing.T) {
 	expectOptions := YurtHubOptions{
-		YurtHubHost:               "127.0.0.1",
-		YurtHubProxyHost:          "127.0.0.1",
-		YurtHubProxyPort:          util.YurtHubProxyPort,
-		YurtHubPort:               util.YurtHubPort,
-		YurtHubProxySecurePort:    util.YurtHubProxySecurePort,
-		GCFrequency:               120,
-		YurtHubCertOrganizations:  make([]string, 0),
-		LBMode:                    "rr",
-		HeartbeatFailedRetry:      3,
-		HeartbeatHealthyThreshold: 2,
-		HeartbeatTimeoutSeconds:   2,
-		HeartbeatIntervalSeconds:  10,
-		MaxRequestInFlight:        250,
-		RootDir:                   filepath.Join("/var/lib/", projectinfo.GetHubName()),
-		EnableProfiling:           true,
-		EnableDummyIf:             true,
-		EnableIptables:            true,
-		HubAgentDummyIfName:       fmt.Sprintf("%s-dummy0", projectinfo.GetHubName()),
-		DiskCachePath:             disk.CacheBaseDir,
-		AccessServerThroughHub:    true,
-		EnableResourceFilter:      true,
-		DisabledResourceFilters:   make([]string, 0),
-		WorkingMode:               string(util.WorkingModeEdge),
-		KubeletHealthGracePeriod:  time.Second * 40,
-		EnableNodePool:            true,
-		MinRequestTimeout:         time.Second * 1800,
-		CACertHashes:              make([]string, 0),
-		UnsafeSkipCAVerification:  true,
-		CoordinatorServerAddr:     fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorAPIServerSvcName, util.DefaultPoolCoordinatorAPIServerSvcPort),
-		CoordinatorStorageAddr:    fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorEtcdSvcName, util.DefaultPoolCoordinatorEtcdSvcPort),
-		CoordinatorStoragePrefix:  "/registry",
+		YurtHubHost:                    "127.0.0.1",
+		YurtHubProxyHost:               "127.0.0.1",
+		YurtHubProxyPort:               util.YurtHubProxyPort,
+		YurtHubPort:                    util.YurtHubPort,
+		YurtHubProxySecurePort:         util.YurtHubProxySecurePort,
+		YurtHubProxyRedirectSecurePort: util.YurtHubProxyRedirectSecurePort,
+		GCFrequency:                    120,
+		YurtHubCertOrganizations:       make([]string, 0),
+		LBMode:                         "rr",
+		HeartbeatFailedRetry:           3,
+		HeartbeatHealthyThreshold:      2,
+		HeartbeatTimeoutSeconds:        2,
+		HeartbeatIntervalSeconds:       10,
+		MaxRequestInFlight:             250,
+		RootDir:                        filepath.Join("/var/lib/", projectinfo.GetHubName()),
+		EnableProfiling:                true,
+		EnableDummyIf:                  true,
+		EnableIptables:                 true,
+		FirewallMode:                   FirewallModeAuto,
+		BPFCgroupPath:                  "/sys/fs/cgroup",
+		HubAgentDummyIfName:            fmt.Sprintf("%s-dummy0", projectinfo.GetHubName()),
+		DiskCachePath:                  disk.CacheBaseDir,
+		AccessServerThroughHub:         true,
+		EnableResourceFilter:           true,
+		DisabledResourceFilters:        make([]string, 0),
+		WorkingMode:                    string(util.WorkingModeEdge),
+		KubeletHealthGracePeriod:       time.Second * 40,
+		EnableNodePool:                 true,
+		MinRequestTimeout:              time.Second * 1800,
+		CACertHashes:                   make([]string, 0),
+		ImageGCLowThreshold:            80,
+		EnableTimeSyncMonitor:          true,
+		ClockSkewThreshold:             time.Minute,
+		StorageBackend:                 StorageBackendDisk,
+		CacheCompression:               "none",
+		ImageGCCRISocket:               "unix:///run/containerd/containerd.sock",
+		ImageFsPath:                    "/var/lib/containerd",
+		CacheEncryptionTPMPCRs:         []int{7},
+		UnsafeSkipCAVerification:       true,
+		CoordinatorServerAddr:          fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorAPIServerSvcName, util.DefaultPoolCoordinatorAPIServerSvcPort),
+		CoordinatorStorageAddr:         fmt.Sprintf("https://%s:%s", util.DefaultPoolCoordinatorEtcdSvcName, util.DefaultPoolCoordinatorEtcdSvcPort),
+		CoordinatorStoragePrefix:       "/registry",
+		CoordinatorDelegates:           1,
+		EnableMetricsAPICache:          true,
+		KubeletResourceMetricsURL:      resourcemetrics.DefaultKubeletResourceMetricsURL,
+		EnableEventAggregation:         true,
+		InterceptorTimeout:             time.Second,
+		InterceptorFailurePolicy:       "Ignore",
+		DNSClusterDomain:               "cluster.local",
 		LeaderElection: componentbaseconfig.LeaderElectionConfiguration{
 			LeaderElect:       true,
 			LeaseDuration:     metav1.Duration{Duration: 15 * time.Second},
@@ -180,6 +200,243 @@ func TestValidate(t *testing.T) {
 			},
 			isErr: true,
 		},
+		"invalid firewall mode": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             "ipvs",
+				UnsafeSkipCAVerification: true,
+			},
+			isErr: true,
+		},
+		"ebpf firewall mode with ipv6 dummy ip": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeEBPF,
+				EnableDummyIf:            true,
+				UnsafeSkipCAVerification: true,
+				HubAgentDummyIfIP:        "fd00::2:1",
+			},
+			isErr: true,
+		},
+		"dummy ips of the same family": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				HubAgentDummyIfIP:        "169.254.2.1,169.254.2.2",
+			},
+			isErr: true,
+		},
+		"invalid registry credential secret": {
+			options: &YurtHubOptions{
+				NodeName:                  "foo",
+				ServerAddr:                "1.2.3.4:56",
+				JoinToken:                 "xxxx",
+				LBMode:                    "rr",
+				WorkingMode:               "cloud",
+				FirewallMode:              FirewallModeAuto,
+				UnsafeSkipCAVerification:  true,
+				RegistryCredentialSecrets: []string{"regcred"},
+			},
+			isErr: true,
+		},
+		"image gc low threshold above high threshold": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "edge",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				ImageGCHighThreshold:     80,
+				ImageGCLowThreshold:      85,
+			},
+			isErr: true,
+		},
+		"standby cluster without join token": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "edge",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StandbyServerAddr:        "5.6.7.8:56",
+			},
+			isErr: true,
+		},
+		"unsupported storage backend": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           "sqlite",
+			},
+			isErr: true,
+		},
+		"cache encryption without keys": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				CacheEncryptionResources: []string{"secrets"},
+			},
+			isErr: true,
+		},
+		"cache encryption with invalid tpm handle": {
+			options: &YurtHubOptions{
+				NodeName:                  "foo",
+				ServerAddr:                "1.2.3.4:56",
+				JoinToken:                 "xxxx",
+				LBMode:                    "rr",
+				WorkingMode:               "cloud",
+				FirewallMode:              FirewallModeAuto,
+				UnsafeSkipCAVerification:  true,
+				StorageBackend:            StorageBackendDisk,
+				CacheEncryptionResources:  []string{"secrets"},
+				CacheEncryptionTPMHandles: []string{"persistent"},
+			},
+			isErr: true,
+		},
+		"invalid egress gateway bind address": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				EgressGatewayBindAddr:    "localhost:1080",
+			},
+			isErr: true,
+		},
+		"egress gateway without credentials": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				EgressGatewayBindAddr:    "127.0.0.1:1080",
+			},
+			isErr: true,
+		},
+		"unsupported cache compression": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				CacheCompression:         "lz4",
+			},
+			isErr: true,
+		},
+		"bolt storage backend with secrets in tmpfs": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendBolt,
+				SecretsTmpfsPath:         "/run/yurthub/secrets",
+			},
+			isErr: true,
+		},
+		"site view without pool coordinator": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "edge",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				SiteViewBindAddr:         "0.0.0.0:10270",
+				SiteViewCredentialsFile:  "/etc/yurthub/site-view",
+				SiteViewTLSCertFile:      "/etc/yurthub/site-view.crt",
+				SiteViewTLSKeyFile:       "/etc/yurthub/site-view.key",
+			},
+			isErr: true,
+		},
+		"site view with invalid bind address": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "edge",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				EnableCoordinator:        true,
+				CoordinatorDelegates:     1,
+				SiteViewBindAddr:         "localhost:10270",
+				SiteViewCredentialsFile:  "/etc/yurthub/site-view",
+				SiteViewTLSCertFile:      "/etc/yurthub/site-view.crt",
+				SiteViewTLSKeyFile:       "/etc/yurthub/site-view.key",
+			},
+			isErr: true,
+		},
+		"site view without tls cert": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "edge",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+				EnableCoordinator:        true,
+				CoordinatorDelegates:     1,
+				SiteViewBindAddr:         "0.0.0.0:10270",
+				SiteViewCredentialsFile:  "/etc/yurthub/site-view",
+			},
+			isErr: true,
+		},
 		"normal options": {
 			options: &YurtHubOptions{
 				NodeName:                 "foo",
@@ -187,7 +444,10 @@ func TestValidate(t *testing.T) {
 				JoinToken:                "xxxx",
 				LBMode:                   "rr",
 				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
 				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
 			},
 			isErr: false,
 		},
@@ -198,8 +458,26 @@ func TestValidate(t *testing.T) {
 				JoinToken:                "xxxx",
 				LBMode:                   "rr",
 				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
 				UnsafeSkipCAVerification: true,
 				HubAgentDummyIfIP:        "fd00::2:1",
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
+			},
+			isErr: false,
+		},
+		"normal options with dual-stack": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				HubAgentDummyIfIP:        "169.254.2.1,fd00::2:1",
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
 			},
 			isErr: false,
 		},
@@ -210,11 +488,28 @@ func TestValidate(t *testing.T) {
 				JoinToken:                "xxxx",
 				LBMode:                   "rr",
 				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
 				UnsafeSkipCAVerification: true,
 				HubAgentDummyIfIP:        "169.254.2.1",
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: interceptor.FailurePolicyIgnore,
 			},
 			isErr: false,
 		},
+		"invalid interceptor failure policy": {
+			options: &YurtHubOptions{
+				NodeName:                 "foo",
+				ServerAddr:               "1.2.3.4:56",
+				JoinToken:                "xxxx",
+				LBMode:                   "rr",
+				WorkingMode:              "cloud",
+				FirewallMode:             FirewallModeAuto,
+				UnsafeSkipCAVerification: true,
+				StorageBackend:           StorageBackendDisk,
+				InterceptorFailurePolicy: "Retry",
+			},
+			isErr: true,
+		},
 	}
 
 	for k, tc := range testcases {
@@ -34,6 +34,7 @@ import (
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/gc"
+	"github.com/openyurtio/openyurt/pkg/yurthub/hardware"
 	"github.com/openyurtio/openyurt/pkg/yurthub/healthchecker"
 	hubrest "github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/rest"
 	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator"
@@ -144,8 +145,37 @@ func Run(ctx context.Context, cfg *config.YurtHubConfiguration) error {
 	}
 	trace++
 
+	if cfg.EventAggregator != nil {
+		klog.Infof("%d. start uploading events which are recorded when the cloud is unreachable", trace)
+		go cfg.EventAggregator.Run(restConfigMgr.GetRestConfig, ctx.Done())
+		trace++
+	}
+
+	if cfg.WebhookMatcher != nil {
+		klog.Infof("%d. start syncing admission webhooks which are deferred by writes when the cloud is unreachable", trace)
+		go cfg.WebhookMatcher.Run(restConfigMgr.GetRestConfig, ctx.Done())
+		trace++
+	}
+
+	if cfg.NodeProblemRelay != nil {
+		klog.Infof("%d. start relaying node conditions which are reported when the cloud is unreachable", trace)
+		go cfg.NodeProblemRelay.Run(restConfigMgr.GetRestConfig, ctx.Done())
+		trace++
+	}
+
+	if cfg.EnableHardwareDiscovery {
+		klog.Infof("%d. new hardware reporter for node %s", trace, cfg.NodeName)
+		hardware.NewReporter(cfg.NodeName, restConfigMgr, ctx.Done()).Run()
+		trace++
+	}
+
 	klog.Infof("%d. new tenant sa manager", trace)
-	tenantMgr := tenant.New(cfg.TenantNs, cfg.SharedFactory, ctx.Done())
+	tenantMgr := tenant.NewWithOptions(tenant.Options{
+		TenantNss:        cfg.TenantNamespaces,
+		ComponentTenants: cfg.TenantComponents,
+		TokenExpiration:  cfg.TenantTokenExpiration,
+		TokenDir:         cfg.TenantTokenDir,
+	}, cfg.ProxiedClient, cfg.SharedFactory, ctx.Done())
 	trace++
 
 	var coordinatorHealthCheckerGetter func() healthchecker.HealthChecker = getFakeCoordinatorHealthChecker
@@ -171,6 +201,14 @@ func Run(ctx context.Context, cfg *config.YurtHubConfiguration) error {
 	cfg.SharedFactory.Start(ctx.Done())
 	cfg.YurtSharedFactory.Start(ctx.Done())
 
+	if cfg.Interceptors != nil {
+		klog.Infof("%d. start interceptors for the requests of proxy", trace)
+		if err := cfg.Interceptors.Start(ctx.Done()); err != nil {
+			return err
+		}
+		trace++
+	}
+
 	klog.Infof("%d. new reverse proxy handler for remote servers", trace)
 	yurtProxyHandler, err := proxy.NewYurtReverseProxyHandler(
 		cfg,
@@ -191,11 +229,58 @@ func Run(ctx context.Context, cfg *config.YurtHubConfiguration) error {
 		cfg.NetworkMgr.Run(ctx.Done())
 	}
 
+	if cfg.CredentialProvider != nil {
+		klog.Infof("%d. start serving registry credentials for kubelet", trace)
+		go cfg.CredentialProvider.Run(ctx.Done())
+		trace++
+	}
+
+	if cfg.TimeSyncMonitor != nil {
+		klog.Infof("%d. start monitoring time synchronization of node", trace)
+		go cfg.TimeSyncMonitor.Run(restConfigMgr.GetRestConfig, ctx.Done())
+		trace++
+	}
+
+	if cfg.CacheKeyring != nil {
+		klog.Infof("%d. start reloading keys for encrypting cache", trace)
+		go cfg.CacheKeyring.Run(ctx.Done())
+		trace++
+	}
+
+	if cfg.TrafficProfiles != nil {
+		klog.Infof("%d. start selecting traffic profile for shaping requests of components", trace)
+		go cfg.TrafficProfiles.Run(ctx.Done())
+		trace++
+	}
+
+	if cfg.EgressGateway != nil {
+		klog.Infof("%d. start serving egress gateway for pods", trace)
+		go cfg.EgressGateway.Run(ctx.Done())
+		trace++
+	}
+
+	if cfg.ImageGCManager != nil {
+		klog.Infof("%d. start collecting unused images", trace)
+		go cfg.ImageGCManager.Run(cloudHealthChecker.IsHealthy, ctx.Done())
+		trace++
+	}
+
+	if cfg.StandbyManager != nil {
+		klog.Infof("%d. start preparing credentials of %s cluster for switchover", trace, cfg.StandbyManager.Dormant())
+		go cfg.StandbyManager.Run(ctx.Done())
+		trace++
+	}
+
 	klog.Infof("%d. new %s server and begin to serve", trace, projectinfo.GetHubName())
 	if err := server.RunYurtHubServers(cfg, yurtProxyHandler, restConfigMgr, ctx.Done()); err != nil {
 		return fmt.Errorf("could not run hub servers, %w", err)
 	}
-	<-ctx.Done()
+	select {
+	case <-ctx.Done():
+	case <-cfg.StandbyManager.Switched():
+		// the node is re-homed to another cluster, yurthub is restarted for serving it
+		klog.Infof("node is switched over to %s cluster, hub agent exits for restarting", cfg.StandbyManager.Dormant())
+	}
 	klog.Infof("hub agent exited")
 	return nil
 }
@@ -261,6 +346,9 @@ func coordinatorRun(ctx context.Context,
 			klog.Errorf("coordinator failed to create coordinator health checker, %v", err)
 			return
 		}
+		if cfg.PoolElection != nil {
+			cfg.PoolElection.SetBackend(coordinatorClient, coorHealthChecker.IsHealthy)
+		}
 
 		var elector *poolcoordinator.HubElector
 		elector, err = poolcoordinator.NewHubElector(cfg, coordinatorClient, coorHealthChecker, cloudHealthChecker, ctx.Done())
@@ -269,6 +357,9 @@ func coordinatorRun(ctx context.Context,
 			return
 		}
 		go elector.Run(ctx.Done())
+		if cfg.SiteView != nil {
+			cfg.SiteView.SetBackend(coordinatorClient, elector.IsLeader)
+		}
 
 		coor, err := poolcoordinator.NewCoordinator(ctx, cfg, cloudHealthChecker, restConfigMgr, coorCertManager, coorTransportMgr, elector)
 		if err != nil {
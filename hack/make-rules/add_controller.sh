@@ -586,7 +586,7 @@ import (
 // Note !!!
 
 func init() {
-    controllerAddFuncs = append(controllerAddFuncs, ${KIND_ALL_LOWER}.Add)
+    controllerAddFuncs["${KIND_ALL_LOWER}"] = ${KIND_ALL_LOWER}.Add
 }
 
 EOF
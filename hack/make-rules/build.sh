@@ -28,6 +28,7 @@ readonly YURT_ALL_TARGETS=(
     yurt-tunnel-server
     yurt-tunnel-agent
     yurt-manager
+    yurt-credential-provider
 )
 
 # clean old binaries at GOOS and GOARCH
@@ -23,4 +23,8 @@ func SetDefaultsNodePool(obj *NodePool) {
 		obj.Annotations = make(map[string]string)
 	}
 
+	if len(obj.Spec.ConflictPolicy) == 0 {
+		obj.Spec.ConflictPolicy = ConflictPolicyForce
+	}
+
 }
@@ -28,6 +28,19 @@ const (
 	Cloud NodePoolType = "Cloud"
 )
 
+// ConflictPolicy specifies how the labels, annotations and taints of NodePool are applied to a node
+// when the node already has the same keys with different values managed by other systems.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyForce overwrites the conflicting values on the node and takes the ownership of them.
+	ConflictPolicyForce ConflictPolicy = "Force"
+	// ConflictPolicyMerge keeps the conflicting values on the node and only applies the non-conflicting ones.
+	ConflictPolicyMerge ConflictPolicy = "Merge"
+	// ConflictPolicyIgnore keeps the node attributes untouched while there are any conflicts on the node.
+	ConflictPolicyIgnore ConflictPolicy = "Ignore"
+)
+
 // NodePoolSpec defines the desired state of NodePool
 type NodePoolSpec struct {
 	// The type of the NodePool
@@ -39,18 +52,203 @@ type NodePoolSpec struct {
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 
 	// If specified, the Labels will be added to all nodes.
-	// NOTE: existing labels with samy keys on the nodes will be overwritten.
+	// NOTE: existing labels with samy keys on the nodes will be overwritten unless ConflictPolicy is Merge or Ignore.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
 
 	// If specified, the Annotations will be added to all nodes.
-	// NOTE: existing labels with samy keys on the nodes will be overwritten.
+	// NOTE: existing labels with samy keys on the nodes will be overwritten unless ConflictPolicy is Merge or Ignore.
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
 
 	// If specified, the Taints will be added to all nodes.
 	// +optional
 	Taints []v1.Taint `json:"taints,omitempty"`
+
+	// ConflictPolicy specifies how Labels, Annotations and Taints are applied to the nodes that already
+	// have the same keys managed by other systems(like cloud provider). Force is the default policy.
+	// The attributes applied by the pool are recorded in the node annotation nodepool.openyurt.io/previous-attributes,
+	// only recorded attributes are updated or removed by the pool later.
+	// +optional
+	// +kubebuilder:validation:Enum=Force;Merge;Ignore
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// PodTolerationSeconds specifies the tolerationSeconds of node not-ready and unreachable tolerations
+	// which are added into the pods on the nodes of the pool, so pods in different pools can have
+	// different eviction patience. 300 seconds is used if not specified. It does not take effect
+	// on the pods that are bound to autonomous nodes, because these pods are never evicted.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	PodTolerationSeconds *int64 `json:"podTolerationSeconds,omitempty"`
+
+	// If specified, the provisioner webhook is called to add or remove nodes of the pool
+	// according to the pending pods and resource utilization of the pool.
+	// +optional
+	Scaling *NodePoolScaling `json:"scaling,omitempty"`
+
+	// If specified, the resource usage of pods on the nodes of the pool is aggregated and
+	// compared with the hard limits, the violations are surfaced on the status of the pool.
+	// +optional
+	Quota *NodePoolQuota `json:"quota,omitempty"`
+
+	// If specified, the pool is decommissioned: the nodes of the pool are cordoned and drained,
+	// and deleted after the pods are migrated out of the pool. The pods bound to the nodes by
+	// apps.openyurt.io/binding annotation or autonomy annotation of node are not evicted.
+	// Decommission can't be cancelled once it's specified.
+	// +optional
+	Decommission *NodePoolDecommission `json:"decommission,omitempty"`
+
+	// MaintenanceWindows are the windows in which the upgrade controllers are allowed to change the
+	// software of the nodes in the pool. The state of windows is published on the nodes by annotation
+	// nodepool.openyurt.io/maintenance-window. Changing at any time if not specified.
+	// +optional
+	MaintenanceWindows []NodePoolMaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// TrafficProfile is the name of traffic profile which yurthub on the nodes of the pool shapes the
+	// requests of local components by, such as lan, metered and satellite. The profile is published
+	// on the nodes by annotation nodepool.openyurt.io/traffic-profile.
+	// +optional
+	TrafficProfile string `json:"trafficProfile,omitempty"`
+
+	// Egress is the policy of the cloud-bound traffic which the pods on the nodes of the pool send
+	// through the egress gateway of yurthub. The policy is published on the nodes by annotation
+	// nodepool.openyurt.io/egress-policy. All traffic through the gateway is allowed if not specified.
+	// +optional
+	Egress *NodePoolEgressPolicy `json:"egress,omitempty"`
+}
+
+// EgressBandwidthClass is the class of bandwidth which the egress gateway on a node is limited to.
+type EgressBandwidthClass string
+
+const (
+	// EgressBandwidthUnlimited doesn't limit the bandwidth of egress gateway
+	EgressBandwidthUnlimited EgressBandwidthClass = "Unlimited"
+	// EgressBandwidthStandard limits the bandwidth of egress gateway to 10Mbit/s
+	EgressBandwidthStandard EgressBandwidthClass = "Standard"
+	// EgressBandwidthLow limits the bandwidth of egress gateway to 1Mbit/s
+	EgressBandwidthLow EgressBandwidthClass = "Low"
+)
+
+// NodePoolEgressPolicy defines the destinations and bandwidth of the egress gateway on the nodes of the pool.
+type NodePoolEgressPolicy struct {
+	// Destinations are the destinations which the pods are allowed to reach through the egress gateway,
+	// a destination is a cidr like 10.0.0.0/8, a domain like registry.example.com or *.example.com,
+	// and an optional port can be appended to a domain like registry.example.com:443.
+	// No destination is allowed if not specified.
+	// +optional
+	Destinations []string `json:"destinations,omitempty"`
+
+	// BandwidthClass is the class of bandwidth which the egress gateway on each node is limited to,
+	// one of Unlimited, Standard and Low. Defaults to Unlimited.
+	// +optional
+	// +kubebuilder:validation:Enum=Unlimited;Standard;Low
+	BandwidthClass EgressBandwidthClass `json:"bandwidthClass,omitempty"`
+}
+
+// NodePoolMaintenanceWindow is a recurring time window in which the software of the nodes in the pool
+// is allowed to be changed.
+type NodePoolMaintenanceWindow struct {
+	// Schedule is the start times of window in cron format with five fields:
+	// minute, hour, day of month, month and day of week, like "0 2 * * 6" for 02:00 every Saturday.
+	Schedule string `json:"schedule"`
+
+	// Duration is the length of window, like 2h or 30m.
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone of schedule, like "Asia/Shanghai". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// NodePoolDecommission defines how the nodes and the pool are removed.
+type NodePoolDecommission struct {
+	// The maximum time in seconds to wait for the pods migrated out of the pool, the nodes are deleted
+	// after timeout even if some pods are not migrated. 0 means waiting until all pods are migrated.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	DrainTimeoutSeconds int32 `json:"drainTimeoutSeconds,omitempty"`
+
+	// DeletePool specifies whether the pool is deleted after all nodes are removed.
+	// +optional
+	DeletePool bool `json:"deletePool,omitempty"`
+}
+
+// DecommissionPhase is the phase of decommissioning NodePool.
+type DecommissionPhase string
+
+const (
+	// DecommissionDraining means the nodes are cordoned and the pods are being evicted.
+	DecommissionDraining DecommissionPhase = "Draining"
+	// DecommissionRemoving means the pods are migrated and the nodes are being deleted.
+	DecommissionRemoving DecommissionPhase = "Removing"
+	// DecommissionCompleted means all nodes of the pool are deleted.
+	DecommissionCompleted DecommissionPhase = "Completed"
+)
+
+// NodePoolQuota defines the aggregate resource quota for the pods on the nodes of NodePool.
+type NodePoolQuota struct {
+	// NamespaceSelector selects the namespaces whose pods are counted by the quota,
+	// pods in all namespaces are counted if not specified. A default LimitRange and
+	// ResourceQuota are created in each of the selected namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Hard is the set of hard limits for each resource, the supported resources are
+	// requests.cpu, requests.memory, limits.cpu, limits.memory, cpu, memory and pods.
+	Hard v1.ResourceList `json:"hard"`
+
+	// DefaultRequests are the default requests of containers in the default LimitRange of
+	// the selected namespaces, 100m cpu and 128Mi memory are used if not specified.
+	// +optional
+	DefaultRequests v1.ResourceList `json:"defaultRequests,omitempty"`
+
+	// DefaultLimits are the default limits of containers in the default LimitRange of
+	// the selected namespaces, 500m cpu and 512Mi memory are used if not specified.
+	// +optional
+	DefaultLimits v1.ResourceList `json:"defaultLimits,omitempty"`
+
+	// NamespaceHard is the set of hard limits of the default ResourceQuota in each of
+	// the selected namespaces, Hard is used if not specified.
+	// +optional
+	NamespaceHard v1.ResourceList `json:"namespaceHard,omitempty"`
+}
+
+// NodePoolScaling defines how the nodes of NodePool are added or removed by the provisioner webhook.
+type NodePoolScaling struct {
+	// Provisioner is the webhook implemented by external systems(like MAAS or vendor APIs) to add or remove nodes.
+	Provisioner ProvisionerWebhook `json:"provisioner"`
+
+	// The minimum number of nodes in the pool.
+	// +optional
+	MinNodes int32 `json:"minNodes,omitempty"`
+
+	// The maximum number of nodes in the pool, 0 means no limit.
+	// +optional
+	MaxNodes int32 `json:"maxNodes,omitempty"`
+
+	// Nodes are removed when both cpu and memory utilization(in percentage) of the pool are below the threshold
+	// and there is no pending pod, 0 means nodes are never removed.
+	// +optional
+	ScaleDownUtilizationThreshold int32 `json:"scaleDownUtilizationThreshold,omitempty"`
+
+	// The minimum interval in seconds between two calls of provisioner webhook, default is 300.
+	// +optional
+	CooldownSeconds int32 `json:"cooldownSeconds,omitempty"`
+}
+
+// ProvisionerWebhook describes the webhook for adding or removing nodes.
+type ProvisionerWebhook struct {
+	// URL of the webhook, the scaling request is sent by POST method in json format.
+	URL string `json:"url"`
+
+	// CABundle is a PEM encoded CA bundle used to validate the webhook server's certificate.
+	// If unspecified, system trust roots are used.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// Timeout in seconds for calling the webhook, default is 10.
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
 }
 
 // NodePoolStatus defines the observed state of NodePool
@@ -66,6 +264,88 @@ type NodePoolStatus struct {
 	// The list of nodes' names in the pool
 	// +optional
 	Nodes []string `json:"nodes,omitempty"`
+
+	// Resource utilization(in percentage) of the pool, which is calculated by the requests of pods
+	// and the allocatable of nodes in the pool.
+	// +optional
+	Utilization map[v1.ResourceName]int32 `json:"utilization,omitempty"`
+
+	// Total number of pending pods which can not be scheduled onto the nodes of the pool.
+	// +optional
+	PendingPodNum int32 `json:"pendingPodNum,omitempty"`
+
+	// The last time the provisioner webhook is called for scaling the pool.
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// QuotaUsed is the resources used by the pods which are counted by the quota of the pool.
+	// +optional
+	QuotaUsed v1.ResourceList `json:"quotaUsed,omitempty"`
+
+	// QuotaViolations are the resources whose usage exceeds the hard limit of the quota.
+	// +optional
+	QuotaViolations []string `json:"quotaViolations,omitempty"`
+
+	// ResourceSummary is the aggregated resources and connectivity state of the nodes in the pool,
+	// it is refreshed periodically.
+	// +optional
+	ResourceSummary *NodePoolResourceSummary `json:"resourceSummary,omitempty"`
+
+	// Decommission is the progress of decommissioning the pool.
+	// +optional
+	Decommission *NodePoolDecommissionStatus `json:"decommission,omitempty"`
+}
+
+// NodePoolDecommissionStatus is the progress of decommissioning NodePool.
+type NodePoolDecommissionStatus struct {
+	// Phase of decommission, Draining, Removing or Completed.
+	// +optional
+	Phase DecommissionPhase `json:"phase,omitempty"`
+
+	// The time when decommission is started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Total number of pods which are not migrated out of the pool yet.
+	// +optional
+	RemainingPodNum int32 `json:"remainingPodNum"`
+
+	// Total number of nodes which are not deleted yet.
+	// +optional
+	RemainingNodeNum int32 `json:"remainingNodeNum"`
+
+	// A human readable message indicating details about the progress.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// NodePoolResourceSummary is the aggregated resources and connectivity state of the nodes in NodePool.
+type NodePoolResourceSummary struct {
+	// Allocatable is the sum of allocatable resources of the nodes in the pool.
+	// +optional
+	Allocatable v1.ResourceList `json:"allocatable,omitempty"`
+
+	// Requested is the sum of resource requests of the pods on the nodes in the pool.
+	// +optional
+	Requested v1.ResourceList `json:"requested,omitempty"`
+
+	// Total number of pods on the nodes in the pool, terminated pods are not counted.
+	// +optional
+	PodNum int32 `json:"podNum"`
+
+	// Total number of nodes which are connected with cloud, the status of these nodes is
+	// reported to cloud by kubelet.
+	// +optional
+	ConnectedNodeNum int32 `json:"connectedNodeNum"`
+
+	// Total number of nodes which are disconnected from cloud, the ready condition of these
+	// nodes is unknown.
+	// +optional
+	DisconnectedNodeNum int32 `json:"disconnectedNodeNum"`
+
+	// The last time the summary is changed.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
 }
 
 // +genclient:nonNamespaced
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CSRApprovalPolicySpec defines which node client and serving CSRs are approved automatically.
+// A CSR is approved if it satisfies all the conditions of any policy, CSRs that are not matched
+// by any policy are left for other approvers.
+type CSRApprovalPolicySpec struct {
+	// SignerNames are the signers of CSRs which are matched by the policy,
+	// kubernetes.io/kube-apiserver-client-kubelet and kubernetes.io/kubelet-serving are supported.
+	SignerNames []string `json:"signerNames"`
+
+	// NodePools are the pools whose nodes are allowed to request certificates, the node must be
+	// registered in one of the pools, so the bootstrap client CSRs of new nodes are not matched.
+	// The nodes of all pools and the nodes not in any pool are allowed if not specified.
+	// +optional
+	NodePools []string `json:"nodePools,omitempty"`
+
+	// NodeNamePatterns are the regular expressions which the node name must match one of.
+	// The node names are not restricted if not specified.
+	// +optional
+	NodeNamePatterns []string `json:"nodeNamePatterns,omitempty"`
+
+	// Usernames are the users who are allowed to request certificates, like system:bootstrap:<token-id>.
+	// +optional
+	Usernames []string `json:"usernames,omitempty"`
+
+	// Groups are the groups that the requesting user should belong to one of, like system:bootstrappers.
+	// The requesting identity is not restricted if neither Usernames nor Groups are specified, except that
+	// client CSRs are only approved for the node itself then. Bootstrap identities, which request client
+	// certificates for other nodes, must be allowed by Usernames or Groups.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// VerifyNodeAddresses specifies whether the subject alternative names of serving CSRs must be the
+	// hostname and addresses of the node object, so nodes can't request certificates for other hosts.
+	// +optional
+	VerifyNodeAddresses bool `json:"verifyNodeAddresses,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,path=csrapprovalpolicies,shortName=cap,categories=all
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +genclient:nonNamespaced
+
+// CSRApprovalPolicy is the Schema for the csrapprovalpolicies API
+type CSRApprovalPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CSRApprovalPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CSRApprovalPolicyList contains a list of CSRApprovalPolicy
+type CSRApprovalPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CSRApprovalPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CSRApprovalPolicy{}, &CSRApprovalPolicyList{})
+}
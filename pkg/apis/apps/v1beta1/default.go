@@ -30,6 +30,10 @@ func SetDefaultsNodePool(obj *NodePool) {
 		obj.Annotations = make(map[string]string)
 	}
 
+	if len(obj.Spec.ConflictPolicy) == 0 {
+		obj.Spec.ConflictPolicy = ConflictPolicyForce
+	}
+
 	obj.Spec.Selector = &metav1.LabelSelector{
 		MatchLabels: map[string]string{apps.LabelCurrentNodePool: obj.Name},
 	}
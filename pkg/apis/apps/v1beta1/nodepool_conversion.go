@@ -31,10 +31,78 @@ func (src *NodePool) ConvertTo(dstRaw conversion.Hub) error {
 	dst.Spec.Selector = src.Spec.Selector
 	dst.Spec.Annotations = src.Spec.Annotations
 	dst.Spec.Taints = src.Spec.Taints
+	dst.Spec.ConflictPolicy = v1alpha1.ConflictPolicy(src.Spec.ConflictPolicy)
+	dst.Spec.PodTolerationSeconds = src.Spec.PodTolerationSeconds
+	dst.Spec.TrafficProfile = src.Spec.TrafficProfile
+	if src.Spec.Egress != nil {
+		dst.Spec.Egress = &v1alpha1.NodePoolEgressPolicy{
+			Destinations:   src.Spec.Egress.Destinations,
+			BandwidthClass: v1alpha1.EgressBandwidthClass(src.Spec.Egress.BandwidthClass),
+		}
+	}
+	if src.Spec.Scaling != nil {
+		dst.Spec.Scaling = &v1alpha1.NodePoolScaling{
+			Provisioner: v1alpha1.ProvisionerWebhook{
+				URL:            src.Spec.Scaling.Provisioner.URL,
+				CABundle:       src.Spec.Scaling.Provisioner.CABundle,
+				TimeoutSeconds: src.Spec.Scaling.Provisioner.TimeoutSeconds,
+			},
+			MinNodes:                      src.Spec.Scaling.MinNodes,
+			MaxNodes:                      src.Spec.Scaling.MaxNodes,
+			ScaleDownUtilizationThreshold: src.Spec.Scaling.ScaleDownUtilizationThreshold,
+			CooldownSeconds:               src.Spec.Scaling.CooldownSeconds,
+		}
+	}
+	if src.Spec.Quota != nil {
+		dst.Spec.Quota = &v1alpha1.NodePoolQuota{
+			NamespaceSelector: src.Spec.Quota.NamespaceSelector,
+			Hard:              src.Spec.Quota.Hard,
+			DefaultRequests:   src.Spec.Quota.DefaultRequests,
+			DefaultLimits:     src.Spec.Quota.DefaultLimits,
+			NamespaceHard:     src.Spec.Quota.NamespaceHard,
+		}
+	}
+	if src.Spec.Decommission != nil {
+		dst.Spec.Decommission = &v1alpha1.NodePoolDecommission{
+			DrainTimeoutSeconds: src.Spec.Decommission.DrainTimeoutSeconds,
+			DeletePool:          src.Spec.Decommission.DeletePool,
+		}
+	}
+	for _, w := range src.Spec.MaintenanceWindows {
+		dst.Spec.MaintenanceWindows = append(dst.Spec.MaintenanceWindows, v1alpha1.NodePoolMaintenanceWindow{
+			Schedule: w.Schedule,
+			Duration: w.Duration,
+			TimeZone: w.TimeZone,
+		})
+	}
 
 	dst.Status.ReadyNodeNum = src.Status.ReadyNodeNum
 	dst.Status.UnreadyNodeNum = src.Status.UnreadyNodeNum
 	dst.Status.Nodes = src.Status.Nodes
+	dst.Status.Utilization = src.Status.Utilization
+	dst.Status.PendingPodNum = src.Status.PendingPodNum
+	dst.Status.LastScaleTime = src.Status.LastScaleTime
+	dst.Status.QuotaUsed = src.Status.QuotaUsed
+	dst.Status.QuotaViolations = src.Status.QuotaViolations
+	if src.Status.ResourceSummary != nil {
+		dst.Status.ResourceSummary = &v1alpha1.NodePoolResourceSummary{
+			Allocatable:         src.Status.ResourceSummary.Allocatable,
+			Requested:           src.Status.ResourceSummary.Requested,
+			PodNum:              src.Status.ResourceSummary.PodNum,
+			ConnectedNodeNum:    src.Status.ResourceSummary.ConnectedNodeNum,
+			DisconnectedNodeNum: src.Status.ResourceSummary.DisconnectedNodeNum,
+			LastUpdateTime:      src.Status.ResourceSummary.LastUpdateTime,
+		}
+	}
+	if src.Status.Decommission != nil {
+		dst.Status.Decommission = &v1alpha1.NodePoolDecommissionStatus{
+			Phase:            v1alpha1.DecommissionPhase(src.Status.Decommission.Phase),
+			StartTime:        src.Status.Decommission.StartTime,
+			RemainingPodNum:  src.Status.Decommission.RemainingPodNum,
+			RemainingNodeNum: src.Status.Decommission.RemainingNodeNum,
+			Message:          src.Status.Decommission.Message,
+		}
+	}
 
 	klog.Infof("convert from v1beta1 to v1alpha1 for %s", dst.Name)
 
@@ -50,10 +118,78 @@ func (dst *NodePool) ConvertFrom(srcRaw conversion.Hub) error {
 	dst.Spec.Selector = src.Spec.Selector
 	dst.Spec.Annotations = src.Spec.Annotations
 	dst.Spec.Taints = src.Spec.Taints
+	dst.Spec.ConflictPolicy = ConflictPolicy(src.Spec.ConflictPolicy)
+	dst.Spec.PodTolerationSeconds = src.Spec.PodTolerationSeconds
+	dst.Spec.TrafficProfile = src.Spec.TrafficProfile
+	if src.Spec.Egress != nil {
+		dst.Spec.Egress = &NodePoolEgressPolicy{
+			Destinations:   src.Spec.Egress.Destinations,
+			BandwidthClass: EgressBandwidthClass(src.Spec.Egress.BandwidthClass),
+		}
+	}
+	if src.Spec.Scaling != nil {
+		dst.Spec.Scaling = &NodePoolScaling{
+			Provisioner: ProvisionerWebhook{
+				URL:            src.Spec.Scaling.Provisioner.URL,
+				CABundle:       src.Spec.Scaling.Provisioner.CABundle,
+				TimeoutSeconds: src.Spec.Scaling.Provisioner.TimeoutSeconds,
+			},
+			MinNodes:                      src.Spec.Scaling.MinNodes,
+			MaxNodes:                      src.Spec.Scaling.MaxNodes,
+			ScaleDownUtilizationThreshold: src.Spec.Scaling.ScaleDownUtilizationThreshold,
+			CooldownSeconds:               src.Spec.Scaling.CooldownSeconds,
+		}
+	}
+	if src.Spec.Quota != nil {
+		dst.Spec.Quota = &NodePoolQuota{
+			NamespaceSelector: src.Spec.Quota.NamespaceSelector,
+			Hard:              src.Spec.Quota.Hard,
+			DefaultRequests:   src.Spec.Quota.DefaultRequests,
+			DefaultLimits:     src.Spec.Quota.DefaultLimits,
+			NamespaceHard:     src.Spec.Quota.NamespaceHard,
+		}
+	}
+	if src.Spec.Decommission != nil {
+		dst.Spec.Decommission = &NodePoolDecommission{
+			DrainTimeoutSeconds: src.Spec.Decommission.DrainTimeoutSeconds,
+			DeletePool:          src.Spec.Decommission.DeletePool,
+		}
+	}
+	for _, w := range src.Spec.MaintenanceWindows {
+		dst.Spec.MaintenanceWindows = append(dst.Spec.MaintenanceWindows, NodePoolMaintenanceWindow{
+			Schedule: w.Schedule,
+			Duration: w.Duration,
+			TimeZone: w.TimeZone,
+		})
+	}
 
 	dst.Status.ReadyNodeNum = src.Status.ReadyNodeNum
 	dst.Status.UnreadyNodeNum = src.Status.UnreadyNodeNum
 	dst.Status.Nodes = src.Status.Nodes
+	dst.Status.Utilization = src.Status.Utilization
+	dst.Status.PendingPodNum = src.Status.PendingPodNum
+	dst.Status.LastScaleTime = src.Status.LastScaleTime
+	dst.Status.QuotaUsed = src.Status.QuotaUsed
+	dst.Status.QuotaViolations = src.Status.QuotaViolations
+	if src.Status.ResourceSummary != nil {
+		dst.Status.ResourceSummary = &NodePoolResourceSummary{
+			Allocatable:         src.Status.ResourceSummary.Allocatable,
+			Requested:           src.Status.ResourceSummary.Requested,
+			PodNum:              src.Status.ResourceSummary.PodNum,
+			ConnectedNodeNum:    src.Status.ResourceSummary.ConnectedNodeNum,
+			DisconnectedNodeNum: src.Status.ResourceSummary.DisconnectedNodeNum,
+			LastUpdateTime:      src.Status.ResourceSummary.LastUpdateTime,
+		}
+	}
+	if src.Status.Decommission != nil {
+		dst.Status.Decommission = &NodePoolDecommissionStatus{
+			Phase:            DecommissionPhase(src.Status.Decommission.Phase),
+			StartTime:        src.Status.Decommission.StartTime,
+			RemainingPodNum:  src.Status.Decommission.RemainingPodNum,
+			RemainingNodeNum: src.Status.Decommission.RemainingNodeNum,
+			Message:          src.Status.Decommission.Message,
+		}
+	}
 
 	klog.Infof("convert from v1alpha1 to v1beta1 for %s", dst.Name)
 	return nil
@@ -0,0 +1,276 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// PoolUpgradePhase is the phase of PoolUpgrade
+type PoolUpgradePhase string
+
+const (
+	// PoolUpgradePrePulling means images are being pre-pulled on the nodes, no node is upgraded until
+	// the pre-pull is finished.
+	PoolUpgradePrePulling PoolUpgradePhase = "PrePulling"
+	// PoolUpgradeRunning means nodes of the pool are being upgraded.
+	PoolUpgradeRunning PoolUpgradePhase = "Running"
+	// PoolUpgradeWaiting means no new node is started because the time is out of maintenance windows.
+	PoolUpgradeWaiting PoolUpgradePhase = "Waiting"
+	// PoolUpgradePaused means no new node is started because the upgrade is paused.
+	PoolUpgradePaused PoolUpgradePhase = "Paused"
+	// PoolUpgradeCompleted means all nodes of the pool are upgraded.
+	PoolUpgradeCompleted PoolUpgradePhase = "Completed"
+	// PoolUpgradeFailed means the upgrade of any node is failed, and no new node is started.
+	PoolUpgradeFailed PoolUpgradePhase = "Failed"
+)
+
+// NodeUpgradePhase is the upgrade phase of a node
+type NodeUpgradePhase string
+
+const (
+	// NodeUpgradeDraining means the node is cordoned and its pods are being evicted.
+	NodeUpgradeDraining NodeUpgradePhase = "Draining"
+	// NodeUpgradeUpgrading means the upgrade job is running on the node.
+	NodeUpgradeUpgrading NodeUpgradePhase = "Upgrading"
+	// NodeUpgradeSucceeded means all upgrade steps are finished on the node.
+	NodeUpgradeSucceeded NodeUpgradePhase = "Succeeded"
+	// NodeUpgradeFailed means the upgrade job is failed on the node.
+	NodeUpgradeFailed NodeUpgradePhase = "Failed"
+)
+
+// NodePrePullPhase is the pre-pull phase of a node
+type NodePrePullPhase string
+
+const (
+	// NodePrePullPulling means the pre-pull job is running on the node.
+	NodePrePullPulling NodePrePullPhase = "Pulling"
+	// NodePrePullReady means all images are pulled and verified on the node.
+	NodePrePullReady NodePrePullPhase = "Ready"
+	// NodePrePullFailed means the pre-pull job is failed on the node, the images are pulled
+	// by the upgrade job of node instead.
+	NodePrePullFailed NodePrePullPhase = "Failed"
+)
+
+// PoolUpgradeStep is a step of node upgrade, like upgrading yurthub, kubelet or running os hooks.
+// The step runs as a privileged container in the host namespaces of node, the root
+// filesystem of node is mounted at /openyurt and the node name is set in env NODE_NAME.
+type PoolUpgradeStep struct {
+	// Name is the name of step, which is used as the container name.
+	Name string `json:"name"`
+
+	// Image is the image of step.
+	Image string `json:"image"`
+
+	// Command is the entrypoint of step, the entrypoint of image is used if not specified.
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Args are the arguments of the entrypoint.
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Signature is the base64 encoded ed25519 signature of the image, command and args of step,
+	// which is made offline by `yurtadm sign` with the fleet signing key. It's required by the
+	// nodes which are provisioned with the fleet public key.
+	// +optional
+	Signature string `json:"signature,omitempty"`
+}
+
+// MaintenanceWindow is a daily time window in which new nodes are allowed to start upgrading.
+type MaintenanceWindow struct {
+	// Start is the start time of window in UTC, in the format of HH:MM.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// Duration is the length of window, like 2h or 30m.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// PoolUpgradePrePull pre-pulls the images on the nodes before upgrading, so the maintenance windows
+// are not spent downloading images over slow links.
+type PoolUpgradePrePull struct {
+	// PullerImage is the node-servant image which pulls and verifies the images on nodes.
+	PullerImage string `json:"pullerImage"`
+
+	// Signature is the base64 encoded ed25519 signature of the puller container, which is made
+	// offline by `yurtadm sign` with the fleet signing key. It's required by the nodes which are
+	// provisioned with the fleet public key.
+	// +optional
+	Signature string `json:"signature,omitempty"`
+
+	// Images are the extra images to pre-pull, the images of steps are always pre-pulled.
+	// The images pinned by digest like image@sha256:... are verified after pulling.
+	// +optional
+	Images []string `json:"images,omitempty"`
+
+	// MaxConcurrency is the maximum number of nodes pulling images at the same time. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrency *int32 `json:"maxConcurrency,omitempty"`
+
+	// PullIntervalSeconds is the pause between pulling two images on a node.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	PullIntervalSeconds int32 `json:"pullIntervalSeconds,omitempty"`
+
+	// TimeoutSeconds is the deadline of pre-pulling on a node, the node is marked as Failed if the
+	// images are not pulled in time, e.g. the node is offline. Defaults to 3600.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+
+	// Windows are the off-peak windows in which new nodes are allowed to start pulling images,
+	// pulling at any time if not specified.
+	// +optional
+	Windows []MaintenanceWindow `json:"windows,omitempty"`
+}
+
+// PoolUpgradeSpec defines the desired state of PoolUpgrade
+type PoolUpgradeSpec struct {
+	// NodePool is the name of pool whose nodes are upgraded.
+	NodePool string `json:"nodePool"`
+
+	// Steps are run on each node in order by a job, a step starts only when the previous step succeeds.
+	// +kubebuilder:validation:MinItems=1
+	Steps []PoolUpgradeStep `json:"steps"`
+
+	// MaxUnavailable is the maximum number of nodes that are upgrading or not ready at the same time,
+	// it can be an absolute number or a percentage of nodes in the pool. Defaults to 1.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// Drain specifies whether the node is cordoned and its pods are evicted before upgrading,
+	// the node is uncordoned after the upgrade.
+	// +optional
+	Drain bool `json:"drain,omitempty"`
+
+	// DrainTimeoutSeconds is the maximum seconds to wait for the pods to be evicted, the upgrade of
+	// node starts after the timeout even if pods are not evicted. Waiting forever if not specified.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	DrainTimeoutSeconds *int32 `json:"drainTimeoutSeconds,omitempty"`
+
+	// MaintenanceWindows are the windows in which new nodes are allowed to start upgrading, nodes which
+	// have been started continue upgrading out of the windows. Upgrading at any time if not specified.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// PrePull pre-pulls the images on all ready nodes of pool before any node is upgraded.
+	// +optional
+	PrePull *PoolUpgradePrePull `json:"prePull,omitempty"`
+
+	// Paused stops starting new nodes, nodes which have been started continue upgrading.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// NodePrePullStatus is the pre-pull progress of a node
+type NodePrePullStatus struct {
+	// NodeName is the name of node.
+	NodeName string `json:"nodeName"`
+
+	// Phase is the pre-pull phase of node.
+	Phase NodePrePullPhase `json:"phase"`
+
+	// Message is the human-readable detail of phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// NodeUpgradeStatus is the upgrade progress of a node
+type NodeUpgradeStatus struct {
+	// NodeName is the name of node.
+	NodeName string `json:"nodeName"`
+
+	// Phase is the upgrade phase of node.
+	Phase NodeUpgradePhase `json:"phase"`
+
+	// StartTime is the time when the node started upgrading.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is the time when the node finished upgrading.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message is the human-readable detail of phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PoolUpgradeStatus defines the observed state of PoolUpgrade
+type PoolUpgradeStatus struct {
+	// Phase is the phase of PoolUpgrade.
+	// +optional
+	Phase PoolUpgradePhase `json:"phase,omitempty"`
+
+	// TotalNodes is the number of nodes in the pool.
+	// +optional
+	TotalNodes int32 `json:"totalNodes,omitempty"`
+
+	// UpgradedNodes is the number of nodes that have been upgraded successfully.
+	// +optional
+	UpgradedNodes int32 `json:"upgradedNodes,omitempty"`
+
+	// Nodes are the upgrade progress of nodes which have started upgrading.
+	// +optional
+	Nodes []NodeUpgradeStatus `json:"nodes,omitempty"`
+
+	// PrePulledNodes is the number of nodes that the images have been pre-pulled on.
+	// +optional
+	PrePulledNodes int32 `json:"prePulledNodes,omitempty"`
+
+	// PrePullNodes are the pre-pull progress of nodes which have started pre-pulling.
+	// +optional
+	PrePullNodes []NodePrePullStatus `json:"prePullNodes,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,path=poolupgrades,shortName=pu,categories=all
+// +kubebuilder:printcolumn:name="NodePool",type="string",JSONPath=".spec.nodePool",description="The pool which is upgraded"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The phase of upgrade"
+// +kubebuilder:printcolumn:name="Upgraded",type="integer",JSONPath=".status.upgradedNodes",description="The number of upgraded nodes"
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.totalNodes",description="The number of nodes in the pool"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +genclient:nonNamespaced
+
+// PoolUpgrade is the Schema for the poolupgrades API
+type PoolUpgrade struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PoolUpgradeSpec   `json:"spec,omitempty"`
+	Status PoolUpgradeStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PoolUpgradeList contains a list of PoolUpgrade
+type PoolUpgradeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PoolUpgrade `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PoolUpgrade{}, &PoolUpgradeList{})
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// UpgradeCampaignPhase is the phase of UpgradeCampaign
+type UpgradeCampaignPhase string
+
+const (
+	// UpgradeCampaignRunning means pools are being upgraded.
+	UpgradeCampaignRunning UpgradeCampaignPhase = "Running"
+	// UpgradeCampaignPaused means no new pool or node is started, because the campaign is paused
+	// or the failure threshold is reached.
+	UpgradeCampaignPaused UpgradeCampaignPhase = "Paused"
+	// UpgradeCampaignCompleted means all pools are upgraded.
+	UpgradeCampaignCompleted UpgradeCampaignPhase = "Completed"
+	// UpgradeCampaignFailed means all pools are finished and the upgrade of any pool is failed.
+	UpgradeCampaignFailed UpgradeCampaignPhase = "Failed"
+)
+
+// RolloutBudget limits how far an upgrade campaign goes through the fleet at the same time.
+type RolloutBudget struct {
+	// MaxConcurrentPools is the maximum number of pools upgrading at the same time. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentPools *int32 `json:"maxConcurrentPools,omitempty"`
+
+	// MaxNodesPerPool is the maximum number of unavailable nodes in a pool, it can be an absolute number
+	// or a percentage of nodes in the pool. It's the maxUnavailable of pool upgrades. Defaults to 1.
+	// +optional
+	MaxNodesPerPool *intstr.IntOrString `json:"maxNodesPerPool,omitempty"`
+
+	// FailureThreshold is the number of failed nodes across all pools which pauses the campaign,
+	// the nodes started before continue upgrading. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// UpgradeCampaignSpec defines the desired state of UpgradeCampaign
+type UpgradeCampaignSpec struct {
+	// NodePools are the pools to upgrade, pools are started in order.
+	// +kubebuilder:validation:MinItems=1
+	NodePools []string `json:"nodePools"`
+
+	// Steps are run on each node in order by a job, the same as the steps of PoolUpgrade.
+	// +kubebuilder:validation:MinItems=1
+	Steps []PoolUpgradeStep `json:"steps"`
+
+	// Drain specifies whether the node is cordoned and its pods are evicted before upgrading.
+	// +optional
+	Drain bool `json:"drain,omitempty"`
+
+	// DrainTimeoutSeconds is the maximum seconds to wait for the pods to be evicted.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	DrainTimeoutSeconds *int32 `json:"drainTimeoutSeconds,omitempty"`
+
+	// MaintenanceWindows are the windows in which new nodes are allowed to start upgrading.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// PrePull pre-pulls the images on the nodes of pool before upgrading the pool.
+	// +optional
+	PrePull *PoolUpgradePrePull `json:"prePull,omitempty"`
+
+	// Budget limits the pools and nodes upgrading at the same time.
+	// +optional
+	Budget RolloutBudget `json:"budget,omitempty"`
+
+	// Paused stops starting new pools and nodes, nodes which have been started continue upgrading.
+	// The campaign paused by reaching the failure threshold is resumed once the spec is changed,
+	// e.g. it's paused and unpaused or the threshold is raised.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// PoolRolloutStatus is the upgrade progress of a pool in campaign
+type PoolRolloutStatus struct {
+	// NodePool is the name of pool.
+	NodePool string `json:"nodePool"`
+
+	// PoolUpgrade is the name of PoolUpgrade which upgrades the pool.
+	PoolUpgrade string `json:"poolUpgrade"`
+
+	// Phase is the phase of PoolUpgrade.
+	// +optional
+	Phase PoolUpgradePhase `json:"phase,omitempty"`
+
+	// TotalNodes is the number of nodes in the pool.
+	// +optional
+	TotalNodes int32 `json:"totalNodes,omitempty"`
+
+	// UpgradedNodes is the number of nodes that have been upgraded successfully.
+	// +optional
+	UpgradedNodes int32 `json:"upgradedNodes,omitempty"`
+
+	// FailedNodes is the number of nodes that failed upgrading.
+	// +optional
+	FailedNodes int32 `json:"failedNodes,omitempty"`
+}
+
+// CampaignNodeCounts are the numbers of nodes in each upgrade state across the pools of campaign
+type CampaignNodeCounts struct {
+	// Total is the number of nodes in the pools of campaign.
+	// +optional
+	Total int32 `json:"total,omitempty"`
+
+	// Pending is the number of nodes which haven't started upgrading.
+	// +optional
+	Pending int32 `json:"pending,omitempty"`
+
+	// Upgrading is the number of nodes which are draining or upgrading.
+	// +optional
+	Upgrading int32 `json:"upgrading,omitempty"`
+
+	// Succeeded is the number of nodes which have been upgraded successfully.
+	// +optional
+	Succeeded int32 `json:"succeeded,omitempty"`
+
+	// Failed is the number of nodes which failed upgrading.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+}
+
+// NodeUpgradeFailure is the failure of a node in campaign
+type NodeUpgradeFailure struct {
+	// NodePool is the pool of node.
+	NodePool string `json:"nodePool"`
+
+	// NodeName is the name of node.
+	NodeName string `json:"nodeName"`
+
+	// Reason is the detail of failure.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// UpgradeCampaignStatus defines the observed state of UpgradeCampaign
+type UpgradeCampaignStatus struct {
+	// Phase is the phase of UpgradeCampaign.
+	// +optional
+	Phase UpgradeCampaignPhase `json:"phase,omitempty"`
+
+	// Message is the human-readable detail of phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// TotalPools is the number of pools in the campaign.
+	// +optional
+	TotalPools int32 `json:"totalPools,omitempty"`
+
+	// UpgradedPools is the number of pools that have been upgraded completely.
+	// +optional
+	UpgradedPools int32 `json:"upgradedPools,omitempty"`
+
+	// FailedNodes is the number of nodes that failed upgrading across all pools.
+	// +optional
+	FailedNodes int32 `json:"failedNodes,omitempty"`
+
+	// FailurePaused is true when the campaign is paused by reaching the failure threshold, it's cleared
+	// once the spec of campaign is changed after the pause.
+	// +optional
+	FailurePaused bool `json:"failurePaused,omitempty"`
+
+	// FailurePausedGeneration is the generation of campaign when it was paused by reaching the failure threshold.
+	// +optional
+	FailurePausedGeneration int64 `json:"failurePausedGeneration,omitempty"`
+
+	// AcknowledgedFailedNodes is the number of failed nodes when the last failure pause was cleared, only
+	// the nodes failed after that count toward the failure threshold.
+	// +optional
+	AcknowledgedFailedNodes int32 `json:"acknowledgedFailedNodes,omitempty"`
+
+	// TargetRevision is the hash of the upgrade steps, the campaigns with the same target revision
+	// upgrade the nodes to the same build.
+	// +optional
+	TargetRevision string `json:"targetRevision,omitempty"`
+
+	// Nodes are the numbers of nodes in each upgrade state.
+	// +optional
+	Nodes CampaignNodeCounts `json:"nodes,omitempty"`
+
+	// StartTime is the time when the first pool started upgrading.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// EstimatedCompletionTime is estimated from the rate of nodes upgraded since the campaign started,
+	// it's not set until a node is upgraded.
+	// +optional
+	EstimatedCompletionTime *metav1.Time `json:"estimatedCompletionTime,omitempty"`
+
+	// Failures are the failed nodes with reasons, at most 50 failures are recorded.
+	// +optional
+	Failures []NodeUpgradeFailure `json:"failures,omitempty"`
+
+	// Pools are the upgrade progress of pools which have started upgrading.
+	// +optional
+	Pools []PoolRolloutStatus `json:"pools,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,path=upgradecampaigns,shortName=uc,categories=all
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The phase of campaign"
+// +kubebuilder:printcolumn:name="Upgraded",type="integer",JSONPath=".status.upgradedPools",description="The number of upgraded pools"
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.totalPools",description="The number of pools in the campaign"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failedNodes",description="The number of failed nodes"
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.targetRevision",description="The target revision of campaign"
+// +kubebuilder:printcolumn:name="ETA",type="date",JSONPath=".status.estimatedCompletionTime",description="The estimated completion time"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +genclient:nonNamespaced
+
+// UpgradeCampaign is the Schema for the upgradecampaigns API, it upgrades the pools of fleet by PoolUpgrades
+// within the rollout budget, so a bad build doesn't reach the entire fleet.
+type UpgradeCampaign struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UpgradeCampaignSpec   `json:"spec,omitempty"`
+	Status UpgradeCampaignStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UpgradeCampaignList contains a list of UpgradeCampaign
+type UpgradeCampaignList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UpgradeCampaign `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UpgradeCampaign{}, &UpgradeCampaignList{})
+}
@@ -42,6 +42,43 @@ const (
 
 	AnnotationPrevAttrs = "nodepool.openyurt.io/previous-attributes"
 
+	// AnnotationAttributeConflicts is added to the nodes whose attributes managed by others conflict with
+	// the attributes of pool by nodepool controller, the value is the comma separated conflicting attributes.
+	AnnotationAttributeConflicts = "nodepool.openyurt.io/attribute-conflicts"
+
+	// AnnotationPodTolerationSeconds is added to the nodes of pool by nodepool controller,
+	// pod binding controller uses it for the toleration seconds of pods on the node.
+	AnnotationPodTolerationSeconds = "nodepool.openyurt.io/pod-toleration-seconds"
+
+	// AnnotationMaintenanceWindow is added to the nodes of pool which has maintenance windows by nodepool
+	// controller, the upgrade controllers don't change the software of the node if it's closed.
+	AnnotationMaintenanceWindow = "nodepool.openyurt.io/maintenance-window"
+	// MaintenanceWindowOpen is the value of AnnotationMaintenanceWindow in a maintenance window
+	MaintenanceWindowOpen = "open"
+	// MaintenanceWindowClosed is the value of AnnotationMaintenanceWindow out of maintenance windows
+	MaintenanceWindowClosed = "closed"
+
+	// AnnotationTrafficProfile is added to the nodes of pool which has traffic profile by nodepool controller,
+	// yurthub shapes the requests of local components on the node by the profile.
+	AnnotationTrafficProfile = "nodepool.openyurt.io/traffic-profile"
+
+	// AnnotationEgressPolicy is added to the nodes of pool which has egress policy by nodepool controller,
+	// the value is the policy in json, and the egress gateway of yurthub on the node enforces it.
+	AnnotationEgressPolicy = "nodepool.openyurt.io/egress-policy"
+
+	// AnnotationP2PImageMirror is added to the NodePool whose nodes fetch images from the peers in the pool,
+	// the value is the address of the p2p image mirror (like Dragonfly or Spegel) served on every node,
+	// such as http://127.0.0.1:5001. The images are fetched from the registry if the mirror fails.
+	AnnotationP2PImageMirror = "nodepool.openyurt.io/p2p-image-mirror"
+
+	// LabelTargetNodePool is added to namespace for specifying the nodepool that the pods in the namespace
+	// are scheduled onto, the nodeSelector and tolerations of the pool are injected into the pods by webhook.
+	LabelTargetNodePool = "apps.openyurt.io/target-nodepool"
+
+	// LabelNodePoolQuota is added to the default LimitRange and ResourceQuota created by the quota of
+	// NodePool in the selected namespaces, the value is the name of the pool.
+	LabelNodePoolQuota = "apps.openyurt.io/nodepool-quota"
+
 	// DefaultCloudNodePoolName defines the name of the default cloud nodepool
 	DefaultCloudNodePoolName = "default-nodepool"
 
@@ -30,4 +30,9 @@ func SetDefaultsGateway(obj *Gateway) {
 			raven.LabelCurrentGateway: obj.Name,
 		},
 	}
+	for i := range obj.Spec.Peers {
+		if obj.Spec.Peers[i].Topology == "" {
+			obj.Spec.Peers[i].Topology = TopologyHubAndSpoke
+		}
+	}
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -30,8 +31,22 @@ const (
 	EventActiveEndpointElected = "ActiveEndpointElected"
 	// EventActiveEndpointLost is the event indicating the active endpoint is lost.
 	EventActiveEndpointLost = "ActiveEndpointLost"
+	// EventActiveEndpointUnhealthy is the event indicating the active endpoint is unhealthy probed by raven agent.
+	EventActiveEndpointUnhealthy = "ActiveEndpointUnhealthy"
+	// EventSubnetConflict is the event indicating the subnets of the Gateway overlap with the subnets of another Gateway.
+	EventSubnetConflict = "SubnetConflict"
 )
 
+// NodeConditionGatewayHealthy is the condition of node reported by the raven agent on the node
+// which hosts an Endpoint, it's True if both of the tunnels and the forwarding of the Endpoint
+// are probed healthy. The Endpoint is not elected active if the condition is False or the
+// heartbeat of the condition times out, and the Endpoint whose node doesn't have the condition
+// is regarded as healthy.
+const NodeConditionGatewayHealthy corev1.NodeConditionType = "RavenGatewayHealthy"
+
+// EndpointReasonProbeTimeout is the reason of the unhealthy Endpoint whose health is not reported in time.
+const EndpointReasonProbeTimeout = "ProbeTimeout"
+
 var ServiceNamespacedName = types.NamespacedName{
 	Namespace: "kube-system",
 	Name:      "raven-agent-service",
@@ -43,6 +58,25 @@ const (
 	ExposeTypeLoadBalancer = "LoadBalancer"
 )
 
+// TopologyType is the way the traffic between a pair of Gateways flows.
+type TopologyType string
+
+const (
+	// TopologyHubAndSpoke forwards the traffic between a pair of Gateways via the cloud Gateway, it's the default topology.
+	TopologyHubAndSpoke TopologyType = "HubAndSpoke"
+	// TopologyDirect establishes a site-to-site tunnel between a pair of Gateways, the traffic
+	// falls back to HubAndSpoke if the direct tunnel is not available.
+	TopologyDirect TopologyType = "Direct"
+)
+
+// The reasons why the traffic between a pair of Gateways falls back to HubAndSpoke.
+const (
+	PeerReasonHubAndSpokeRequested = "HubAndSpokeRequested"
+	PeerReasonPeerNotFound         = "PeerNotFound"
+	PeerReasonNoActiveEndpoint     = "NoActiveEndpoint"
+	PeerReasonBothUnderNAT         = "BothUnderNAT"
+)
+
 // GatewaySpec defines the desired state of Gateway
 type GatewaySpec struct {
 	// NodeSelector is a label query over nodes that managed by the gateway.
@@ -53,23 +87,66 @@ type GatewaySpec struct {
 	Endpoints []Endpoint `json:"endpoints"`
 	// ExposeType determines how the Gateway is exposed.
 	ExposeType ExposeType `json:"exposeType,omitempty"`
+	// MaxActiveEndpoints is the maximum number of endpoints which are active simultaneously,
+	// the traffic of the Gateway is split among the active endpoints. Defaults to 1.
+	// +optional
+	MaxActiveEndpoints int `json:"maxActiveEndpoints,omitempty"`
+	// Peers specify the topologies between the Gateway and other Gateways, the traffic between
+	// the Gateways which are not listed flows via the cloud Gateway.
+	// +optional
+	Peers []GatewayPeer `json:"peers,omitempty"`
+	// SubnetMappings declare the NAT mappings of the pod subnets of the Gateway, the pods in Subnet
+	// are reachable from other Gateways by the addresses in MappedSubnet, so the Gateways with
+	// overlapping pod subnets are able to communicate with each other.
+	// +optional
+	SubnetMappings []SubnetMapping `json:"subnetMappings,omitempty"`
+}
+
+// SubnetMapping maps the addresses in Subnet to the addresses in MappedSubnet one to one.
+type SubnetMapping struct {
+	// Subnet is the pod subnet of the nodes managed by the Gateway.
+	Subnet string `json:"subnet"`
+	// MappedSubnet is the subnet which is routed to the Gateway, it must have the same size as Subnet.
+	MappedSubnet string `json:"mappedSubnet"`
+}
+
+// GatewayPeer specifies the topology between the Gateway and a peer Gateway.
+type GatewayPeer struct {
+	// Name is the name of the peer Gateway.
+	Name string `json:"name"`
+	// Topology is the way the traffic between the Gateway and the peer Gateway flows, the pair
+	// of Gateways is connected directly if any of them requests Direct and none of them requests
+	// HubAndSpoke. Defaults to HubAndSpoke.
+	// +optional
+	Topology TopologyType `json:"topology,omitempty"`
 }
 
 // Endpoint stores all essential data for establishing the VPN tunnel.
 // TODO add priority field?
 type Endpoint struct {
 	// NodeName is the Node hosting this endpoint.
-	NodeName string            `json:"nodeName"`
-	UnderNAT bool              `json:"underNAT,omitempty"`
-	PublicIP string            `json:"publicIP,omitempty"`
-	Config   map[string]string `json:"config,omitempty"`
+	NodeName string `json:"nodeName"`
+	UnderNAT bool   `json:"underNAT,omitempty"`
+	PublicIP string `json:"publicIP,omitempty"`
+	// PublicIPs are the public addresses of dual-stack endpoint, at most one address for each IP family.
+	// PublicIP is the first one of them if both are set.
+	// +optional
+	PublicIPs []string          `json:"publicIPs,omitempty"`
+	Config    map[string]string `json:"config,omitempty"`
 }
 
 // NodeInfo stores information of node managed by Gateway.
 type NodeInfo struct {
-	NodeName  string   `json:"nodeName"`
-	PrivateIP string   `json:"privateIP"`
-	Subnets   []string `json:"subnets"`
+	NodeName  string `json:"nodeName"`
+	PrivateIP string `json:"privateIP"`
+	// PrivateIPs are the internal addresses of node for each IP family, the first one is PrivateIP.
+	// +optional
+	PrivateIPs []string `json:"privateIPs,omitempty"`
+	Subnets    []string `json:"subnets"`
+	// MappedSubnets are the Subnets translated by the subnet mappings of Gateway, the other Gateways
+	// route to MappedSubnets instead of Subnets if specified.
+	// +optional
+	MappedSubnets []string `json:"mappedSubnets,omitempty"`
 }
 
 // GatewayStatus defines the observed state of Gateway
@@ -77,7 +154,46 @@ type GatewayStatus struct {
 	// Nodes contains all information of nodes managed by Gateway.
 	Nodes []NodeInfo `json:"nodes,omitempty"`
 	// ActiveEndpoint is the reference of the active endpoint.
+	// It is the first one of ActiveEndpoints if multiple endpoints are active.
 	ActiveEndpoint *Endpoint `json:"activeEndpoint,omitempty"`
+	// ActiveEndpoints is the list of the active endpoints.
+	ActiveEndpoints []*Endpoint `json:"activeEndpoints,omitempty"`
+	// Peers contains the effective topologies between the Gateway and its peer Gateways.
+	Peers []PeerStatus `json:"peers,omitempty"`
+	// EndpointsHealth contains the health of the Endpoints reported by raven agents.
+	// +optional
+	EndpointsHealth []EndpointHealth `json:"endpointsHealth,omitempty"`
+	// SubnetConflicts are the names of the Gateways whose routed subnets overlap with the routed subnets of the Gateway.
+	// +optional
+	SubnetConflicts []string `json:"subnetConflicts,omitempty"`
+}
+
+// EndpointHealth is the health of an Endpoint probed by the raven agent on its node.
+type EndpointHealth struct {
+	// NodeName is the Node hosting the Endpoint.
+	NodeName string `json:"nodeName"`
+	// Healthy indicates whether the tunnels and the forwarding of the Endpoint are healthy.
+	Healthy bool `json:"healthy"`
+	// Reason is the reason of the last probe result.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is the details of the last probe result.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastProbeTime is the last time the health is reported.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// PeerStatus is the effective topology between the Gateway and a peer Gateway.
+type PeerStatus struct {
+	// Name is the name of the peer Gateway.
+	Name string `json:"name"`
+	// Topology is the effective topology, it's HubAndSpoke if the direct tunnel is not available.
+	Topology TopologyType `json:"topology"`
+	// Reason is the reason why the traffic falls back to HubAndSpoke.
+	// +optional
+	Reason string `json:"reason,omitempty"`
 }
 
 // +genclient
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TunnelAccessPolicySpec defines the destinations which are reachable through yurt-tunnel and raven.
+// The nodes which are not selected by any policy are not restricted, and the destinations of a node
+// selected by policies must be matched by a rule of one of them.
+type TunnelAccessPolicySpec struct {
+	// NodeSelector is a label query over the edge nodes which the policy applies to.
+	// All nodes are selected if not specified.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// Ingress are the destinations on the edge nodes which the cloud can reach through the tunnel,
+	// they are enforced by both yurt-tunnel-server and yurt-tunnel-agent.
+	// +optional
+	Ingress []AccessRule `json:"ingress,omitempty"`
+
+	// Egress are the destinations in the cloud which the edge nodes can reach through raven.
+	// +optional
+	Egress []AccessRule `json:"egress,omitempty"`
+}
+
+// AccessRule matches a destination if both of its address and port are matched.
+type AccessRule struct {
+	// CIDRs are the destination addresses, only the addresses of the node itself
+	// and the loopback addresses are matched if not specified.
+	// +optional
+	CIDRs []string `json:"cidrs,omitempty"`
+
+	// Ports are the destination ports, all ports are matched if not specified.
+	// +optional
+	Ports []AccessPort `json:"ports,omitempty"`
+}
+
+// AccessPort is a port or a range of ports of a protocol.
+type AccessPort struct {
+	// Protocol is the protocol of the port, TCP or UDP. Defaults to TCP.
+	// +optional
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+
+	// Port is the destination port.
+	Port int32 `json:"port"`
+
+	// EndPort indicates that the range of ports from Port to EndPort are matched.
+	// +optional
+	EndPort int32 `json:"endPort,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,path=tunnelaccesspolicies,shortName=tap,categories=all
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +genclient:nonNamespaced
+
+// TunnelAccessPolicy is the Schema for the tunnelaccesspolicies API
+type TunnelAccessPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TunnelAccessPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TunnelAccessPolicyList contains a list of TunnelAccessPolicy
+type TunnelAccessPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TunnelAccessPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TunnelAccessPolicy{}, &TunnelAccessPolicyList{})
+}
@@ -27,5 +27,6 @@ import (
 // Note !!!
 
 func init() {
-	controllerAddFuncs = append(controllerAddFuncs, gateway.Add, service.Add)
+	controllerAddFuncs["gateway"] = gateway.Add
+	controllerAddFuncs["gatewayservice"] = service.Add
 }
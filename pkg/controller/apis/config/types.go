@@ -23,6 +23,7 @@ import (
 
 	gatewayconfig "github.com/openyurtio/openyurt/pkg/controller/gateway/config"
 	nodepoolconfig "github.com/openyurtio/openyurt/pkg/controller/nodepool/config"
+	nodepoolingressconfig "github.com/openyurtio/openyurt/pkg/controller/nodepoolingress/config"
 )
 
 // YurtControllerManagerConfiguration contains elements describing yurt-controller manager.
@@ -46,6 +47,9 @@ type YurtManagerConfiguration struct {
 
 	// GatewayControllerConfiguration holds configuration for  GatewayController related features.
 	GatewayController gatewayconfig.GatewayControllerConfiguration
+
+	// NodePoolIngressControllerConfiguration holds configuration for NodePoolIngressController related features.
+	NodePoolIngressController nodepoolingressconfig.NodePoolIngressControllerConfiguration
 }
 
 type GenericConfiguration struct {
@@ -54,6 +58,9 @@ type GenericConfiguration struct {
 	HealthProbeAddr         string
 	EnableLeaderElection    bool
 	LeaderElectionNamespace string
-	RestConfigQPS           int
-	RestConfigBurst         int
+	// LeaderElectionGroups maps the name of controller to its leader election group, controllers in a group are
+	// elected by the lease of group independently. Controllers not in any group are elected by the lease of yurt-manager.
+	LeaderElectionGroups map[string]string
+	RestConfigQPS        int
+	RestConfigBurst      int
 }
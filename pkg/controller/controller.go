@@ -17,6 +17,9 @@ limitations under the License.
 package controller
 
 import (
+	"fmt"
+	"sort"
+
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -30,7 +33,8 @@ import (
 
 // Don`t Change this Name !!!!  @kadisi
 // TODO support feature gate @kadisi
-var controllerAddFuncs []func(*config.CompletedConfig, manager.Manager) error
+// The key is the name of controller, which is used for assigning the controller to a leader election group.
+var controllerAddFuncs = make(map[string]func(*config.CompletedConfig, manager.Manager) error)
 
 func init() {
 }
@@ -41,8 +45,25 @@ func init() {
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 
 func SetupWithManager(c *config.CompletedConfig, m manager.Manager) error {
-	for _, f := range controllerAddFuncs {
-		if err := f(c, m); err != nil {
+	groups, err := newLeaderElectionGroups(c, m)
+	if err != nil {
+		return err
+	}
+	for name := range c.ComponentConfig.Generic.LeaderElectionGroups {
+		if _, ok := controllerAddFuncs[name]; !ok {
+			return fmt.Errorf("controller %s in leader election groups is not found", name)
+		}
+	}
+
+	names := make([]string, 0, len(controllerAddFuncs))
+	for name := range controllerAddFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// controllers in a leader election group are added into the group instead of the manager
+		if err := controllerAddFuncs[name](c, groups.managerFor(name)); err != nil {
 			if kindMatchErr, ok := err.(*meta.NoKindMatchError); ok {
 				klog.Infof("CRD %v is not installed, its controller will perform noops!", kindMatchErr.GroupKind)
 				continue
@@ -50,5 +71,5 @@ func SetupWithManager(c *config.CompletedConfig, m manager.Manager) error {
 			return err
 		}
 	}
-	return nil
+	return groups.addToManager(m)
 }
@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csrapproval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
+	utildiscovery "github.com/openyurtio/openyurt/pkg/util/discovery"
+)
+
+var (
+	concurrentReconciles = 3
+	controllerKind       = appsv1beta1.GroupVersion.WithKind("CSRApprovalPolicy")
+	csrKind              = certificatesv1.SchemeGroupVersion.WithKind("CertificateSigningRequest")
+)
+
+const (
+	controllerName = "CSRApproval-controller"
+)
+
+func Format(format string, args ...interface{}) string {
+	s := fmt.Sprintf(format, args...)
+	return fmt.Sprintf("%s: %s", controllerName, s)
+}
+
+// ReconcileCSRApproval approves the node client and serving CSRs which are matched by CSRApprovalPolicy.
+type ReconcileCSRApproval struct {
+	client.Client
+	// kubeClient is used for updating the approval subresource of CSR
+	kubeClient kubernetes.Interface
+}
+
+var _ reconcile.Reconciler = &ReconcileCSRApproval{}
+
+// Add creates a new CSRApproval Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(c *config.CompletedConfig, mgr manager.Manager) error {
+	if !utildiscovery.DiscoverGVK(controllerKind) || !utildiscovery.DiscoverGVK(csrKind) {
+		klog.Errorf(Format("DiscoverGVK error"))
+		return nil
+	}
+
+	return add(mgr, newReconciler(c, mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(_ *config.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileCSRApproval{
+		Client:     utilclient.NewClientFromManager(mgr, controllerName),
+		kubeClient: kubernetes.NewForConfigOrDie(mgr.GetConfig()),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler: r, MaxConcurrentReconciles: concurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to CertificateSigningRequest
+	err = c.Watch(&source.Kind{Type: &certificatesv1.CertificateSigningRequest{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to CSRApprovalPolicy, all pending CSRs are enqueued
+	reader := mgr.GetClient()
+	return c.Watch(&source.Kind{Type: &appsv1beta1.CSRApprovalPolicy{}}, handler.EnqueueRequestsFromMapFunc(func(_ client.Object) []reconcile.Request {
+		var csrList certificatesv1.CertificateSigningRequestList
+		if err := reader.List(context.TODO(), &csrList); err != nil {
+			klog.Errorf(Format("could not list csr, %v", err))
+			return nil
+		}
+		var requests []reconcile.Request
+		for i := range csrList.Items {
+			if isPending(&csrList.Items[i]) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: csrList.Items[i].Name}})
+			}
+		}
+		return requests
+	}))
+}
+
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=csrapprovalpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=certificatesigningrequests/approval,verbs=update
+// +kubebuilder:rbac:groups=certificates.k8s.io,resources=signers,resourceNames=kubernetes.io/kube-apiserver-client-kubelet;kubernetes.io/kubelet-serving,verbs=approve
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile approves the pending node client or serving CSR if it's matched by any CSRApprovalPolicy.
+// CSRs are never denied by the controller, so the CSRs not matched by policies are left for other approvers.
+func (r *ReconcileCSRApproval) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	klog.V(4).Infof(Format("Reconcile CertificateSigningRequest %s", req.Name))
+
+	var csr certificatesv1.CertificateSigningRequest
+	if err := r.Get(ctx, req.NamespacedName, &csr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !isPending(&csr) || !supportedSigners.Has(csr.Spec.SignerName) {
+		return ctrl.Result{}, nil
+	}
+
+	nodeCSR, err := parseNodeCSR(&csr)
+	if err != nil {
+		klog.Infof(Format("csr %s is not a valid node certificate request, %v", csr.Name, err))
+		return ctrl.Result{}, nil
+	}
+
+	var node *corev1.Node
+	var n corev1.Node
+	if err := r.Get(ctx, types.NamespacedName{Name: nodeCSR.nodeName}, &n); err == nil {
+		node = &n
+	} else if !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	var policyList appsv1beta1.CSRApprovalPolicyList
+	if err := r.List(ctx, &policyList); err != nil {
+		return ctrl.Result{}, err
+	}
+	sort.Slice(policyList.Items, func(i, j int) bool {
+		return policyList.Items[i].Name < policyList.Items[j].Name
+	})
+
+	for i := range policyList.Items {
+		policy := &policyList.Items[i]
+		matched, reason := matchPolicy(policy, nodeCSR, node)
+		if !matched {
+			klog.V(4).Infof(Format("csr %s is not matched by policy %s, %s", csr.Name, policy.Name, reason))
+			continue
+		}
+
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:    certificatesv1.CertificateApproved,
+			Status:  corev1.ConditionTrue,
+			Reason:  "AutoApproved",
+			Message: fmt.Sprintf("Auto approved by CSRApprovalPolicy %s", policy.Name),
+		})
+		if _, err := r.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, &csr, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf(Format("could not approve csr %s, %v", csr.Name, err))
+			return ctrl.Result{}, err
+		}
+		klog.Infof(Format("csr %s of node %s is approved by policy %s", csr.Name, nodeCSR.nodeName, policy.Name))
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// isPending returns true if the CSR is neither approved nor denied.
+func isPending(csr *certificatesv1.CertificateSigningRequest) bool {
+	if len(csr.Status.Certificate) != 0 {
+		return false
+	}
+	for _, c := range csr.Status.Conditions {
+		if c.Type == certificatesv1.CertificateApproved || c.Type == certificatesv1.CertificateDenied || c.Type == certificatesv1.CertificateFailed {
+			return false
+		}
+	}
+	return true
+}
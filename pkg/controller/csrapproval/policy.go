@@ -0,0 +1,207 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csrapproval
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+const (
+	nodeUserPrefix = "system:node:"
+	// bootstrappersGroup is the group of bootstrap token users, which request the first client certificates of nodes
+	bootstrappersGroup = "system:bootstrappers"
+)
+
+var (
+	supportedSigners = sets.NewString(certificatesv1.KubeAPIServerClientKubeletSignerName, certificatesv1.KubeletServingSignerName)
+
+	clientAllowedUsages = sets.NewString(
+		string(certificatesv1.UsageDigitalSignature),
+		string(certificatesv1.UsageKeyEncipherment),
+		string(certificatesv1.UsageClientAuth))
+
+	servingAllowedUsages = sets.NewString(
+		string(certificatesv1.UsageDigitalSignature),
+		string(certificatesv1.UsageKeyEncipherment),
+		string(certificatesv1.UsageServerAuth))
+)
+
+// nodeCSR is the node client or serving CSR which is checked against the policies.
+type nodeCSR struct {
+	csr      *certificatesv1.CertificateSigningRequest
+	x509cr   *x509.CertificateRequest
+	nodeName string
+}
+
+// parseNodeCSR parses the CSR and verifies that it's a well-formed node client or serving CSR.
+func parseNodeCSR(csr *certificatesv1.CertificateSigningRequest) (*nodeCSR, error) {
+	if !supportedSigners.Has(csr.Spec.SignerName) {
+		return nil, fmt.Errorf("signer %s is not supported", csr.Spec.SignerName)
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("PEM block type must be CERTIFICATE REQUEST")
+	}
+	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(x509cr.Subject.CommonName, nodeUserPrefix) || len(x509cr.Subject.CommonName) == len(nodeUserPrefix) {
+		return nil, fmt.Errorf("common name %q should be %s<node-name>", x509cr.Subject.CommonName, nodeUserPrefix)
+	}
+	if len(x509cr.Subject.Organization) != 1 || x509cr.Subject.Organization[0] != user.NodesGroup {
+		return nil, fmt.Errorf("organization %v should be [%s]", x509cr.Subject.Organization, user.NodesGroup)
+	}
+	if len(x509cr.EmailAddresses) != 0 || len(x509cr.URIs) != 0 {
+		return nil, errors.New("email and uri subject alternative names are not allowed")
+	}
+
+	usages := sets.NewString()
+	for _, usage := range csr.Spec.Usages {
+		usages.Insert(string(usage))
+	}
+	switch csr.Spec.SignerName {
+	case certificatesv1.KubeAPIServerClientKubeletSignerName:
+		if len(x509cr.DNSNames) != 0 || len(x509cr.IPAddresses) != 0 {
+			return nil, errors.New("subject alternative names are not allowed for client certificate")
+		}
+		if !usages.Has(string(certificatesv1.UsageClientAuth)) || !clientAllowedUsages.IsSuperset(usages) {
+			return nil, fmt.Errorf("usages %v are not allowed for client certificate", usages.List())
+		}
+	case certificatesv1.KubeletServingSignerName:
+		if len(x509cr.DNSNames) == 0 && len(x509cr.IPAddresses) == 0 {
+			return nil, errors.New("at least one dns or ip subject alternative name is required for serving certificate")
+		}
+		if !usages.Has(string(certificatesv1.UsageServerAuth)) || !servingAllowedUsages.IsSuperset(usages) {
+			return nil, fmt.Errorf("usages %v are not allowed for serving certificate", usages.List())
+		}
+	}
+
+	return &nodeCSR{
+		csr:      csr,
+		x509cr:   x509cr,
+		nodeName: strings.TrimPrefix(x509cr.Subject.CommonName, nodeUserPrefix),
+	}, nil
+}
+
+// matchPolicy checks the CSR against the policy, node is nil if the node is not registered.
+// It returns the reason if the CSR is not matched.
+func matchPolicy(policy *appsv1beta1.CSRApprovalPolicy, req *nodeCSR, node *corev1.Node) (bool, string) {
+	spec := &policy.Spec
+	if !sets.NewString(spec.SignerNames...).Has(req.csr.Spec.SignerName) {
+		return false, "signer is not matched"
+	}
+
+	// serving certificates can only be requested by the node itself
+	if req.csr.Spec.SignerName == certificatesv1.KubeletServingSignerName && req.csr.Spec.Username != req.x509cr.Subject.CommonName {
+		return false, "serving certificate is not requested by the node itself"
+	}
+
+	// client certificates are renewed by the node itself, or requested by a bootstrap identity which is
+	// allowed by the policy explicitly, so a node can't get the certificate of another node
+	if req.csr.Spec.SignerName == certificatesv1.KubeAPIServerClientKubeletSignerName && req.csr.Spec.Username != req.x509cr.Subject.CommonName {
+		if !sets.NewString(req.csr.Spec.Groups...).Has(bootstrappersGroup) {
+			return false, fmt.Sprintf("client certificate of node %s is requested by %s, which is neither the node nor a bootstrap identity", req.nodeName, req.csr.Spec.Username)
+		}
+		if len(spec.Usernames) == 0 && len(spec.Groups) == 0 {
+			return false, fmt.Sprintf("bootstrap identity %s is not allowed by usernames or groups of policy", req.csr.Spec.Username)
+		}
+	}
+
+	if len(spec.Usernames) != 0 || len(spec.Groups) != 0 {
+		if !sets.NewString(spec.Usernames...).Has(req.csr.Spec.Username) && !sets.NewString(spec.Groups...).HasAny(req.csr.Spec.Groups...) {
+			return false, fmt.Sprintf("requesting user %s is not allowed", req.csr.Spec.Username)
+		}
+	}
+
+	if len(spec.NodeNamePatterns) != 0 {
+		matched := false
+		for _, pattern := range spec.NodeNamePatterns {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return false, fmt.Sprintf("invalid node name pattern %q, %v", pattern, err)
+			}
+			if re.MatchString(req.nodeName) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, fmt.Sprintf("node name %s is not matched", req.nodeName)
+		}
+	}
+
+	if len(spec.NodePools) != 0 {
+		if node == nil {
+			return false, fmt.Sprintf("node %s is not registered", req.nodeName)
+		}
+		if pool := node.Labels[apps.LabelCurrentNodePool]; !sets.NewString(spec.NodePools...).Has(pool) {
+			return false, fmt.Sprintf("nodepool %q of node %s is not allowed", pool, req.nodeName)
+		}
+	}
+
+	if spec.VerifyNodeAddresses && req.csr.Spec.SignerName == certificatesv1.KubeletServingSignerName {
+		if node == nil {
+			return false, fmt.Sprintf("node %s is not registered", req.nodeName)
+		}
+		if err := verifyNodeAddresses(req.x509cr, node); err != nil {
+			return false, err.Error()
+		}
+	}
+	return true, ""
+}
+
+// verifyNodeAddresses verifies that the subject alternative names are the hostname and addresses of node.
+func verifyNodeAddresses(x509cr *x509.CertificateRequest, node *corev1.Node) error {
+	dnsNames := sets.NewString(node.Name)
+	ips := sets.NewString()
+	for _, addr := range node.Status.Addresses {
+		switch addr.Type {
+		case corev1.NodeHostName, corev1.NodeInternalDNS, corev1.NodeExternalDNS:
+			dnsNames.Insert(addr.Address)
+		case corev1.NodeInternalIP, corev1.NodeExternalIP:
+			ips.Insert(addr.Address)
+		}
+	}
+
+	for _, name := range x509cr.DNSNames {
+		if !dnsNames.Has(name) {
+			return fmt.Errorf("dns name %s is not the hostname of node %s", name, node.Name)
+		}
+	}
+	for _, ip := range x509cr.IPAddresses {
+		if !ips.Has(ip.String()) {
+			return fmt.Errorf("ip %s is not the address of node %s", ip.String(), node.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csrapproval
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/util/cert"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+var (
+	clientUsages  = []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment, certificatesv1.UsageClientAuth}
+	servingUsages = []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment, certificatesv1.UsageServerAuth}
+)
+
+func newCSR(t *testing.T, signer, username string, usages []certificatesv1.KeyUsage, commonName string, dnsNames []string, ips []net.IP) *certificatesv1.CertificateSigningRequest {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatalf("could not create private key, %v", err)
+	}
+	data, err := cert.MakeCSRFromTemplate(privateKey, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: commonName, Organization: []string{user.NodesGroup}},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+	})
+	if err != nil {
+		t.Fatalf("could not make csr, %v", err)
+	}
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    data,
+			SignerName: signer,
+			Usages:     usages,
+			Username:   username,
+			Groups:     []string{"system:bootstrappers", "system:authenticated"},
+		},
+	}
+}
+
+func TestParseNodeCSR(t *testing.T) {
+	tests := []struct {
+		name    string
+		csr     *certificatesv1.CertificateSigningRequest
+		wantErr bool
+	}{
+		{
+			name: "valid client csr",
+			csr:  newCSR(t, certificatesv1.KubeAPIServerClientKubeletSignerName, "system:bootstrap:abcdef", clientUsages, "system:node:edge-1", nil, nil),
+		},
+		{
+			name: "valid serving csr",
+			csr:  newCSR(t, certificatesv1.KubeletServingSignerName, "system:node:edge-1", servingUsages, "system:node:edge-1", []string{"edge-1"}, []net.IP{net.ParseIP("192.168.0.10")}),
+		},
+		{
+			name:    "unsupported signer",
+			csr:     newCSR(t, certificatesv1.KubeAPIServerClientSignerName, "admin", clientUsages, "system:node:edge-1", nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "invalid common name",
+			csr:     newCSR(t, certificatesv1.KubeAPIServerClientKubeletSignerName, "system:bootstrap:abcdef", clientUsages, "admin", nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "client csr with san",
+			csr:     newCSR(t, certificatesv1.KubeAPIServerClientKubeletSignerName, "system:bootstrap:abcdef", clientUsages, "system:node:edge-1", []string{"edge-1"}, nil),
+			wantErr: true,
+		},
+		{
+			name:    "serving csr with client usage",
+			csr:     newCSR(t, certificatesv1.KubeletServingSignerName, "system:node:edge-1", clientUsages, "system:node:edge-1", []string{"edge-1"}, nil),
+			wantErr: true,
+		},
+		{
+			name:    "serving csr without san",
+			csr:     newCSR(t, certificatesv1.KubeletServingSignerName, "system:node:edge-1", servingUsages, "system:node:edge-1", nil, nil),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := parseNodeCSR(tt.csr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expect error %v, but got %v", tt.wantErr, err)
+			}
+			if err == nil && req.nodeName != "edge-1" {
+				t.Errorf("expect node name edge-1, but got %s", req.nodeName)
+			}
+		})
+	}
+}
+
+func TestMatchPolicy(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge-1", Labels: map[string]string{apps.LabelCurrentNodePool: "hangzhou"}},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.168.0.10"},
+			{Type: corev1.NodeHostName, Address: "edge-1.local"},
+		}},
+	}
+	clientCSR := newCSR(t, certificatesv1.KubeAPIServerClientKubeletSignerName, "system:bootstrap:abcdef", clientUsages, "system:node:edge-1", nil, nil)
+	servingCSR := newCSR(t, certificatesv1.KubeletServingSignerName, "system:node:edge-1", servingUsages, "system:node:edge-1", []string{"edge-1.local"}, []net.IP{net.ParseIP("192.168.0.10")})
+	spoofedCSR := newCSR(t, certificatesv1.KubeletServingSignerName, "system:node:edge-1", servingUsages, "system:node:edge-1", []string{"edge-1.local"}, []net.IP{net.ParseIP("10.0.0.1")})
+	otherNodeCSR := newCSR(t, certificatesv1.KubeletServingSignerName, "system:node:edge-2", servingUsages, "system:node:edge-1", []string{"edge-1.local"}, nil)
+	renewalCSR := newCSR(t, certificatesv1.KubeAPIServerClientKubeletSignerName, "system:node:edge-1", clientUsages, "system:node:edge-1", nil, nil)
+	renewalCSR.Spec.Groups = []string{user.NodesGroup, user.AllAuthenticated}
+	otherNodeClientCSR := newCSR(t, certificatesv1.KubeAPIServerClientKubeletSignerName, "system:node:edge-2", clientUsages, "system:node:edge-1", nil, nil)
+	otherNodeClientCSR.Spec.Groups = []string{user.NodesGroup, user.AllAuthenticated}
+
+	tests := []struct {
+		name   string
+		spec   appsv1beta1.CSRApprovalPolicySpec
+		csr    *certificatesv1.CertificateSigningRequest
+		node   *corev1.Node
+		expect bool
+	}{
+		{
+			name:   "bootstrap client csr by group",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}, Groups: []string{"system:bootstrappers"}, NodeNamePatterns: []string{"edge-.*"}},
+			csr:    clientCSR,
+			expect: true,
+		},
+		{
+			name:   "bootstrap client csr without allowed identities",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}},
+			csr:    clientCSR,
+			expect: false,
+		},
+		{
+			name:   "client csr renewed by the node itself",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}},
+			csr:    renewalCSR,
+			expect: true,
+		},
+		{
+			name:   "client csr requested by other node",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}, Groups: []string{user.NodesGroup}},
+			csr:    otherNodeClientCSR,
+			expect: false,
+		},
+		{
+			name:   "signer not matched",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeletServingSignerName}},
+			csr:    clientCSR,
+			expect: false,
+		},
+		{
+			name:   "user not matched",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}, Usernames: []string{"system:bootstrap:123456"}},
+			csr:    clientCSR,
+			expect: false,
+		},
+		{
+			name:   "node name not matched",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}, NodeNamePatterns: []string{"edge"}},
+			csr:    clientCSR,
+			expect: false,
+		},
+		{
+			name:   "bootstrap client csr of unregistered node with pool restriction",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeAPIServerClientKubeletSignerName}, NodePools: []string{"hangzhou"}},
+			csr:    clientCSR,
+			expect: false,
+		},
+		{
+			name:   "serving csr in allowed pool",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeletServingSignerName}, NodePools: []string{"hangzhou"}, VerifyNodeAddresses: true},
+			csr:    servingCSR,
+			node:   node,
+			expect: true,
+		},
+		{
+			name:   "serving csr in other pool",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeletServingSignerName}, NodePools: []string{"beijing"}},
+			csr:    servingCSR,
+			node:   node,
+			expect: false,
+		},
+		{
+			name:   "serving csr with address of other host",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeletServingSignerName}, VerifyNodeAddresses: true},
+			csr:    spoofedCSR,
+			node:   node,
+			expect: false,
+		},
+		{
+			name:   "serving csr requested by other node",
+			spec:   appsv1beta1.CSRApprovalPolicySpec{SignerNames: []string{certificatesv1.KubeletServingSignerName}},
+			csr:    otherNodeCSR,
+			node:   node,
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := parseNodeCSR(tt.csr)
+			if err != nil {
+				t.Fatalf("could not parse csr, %v", err)
+			}
+			policy := &appsv1beta1.CSRApprovalPolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy"}, Spec: tt.spec}
+			if matched, reason := matchPolicy(policy, req, tt.node); matched != tt.expect {
+				t.Errorf("expect matched %v, but got %v(%s)", tt.expect, matched, reason)
+			}
+		})
+	}
+}
@@ -27,10 +27,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	intstrutil "k8s.io/apimachinery/pkg/util/intstr"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	appsinformers "k8s.io/client-go/informers/apps/v1"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	client "k8s.io/client-go/kubernetes"
@@ -43,6 +45,7 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
 	k8sutil "github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater/kubernetes"
 )
 
@@ -58,6 +61,18 @@ const (
 	// In this mode, daemonset will keep updating even if there are not-ready nodes.
 	// For more details, see https://github.com/openyurtio/openyurt/pull/921.
 	AutoUpdate = "auto"
+	// OnRebootUpdate set daemonset to on-reboot update mode.
+	// In this mode, new revisions are staged to nodes, and the old pod on a node is deleted
+	// only when the node is rebooted or cordoned for maintenance.
+	OnRebootUpdate = "onreboot"
+
+	// StagedBootIDAnnotation is the annotation key added to pod to record the boot id of node
+	// when the new revision is staged in on-reboot update mode.
+	StagedBootIDAnnotation = "apps.openyurt.io/staged-boot-id"
+
+	// PodUpgradeStagedReason is the reason of condition PodNeedUpgrade when the new revision is
+	// staged and waiting for the reboot or maintenance of node.
+	PodUpgradeStagedReason = "WaitingForReboot"
 
 	// PodNeedUpgrade indicates whether the pod is able to upgrade.
 	PodNeedUpgrade corev1.PodConditionType = "PodNeedUpgrade"
@@ -68,6 +83,22 @@ const (
 	MaxUnavailableAnnotation = "apps.openyurt.io/max-unavailable"
 	DefaultMaxUnavailable    = "10%"
 
+	// OTAApprovedUntilAnnotation is the annotation key added to node to approve the staged OTA
+	// upgrades of pods on the node until the RFC3339 time. The approved upgrades are applied by
+	// daemonPodUpdater controller, and the upgrades on the nodes without approval are applied
+	// only when they are triggered through yurthub.
+	OTAApprovedUntilAnnotation = "apps.openyurt.io/ota-approved-until"
+	// OTAApprovedByAnnotation is the annotation key added to node to record who approves the OTA upgrades.
+	OTAApprovedByAnnotation = "apps.openyurt.io/ota-approved-by"
+
+	// OTAPayloadSignatureAnnotation is the annotation key added to daemonset to record the signature of
+	// OTA payload, which is the pod template of daemonset signed offline by `yurtadm sign`. yurthub
+	// refuses to apply the upgrade whose payload isn't signed when the fleet public key is provisioned.
+	OTAPayloadSignatureAnnotation = "apps.openyurt.io/ota-payload-signature"
+
+	// EventOTAUpgradeApproved is the event reason of the OTA upgrade applied by approval.
+	EventOTAUpgradeApproved = "OTAUpgradeApproved"
+
 	// BurstReplicas is a rate limiter for booting pods on a lot of pods.
 	// The value of 250 is chosen b/c values that are too high can cause registry DoS issues.
 	BurstReplicas = 250
@@ -81,6 +112,7 @@ var controllerKind = appsv1.SchemeGroupVersion.WithKind("DaemonSet")
 type Controller struct {
 	kubeclientset client.Interface
 	podControl    k8sutil.PodControlInterface
+	recorder      record.EventRecorder
 	// daemonPodUpdater watches daemonset, node and pod resource
 	daemonsetLister    appslisters.DaemonSetLister
 	daemonsetSynced    cache.InformerSynced
@@ -88,24 +120,29 @@ type Controller struct {
 	nodeSynced         cache.InformerSynced
 	podLister          corelisters.PodLister
 	podSynced          cache.InformerSynced
+	nodePoolLister     cache.GenericLister
+	nodePoolSynced     cache.InformerSynced
 	daemonsetWorkqueue workqueue.RateLimitingInterface
 	expectations       k8sutil.ControllerExpectationsInterface
 }
 
 func NewController(kc client.Interface, daemonsetInformer appsinformers.DaemonSetInformer,
-	nodeInformer coreinformers.NodeInformer, podInformer coreinformers.PodInformer) *Controller {
+	nodeInformer coreinformers.NodeInformer, podInformer coreinformers.PodInformer,
+	nodePoolInformer informers.GenericInformer) *Controller {
 
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartStructuredLogging(0)
 	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: kc.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "daemonPodUpdater"})
 
 	ctrl := Controller{
 		kubeclientset: kc,
 		// Use PodControlInterface to delete pods, which is convenient for testing
 		podControl: k8sutil.RealPodControl{
 			KubeClient: kc,
-			Recorder:   eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "daemonPodUpdater"}),
+			Recorder:   recorder,
 		},
+		recorder: recorder,
 
 		daemonsetLister: daemonsetInformer.Lister(),
 		daemonsetSynced: daemonsetInformer.Informer().HasSynced,
@@ -116,6 +153,9 @@ func NewController(kc client.Interface, daemonsetInformer appsinformers.DaemonSe
 		podLister: podInformer.Lister(),
 		podSynced: podInformer.Informer().HasSynced,
 
+		nodePoolLister: nodePoolInformer.Lister(),
+		nodePoolSynced: nodePoolInformer.Informer().HasSynced,
+
 		daemonsetWorkqueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 		expectations:       k8sutil.NewControllerExpectations(),
 	}
@@ -150,9 +190,52 @@ func NewController(kc client.Interface, daemonsetInformer appsinformers.DaemonSe
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		DeleteFunc: ctrl.deletePod,
 	})
+
+	// Watch for the OTA approval, reboot and maintenance of nodes, the staged upgrades
+	// are applied once they are approved or the nodes are rebooted or cordoned.
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: ctrl.updateNode,
+	})
 	return &ctrl
 }
 
+func (c *Controller) updateNode(old, new interface{}) {
+	oldNode := old.(*corev1.Node)
+	newNode := new.(*corev1.Node)
+	if oldNode.Annotations[OTAApprovedUntilAnnotation] != newNode.Annotations[OTAApprovedUntilAnnotation] {
+		if _, _, ok := OTAApprovalOf(newNode, time.Now()); ok {
+			klog.V(4).Infof("OTA upgrades on node %s are approved", newNode.Name)
+			c.enqueueDaemonSetsWithStrategy(OTAUpdate)
+		}
+	}
+	// the state published on node only triggers the sync, the windows are checked by the NodePool
+	if oldNode.Annotations[apps.AnnotationMaintenanceWindow] != newNode.Annotations[apps.AnnotationMaintenanceWindow] &&
+		newNode.Annotations[apps.AnnotationMaintenanceWindow] != apps.MaintenanceWindowClosed {
+		klog.V(4).Infof("maintenance window of node %s is open", newNode.Name)
+		c.enqueueDaemonSetsWithStrategy(AutoUpdate)
+		c.enqueueDaemonSetsWithStrategy(OTAUpdate)
+	}
+	if oldNode.Status.NodeInfo.BootID != newNode.Status.NodeInfo.BootID ||
+		(!oldNode.Spec.Unschedulable && newNode.Spec.Unschedulable) {
+		klog.V(4).Infof("node %s is rebooted or cordoned", newNode.Name)
+		c.enqueueDaemonSetsWithStrategy(OnRebootUpdate)
+	}
+}
+
+// enqueueDaemonSetsWithStrategy enqueues all daemonsets in the update mode of strategy.
+func (c *Controller) enqueueDaemonSetsWithStrategy(strategy string) {
+	dsList, err := c.daemonsetLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't list daemonsets: %v", err))
+		return
+	}
+	for _, ds := range dsList {
+		if checkPrerequisites(ds) && ds.Annotations[UpdateAnnotation] == strategy {
+			c.enqueueDaemonSet(ds)
+		}
+	}
+}
+
 func (c *Controller) enqueueDaemonSet(ds *appsv1.DaemonSet) {
 	key, err := cache.MetaNamespaceKeyFunc(ds)
 	if err != nil {
@@ -217,7 +300,7 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 	defer c.daemonsetWorkqueue.ShutDown()
 
 	// Synchronize the cache before starting to process events
-	if !cache.WaitForCacheSync(stopCh, c.daemonsetSynced, c.nodeSynced, c.podSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.daemonsetSynced, c.nodeSynced, c.podSynced, c.nodePoolSynced) {
 		klog.Error("sync daemonPodUpdater controller timeout")
 	}
 
@@ -298,6 +381,11 @@ func (c *Controller) syncHandler(key string) error {
 		if err := c.autoUpdate(ds); err != nil {
 			return err
 		}
+
+	case OnRebootUpdate:
+		if err := c.onRebootUpdate(ds); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unknown annotation type %v", v)
 	}
@@ -308,6 +396,7 @@ func (c *Controller) syncHandler(key string) error {
 // otaUpdate compare every pod to its owner daemonset to check if pod is updatable
 // If pod is in line with the latest daemonset spec, set pod condition "PodNeedUpgrade" to "false"
 // while not, set pod condition "PodNeedUpgrade" to "true"
+// The staged upgrades on the nodes approved by annotation are applied.
 func (c *Controller) otaUpdate(ds *appsv1.DaemonSet) error {
 	pods, err := GetDaemonsetPods(c.podLister, ds)
 	if err != nil {
@@ -319,7 +408,114 @@ func (c *Controller) otaUpdate(ds *appsv1.DaemonSet) error {
 			return err
 		}
 	}
-	return nil
+
+	podsToUpgrade := c.approvedOTAUpgrades(ds, pods)
+	if len(podsToUpgrade) == 0 {
+		return nil
+	}
+	return c.syncPodsOnNodes(ds, podsToUpgrade)
+}
+
+// onRebootUpdate stages the new revision to every node, and deletes the old pods on the ready
+// nodes which are rebooted or cordoned after the revision is staged. The boot id of node is
+// recorded in pod annotation when the revision is staged, so a reboot is detected by the change
+// of boot id, and the staged nodes are reported by the PodNeedUpgrade condition of pods.
+// The old pod which comes back after the reboot, e.g. restarted by kubelet from the pods cached
+// on the edge node, is deleted once the new pod on the node is running.
+func (c *Controller) onRebootUpdate(ds *appsv1.DaemonSet) error {
+	pods, err := GetDaemonsetPods(c.podLister, ds)
+	if err != nil {
+		return err
+	}
+
+	// the nodes on which the new revision is activated
+	activated := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && IsDaemonsetPodLatest(ds, pod) && pod.Status.Phase == corev1.PodRunning {
+			if nodeName, err := GetTargetNodeName(pod); err == nil {
+				activated[nodeName] = true
+			}
+		}
+	}
+
+	var podsToDelete []string
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if IsDaemonsetPodLatest(ds, pod) {
+			if err := SetPodUpgradeCondition(c.kubeclientset, ds, pod); err != nil {
+				return err
+			}
+			continue
+		}
+
+		nodeName, err := GetTargetNodeName(pod)
+		if err != nil {
+			continue
+		}
+		if activated[nodeName] {
+			klog.Infof("new revision is running on node %s, delete the old pod %s/%s", nodeName, pod.Namespace, pod.Name)
+			podsToDelete = append(podsToDelete, pod.Name)
+			continue
+		}
+		node, err := c.nodeLister.Get(nodeName)
+		if err != nil {
+			klog.Warningf("couldn't get node %s of pod %s/%s, %v", nodeName, pod.Namespace, pod.Name, err)
+			continue
+		}
+
+		stagedBootID, staged := pod.Annotations[StagedBootIDAnnotation]
+		if !staged {
+			if err := SetPodUpgradeStaged(c.kubeclientset, ds, pod, node.Status.NodeInfo.BootID); err != nil {
+				return err
+			}
+			continue
+		}
+		if NodeReady(&node.Status) && (stagedBootID != node.Status.NodeInfo.BootID || node.Spec.Unschedulable) {
+			klog.Infof("node %s is rebooted or cordoned, activate the staged revision of pod %s/%s", nodeName, pod.Namespace, pod.Name)
+			podsToDelete = append(podsToDelete, pod.Name)
+		}
+	}
+
+	if len(podsToDelete) == 0 {
+		return nil
+	}
+	return c.syncPodsOnNodes(ds, podsToDelete)
+}
+
+// approvedOTAUpgrades returns the names of the old pods on the ready nodes whose OTA upgrades are
+// approved, an event is recorded on every pod for the audit of approval.
+func (c *Controller) approvedOTAUpgrades(ds *appsv1.DaemonSet, pods []*corev1.Pod) []string {
+	now := time.Now()
+	var podsToUpgrade []string
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || IsDaemonsetPodLatest(ds, pod) {
+			continue
+		}
+		nodeName, err := GetTargetNodeName(pod)
+		if err != nil {
+			continue
+		}
+		node, err := c.nodeLister.Get(nodeName)
+		if err != nil || !NodeReady(&node.Status) {
+			continue
+		}
+		if closed, err := IsMaintenanceWindowClosed(c.nodePoolLister, node); err != nil || closed {
+			continue
+		}
+		approver, until, ok := OTAApprovalOf(node, now)
+		if !ok {
+			continue
+		}
+
+		c.recorder.Eventf(pod, corev1.EventTypeNormal, EventOTAUpgradeApproved,
+			"OTA upgrade on node %s is approved by %s until %s", nodeName, approver, until.Format(time.RFC3339))
+		klog.Infof("OTA upgrade of pod %s/%s on node %s is approved by %s until %s",
+			pod.Namespace, pod.Name, nodeName, approver, until.Format(time.RFC3339))
+		podsToUpgrade = append(podsToUpgrade, pod.Name)
+	}
+	return podsToUpgrade
 }
 
 // autoUpdate identifies the set of old pods to delete within the constraints imposed by the max-unavailable number.
@@ -350,6 +546,15 @@ func (c *Controller) autoUpdate(ds *appsv1.DaemonSet) error {
 		if !ready {
 			continue
 		}
+		// Ignore the node out of the maintenance windows of its pool
+		closed, err := MaintenanceWindowClosedByName(c.nodeLister, c.nodePoolLister, nodeName)
+		if err != nil {
+			return fmt.Errorf("couldn't check node %q maintenance window, %v", nodeName, err)
+		}
+		if closed {
+			klog.V(4).Infof("maintenance window of node %s is closed, skip updating pods", nodeName)
+			continue
+		}
 
 		newPod, oldPod, ok := findUpdatedPodsOnNode(ds, pods)
 		if !ok {
@@ -22,20 +22,28 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	intstrutil "k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
 	k8sutil "github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater/kubernetes"
 )
 
@@ -126,6 +134,19 @@ func newPod(podName string, nodeName string, label map[string]string, ds *appsv1
 	return pod
 }
 
+func newNodePool(t *testing.T, name string, windows ...appsv1beta1.NodePoolMaintenanceWindow) *unstructured.Unstructured {
+	nodePool := &appsv1beta1.NodePool{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1beta1.GroupVersion.String(), Kind: "NodePool"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       appsv1beta1.NodePoolSpec{MaintenanceWindows: windows},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(nodePool)
+	if err != nil {
+		t.Fatalf("failed to convert NodePool %s, %v", name, err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
 func newNode(name string, ready bool) *corev1.Node {
 	cond := corev1.NodeCondition{
 		Type:   corev1.NodeReady,
@@ -158,9 +179,10 @@ func newNode(name string, ready bool) *corev1.Node {
 type fakeController struct {
 	*Controller
 
-	dsStore   cache.Store
-	nodeStore cache.Store
-	podStore  cache.Store
+	dsStore       cache.Store
+	nodeStore     cache.Store
+	podStore      cache.Store
+	nodePoolStore cache.Store
 }
 
 // ----------------------------------------------------------------------------------------------------------------
@@ -205,17 +227,23 @@ func (f *fakePodControl) DeletePod(ctx context.Context, namespace string, podID
 func newTest(initialObjests ...runtime.Object) (*fakeController, *fakePodControl) {
 	clientset := fake.NewSimpleClientset(initialObjests...)
 	informerFactory := informers.NewSharedInformerFactory(clientset, 0)
+	nodePoolGVR := appsv1beta1.GroupVersion.WithResource("nodepools")
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{nodePoolGVR: "NodePoolList"})
+	nodePoolInformer := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0).ForResource(nodePoolGVR)
 
 	c := NewController(
 		clientset,
 		informerFactory.Apps().V1().DaemonSets(),
 		informerFactory.Core().V1().Nodes(),
 		informerFactory.Core().V1().Pods(),
+		nodePoolInformer,
 	)
 
 	c.daemonsetSynced = alwaysReady
 	c.nodeSynced = alwaysReady
 	c.podSynced = alwaysReady
+	c.nodePoolSynced = alwaysReady
 
 	podControl := newFakePodControl()
 	c.podControl = podControl
@@ -226,6 +254,7 @@ func newTest(initialObjests ...runtime.Object) (*fakeController, *fakePodControl
 		informerFactory.Apps().V1().DaemonSets().Informer().GetStore(),
 		informerFactory.Core().V1().Nodes().Informer().GetStore(),
 		informerFactory.Core().V1().Pods().Informer().GetStore(),
+		nodePoolInformer.Informer().GetStore(),
 	}
 
 	podControl.expectations = c.expectations
@@ -281,6 +310,10 @@ func setOTAUpdateAnnotation(ds *appsv1.DaemonSet) {
 	metav1.SetMetaDataAnnotation(&ds.ObjectMeta, UpdateAnnotation, OTAUpdate)
 }
 
+func setOnRebootUpdateAnnotation(ds *appsv1.DaemonSet) {
+	metav1.SetMetaDataAnnotation(&ds.ObjectMeta, UpdateAnnotation, OnRebootUpdate)
+}
+
 func setMaxUnavailableAnnotation(ds *appsv1.DaemonSet, v string) {
 	metav1.SetMetaDataAnnotation(&ds.ObjectMeta, MaxUnavailableAnnotation, v)
 }
@@ -513,6 +546,205 @@ func TestOTAUpdate(t *testing.T) {
 	assert.Equal(t, false, IsPodUpdatable(newPodGot))
 }
 
+func TestOTAUpdateWithApproval(t *testing.T) {
+	tcases := []struct {
+		name              string
+		approvedUntil     string
+		maintenanceClosed bool
+		wantDelete        bool
+	}{
+		{
+			name:       "no approval",
+			wantDelete: false,
+		},
+		{
+			name:          "approved",
+			approvedUntil: time.Now().Add(time.Hour).Format(time.RFC3339),
+			wantDelete:    true,
+		},
+		{
+			name:          "approval expired",
+			approvedUntil: time.Now().Add(-time.Hour).Format(time.RFC3339),
+			wantDelete:    false,
+		},
+		{
+			name:          "invalid approval",
+			approvedUntil: "tomorrow",
+			wantDelete:    false,
+		},
+		{
+			name:              "approved out of maintenance window",
+			approvedUntil:     time.Now().Add(time.Hour).Format(time.RFC3339),
+			maintenanceClosed: true,
+			wantDelete:        false,
+		},
+	}
+
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			ds := newDaemonSet("ds", "foo/bar:v1")
+			setOTAUpdateAnnotation(ds)
+
+			node := newNode("node", true)
+			if len(tcase.approvedUntil) != 0 {
+				node.Annotations = map[string]string{
+					OTAApprovedUntilAnnotation: tcase.approvedUntil,
+					OTAApprovedByAnnotation:    "admin",
+				}
+			}
+			var nodePool *unstructured.Unstructured
+			if tcase.maintenanceClosed {
+				// the windows of NodePool are honored even if the node claims they are open
+				node.Labels = map[string]string{apps.LabelCurrentNodePool: "hangzhou"}
+				metav1.SetMetaDataAnnotation(&node.ObjectMeta, apps.AnnotationMaintenanceWindow, apps.MaintenanceWindowOpen)
+				nodePool = newNodePool(t, "hangzhou", appsv1beta1.NodePoolMaintenanceWindow{
+					Schedule: "0 0 30 2 *",
+					Duration: metav1.Duration{Duration: time.Hour},
+				})
+			}
+			oldPod := newPod("old-pod", node.Name, simpleDaemonSetLabel, ds)
+			ds.Spec.Template.Spec.Containers[0].Image = "foo/bar:v2"
+
+			fakeCtrl, podControl := newTest(ds, oldPod, node)
+			recorder := record.NewFakeRecorder(10)
+			fakeCtrl.recorder = recorder
+
+			fakeCtrl.podStore.Add(oldPod)
+			podControl.podIDMap[oldPod.Name] = oldPod
+			fakeCtrl.dsStore.Add(ds)
+			fakeCtrl.nodeStore.Add(node)
+			if nodePool != nil {
+				fakeCtrl.nodePoolStore.Add(nodePool)
+			}
+
+			key, err := cache.MetaNamespaceKeyFunc(ds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = fakeCtrl.syncHandler(key); err != nil {
+				t.Fatalf("got syncDaemonsetHandler error %v", err)
+			}
+
+			if tcase.wantDelete {
+				assert.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+				assert.Len(t, recorder.Events, 1)
+			} else {
+				assert.Empty(t, podControl.DeletePodName)
+				assert.Len(t, recorder.Events, 0)
+			}
+		})
+	}
+}
+
+func TestOnRebootUpdate(t *testing.T) {
+	tcases := []struct {
+		name          string
+		stagedBootID  string
+		nodeBootID    string
+		unschedulable bool
+		newPodRunning bool
+		wantStaged    bool
+		wantDelete    bool
+	}{
+		{
+			name:       "stage new revision",
+			nodeBootID: "boot-1",
+			wantStaged: true,
+			wantDelete: false,
+		},
+		{
+			name:         "not rebooted",
+			stagedBootID: "boot-1",
+			nodeBootID:   "boot-1",
+			wantStaged:   true,
+			wantDelete:   false,
+		},
+		{
+			name:         "rebooted",
+			stagedBootID: "boot-1",
+			nodeBootID:   "boot-2",
+			wantStaged:   true,
+			wantDelete:   true,
+		},
+		{
+			name:          "cordoned for maintenance",
+			stagedBootID:  "boot-1",
+			nodeBootID:    "boot-1",
+			unschedulable: true,
+			wantStaged:    true,
+			wantDelete:    true,
+		},
+		{
+			// e.g. the old pod is restarted by kubelet from the pods cached on node after the reboot
+			name:          "old pod comes back after new pod is running",
+			stagedBootID:  "boot-2",
+			nodeBootID:    "boot-2",
+			newPodRunning: true,
+			wantStaged:    true,
+			wantDelete:    true,
+		},
+	}
+
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			ds := newDaemonSet("ds", "foo/bar:v1")
+			setOnDelete(ds)
+			setOnRebootUpdateAnnotation(ds)
+
+			node := newNode("node", true)
+			node.Status.NodeInfo.BootID = tcase.nodeBootID
+			node.Spec.Unschedulable = tcase.unschedulable
+			oldPod := newPod("old-pod", node.Name, simpleDaemonSetLabel, ds)
+			if len(tcase.stagedBootID) != 0 {
+				oldPod.Annotations = map[string]string{StagedBootIDAnnotation: tcase.stagedBootID}
+			}
+			ds.Spec.Template.Spec.Containers[0].Image = "foo/bar:v2"
+
+			objs := []runtime.Object{ds, oldPod, node}
+			var newPodOnNode *corev1.Pod
+			if tcase.newPodRunning {
+				newPodOnNode = newPod("new-pod", node.Name, simpleDaemonSetLabel, ds)
+				newPodOnNode.Status.Phase = corev1.PodRunning
+				objs = append(objs, newPodOnNode)
+			}
+			fakeCtrl, podControl := newTest(objs...)
+			fakeCtrl.podStore.Add(oldPod)
+			podControl.podIDMap[oldPod.Name] = oldPod
+			if newPodOnNode != nil {
+				fakeCtrl.podStore.Add(newPodOnNode)
+				podControl.podIDMap[newPodOnNode.Name] = newPodOnNode
+			}
+			fakeCtrl.dsStore.Add(ds)
+			fakeCtrl.nodeStore.Add(node)
+
+			key, err := cache.MetaNamespaceKeyFunc(ds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = fakeCtrl.syncHandler(key); err != nil {
+				t.Fatalf("got syncDaemonsetHandler error %v", err)
+			}
+
+			if tcase.wantDelete {
+				assert.Equal(t, []string{oldPod.Name}, podControl.DeletePodName)
+			} else {
+				assert.Empty(t, podControl.DeletePodName)
+			}
+
+			oldPodGot, err := fakeCtrl.kubeclientset.CoreV1().Pods(ds.Namespace).Get(context.TODO(), oldPod.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, staged := oldPodGot.Annotations[StagedBootIDAnnotation]
+			assert.Equal(t, tcase.wantStaged, staged)
+			if len(tcase.stagedBootID) == 0 {
+				assert.Equal(t, tcase.nodeBootID, oldPodGot.Annotations[StagedBootIDAnnotation])
+				assert.True(t, IsPodUpdatable(oldPodGot))
+			}
+		})
+	}
+}
+
 func TestController_maxUnavailableCounts(t *testing.T) {
 	tests := []struct {
 		name           string
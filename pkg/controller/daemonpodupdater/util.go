@@ -18,20 +18,30 @@ package daemonpodupdater
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	client "k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
 	k8sutil "github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater/kubernetes"
 	util "github.com/openyurtio/openyurt/pkg/controller/util/node"
+	"github.com/openyurtio/openyurt/pkg/util/maintenancewindow"
 )
 
 // GetDaemonsetPods get all pods belong to the given daemonset
@@ -101,6 +111,41 @@ func NodeReadyByName(nodeList corelisters.NodeLister, nodeName string) (bool, er
 	return NodeReady(&node.Status), nil
 }
 
+// MaintenanceWindowClosedByName check if the maintenance windows of the pool of given node are closed
+func MaintenanceWindowClosedByName(nodeList corelisters.NodeLister, nodePoolLister cache.GenericLister, nodeName string) (bool, error) {
+	node, err := nodeList.Get(nodeName)
+	if err != nil {
+		return false, err
+	}
+
+	return IsMaintenanceWindowClosed(nodePoolLister, node)
+}
+
+// IsMaintenanceWindowClosed check if the maintenance windows of the pool of given node are closed. The
+// windows are read from the NodePool instead of the state annotated on node, which can be changed by
+// the node itself. The node is always in maintenance window if its pool has no maintenance windows.
+func IsMaintenanceWindowClosed(nodePoolLister cache.GenericLister, node *corev1.Node) (bool, error) {
+	poolName := node.Labels[apps.LabelCurrentNodePool]
+	if len(poolName) == 0 {
+		return false, nil
+	}
+	obj, err := nodePoolLister.Get(poolName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return false, fmt.Errorf("unexpected object %T of NodePool %s", obj, poolName)
+	}
+	var nodePool appsv1beta1.NodePool
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &nodePool); err != nil {
+		return false, fmt.Errorf("failed to convert NodePool %s, %w", poolName, err)
+	}
+	return maintenancewindow.Closed(nodePool.Spec.MaintenanceWindows, time.Now()), nil
+}
+
 // NodeReady check if the given node status is ready
 func NodeReady(nodeStatus *corev1.NodeStatus) bool {
 	for _, cond := range nodeStatus.Conditions {
@@ -138,12 +183,43 @@ func SetPodUpgradeCondition(clientset client.Interface, ds *appsv1.DaemonSet, po
 	return nil
 }
 
+// SetPodUpgradeStaged records the boot id of node in pod annotation, and sets pod condition "PodNeedUpgrade"
+// to "true" with reason "WaitingForReboot"
+func SetPodUpgradeStaged(clientset client.Interface, ds *appsv1.DaemonSet, pod *corev1.Pod, bootID string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{StagedBootIDAnnotation: bootID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	updated, err := clientset.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+
+	cond := &corev1.PodCondition{
+		Type:    PodNeedUpgrade,
+		Status:  corev1.ConditionTrue,
+		Reason:  PodUpgradeStagedReason,
+		Message: fmt.Sprintf("new revision of daemonset %s is staged until node is rebooted or cordoned", ds.Name),
+	}
+	if change := util.UpdatePodCondition(&updated.Status, cond); change {
+		if _, err := clientset.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	klog.Infof("stage new revision of pod %q until node is rebooted or cordoned", pod.Name)
+	return nil
+}
+
 // checkPrerequisites checks that daemonset meets two conditions
-// 1. annotation "apps.openyurt.io/update-strategy"="auto" or "ota"
+// 1. annotation "apps.openyurt.io/update-strategy"="auto", "ota" or "onreboot"
 // 2. update strategy is "OnDelete"
 func checkPrerequisites(ds *appsv1.DaemonSet) bool {
 	v, ok := ds.Annotations[UpdateAnnotation]
-	if !ok || (v != AutoUpdate && v != OTAUpdate) {
+	if !ok || (v != AutoUpdate && v != OTAUpdate && v != OnRebootUpdate) {
 		return false
 	}
 	return ds.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType
@@ -246,3 +322,21 @@ func GetPodUpgradeCondition(status corev1.PodStatus) *corev1.PodCondition {
 	_, condition := k8sutil.GetPodCondition(&status, PodNeedUpgrade)
 	return condition
 }
+
+// OTAApprovalOf returns the approver and the expiry of the OTA approval of node, ok is false if
+// the OTA upgrades on node are not approved or the approval has expired.
+func OTAApprovalOf(node *corev1.Node, now time.Time) (approver string, until time.Time, ok bool) {
+	v, exists := node.Annotations[OTAApprovedUntilAnnotation]
+	if !exists {
+		return "", time.Time{}, false
+	}
+	until, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		klog.Warningf("OTA approval %q of node %s is invalid, %v", v, node.Name, err)
+		return "", time.Time{}, false
+	}
+	if !now.Before(until) {
+		return "", until, false
+	}
+	return node.Annotations[OTAApprovedByAnnotation], until, true
+}
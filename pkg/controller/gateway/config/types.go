@@ -16,6 +16,11 @@ limitations under the License.
 
 package config
 
+import "time"
+
 // GatewayControllerConfiguration contains elements describing GatewayController.
 type GatewayControllerConfiguration struct {
+	// EndpointHealthTimeout is the duration after which the endpoint is regarded as unhealthy
+	// if its health is not reported by raven agent, 0 means no timeout.
+	EndpointHealthTimeout time.Duration
 }
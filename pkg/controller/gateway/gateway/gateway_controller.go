@@ -19,6 +19,7 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"net"
 	"reflect"
 	"strings"
 	"time"
@@ -27,6 +28,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -104,6 +106,12 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the peers of Gateway
+	err = c.Watch(&source.Kind{Type: &ravenv1alpha1.Gateway{}}, &EnqueueGatewayForPeer{client: mgr.GetClient()})
+	if err != nil {
+		return err
+	}
+
 	// Watch for changes to Nodes
 	err = c.Watch(&source.Kind{Type: &corev1.Node{}}, &EnqueueGatewayForNode{})
 	if err != nil {
@@ -153,9 +161,11 @@ func (r *ReconcileGateway) Reconcile(ctx context.Context, req reconcile.Request)
 		return reconcile.Result{}, err
 	}
 
-	// 1. try to elect an active endpoint if possible
-	activeEp := r.electActiveEndpoint(nodeList, &gw)
-	r.recordEndpointEvent(ctx, &gw, gw.Status.ActiveEndpoint, activeEp)
+	// 1. try to elect active endpoints if possible, the unhealthy endpoints are replaced
+	healthList := r.endpointsHealth(nodeList, &gw)
+	r.recordUnhealthyEvents(&gw, healthList)
+	activeEps := r.electActiveEndpoints(nodeList, &gw)
+	r.recordEndpointEvents(ctx, &gw, currentActiveEndpoints(&gw), activeEps)
 	if utils.IsGatewayExposeByLB(&gw) {
 		var svc corev1.Service
 		if err := r.Get(ctx, ravenv1alpha1.ServiceNamespacedName, &svc); err != nil {
@@ -166,9 +176,23 @@ func (r *ReconcileGateway) Reconcile(ctx context.Context, req reconcile.Request)
 			klog.V(2).Info("waiting for LB ingress sync")
 			return reconcile.Result{Requeue: true, RequeueAfter: 5 * time.Second}, nil
 		}
-		activeEp.PublicIP = svc.Status.LoadBalancer.Ingress[0].IP
+		// the LB of dual-stack service has an ingress address for each IP family
+		publicIPs := lbIngressIPs(&svc)
+		for _, ep := range activeEps {
+			ep.PublicIP = svc.Status.LoadBalancer.Ingress[0].IP
+			ep.PublicIPs = nil
+			if len(publicIPs) > 1 {
+				ep.PublicIP = publicIPs[0]
+				ep.PublicIPs = publicIPs
+			}
+		}
+	}
+	gw.Status.ActiveEndpoints = activeEps
+	gw.Status.EndpointsHealth = healthList
+	gw.Status.ActiveEndpoint = nil
+	if len(activeEps) != 0 {
+		gw.Status.ActiveEndpoint = activeEps[0]
 	}
-	gw.Status.ActiveEndpoint = activeEp
 
 	// 2. get nodeInfo list of nodes managed by the Gateway
 	var nodes []ravenv1alpha1.NodeInfo
@@ -178,22 +202,67 @@ func (r *ReconcileGateway) Reconcile(ctx context.Context, req reconcile.Request)
 			klog.ErrorS(err, "unable to get podCIDR")
 			return reconcile.Result{}, err
 		}
-		nodes = append(nodes, ravenv1alpha1.NodeInfo{
+		nodeInfo := ravenv1alpha1.NodeInfo{
 			NodeName:  v.Name,
 			PrivateIP: utils.GetNodeInternalIP(v),
 			Subnets:   podCIDRs,
-		})
+		}
+		// the other Gateways route to the mapped subnets if the pod subnets are mapped
+		nodeInfo.MappedSubnets = mapSubnets(&gw, podCIDRs)
+		// the addresses of all IP families are listed for dual-stack node
+		if privateIPs := utils.GetNodeInternalIPs(v); len(privateIPs) > 1 {
+			nodeInfo.PrivateIPs = privateIPs
+		}
+		nodes = append(nodes, nodeInfo)
 	}
 	klog.V(4).Info(Format("managed node info list, nodes: %v", nodes))
 	gw.Status.Nodes = nodes
 
+	// 3. resolve the effective topologies between the Gateway and its peers
+	var gwList ravenv1alpha1.GatewayList
+	if err := r.List(ctx, &gwList); err != nil {
+		err = fmt.Errorf("unable to list gateways: %s", err)
+		return reconcile.Result{}, err
+	}
+	gw.Status.Peers = resolvePeers(&gw, activeEps, gwList.Items)
+	r.recordSubnetConflicts(&gw, conflictGatewayNames(&gw, gwList.Items))
+
 	err = r.Status().Update(ctx, &gw)
 	if err != nil {
 		klog.V(4).ErrorS(err, Format("unable to Update Gateway.status"))
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{}, nil
+	// the healthy endpoints turn unhealthy if their health is not reported in time
+	return reconcile.Result{RequeueAfter: r.healthRequeueAfter(healthList)}, nil
+}
+
+// recordSubnetConflicts records the gateways whose subnets overlap with gw in the status, the event is
+// only recorded when the conflicting gateways change instead of in every reconcile.
+func (r *ReconcileGateway) recordSubnetConflicts(gw *ravenv1alpha1.Gateway, conflicts []string) {
+	if len(conflicts) != 0 && !reflect.DeepEqual(conflicts, gw.Status.SubnetConflicts) {
+		r.recorder.Event(gw.DeepCopy(), corev1.EventTypeWarning, ravenv1alpha1.EventSubnetConflict,
+			fmt.Sprintf("The subnets overlap with the subnets of gateways %s, map the subnets by spec.subnetMappings", strings.Join(conflicts, ",")))
+		klog.V(2).InfoS(Format("subnets overlap with other gateways"), "gateway", gw.Name, "conflicts", conflicts)
+	}
+	gw.Status.SubnetConflicts = conflicts
+}
+
+// recordEndpointEvents records the events for the endpoints which are elected or lost.
+func (r *ReconcileGateway) recordEndpointEvents(ctx context.Context, sourceObj *ravenv1alpha1.Gateway, previous, current []*ravenv1alpha1.Endpoint) {
+	previousEps := make(map[string]*ravenv1alpha1.Endpoint, len(previous))
+	for _, ep := range previous {
+		previousEps[ep.NodeName] = ep
+	}
+	for _, ep := range current {
+		r.recordEndpointEvent(ctx, sourceObj, previousEps[ep.NodeName], ep)
+		delete(previousEps, ep.NodeName)
+	}
+	for _, ep := range previous {
+		if _, ok := previousEps[ep.NodeName]; ok {
+			r.recordEndpointEvent(ctx, sourceObj, ep, nil)
+		}
+	}
 }
 
 func (r *ReconcileGateway) recordEndpointEvent(ctx context.Context, sourceObj *ravenv1alpha1.Gateway, previous, current *ravenv1alpha1.Endpoint) {
@@ -213,53 +282,86 @@ func (r *ReconcileGateway) recordEndpointEvent(ctx context.Context, sourceObj *r
 	}
 }
 
-// electActiveEndpoint trys to elect an active Endpoint.
-// If the current active endpoint remains valid, then we don't change it.
-// Otherwise, try to elect a new one.
-func (r *ReconcileGateway) electActiveEndpoint(nodeList corev1.NodeList, gw *ravenv1alpha1.Gateway) (ep *ravenv1alpha1.Endpoint) {
+// currentActiveEndpoints returns the active endpoints in the status, the status updated by
+// previous versions only has ActiveEndpoint.
+func currentActiveEndpoints(gw *ravenv1alpha1.Gateway) []*ravenv1alpha1.Endpoint {
+	if len(gw.Status.ActiveEndpoints) != 0 {
+		return gw.Status.ActiveEndpoints
+	}
+	if gw.Status.ActiveEndpoint != nil {
+		return []*ravenv1alpha1.Endpoint{gw.Status.ActiveEndpoint}
+	}
+	return nil
+}
+
+// electActiveEndpoints trys to elect at most MaxActiveEndpoints active Endpoints.
+// The endpoints on the nodes which are not ready or reported unhealthy are not valid.
+// If the current active endpoints remain valid, then we don't change them.
+// Otherwise, try to elect new ones in the order of Endpoints. If no endpoint is valid, the current
+// active endpoints which are still in Endpoints are kept until a valid replacement exists, because
+// cross-pool traffic stops without any active endpoint.
+func (r *ReconcileGateway) electActiveEndpoints(nodeList corev1.NodeList, gw *ravenv1alpha1.Gateway) []*ravenv1alpha1.Endpoint {
 	// get all ready nodes referenced by endpoints
 	readyNodes := make(map[string]corev1.Node)
 	for _, v := range nodeList.Items {
-		if isNodeReady(v) {
+		if isNodeReady(v) && r.isEndpointHealthy(&v) {
 			readyNodes[v.Name] = v
 		}
 	}
 	// checkActive check if the given endpoint is able to become the active endpoint.
+	// inList check if ep is in the Endpoint list
+	inList := func(ep *ravenv1alpha1.Endpoint) bool {
+		for _, v := range gw.Spec.Endpoints {
+			if reflect.DeepEqual(v, *ep) {
+				return true
+			}
+		}
+		return false
+	}
 	checkActive := func(ep *ravenv1alpha1.Endpoint) bool {
 		if ep == nil {
 			return false
 		}
 		// check if the node status is ready
 		if _, ok := readyNodes[ep.NodeName]; ok {
-			var inList bool
-			// check if ep is in the Endpoint list
-			for _, v := range gw.Spec.Endpoints {
-				if reflect.DeepEqual(v, *ep) {
-					inList = true
-					break
-				}
-			}
-			return inList
+			return inList(ep)
 		}
 		return false
 	}
 
-	// the current active endpoint is still competent.
-	if checkActive(gw.Status.ActiveEndpoint) {
-		for _, v := range gw.Spec.Endpoints {
-			if v.NodeName == gw.Status.ActiveEndpoint.NodeName {
-				return v.DeepCopy()
-			}
+	maxActive := gw.Spec.MaxActiveEndpoints
+	if maxActive < 1 {
+		maxActive = 1
+	}
+	activeEps := make([]*ravenv1alpha1.Endpoint, 0, maxActive)
+	elected := sets.NewString()
+	elect := func(ep *ravenv1alpha1.Endpoint) {
+		if len(activeEps) < maxActive && !elected.Has(ep.NodeName) && checkActive(ep) {
+			activeEps = append(activeEps, ep.DeepCopy())
+			elected.Insert(ep.NodeName)
 		}
 	}
 
-	// try to elect an active endpoint.
-	for _, v := range gw.Spec.Endpoints {
-		if checkActive(&v) {
-			return v.DeepCopy()
+	// the current active endpoints which are still competent.
+	for _, ep := range currentActiveEndpoints(gw) {
+		elect(ep)
+	}
+	// try to elect active endpoints for the rest.
+	for i := range gw.Spec.Endpoints {
+		elect(&gw.Spec.Endpoints[i])
+	}
+	if len(activeEps) != 0 {
+		return activeEps
+	}
+
+	for _, ep := range currentActiveEndpoints(gw) {
+		if len(activeEps) < maxActive && !elected.Has(ep.NodeName) && inList(ep) {
+			klog.Warning(Format("no endpoint of gateway %s is valid, keep the active endpoint on node %s", gw.Name, ep.NodeName))
+			activeEps = append(activeEps, ep.DeepCopy())
+			elected.Insert(ep.NodeName)
 		}
 	}
-	return
+	return activeEps
 }
 
 // isNodeReady checks if the `node` is `corev1.NodeReady`
@@ -283,6 +385,25 @@ func getNodeCondition(status *corev1.NodeStatus, conditionType corev1.NodeCondit
 	return -1, nil
 }
 
+// lbIngressIPs returns the first ingress ip of each IP family of the LB service.
+func lbIngressIPs(svc *corev1.Service) []string {
+	var hasIPv4, hasIPv6 bool
+	ips := make([]string, 0, 2)
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		ip := net.ParseIP(ingress.IP)
+		switch {
+		case ip == nil:
+		case ip.To4() != nil && !hasIPv4:
+			hasIPv4 = true
+			ips = append(ips, ingress.IP)
+		case ip.To4() == nil && !hasIPv6:
+			hasIPv6 = true
+			ips = append(ips, ingress.IP)
+		}
+	}
+	return ips
+}
+
 // getPodCIDRs returns the pod IP ranges assigned to the node.
 func (r *ReconcileGateway) getPodCIDRs(ctx context.Context, node corev1.Node) ([]string, error) {
 	podCIDRs := make([]string, 0)
@@ -303,5 +424,9 @@ func (r *ReconcileGateway) getPodCIDRs(ctx context.Context, node corev1.Node) ([
 			return podCIDRs, nil
 		}
 	}
+	// the pod IP ranges of dual-stack node are listed in PodCIDRs, and PodCIDR is the first one
+	if len(node.Spec.PodCIDRs) != 0 {
+		return append(podCIDRs, node.Spec.PodCIDRs...), nil
+	}
 	return append(podCIDRs, node.Spec.PodCIDR), nil
 }
@@ -54,8 +54,8 @@ func TestReconcileGateway_electActiveEndpoint(t *testing.T) {
 
 		{
 			// The node hosting active endpoint becomes NotReady, and it is the only node in the Gateway,
-			// then the active endpoint should be removed.
-			name: "lost active endpoint",
+			// then the active endpoint should be kept until a healthy replacement exists.
+			name: "keep lost active endpoint until a replacement exists",
 			nodeList: corev1.NodeList{
 				Items: []corev1.Node{
 					{
@@ -83,7 +83,9 @@ func TestReconcileGateway_electActiveEndpoint(t *testing.T) {
 					},
 				},
 			},
-			expectedEp: nil,
+			expectedEp: &ravenv1alpha1.Endpoint{
+				NodeName: "node-1",
+			},
 		},
 		{
 			// The node hosting active endpoint becomes NotReady, but there are at least one Ready node,
@@ -253,13 +255,95 @@ func TestReconcileGateway_electActiveEndpoint(t *testing.T) {
 	for _, v := range tt {
 		t.Run(v.name, func(t *testing.T) {
 			a := assert.New(t)
-			ep := mockReconciler.electActiveEndpoint(v.nodeList, v.gw)
+			eps := mockReconciler.electActiveEndpoints(v.nodeList, v.gw)
+			var ep *ravenv1alpha1.Endpoint
+			if len(eps) != 0 {
+				ep = eps[0]
+			}
 			a.Equal(v.expectedEp, ep)
 		})
 	}
 
 }
 
+func TestReconcileGateway_electActiveEndpoints(t *testing.T) {
+	mockReconciler := &ReconcileGateway{}
+	nodeList := corev1.NodeList{
+		Items: []corev1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Status: nodeReadyStatus},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Status: nodeNotReadyStatus},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}, Status: nodeReadyStatus},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}, Status: nodeReadyStatus},
+		},
+	}
+	endpoints := []ravenv1alpha1.Endpoint{
+		{NodeName: "node-1"},
+		{NodeName: "node-2"},
+		{NodeName: "node-3"},
+		{NodeName: "node-4"},
+	}
+	var tt = []struct {
+		name        string
+		maxActive   int
+		noneReady   bool
+		activeEps   []*ravenv1alpha1.Endpoint
+		expectedEps []*ravenv1alpha1.Endpoint
+	}{
+		{
+			name:        "elect multiple active endpoints",
+			maxActive:   2,
+			expectedEps: []*ravenv1alpha1.Endpoint{{NodeName: "node-1"}, {NodeName: "node-3"}},
+		},
+		{
+			// node-2 becomes NotReady, so node-1 is elected to take over its traffic.
+			name:        "replace the lost active endpoint",
+			maxActive:   2,
+			activeEps:   []*ravenv1alpha1.Endpoint{{NodeName: "node-4"}, {NodeName: "node-2"}},
+			expectedEps: []*ravenv1alpha1.Endpoint{{NodeName: "node-4"}, {NodeName: "node-1"}},
+		},
+		{
+			name:        "reduce active endpoints",
+			maxActive:   1,
+			activeEps:   []*ravenv1alpha1.Endpoint{{NodeName: "node-4"}, {NodeName: "node-3"}},
+			expectedEps: []*ravenv1alpha1.Endpoint{{NodeName: "node-4"}},
+		},
+		{
+			name:        "keep the active endpoint if no endpoint is ready",
+			maxActive:   1,
+			noneReady:   true,
+			activeEps:   []*ravenv1alpha1.Endpoint{{NodeName: "node-3"}},
+			expectedEps: []*ravenv1alpha1.Endpoint{{NodeName: "node-3"}},
+		},
+		{
+			name:        "not enough ready endpoints",
+			maxActive:   5,
+			expectedEps: []*ravenv1alpha1.Endpoint{{NodeName: "node-1"}, {NodeName: "node-3"}, {NodeName: "node-4"}},
+		},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			a := assert.New(t)
+			gw := &ravenv1alpha1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway-1"},
+				Spec: ravenv1alpha1.GatewaySpec{
+					Endpoints:          endpoints,
+					MaxActiveEndpoints: v.maxActive,
+				},
+				Status: ravenv1alpha1.GatewayStatus{ActiveEndpoints: v.activeEps},
+			}
+			nodes := nodeList
+			if v.noneReady {
+				nodes = corev1.NodeList{}
+				for _, node := range nodeList.Items {
+					node.Status = nodeNotReadyStatus
+					nodes.Items = append(nodes.Items, node)
+				}
+			}
+			a.Equal(v.expectedEps, mockReconciler.electActiveEndpoints(nodes, gw))
+		})
+	}
+}
+
 func TestReconcileGateway_getPodCIDRs(t *testing.T) {
 	mockReconciler := &ReconcileGateway{}
 	var tt = []struct {
@@ -276,6 +360,16 @@ func TestReconcileGateway_getPodCIDRs(t *testing.T) {
 			},
 			expectPodCIDR: []string{"10.0.0.1/24"},
 		},
+		{
+			name: "dual-stack node has pod CIDRs",
+			node: corev1.Node{
+				Spec: corev1.NodeSpec{
+					PodCIDR:  "10.0.0.1/24",
+					PodCIDRs: []string{"10.0.0.1/24", "fd00:10::/64"},
+				},
+			},
+			expectPodCIDR: []string{"10.0.0.1/24", "fd00:10::/64"},
+		},
 		{
 			name: "node hasn't pod CIDR",
 			node: corev1.Node{
@@ -295,3 +389,33 @@ func TestReconcileGateway_getPodCIDRs(t *testing.T) {
 		})
 	}
 }
+
+func TestLBIngressIPs(t *testing.T) {
+	var tt = []struct {
+		name      string
+		ingress   []corev1.LoadBalancerIngress
+		expectIPs []string
+	}{
+		{
+			name:      "ipv4 ingress",
+			ingress:   []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}},
+			expectIPs: []string{"1.1.1.1"},
+		},
+		{
+			name:      "dual-stack ingress",
+			ingress:   []corev1.LoadBalancerIngress{{IP: "2001:db8::1"}, {IP: "1.1.1.1"}, {IP: "1.1.1.2"}},
+			expectIPs: []string{"2001:db8::1", "1.1.1.1"},
+		},
+		{
+			name:      "hostname ingress",
+			ingress:   []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}},
+			expectIPs: []string{},
+		},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			svc := &corev1.Service{Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: v.ingress}}}
+			assert.Equal(t, v.expectIPs, lbIngressIPs(svc))
+		})
+	}
+}
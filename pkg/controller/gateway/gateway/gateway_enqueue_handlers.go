@@ -14,12 +14,16 @@ limitations under the License.
 package gateway
 
 import (
+	"context"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	"github.com/openyurtio/openyurt/pkg/apis/raven"
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
 	"github.com/openyurtio/openyurt/pkg/controller/gateway/utils"
 )
 
@@ -63,9 +67,11 @@ func (e *EnqueueGatewayForNode) Update(evt event.UpdateEvent,
 	oldGwName := oldNode.Labels[raven.LabelCurrentGateway]
 	newGwName := newNode.Labels[raven.LabelCurrentGateway]
 
-	// check if NodeReady condition changed
+	// check if NodeReady or RavenGatewayHealthy condition changed, the heartbeat timeout of
+	// RavenGatewayHealthy is checked by requeue of the gateway.
 	statusChanged := func(oldObj, newObj *corev1.Node) bool {
-		return isNodeReady(*oldObj) != isNodeReady(*newObj)
+		return isNodeReady(*oldObj) != isNodeReady(*newObj) ||
+			gatewayHealthStatus(oldObj) != gatewayHealthStatus(newObj)
 	}
 
 	if oldGwName != newGwName || statusChanged(oldNode, newNode) {
@@ -98,3 +104,58 @@ func (e *EnqueueGatewayForNode) Delete(evt event.DeleteEvent,
 func (e *EnqueueGatewayForNode) Generic(evt event.GenericEvent,
 	q workqueue.RateLimitingInterface) {
 }
+
+// EnqueueGatewayForPeer enqueues the peer Gateways of the changed Gateway, as the effective
+// topology of a pair of Gateways depends on the peers and active endpoints of both of them.
+type EnqueueGatewayForPeer struct {
+	client client.Client
+}
+
+// Create implements EventHandler
+func (e *EnqueueGatewayForPeer) Create(evt event.CreateEvent,
+	q workqueue.RateLimitingInterface) {
+	e.enqueuePeers(evt.Object, q)
+}
+
+// Update implements EventHandler
+func (e *EnqueueGatewayForPeer) Update(evt event.UpdateEvent,
+	q workqueue.RateLimitingInterface) {
+	// the Gateways which are removed from peers are enqueued too
+	e.enqueuePeers(evt.ObjectOld, q)
+	e.enqueuePeers(evt.ObjectNew, q)
+}
+
+// Delete implements EventHandler
+func (e *EnqueueGatewayForPeer) Delete(evt event.DeleteEvent,
+	q workqueue.RateLimitingInterface) {
+	e.enqueuePeers(evt.Object, q)
+}
+
+// Generic implements EventHandler
+func (e *EnqueueGatewayForPeer) Generic(evt event.GenericEvent,
+	q workqueue.RateLimitingInterface) {
+}
+
+func (e *EnqueueGatewayForPeer) enqueuePeers(obj client.Object, q workqueue.RateLimitingInterface) {
+	gw, ok := obj.(*ravenv1alpha1.Gateway)
+	if !ok {
+		klog.Error(Format("fail to assert runtime Object to v1alpha1.Gateway"))
+		return
+	}
+	var gwList ravenv1alpha1.GatewayList
+	if err := e.client.List(context.TODO(), &gwList); err != nil {
+		klog.Errorf(Format("unable to list gateways for peers of gateway(%s), %v", gw.Name, err))
+		return
+	}
+	for _, name := range peerGatewayNames(gw, gwList.Items).List() {
+		klog.V(5).Infof(Format("will enqueue gateway(%s) as peer gateway(%s) has been changed", name, gw.Name))
+		utils.AddGatewayToWorkQueue(name, q)
+	}
+}
+
+func gatewayHealthStatus(node *corev1.Node) corev1.ConditionStatus {
+	if _, cond := getNodeCondition(&node.Status, ravenv1alpha1.NodeConditionGatewayHealthy); cond != nil {
+		return cond.Status
+	}
+	return ""
+}
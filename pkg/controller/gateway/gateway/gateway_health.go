@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+// endpointHealthOf returns the health of the endpoint hosted by node, which is reported by the
+// raven agent as the RavenGatewayHealthy condition of node. The health is not reported if the
+// raven agent doesn't probe, and the reported healthy endpoint turns unhealthy if the heartbeat
+// of condition is older than timeout.
+func endpointHealthOf(node *corev1.Node, timeout time.Duration, now time.Time) (ravenv1alpha1.EndpointHealth, bool) {
+	_, cond := getNodeCondition(&node.Status, ravenv1alpha1.NodeConditionGatewayHealthy)
+	if cond == nil {
+		return ravenv1alpha1.EndpointHealth{}, false
+	}
+	health := ravenv1alpha1.EndpointHealth{
+		NodeName:      node.Name,
+		Healthy:       cond.Status == corev1.ConditionTrue,
+		Reason:        cond.Reason,
+		Message:       cond.Message,
+		LastProbeTime: cond.LastHeartbeatTime,
+	}
+	if health.Healthy && timeout > 0 && now.Sub(cond.LastHeartbeatTime.Time) > timeout {
+		health.Healthy = false
+		health.Reason = ravenv1alpha1.EndpointReasonProbeTimeout
+		health.Message = fmt.Sprintf("the health is not reported by raven agent in %v", timeout)
+	}
+	return health, true
+}
+
+// endpointsHealth returns the reported health of the endpoints of gw.
+func (r *ReconcileGateway) endpointsHealth(nodeList corev1.NodeList, gw *ravenv1alpha1.Gateway) []ravenv1alpha1.EndpointHealth {
+	nodes := make(map[string]*corev1.Node, len(nodeList.Items))
+	for i := range nodeList.Items {
+		nodes[nodeList.Items[i].Name] = &nodeList.Items[i]
+	}
+	now := time.Now()
+	var healthList []ravenv1alpha1.EndpointHealth
+	for _, ep := range gw.Spec.Endpoints {
+		node, ok := nodes[ep.NodeName]
+		if !ok {
+			continue
+		}
+		if health, ok := endpointHealthOf(node, r.Configration.EndpointHealthTimeout, now); ok {
+			healthList = append(healthList, health)
+		}
+	}
+	return healthList
+}
+
+// isEndpointHealthy checks the endpoint hosted by node is not reported unhealthy.
+func (r *ReconcileGateway) isEndpointHealthy(node *corev1.Node) bool {
+	health, ok := endpointHealthOf(node, r.Configration.EndpointHealthTimeout, time.Now())
+	return !ok || health.Healthy
+}
+
+// recordUnhealthyEvents records the events for the active endpoints which are reported unhealthy,
+// they are replaced by the standby endpoints if possible.
+func (r *ReconcileGateway) recordUnhealthyEvents(gw *ravenv1alpha1.Gateway, healthList []ravenv1alpha1.EndpointHealth) {
+	unhealthy := make(map[string]ravenv1alpha1.EndpointHealth)
+	for _, health := range healthList {
+		if !health.Healthy {
+			unhealthy[health.NodeName] = health
+		}
+	}
+	for _, ep := range currentActiveEndpoints(gw) {
+		health, ok := unhealthy[ep.NodeName]
+		if !ok {
+			continue
+		}
+		r.recorder.Event(gw.DeepCopy(), corev1.EventTypeWarning,
+			ravenv1alpha1.EventActiveEndpointUnhealthy,
+			fmt.Sprintf("The active endpoint hosted by node %s is unhealthy, reason: %s, message: %s", ep.NodeName, health.Reason, health.Message))
+	}
+}
+
+// healthRequeueAfter returns the duration after which the gateway is reconciled again for the
+// heartbeat timeout of healthy endpoints, 0 means no requeue is needed.
+func (r *ReconcileGateway) healthRequeueAfter(healthList []ravenv1alpha1.EndpointHealth) time.Duration {
+	timeout := r.Configration.EndpointHealthTimeout
+	if timeout <= 0 {
+		return 0
+	}
+	var after time.Duration
+	now := time.Now()
+	for _, health := range healthList {
+		if !health.Healthy {
+			continue
+		}
+		d := health.LastProbeTime.Add(timeout).Sub(now) + time.Second
+		if after == 0 || d < after {
+			after = d
+		}
+	}
+	return after
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+	"github.com/openyurtio/openyurt/pkg/controller/gateway/config"
+)
+
+func newHealthNode(name string, healthy bool, heartbeat time.Time) corev1.Node {
+	status := corev1.ConditionFalse
+	if healthy {
+		status = corev1.ConditionTrue
+	}
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: *nodeReadyStatus.DeepCopy()}
+	node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{
+		Type:              ravenv1alpha1.NodeConditionGatewayHealthy,
+		Status:            status,
+		Reason:            "Probed",
+		LastHeartbeatTime: metav1.NewTime(heartbeat),
+	})
+	return node
+}
+
+func TestEndpointHealthOf(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		node     corev1.Node
+		reported bool
+		healthy  bool
+		reason   string
+	}{
+		{
+			name:     "health is not reported",
+			node:     corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Status: nodeReadyStatus},
+			reported: false,
+		},
+		{
+			name:     "healthy endpoint",
+			node:     newHealthNode("node-1", true, now.Add(-10*time.Second)),
+			reported: true,
+			healthy:  true,
+			reason:   "Probed",
+		},
+		{
+			name:     "unhealthy endpoint",
+			node:     newHealthNode("node-1", false, now),
+			reported: true,
+			healthy:  false,
+			reason:   "Probed",
+		},
+		{
+			name:     "heartbeat times out",
+			node:     newHealthNode("node-1", true, now.Add(-2*time.Minute)),
+			reported: true,
+			healthy:  false,
+			reason:   ravenv1alpha1.EndpointReasonProbeTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health, ok := endpointHealthOf(&tt.node, time.Minute, now)
+			assert.Equal(t, tt.reported, ok)
+			assert.Equal(t, tt.healthy, health.Healthy)
+			assert.Equal(t, tt.reason, health.Reason)
+		})
+	}
+}
+
+func TestElectActiveEndpointsWithHealth(t *testing.T) {
+	now := time.Now()
+	r := &ReconcileGateway{Configration: config.GatewayControllerConfiguration{EndpointHealthTimeout: time.Minute}}
+	nodeList := corev1.NodeList{
+		Items: []corev1.Node{
+			newHealthNode("node-1", false, now),
+			newHealthNode("node-2", true, now.Add(-2*time.Minute)),
+			newHealthNode("node-3", true, now.Add(-30*time.Second)),
+			{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}, Status: nodeReadyStatus},
+		},
+	}
+	gw := &ravenv1alpha1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-1"},
+		Spec: ravenv1alpha1.GatewaySpec{
+			Endpoints: []ravenv1alpha1.Endpoint{
+				{NodeName: "node-1"}, {NodeName: "node-2"}, {NodeName: "node-3"}, {NodeName: "node-4"},
+			},
+			MaxActiveEndpoints: 2,
+		},
+		Status: ravenv1alpha1.GatewayStatus{ActiveEndpoints: []*ravenv1alpha1.Endpoint{{NodeName: "node-1"}}},
+	}
+
+	// node-1 is unhealthy and the heartbeat of node-2 times out, so the standby endpoints are promoted
+	assert.Equal(t, []*ravenv1alpha1.Endpoint{{NodeName: "node-3"}, {NodeName: "node-4"}}, r.electActiveEndpoints(nodeList, gw))
+
+	healthList := r.endpointsHealth(nodeList, gw)
+	assert.Len(t, healthList, 3)
+	// the gateway is reconciled again before the heartbeat of node-3 times out
+	after := r.healthRequeueAfter(healthList)
+	assert.True(t, after > 0 && after <= 31*time.Second, "unexpected requeue after %v", after)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+// peerTopology returns the requested topology for the peer, the empty topology is HubAndSpoke.
+func peerTopology(gw *ravenv1alpha1.Gateway, peerName string) (ravenv1alpha1.TopologyType, bool) {
+	for _, p := range gw.Spec.Peers {
+		if p.Name != peerName {
+			continue
+		}
+		if p.Topology == "" {
+			return ravenv1alpha1.TopologyHubAndSpoke, true
+		}
+		return p.Topology, true
+	}
+	return "", false
+}
+
+// peerGatewayNames returns the names of the Gateways which have a topology with gw specified
+// by any side of them.
+func peerGatewayNames(gw *ravenv1alpha1.Gateway, gateways []ravenv1alpha1.Gateway) sets.String {
+	names := sets.NewString()
+	for _, p := range gw.Spec.Peers {
+		names.Insert(p.Name)
+	}
+	for i := range gateways {
+		if _, ok := peerTopology(&gateways[i], gw.Name); ok {
+			names.Insert(gateways[i].Name)
+		}
+	}
+	names.Delete(gw.Name)
+	return names
+}
+
+// resolvePeers resolves the effective topologies between gw and its peer Gateways. A pair of
+// Gateways is connected directly if any of them requests Direct and none of them requests
+// HubAndSpoke, and it falls back to HubAndSpoke if any of them has no active endpoint or the
+// active endpoints of both of them are under NAT.
+func resolvePeers(gw *ravenv1alpha1.Gateway, activeEps []*ravenv1alpha1.Endpoint, gateways []ravenv1alpha1.Gateway) []ravenv1alpha1.PeerStatus {
+	names := peerGatewayNames(gw, gateways)
+	if names.Len() == 0 {
+		return nil
+	}
+	gatewayByName := make(map[string]*ravenv1alpha1.Gateway, len(gateways))
+	for i := range gateways {
+		gatewayByName[gateways[i].Name] = &gateways[i]
+	}
+
+	peers := make([]ravenv1alpha1.PeerStatus, 0, names.Len())
+	for _, name := range names.List() {
+		peers = append(peers, resolvePeer(gw, activeEps, name, gatewayByName[name]))
+	}
+	return peers
+}
+
+func resolvePeer(gw *ravenv1alpha1.Gateway, activeEps []*ravenv1alpha1.Endpoint, name string, peer *ravenv1alpha1.Gateway) ravenv1alpha1.PeerStatus {
+	hubAndSpoke := func(reason string) ravenv1alpha1.PeerStatus {
+		return ravenv1alpha1.PeerStatus{Name: name, Topology: ravenv1alpha1.TopologyHubAndSpoke, Reason: reason}
+	}
+	if peer == nil {
+		return hubAndSpoke(ravenv1alpha1.PeerReasonPeerNotFound)
+	}
+
+	requested, _ := peerTopology(gw, name)
+	peerRequested, _ := peerTopology(peer, gw.Name)
+	if requested == ravenv1alpha1.TopologyHubAndSpoke || peerRequested == ravenv1alpha1.TopologyHubAndSpoke ||
+		(requested != ravenv1alpha1.TopologyDirect && peerRequested != ravenv1alpha1.TopologyDirect) {
+		return hubAndSpoke(ravenv1alpha1.PeerReasonHubAndSpokeRequested)
+	}
+
+	peerActiveEps := currentActiveEndpoints(peer)
+	if len(activeEps) == 0 || len(peerActiveEps) == 0 {
+		return hubAndSpoke(ravenv1alpha1.PeerReasonNoActiveEndpoint)
+	}
+	// the direct tunnel is initiated by the Gateway under NAT, so one side must be reachable
+	if activeEps[0].UnderNAT && peerActiveEps[0].UnderNAT {
+		return hubAndSpoke(ravenv1alpha1.PeerReasonBothUnderNAT)
+	}
+	return ravenv1alpha1.PeerStatus{Name: name, Topology: ravenv1alpha1.TopologyDirect}
+}
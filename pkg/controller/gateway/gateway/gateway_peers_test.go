@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+func newPeerGateway(name string, underNAT, active bool, peers ...ravenv1alpha1.GatewayPeer) ravenv1alpha1.Gateway {
+	gw := ravenv1alpha1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       ravenv1alpha1.GatewaySpec{Peers: peers},
+	}
+	if active {
+		gw.Status.ActiveEndpoints = []*ravenv1alpha1.Endpoint{{NodeName: name + "-node", UnderNAT: underNAT}}
+	}
+	return gw
+}
+
+func TestResolvePeers(t *testing.T) {
+	direct := func(name string) ravenv1alpha1.GatewayPeer {
+		return ravenv1alpha1.GatewayPeer{Name: name, Topology: ravenv1alpha1.TopologyDirect}
+	}
+	hubAndSpoke := func(name string) ravenv1alpha1.GatewayPeer {
+		return ravenv1alpha1.GatewayPeer{Name: name, Topology: ravenv1alpha1.TopologyHubAndSpoke}
+	}
+
+	tests := []struct {
+		name   string
+		gw     ravenv1alpha1.Gateway
+		others []ravenv1alpha1.Gateway
+		expect []ravenv1alpha1.PeerStatus
+	}{
+		{
+			name:   "no peers",
+			gw:     newPeerGateway("gw-a", true, true),
+			others: []ravenv1alpha1.Gateway{newPeerGateway("gw-b", true, true)},
+			expect: nil,
+		},
+		{
+			name:   "direct requested by this side",
+			gw:     newPeerGateway("gw-a", true, true, direct("gw-b")),
+			others: []ravenv1alpha1.Gateway{newPeerGateway("gw-b", false, true)},
+			expect: []ravenv1alpha1.PeerStatus{{Name: "gw-b", Topology: ravenv1alpha1.TopologyDirect}},
+		},
+		{
+			name:   "direct requested by the peer",
+			gw:     newPeerGateway("gw-a", false, true),
+			others: []ravenv1alpha1.Gateway{newPeerGateway("gw-b", true, true, direct("gw-a"))},
+			expect: []ravenv1alpha1.PeerStatus{{Name: "gw-b", Topology: ravenv1alpha1.TopologyDirect}},
+		},
+		{
+			name:   "hub and spoke requested by the peer",
+			gw:     newPeerGateway("gw-a", false, true, direct("gw-b")),
+			others: []ravenv1alpha1.Gateway{newPeerGateway("gw-b", false, true, hubAndSpoke("gw-a"))},
+			expect: []ravenv1alpha1.PeerStatus{{Name: "gw-b", Topology: ravenv1alpha1.TopologyHubAndSpoke,
+				Reason: ravenv1alpha1.PeerReasonHubAndSpokeRequested}},
+		},
+		{
+			name:   "peer not found",
+			gw:     newPeerGateway("gw-a", false, true, direct("gw-b")),
+			others: nil,
+			expect: []ravenv1alpha1.PeerStatus{{Name: "gw-b", Topology: ravenv1alpha1.TopologyHubAndSpoke,
+				Reason: ravenv1alpha1.PeerReasonPeerNotFound}},
+		},
+		{
+			name:   "peer has no active endpoint",
+			gw:     newPeerGateway("gw-a", false, true, direct("gw-b")),
+			others: []ravenv1alpha1.Gateway{newPeerGateway("gw-b", false, false)},
+			expect: []ravenv1alpha1.PeerStatus{{Name: "gw-b", Topology: ravenv1alpha1.TopologyHubAndSpoke,
+				Reason: ravenv1alpha1.PeerReasonNoActiveEndpoint}},
+		},
+		{
+			name:   "both under NAT",
+			gw:     newPeerGateway("gw-a", true, true, direct("gw-b")),
+			others: []ravenv1alpha1.Gateway{newPeerGateway("gw-b", true, true)},
+			expect: []ravenv1alpha1.PeerStatus{{Name: "gw-b", Topology: ravenv1alpha1.TopologyHubAndSpoke,
+				Reason: ravenv1alpha1.PeerReasonBothUnderNAT}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateways := append([]ravenv1alpha1.Gateway{tt.gw}, tt.others...)
+			got := resolvePeers(&tt.gw, currentActiveEndpoints(&tt.gw), gateways)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
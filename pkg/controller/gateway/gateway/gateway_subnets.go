@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"net"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+// mapSubnets translates the subnets by the subnet mappings of gw, the subnets which are not
+// covered by any mapping are kept. It returns nil if gw has no subnet mapping.
+func mapSubnets(gw *ravenv1alpha1.Gateway, subnets []string) []string {
+	if len(gw.Spec.SubnetMappings) == 0 {
+		return nil
+	}
+	mapped := make([]string, 0, len(subnets))
+	for _, subnet := range subnets {
+		mapped = append(mapped, mapSubnet(gw.Spec.SubnetMappings, subnet))
+	}
+	return mapped
+}
+
+// mapSubnet translates subnet by the first mapping whose Subnet covers it, the host bits
+// of the mapping are kept, so the addresses are mapped one to one.
+func mapSubnet(mappings []ravenv1alpha1.SubnetMapping, subnet string) string {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return subnet
+	}
+	ones, bits := ipNet.Mask.Size()
+	for _, m := range mappings {
+		_, from, err := net.ParseCIDR(m.Subnet)
+		if err != nil {
+			klog.Error(Format("subnet %q of subnet mapping is invalid, %v", m.Subnet, err))
+			continue
+		}
+		_, to, err := net.ParseCIDR(m.MappedSubnet)
+		if err != nil {
+			klog.Error(Format("mapped subnet %q of subnet mapping is invalid, %v", m.MappedSubnet, err))
+			continue
+		}
+		fromOnes, fromBits := from.Mask.Size()
+		toOnes, toBits := to.Mask.Size()
+		if fromOnes != toOnes || fromBits != toBits || fromBits != bits || fromOnes > ones || !from.Contains(ipNet.IP) {
+			continue
+		}
+
+		ip := make(net.IP, len(to.IP))
+		for i := range ip {
+			ip[i] = to.IP[i] | (ipNet.IP[i] &^ from.Mask[i])
+		}
+		return (&net.IPNet{IP: ip, Mask: ipNet.Mask}).String()
+	}
+	return subnet
+}
+
+// routedSubnets returns the subnets which the other Gateways route to gw.
+func routedSubnets(gw *ravenv1alpha1.Gateway) []*net.IPNet {
+	var subnets []*net.IPNet
+	for _, node := range gw.Status.Nodes {
+		cidrs := node.Subnets
+		if len(node.MappedSubnets) != 0 {
+			cidrs = node.MappedSubnets
+		}
+		for _, cidr := range cidrs {
+			if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+				subnets = append(subnets, ipNet)
+			}
+		}
+	}
+	return subnets
+}
+
+// conflictGatewayNames returns the names of the Gateways whose routed subnets overlap with
+// the routed subnets of gw, the pods in the overlapping subnets are not reachable from each
+// other until the subnets are mapped.
+func conflictGatewayNames(gw *ravenv1alpha1.Gateway, gateways []ravenv1alpha1.Gateway) []string {
+	subnets := routedSubnets(gw)
+	if len(subnets) == 0 {
+		return nil
+	}
+	names := sets.NewString()
+	for i := range gateways {
+		if gateways[i].Name == gw.Name {
+			continue
+		}
+		for _, peerSubnet := range routedSubnets(&gateways[i]) {
+			if overlaps(subnets, peerSubnet) {
+				names.Insert(gateways[i].Name)
+				break
+			}
+		}
+	}
+	return names.List()
+}
+
+func overlaps(subnets []*net.IPNet, subnet *net.IPNet) bool {
+	for _, s := range subnets {
+		if s.Contains(subnet.IP) || subnet.Contains(s.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+func TestMapSubnets(t *testing.T) {
+	mappings := []ravenv1alpha1.SubnetMapping{
+		{Subnet: "10.244.0.0/16", MappedSubnet: "100.64.0.0/16"},
+		{Subnet: "fd00:10:244::/56", MappedSubnet: "fd00:100:64::/56"},
+	}
+	tests := []struct {
+		name     string
+		mappings []ravenv1alpha1.SubnetMapping
+		subnets  []string
+		expect   []string
+	}{
+		{
+			name:    "no mappings",
+			subnets: []string{"10.244.1.0/24"},
+			expect:  nil,
+		},
+		{
+			name:     "ipv4 subnet is mapped",
+			mappings: mappings,
+			subnets:  []string{"10.244.1.0/24", "10.244.255.128/25"},
+			expect:   []string{"100.64.1.0/24", "100.64.255.128/25"},
+		},
+		{
+			name:     "dual-stack subnets are mapped",
+			mappings: mappings,
+			subnets:  []string{"10.244.3.0/24", "fd00:10:244:2::/64"},
+			expect:   []string{"100.64.3.0/24", "fd00:100:64:2::/64"},
+		},
+		{
+			name:     "subnets not covered by mappings are kept",
+			mappings: mappings,
+			subnets:  []string{"10.245.1.0/24", "10.0.0.0/8", "invalid"},
+			expect:   []string{"10.245.1.0/24", "10.0.0.0/8", "invalid"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := &ravenv1alpha1.Gateway{Spec: ravenv1alpha1.GatewaySpec{SubnetMappings: tt.mappings}}
+			assert.Equal(t, tt.expect, mapSubnets(gw, tt.subnets))
+		})
+	}
+}
+
+func TestConflictGatewayNames(t *testing.T) {
+	newGateway := func(name string, nodes ...ravenv1alpha1.NodeInfo) ravenv1alpha1.Gateway {
+		return ravenv1alpha1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     ravenv1alpha1.GatewayStatus{Nodes: nodes},
+		}
+	}
+	gw := newGateway("gw-a", ravenv1alpha1.NodeInfo{NodeName: "a", Subnets: []string{"10.244.1.0/24"}})
+	others := []ravenv1alpha1.Gateway{
+		gw,
+		newGateway("gw-b", ravenv1alpha1.NodeInfo{NodeName: "b", Subnets: []string{"10.244.0.0/16"}}),
+		newGateway("gw-c", ravenv1alpha1.NodeInfo{NodeName: "c", Subnets: []string{"10.244.1.0/24"},
+			MappedSubnets: []string{"100.64.1.0/24"}}),
+		newGateway("gw-d", ravenv1alpha1.NodeInfo{NodeName: "d", Subnets: []string{"10.245.1.0/24"}}),
+	}
+	assert.Equal(t, []string{"gw-b"}, conflictGatewayNames(&gw, others))
+
+	mapped := newGateway("gw-a", ravenv1alpha1.NodeInfo{NodeName: "a", Subnets: []string{"10.244.1.0/24"},
+		MappedSubnets: []string{"100.65.1.0/24"}})
+	assert.Empty(t, conflictGatewayNames(&mapped, others))
+}
+
+func TestRecordSubnetConflicts(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &ReconcileGateway{recorder: recorder}
+	gw := &ravenv1alpha1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "gw-a"}}
+
+	steps := []struct {
+		conflicts   []string
+		expectEvent bool
+	}{
+		{conflicts: []string{"gw-b"}, expectEvent: true},
+		{conflicts: []string{"gw-b"}, expectEvent: false},
+		{conflicts: []string{"gw-b", "gw-c"}, expectEvent: true},
+		{conflicts: nil, expectEvent: false},
+		{conflicts: []string{"gw-b"}, expectEvent: true},
+	}
+	for i, s := range steps {
+		r.recordSubnetConflicts(gw, s.conflicts)
+		assert.Equal(t, s.conflicts, gw.Status.SubnetConflicts, "step %d", i)
+		assert.Equal(t, s.expectEvent, len(recorder.Events) == 1, "step %d", i)
+		for len(recorder.Events) != 0 {
+			<-recorder.Events
+		}
+	}
+}
@@ -182,6 +182,9 @@ func (r *ReconcileService) ensureService(ctx context.Context, req ctrl.Request)
 					},
 					Type:                  corev1.ServiceTypeLoadBalancer,
 					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+					// the service is dual-stack if the cluster supports, so the
+					// endpoints can be reached by both IPv4 and IPv6
+					IPFamilyPolicy: func(p corev1.IPFamilyPolicyType) *corev1.IPFamilyPolicyType { return &p }(corev1.IPFamilyPolicyPreferDualStack),
 				},
 			})
 
@@ -195,14 +198,23 @@ func (r *ReconcileService) reconcileEndpoint(ctx context.Context, req ctrl.Reque
 	for _, gw := range gatewayList.Items {
 		if utils.IsGatewayExposeByLB(&gw) {
 			exposedByLB = true
-			if gw.Status.ActiveEndpoint != nil {
-				var node corev1.Node
-				if err := r.Get(ctx, types.NamespacedName{
-					Name: gw.Status.ActiveEndpoint.NodeName,
-				}, &node); err != nil {
-					return err
+			activeEps := gw.Status.ActiveEndpoints
+			if len(activeEps) == 0 && gw.Status.ActiveEndpoint != nil {
+				activeEps = []*ravenv1alpha1.Endpoint{gw.Status.ActiveEndpoint}
+			}
+			if len(activeEps) != 0 {
+				// the traffic from LB is split among all of the active endpoints
+				nodes := make([]corev1.Node, 0, len(activeEps))
+				for _, ep := range activeEps {
+					var node corev1.Node
+					if err := r.Get(ctx, types.NamespacedName{
+						Name: ep.NodeName,
+					}, &node); err != nil {
+						return err
+					}
+					nodes = append(nodes, node)
 				}
-				return r.ensureEndpoint(ctx, req, node)
+				return r.ensureEndpoint(ctx, req, nodes)
 			}
 		}
 	}
@@ -224,16 +236,21 @@ func (r *ReconcileService) cleanEndpoint(ctx context.Context, req ctrl.Request)
 	return nil
 }
 
-func (r *ReconcileService) ensureEndpoint(ctx context.Context, req ctrl.Request, node corev1.Node) error {
+func (r *ReconcileService) ensureEndpoint(ctx context.Context, req ctrl.Request, nodes []corev1.Node) error {
 	var serviceEndpoint corev1.Endpoints
+	addresses := make([]corev1.EndpointAddress, 0, len(nodes))
+	for _, node := range nodes {
+		// the addresses of all IP families are added for the dual-stack service
+		for _, ip := range utils.GetNodeInternalIPs(node) {
+			addresses = append(addresses, corev1.EndpointAddress{
+				IP:       ip,
+				NodeName: func(n corev1.Node) *string { return &n.Name }(node),
+			})
+		}
+	}
 	newSubnets := []corev1.EndpointSubset{
 		{
-			Addresses: []corev1.EndpointAddress{
-				{
-					IP:       utils.GetNodeInternalIP(node),
-					NodeName: func(n corev1.Node) *string { return &n.Name }(node),
-				},
-			},
+			Addresses: addresses,
 			Ports: []corev1.EndpointPort{
 				{
 					Port:     4500,
@@ -39,6 +39,29 @@ func GetNodeInternalIP(node corev1.Node) string {
 	return ip
 }
 
+// GetNodeInternalIPs returns the first internal ip of each IP family of the given `node`,
+// the order of families is the same as node addresses, so the first one is GetNodeInternalIP.
+func GetNodeInternalIPs(node corev1.Node) []string {
+	var ipv4, ipv6 string
+	ips := make([]string, 0, 2)
+	for _, addr := range node.Status.Addresses {
+		if addr.Type != corev1.NodeInternalIP {
+			continue
+		}
+		ip := net.ParseIP(addr.Address)
+		switch {
+		case ip == nil:
+		case ip.To4() != nil && len(ipv4) == 0:
+			ipv4 = addr.Address
+			ips = append(ips, ipv4)
+		case ip.To4() == nil && len(ipv6) == 0:
+			ipv6 = addr.Address
+			ips = append(ips, ipv6)
+		}
+	}
+	return ips
+}
+
 func IsGatewayExposeByLB(gateway *v1alpha1.Gateway) bool {
 	return gateway.Spec.ExposeType == v1alpha1.ExposeTypeLoadBalancer
 }
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+const (
+	groupLeaseDuration = 15 * time.Second
+	groupRenewDeadline = 10 * time.Second
+	groupRetryPeriod   = 2 * time.Second
+)
+
+// leaderElectionGroups holds the leader election groups of controllers, the controllers not in any group
+// are added into the manager and elected by the lease of yurt-manager.
+type leaderElectionGroups struct {
+	mgr         manager.Manager
+	groups      map[string]*groupManager
+	controllers map[string]string
+}
+
+func newLeaderElectionGroups(c *config.CompletedConfig, mgr manager.Manager) (*leaderElectionGroups, error) {
+	generic := &c.ComponentConfig.Generic
+	g := &leaderElectionGroups{
+		mgr:         mgr,
+		groups:      make(map[string]*groupManager),
+		controllers: generic.LeaderElectionGroups,
+	}
+	// all controllers are elected together with the manager if leader election is disabled
+	if !generic.EnableLeaderElection || len(generic.LeaderElectionGroups) == 0 {
+		g.controllers = nil
+		return g, nil
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range generic.LeaderElectionGroups {
+		if _, ok := g.groups[group]; ok {
+			continue
+		}
+		lock, err := resourcelock.New(resourcelock.LeasesResourceLock,
+			generic.LeaderElectionNamespace,
+			projectinfo.GetYurtManagerName()+"-"+group,
+			kubeClient.CoreV1(),
+			kubeClient.CoordinationV1(),
+			resourcelock.ResourceLockConfig{
+				Identity:      hostname + "_" + string(uuid.NewUUID()),
+				EventRecorder: mgr.GetEventRecorderFor(projectinfo.GetYurtManagerName()),
+			})
+		if err != nil {
+			return nil, err
+		}
+		g.groups[group] = &groupManager{Manager: mgr, name: group, lock: lock}
+	}
+	return g, nil
+}
+
+// managerFor returns the manager which the controller should be added into.
+func (g *leaderElectionGroups) managerFor(controller string) manager.Manager {
+	if group, ok := g.controllers[controller]; ok {
+		return g.groups[group]
+	}
+	return g.mgr
+}
+
+// addToManager adds the groups into the manager, every group runs its own leader election.
+func (g *leaderElectionGroups) addToManager(mgr manager.Manager) error {
+	names := make([]string, 0, len(g.groups))
+	for name := range g.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := mgr.Add(g.groups[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupManager collects the controllers of a leader election group instead of adding them into the manager,
+// and starts them only when the lease of group is acquired. Caches and clients are still shared with the manager.
+type groupManager struct {
+	manager.Manager
+	name      string
+	lock      resourcelock.Interface
+	runnables []manager.Runnable
+}
+
+var _ manager.LeaderElectionRunnable = &groupManager{}
+
+// Add collects the runnable, which is started after the lease of group is acquired.
+func (g *groupManager) Add(r manager.Runnable) error {
+	if err := g.Manager.SetFields(r); err != nil {
+		return err
+	}
+	g.runnables = append(g.runnables, r)
+	return nil
+}
+
+// NeedLeaderElection returns false because the group doesn't depend on the lease of yurt-manager.
+func (g *groupManager) NeedLeaderElection() bool {
+	return false
+}
+
+// Start runs leader election of the group until ctx is done, the process exits if the lease is lost
+// because controllers can't be started again.
+func (g *groupManager) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            g.lock,
+		LeaseDuration:   groupLeaseDuration,
+		RenewDeadline:   groupRenewDeadline,
+		RetryPeriod:     groupRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            g.name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.Infof("leader election group %s is acquired, start %d controllers", g.name, len(g.runnables))
+				for i := range g.runnables {
+					r := g.runnables[i]
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if err := r.Start(ctx); err != nil {
+							klog.Errorf("controller in leader election group %s exits, %v", g.name, err)
+						}
+					}()
+				}
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					return
+				}
+				klog.Fatalf("leader election group %s is lost", g.name)
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	elector.Run(ctx)
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+type fakeManager struct {
+	manager.Manager
+	runnables []manager.Runnable
+}
+
+func (m *fakeManager) SetFields(interface{}) error {
+	return nil
+}
+
+func (m *fakeManager) Add(r manager.Runnable) error {
+	m.runnables = append(m.runnables, r)
+	return nil
+}
+
+func TestLeaderElectionGroups(t *testing.T) {
+	mgr := &fakeManager{}
+	groups := &leaderElectionGroups{
+		mgr: mgr,
+		groups: map[string]*groupManager{
+			"pool": {Manager: mgr, name: "pool"},
+		},
+		controllers: map[string]string{"nodepool": "pool", "nodepoolquota": "pool"},
+	}
+
+	runnable := manager.RunnableFunc(func(context.Context) error { return nil })
+	for _, name := range []string{"nodepool", "nodepoolquota", "gateway"} {
+		if err := groups.managerFor(name).Add(runnable); err != nil {
+			t.Fatalf("could not add runnable, %v", err)
+		}
+	}
+
+	if n := len(groups.groups["pool"].runnables); n != 2 {
+		t.Errorf("expect 2 controllers in group pool, but got %d", n)
+	}
+	if len(mgr.runnables) != 1 {
+		t.Errorf("expect 1 controller in manager, but got %d", len(mgr.runnables))
+	}
+
+	if err := groups.addToManager(mgr); err != nil {
+		t.Fatalf("could not add groups to manager, %v", err)
+	}
+	if len(mgr.runnables) != 2 || mgr.runnables[1] != groups.groups["pool"] {
+		t.Errorf("expect group pool is added into manager")
+	}
+	if groups.groups["pool"].NeedLeaderElection() {
+		t.Errorf("expect group doesn't need leader election of manager")
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodehardware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/hardware"
+)
+
+const (
+	// LabelHardwarePrefix is the prefix of node labels converted from the hardware report,
+	// all labels with the prefix are managed by the controller.
+	LabelHardwarePrefix = "hardware.openyurt.io/"
+
+	LabelArch      = LabelHardwarePrefix + "arch"
+	LabelGPU       = LabelHardwarePrefix + "gpu"
+	LabelGPUCount  = LabelHardwarePrefix + "gpu-count"
+	LabelNPU       = LabelHardwarePrefix + "npu"
+	LabelNPUCount  = LabelHardwarePrefix + "npu-count"
+	LabelModem     = LabelHardwarePrefix + "modem"
+	LabelDiskClass = LabelHardwarePrefix + "disk-class"
+)
+
+// reportToLabels converts the hardware report into node labels, the attributes which are
+// not detected have no labels.
+func reportToLabels(report *hardware.Report) map[string]string {
+	labels := make(map[string]string)
+	if len(report.Arch) != 0 {
+		labels[LabelArch] = report.Arch
+	}
+	if len(report.GPU) != 0 {
+		labels[LabelGPU] = report.GPU
+		labels[LabelGPUCount] = strconv.Itoa(report.GPUCount)
+	}
+	if len(report.NPU) != 0 {
+		labels[LabelNPU] = report.NPU
+		labels[LabelNPUCount] = strconv.Itoa(report.NPUCount)
+	}
+	if len(report.Modem) != 0 {
+		labels[LabelModem] = report.Modem
+	}
+	if len(report.DiskClass) != 0 {
+		labels[LabelDiskClass] = report.DiskClass
+	}
+	return labels
+}
+
+// mergeLabels replaces the hardware labels in current labels with desired labels,
+// and returns whether the labels are changed.
+func mergeLabels(current, desired map[string]string) (map[string]string, bool) {
+	merged := make(map[string]string, len(current)+len(desired))
+	changed := false
+	for k, v := range current {
+		if strings.HasPrefix(k, LabelHardwarePrefix) {
+			if _, ok := desired[k]; !ok {
+				changed = true
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	for k, v := range desired {
+		if merged[k] != v {
+			changed = true
+		}
+		merged[k] = v
+	}
+	return merged, changed
+}
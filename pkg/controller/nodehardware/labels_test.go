@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodehardware
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/hardware"
+)
+
+func TestMergeLabels(t *testing.T) {
+	testcases := map[string]struct {
+		current       map[string]string
+		report        *hardware.Report
+		expect        map[string]string
+		expectChanged bool
+	}{
+		"add hardware labels": {
+			current: map[string]string{"kubernetes.io/hostname": "edge-1"},
+			report:  &hardware.Report{Arch: "arm64", NPU: "ascend", NPUCount: 2, Modem: "qmi", DiskClass: "ssd"},
+			expect: map[string]string{
+				"kubernetes.io/hostname": "edge-1",
+				LabelArch:                "arm64",
+				LabelNPU:                 "ascend",
+				LabelNPUCount:            "2",
+				LabelModem:               "qmi",
+				LabelDiskClass:           "ssd",
+			},
+			expectChanged: true,
+		},
+		"remove stale hardware labels": {
+			current: map[string]string{
+				"kubernetes.io/hostname": "edge-1",
+				LabelArch:                "amd64",
+				LabelGPU:                 "nvidia",
+				LabelGPUCount:            "1",
+				LabelModem:               "qmi",
+			},
+			report: &hardware.Report{Arch: "amd64", GPU: "nvidia", GPUCount: 2},
+			expect: map[string]string{
+				"kubernetes.io/hostname": "edge-1",
+				LabelArch:                "amd64",
+				LabelGPU:                 "nvidia",
+				LabelGPUCount:            "2",
+			},
+			expectChanged: true,
+		},
+		"labels are not changed": {
+			current: map[string]string{LabelArch: "amd64", LabelDiskClass: "nvme"},
+			report:  &hardware.Report{Arch: "amd64", DiskClass: "nvme"},
+			expect:  map[string]string{LabelArch: "amd64", LabelDiskClass: "nvme"},
+		},
+		"report is removed": {
+			current:       map[string]string{"kubernetes.io/hostname": "edge-1", LabelArch: "amd64"},
+			report:        &hardware.Report{},
+			expect:        map[string]string{"kubernetes.io/hostname": "edge-1"},
+			expectChanged: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			labels, changed := mergeLabels(tc.current, reportToLabels(tc.report))
+			if changed != tc.expectChanged {
+				t.Errorf("expect changed %v, but got %v", tc.expectChanged, changed)
+			}
+			if !reflect.DeepEqual(labels, tc.expect) {
+				t.Errorf("expect labels %v, but got %v", tc.expect, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodehardware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
+	"github.com/openyurtio/openyurt/pkg/yurthub/hardware"
+)
+
+var (
+	concurrentReconciles = 3
+)
+
+const (
+	controllerName = "NodeHardware-controller"
+)
+
+func Format(format string, args ...interface{}) string {
+	s := fmt.Sprintf(format, args...)
+	return fmt.Sprintf("%s: %s", controllerName, s)
+}
+
+// ReconcileNodeHardware converts the hardware report of node into standardized node labels.
+type ReconcileNodeHardware struct {
+	client.Client
+}
+
+var _ reconcile.Reconciler = &ReconcileNodeHardware{}
+
+// Add creates a new NodeHardware Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(c *config.CompletedConfig, mgr manager.Manager) error {
+	return add(mgr, newReconciler(c, mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(_ *config.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileNodeHardware{
+		Client: utilclient.NewClientFromManager(mgr, controllerName),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler: r, MaxConcurrentReconciles: concurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for the nodes which have hardware report, and only the changes of report are concerned
+	return c.Watch(&source.Kind{Type: &corev1.Node{}}, &handler.EnqueueRequestForObject{}, predicate.Funcs{
+		CreateFunc: func(evt event.CreateEvent) bool {
+			_, ok := evt.Object.GetAnnotations()[hardware.AnnotationHardwareReport]
+			return ok
+		},
+		UpdateFunc: func(evt event.UpdateEvent) bool {
+			return evt.ObjectOld.GetAnnotations()[hardware.AnnotationHardwareReport] != evt.ObjectNew.GetAnnotations()[hardware.AnnotationHardwareReport]
+		},
+		DeleteFunc: func(evt event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(evt event.GenericEvent) bool {
+			return false
+		},
+	})
+}
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update
+
+// Reconcile replaces the hardware labels of node with the labels converted from the hardware report,
+// the hardware labels are removed if the report is removed.
+func (r *ReconcileNodeHardware) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	klog.V(4).Infof(Format("Reconcile Node %s", req.Name))
+
+	var node corev1.Node
+	if err := r.Get(ctx, req.NamespacedName, &node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	desired := make(map[string]string)
+	if data, ok := node.Annotations[hardware.AnnotationHardwareReport]; ok {
+		var report hardware.Report
+		if err := json.Unmarshal([]byte(data), &report); err != nil {
+			klog.Errorf(Format("could not unmarshal hardware report %q of node %s, %v", data, node.Name, err))
+			return ctrl.Result{}, nil
+		}
+		desired = reportToLabels(&report)
+	}
+
+	labels, changed := mergeLabels(node.Labels, desired)
+	if !changed {
+		return ctrl.Result{}, nil
+	}
+	node.Labels = labels
+	if err := r.Update(ctx, &node); err != nil {
+		klog.Errorf(Format("could not update hardware labels of node %s, %v", node.Name, err))
+		return ctrl.Result{}, err
+	}
+	klog.Infof(Format("hardware labels of node %s are updated", node.Name))
+	return ctrl.Result{}, nil
+}
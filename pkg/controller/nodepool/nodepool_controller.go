@@ -20,11 +20,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	schemeappsv1beta1 "k8s.io/client-go/scale/scheme/appsv1beta1"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,6 +42,7 @@ import (
 	"github.com/openyurtio/openyurt/pkg/apis/apps"
 	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
 	nodepoolconfig "github.com/openyurtio/openyurt/pkg/controller/nodepool/config"
+	"github.com/openyurtio/openyurt/pkg/controller/nodepool/provisioner"
 	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
 	utildiscovery "github.com/openyurtio/openyurt/pkg/util/discovery"
 )
@@ -62,6 +67,12 @@ type ReconcileNodePool struct {
 	scheme       *runtime.Scheme
 	recorder     record.EventRecorder
 	Configration nodepoolconfig.NodePoolControllerConfiguration
+	// newProvisioner creates the provisioner for scaling nodepool
+	newProvisioner provisioner.Factory
+	// evictPod evicts the pods for decommissioning nodepool
+	evictPod PodEvictor
+	// decommissionBackoff backs off checking the progress of decommission which makes no progress
+	decommissionBackoff *flowcontrol.Backoff
 }
 
 var _ reconcile.Reconciler = &ReconcileNodePool{}
@@ -87,10 +98,14 @@ type NodePoolRelatedAttributes struct {
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(c *config.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
 	return &ReconcileNodePool{
-		Client:       utilclient.NewClientFromManager(mgr, controllerName),
-		scheme:       mgr.GetScheme(),
-		recorder:     mgr.GetEventRecorderFor(controllerName),
-		Configration: c.ComponentConfig.NodePoolController,
+		Client:         utilclient.NewClientFromManager(mgr, controllerName),
+		scheme:         mgr.GetScheme(),
+		recorder:       mgr.GetEventRecorderFor(controllerName),
+		Configration:   c.ComponentConfig.NodePoolController,
+		newProvisioner: provisioner.NewWebhookProvisioner,
+		evictPod:       NewPodEvictor(kubernetes.NewForConfigOrDie(mgr.GetConfig())),
+
+		decommissionBackoff: newDecommissionBackoff(),
 	}
 }
 
@@ -104,6 +119,11 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// index pods by node name for calculating utilization and pending pods of pool
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, podNodeNameIndex, indexPodByNodeName); err != nil {
+		return err
+	}
+
 	// Watch for changes to NodePool
 	err = c.Watch(&source.Kind{Type: &appsv1beta1.NodePool{}}, &handler.EnqueueRequestForObject{})
 	if err != nil {
@@ -132,8 +152,9 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 
 // +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
 
 // Reconcile reads that state of the cluster for a NodePool object and makes changes based on the state read
 // and what is in the NodePool.Spec
@@ -147,9 +168,15 @@ func (r *ReconcileNodePool) Reconcile(ctx context.Context, req reconcile.Request
 	var nodePool appsv1beta1.NodePool
 	// try to reconcile the NodePool object
 	if err := r.Get(ctx, req.NamespacedName, &nodePool); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.decommissionBackoff.DeleteEntry(req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 	klog.Infof(Format("NodePool %++v", nodePool))
+	// the status is patched against the original pool, so the fields of status maintained by
+	// other controllers(like quota usage) are not overwritten.
+	original := nodePool.DeepCopy()
 
 	var desiredNodeList corev1.NodeList
 	if err := r.List(ctx, &desiredNodeList, client.MatchingLabels(map[string]string{
@@ -196,10 +223,17 @@ func (r *ReconcileNodePool) Reconcile(ctx context.Context, req reconcile.Request
 		}
 
 		// update node status according to nodepool
-		updated, err := concilateNode(&node, nodePool)
+		prevConflicts := node.Annotations[apps.AnnotationAttributeConflicts]
+		updated, conflicts, err := concilateNode(&node, nodePool)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		// the conflicts are only reported when they change instead of in every reconcile
+		if len(conflicts) != 0 && strings.Join(conflicts, ",") != prevConflicts {
+			r.recorder.Eventf(&nodePool, corev1.EventTypeWarning, "NodeAttributesConflict",
+				"attributes(%s) of node %s are managed by others, conflict policy is %s",
+				strings.Join(conflicts, ","), node.Name, nodePool.Spec.ConflictPolicy)
+		}
 		if updated {
 			if err := r.Update(ctx, &node); err != nil {
 				klog.Errorf(Format("Update Node %s error %v", node.Name, err))
@@ -210,8 +244,71 @@ func (r *ReconcileNodePool) Reconcile(ctx context.Context, req reconcile.Request
 
 	// 3. always update the node pool status if necessary
 	needUpdate := conciliateNodePoolStatus(readyNode, notReadyNode, nodes, &nodePool)
+
+	// 4. publish utilization and pending pods of the pool, and call provisioner webhook
+	// for adding or removing nodes if scaling is specified.
+	nodePods, err := r.listNodePods(ctx, desiredNodeList.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	utilization, candidates := calculateUtilization(desiredNodeList.Items, nodePods)
+	pendingPodNum, err := r.countPendingPods(ctx, nodePool.GetName())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conciliateScalingStatus(utilization, pendingPodNum, &nodePool) {
+		needUpdate = true
+	}
+
+	// 5. publish the aggregated resources and connectivity state of the pool, the summary
+	// is refreshed periodically because pod changes are not watched.
+	if conciliateResourceSummary(summarizeResources(desiredNodeList.Items, nodePods), &nodePool) {
+		needUpdate = true
+	}
+
+	result := ctrl.Result{RequeueAfter: summaryResyncPeriod}
+	if nodePool.Spec.Scaling != nil && nodePool.Spec.Decommission == nil {
+		if r.scaleNodePool(ctx, &nodePool, candidates) {
+			needUpdate = true
+		}
+		// pod changes are not watched, so utilization is refreshed periodically
+		result.RequeueAfter = scalingResyncPeriod
+	}
+
+	// 6. cordon and drain the nodes, then delete the nodes and the pool if decommission is specified.
+	decommissionUpdated := false
+	if nodePool.Spec.Decommission != nil {
+		updated, err := r.decommissionNodePool(ctx, &nodePool, desiredNodeList.Items, nodePods)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if updated {
+			needUpdate = true
+			decommissionUpdated = true
+		}
+		if nodePool.Status.Decommission.Phase == appsv1beta1.DecommissionCompleted && nodePool.Spec.Decommission.DeletePool {
+			// persist the completed status before deleting the pool, so the watchers of pool can see
+			// the decommission is completed instead of the pool disappearing during migration.
+			if needUpdate {
+				if err := r.Status().Patch(ctx, &nodePool, client.MergeFrom(original)); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, &nodePool))
+		}
+	}
+	// pod changes are not watched, so the progress of pending decommission is checked with backoff
+	if wait := r.decommissionRequeueAfter(&nodePool, decommissionUpdated, timeNow()); wait > 0 {
+		result.RequeueAfter = wait
+	}
+
+	// 7. the state of maintenance windows on nodes is refreshed when the windows open or close
+	if wait := untilMaintenanceWindowBoundary(&nodePool, timeNow()); wait > 0 && wait < result.RequeueAfter {
+		result.RequeueAfter = wait
+	}
+
 	if needUpdate {
-		return ctrl.Result{}, r.Status().Update(ctx, &nodePool)
+		return result, r.Status().Patch(ctx, &nodePool, client.MergeFrom(original))
 	}
-	return ctrl.Result{}, nil
+	return result, nil
 }
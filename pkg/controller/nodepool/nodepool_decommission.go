@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+const (
+	// decommissionResyncPeriod is the initial period for checking the progress of pod migration, it's
+	// doubled up to decommissionMaxResyncPeriod while the migration makes no progress.
+	decommissionResyncPeriod    = 10 * time.Second
+	decommissionMaxResyncPeriod = 5 * time.Minute
+	decommissionEventReason     = "DecommissionNodePool"
+)
+
+// newDecommissionBackoff returns the backoff of checking the progress of decommission per pool.
+func newDecommissionBackoff() *flowcontrol.Backoff {
+	return flowcontrol.NewBackOff(decommissionResyncPeriod, decommissionMaxResyncPeriod)
+}
+
+// PodEvictor evicts the pod by eviction api, so PodDisruptionBudget is respected.
+type PodEvictor func(ctx context.Context, pod *corev1.Pod) error
+
+// NewPodEvictor returns a PodEvictor which evicts pods by the kube client.
+func NewPodEvictor(kubeClient kubernetes.Interface) PodEvictor {
+	return func(ctx context.Context, pod *corev1.Pod) error {
+		return kubeClient.CoreV1().Pods(pod.Namespace).Evict(ctx, &policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name},
+		})
+	}
+}
+
+// decommissionNodePool cordons and drains the nodes of the pool, and deletes the nodes after the pods
+// are migrated out of the pool or drain timeout. It returns true if the status of pool is updated.
+func (r *ReconcileNodePool) decommissionNodePool(ctx context.Context, nodePool *appsv1beta1.NodePool, nodes []corev1.Node, nodePods map[string][]corev1.Pod) (bool, error) {
+	old := nodePool.Status.Decommission.DeepCopy()
+	status := nodePool.Status.Decommission
+	if status == nil {
+		now := metav1.Now()
+		status = &appsv1beta1.NodePoolDecommissionStatus{Phase: appsv1beta1.DecommissionDraining, StartTime: &now}
+		nodePool.Status.Decommission = status
+		r.recorder.Eventf(nodePool, corev1.EventTypeNormal, decommissionEventReason, "start decommissioning the pool with %d nodes", len(nodes))
+	}
+	status.RemainingNodeNum = int32(len(nodes))
+
+	if status.Phase == appsv1beta1.DecommissionDraining {
+		var remaining int32
+		for i := range nodes {
+			if err := r.cordonNode(ctx, &nodes[i]); err != nil {
+				return false, err
+			}
+			for j := range nodePods[nodes[i].Name] {
+				pod := &nodePods[nodes[i].Name][j]
+				if !isPodMigratable(pod, &nodes[i]) {
+					continue
+				}
+				remaining++
+				if pod.DeletionTimestamp != nil {
+					continue
+				}
+				// eviction may be rejected by PodDisruptionBudget temporarily, it will be retried in the next round.
+				if err := r.evictPod(ctx, pod); err != nil && !apierrors.IsNotFound(err) {
+					klog.Warningf(Format("could not evict pod %s/%s for decommissioning pool %s, %v", pod.Namespace, pod.Name, nodePool.Name, err))
+				}
+			}
+		}
+		status.RemainingPodNum = remaining
+
+		timeout := nodePool.Spec.Decommission.DrainTimeoutSeconds
+		switch {
+		case remaining == 0:
+			status.Phase = appsv1beta1.DecommissionRemoving
+			status.Message = "all pods are migrated out of the pool"
+		case timeout > 0 && time.Since(status.StartTime.Time) > time.Duration(timeout)*time.Second:
+			status.Phase = appsv1beta1.DecommissionRemoving
+			status.Message = fmt.Sprintf("%d pods are not migrated out of the pool in %d seconds", remaining, timeout)
+			r.recorder.Eventf(nodePool, corev1.EventTypeWarning, decommissionEventReason, status.Message)
+		default:
+			status.Message = fmt.Sprintf("waiting for %d pods to be migrated out of the pool", remaining)
+		}
+	}
+
+	if status.Phase == appsv1beta1.DecommissionRemoving {
+		if len(nodes) == 0 {
+			status.Phase = appsv1beta1.DecommissionCompleted
+			status.Message = "all nodes of the pool are deleted"
+			r.recorder.Eventf(nodePool, corev1.EventTypeNormal, decommissionEventReason, "the pool is decommissioned")
+		}
+		for i := range nodes {
+			if err := r.Delete(ctx, &nodes[i]); err != nil && !apierrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+	}
+
+	return !reflect.DeepEqual(old, status), nil
+}
+
+// decommissionRequeueAfter returns the time after which the progress of decommission is checked again, zero is
+// returned if the decommission is not pending. The period is reset if the status of decommission is updated,
+// otherwise it's backed off, and it's shortened if the drain timeout comes earlier.
+func (r *ReconcileNodePool) decommissionRequeueAfter(nodePool *appsv1beta1.NodePool, updated bool, now time.Time) time.Duration {
+	status := nodePool.Status.Decommission
+	if nodePool.Spec.Decommission == nil || status == nil || status.Phase == appsv1beta1.DecommissionCompleted {
+		r.decommissionBackoff.DeleteEntry(nodePool.Name)
+		return 0
+	}
+	if updated {
+		r.decommissionBackoff.Reset(nodePool.Name)
+	}
+	r.decommissionBackoff.Next(nodePool.Name, now)
+	wait := r.decommissionBackoff.Get(nodePool.Name)
+
+	timeout := nodePool.Spec.Decommission.DrainTimeoutSeconds
+	if status.Phase == appsv1beta1.DecommissionDraining && timeout > 0 && status.StartTime != nil {
+		// check once more right after the timeout, so the nodes are removed in time
+		if untilTimeout := status.StartTime.Add(time.Duration(timeout)*time.Second + time.Second).Sub(now); untilTimeout > 0 && untilTimeout < wait {
+			wait = untilTimeout
+		}
+	}
+	return wait
+}
+
+// cordonNode marks the node unschedulable, so pods evicted from the pool are not scheduled back.
+func (r *ReconcileNodePool) cordonNode(ctx context.Context, node *corev1.Node) error {
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = true
+	return r.Patch(ctx, node, patch)
+}
+
+// isPodMigratable returns true if the pod should be migrated out of the pool before the node is deleted.
+// DaemonSet pods, static pods and the pods bound to the node by the binding annotation of pod or the
+// autonomy annotation of node are not migrated, they are removed together with the node.
+func isPodMigratable(pod *corev1.Pod, node *corev1.Node) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if len(pod.Annotations[corev1.MirrorPodAnnotationKey]) != 0 || pod.Annotations[constant.PodBindingAnnotation] == "true" {
+		return false
+	}
+	for i := range pod.OwnerReferences {
+		if pod.OwnerReferences[i].Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return node.Annotations[projectinfo.GetAutonomyAnnotation()] != "true"
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+func TestIsPodMigratable(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	autonomousNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node2",
+		Annotations: map[string]string{projectinfo.GetAutonomyAnnotation(): "true"},
+	}}
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		node   *corev1.Node
+		expect bool
+	}{
+		{
+			name:   "normal pod",
+			pod:    &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			node:   node,
+			expect: true,
+		},
+		{
+			name:   "succeeded pod",
+			pod:    &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+			node:   node,
+			expect: false,
+		},
+		{
+			name: "daemonset pod",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}},
+			}},
+			node:   node,
+			expect: false,
+		},
+		{
+			name: "static pod",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"},
+			}},
+			node:   node,
+			expect: false,
+		},
+		{
+			name: "pod bound to node",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{constant.PodBindingAnnotation: "true"},
+			}},
+			node:   node,
+			expect: false,
+		},
+		{
+			name:   "pod on autonomous node",
+			pod:    &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			node:   autonomousNode,
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPodMigratable(tt.pod, tt.node); got != tt.expect {
+				t.Errorf("expect %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestDecommissionRequeueAfter(t *testing.T) {
+	now := time.Now()
+	r := &ReconcileNodePool{decommissionBackoff: newDecommissionBackoff()}
+	nodePool := &appsv1beta1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+		Spec: appsv1beta1.NodePoolSpec{
+			Decommission: &appsv1beta1.NodePoolDecommission{},
+		},
+		Status: appsv1beta1.NodePoolStatus{
+			Decommission: &appsv1beta1.NodePoolDecommissionStatus{
+				Phase:     appsv1beta1.DecommissionDraining,
+				StartTime: &metav1.Time{Time: now},
+			},
+		},
+	}
+
+	steps := []struct {
+		name    string
+		updated bool
+		expect  time.Duration
+	}{
+		{name: "start decommission", updated: true, expect: decommissionResyncPeriod},
+		{name: "no progress", expect: 2 * decommissionResyncPeriod},
+		{name: "no progress again", expect: 4 * decommissionResyncPeriod},
+		{name: "progress resets backoff", updated: true, expect: decommissionResyncPeriod},
+	}
+	for _, s := range steps {
+		if got := r.decommissionRequeueAfter(nodePool, s.updated, now); got != s.expect {
+			t.Errorf("%s: expect requeue after %v, but got %v", s.name, s.expect, got)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		r.decommissionRequeueAfter(nodePool, false, now)
+	}
+	if got := r.decommissionRequeueAfter(nodePool, false, now); got != decommissionMaxResyncPeriod {
+		t.Errorf("expect requeue after %v at most, but got %v", decommissionMaxResyncPeriod, got)
+	}
+
+	// the drain timeout comes before the backoff
+	nodePool.Spec.Decommission.DrainTimeoutSeconds = 60
+	if got := r.decommissionRequeueAfter(nodePool, false, now); got != 61*time.Second {
+		t.Errorf("expect requeue after the drain timeout, but got %v", got)
+	}
+
+	nodePool.Status.Decommission.Phase = appsv1beta1.DecommissionCompleted
+	if got := r.decommissionRequeueAfter(nodePool, false, now); got != 0 {
+		t.Errorf("expect no requeue after decommission is completed, but got %v", got)
+	}
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/controller/nodepool/provisioner"
+)
+
+const (
+	// podNodeNameIndex is the field index of pods by spec.nodeName
+	podNodeNameIndex = "spec.nodeName"
+	// scalingResyncPeriod is the period for refreshing utilization of the pool with scaling config
+	scalingResyncPeriod   = 30 * time.Second
+	defaultScaleCooldown  = 300 * time.Second
+	scaleEventReasonScale = "ScaleNodePool"
+)
+
+// indexPodByNodeName is used for listing pods on the nodes of pool and the pending pods.
+func indexPodByNodeName(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return []string{}
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+// listNodePods lists the pods on each node of the pool.
+func (r *ReconcileNodePool) listNodePods(ctx context.Context, nodes []corev1.Node) (map[string][]corev1.Pod, error) {
+	nodePods := make(map[string][]corev1.Pod, len(nodes))
+	for i := range nodes {
+		var pods corev1.PodList
+		if err := r.List(ctx, &pods, client.MatchingFields{podNodeNameIndex: nodes[i].Name}); err != nil {
+			return nil, err
+		}
+		nodePods[nodes[i].Name] = pods.Items
+	}
+	return nodePods, nil
+}
+
+// calculateUtilization calculates the cpu and memory utilization(in percentage) of the pool by the
+// requests of pods and allocatable of nodes, and returns the node names ordered by utilization.
+func calculateUtilization(nodes []corev1.Node, nodePods map[string][]corev1.Pod) (map[corev1.ResourceName]int32, []string) {
+	type nodeUtilization struct {
+		name  string
+		value int64
+	}
+	total := map[corev1.ResourceName]*[2]int64{
+		corev1.ResourceCPU:    {},
+		corev1.ResourceMemory: {},
+	}
+	nodeUtils := make([]nodeUtilization, 0, len(nodes))
+
+	for i := range nodes {
+		requests := podsRequests(nodePods[nodes[i].Name])
+
+		var nodeValue int64
+		for name, t := range total {
+			allocatable := nodes[i].Status.Allocatable[name]
+			used := requests[name]
+			t[0] += quantityValue(name, used)
+			t[1] += quantityValue(name, allocatable)
+			if v := percentage(quantityValue(name, used), quantityValue(name, allocatable)); v > nodeValue {
+				nodeValue = v
+			}
+		}
+		nodeUtils = append(nodeUtils, nodeUtilization{name: nodes[i].Name, value: nodeValue})
+	}
+
+	utilization := make(map[corev1.ResourceName]int32)
+	for name, t := range total {
+		if t[1] != 0 {
+			utilization[name] = int32(percentage(t[0], t[1]))
+		}
+	}
+	if len(utilization) == 0 {
+		utilization = nil
+	}
+
+	sort.SliceStable(nodeUtils, func(i, j int) bool {
+		return nodeUtils[i].value < nodeUtils[j].value
+	})
+	candidates := make([]string, 0, len(nodeUtils))
+	for i := range nodeUtils {
+		candidates = append(candidates, nodeUtils[i].name)
+	}
+	return utilization, candidates
+}
+
+// countPendingPods counts the unschedulable pods which are restricted to the pool by
+// node selector or required node affinity.
+func (r *ReconcileNodePool) countPendingPods(ctx context.Context, poolName string) (int32, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.MatchingFields{podNodeNameIndex: ""}); err != nil {
+		return 0, err
+	}
+
+	var pending int32
+	for i := range pods.Items {
+		if isPodUnschedulable(&pods.Items[i]) && isPodTargetingPool(&pods.Items[i], poolName) {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// scaleNodePool calls the provisioner webhook if the pool should be scaled, and returns
+// true if the status of pool is updated.
+func (r *ReconcileNodePool) scaleNodePool(ctx context.Context, nodePool *appsv1beta1.NodePool, candidates []string) bool {
+	scaling := nodePool.Spec.Scaling
+	cooldown := defaultScaleCooldown
+	if scaling.CooldownSeconds > 0 {
+		cooldown = time.Duration(scaling.CooldownSeconds) * time.Second
+	}
+	if nodePool.Status.LastScaleTime != nil && time.Since(nodePool.Status.LastScaleTime.Time) < cooldown {
+		return false
+	}
+
+	delta, reason := desiredScaling(scaling, int32(len(nodePool.Status.Nodes)), nodePool.Status.PendingPodNum, nodePool.Status.Utilization)
+	if delta == 0 {
+		return false
+	}
+
+	req := &provisioner.ScaleRequest{
+		NodePool:      nodePool.Name,
+		Type:          nodePool.Spec.Type,
+		Delta:         delta,
+		Reason:        reason,
+		Nodes:         nodePool.Status.Nodes,
+		PendingPodNum: nodePool.Status.PendingPodNum,
+		Utilization:   nodePool.Status.Utilization,
+	}
+	if delta < 0 {
+		req.CandidateNodes = candidates
+	}
+
+	// the last scale time is updated even if the webhook fails, so the webhook is not called too frequently.
+	now := metav1.Now()
+	nodePool.Status.LastScaleTime = &now
+
+	p, err := r.newProvisioner(scaling)
+	if err != nil {
+		r.recorder.Eventf(nodePool, corev1.EventTypeWarning, scaleEventReasonScale, "could not create provisioner, %v", err)
+		return true
+	}
+	resp, err := p.Scale(ctx, req)
+	if err != nil {
+		klog.Errorf(Format("could not call provisioner webhook for pool %s, %v", nodePool.Name, err))
+		r.recorder.Eventf(nodePool, corev1.EventTypeWarning, scaleEventReasonScale, "could not call provisioner webhook, %v", err)
+		return true
+	}
+	if !resp.Accepted {
+		r.recorder.Eventf(nodePool, corev1.EventTypeWarning, scaleEventReasonScale, "scaling by %d nodes(%s) is rejected by provisioner, %s", delta, reason, resp.Message)
+		return true
+	}
+	r.recorder.Eventf(nodePool, corev1.EventTypeNormal, scaleEventReasonScale, "scaling by %d nodes(%s) is accepted by provisioner, %s", delta, reason, resp.Message)
+	return true
+}
+
+// desiredScaling returns the number of nodes that should be added(positive) or removed(negative)
+// and the reason. The pool is scaled by one node at a time, except for keeping the number of nodes
+// between MinNodes and MaxNodes.
+func desiredScaling(scaling *appsv1beta1.NodePoolScaling, nodeNum, pendingPodNum int32, utilization map[corev1.ResourceName]int32) (int32, string) {
+	if nodeNum < scaling.MinNodes {
+		return scaling.MinNodes - nodeNum, fmt.Sprintf("the number of nodes %d is less than min nodes %d", nodeNum, scaling.MinNodes)
+	}
+	if scaling.MaxNodes > 0 && nodeNum > scaling.MaxNodes {
+		return scaling.MaxNodes - nodeNum, fmt.Sprintf("the number of nodes %d is more than max nodes %d", nodeNum, scaling.MaxNodes)
+	}
+
+	if pendingPodNum > 0 {
+		if scaling.MaxNodes == 0 || nodeNum < scaling.MaxNodes {
+			return 1, fmt.Sprintf("%d pods are pending for the pool", pendingPodNum)
+		}
+		return 0, ""
+	}
+
+	threshold := scaling.ScaleDownUtilizationThreshold
+	if threshold > 0 && nodeNum > 0 && nodeNum > scaling.MinNodes &&
+		utilization[corev1.ResourceCPU] < threshold && utilization[corev1.ResourceMemory] < threshold {
+		return -1, fmt.Sprintf("cpu and memory utilization are below %d%%", threshold)
+	}
+	return 0, ""
+}
+
+// conciliateScalingStatus will update the utilization and pending pods of nodepool status if necessary
+func conciliateScalingStatus(utilization map[corev1.ResourceName]int32, pendingPodNum int32, nodePool *appsv1beta1.NodePool) (needUpdate bool) {
+	if !reflect.DeepEqual(utilization, nodePool.Status.Utilization) {
+		nodePool.Status.Utilization = utilization
+		needUpdate = true
+	}
+	if pendingPodNum != nodePool.Status.PendingPodNum {
+		nodePool.Status.PendingPodNum = pendingPodNum
+		needUpdate = true
+	}
+	return needUpdate
+}
+
+// podsRequests sums the resource requests of the running pods.
+func podsRequests(pods []corev1.Pod) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodSucceeded || pods[i].Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, c := range pods[i].Spec.Containers {
+			for name, q := range c.Resources.Requests {
+				sum := requests[name]
+				sum.Add(q)
+				requests[name] = sum
+			}
+		}
+	}
+	return requests
+}
+
+func isPodUnschedulable(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodPending || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+func isPodTargetingPool(pod *corev1.Pod, poolName string) bool {
+	if pod.Spec.NodeSelector[apps.LabelCurrentNodePool] == poolName {
+		return true
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil ||
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key != apps.LabelCurrentNodePool || expr.Operator != corev1.NodeSelectorOpIn {
+				continue
+			}
+			for _, v := range expr.Values {
+				if v == poolName {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func quantityValue(name corev1.ResourceName, q resource.Quantity) int64 {
+	if name == corev1.ResourceCPU {
+		return q.MilliValue()
+	}
+	return q.Value()
+}
+
+func percentage(used, total int64) int64 {
+	if total == 0 {
+		return 0
+	}
+	return used * 100 / total
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestDesiredScaling(t *testing.T) {
+	scaling := &appsv1beta1.NodePoolScaling{
+		MinNodes:                      1,
+		MaxNodes:                      3,
+		ScaleDownUtilizationThreshold: 30,
+	}
+	tests := []struct {
+		name        string
+		nodeNum     int32
+		pending     int32
+		utilization map[corev1.ResourceName]int32
+		expectDelta int32
+	}{
+		{name: "less than min nodes", nodeNum: 0, expectDelta: 1},
+		{name: "more than max nodes", nodeNum: 5, expectDelta: -2},
+		{name: "pending pods", nodeNum: 2, pending: 3, expectDelta: 1},
+		{name: "pending pods with max nodes", nodeNum: 3, pending: 3, expectDelta: 0},
+		{
+			name:        "low utilization",
+			nodeNum:     2,
+			utilization: map[corev1.ResourceName]int32{corev1.ResourceCPU: 10, corev1.ResourceMemory: 20},
+			expectDelta: -1,
+		},
+		{
+			name:        "low utilization with min nodes",
+			nodeNum:     1,
+			utilization: map[corev1.ResourceName]int32{corev1.ResourceCPU: 10, corev1.ResourceMemory: 20},
+			expectDelta: 0,
+		},
+		{
+			name:        "high memory utilization",
+			nodeNum:     2,
+			utilization: map[corev1.ResourceName]int32{corev1.ResourceCPU: 10, corev1.ResourceMemory: 80},
+			expectDelta: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, _ := desiredScaling(scaling, tt.nodeNum, tt.pending, tt.utilization)
+			if delta != tt.expectDelta {
+				t.Errorf("expect delta %d, but got %d", tt.expectDelta, delta)
+			}
+		})
+	}
+}
+
+func TestPendingPodForPool(t *testing.T) {
+	unschedulable := corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{
+			{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable},
+		},
+	}
+	bySelector := &corev1.Pod{
+		Spec:   corev1.PodSpec{NodeSelector: map[string]string{apps.LabelCurrentNodePool: "hangzhou"}},
+		Status: unschedulable,
+	}
+	byAffinity := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								{Key: apps.LabelCurrentNodePool, Operator: corev1.NodeSelectorOpIn, Values: []string{"beijing", "hangzhou"}},
+							},
+						}},
+					},
+				},
+			},
+		},
+		Status: unschedulable,
+	}
+	scheduling := &corev1.Pod{
+		Spec:   corev1.PodSpec{NodeSelector: map[string]string{apps.LabelCurrentNodePool: "hangzhou"}},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+
+	if !isPodUnschedulable(bySelector) || !isPodTargetingPool(bySelector, "hangzhou") {
+		t.Errorf("pod with pool node selector should be pending for pool")
+	}
+	if !isPodUnschedulable(byAffinity) || !isPodTargetingPool(byAffinity, "hangzhou") {
+		t.Errorf("pod with pool node affinity should be pending for pool")
+	}
+	if isPodTargetingPool(bySelector, "beijing") {
+		t.Errorf("pod should not be pending for other pool")
+	}
+	if isPodUnschedulable(scheduling) {
+		t.Errorf("pod which is not marked as unschedulable should not be counted")
+	}
+}
+
+func TestPodsRequests(t *testing.T) {
+	pods := []corev1.Pod{
+		{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}}},
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}},
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+	}
+	requests := podsRequests(pods)
+	if cpu := requests[corev1.ResourceCPU]; cpu.MilliValue() != 750 {
+		t.Errorf("expect 750m cpu requests, but got %s", cpu.String())
+	}
+}
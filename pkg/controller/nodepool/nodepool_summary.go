@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+// summaryResyncPeriod is the period for recalculating the resource summary of the pool
+var summaryResyncPeriod = 60 * time.Second
+
+// summarizeResources aggregates the allocatable of nodes, the requests and number of pods,
+// and the connectivity state of nodes in the pool.
+func summarizeResources(nodes []corev1.Node, nodePods map[string][]corev1.Pod) *appsv1beta1.NodePoolResourceSummary {
+	summary := &appsv1beta1.NodePoolResourceSummary{
+		Allocatable: corev1.ResourceList{},
+		Requested:   corev1.ResourceList{},
+	}
+	for i := range nodes {
+		for name, q := range nodes[i].Status.Allocatable {
+			sum := summary.Allocatable[name]
+			sum.Add(q)
+			summary.Allocatable[name] = sum
+		}
+
+		pods := nodePods[nodes[i].Name]
+		for j := range pods {
+			if pods[j].Status.Phase != corev1.PodSucceeded && pods[j].Status.Phase != corev1.PodFailed {
+				summary.PodNum++
+			}
+		}
+		for name, q := range podsRequests(pods) {
+			sum := summary.Requested[name]
+			sum.Add(q)
+			summary.Requested[name] = sum
+		}
+
+		if isNodeConnected(&nodes[i]) {
+			summary.ConnectedNodeNum++
+		} else {
+			summary.DisconnectedNodeNum++
+		}
+	}
+	return summary
+}
+
+// isNodeConnected returns false if the ready condition of node is unknown, which means
+// the node status has not been reported to cloud by kubelet for a while.
+func isNodeConnected(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status != corev1.ConditionUnknown
+		}
+	}
+	return false
+}
+
+// conciliateResourceSummary will update the resource summary of nodepool status if the summary is
+// changed, so the status isn't written when the pool is resynced without changes.
+func conciliateResourceSummary(summary *appsv1beta1.NodePoolResourceSummary, nodePool *appsv1beta1.NodePool) bool {
+	current := nodePool.Status.ResourceSummary
+	if current != nil && resourceSummaryEqual(summary, current) {
+		return false
+	}
+
+	now := metav1.Now()
+	summary.LastUpdateTime = &now
+	nodePool.Status.ResourceSummary = summary
+	return true
+}
+
+func resourceSummaryEqual(a, b *appsv1beta1.NodePoolResourceSummary) bool {
+	return a.PodNum == b.PodNum &&
+		a.ConnectedNodeNum == b.ConnectedNodeNum &&
+		a.DisconnectedNodeNum == b.DisconnectedNodeNum &&
+		quotav1.Equals(a.Allocatable, b.Allocatable) &&
+		quotav1.Equals(a.Requested, b.Requested)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestSummarizeResources(t *testing.T) {
+	newNode := func(name string, ready corev1.ConditionStatus) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		}
+	}
+	newPod := func(phase corev1.PodPhase, cpu string) corev1.Pod {
+		return corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	nodes := []corev1.Node{
+		newNode("node1", corev1.ConditionTrue),
+		newNode("node2", corev1.ConditionFalse),
+		newNode("node3", corev1.ConditionUnknown),
+	}
+	nodePods := map[string][]corev1.Pod{
+		"node1": {newPod(corev1.PodRunning, "500m"), newPod(corev1.PodSucceeded, "1")},
+		"node3": {newPod(corev1.PodRunning, "1")},
+	}
+
+	summary := summarizeResources(nodes, nodePods)
+	expect := &appsv1beta1.NodePoolResourceSummary{
+		Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("6"),
+			corev1.ResourceMemory: resource.MustParse("12Gi"),
+		},
+		Requested: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1500m"),
+		},
+		PodNum:              2,
+		ConnectedNodeNum:    2,
+		DisconnectedNodeNum: 1,
+	}
+	if !resourceSummaryEqual(expect, summary) {
+		t.Errorf("expect summary %v, but got %v", expect, summary)
+	}
+}
+
+func TestConciliateResourceSummary(t *testing.T) {
+	summary := &appsv1beta1.NodePoolResourceSummary{PodNum: 1, ConnectedNodeNum: 1}
+	stale := metav1.NewTime(time.Now().Add(-2 * summaryResyncPeriod))
+	tests := []struct {
+		name         string
+		current      *appsv1beta1.NodePoolResourceSummary
+		expectUpdate bool
+	}{
+		{name: "no summary", expectUpdate: true},
+		{name: "summary is not changed", current: &appsv1beta1.NodePoolResourceSummary{PodNum: 1, ConnectedNodeNum: 1, LastUpdateTime: &stale}},
+		{name: "summary is changed", current: &appsv1beta1.NodePoolResourceSummary{PodNum: 2, ConnectedNodeNum: 1, LastUpdateTime: &stale}, expectUpdate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			np := &appsv1beta1.NodePool{Status: appsv1beta1.NodePoolStatus{ResourceSummary: tt.current}}
+			if got := conciliateResourceSummary(summary.DeepCopy(), np); got != tt.expectUpdate {
+				t.Errorf("expect update %v, but got %v", tt.expectUpdate, got)
+			}
+			if tt.expectUpdate && np.Status.ResourceSummary.LastUpdateTime == nil {
+				t.Errorf("expect last update time is set")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// maxResponseSize is the max size of response body read from the webhook
+	maxResponseSize = 1 << 20
+)
+
+// ScaleRequest is sent to the provisioner webhook for adding or removing nodes of a pool.
+type ScaleRequest struct {
+	// NodePool is the name of pool
+	NodePool string `json:"nodePool"`
+	// Type is the type of pool, Edge or Cloud
+	Type appsv1beta1.NodePoolType `json:"type"`
+	// Delta is the number of nodes to add(positive) or remove(negative)
+	Delta int32 `json:"delta"`
+	// Reason describes why the pool should be scaled
+	Reason string `json:"reason"`
+	// Nodes are the nodes in the pool currently
+	Nodes []string `json:"nodes"`
+	// CandidateNodes are the suggested nodes to remove when Delta is negative, ordered by utilization
+	CandidateNodes []string `json:"candidateNodes,omitempty"`
+	// PendingPodNum is the number of pods waiting for nodes of the pool
+	PendingPodNum int32 `json:"pendingPodNum"`
+	// Utilization is the resource utilization(in percentage) of the pool
+	Utilization map[corev1.ResourceName]int32 `json:"utilization,omitempty"`
+}
+
+// ScaleResponse is returned by the provisioner webhook.
+type ScaleResponse struct {
+	// Accepted means the provisioner will add or remove the nodes
+	Accepted bool `json:"accepted"`
+	// Message is the reason of rejection or other information of the request
+	Message string `json:"message,omitempty"`
+}
+
+// Provisioner adds or removes the nodes of pool, it is implemented by the
+// external systems like MAAS or vendor APIs.
+type Provisioner interface {
+	Scale(ctx context.Context, req *ScaleRequest) (*ScaleResponse, error)
+}
+
+// Factory creates a provisioner for the scaling config of pool.
+type Factory func(scaling *appsv1beta1.NodePoolScaling) (Provisioner, error)
+
+type webhookProvisioner struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookProvisioner returns a provisioner which sends the scaling requests to the provisioner webhook.
+func NewWebhookProvisioner(scaling *appsv1beta1.NodePoolScaling) (Provisioner, error) {
+	if scaling == nil || len(scaling.Provisioner.URL) == 0 {
+		return nil, fmt.Errorf("provisioner webhook url is not specified")
+	}
+
+	timeout := defaultTimeout
+	if scaling.Provisioner.TimeoutSeconds > 0 {
+		timeout = time.Duration(scaling.Provisioner.TimeoutSeconds) * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(scaling.Provisioner.CABundle) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(scaling.Provisioner.CABundle) {
+			return nil, fmt.Errorf("could not parse ca bundle of provisioner webhook")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &webhookProvisioner{
+		url: scaling.Provisioner.URL,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// Scale sends the scaling request to webhook by POST method.
+func (p *webhookProvisioner) Scale(ctx context.Context, req *ScaleRequest) (*ScaleResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provisioner webhook returns status code %d, %s", resp.StatusCode, string(content))
+	}
+
+	scaleResp := &ScaleResponse{}
+	if err := json.Unmarshal(content, scaleResp); err != nil {
+		return nil, fmt.Errorf("could not decode response of provisioner webhook, %w", err)
+	}
+	return scaleResp, nil
+}
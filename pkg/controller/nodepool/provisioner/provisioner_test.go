@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestWebhookProvisioner(t *testing.T) {
+	var got ScaleRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(&ScaleResponse{Accepted: true, Message: "machine is provisioning"})
+	}))
+	defer server.Close()
+
+	p, err := NewWebhookProvisioner(&appsv1beta1.NodePoolScaling{
+		Provisioner: appsv1beta1.ProvisionerWebhook{URL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("failed to create provisioner, %v", err)
+	}
+	resp, err := p.Scale(context.TODO(), &ScaleRequest{NodePool: "hangzhou", Delta: 1, PendingPodNum: 2})
+	if err != nil {
+		t.Fatalf("failed to scale, %v", err)
+	}
+	if !resp.Accepted || resp.Message != "machine is provisioning" {
+		t.Errorf("unexpected response %v", resp)
+	}
+	if got.NodePool != "hangzhou" || got.Delta != 1 || got.PendingPodNum != 2 {
+		t.Errorf("unexpected request %v", got)
+	}
+}
+
+func TestWebhookProvisionerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	p, err := NewWebhookProvisioner(&appsv1beta1.NodePoolScaling{
+		Provisioner: appsv1beta1.ProvisionerWebhook{URL: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("failed to create provisioner, %v", err)
+	}
+	if _, err := p.Scale(context.TODO(), &ScaleRequest{NodePool: "hangzhou", Delta: 1}); err == nil {
+		t.Errorf("expect error when webhook returns 500, but got nil")
+	}
+
+	if _, err := NewWebhookProvisioner(&appsv1beta1.NodePoolScaling{}); err == nil {
+		t.Errorf("expect error when url is empty, but got nil")
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -33,10 +35,41 @@ import (
 
 	"github.com/openyurtio/openyurt/pkg/apis/apps"
 	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/util/maintenancewindow"
 )
 
 var timeSleep = time.Sleep
 
+var timeNow = time.Now
+
+// maintenanceWindowState returns whether the maintenance windows of pool are open, the windows are
+// closed if they are invalid, so the software of nodes is never changed unexpectedly.
+func maintenanceWindowState(nodePool *appsv1beta1.NodePool, now time.Time) string {
+	in, err := maintenancewindow.InWindows(nodePool.Spec.MaintenanceWindows, now)
+	if err != nil {
+		klog.Errorf(Format("maintenance windows of NodePool %s are invalid, %v", nodePool.Name, err))
+		return apps.MaintenanceWindowClosed
+	}
+	if in {
+		return apps.MaintenanceWindowOpen
+	}
+	return apps.MaintenanceWindowClosed
+}
+
+// untilMaintenanceWindowBoundary returns the duration until the maintenance windows of pool open
+// or close, 0 is returned if the pool has no valid windows or no window starts within a year.
+func untilMaintenanceWindowBoundary(nodePool *appsv1beta1.NodePool, now time.Time) time.Duration {
+	if len(nodePool.Spec.MaintenanceWindows) == 0 {
+		return 0
+	}
+	next, err := maintenancewindow.NextBoundary(nodePool.Spec.MaintenanceWindows, now)
+	if err != nil || next.IsZero() {
+		return 0
+	}
+	// requeue a moment after the boundary, so the state of windows is changed when reconciling
+	return next.Sub(now) + time.Second
+}
+
 // createNodePool creates an nodepool, it will retry 5 times if it fails
 func createNodePool(c client.Client, name string,
 	poolType appsv1beta1.NodePoolType) bool {
@@ -74,46 +107,77 @@ func createDefaultNodePool(client client.Client) {
 }
 
 // conciliatePoolRelatedAttrs will update the node's attributes that related to
-// the nodepool
-func concilateNode(node *corev1.Node, nodePool appsv1beta1.NodePool) (attrUpdated bool, err error) {
+// the nodepool. The attributes applied by the pool are recorded in the node annotation
+// as ownership record, only the owned attributes are updated or removed later, so the
+// attributes managed by other systems are not overwritten unless the conflict policy is Force.
+// The attributes conflicting with the values managed by others are returned as conflicts.
+func concilateNode(node *corev1.Node, nodePool appsv1beta1.NodePool) (attrUpdated bool, conflicts []string, err error) {
 	// update node attr
 	npra := NodePoolRelatedAttributes{
 		Labels:      nodePool.Spec.Labels,
 		Annotations: nodePool.Spec.Annotations,
 		Taints:      nodePool.Spec.Taints,
 	}
+	if nodePool.Spec.PodTolerationSeconds != nil {
+		npra.Annotations = mergeMap(mergeMap(nil, npra.Annotations), map[string]string{
+			apps.AnnotationPodTolerationSeconds: strconv.FormatInt(*nodePool.Spec.PodTolerationSeconds, 10),
+		})
+	}
+	if len(nodePool.Spec.MaintenanceWindows) != 0 {
+		npra.Annotations = mergeMap(mergeMap(nil, npra.Annotations), map[string]string{
+			apps.AnnotationMaintenanceWindow: maintenanceWindowState(&nodePool, timeNow()),
+		})
+	}
+	if len(nodePool.Spec.TrafficProfile) != 0 {
+		npra.Annotations = mergeMap(mergeMap(nil, npra.Annotations), map[string]string{
+			apps.AnnotationTrafficProfile: nodePool.Spec.TrafficProfile,
+		})
+	}
+	if nodePool.Spec.Egress != nil {
+		policy, err := json.Marshal(nodePool.Spec.Egress)
+		if err != nil {
+			return false, nil, err
+		}
+		npra.Annotations = mergeMap(mergeMap(nil, npra.Annotations), map[string]string{
+			apps.AnnotationEgressPolicy: string(policy),
+		})
+	}
 
-	if preAttrs, exist := node.Annotations[apps.AnnotationPrevAttrs]; !exist {
-		node.Labels = mergeMap(node.Labels, npra.Labels)
-		node.Annotations = mergeMap(node.Annotations, npra.Annotations)
-		for _, npt := range npra.Taints {
-			for i, nt := range node.Spec.Taints {
-				if npt.Effect == nt.Effect && npt.Key == nt.Key {
-					node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
-					break
-				}
-			}
-			node.Spec.Taints = append(node.Spec.Taints, npt)
+	var preNpra NodePoolRelatedAttributes
+	preAttrs, exist := node.Annotations[apps.AnnotationPrevAttrs]
+	if exist {
+		if err := json.Unmarshal([]byte(preAttrs), &preNpra); err != nil {
+			return attrUpdated, conflicts, err
 		}
-		if err := cachePrevPoolAttrs(node, npra); err != nil {
-			return attrUpdated, err
+	}
+
+	policy := nodePool.Spec.ConflictPolicy
+	ownedNpra, conflicts := resolveOwnership(node, preNpra, npra, policy)
+	if len(conflicts) != 0 && policy == appsv1beta1.ConflictPolicyIgnore {
+		// keep node attributes untouched until conflicts are resolved
+		klog.V(4).Infof(Format("skip updating attributes of node %s, conflicts: %v", node.Name, conflicts))
+	} else if !exist || !reflect.DeepEqual(preNpra, ownedNpra) {
+		// pool related attributes will be updated
+		conciliateLabels(node, preNpra.Labels, ownedNpra.Labels)
+		conciliateAnnotations(node, preNpra.Annotations, ownedNpra.Annotations)
+		conciliateTaints(node, preNpra.Taints, ownedNpra.Taints)
+		if err := cachePrevPoolAttrs(node, ownedNpra); err != nil {
+			return attrUpdated, conflicts, err
 		}
 		attrUpdated = true
-	} else {
-		var preNpra NodePoolRelatedAttributes
-		if err := json.Unmarshal([]byte(preAttrs), &preNpra); err != nil {
-			return attrUpdated, err
-		}
-		if !reflect.DeepEqual(preNpra, npra) {
-			// pool related attributes will be updated
-			conciliateLabels(node, preNpra.Labels, npra.Labels)
-			conciliateAnnotations(node, preNpra.Annotations, npra.Annotations)
-			conciliateTaints(node, preNpra.Taints, npra.Taints)
-			if err := cachePrevPoolAttrs(node, npra); err != nil {
-				return attrUpdated, err
+	}
+
+	// record the conflicts on node, so they are only reported when they change
+	if value := strings.Join(conflicts, ","); node.Annotations[apps.AnnotationAttributeConflicts] != value {
+		if len(value) == 0 {
+			delete(node.Annotations, apps.AnnotationAttributeConflicts)
+		} else {
+			if node.Annotations == nil {
+				node.Annotations = make(map[string]string)
 			}
-			attrUpdated = true
+			node.Annotations[apps.AnnotationAttributeConflicts] = value
 		}
+		attrUpdated = true
 	}
 
 	// update ownerLabel
@@ -124,7 +188,61 @@ func concilateNode(node *corev1.Node, nodePool appsv1beta1.NodePool) (attrUpdate
 		node.Labels[apps.LabelCurrentNodePool] = nodePool.GetName()
 		attrUpdated = true
 	}
-	return attrUpdated, nil
+	return attrUpdated, conflicts, nil
+}
+
+// resolveOwnership calculates the attributes that should be owned by the pool. An attribute is
+// conflicting if the node has the same key with a different value and the key is not owned by the pool
+// before, and the conflicting attribute is owned by the pool only when the conflict policy is Force.
+func resolveOwnership(node *corev1.Node, owned, desired NodePoolRelatedAttributes, policy appsv1beta1.ConflictPolicy) (NodePoolRelatedAttributes, []string) {
+	var result NodePoolRelatedAttributes
+	var conflicts []string
+	force := len(policy) == 0 || policy == appsv1beta1.ConflictPolicyForce
+
+	for k, v := range desired.Labels {
+		if _, ok := owned.Labels[k]; !ok {
+			if nv, exist := node.Labels[k]; exist && nv != v {
+				conflicts = append(conflicts, "label "+k)
+				if !force {
+					continue
+				}
+			}
+		}
+		if result.Labels == nil {
+			result.Labels = make(map[string]string)
+		}
+		result.Labels[k] = v
+	}
+
+	for k, v := range desired.Annotations {
+		if _, ok := owned.Annotations[k]; !ok {
+			if nv, exist := node.Annotations[k]; exist && nv != v {
+				conflicts = append(conflicts, "annotation "+k)
+				if !force {
+					continue
+				}
+			}
+		}
+		if result.Annotations == nil {
+			result.Annotations = make(map[string]string)
+		}
+		result.Annotations[k] = v
+	}
+
+	for _, t := range desired.Taints {
+		if _, ok := containTaint(t, owned.Taints); !ok {
+			if i, exist := containTaint(t, node.Spec.Taints); exist && node.Spec.Taints[i].Value != t.Value {
+				conflicts = append(conflicts, "taint "+t.Key+":"+string(t.Effect))
+				if !force {
+					continue
+				}
+			}
+		}
+		result.Taints = append(result.Taints, t)
+	}
+
+	sort.Strings(conflicts)
+	return result, conflicts
 }
 
 // getRemovedNodes calculates removed nodes from current nodes and desired nodes
@@ -180,6 +298,7 @@ func removePoolRelatedAttrs(node *corev1.Node) error {
 		}
 	}
 	delete(node.Annotations, apps.AnnotationPrevAttrs)
+	delete(node.Annotations, apps.AnnotationAttributeConflicts)
 	delete(node.Labels, apps.LabelCurrentNodePool)
 
 	return nil
@@ -227,6 +346,7 @@ func conciliateTaints(node *corev1.Node, oldTaints, newTaints []corev1.Taint) {
 
 	// 2. update the node taints based on the latest node pool taints
 	for _, nt := range newTaints {
+		node.Spec.Taints = removeTaint(nt, node.Spec.Taints)
 		node.Spec.Taints = append(node.Spec.Taints, nt)
 	}
 }
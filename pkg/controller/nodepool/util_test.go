@@ -0,0 +1,286 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepool
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func newTestNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Labels: map[string]string{
+				"topology.kubernetes.io/region": "cloud-region",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: "node.cloudprovider.kubernetes.io/uninitialized", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+}
+
+func newTestNodePool(policy appsv1beta1.ConflictPolicy) appsv1beta1.NodePool {
+	return appsv1beta1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "hangzhou"},
+		Spec: appsv1beta1.NodePoolSpec{
+			Labels: map[string]string{
+				"topology.kubernetes.io/region": "hangzhou",
+				"site":                          "hangzhou",
+			},
+			Taints: []corev1.Taint{
+				{Key: "node.cloudprovider.kubernetes.io/uninitialized", Value: "false", Effect: corev1.TaintEffectNoSchedule},
+			},
+			ConflictPolicy: policy,
+		},
+	}
+}
+
+func TestConcilateNodeConflictPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          appsv1beta1.ConflictPolicy
+		expectRegion    string
+		expectSite      string
+		expectTaint     string
+		expectConflicts []string
+	}{
+		{
+			name:            "force overwrites conflicting values",
+			policy:          appsv1beta1.ConflictPolicyForce,
+			expectRegion:    "hangzhou",
+			expectSite:      "hangzhou",
+			expectTaint:     "false",
+			expectConflicts: []string{"label topology.kubernetes.io/region", "taint node.cloudprovider.kubernetes.io/uninitialized:NoSchedule"},
+		},
+		{
+			name:            "merge keeps conflicting values",
+			policy:          appsv1beta1.ConflictPolicyMerge,
+			expectRegion:    "cloud-region",
+			expectSite:      "hangzhou",
+			expectTaint:     "true",
+			expectConflicts: []string{"label topology.kubernetes.io/region", "taint node.cloudprovider.kubernetes.io/uninitialized:NoSchedule"},
+		},
+		{
+			name:            "ignore keeps node untouched",
+			policy:          appsv1beta1.ConflictPolicyIgnore,
+			expectRegion:    "cloud-region",
+			expectSite:      "",
+			expectTaint:     "true",
+			expectConflicts: []string{"label topology.kubernetes.io/region", "taint node.cloudprovider.kubernetes.io/uninitialized:NoSchedule"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newTestNode()
+			_, conflicts, err := concilateNode(node, newTestNodePool(tt.policy))
+			if err != nil {
+				t.Fatalf("failed to concilate node, %v", err)
+			}
+			if !reflect.DeepEqual(conflicts, tt.expectConflicts) {
+				t.Errorf("expect conflicts %v, but got %v", tt.expectConflicts, conflicts)
+			}
+			if got := node.Labels["topology.kubernetes.io/region"]; got != tt.expectRegion {
+				t.Errorf("expect region label %q, but got %q", tt.expectRegion, got)
+			}
+			if got := node.Labels["site"]; got != tt.expectSite {
+				t.Errorf("expect site label %q, but got %q", tt.expectSite, got)
+			}
+			if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Value != tt.expectTaint {
+				t.Errorf("expect one taint with value %q, but got %v", tt.expectTaint, node.Spec.Taints)
+			}
+			if node.Labels[apps.LabelCurrentNodePool] != "hangzhou" {
+				t.Errorf("expect node in pool hangzhou, but got %q", node.Labels[apps.LabelCurrentNodePool])
+			}
+		})
+	}
+}
+
+func TestMergeOwnershipRecord(t *testing.T) {
+	node := newTestNode()
+	if _, _, err := concilateNode(node, newTestNodePool(appsv1beta1.ConflictPolicyMerge)); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+
+	var owned NodePoolRelatedAttributes
+	if err := json.Unmarshal([]byte(node.Annotations[apps.AnnotationPrevAttrs]), &owned); err != nil {
+		t.Fatalf("failed to decode ownership record, %v", err)
+	}
+	expect := NodePoolRelatedAttributes{Labels: map[string]string{"site": "hangzhou"}}
+	if !reflect.DeepEqual(owned, expect) {
+		t.Errorf("expect ownership record %v, but got %v", expect, owned)
+	}
+	expectConflicts := "label topology.kubernetes.io/region,taint node.cloudprovider.kubernetes.io/uninitialized:NoSchedule"
+	if got := node.Annotations[apps.AnnotationAttributeConflicts]; got != expectConflicts {
+		t.Errorf("expect conflicts annotation %q, but got %q", expectConflicts, got)
+	}
+
+	// the node is not updated again if the conflicts don't change
+	if updated, _, err := concilateNode(node, newTestNodePool(appsv1beta1.ConflictPolicyMerge)); err != nil || updated {
+		t.Errorf("expect node not updated, but got updated %v, err %v", updated, err)
+	}
+
+	// the attributes managed by others are kept when node is removed from the pool
+	if err := removePoolRelatedAttrs(node); err != nil {
+		t.Fatalf("failed to remove pool attributes, %v", err)
+	}
+	if node.Labels["topology.kubernetes.io/region"] != "cloud-region" || len(node.Spec.Taints) != 1 {
+		t.Errorf("attributes managed by others should be kept, got labels %v and taints %v", node.Labels, node.Spec.Taints)
+	}
+	if _, ok := node.Labels["site"]; ok {
+		t.Errorf("label owned by pool should be removed, got labels %v", node.Labels)
+	}
+	if _, ok := node.Annotations[apps.AnnotationAttributeConflicts]; ok {
+		t.Errorf("conflicts annotation should be removed, got annotations %v", node.Annotations)
+	}
+}
+
+func TestConcilateNodeTrafficProfile(t *testing.T) {
+	np := newTestNodePool(appsv1beta1.ConflictPolicyForce)
+	np.Spec.TrafficProfile = "satellite"
+
+	node := newTestNode()
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if node.Annotations[apps.AnnotationTrafficProfile] != "satellite" {
+		t.Errorf("expect traffic profile on node, but got %v", node.Annotations)
+	}
+
+	np.Spec.TrafficProfile = ""
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if _, ok := node.Annotations[apps.AnnotationTrafficProfile]; ok {
+		t.Errorf("traffic profile should be removed from node, but got %v", node.Annotations)
+	}
+}
+
+func TestConcilateNodeEgressPolicy(t *testing.T) {
+	np := newTestNodePool(appsv1beta1.ConflictPolicyForce)
+	np.Spec.Egress = &appsv1beta1.NodePoolEgressPolicy{
+		Destinations:   []string{"10.0.0.0/8", "*.example.com:443"},
+		BandwidthClass: appsv1beta1.EgressBandwidthLow,
+	}
+
+	node := newTestNode()
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	expected := `{"destinations":["10.0.0.0/8","*.example.com:443"],"bandwidthClass":"Low"}`
+	if node.Annotations[apps.AnnotationEgressPolicy] != expected {
+		t.Errorf("expect egress policy %s on node, but got %v", expected, node.Annotations)
+	}
+
+	np.Spec.Egress = nil
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if _, ok := node.Annotations[apps.AnnotationEgressPolicy]; ok {
+		t.Errorf("egress policy should be removed from node, but got %v", node.Annotations)
+	}
+}
+
+func TestConcilateNodePodTolerationSeconds(t *testing.T) {
+	seconds := int64(3600)
+	np := newTestNodePool(appsv1beta1.ConflictPolicyForce)
+	np.Spec.Annotations = map[string]string{"site": "hangzhou"}
+	np.Spec.PodTolerationSeconds = &seconds
+
+	node := newTestNode()
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if node.Annotations[apps.AnnotationPodTolerationSeconds] != "3600" || node.Annotations["site"] != "hangzhou" {
+		t.Errorf("expect toleration seconds and pool annotations on node, but got %v", node.Annotations)
+	}
+	if _, ok := np.Spec.Annotations[apps.AnnotationPodTolerationSeconds]; ok {
+		t.Errorf("annotations of nodepool spec should not be changed")
+	}
+
+	np.Spec.PodTolerationSeconds = nil
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if _, ok := node.Annotations[apps.AnnotationPodTolerationSeconds]; ok {
+		t.Errorf("toleration seconds should be removed from node, but got %v", node.Annotations)
+	}
+}
+
+func TestConcilateNodeMaintenanceWindow(t *testing.T) {
+	now := time.Date(2023, 7, 1, 2, 30, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	np := newTestNodePool(appsv1beta1.ConflictPolicyForce)
+	np.Spec.MaintenanceWindows = []appsv1beta1.NodePoolMaintenanceWindow{{
+		Schedule: "0 2 * * 6",
+		Duration: metav1.Duration{Duration: time.Hour},
+	}}
+
+	node := newTestNode()
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if node.Annotations[apps.AnnotationMaintenanceWindow] != apps.MaintenanceWindowOpen {
+		t.Errorf("expect maintenance window open, but got %v", node.Annotations)
+	}
+
+	now = now.Add(time.Hour)
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if node.Annotations[apps.AnnotationMaintenanceWindow] != apps.MaintenanceWindowClosed {
+		t.Errorf("expect maintenance window closed, but got %v", node.Annotations)
+	}
+
+	np.Spec.MaintenanceWindows = nil
+	if _, _, err := concilateNode(node, np); err != nil {
+		t.Fatalf("failed to concilate node, %v", err)
+	}
+	if _, ok := node.Annotations[apps.AnnotationMaintenanceWindow]; ok {
+		t.Errorf("maintenance window should be removed from node, but got %v", node.Annotations)
+	}
+}
+
+func TestUntilMaintenanceWindowBoundary(t *testing.T) {
+	np := newTestNodePool(appsv1beta1.ConflictPolicyForce)
+	if wait := untilMaintenanceWindowBoundary(&np, time.Now()); wait != 0 {
+		t.Errorf("expect no requeue for pool without maintenance windows, but got %v", wait)
+	}
+
+	np.Spec.MaintenanceWindows = []appsv1beta1.NodePoolMaintenanceWindow{{
+		Schedule: "0 2 * * 6",
+		Duration: metav1.Duration{Duration: time.Hour},
+	}}
+	now := time.Date(2023, 7, 1, 2, 30, 0, 0, time.UTC)
+	if wait := untilMaintenanceWindowBoundary(&np, now); wait != 30*time.Minute+time.Second {
+		t.Errorf("expect requeue after the window closes, but got %v", wait)
+	}
+}
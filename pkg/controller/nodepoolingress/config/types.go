@@ -0,0 +1,27 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// NodePoolIngressControllerConfiguration contains elements describing NodePoolIngressController.
+type NodePoolIngressControllerConfiguration struct {
+	// GatewayClassName is the name of GatewayClass whose Gateways are provisioned by the controller.
+	GatewayClassName string
+	// DataPlaneImage is the image of the ingress data plane which is deployed into the nodepool,
+	// it serves the routes in the RoutesConfig file passed by --routes-config. The controller is
+	// disabled if it's empty, since OpenYurt doesn't ship a data plane image.
+	DataPlaneImage string
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepoolingress
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+)
+
+const (
+	// AnnotationIngressMode specifies how the data plane of Gateway is exposed in the nodepool,
+	// HostPort(default) or LoadBalancer.
+	AnnotationIngressMode = "nodepool.openyurt.io/ingress-mode"
+	// AnnotationRoutesHash is the hash of routes config, the data plane is restarted when routes are changed.
+	AnnotationRoutesHash = "nodepool.openyurt.io/routes-hash"
+	// AnnotationTemplateHash is the hash of pod template of data plane, the template of workload is
+	// only updated when the hash is changed, so fields defaulted by apiserver don't cause an update.
+	AnnotationTemplateHash = "nodepool.openyurt.io/template-hash"
+
+	IngressModeHostPort     = "HostPort"
+	IngressModeLoadBalancer = "LoadBalancer"
+
+	labelGatewayName = "nodepool.openyurt.io/gateway"
+	routesConfigKey  = "routes.json"
+	routesMountPath  = "/etc/nodepool-ingress"
+)
+
+// Listener is a listener of Gateway which is served by the data plane.
+type Listener struct {
+	Name     string `json:"name"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+	Hostname string `json:"hostname,omitempty"`
+	// AllowAllNamespaces is true if routes in all namespaces can be attached to the listener,
+	// otherwise only routes in the namespace of Gateway can be attached.
+	AllowAllNamespaces bool `json:"-"`
+}
+
+// PathMatch is the path match of a route rule.
+type PathMatch struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Backend is the service that requests are forwarded to.
+type Backend struct {
+	Service   string `json:"service"`
+	Namespace string `json:"namespace"`
+	Port      int32  `json:"port"`
+	Weight    int32  `json:"weight"`
+}
+
+// Route is a rule of HTTPRoute attached to the Gateway.
+type Route struct {
+	Route     string      `json:"route"`
+	Listeners []string    `json:"listeners"`
+	Hostnames []string    `json:"hostnames,omitempty"`
+	Matches   []PathMatch `json:"matches,omitempty"`
+	Backends  []Backend   `json:"backends"`
+}
+
+// RoutesConfig is rendered into routes.json of the ConfigMap which is mounted into the data plane,
+// and it's passed to the data plane image by --routes-config. The data plane serves the listeners
+// and forwards the matched requests to the backends, it's restarted when the routes are changed.
+type RoutesConfig struct {
+	Gateway   string     `json:"gateway"`
+	NodePool  string     `json:"nodePool"`
+	Listeners []Listener `json:"listeners"`
+	Routes    []Route    `json:"routes"`
+}
+
+// dataPlaneName returns the name of resources which make up the data plane of Gateway.
+func dataPlaneName(gw *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s-nodepool-ingress", gw.GetName())
+}
+
+// ingressMode returns the ingress mode of Gateway.
+func ingressMode(gw *unstructured.Unstructured) string {
+	if gw.GetAnnotations()[AnnotationIngressMode] == IngressModeLoadBalancer {
+		return IngressModeLoadBalancer
+	}
+	return IngressModeHostPort
+}
+
+// parseListeners parses the listeners in Gateway spec.
+func parseListeners(gw *unstructured.Unstructured) []Listener {
+	items, _, _ := unstructured.NestedSlice(gw.Object, "spec", "listeners")
+	listeners := make([]Listener, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		l := Listener{}
+		l.Name, _, _ = unstructured.NestedString(m, "name")
+		port, _, _ := unstructured.NestedInt64(m, "port")
+		l.Port = int32(port)
+		l.Protocol, _, _ = unstructured.NestedString(m, "protocol")
+		l.Hostname, _, _ = unstructured.NestedString(m, "hostname")
+		from, _, _ := unstructured.NestedString(m, "allowedRoutes", "namespaces", "from")
+		l.AllowAllNamespaces = from == "All"
+		if len(l.Name) == 0 || l.Port == 0 {
+			continue
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// parseRoutes parses the rules of HTTPRoute which are attached to the listeners of Gateway.
+// Backends in other namespaces are ignored because ReferenceGrant is not supported.
+func parseRoutes(gw *unstructured.Unstructured, listeners []Listener, route *unstructured.Unstructured) []Route {
+	attached := attachedListeners(gw, listeners, route)
+	if len(attached) == 0 {
+		return nil
+	}
+	hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+
+	var routes []Route
+	for _, item := range rules {
+		rule, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		r := Route{
+			Route:     fmt.Sprintf("%s/%s", route.GetNamespace(), route.GetName()),
+			Listeners: attached,
+			Hostnames: hostnames,
+		}
+
+		matches, _, _ := unstructured.NestedSlice(rule, "matches")
+		for _, mi := range matches {
+			m, ok := mi.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pm := PathMatch{Type: "PathPrefix", Value: "/"}
+			if t, ok, _ := unstructured.NestedString(m, "path", "type"); ok {
+				pm.Type = t
+			}
+			if v, ok, _ := unstructured.NestedString(m, "path", "value"); ok {
+				pm.Value = v
+			}
+			r.Matches = append(r.Matches, pm)
+		}
+
+		refs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, ri := range refs {
+			ref, ok := ri.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if kind, ok, _ := unstructured.NestedString(ref, "kind"); ok && kind != "Service" {
+				continue
+			}
+			ns, ok, _ := unstructured.NestedString(ref, "namespace")
+			if ok && ns != route.GetNamespace() {
+				continue
+			}
+			b := Backend{Namespace: route.GetNamespace(), Weight: 1}
+			b.Service, _, _ = unstructured.NestedString(ref, "name")
+			port, _, _ := unstructured.NestedInt64(ref, "port")
+			b.Port = int32(port)
+			if weight, ok, _ := unstructured.NestedInt64(ref, "weight"); ok {
+				b.Weight = int32(weight)
+			}
+			if len(b.Service) == 0 || b.Port == 0 {
+				continue
+			}
+			r.Backends = append(r.Backends, b)
+		}
+		if len(r.Backends) != 0 {
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+// attachedListeners returns the names of listeners that the route is attached to.
+func attachedListeners(gw *unstructured.Unstructured, listeners []Listener, route *unstructured.Unstructured) []string {
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	attached := make(map[string]struct{})
+	for _, item := range parentRefs {
+		ref, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if kind, ok, _ := unstructured.NestedString(ref, "kind"); ok && kind != gatewayGVK.Kind {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(ref, "name")
+		ns, ok, _ := unstructured.NestedString(ref, "namespace")
+		if !ok {
+			ns = route.GetNamespace()
+		}
+		if name != gw.GetName() || ns != gw.GetNamespace() {
+			continue
+		}
+		section, _, _ := unstructured.NestedString(ref, "sectionName")
+		for _, l := range listeners {
+			if len(section) != 0 && section != l.Name {
+				continue
+			}
+			if route.GetNamespace() != gw.GetNamespace() && !l.AllowAllNamespaces {
+				continue
+			}
+			attached[l.Name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(attached))
+	for name := range attached {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderRoutesConfig renders the routes config and returns the content and its hash.
+func renderRoutesConfig(cfg *RoutesConfig) (string, string, error) {
+	sort.SliceStable(cfg.Routes, func(i, j int) bool {
+		return cfg.Routes[i].Route < cfg.Routes[j].Route
+	})
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	return string(b), fmt.Sprintf("%x", sha256.Sum256(b))[:16], nil
+}
+
+// newPodTemplate returns the pod template of data plane, which is placed onto the nodes of pool.
+func newPodTemplate(gw *unstructured.Unstructured, pool, image, mode, routesHash string, listeners []Listener) corev1.PodTemplateSpec {
+	name := dataPlaneName(gw)
+	ports := make([]corev1.ContainerPort, 0, len(listeners))
+	for i, l := range listeners {
+		p := corev1.ContainerPort{Name: portName(i), ContainerPort: l.Port, Protocol: corev1.ProtocolTCP}
+		if mode == IngressModeHostPort {
+			p.HostPort = l.Port
+		}
+		ports = append(ports, p)
+	}
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      dataPlaneLabels(gw),
+			Annotations: map[string]string{AnnotationRoutesHash: routesHash},
+		},
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{apps.LabelCurrentNodePool: pool},
+			Containers: []corev1.Container{{
+				Name:  "ingress",
+				Image: image,
+				Args:  []string{fmt.Sprintf("--routes-config=%s/%s", routesMountPath, routesConfigKey)},
+				Ports: ports,
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "routes",
+					MountPath: routesMountPath,
+					ReadOnly:  true,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "routes",
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					},
+				},
+			}},
+		},
+	}
+}
+
+// portName returns the name of container port and service port for the listener at index. listener
+// names may be longer than 15 characters, which is not a valid IANA_SVC_NAME, so they are not used.
+func portName(index int) string {
+	return fmt.Sprintf("l%d", index)
+}
+
+// templateHash returns the hash of pod template.
+func templateHash(template *corev1.PodTemplateSpec) string {
+	b, _ := json.Marshal(template)
+	return fmt.Sprintf("%x", sha256.Sum256(b))[:16]
+}
+
+// setTemplate replaces the template of workload only when the desired template is changed.
+func setTemplate(obj *metav1.ObjectMeta, current *corev1.PodTemplateSpec, desired corev1.PodTemplateSpec) {
+	hash := templateHash(&desired)
+	if obj.Annotations[AnnotationTemplateHash] == hash {
+		return
+	}
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	obj.Annotations[AnnotationTemplateHash] = hash
+	*current = desired
+}
+
+func dataPlaneLabels(gw *unstructured.Unstructured) map[string]string {
+	return map[string]string{labelGatewayName: gw.GetName()}
+}
+
+// mutateDaemonSet sets the desired state of data plane in HostPort mode, the data plane runs on
+// every node of pool and listens on the host ports.
+func mutateDaemonSet(ds *appsv1.DaemonSet, template corev1.PodTemplateSpec, labels map[string]string) {
+	ds.Labels = labels
+	ds.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	setTemplate(&ds.ObjectMeta, &ds.Spec.Template, template)
+}
+
+// mutateDeployment sets the desired state of data plane in LoadBalancer mode.
+func mutateDeployment(deploy *appsv1.Deployment, template corev1.PodTemplateSpec, labels map[string]string) {
+	deploy.Labels = labels
+	deploy.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	setTemplate(&deploy.ObjectMeta, &deploy.Spec.Template, template)
+}
+
+// mutateService sets the desired state of LoadBalancer service in front of the data plane.
+func mutateService(svc *corev1.Service, labels map[string]string, listeners []Listener) {
+	svc.Labels = labels
+	svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+	svc.Spec.Selector = labels
+	// traffic is kept in the pool, so requests are not forwarded to the data plane of other pools
+	svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+	// keep the node ports allocated by apiserver, otherwise every update reallocates them
+	nodePorts := make(map[string]int32, len(svc.Spec.Ports))
+	for _, p := range svc.Spec.Ports {
+		nodePorts[p.Name] = p.NodePort
+	}
+	ports := make([]corev1.ServicePort, 0, len(listeners))
+	for i, l := range listeners {
+		name := portName(i)
+		ports = append(ports, corev1.ServicePort{
+			Name:       name,
+			Port:       l.Port,
+			TargetPort: intstr.FromInt(int(l.Port)),
+			Protocol:   corev1.ProtocolTCP,
+			NodePort:   nodePorts[name],
+		})
+	}
+	svc.Spec.Ports = ports
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepoolingress
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newGateway() *unstructured.Unstructured {
+	gw := newUnstructured(gatewayGVK)
+	gw.SetNamespace("default")
+	gw.SetName("hangzhou")
+	gw.Object["spec"] = map[string]interface{}{
+		"gatewayClassName": "openyurt-nodepool",
+		"listeners": []interface{}{
+			map[string]interface{}{
+				"name":     "http",
+				"port":     int64(80),
+				"protocol": "HTTP",
+			},
+			map[string]interface{}{
+				"name":     "public",
+				"port":     int64(8080),
+				"protocol": "HTTP",
+				"allowedRoutes": map[string]interface{}{
+					"namespaces": map[string]interface{}{"from": "All"},
+				},
+			},
+			map[string]interface{}{
+				"name":     "invalid",
+				"protocol": "HTTP",
+			},
+		},
+	}
+	return gw
+}
+
+func newRoute(namespace string, parentRefs ...interface{}) *unstructured.Unstructured {
+	route := newUnstructured(httpRouteGVK)
+	route.SetNamespace(namespace)
+	route.SetName("web")
+	route.Object["spec"] = map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  []interface{}{"web.example.com"},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{"type": "Exact", "value": "/api"},
+					},
+					map[string]interface{}{},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{"name": "web", "port": int64(8000), "weight": int64(3)},
+					map[string]interface{}{"name": "cross", "namespace": "other", "port": int64(8000)},
+					map[string]interface{}{"name": "bucket", "kind": "Bucket", "port": int64(8000)},
+				},
+			},
+			map[string]interface{}{
+				"backendRefs": []interface{}{
+					map[string]interface{}{"name": "no-port"},
+				},
+			},
+		},
+	}
+	return route
+}
+
+func TestParseListeners(t *testing.T) {
+	listeners := parseListeners(newGateway())
+	expect := []Listener{
+		{Name: "http", Port: 80, Protocol: "HTTP"},
+		{Name: "public", Port: 8080, Protocol: "HTTP", AllowAllNamespaces: true},
+	}
+	if !reflect.DeepEqual(expect, listeners) {
+		t.Errorf("expect listeners %v, but got %v", expect, listeners)
+	}
+}
+
+func TestAttachedListeners(t *testing.T) {
+	gw := newGateway()
+	listeners := parseListeners(gw)
+	tests := []struct {
+		name   string
+		route  *unstructured.Unstructured
+		expect []string
+	}{
+		{
+			name:   "attached to all listeners",
+			route:  newRoute("default", map[string]interface{}{"name": "hangzhou"}),
+			expect: []string{"http", "public"},
+		},
+		{
+			name:   "attached to section",
+			route:  newRoute("default", map[string]interface{}{"name": "hangzhou", "sectionName": "public"}),
+			expect: []string{"public"},
+		},
+		{
+			name:   "route in other namespace",
+			route:  newRoute("other", map[string]interface{}{"name": "hangzhou", "namespace": "default"}),
+			expect: []string{"public"},
+		},
+		{
+			name:   "other gateway",
+			route:  newRoute("default", map[string]interface{}{"name": "beijing"}),
+			expect: []string{},
+		},
+		{
+			name:   "other parent kind",
+			route:  newRoute("default", map[string]interface{}{"name": "hangzhou", "kind": "Service"}),
+			expect: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attachedListeners(gw, listeners, tt.route); !reflect.DeepEqual(tt.expect, got) {
+				t.Errorf("expect attached listeners %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	gw := newGateway()
+	listeners := parseListeners(gw)
+	route := newRoute("default", map[string]interface{}{"name": "hangzhou", "sectionName": "http"})
+
+	routes := parseRoutes(gw, listeners, route)
+	expect := []Route{
+		{
+			Route:     "default/web",
+			Listeners: []string{"http"},
+			Hostnames: []string{"web.example.com"},
+			Matches: []PathMatch{
+				{Type: "Exact", Value: "/api"},
+				{Type: "PathPrefix", Value: "/"},
+			},
+			Backends: []Backend{
+				{Service: "web", Namespace: "default", Port: 8000, Weight: 3},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expect, routes) {
+		t.Errorf("expect routes %v, but got %v", expect, routes)
+	}
+
+	if routes := parseRoutes(gw, listeners, newRoute("default", map[string]interface{}{"name": "beijing"})); len(routes) != 0 {
+		t.Errorf("expect no routes for other gateway, but got %v", routes)
+	}
+}
+
+func TestRenderRoutesConfig(t *testing.T) {
+	cfg := &RoutesConfig{Gateway: "default/hangzhou", NodePool: "hangzhou"}
+	_, hash1, err := renderRoutesConfig(cfg)
+	if err != nil {
+		t.Fatalf("could not render routes config, %v", err)
+	}
+	cfg.Routes = []Route{{Route: "default/web"}}
+	_, hash2, err := renderRoutesConfig(cfg)
+	if err != nil {
+		t.Fatalf("could not render routes config, %v", err)
+	}
+	if hash1 == hash2 {
+		t.Errorf("expect hash changed after routes are changed")
+	}
+}
+
+func TestPortNames(t *testing.T) {
+	listeners := []Listener{{Name: "a-very-long-listener-name", Port: 80}, {Name: "https", Port: 443}}
+	template := newPodTemplate(newGateway(), "hangzhou", "ingress", IngressModeLoadBalancer, "hash", listeners)
+	svc := &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "l0", NodePort: 30080}}}}
+	mutateService(svc, nil, listeners)
+	for i, name := range []string{"l0", "l1"} {
+		if got := template.Spec.Containers[0].Ports[i].Name; got != name {
+			t.Errorf("expect container port name %s, but got %s", name, got)
+		}
+		if got := svc.Spec.Ports[i].Name; got != name {
+			t.Errorf("expect service port name %s, but got %s", name, got)
+		}
+	}
+	if svc.Spec.Ports[0].NodePort != 30080 {
+		t.Errorf("expect node port to be kept, but got %d", svc.Spec.Ports[0].NodePort)
+	}
+}
+
+func TestMutateDeploymentTemplate(t *testing.T) {
+	listeners := []Listener{{Name: "http", Port: 80}}
+	template := newPodTemplate(newGateway(), "hangzhou", "ingress", IngressModeLoadBalancer, "hash", listeners)
+	deploy := &appsv1.Deployment{}
+	mutateDeployment(deploy, template, nil)
+	if deploy.Annotations[AnnotationTemplateHash] == "" {
+		t.Fatalf("expect template hash to be set")
+	}
+
+	// fields defaulted by apiserver are kept when the desired template is not changed
+	deploy.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	mutateDeployment(deploy, template, nil)
+	if deploy.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyAlways {
+		t.Errorf("expect template is not rewritten when it is not changed")
+	}
+
+	template = newPodTemplate(newGateway(), "hangzhou", "ingress", IngressModeLoadBalancer, "new-hash", listeners)
+	mutateDeployment(deploy, template, nil)
+	if !reflect.DeepEqual(deploy.Spec.Template, template) {
+		t.Errorf("expect template is updated when it is changed")
+	}
+}
@@ -0,0 +1,354 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepoolingress
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	appconfig "github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/controller/nodepoolingress/config"
+	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
+	utildiscovery "github.com/openyurtio/openyurt/pkg/util/discovery"
+)
+
+var (
+	concurrentReconciles = 3
+	gatewayGVK           = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1beta1", Kind: "Gateway"}
+	httpRouteGVK         = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1beta1", Kind: "HTTPRoute"}
+)
+
+const (
+	controllerName = "NodePoolIngress-controller"
+	// routeParentIndex is the field index of HTTPRoutes by their parent Gateways in the form of namespace/name
+	routeParentIndex = "spec.parentRefs.gateway"
+)
+
+func Format(format string, args ...interface{}) string {
+	s := fmt.Sprintf(format, args...)
+	return fmt.Sprintf("%s: %s", controllerName, s)
+}
+
+// ReconcileNodePoolIngress provisions the ingress data plane for Gateways scoped by nodepool,
+// and programs the routes attached to the Gateways into the data plane.
+type ReconcileNodePoolIngress struct {
+	client.Client
+	// routeReader lists HTTPRoutes from the informer cache by routeParentIndex, because the
+	// unstructured objects are not read from cache by the client.
+	routeReader   client.Reader
+	scheme        *runtime.Scheme
+	recorder      record.EventRecorder
+	Configuration config.NodePoolIngressControllerConfiguration
+}
+
+var _ reconcile.Reconciler = &ReconcileNodePoolIngress{}
+
+// Add creates a new NodePoolIngress Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(c *appconfig.CompletedConfig, mgr manager.Manager) error {
+	if len(c.ComponentConfig.NodePoolIngressController.DataPlaneImage) == 0 {
+		klog.Infof(Format("image of ingress data plane is not specified, skip the controller"))
+		return nil
+	}
+	if !utildiscovery.DiscoverGVK(gatewayGVK) || !utildiscovery.DiscoverGVK(httpRouteGVK) {
+		klog.Infof(Format("Gateway API is not installed, skip the controller"))
+		return nil
+	}
+	return add(mgr, newReconciler(c, mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(c *appconfig.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileNodePoolIngress{
+		Client:        utilclient.NewClientFromManager(mgr, controllerName),
+		routeReader:   mgr.GetCache(),
+		scheme:        mgr.GetScheme(),
+		recorder:      mgr.GetEventRecorderFor(controllerName),
+		Configuration: c.ComponentConfig.NodePoolIngressController,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler: r, MaxConcurrentReconciles: concurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	// index HTTPRoutes by parent Gateways, so only the routes attached to Gateway are listed
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), newUnstructured(httpRouteGVK), routeParentIndex, indexRouteByParent); err != nil {
+		return err
+	}
+
+	// Watch for changes to Gateway
+	if err := c.Watch(&source.Kind{Type: newUnstructured(gatewayGVK)}, &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+
+	// Watch for changes to HTTPRoute, the parent Gateways of route are enqueued
+	if err := c.Watch(&source.Kind{Type: newUnstructured(httpRouteGVK)}, handler.EnqueueRequestsFromMapFunc(mapRouteToGateways)); err != nil {
+		return err
+	}
+
+	// Watch for changes to the data plane owned by Gateway
+	owner := &handler.EnqueueRequestForOwner{OwnerType: newUnstructured(gatewayGVK), IsController: true}
+	for _, t := range []client.Object{&appsv1.DaemonSet{}, &appsv1.Deployment{}, &corev1.Service{}, &corev1.ConfigMap{}} {
+		if err := c.Watch(&source.Kind{Type: t}, owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	return u
+}
+
+func mapRouteToGateways(obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, parent := range routeParents(obj) {
+		requests = append(requests, reconcile.Request{NamespacedName: parent})
+	}
+	return requests
+}
+
+// indexRouteByParent is used for listing the HTTPRoutes attached to a Gateway.
+func indexRouteByParent(obj client.Object) []string {
+	parents := routeParents(obj)
+	keys := make([]string, 0, len(parents))
+	for _, parent := range parents {
+		keys = append(keys, parent.String())
+	}
+	return keys
+}
+
+// routeParents returns the Gateways in parentRefs of HTTPRoute.
+func routeParents(obj client.Object) []types.NamespacedName {
+	route, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	var parents []types.NamespacedName
+	for _, item := range parentRefs {
+		ref, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if kind, ok, _ := unstructured.NestedString(ref, "kind"); ok && kind != gatewayGVK.Kind {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(ref, "name")
+		ns, ok, _ := unstructured.NestedString(ref, "namespace")
+		if !ok {
+			ns = route.GetNamespace()
+		}
+		if len(name) != 0 {
+			parents = append(parents, types.NamespacedName{Namespace: ns, Name: name})
+		}
+	}
+	return parents
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways;httproutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=daemonsets;deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile provisions the data plane for a Gateway whose class is handled by the controller. The Gateway
+// is scoped by the nodepool specified by apps.openyurt.io/pool-name label, the data plane is placed onto
+// the nodes of the pool, so each site can expose local services without traffic hairpinning through the cloud.
+func (r *ReconcileNodePoolIngress) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	klog.V(4).Infof(Format("Reconcile Gateway %s", req.NamespacedName))
+
+	gw := newUnstructured(gatewayGVK)
+	if err := r.Get(ctx, req.NamespacedName, gw); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if gw.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+	if className, _, _ := unstructured.NestedString(gw.Object, "spec", "gatewayClassName"); className != r.Configuration.GatewayClassName {
+		return ctrl.Result{}, nil
+	}
+	pool := gw.GetLabels()[apps.PoolNameLabelKey]
+	if len(pool) == 0 {
+		r.recorder.Eventf(gw, corev1.EventTypeWarning, "NodePoolNotSpecified", "label %s is required for scoping gateway by nodepool", apps.PoolNameLabelKey)
+		return ctrl.Result{}, nil
+	}
+
+	listeners := parseListeners(gw)
+	var routeList unstructured.UnstructuredList
+	routeList.SetGroupVersionKind(httpRouteGVK.GroupVersion().WithKind(httpRouteGVK.Kind + "List"))
+	if err := r.routeReader.List(ctx, &routeList, client.MatchingFields{routeParentIndex: req.NamespacedName.String()}); err != nil {
+		return ctrl.Result{}, err
+	}
+	routesConfig := &RoutesConfig{Gateway: req.NamespacedName.String(), NodePool: pool, Listeners: listeners, Routes: []Route{}}
+	for i := range routeList.Items {
+		routesConfig.Routes = append(routesConfig.Routes, parseRoutes(gw, listeners, &routeList.Items[i])...)
+	}
+	content, hash, err := renderRoutesConfig(routesConfig)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileDataPlane(ctx, gw, pool, listeners, content, hash); err != nil {
+		klog.Errorf(Format("could not reconcile data plane of gateway %s, %v", req.NamespacedName, err))
+		return ctrl.Result{}, err
+	}
+
+	addresses, err := r.gatewayAddresses(ctx, gw, pool)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.updateGatewayStatus(ctx, gw, addresses)
+}
+
+// reconcileDataPlane creates or updates the routes config and the workload of data plane, the workload
+// of the other ingress mode is removed.
+func (r *ReconcileNodePoolIngress) reconcileDataPlane(ctx context.Context, gw *unstructured.Unstructured, pool string, listeners []Listener, content, hash string) error {
+	name := dataPlaneName(gw)
+	labels := dataPlaneLabels(gw)
+	mode := ingressMode(gw)
+
+	cm := &corev1.ConfigMap{ObjectMeta: objectMeta(gw, name)}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = labels
+		cm.Data = map[string]string{routesConfigKey: content}
+		return controllerutil.SetControllerReference(gw, cm, r.scheme)
+	}); err != nil {
+		return err
+	}
+
+	template := newPodTemplate(gw, pool, r.Configuration.DataPlaneImage, mode, hash, listeners)
+	ds := &appsv1.DaemonSet{ObjectMeta: objectMeta(gw, name)}
+	deploy := &appsv1.Deployment{ObjectMeta: objectMeta(gw, name)}
+	svc := &corev1.Service{ObjectMeta: objectMeta(gw, name)}
+	if mode == IngressModeHostPort {
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, ds, func() error {
+			mutateDaemonSet(ds, template, labels)
+			return controllerutil.SetControllerReference(gw, ds, r.scheme)
+		}); err != nil {
+			return err
+		}
+		return r.deleteIgnoreNotFound(ctx, deploy, svc)
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, deploy, func() error {
+		mutateDeployment(deploy, template, labels)
+		return controllerutil.SetControllerReference(gw, deploy, r.scheme)
+	}); err != nil {
+		return err
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		mutateService(svc, labels, listeners)
+		return controllerutil.SetControllerReference(gw, svc, r.scheme)
+	}); err != nil {
+		return err
+	}
+	return r.deleteIgnoreNotFound(ctx, ds)
+}
+
+func (r *ReconcileNodePoolIngress) deleteIgnoreNotFound(ctx context.Context, objs ...client.Object) error {
+	for _, obj := range objs {
+		if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// gatewayAddresses returns the internal ips of nodes in the pool for HostPort mode, or the
+// ingress addresses of LoadBalancer service.
+func (r *ReconcileNodePoolIngress) gatewayAddresses(ctx context.Context, gw *unstructured.Unstructured, pool string) ([]string, error) {
+	var addresses []string
+	if ingressMode(gw) == IngressModeLoadBalancer {
+		var svc corev1.Service
+		if err := r.Get(ctx, types.NamespacedName{Namespace: gw.GetNamespace(), Name: dataPlaneName(gw)}, &svc); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if len(ingress.IP) != 0 {
+				addresses = append(addresses, ingress.IP)
+			}
+		}
+		return addresses, nil
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes, client.MatchingLabels{apps.LabelCurrentNodePool: pool}); err != nil {
+		return nil, err
+	}
+	for i := range nodes.Items {
+		for _, addr := range nodes.Items[i].Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP {
+				addresses = append(addresses, addr.Address)
+				break
+			}
+		}
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// updateGatewayStatus updates the addresses of Gateway status if necessary.
+func (r *ReconcileNodePoolIngress) updateGatewayStatus(ctx context.Context, gw *unstructured.Unstructured, addresses []string) error {
+	desired := make([]interface{}, 0, len(addresses))
+	for _, addr := range addresses {
+		desired = append(desired, map[string]interface{}{"type": "IPAddress", "value": addr})
+	}
+	current, _, _ := unstructured.NestedSlice(gw.Object, "status", "addresses")
+	if reflect.DeepEqual(current, desired) || (len(current) == 0 && len(desired) == 0) {
+		return nil
+	}
+	if err := unstructured.SetNestedSlice(gw.Object, desired, "status", "addresses"); err != nil {
+		return err
+	}
+	return r.Status().Update(ctx, gw)
+}
+
+func objectMeta(gw *unstructured.Unstructured, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: gw.GetNamespace(), Name: name}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepoolingress
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestIndexRouteByParent(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    client.Object
+		expect []string
+	}{
+		{
+			name: "parents in the namespace of route and other namespace",
+			obj: newRoute("web",
+				map[string]interface{}{"name": "hangzhou"},
+				map[string]interface{}{"name": "hangzhou", "namespace": "default", "sectionName": "public"},
+			),
+			expect: []string{"web/hangzhou", "default/hangzhou"},
+		},
+		{
+			name:   "parents of other kinds are ignored",
+			obj:    newRoute("default", map[string]interface{}{"name": "mesh", "kind": "Service"}),
+			expect: []string{},
+		},
+		{
+			name:   "not a route",
+			obj:    &corev1.Pod{},
+			expect: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexRouteByParent(tt.obj); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expect index keys %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
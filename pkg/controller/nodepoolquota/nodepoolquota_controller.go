@@ -0,0 +1,423 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepoolquota
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
+	utildiscovery "github.com/openyurtio/openyurt/pkg/util/discovery"
+)
+
+var (
+	concurrentReconciles = 1
+	controllerKind       = appsv1beta1.GroupVersion.WithKind("NodePool")
+	// pod changes are not watched, so the usage of quota is refreshed periodically
+	quotaResyncPeriod = 30 * time.Second
+
+	// defaultContainerRequests and defaultContainerLimits are used by the default LimitRange
+	// if they are not specified in the quota of pool.
+	defaultContainerRequests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("128Mi"),
+	}
+	defaultContainerLimits = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	}
+)
+
+const (
+	controllerName = "NodePoolQuota-controller"
+)
+
+func Format(format string, args ...interface{}) string {
+	s := fmt.Sprintf(format, args...)
+	return fmt.Sprintf("%s: %s", controllerName, s)
+}
+
+// ReconcileNodePoolQuota aggregates the resource usage of pods on the nodes of NodePool
+// and compares it with the quota of NodePool.
+type ReconcileNodePoolQuota struct {
+	client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+}
+
+var _ reconcile.Reconciler = &ReconcileNodePoolQuota{}
+
+// Add creates a new NodePoolQuota Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(c *config.CompletedConfig, mgr manager.Manager) error {
+	if !utildiscovery.DiscoverGVK(controllerKind) {
+		klog.Errorf(Format("DiscoverGVK error"))
+		return nil
+	}
+
+	return add(mgr, newReconciler(c, mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(_ *config.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileNodePoolQuota{
+		Client:   utilclient.NewClientFromManager(mgr, controllerName),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor(controllerName),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler: r, MaxConcurrentReconciles: concurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to NodePool
+	err = c.Watch(&source.Kind{Type: &appsv1beta1.NodePool{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Node, the pool of node is enqueued
+	return c.Watch(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(mapNodeToNodePool))
+}
+
+func mapNodeToNodePool(obj client.Object) []reconcile.Request {
+	pool := obj.GetLabels()[apps.LabelDesiredNodePool]
+	if len(pool) == 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: pool}}}
+}
+
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=limitranges,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile aggregates the resource usage of pods on the nodes of NodePool for the namespaces
+// selected by NodePool.Spec.Quota, and surfaces the resources exceeding the quota on the NodePool status.
+// A default LimitRange and ResourceQuota are maintained in each namespace selected by the quota.
+func (r *ReconcileNodePoolQuota) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	klog.V(4).Infof(Format("Reconcile NodePool %s", req.Name))
+
+	var nodePool appsv1beta1.NodePool
+	if err := r.Get(ctx, req.NamespacedName, &nodePool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// only the quota fields of status are patched, the others are maintained by nodepool controller
+	original := nodePool.DeepCopy()
+
+	quota := nodePool.Spec.Quota
+	if quota == nil {
+		// clean up the namespace defaults and quota status after quota is removed from the pool
+		if err := r.reconcileNamespaceDefaults(ctx, &nodePool, nil); err != nil {
+			return ctrl.Result{}, err
+		}
+		if nodePool.Status.QuotaUsed == nil && nodePool.Status.QuotaViolations == nil {
+			return ctrl.Result{}, nil
+		}
+		nodePool.Status.QuotaUsed = nil
+		nodePool.Status.QuotaViolations = nil
+		return ctrl.Result{}, r.Status().Patch(ctx, &nodePool, client.MergeFrom(original))
+	}
+
+	namespaces, err := r.selectedNamespaces(ctx, quota)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// the defaults are only created in the namespaces selected explicitly, not in all namespaces
+	var defaultNamespaces []string
+	if quota.NamespaceSelector != nil {
+		defaultNamespaces = namespaces
+	}
+	if err := r.reconcileNamespaceDefaults(ctx, &nodePool, defaultNamespaces); err != nil {
+		klog.Errorf(Format("could not reconcile namespace defaults of NodePool %s, %v", req.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	pods, err := r.listPoolPods(ctx, &nodePool, namespaces)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	used := calculateQuotaUsed(pods, quota.Hard)
+	violations := quotaViolations(used, quota.Hard)
+	if len(violations) != 0 && !reflect.DeepEqual(violations, nodePool.Status.QuotaViolations) {
+		r.recorder.Eventf(&nodePool, corev1.EventTypeWarning, "QuotaExceeded",
+			"resources(%s) of pods in the pool exceed the quota", strings.Join(violations, ","))
+	}
+
+	result := ctrl.Result{RequeueAfter: quotaResyncPeriod}
+	if quotav1.Equals(used, nodePool.Status.QuotaUsed) && reflect.DeepEqual(violations, nodePool.Status.QuotaViolations) {
+		return result, nil
+	}
+	nodePool.Status.QuotaUsed = used
+	nodePool.Status.QuotaViolations = violations
+	return result, r.Status().Patch(ctx, &nodePool, client.MergeFrom(original))
+}
+
+// selectedNamespaces returns the namespaces selected by the quota, or NamespaceAll if no selector is specified.
+func (r *ReconcileNodePoolQuota) selectedNamespaces(ctx context.Context, quota *appsv1beta1.NodePoolQuota) ([]string, error) {
+	if quota.NamespaceSelector == nil {
+		return []string{metav1.NamespaceAll}, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(quota.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	var nsList corev1.NamespaceList
+	if err := r.List(ctx, &nsList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	namespaces := make([]string, 0, len(nsList.Items))
+	for i := range nsList.Items {
+		namespaces = append(namespaces, nsList.Items[i].Name)
+	}
+	return namespaces, nil
+}
+
+// reconcileNamespaceDefaults creates or updates the default LimitRange and ResourceQuota of pool in the
+// namespaces, the ones in the namespaces which are not selected by the pool any more are removed.
+func (r *ReconcileNodePoolQuota) reconcileNamespaceDefaults(ctx context.Context, nodePool *appsv1beta1.NodePool, namespaces []string) error {
+	name := namespaceDefaultsName(nodePool)
+	labels := map[string]string{apps.LabelNodePoolQuota: nodePool.Name}
+	for _, ns := range namespaces {
+		lr := &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, lr, func() error {
+			lr.Labels = labels
+			lr.Spec = newLimitRangeSpec(nodePool.Spec.Quota)
+			return controllerutil.SetControllerReference(nodePool, lr, r.scheme)
+		}); err != nil {
+			return err
+		}
+
+		rq := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, rq, func() error {
+			rq.Labels = labels
+			rq.Spec = newResourceQuotaSpec(nodePool.Spec.Quota)
+			return controllerutil.SetControllerReference(nodePool, rq, r.scheme)
+		}); err != nil {
+			return err
+		}
+	}
+
+	selected := sets.NewString(namespaces...)
+	var lrList corev1.LimitRangeList
+	if err := r.List(ctx, &lrList, client.MatchingLabels(labels)); err != nil {
+		return err
+	}
+	for i := range lrList.Items {
+		if !selected.Has(lrList.Items[i].Namespace) {
+			if err := r.Delete(ctx, &lrList.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	var rqList corev1.ResourceQuotaList
+	if err := r.List(ctx, &rqList, client.MatchingLabels(labels)); err != nil {
+		return err
+	}
+	for i := range rqList.Items {
+		if !selected.Has(rqList.Items[i].Namespace) {
+			if err := r.Delete(ctx, &rqList.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func namespaceDefaultsName(nodePool *appsv1beta1.NodePool) string {
+	return fmt.Sprintf("nodepool-%s", nodePool.Name)
+}
+
+// newLimitRangeSpec returns the spec of default LimitRange for the containers in the namespaces selected by quota,
+// the default limit of a resource is raised to the default request if it's smaller.
+func newLimitRangeSpec(quota *appsv1beta1.NodePoolQuota) corev1.LimitRangeSpec {
+	requests, limits := quota.DefaultRequests, quota.DefaultLimits
+	if len(requests) == 0 {
+		requests = defaultContainerRequests
+	}
+	if len(limits) == 0 {
+		limits = defaultContainerLimits
+	}
+	limits = limits.DeepCopy()
+	for name, request := range requests {
+		if limit, ok := limits[name]; ok && request.Cmp(limit) > 0 {
+			limits[name] = request.DeepCopy()
+		}
+	}
+	return corev1.LimitRangeSpec{
+		Limits: []corev1.LimitRangeItem{{
+			Type:           corev1.LimitTypeContainer,
+			Default:        limits,
+			DefaultRequest: requests.DeepCopy(),
+		}},
+	}
+}
+
+// newResourceQuotaSpec returns the spec of default ResourceQuota in the namespaces selected by quota.
+func newResourceQuotaSpec(quota *appsv1beta1.NodePoolQuota) corev1.ResourceQuotaSpec {
+	hard := quota.NamespaceHard
+	if len(hard) == 0 {
+		hard = quota.Hard
+	}
+	return corev1.ResourceQuotaSpec{Hard: hard.DeepCopy()}
+}
+
+// listPoolPods lists the pods on the nodes of pool in the namespaces.
+func (r *ReconcileNodePoolQuota) listPoolPods(ctx context.Context, nodePool *appsv1beta1.NodePool, namespaces []string) ([]corev1.Pod, error) {
+	var nodeList corev1.NodeList
+	if err := r.List(ctx, &nodeList, client.MatchingLabels{apps.LabelDesiredNodePool: nodePool.GetName()}); err != nil {
+		return nil, err
+	}
+	if len(nodeList.Items) == 0 {
+		return nil, nil
+	}
+	nodes := sets.NewString()
+	for i := range nodeList.Items {
+		nodes.Insert(nodeList.Items[i].Name)
+	}
+
+	var pods []corev1.Pod
+	for _, ns := range namespaces {
+		var podList corev1.PodList
+		if err := r.List(ctx, &podList, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		for i := range podList.Items {
+			if nodes.Has(podList.Items[i].Spec.NodeName) {
+				pods = append(pods, podList.Items[i])
+			}
+		}
+	}
+	return pods, nil
+}
+
+// calculateQuotaUsed calculates the usage of the resources in hard by the pods,
+// terminated pods are not counted.
+func calculateQuotaUsed(pods []corev1.Pod, hard corev1.ResourceList) corev1.ResourceList {
+	used := corev1.ResourceList{}
+	for name := range hard {
+		used[name] = resource.Quantity{Format: hard[name].Format}
+	}
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodSucceeded || pods[i].Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requests, limits := podRequestsAndLimits(&pods[i])
+		for name := range hard {
+			var q resource.Quantity
+			switch name {
+			case corev1.ResourcePods:
+				q = *resource.NewQuantity(1, resource.DecimalSI)
+			case corev1.ResourceCPU, corev1.ResourceRequestsCPU:
+				q = requests[corev1.ResourceCPU]
+			case corev1.ResourceMemory, corev1.ResourceRequestsMemory:
+				q = requests[corev1.ResourceMemory]
+			case corev1.ResourceLimitsCPU:
+				q = limits[corev1.ResourceCPU]
+			case corev1.ResourceLimitsMemory:
+				q = limits[corev1.ResourceMemory]
+			default:
+				continue
+			}
+			sum := used[name]
+			sum.Add(q)
+			used[name] = sum
+		}
+	}
+	return used
+}
+
+// podRequestsAndLimits returns the effective requests and limits of pod, which is the sum of
+// containers or the max of init containers, whichever is larger.
+func podRequestsAndLimits(pod *corev1.Pod) (requests, limits corev1.ResourceList) {
+	requests, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addResourceList(requests, c.Resources.Requests)
+		addResourceList(limits, c.Resources.Limits)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		maxResourceList(requests, c.Resources.Requests)
+		maxResourceList(limits, c.Resources.Limits)
+	}
+	return requests, limits
+}
+
+func addResourceList(list, added corev1.ResourceList) {
+	for name, q := range added {
+		sum := list[name]
+		sum.Add(q)
+		list[name] = sum
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, q := range other {
+		if current, ok := list[name]; !ok || q.Cmp(current) > 0 {
+			list[name] = q.DeepCopy()
+		}
+	}
+}
+
+// quotaViolations returns the sorted names of resources whose usage exceeds the hard limit.
+func quotaViolations(used, hard corev1.ResourceList) []string {
+	var violations []string
+	for name, limit := range hard {
+		if q, ok := used[name]; ok && q.Cmp(limit) > 0 {
+			violations = append(violations, string(name))
+		}
+	}
+	sort.Strings(violations)
+	return violations
+}
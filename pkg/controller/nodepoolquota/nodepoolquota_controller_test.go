@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodepoolquota
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	quotav1 "k8s.io/apiserver/pkg/quota/v1"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func newPod(phase corev1.PodPhase, cpu, memory string) corev1.Pod {
+	return corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse(cpu),
+						corev1.ResourceMemory: resource.MustParse(memory),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse(cpu),
+					},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestCalculateQuotaUsed(t *testing.T) {
+	initPod := newPod(corev1.PodRunning, "100m", "64Mi")
+	initPod.Spec.InitContainers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		},
+	}}
+	pods := []corev1.Pod{
+		newPod(corev1.PodRunning, "500m", "128Mi"),
+		newPod(corev1.PodPending, "250m", "128Mi"),
+		newPod(corev1.PodSucceeded, "2", "1Gi"),
+		initPod,
+	}
+	hard := corev1.ResourceList{
+		corev1.ResourceRequestsCPU:      resource.MustParse("1"),
+		corev1.ResourceMemory:           resource.MustParse("1Gi"),
+		corev1.ResourceLimitsCPU:        resource.MustParse("2"),
+		corev1.ResourceLimitsMemory:     resource.MustParse("1Gi"),
+		corev1.ResourcePods:             resource.MustParse("3"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+	}
+
+	used := calculateQuotaUsed(pods, hard)
+	expect := corev1.ResourceList{
+		corev1.ResourceRequestsCPU:      resource.MustParse("1750m"),
+		corev1.ResourceMemory:           resource.MustParse("320Mi"),
+		corev1.ResourceLimitsCPU:        resource.MustParse("850m"),
+		corev1.ResourceLimitsMemory:     resource.MustParse("0"),
+		corev1.ResourcePods:             resource.MustParse("3"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("0"),
+	}
+	if !quotav1.Equals(expect, used) {
+		t.Errorf("expect used %v, but got %v", expect, used)
+	}
+
+	violations := quotaViolations(used, hard)
+	if !reflect.DeepEqual([]string{string(corev1.ResourceRequestsCPU)}, violations) {
+		t.Errorf("expect violations of requests.cpu, but got %v", violations)
+	}
+}
+
+func TestQuotaViolations(t *testing.T) {
+	hard := corev1.ResourceList{
+		corev1.ResourcePods:   resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	tests := []struct {
+		name   string
+		used   corev1.ResourceList
+		expect []string
+	}{
+		{
+			name: "within quota",
+			used: corev1.ResourceList{
+				corev1.ResourcePods:   resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			name: "exceed quota",
+			used: corev1.ResourceList{
+				corev1.ResourcePods:   resource.MustParse("3"),
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+			},
+			expect: []string{string(corev1.ResourceMemory), string(corev1.ResourcePods)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quotaViolations(tt.used, hard); !reflect.DeepEqual(tt.expect, got) {
+				t.Errorf("expect violations %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestNewLimitRangeSpec(t *testing.T) {
+	tests := []struct {
+		name          string
+		quota         *appsv1beta1.NodePoolQuota
+		expectRequest corev1.ResourceList
+		expectLimit   corev1.ResourceList
+	}{
+		{
+			name:          "use builtin defaults",
+			quota:         &appsv1beta1.NodePoolQuota{},
+			expectRequest: defaultContainerRequests,
+			expectLimit:   defaultContainerLimits,
+		},
+		{
+			name: "raise default limit to request",
+			quota: &appsv1beta1.NodePoolQuota{
+				DefaultRequests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+			},
+			expectRequest: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+			expectLimit: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newLimitRangeSpec(tt.quota)
+			if len(spec.Limits) != 1 || spec.Limits[0].Type != corev1.LimitTypeContainer {
+				t.Fatalf("expect one limit of container, but got %v", spec.Limits)
+			}
+			if !quotav1.Equals(tt.expectRequest, spec.Limits[0].DefaultRequest) {
+				t.Errorf("expect default request %v, but got %v", tt.expectRequest, spec.Limits[0].DefaultRequest)
+			}
+			if !quotav1.Equals(tt.expectLimit, spec.Limits[0].Default) {
+				t.Errorf("expect default limit %v, but got %v", tt.expectLimit, spec.Limits[0].Default)
+			}
+		})
+	}
+	// the builtin defaults should not be changed by raising the limit
+	if q := defaultContainerLimits[corev1.ResourceCPU]; q.Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("expect builtin default cpu limit 500m, but got %s", q.String())
+	}
+}
+
+func TestNewResourceQuotaSpec(t *testing.T) {
+	hard := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}
+	namespaceHard := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("5")}
+
+	if spec := newResourceQuotaSpec(&appsv1beta1.NodePoolQuota{Hard: hard}); !quotav1.Equals(hard, spec.Hard) {
+		t.Errorf("expect hard %v, but got %v", hard, spec.Hard)
+	}
+	if spec := newResourceQuotaSpec(&appsv1beta1.NodePoolQuota{Hard: hard, NamespaceHard: namespaceHard}); !quotav1.Equals(namespaceHard, spec.Hard) {
+		t.Errorf("expect hard %v, but got %v", namespaceHard, spec.Hard)
+	}
+}
@@ -20,6 +20,19 @@ package constant
 const (
 	DelegateHeartBeat = "openyurt.io/delegate-heartbeat"
 
+	// DelegatedBy is the name of node whose yurthub delegates the node lease to cloud
+	DelegatedBy = "openyurt.io/delegated-by"
+
+	// NodeConditionsAnnotation is the annotation of delegated node lease, which carries the node conditions
+	// reported by node-problem-detector when the node can't reach cloud. The conditions are applied to the
+	// node only if the lease is delegated by a node in the same pool and they are signed by the node itself.
+	NodeConditionsAnnotation = "openyurt.io/node-problem-conditions"
+
+	// NodeConditionsSignatureAnnotation is the annotation of delegated node lease, which carries the signature
+	// of NodeConditionsAnnotation by the client certificate of node. All delegates share the same identity
+	// for updating leases, so the signature tells the conditions are not forged by the delegate.
+	NodeConditionsSignatureAnnotation = "openyurt.io/node-problem-conditions-signature"
+
 	// when node cannot reach api-server directly but can be delegated lease, we should taint the node as unschedulable
 	NodeNotSchedulableTaint = "node.openyurt.io/unschedulable"
 
@@ -19,12 +19,16 @@ package delegatelease
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	coordv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
@@ -37,12 +41,18 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
 	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
 	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/utils"
 )
 
 const (
 	numWorkers = 5
+
+	// the client CA of kube-apiserver which is used to verify the signatures of node conditions
+	clientCANamespace = "kube-system"
+	clientCAConfigMap = "extension-apiserver-authentication"
+	clientCAKey       = "client-ca-file"
 )
 
 type Controller struct {
@@ -56,6 +66,29 @@ type Controller struct {
 	nodeUpdateQueue workqueue.Interface
 
 	ldc *utils.LeaseDelegatedCounter
+	// clientCAs returns the CAs of client certificates of nodes
+	clientCAs func() (*x509.CertPool, error)
+
+	// reports records the latest delegated renew time of node leases, multiple delegates
+	// in a pool may report the same lease, so only the reports with newer renew time are counted.
+	reportsLock sync.Mutex
+	reports     map[string]delegateReport
+}
+
+type delegateReport struct {
+	delegate  string
+	renewTime time.Time
+	// conditions is the value of NodeConditionsAnnotation applied to the node
+	conditions string
+}
+
+// kubeletConditions are maintained by kubelet and node lifecycle controller, they can't be changed by delegates.
+var kubeletConditions = map[corev1.NodeConditionType]bool{
+	corev1.NodeReady:              true,
+	corev1.NodeMemoryPressure:     true,
+	corev1.NodeDiskPressure:       true,
+	corev1.NodePIDPressure:        true,
+	corev1.NodeNetworkUnavailable: true,
 }
 
 func (c *Controller) onLeaseCreate(n interface{}) {
@@ -87,7 +120,9 @@ func NewController(kc client.Interface, informerFactory informers.SharedInformer
 		client:          kc,
 		nodeUpdateQueue: workqueue.NewNamed("poolcoordinator_node"),
 		ldc:             utils.NewLeaseDelegatedCounter(),
+		reports:         make(map[string]delegateReport),
 	}
+	ctl.clientCAs = ctl.loadClientCAs
 
 	if informerFactory != nil {
 		ctl.nodeInformer = informerFactory.Core().V1().Nodes()
@@ -177,26 +212,175 @@ func (c *Controller) syncHandler(key string) error {
 	if err != nil {
 		klog.Errorf("couldn't get lease for %s, maybe it has been deleted\n", name)
 		c.ldc.Del(name)
+		c.forgetReport(name)
 		return nil
 	}
 
 	nval, nok := nl.Annotations[constant.DelegateHeartBeat]
 
 	if nok && nval == "true" {
+		if !c.acceptDelegation(nl) {
+			return nil
+		}
 		c.ldc.Inc(nl.Name)
 		if c.ldc.Counter(nl.Name) >= constant.LeaseDelegationThreshold {
 			c.taintNodeNotSchedulable(nl.Name)
 		}
+		c.applyNodeConditions(nl)
 	} else {
 		if c.ldc.Counter(nl.Name) >= constant.LeaseDelegationThreshold {
 			c.deTaintNodeNotSchedulable(nl.Name)
 		}
 		c.ldc.Reset(nl.Name)
+		c.forgetReport(nl.Name)
 	}
 
 	return nil
 }
 
+// acceptDelegation checks the delegated lease is reported by a current delegate and renewed
+// since the last report. Duplicated reports from multiple delegates are not counted, and the
+// reports with earlier renew time than the last report from other delegate are regarded as conflicts.
+func (c *Controller) acceptDelegation(nl *coordv1.Lease) bool {
+	delegate := nl.Annotations[constant.DelegatedBy]
+	if len(delegate) != 0 && !c.isCurrentDelegate(delegate) {
+		klog.Warningf("lease %s is delegated by %s which can not reach cloud, skip it", nl.Name, delegate)
+		return false
+	}
+	if nl.Spec.RenewTime == nil {
+		return true
+	}
+
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+	renewTime := nl.Spec.RenewTime.Time
+	last, ok := c.reports[nl.Name]
+	if ok && !renewTime.After(last.renewTime) {
+		if renewTime.Before(last.renewTime) && delegate != last.delegate {
+			klog.Warningf("conflicting delegation of lease %s, renew time %s reported by %s is earlier than %s reported by %s",
+				nl.Name, renewTime, delegate, last.renewTime, last.delegate)
+		}
+		return false
+	}
+	c.reports[nl.Name] = delegateReport{delegate: delegate, renewTime: renewTime, conditions: last.conditions}
+	return true
+}
+
+// isCurrentDelegate returns true if the lease of delegate node is renewed by itself rather than delegated.
+func (c *Controller) isCurrentDelegate(name string) bool {
+	if c.leaseLister == nil {
+		return true
+	}
+	dl, err := c.leaseLister.Get(name)
+	if err != nil {
+		klog.Warningf("couldn't get lease of delegate %s, %v", name, err)
+		return false
+	}
+	return dl.Annotations[constant.DelegateHeartBeat] != "true"
+}
+
+// applyNodeConditions patches the node conditions carried by the delegated lease into the node status.
+// The conditions are only accepted if the delegate is in the same pool as the node and the conditions are
+// signed by the node itself, so a leader yurthub can't forge the status of nodes, and the conditions
+// maintained by kubelet are ignored.
+func (c *Controller) applyNodeConditions(nl *coordv1.Lease) {
+	annotation := nl.Annotations[constant.NodeConditionsAnnotation]
+	if len(annotation) == 0 || c.nodeLister == nil {
+		return
+	}
+	c.reportsLock.Lock()
+	applied := c.reports[nl.Name].conditions == annotation
+	c.reportsLock.Unlock()
+	if applied {
+		return
+	}
+
+	conditions, err := c.delegatedConditions(nl.Name, nl.Annotations[constant.DelegatedBy], annotation, nl.Annotations[constant.NodeConditionsSignatureAnnotation])
+	if err != nil {
+		klog.Warningf("reject node conditions in lease %s, %v", nl.Name, err)
+		return
+	}
+	if len(conditions) != 0 && c.client != nil {
+		patch, err := json.Marshal(map[string]interface{}{
+			"status": map[string]interface{}{"conditions": conditions},
+		})
+		if err != nil {
+			klog.Errorf("failed to encode node conditions of %s, %v", nl.Name, err)
+			return
+		}
+		if _, err := c.client.CoreV1().Nodes().Patch(context.TODO(), nl.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+			klog.Errorf("failed to patch node conditions of %s, %v", nl.Name, err)
+			return
+		}
+		klog.Infof("%d node conditions of %s delegated by %s are applied", len(conditions), nl.Name, nl.Annotations[constant.DelegatedBy])
+	}
+
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+	report := c.reports[nl.Name]
+	report.conditions = annotation
+	c.reports[nl.Name] = report
+}
+
+// delegatedConditions returns the conditions in annotation which can be applied to the node by delegate.
+func (c *Controller) delegatedConditions(nodeName, delegate, annotation, signature string) ([]corev1.NodeCondition, error) {
+	if len(delegate) == 0 {
+		return nil, fmt.Errorf("delegate is unknown")
+	}
+	node, err := c.nodeLister.Get(nodeName)
+	if err != nil {
+		return nil, err
+	}
+	delegateNode, err := c.nodeLister.Get(delegate)
+	if err != nil {
+		return nil, err
+	}
+	pool := node.Labels[apps.LabelCurrentNodePool]
+	if len(pool) == 0 || pool != delegateNode.Labels[apps.LabelCurrentNodePool] {
+		return nil, fmt.Errorf("delegate %s is not in the pool of node", delegate)
+	}
+	roots, err := c.clientCAs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client CAs, %w", err)
+	}
+	if err := utils.VerifyNodeConditions(roots, nodeName, annotation, signature, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var conditions []corev1.NodeCondition
+	if err := json.Unmarshal([]byte(annotation), &conditions); err != nil {
+		return nil, fmt.Errorf("failed to decode node conditions, %w", err)
+	}
+	accepted := conditions[:0]
+	for _, condition := range conditions {
+		if kubeletConditions[condition.Type] {
+			klog.Warningf("condition %s of node %s can not be changed by delegate %s", condition.Type, nodeName, delegate)
+			continue
+		}
+		accepted = append(accepted, condition)
+	}
+	return accepted, nil
+}
+
+// loadClientCAs loads the client CAs of kube-apiserver, which issue the client certificates of nodes.
+func (c *Controller) loadClientCAs() (*x509.CertPool, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(clientCANamespace).Get(context.TODO(), clientCAConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(cm.Data[clientCAKey])) {
+		return nil, fmt.Errorf("no certificate is found in %s of configmap %s/%s", clientCAKey, clientCANamespace, clientCAConfigMap)
+	}
+	return roots, nil
+}
+
+func (c *Controller) forgetReport(name string) {
+	c.reportsLock.Lock()
+	defer c.reportsLock.Unlock()
+	delete(c.reports, name)
+}
+
 func (c *Controller) nodeWorker() {
 	for {
 		key, shutdown := c.nodeUpdateQueue.Get()
@@ -17,10 +17,27 @@ limitations under the License.
 package delegatelease
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"testing"
+	"time"
 
 	coordv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/utils"
 )
 
 func TestTaintNode(t *testing.T) {
@@ -58,3 +75,161 @@ func TestOnLeaseUpdate(t *testing.T) {
 	l.Name = "ai-ice-vm05"
 	c.onLeaseUpdate(l, l)
 }
+
+func TestAcceptDelegation(t *testing.T) {
+	c := NewController(nil, nil)
+	now := time.Now()
+	newLease := func(delegate string, renewTime time.Time) *coordv1.Lease {
+		return &coordv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ai-ice-vm05",
+				Namespace: corev1.NamespaceNodeLease,
+				Annotations: map[string]string{
+					constant.DelegateHeartBeat: "true",
+					constant.DelegatedBy:       delegate,
+				},
+			},
+			Spec: coordv1.LeaseSpec{RenewTime: &metav1.MicroTime{Time: renewTime}},
+		}
+	}
+
+	if !c.acceptDelegation(newLease("node1", now)) {
+		t.Errorf("expect the first report is accepted")
+	}
+	if c.acceptDelegation(newLease("node2", now)) {
+		t.Errorf("expect the duplicated report from another delegate is not accepted")
+	}
+	if c.acceptDelegation(newLease("node2", now.Add(-time.Second))) {
+		t.Errorf("expect the conflicting report is not accepted")
+	}
+	if !c.acceptDelegation(newLease("node2", now.Add(time.Second))) {
+		t.Errorf("expect the renewed report from another delegate is accepted")
+	}
+	c.forgetReport("ai-ice-vm05")
+	if !c.acceptDelegation(newLease("node1", now)) {
+		t.Errorf("expect the report is accepted after the lease is renewed by node itself")
+	}
+}
+
+func TestApplyNodeConditions(t *testing.T) {
+	newNode := func(name, pool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{apps.LabelCurrentNodePool: pool}}}
+	}
+	conditions := `[{"type":"KernelDeadlock","status":"True","reason":"DockerHung"},{"type":"Ready","status":"True"}]`
+	ca, caKey := newTestCert(t, "kubernetes", nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	testcases := map[string]struct {
+		delegate        string
+		signer          string
+		expectCondition bool
+	}{
+		"delegate in the same pool": {
+			delegate:        "node2",
+			signer:          "node1",
+			expectCondition: true,
+		},
+		"delegate in other pool": {
+			delegate: "node3",
+			signer:   "node1",
+		},
+		"unknown delegate": {
+			delegate: "node4",
+			signer:   "node1",
+		},
+		"conditions signed by delegate": {
+			delegate: "node2",
+			signer:   "node2",
+		},
+		"conditions not signed": {
+			delegate: "node2",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			nodes := []*corev1.Node{newNode("node1", "hangzhou"), newNode("node2", "hangzhou"), newNode("node3", "beijing")}
+			client := fake.NewSimpleClientset()
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, node := range nodes {
+				if _, err := client.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+				indexer.Add(node)
+			}
+			c := NewController(client, nil)
+			c.nodeLister = listerv1.NewNodeLister(indexer)
+			c.clientCAs = func() (*x509.CertPool, error) {
+				return roots, nil
+			}
+			var signature string
+			if len(tc.signer) != 0 {
+				cert, key := newTestCert(t, "system:node:"+tc.signer, ca, caKey)
+				var err error
+				signature, err = utils.SignNodeConditions(&tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}, "node1", conditions, time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c.applyNodeConditions(&coordv1.Lease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "node1",
+					Namespace: corev1.NamespaceNodeLease,
+					Annotations: map[string]string{
+						constant.DelegateHeartBeat:                 "true",
+						constant.DelegatedBy:                       tc.delegate,
+						constant.NodeConditionsAnnotation:          conditions,
+						constant.NodeConditionsSignatureAnnotation: signature,
+					},
+				},
+			})
+
+			node, err := client.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var applied []corev1.NodeConditionType
+			for _, condition := range node.Status.Conditions {
+				applied = append(applied, condition.Type)
+			}
+			if tc.expectCondition {
+				if len(applied) != 1 || applied[0] != "KernelDeadlock" {
+					t.Errorf("expect only KernelDeadlock condition applied, but got %v", applied)
+				}
+			} else if len(applied) != 0 {
+				t.Errorf("expect no condition applied, but got %v", applied)
+			}
+		})
+	}
+}
+
+// newTestCert returns a client certificate issued by parent, or a self-signed CA if parent is nil.
+func newTestCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
@@ -18,6 +18,7 @@ package podbinding
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
 	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
 )
@@ -69,8 +71,10 @@ func (c *Controller) onNodeUpdate(o interface{}, n interface{}) {
 	oldNode := o.(*corev1.Node)
 	newNode := n.(*corev1.Node)
 
-	// if node autonomy annotation changed, we need to handle all pods on this node except DaemonSet pod.
-	if oldNode.Annotations[projectinfo.GetAutonomyAnnotation()] != newNode.Annotations[projectinfo.GetAutonomyAnnotation()] {
+	// if node autonomy annotation or pod toleration seconds of node pool changed, we need to handle
+	// all pods on this node except DaemonSet pod.
+	if oldNode.Annotations[projectinfo.GetAutonomyAnnotation()] != newNode.Annotations[projectinfo.GetAutonomyAnnotation()] ||
+		oldNode.Annotations[apps.AnnotationPodTolerationSeconds] != newNode.Annotations[apps.AnnotationPodTolerationSeconds] {
 		pods, err := c.getPodsAssignedToNode(newNode.Name)
 		if err != nil {
 			klog.Errorf("failed to get pods for node(%s)", newNode.Name)
@@ -97,39 +101,62 @@ func (c *Controller) onNodeUpdate(o interface{}, n interface{}) {
 	}
 }
 
-func (c *Controller) onPodUpdate(_ interface{}, n interface{}) {
+func (c *Controller) onPodAdd(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	c.enqueuePod(pod, "pod add")
+}
+
+func (c *Controller) onPodUpdate(o interface{}, n interface{}) {
+	oldPod := o.(*corev1.Pod)
 	newPod := n.(*corev1.Pod)
 
+	// a pod that is bound to a node needs its tolerations reconciled against
+	// the node pool it landed in.
+	reason := "pod update"
+	if oldPod.Spec.NodeName != newPod.Spec.NodeName {
+		reason = "pod bind"
+	}
+	c.enqueuePod(newPod, reason)
+}
+
+// enqueuePod adds pod into the work queue when its tolerations need to be reconciled,
+// such as pods with binding annotation, pods on autonomy nodes or pods on nodes whose
+// node pool specifies pod toleration seconds.
+func (c *Controller) enqueuePod(pod *corev1.Pod, reason string) {
 	// skip DaemonSet pod and static pod
-	if isDaemonSetPodOrStaticPod(newPod) {
+	if isDaemonSetPodOrStaticPod(pod) {
 		return
 	}
 
-	// skip not running pods
-	if newPod.Status.Phase != corev1.PodRunning {
+	// skip pods that are not bound to a node or have terminated
+	if len(pod.Spec.NodeName) == 0 ||
+		pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
 		return
 	}
 
 	needHandled := false
-	// only handle pods with binding annotation or work on node with autonomy annotation
-	if newPod.Annotations != nil && newPod.Annotations[constant.PodBindingAnnotation] == "true" {
+	if pod.Annotations != nil && pod.Annotations[constant.PodBindingAnnotation] == "true" {
 		needHandled = true
-	} else if len(newPod.Spec.NodeName) != 0 {
-		node, err := c.nodeLister.Get(newPod.Spec.NodeName)
+	} else {
+		node, err := c.nodeLister.Get(pod.Spec.NodeName)
 		if err != nil {
-			klog.Errorf("failed to get node(%s) for pod(%s/%s)", newPod.Spec.NodeName, newPod.Namespace, newPod.Name)
+			klog.Errorf("failed to get node(%s) for pod(%s/%s)", pod.Spec.NodeName, pod.Namespace, pod.Name)
 			return
 		}
 
-		if node.Annotations != nil && node.Annotations[projectinfo.GetAutonomyAnnotation()] == "true" {
+		if node.Annotations != nil && (node.Annotations[projectinfo.GetAutonomyAnnotation()] == "true" ||
+			len(node.Annotations[apps.AnnotationPodTolerationSeconds]) != 0) {
 			needHandled = true
 		}
 	}
 
 	if needHandled {
-		key, err := cache.MetaNamespaceKeyFunc(newPod)
+		key, err := cache.MetaNamespaceKeyFunc(pod)
 		if err == nil {
-			klog.Infof("pod(%s) tolerations should be handled for pod update", key)
+			klog.Infof("pod(%s) tolerations should be handled for %s", key, reason)
 			c.podUpdateQueue.Add(key)
 		}
 	}
@@ -150,6 +177,7 @@ func NewController(kc client.Interface, informerFactory informers.SharedInformer
 
 	podInformer := informerFactory.Core().V1().Pods()
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    ctl.onPodAdd,
 		UpdateFunc: ctl.onPodUpdate,
 	})
 	podInformer.Informer().AddIndexers(cache.Indexers{
@@ -226,8 +254,9 @@ func (c *Controller) syncHandler(key string) error {
 		shouldBeAdded = true
 	}
 
+	var node *corev1.Node
 	if len(pod.Spec.NodeName) != 0 {
-		node, _ := c.nodeLister.Get(pod.Spec.NodeName)
+		node, _ = c.nodeLister.Get(pod.Spec.NodeName)
 		if node != nil && node.Annotations != nil && node.Annotations[projectinfo.GetAutonomyAnnotation()] == "true" {
 			shouldBeAdded = true
 		}
@@ -236,7 +265,7 @@ func (c *Controller) syncHandler(key string) error {
 	if shouldBeAdded {
 		return c.configureTolerationForPod(pod, nil)
 	} else {
-		tolerationSeconds := int64(defaultTolerationSeconds)
+		tolerationSeconds := getTolerationSeconds(node)
 		return c.configureTolerationForPod(pod, &tolerationSeconds)
 	}
 }
@@ -279,6 +308,22 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	<-stopCh
 }
 
+// getTolerationSeconds returns the toleration seconds specified by the node pool of node,
+// and returns the default toleration seconds if it is not specified.
+func getTolerationSeconds(node *corev1.Node) int64 {
+	if node == nil || len(node.Annotations[apps.AnnotationPodTolerationSeconds]) == 0 {
+		return int64(defaultTolerationSeconds)
+	}
+
+	seconds, err := strconv.ParseInt(node.Annotations[apps.AnnotationPodTolerationSeconds], 10, 64)
+	if err != nil || seconds < 0 {
+		klog.Warningf("invalid pod toleration seconds(%s) of node(%s), use default toleration seconds",
+			node.Annotations[apps.AnnotationPodTolerationSeconds], node.Name)
+		return int64(defaultTolerationSeconds)
+	}
+	return seconds
+}
+
 func isDaemonSetPodOrStaticPod(pod *corev1.Pod) bool {
 	if pod != nil {
 		for i := range pod.OwnerReferences {
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podbinding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+)
+
+func TestGetTolerationSeconds(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   *corev1.Node
+		expect int64
+	}{
+		{
+			name:   "node is not found",
+			node:   nil,
+			expect: int64(defaultTolerationSeconds),
+		},
+		{
+			name:   "pool does not specify toleration seconds",
+			node:   &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+			expect: int64(defaultTolerationSeconds),
+		},
+		{
+			name: "pool specifies toleration seconds",
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:        "node1",
+				Annotations: map[string]string{apps.AnnotationPodTolerationSeconds: "3600"},
+			}},
+			expect: 3600,
+		},
+		{
+			name: "invalid toleration seconds",
+			node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:        "node1",
+				Annotations: map[string]string{apps.AnnotationPodTolerationSeconds: "-1"},
+			}},
+			expect: int64(defaultTolerationSeconds),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getTolerationSeconds(tt.node); got != tt.expect {
+				t.Errorf("expect toleration seconds %d, but got %d", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestPodCreatedAfterNodeJoinedPool(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node1",
+		Annotations: map[string]string{apps.AnnotationPodTolerationSeconds: "3600"},
+	}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodPending},
+	}
+
+	kc := fake.NewSimpleClientset(node)
+	factory := informers.NewSharedInformerFactory(kc, 0)
+	ctl := NewController(kc, factory)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, ctl.nodeSynced, ctl.podSynced) {
+		t.Fatalf("failed to sync informer cache")
+	}
+
+	// pod is created and bound after the node has already joined the pool
+	if _, err := kc.CoreV1().Pods("default").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create pod, %v", err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return ctl.podUpdateQueue.Len() != 0, nil
+	}); err != nil {
+		t.Fatalf("pod is not enqueued after it is bound to node in pool")
+	}
+	ctl.processNextItem()
+
+	got, err := kc.CoreV1().Pods("default").Get(context.TODO(), "pod1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get pod, %v", err)
+	}
+	for _, key := range []string{corev1.TaintNodeNotReady, corev1.TaintNodeUnreachable} {
+		found := false
+		for _, toleration := range got.Spec.Tolerations {
+			if toleration.Key == key && toleration.TolerationSeconds != nil && *toleration.TolerationSeconds == 3600 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expect toleration %s with 3600 seconds, but got %v", key, got.Spec.Tolerations)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaxNodeConditionsSignatureAge is the maximum age of the signature of node conditions, so the
+// conditions signed long ago can't be replayed by delegates.
+const MaxNodeConditionsSignatureAge = 5 * time.Minute
+
+// nodeConditionsSignature is the value of NodeConditionsSignatureAnnotation
+type nodeConditionsSignature struct {
+	// Certificate is the DER of client certificate of node which signs the conditions
+	Certificate []byte    `json:"certificate"`
+	SignedAt    time.Time `json:"signedAt"`
+	Signature   []byte    `json:"signature"`
+}
+
+func nodeConditionsDigest(nodeName, conditions string, signedAt time.Time) []byte {
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%s", nodeName, signedAt.UTC().Format(time.RFC3339), conditions)))
+	return digest[:]
+}
+
+// SignNodeConditions signs the value of NodeConditionsAnnotation of node by the client certificate of node,
+// and returns the value of NodeConditionsSignatureAnnotation.
+func SignNodeConditions(cert *tls.Certificate, nodeName, conditions string, now time.Time) (string, error) {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return "", fmt.Errorf("client certificate is not ready")
+	}
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("private key %T of client certificate can't sign", cert.PrivateKey)
+	}
+	sig := nodeConditionsSignature{Certificate: cert.Certificate[0], SignedAt: now.UTC().Truncate(time.Second)}
+	var err error
+	if sig.Signature, err = signer.Sign(rand.Reader, nodeConditionsDigest(nodeName, conditions, sig.SignedAt), crypto.SHA256); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(&sig)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// VerifyNodeConditions verifies the conditions of node are signed by a client certificate of the node itself,
+// which is issued by roots, rather than by the delegates which relay the conditions.
+func VerifyNodeConditions(roots *x509.CertPool, nodeName, conditions, signature string, now time.Time) error {
+	if len(signature) == 0 {
+		return fmt.Errorf("node conditions are not signed")
+	}
+	var sig nodeConditionsSignature
+	if err := json.Unmarshal([]byte(signature), &sig); err != nil {
+		return fmt.Errorf("failed to decode signature, %w", err)
+	}
+	if age := now.Sub(sig.SignedAt); age > MaxNodeConditionsSignatureAge || age < -MaxNodeConditionsSignatureAge {
+		return fmt.Errorf("signature signed at %s is expired", sig.SignedAt)
+	}
+
+	cert, err := x509.ParseCertificate(sig.Certificate)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate of signature, %w", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("certificate of signature is not trusted, %w", err)
+	}
+	if cert.Subject.CommonName != "system:node:"+nodeName {
+		return fmt.Errorf("conditions are signed by %s instead of the node", cert.Subject.CommonName)
+	}
+
+	digest := nodeConditionsDigest(nodeName, conditions, sig.SignedAt)
+	switch pub := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, sig.Signature) {
+			return fmt.Errorf("signature is invalid")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig.Signature); err != nil {
+			return fmt.Errorf("signature is invalid, %w", err)
+		}
+	default:
+		return fmt.Errorf("public key %T of certificate is not supported", cert.PublicKey)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newTestCert(t *testing.T, cn string, usage x509.ExtKeyUsage, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"system:nodes"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestVerifyNodeConditions(t *testing.T) {
+	ca, caKey := newTestCert(t, "kubernetes", x509.ExtKeyUsageAny, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	otherCA, otherCAKey := newTestCert(t, "other", x509.ExtKeyUsageAny, nil, nil)
+	newClientCert := func(cn string, usage x509.ExtKeyUsage, parent *x509.Certificate, parentKey crypto.Signer) *tls.Certificate {
+		cert, key := newTestCert(t, cn, usage, parent, parentKey)
+		return &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}
+	}
+
+	conditions := `[{"type":"KernelDeadlock","status":"True"}]`
+	now := time.Now()
+	testcases := map[string]struct {
+		cert       *tls.Certificate
+		signedAt   time.Time
+		conditions string
+		expectErr  bool
+	}{
+		"signed by node itself": {
+			cert:       newClientCert("system:node:node1", x509.ExtKeyUsageClientAuth, ca, caKey),
+			signedAt:   now,
+			conditions: conditions,
+		},
+		"signed by other node": {
+			cert:       newClientCert("system:node:node2", x509.ExtKeyUsageClientAuth, ca, caKey),
+			signedAt:   now,
+			conditions: conditions,
+			expectErr:  true,
+		},
+		"signed by untrusted certificate": {
+			cert:       newClientCert("system:node:node1", x509.ExtKeyUsageClientAuth, otherCA, otherCAKey),
+			signedAt:   now,
+			conditions: conditions,
+			expectErr:  true,
+		},
+		"signed by serving certificate": {
+			cert:       newClientCert("system:node:node1", x509.ExtKeyUsageServerAuth, ca, caKey),
+			signedAt:   now,
+			conditions: conditions,
+			expectErr:  true,
+		},
+		"expired signature": {
+			cert:       newClientCert("system:node:node1", x509.ExtKeyUsageClientAuth, ca, caKey),
+			signedAt:   now.Add(-2 * MaxNodeConditionsSignatureAge),
+			conditions: conditions,
+			expectErr:  true,
+		},
+		"conditions changed after signing": {
+			cert:       newClientCert("system:node:node1", x509.ExtKeyUsageClientAuth, ca, caKey),
+			signedAt:   now,
+			conditions: `[{"type":"KernelDeadlock","status":"False"}]`,
+			expectErr:  true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			signature, err := SignNodeConditions(tc.cert, "node1", conditions, tc.signedAt)
+			if err != nil {
+				t.Fatalf("failed to sign node conditions, %v", err)
+			}
+			err = VerifyNodeConditions(roots, "node1", tc.conditions, signature, now)
+			if tc.expectErr != (err != nil) {
+				t.Errorf("expect error %v, but got %v", tc.expectErr, err)
+			}
+		})
+	}
+
+	if err := VerifyNodeConditions(roots, "node1", conditions, "", now); err == nil {
+		t.Errorf("expect unsigned conditions are rejected")
+	}
+}
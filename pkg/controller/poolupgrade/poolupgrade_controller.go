@@ -0,0 +1,426 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolupgrade
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/controller/nodepool"
+	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
+	utildiscovery "github.com/openyurtio/openyurt/pkg/util/discovery"
+)
+
+var (
+	concurrentReconciles = 3
+	controllerKind       = appsv1beta1.GroupVersion.WithKind("PoolUpgrade")
+)
+
+const (
+	controllerName = "PoolUpgrade-controller"
+
+	// upgradeResyncPeriod is the period for checking the progress of draining and upgrade jobs
+	upgradeResyncPeriod = 10 * time.Second
+	upgradeEventReason  = "PoolUpgrade"
+)
+
+func Format(format string, args ...interface{}) string {
+	s := fmt.Sprintf(format, args...)
+	return fmt.Sprintf("%s: %s", controllerName, s)
+}
+
+// ReconcilePoolUpgrade upgrades the nodes of pool by running upgrade jobs on nodes, honoring maxUnavailable
+// and maintenance windows.
+type ReconcilePoolUpgrade struct {
+	client.Client
+	recorder record.EventRecorder
+	// kubeClient is used for listing pods on the node from kube-apiserver directly
+	kubeClient kubernetes.Interface
+	evictPod   nodepool.PodEvictor
+}
+
+var _ reconcile.Reconciler = &ReconcilePoolUpgrade{}
+
+// Add creates a new PoolUpgrade Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(c *config.CompletedConfig, mgr manager.Manager) error {
+	if !utildiscovery.DiscoverGVK(controllerKind) {
+		klog.Errorf(Format("DiscoverGVK error"))
+		return nil
+	}
+
+	return add(mgr, newReconciler(c, mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(_ *config.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
+	kubeClient := kubernetes.NewForConfigOrDie(mgr.GetConfig())
+	return &ReconcilePoolUpgrade{
+		Client:     utilclient.NewClientFromManager(mgr, controllerName),
+		recorder:   mgr.GetEventRecorderFor(controllerName),
+		kubeClient: kubeClient,
+		evictPod:   nodepool.NewPodEvictor(kubeClient),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler: r, MaxConcurrentReconciles: concurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to PoolUpgrade
+	err = c.Watch(&source.Kind{Type: &appsv1beta1.PoolUpgrade{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to upgrade jobs owned by PoolUpgrade
+	return c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
+		OwnerType: &appsv1beta1.PoolUpgrade{}, IsController: true,
+	})
+}
+
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=poolupgrades,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=poolupgrades/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+
+// Reconcile starts upgrading the nodes of pool when the number of unavailable nodes is less than maxUnavailable,
+// and records the progress of every node in the status of PoolUpgrade.
+func (r *ReconcilePoolUpgrade) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	klog.V(4).Infof(Format("Reconcile PoolUpgrade %s", req.Name))
+
+	var upgrade appsv1beta1.PoolUpgrade
+	if err := r.Get(ctx, req.NamespacedName, &upgrade); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if upgrade.DeletionTimestamp != nil ||
+		upgrade.Status.Phase == appsv1beta1.PoolUpgradeCompleted || upgrade.Status.Phase == appsv1beta1.PoolUpgradeFailed {
+		return ctrl.Result{}, nil
+	}
+
+	var nodeList corev1.NodeList
+	if err := r.List(ctx, &nodeList, client.MatchingLabels{apps.LabelCurrentNodePool: upgrade.Spec.NodePool}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	oldStatus := upgrade.Status.DeepCopy()
+	result, err := r.syncPoolUpgrade(ctx, &upgrade, nodeList.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !reflect.DeepEqual(oldStatus, &upgrade.Status) {
+		if err := r.Status().Update(ctx, &upgrade); err != nil {
+			klog.Errorf(Format("could not update status of PoolUpgrade %s, %v", upgrade.Name, err))
+			return ctrl.Result{}, err
+		}
+	}
+	return result, nil
+}
+
+// syncPoolUpgrade checks the progress of nodes which are upgrading, and starts new nodes if allowed.
+func (r *ReconcilePoolUpgrade) syncPoolUpgrade(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, nodes []corev1.Node) (reconcile.Result, error) {
+	status := &upgrade.Status
+	if len(upgrade.Spec.Steps) == 0 {
+		klog.Errorf(Format("PoolUpgrade %s has no upgrade steps", upgrade.Name))
+		status.Phase = appsv1beta1.PoolUpgradeFailed
+		return ctrl.Result{}, nil
+	}
+
+	nodeMap := make(map[string]*corev1.Node, len(nodes))
+	for i := range nodes {
+		nodeMap[nodes[i].Name] = &nodes[i]
+	}
+
+	// the nodes which are removed from the pool during upgrading are not tracked any more
+	nodeStatuses := status.Nodes[:0]
+	for _, ns := range status.Nodes {
+		if _, ok := nodeMap[ns.NodeName]; ok || ns.Phase == appsv1beta1.NodeUpgradeSucceeded || ns.Phase == appsv1beta1.NodeUpgradeFailed {
+			nodeStatuses = append(nodeStatuses, ns)
+		}
+	}
+	status.Nodes = nodeStatuses
+
+	statusMap := make(map[string]*appsv1beta1.NodeUpgradeStatus, len(status.Nodes))
+	for i := range status.Nodes {
+		ns := &status.Nodes[i]
+		statusMap[ns.NodeName] = ns
+		if ns.Phase == appsv1beta1.NodeUpgradeDraining || ns.Phase == appsv1beta1.NodeUpgradeUpgrading {
+			if err := r.syncNode(ctx, upgrade, nodeMap[ns.NodeName], ns); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	var upgraded, failed int32
+	for _, node := range nodes {
+		if ns, ok := statusMap[node.Name]; ok {
+			switch ns.Phase {
+			case appsv1beta1.NodeUpgradeSucceeded:
+				upgraded++
+			case appsv1beta1.NodeUpgradeFailed:
+				failed++
+			}
+		}
+	}
+	status.TotalNodes = int32(len(nodes))
+	status.UpgradedNodes = upgraded
+
+	pending, upgrading, unavailable := selectNodesToUpgrade(nodes, statusMap)
+	switch {
+	case failed != 0:
+		if upgrading == 0 {
+			status.Phase = appsv1beta1.PoolUpgradeFailed
+			r.recorder.Eventf(upgrade, corev1.EventTypeWarning, upgradeEventReason, "upgrade of %d nodes failed", failed)
+			return ctrl.Result{}, nil
+		}
+		// wait for the nodes which are upgrading, no new node is started
+		return ctrl.Result{RequeueAfter: upgradeResyncPeriod}, nil
+	case len(pending) == 0 && upgrading == 0:
+		status.Phase = appsv1beta1.PoolUpgradeCompleted
+		r.recorder.Eventf(upgrade, corev1.EventTypeNormal, upgradeEventReason, "%d nodes of pool %s are upgraded", upgraded, upgrade.Spec.NodePool)
+		return ctrl.Result{}, nil
+	case len(pending) == 0:
+		status.Phase = appsv1beta1.PoolUpgradeRunning
+		return ctrl.Result{RequeueAfter: upgradeResyncPeriod}, nil
+	case upgrade.Spec.Paused:
+		status.Phase = appsv1beta1.PoolUpgradePaused
+		if upgrading != 0 {
+			return ctrl.Result{RequeueAfter: upgradeResyncPeriod}, nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// the images are pre-pulled out of the maintenance windows, so the windows are spent on upgrading only
+	if upgrade.Spec.PrePull != nil {
+		prePulled, result, err := r.syncPrePull(ctx, upgrade, nodeMap, pending)
+		if err != nil || !prePulled {
+			// the nodes which are upgrading are checked periodically
+			if upgrading != 0 && result.RequeueAfter > upgradeResyncPeriod {
+				result.RequeueAfter = upgradeResyncPeriod
+			}
+			return result, err
+		}
+	}
+
+	inWindow, wait, err := inMaintenanceWindow(upgrade.Spec.MaintenanceWindows, time.Now())
+	if err != nil {
+		klog.Errorf(Format("PoolUpgrade %s has invalid maintenance windows, %v", upgrade.Name, err))
+		return ctrl.Result{}, nil
+	}
+	if !inWindow {
+		status.Phase = appsv1beta1.PoolUpgradeWaiting
+		if upgrading != 0 && wait > upgradeResyncPeriod {
+			wait = upgradeResyncPeriod
+		}
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	// the nodes are changed only in the maintenance windows of NodePool too
+	closed, err := r.isMaintenanceWindowClosed(ctx, upgrade.Spec.NodePool)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if closed {
+		status.Phase = appsv1beta1.PoolUpgradeWaiting
+		return ctrl.Result{RequeueAfter: upgradeResyncPeriod}, nil
+	}
+
+	status.Phase = appsv1beta1.PoolUpgradeRunning
+	maxUnavailable, err := getMaxUnavailable(&upgrade.Spec, len(nodes))
+	if err != nil {
+		klog.Errorf(Format("PoolUpgrade %s has invalid maxUnavailable, %v", upgrade.Name, err))
+		return ctrl.Result{}, nil
+	}
+	// the nodes which are not ready are not started, they're upgraded after they're ready again
+	for i := 0; i < len(pending) && unavailable < maxUnavailable; i++ {
+		if !isNodeReady(pending[i]) {
+			continue
+		}
+		if err := r.startNode(ctx, upgrade, pending[i]); err != nil {
+			return ctrl.Result{}, err
+		}
+		unavailable++
+	}
+	return ctrl.Result{RequeueAfter: upgradeResyncPeriod}, nil
+}
+
+// startNode records the node in status and starts draining or upgrading the node.
+func (r *ReconcilePoolUpgrade) startNode(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, node *corev1.Node) error {
+	now := metav1.Now()
+	upgrade.Status.Nodes = append(upgrade.Status.Nodes, appsv1beta1.NodeUpgradeStatus{
+		NodeName:  node.Name,
+		Phase:     appsv1beta1.NodeUpgradeUpgrading,
+		StartTime: &now,
+	})
+	ns := &upgrade.Status.Nodes[len(upgrade.Status.Nodes)-1]
+	if upgrade.Spec.Drain {
+		ns.Phase = appsv1beta1.NodeUpgradeDraining
+	}
+	klog.Infof(Format("start upgrading node %s for PoolUpgrade %s", node.Name, upgrade.Name))
+	return r.syncNode(ctx, upgrade, node, ns)
+}
+
+// syncNode drives the node through Draining and Upgrading phases.
+func (r *ReconcilePoolUpgrade) syncNode(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, node *corev1.Node, ns *appsv1beta1.NodeUpgradeStatus) error {
+	if node == nil {
+		// the node has been removed from the pool
+		return nil
+	}
+
+	if ns.Phase == appsv1beta1.NodeUpgradeDraining {
+		drained, err := r.drainNode(ctx, upgrade, node, ns)
+		if err != nil || !drained {
+			return err
+		}
+		ns.Phase = appsv1beta1.NodeUpgradeUpgrading
+	}
+
+	var job batchv1.Job
+	jobName := upgradeJobName(upgrade, node.Name)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: upgradeJobNamespace, Name: jobName}, &job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := r.Create(ctx, renderUpgradeJob(upgrade, node.Name)); err != nil && !apierrors.IsAlreadyExists(err) {
+			klog.Errorf(Format("could not create upgrade job for node %s, %v", node.Name, err))
+			return err
+		}
+		ns.Message = fmt.Sprintf("upgrade job %s/%s is created", upgradeJobNamespace, jobName)
+		return nil
+	}
+
+	cond := getJobFinishedCondition(&job)
+	if cond == nil {
+		ns.Message = fmt.Sprintf("upgrade job %s/%s is running", upgradeJobNamespace, jobName)
+		return nil
+	}
+
+	now := metav1.Now()
+	ns.CompletionTime = &now
+	if cond.Type == batchv1.JobFailed {
+		// the failed node is left cordoned for investigation
+		ns.Phase = appsv1beta1.NodeUpgradeFailed
+		ns.Message = fmt.Sprintf("upgrade job %s/%s failed, %s", upgradeJobNamespace, jobName, cond.Message)
+		r.recorder.Eventf(upgrade, corev1.EventTypeWarning, upgradeEventReason, "upgrade of node %s failed, %s", node.Name, cond.Message)
+		return nil
+	}
+	if err := r.uncordonNode(ctx, upgrade, node); err != nil {
+		return err
+	}
+	ns.Phase = appsv1beta1.NodeUpgradeSucceeded
+	ns.Message = "node is upgraded"
+	klog.Infof(Format("node %s is upgraded by PoolUpgrade %s", node.Name, upgrade.Name))
+	return nil
+}
+
+// drainNode cordons the node and evicts the pods on it, it returns true if the pods are evicted or drain timeout.
+func (r *ReconcilePoolUpgrade) drainNode(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, node *corev1.Node, ns *appsv1beta1.NodeUpgradeStatus) (bool, error) {
+	if err := r.cordonNode(ctx, upgrade, node); err != nil {
+		return false, err
+	}
+
+	podList, err := r.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var remaining int
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !isPodDrainable(pod) {
+			continue
+		}
+		remaining++
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		// eviction may be rejected by PodDisruptionBudget temporarily, it will be retried in the next round.
+		if err := r.evictPod(ctx, pod); err != nil && !apierrors.IsNotFound(err) {
+			klog.Warningf(Format("could not evict pod %s/%s for upgrading node %s, %v", pod.Namespace, pod.Name, node.Name, err))
+		}
+	}
+
+	timeout := upgrade.Spec.DrainTimeoutSeconds
+	switch {
+	case remaining == 0:
+		return true, nil
+	case timeout != nil && time.Since(ns.StartTime.Time) > time.Duration(*timeout)*time.Second:
+		r.recorder.Eventf(upgrade, corev1.EventTypeWarning, upgradeEventReason, "%d pods are not evicted from node %s in %d seconds", remaining, node.Name, *timeout)
+		return true, nil
+	default:
+		ns.Message = fmt.Sprintf("waiting for %d pods to be evicted", remaining)
+		return false, nil
+	}
+}
+
+// cordonNode marks the node unschedulable, and records the PoolUpgrade which cordons the node.
+func (r *ReconcilePoolUpgrade) cordonNode(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, node *corev1.Node) error {
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = true
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[AnnotationCordonedBy] = upgrade.Name
+	return r.Patch(ctx, node, patch)
+}
+
+// uncordonNode marks the node schedulable if it's cordoned by the PoolUpgrade.
+func (r *ReconcilePoolUpgrade) uncordonNode(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, node *corev1.Node) error {
+	if node.Annotations[AnnotationCordonedBy] != upgrade.Name {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = false
+	delete(node.Annotations, AnnotationCordonedBy)
+	return r.Patch(ctx, node, patch)
+}
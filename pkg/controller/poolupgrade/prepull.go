@@ -0,0 +1,275 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolupgrade
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	nodeservant "github.com/openyurtio/openyurt/pkg/node-servant"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+)
+
+const (
+	// LabelPoolUpgradePrePull is added to the pre-pull jobs, the value is the name of PoolUpgrade.
+	LabelPoolUpgradePrePull = "apps.openyurt.io/pool-upgrade-pre-pull"
+
+	prePullContainerName = "pull-image"
+)
+
+var (
+	prePullJobBackoffLimit       int32 = 2
+	defaultPrePullTimeoutSeconds int64 = 3600
+)
+
+// syncPrePull pre-pulls the images on the ready nodes which haven't started upgrading, honoring
+// maxConcurrency and the off-peak windows of pre-pull. It returns true if the pre-pull is finished
+// on all these nodes, the nodes which failed pre-pulling are upgraded as well and pull the images
+// by their upgrade jobs.
+func (r *ReconcilePoolUpgrade) syncPrePull(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, nodeMap map[string]*corev1.Node, pending []*corev1.Node) (bool, reconcile.Result, error) {
+	status := &upgrade.Status
+	prePull := upgrade.Spec.PrePull
+
+	// the nodes which are removed from the pool are not tracked any more
+	prePullStatuses := status.PrePullNodes[:0]
+	for _, ps := range status.PrePullNodes {
+		if _, ok := nodeMap[ps.NodeName]; ok {
+			prePullStatuses = append(prePullStatuses, ps)
+		}
+	}
+	status.PrePullNodes = prePullStatuses
+
+	statusMap := make(map[string]*appsv1beta1.NodePrePullStatus, len(status.PrePullNodes))
+	var pulled, pulling int32
+	for i := range status.PrePullNodes {
+		ps := &status.PrePullNodes[i]
+		statusMap[ps.NodeName] = ps
+		if ps.Phase == appsv1beta1.NodePrePullPulling {
+			if err := r.syncPrePullNode(ctx, upgrade, ps); err != nil {
+				return false, ctrl.Result{}, err
+			}
+		}
+		switch ps.Phase {
+		case appsv1beta1.NodePrePullReady:
+			pulled++
+		case appsv1beta1.NodePrePullPulling:
+			pulling++
+		}
+	}
+	status.PrePulledNodes = pulled
+
+	waiting := selectNodesToPrePull(pending, statusMap)
+	if len(waiting) == 0 && pulling == 0 {
+		return true, ctrl.Result{}, nil
+	}
+
+	status.Phase = appsv1beta1.PoolUpgradePrePulling
+	inWindow, wait, err := inMaintenanceWindow(prePull.Windows, time.Now())
+	if err != nil {
+		klog.Errorf(Format("PoolUpgrade %s has invalid pre-pull windows, %v", upgrade.Name, err))
+		return false, ctrl.Result{}, nil
+	}
+	if !inWindow {
+		if pulling != 0 && wait > upgradeResyncPeriod {
+			wait = upgradeResyncPeriod
+		}
+		return false, ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	maxConcurrency := int32(1)
+	if prePull.MaxConcurrency != nil && *prePull.MaxConcurrency > 1 {
+		maxConcurrency = *prePull.MaxConcurrency
+	}
+	for i := 0; i < len(waiting) && pulling < maxConcurrency; i++ {
+		if err := r.startPrePull(ctx, upgrade, waiting[i]); err != nil {
+			return false, ctrl.Result{}, err
+		}
+		pulling++
+	}
+	return false, ctrl.Result{RequeueAfter: upgradeResyncPeriod}, nil
+}
+
+// startPrePull records the node in status and creates the pre-pull job for the node.
+func (r *ReconcilePoolUpgrade) startPrePull(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, node *corev1.Node) error {
+	upgrade.Status.PrePullNodes = append(upgrade.Status.PrePullNodes, appsv1beta1.NodePrePullStatus{
+		NodeName: node.Name,
+		Phase:    appsv1beta1.NodePrePullPulling,
+	})
+	klog.Infof(Format("start pre-pulling images on node %s for PoolUpgrade %s", node.Name, upgrade.Name))
+	return r.syncPrePullNode(ctx, upgrade, &upgrade.Status.PrePullNodes[len(upgrade.Status.PrePullNodes)-1])
+}
+
+// syncPrePullNode creates the pre-pull job for the node if not exists, and records the result of job.
+func (r *ReconcilePoolUpgrade) syncPrePullNode(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, ps *appsv1beta1.NodePrePullStatus) error {
+	var job batchv1.Job
+	jobName := prePullJobName(upgrade, ps.NodeName)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: upgradeJobNamespace, Name: jobName}, &job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		mirror, err := r.getP2PImageMirror(ctx, upgrade.Spec.NodePool)
+		if err != nil {
+			return err
+		}
+		if err := r.Create(ctx, renderPrePullJob(upgrade, ps.NodeName, mirror)); err != nil && !apierrors.IsAlreadyExists(err) {
+			klog.Errorf(Format("could not create pre-pull job for node %s, %v", ps.NodeName, err))
+			return err
+		}
+		ps.Message = fmt.Sprintf("pre-pull job %s/%s is created", upgradeJobNamespace, jobName)
+		return nil
+	}
+
+	cond := getJobFinishedCondition(&job)
+	switch {
+	case cond == nil && prePullTimedOut(upgrade, &job, time.Now()):
+		// the job is failed by job controller after the deadline as well, but the node shouldn't
+		// hold the slot of pre-pulling if the job controller is not done yet
+		ps.Phase = appsv1beta1.NodePrePullFailed
+		ps.Message = fmt.Sprintf("pre-pull job %s/%s is not finished in %ds", upgradeJobNamespace, jobName, prePullTimeoutSeconds(upgrade))
+		r.recorder.Eventf(upgrade, corev1.EventTypeWarning, upgradeEventReason, "pre-pull of node %s timed out", ps.NodeName)
+	case cond == nil:
+		ps.Message = fmt.Sprintf("pre-pull job %s/%s is running", upgradeJobNamespace, jobName)
+	case cond.Type == batchv1.JobFailed:
+		ps.Phase = appsv1beta1.NodePrePullFailed
+		ps.Message = fmt.Sprintf("pre-pull job %s/%s failed, %s", upgradeJobNamespace, jobName, cond.Message)
+		r.recorder.Eventf(upgrade, corev1.EventTypeWarning, upgradeEventReason, "pre-pull of node %s failed, %s", ps.NodeName, cond.Message)
+	default:
+		ps.Phase = appsv1beta1.NodePrePullReady
+		ps.Message = "images are pre-pulled"
+		klog.Infof(Format("images are pre-pulled on node %s for PoolUpgrade %s", ps.NodeName, upgrade.Name))
+	}
+	return nil
+}
+
+// prePullTimeoutSeconds returns the deadline of pre-pull job.
+func prePullTimeoutSeconds(upgrade *appsv1beta1.PoolUpgrade) int64 {
+	if upgrade.Spec.PrePull.TimeoutSeconds > 0 {
+		return upgrade.Spec.PrePull.TimeoutSeconds
+	}
+	return defaultPrePullTimeoutSeconds
+}
+
+// prePullTimedOut checks the pre-pull job is not finished before the deadline.
+func prePullTimedOut(upgrade *appsv1beta1.PoolUpgrade, job *batchv1.Job, now time.Time) bool {
+	start := job.CreationTimestamp.Time
+	if job.Status.StartTime != nil {
+		start = job.Status.StartTime.Time
+	}
+	return now.Sub(start) > time.Duration(prePullTimeoutSeconds(upgrade))*time.Second
+}
+
+// getP2PImageMirror returns the p2p image mirror enabled for the pool, empty if not enabled.
+func (r *ReconcilePoolUpgrade) getP2PImageMirror(ctx context.Context, poolName string) (string, error) {
+	var pool appsv1beta1.NodePool
+	if err := r.Get(ctx, types.NamespacedName{Name: poolName}, &pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return pool.Annotations[apps.AnnotationP2PImageMirror], nil
+}
+
+// selectNodesToPrePull returns the ready nodes which haven't started pre-pulling, the images of
+// nodes which are not ready are pulled by their upgrade jobs.
+func selectNodesToPrePull(pending []*corev1.Node, statuses map[string]*appsv1beta1.NodePrePullStatus) []*corev1.Node {
+	var nodes []*corev1.Node
+	for _, node := range pending {
+		if _, ok := statuses[node.Name]; !ok && isNodeReady(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// prePullJobName returns the name of pre-pull job for the node, the node name is hashed like upgradeJobName.
+func prePullJobName(upgrade *appsv1beta1.PoolUpgrade, nodeName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(nodeName))
+	return fmt.Sprintf("%.35s-pull-%08x", upgrade.Name, h.Sum32())
+}
+
+// renderPrePullJob renders the job which pulls and verifies the images on the node by node-servant,
+// the host root filesystem is mounted like the upgrade jobs so the images are pulled by the node runtime.
+// The images are fetched from the peers in pool through mirror if it's not empty.
+func renderPrePullJob(upgrade *appsv1beta1.PoolUpgrade, nodeName, mirror string) *batchv1.Job {
+	args := nodeservant.PrePullArgs(upgrade)
+	if len(mirror) != 0 {
+		args = append(args, "--p2p-mirror="+mirror)
+	}
+
+	privileged := true
+	hostPathType := corev1.HostPathDirectory
+	deadline := prePullTimeoutSeconds(upgrade)
+	labels := map[string]string{LabelPoolUpgradePrePull: upgrade.Name}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            prePullJobName(upgrade, nodeName),
+			Namespace:       upgradeJobNamespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(upgrade, controllerKind)},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &prePullJobBackoffLimit,
+			// the job tolerates all taints, so it's pending forever on the offline node without deadline
+			ActiveDeadlineSeconds: &deadline,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						signature.ContainerSignaturesAnnotation: signature.ContainerSignatures(map[string]string{prePullContainerName: upgrade.Spec.PrePull.Signature}),
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      nodeName,
+					HostPID:       true,
+					HostNetwork:   true,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					Containers: []corev1.Container{{
+						Name:            prePullContainerName,
+						Image:           upgrade.Spec.PrePull.PullerImage,
+						Command:         []string{signature.NodeServantEntry},
+						Args:            args,
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+						VolumeMounts:    []corev1.VolumeMount{{Name: hostRootVolume, MountPath: hostRootMountPath}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: hostRootVolume,
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{Path: "/", Type: &hostPathType},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
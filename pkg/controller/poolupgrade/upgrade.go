@@ -0,0 +1,243 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolupgrade
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+	"github.com/openyurtio/openyurt/pkg/util/maintenancewindow"
+)
+
+const (
+	// LabelPoolUpgrade is added to the upgrade jobs, the value is the name of PoolUpgrade.
+	LabelPoolUpgrade = "apps.openyurt.io/pool-upgrade"
+	// AnnotationCordonedBy is added to the node which is cordoned for draining, the value is the name
+	// of PoolUpgrade, so nodes cordoned by others are not uncordoned after the upgrade.
+	AnnotationCordonedBy = "apps.openyurt.io/pool-upgrade-cordoned-by"
+
+	upgradeJobNamespace = "kube-system"
+	hostRootVolume      = "host-root"
+	hostRootMountPath   = "/openyurt"
+	maintenanceTimeFmt  = "15:04"
+)
+
+var (
+	upgradeJobBackoffLimit int32 = 2
+)
+
+// getMaxUnavailable returns the maximum number of unavailable nodes, at least one node is allowed.
+func getMaxUnavailable(spec *appsv1beta1.PoolUpgradeSpec, total int) (int, error) {
+	maxUnavailable := intstr.FromInt(1)
+	if spec.MaxUnavailable != nil {
+		maxUnavailable = *spec.MaxUnavailable
+	}
+	n, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, total, false)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
+
+// inMaintenanceWindow returns whether now is in any of the windows, and how long to wait for the
+// next window if not. It's always in window if no window is specified.
+func inMaintenanceWindow(windows []appsv1beta1.MaintenanceWindow, now time.Time) (bool, time.Duration, error) {
+	if len(windows) == 0 {
+		return true, 0, nil
+	}
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var wait time.Duration
+	for _, w := range windows {
+		t, err := time.Parse(maintenanceTimeFmt, w.Start)
+		if err != nil {
+			return false, 0, fmt.Errorf("invalid start %q of maintenance window, %v", w.Start, err)
+		}
+		offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		// the window started yesterday may last until today
+		for _, start := range []time.Time{today.Add(offset).AddDate(0, 0, -1), today.Add(offset), today.Add(offset).AddDate(0, 0, 1)} {
+			if !now.Before(start) && now.Before(start.Add(w.Duration.Duration)) {
+				return true, 0, nil
+			}
+			if start.After(now) && (wait == 0 || start.Sub(now) < wait) {
+				wait = start.Sub(now)
+			}
+		}
+	}
+	return false, wait, nil
+}
+
+// selectNodesToUpgrade returns the nodes which haven't started upgrading in the order of names, the
+// number of nodes which are upgrading, and the number of nodes which are unavailable because of
+// upgrading or not ready. Every node which is not ready now is unavailable, including the nodes
+// which have been upgraded.
+func selectNodesToUpgrade(nodes []corev1.Node, statuses map[string]*appsv1beta1.NodeUpgradeStatus) ([]*corev1.Node, int, int) {
+	var pending []*corev1.Node
+	var upgrading, unavailable int
+	for i := range nodes {
+		status, started := statuses[nodes[i].Name]
+		if !started {
+			pending = append(pending, &nodes[i])
+		} else if status.Phase == appsv1beta1.NodeUpgradeDraining || status.Phase == appsv1beta1.NodeUpgradeUpgrading {
+			upgrading++
+			unavailable++
+			continue
+		}
+		if !isNodeReady(&nodes[i]) {
+			unavailable++
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Name < pending[j].Name
+	})
+	return pending, upgrading, unavailable
+}
+
+// isMaintenanceWindowClosed returns true if the maintenance windows of the pool are closed. The
+// windows are read from NodePool instead of the state published on nodes, which can be changed
+// by the nodes themselves.
+func (r *ReconcilePoolUpgrade) isMaintenanceWindowClosed(ctx context.Context, poolName string) (bool, error) {
+	var pool appsv1beta1.NodePool
+	if err := r.Get(ctx, types.NamespacedName{Name: poolName}, &pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return maintenancewindow.Closed(pool.Spec.MaintenanceWindows, time.Now()), nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isPodDrainable returns true if the pod should be evicted before upgrading the node,
+// DaemonSet pods and static pods are left on the node.
+func isPodDrainable(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if len(pod.Annotations[corev1.MirrorPodAnnotationKey]) != 0 {
+		return false
+	}
+	for i := range pod.OwnerReferences {
+		if pod.OwnerReferences[i].Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// upgradeJobName returns the name of upgrade job for the node, the node name is hashed
+// because the job name is used as a label value of pods which is limited to 63 characters.
+func upgradeJobName(upgrade *appsv1beta1.PoolUpgrade, nodeName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(nodeName))
+	return fmt.Sprintf("%.40s-%08x", upgrade.Name, h.Sum32())
+}
+
+// renderUpgradeJob renders the job which runs the upgrade steps in order on the node, all steps except
+// the last one run as init containers. The pod is bound to the node directly, so it runs on the cordoned node.
+// The offline signatures of steps are recorded in the pod, and verified by yurthub on the node.
+func renderUpgradeJob(upgrade *appsv1beta1.PoolUpgrade, nodeName string) *batchv1.Job {
+	var containers []corev1.Container
+	sigs := make(map[string]string, len(upgrade.Spec.Steps))
+	for _, step := range upgrade.Spec.Steps {
+		sigs[step.Name] = step.Signature
+		privileged := true
+		containers = append(containers, corev1.Container{
+			Name:            step.Name,
+			Image:           step.Image,
+			Command:         step.Command,
+			Args:            step.Args,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+			VolumeMounts:    []corev1.VolumeMount{{Name: hostRootVolume, MountPath: hostRootMountPath}},
+			Env: []corev1.EnvVar{{
+				Name:      "NODE_NAME",
+				ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+			}},
+		})
+	}
+
+	hostPathType := corev1.HostPathDirectory
+	labels := map[string]string{LabelPoolUpgrade: upgrade.Name}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            upgradeJobName(upgrade, nodeName),
+			Namespace:       upgradeJobNamespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(upgrade, controllerKind)},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &upgradeJobBackoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{signature.ContainerSignaturesAnnotation: signature.ContainerSignatures(sigs)},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:       nodeName,
+					HostPID:        true,
+					HostNetwork:    true,
+					RestartPolicy:  corev1.RestartPolicyNever,
+					Tolerations:    []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					InitContainers: containers[:len(containers)-1],
+					Containers:     containers[len(containers)-1:],
+					Volumes: []corev1.Volume{{
+						Name: hostRootVolume,
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{Path: "/", Type: &hostPathType},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// getJobFinishedCondition returns the Complete or Failed condition of job, nil if the job is not finished.
+func getJobFinishedCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		cond := &job.Status.Conditions[i]
+		if (cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed) && cond.Status == corev1.ConditionTrue {
+			return cond
+		}
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolupgrade
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+)
+
+func TestGetMaxUnavailable(t *testing.T) {
+	intValue := intstr.FromInt(3)
+	percentValue := intstr.FromString("25%")
+	smallPercentValue := intstr.FromString("5%")
+	testcases := map[string]struct {
+		maxUnavailable *intstr.IntOrString
+		total          int
+		expect         int
+	}{
+		"default": {
+			total:  10,
+			expect: 1,
+		},
+		"absolute number": {
+			maxUnavailable: &intValue,
+			total:          10,
+			expect:         3,
+		},
+		"percentage": {
+			maxUnavailable: &percentValue,
+			total:          10,
+			expect:         2,
+		},
+		"at least one node": {
+			maxUnavailable: &smallPercentValue,
+			total:          10,
+			expect:         1,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			n, err := getMaxUnavailable(&appsv1beta1.PoolUpgradeSpec{MaxUnavailable: tc.maxUnavailable}, tc.total)
+			if err != nil {
+				t.Fatalf("unexpected error, %v", err)
+			}
+			if n != tc.expect {
+				t.Errorf("expect %d, but got %d", tc.expect, n)
+			}
+		})
+	}
+}
+
+func TestInMaintenanceWindow(t *testing.T) {
+	windows := []appsv1beta1.MaintenanceWindow{
+		{Start: "02:00", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+		{Start: "23:00", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+	}
+	testcases := map[string]struct {
+		windows    []appsv1beta1.MaintenanceWindow
+		now        time.Time
+		expectIn   bool
+		expectWait time.Duration
+		expectErr  bool
+	}{
+		"no windows": {
+			now:      time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+			expectIn: true,
+		},
+		"in window": {
+			windows:  windows,
+			now:      time.Date(2023, 5, 1, 3, 0, 0, 0, time.UTC),
+			expectIn: true,
+		},
+		"in window started yesterday": {
+			windows:  windows,
+			now:      time.Date(2023, 5, 1, 0, 30, 0, 0, time.UTC),
+			expectIn: true,
+		},
+		"between windows": {
+			windows:    windows,
+			now:        time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+			expectWait: 11 * time.Hour,
+		},
+		"after window end": {
+			windows:    windows,
+			now:        time.Date(2023, 5, 1, 1, 0, 0, 0, time.UTC),
+			expectWait: time.Hour,
+		},
+		"time in other zone": {
+			windows:  windows,
+			now:      time.Date(2023, 5, 1, 11, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+			expectIn: true,
+		},
+		"invalid start": {
+			windows:   []appsv1beta1.MaintenanceWindow{{Start: "2am"}},
+			now:       time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+			expectErr: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			in, wait, err := inMaintenanceWindow(tc.windows, tc.now)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expect error %v, but got %v", tc.expectErr, err)
+			}
+			if in != tc.expectIn || wait != tc.expectWait {
+				t.Errorf("expect in window %v and wait %v, but got %v and %v", tc.expectIn, tc.expectWait, in, wait)
+			}
+		})
+	}
+}
+
+func newNode(name string, ready bool) corev1.Node {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func TestSelectNodesToUpgrade(t *testing.T) {
+	nodes := []corev1.Node{
+		newNode("node-f", false),
+		newNode("node-e", true),
+		newNode("node-d", false),
+		newNode("node-c", true),
+		newNode("node-b", true),
+		newNode("node-a", true),
+	}
+	statuses := map[string]*appsv1beta1.NodeUpgradeStatus{
+		"node-a": {NodeName: "node-a", Phase: appsv1beta1.NodeUpgradeSucceeded},
+		"node-b": {NodeName: "node-b", Phase: appsv1beta1.NodeUpgradeUpgrading},
+		"node-c": {NodeName: "node-c", Phase: appsv1beta1.NodeUpgradeDraining},
+		"node-f": {NodeName: "node-f", Phase: appsv1beta1.NodeUpgradeSucceeded},
+	}
+
+	pending, upgrading, unavailable := selectNodesToUpgrade(nodes, statuses)
+	var names []string
+	for _, node := range pending {
+		names = append(names, node.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"node-d", "node-e"}) {
+		t.Errorf("expect pending nodes [node-d node-e], but got %v", names)
+	}
+	if upgrading != 2 {
+		t.Errorf("expect 2 upgrading nodes, but got %d", upgrading)
+	}
+	// node-f is upgraded but not ready now
+	if unavailable != 4 {
+		t.Errorf("expect 4 unavailable nodes, but got %d", unavailable)
+	}
+}
+
+func TestRenderUpgradeJob(t *testing.T) {
+	upgrade := &appsv1beta1.PoolUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade-hangzhou", UID: "uid"},
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			NodePool: "hangzhou",
+			Steps: []appsv1beta1.PoolUpgradeStep{
+				{Name: "yurthub", Image: "openyurt/node-servant:v1.3.0", Args: []string{"upgrade", "yurthub"}},
+				{Name: "kubelet", Image: "openyurt/node-servant:v1.3.0", Args: []string{"upgrade", "kubelet"}},
+				{Name: "os-hook", Image: "example/os-hook:v1"},
+			},
+		},
+	}
+
+	job := renderUpgradeJob(upgrade, "edge-node-1")
+	if job.Name != upgradeJobName(upgrade, "edge-node-1") || job.Namespace != upgradeJobNamespace {
+		t.Errorf("unexpected job %s/%s", job.Namespace, job.Name)
+	}
+	if len(job.Name) > 63 {
+		t.Errorf("job name %s is too long", job.Name)
+	}
+	if len(job.OwnerReferences) != 1 || job.OwnerReferences[0].Name != upgrade.Name {
+		t.Errorf("unexpected owner references %v", job.OwnerReferences)
+	}
+
+	podSpec := job.Spec.Template.Spec
+	if podSpec.NodeName != "edge-node-1" {
+		t.Errorf("expect pod on node edge-node-1, but got %s", podSpec.NodeName)
+	}
+	if len(podSpec.InitContainers) != 2 || podSpec.InitContainers[0].Name != "yurthub" || podSpec.InitContainers[1].Name != "kubelet" {
+		t.Errorf("unexpected init containers %v", podSpec.InitContainers)
+	}
+	if len(podSpec.Containers) != 1 || podSpec.Containers[0].Name != "os-hook" {
+		t.Errorf("unexpected containers %v", podSpec.Containers)
+	}
+
+	if upgradeJobName(upgrade, "edge-node-1") == upgradeJobName(upgrade, "edge-node-2") {
+		t.Errorf("expect different job names for different nodes")
+	}
+}
+
+func TestUpgradeJobSignatures(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+	upgradeArgs := []string{"upgrade", "yurthub", "--yurthub-image=openyurt/yurthub@sha256:0123"}
+	upgrade := &appsv1beta1.PoolUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade-hangzhou", UID: "uid"},
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			NodePool: "hangzhou",
+			Steps: []appsv1beta1.PoolUpgradeStep{
+				{
+					Name: "yurthub", Image: "openyurt/node-servant:v1.3.0", Command: []string{signature.NodeServantEntry}, Args: upgradeArgs,
+					Signature: signature.SignContainer(key, "openyurt/node-servant:v1.3.0", []string{signature.NodeServantEntry}, upgradeArgs),
+				},
+				{Name: "os-hook", Image: "example/os-hook:v1", Args: []string{"--reboot"}},
+			},
+		},
+	}
+
+	template := renderUpgradeJob(upgrade, "edge-node-1").Spec.Template
+	sigs := map[string]string{}
+	if err := json.Unmarshal([]byte(template.Annotations[signature.ContainerSignaturesAnnotation]), &sigs); err != nil {
+		t.Fatalf("invalid container signatures, %v", err)
+	}
+	if _, ok := sigs["os-hook"]; ok || len(sigs) != 1 {
+		t.Errorf("expect only the signed step recorded, but got %v", sigs)
+	}
+
+	c := template.Spec.InitContainers[0]
+	if !reflect.DeepEqual(c.Args, upgradeArgs) {
+		t.Errorf("expect args of step not changed, but got %v", c.Args)
+	}
+	sig, err := base64.StdEncoding.DecodeString(sigs[c.Name])
+	if err != nil || !ed25519.Verify(pub, signature.ContainerMessage(c.Image, c.Command, c.Args), sig) {
+		t.Errorf("expect the signature of step %s verified", c.Name)
+	}
+}
+
+func TestSelectNodesToPrePull(t *testing.T) {
+	nodes := []corev1.Node{
+		newNode("node-a", true),
+		newNode("node-b", false),
+		newNode("node-c", true),
+	}
+	pending := []*corev1.Node{&nodes[0], &nodes[1], &nodes[2]}
+	statuses := map[string]*appsv1beta1.NodePrePullStatus{
+		"node-a": {NodeName: "node-a", Phase: appsv1beta1.NodePrePullReady},
+	}
+
+	waiting := selectNodesToPrePull(pending, statuses)
+	if len(waiting) != 1 || waiting[0].Name != "node-c" {
+		t.Errorf("expect node-c to pre-pull, but got %v", waiting)
+	}
+}
+
+func TestRenderPrePullJob(t *testing.T) {
+	upgrade := &appsv1beta1.PoolUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade-hangzhou", UID: "uid"},
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			NodePool: "hangzhou",
+			Steps: []appsv1beta1.PoolUpgradeStep{
+				{Name: "yurthub", Image: "openyurt/node-servant:v1.3.0", Args: []string{"upgrade", "yurthub"}},
+			},
+			PrePull: &appsv1beta1.PoolUpgradePrePull{
+				PullerImage:         "openyurt/node-servant:v1.3.0",
+				Images:              []string{"openyurt/yurthub@sha256:0123"},
+				PullIntervalSeconds: 30,
+			},
+		},
+	}
+
+	job := renderPrePullJob(upgrade, "edge-node-1", "")
+	if job.Name == upgradeJobName(upgrade, "edge-node-1") || len(job.Name) > 63 {
+		t.Errorf("unexpected job name %s", job.Name)
+	}
+	podSpec := job.Spec.Template.Spec
+	if podSpec.NodeName != "edge-node-1" || len(podSpec.Containers) != 1 {
+		t.Fatalf("unexpected pod spec %v", podSpec)
+	}
+	expectArgs := []string{"pull-image", "--images=openyurt/node-servant:v1.3.0,openyurt/yurthub@sha256:0123", "--pull-interval=30s"}
+	if !reflect.DeepEqual(podSpec.Containers[0].Args, expectArgs) {
+		t.Errorf("expect args %v, but got %v", expectArgs, podSpec.Containers[0].Args)
+	}
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != defaultPrePullTimeoutSeconds {
+		t.Errorf("expect active deadline %ds, but got %v", defaultPrePullTimeoutSeconds, job.Spec.ActiveDeadlineSeconds)
+	}
+}
+
+func TestPrePullTimedOut(t *testing.T) {
+	now := time.Now()
+	upgrade := &appsv1beta1.PoolUpgrade{
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			PrePull: &appsv1beta1.PoolUpgradePrePull{TimeoutSeconds: 600},
+		},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-20 * time.Minute))}}
+	if !prePullTimedOut(upgrade, job, now) {
+		t.Errorf("expect pre-pull job timed out")
+	}
+	startTime := metav1.NewTime(now.Add(-5 * time.Minute))
+	job.Status.StartTime = &startTime
+	if prePullTimedOut(upgrade, job, now) {
+		t.Errorf("expect pre-pull job not timed out since it's started")
+	}
+}
+
+func TestRenderPrePullJobWithP2PMirror(t *testing.T) {
+	upgrade := &appsv1beta1.PoolUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade-hangzhou", UID: "uid"},
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			NodePool: "hangzhou",
+			Steps:    []appsv1beta1.PoolUpgradeStep{{Name: "yurthub", Image: "openyurt/node-servant:v1.3.0"}},
+			PrePull:  &appsv1beta1.PoolUpgradePrePull{PullerImage: "openyurt/node-servant:v1.3.0"},
+		},
+	}
+
+	job := renderPrePullJob(upgrade, "edge-node-1", "http://127.0.0.1:5001")
+	expectArgs := []string{"pull-image", "--images=openyurt/node-servant:v1.3.0", "--p2p-mirror=http://127.0.0.1:5001"}
+	if args := job.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(args, expectArgs) {
+		t.Errorf("expect args %v, but got %v", expectArgs, args)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgradecampaign
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+const (
+	// LabelUpgradeCampaign is added to the PoolUpgrades created by campaign, the value is the name of UpgradeCampaign.
+	LabelUpgradeCampaign = "apps.openyurt.io/upgrade-campaign"
+
+	// maxReportedFailures is the maximum number of failures recorded in status, which keeps the
+	// status small when a bad build fails on many nodes.
+	maxReportedFailures = 50
+)
+
+// poolUpgradeName returns the name of PoolUpgrade for the pool, the pool name is hashed because the
+// name of PoolUpgrade is used as a label value which is limited to 63 characters.
+func poolUpgradeName(campaign *appsv1beta1.UpgradeCampaign, poolName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(poolName))
+	return fmt.Sprintf("%.40s-%08x", campaign.Name, h.Sum32())
+}
+
+// renderPoolUpgrade renders the PoolUpgrade of the pool from campaign, the nodes of pool are
+// upgraded within the maxNodesPerPool of budget.
+func renderPoolUpgrade(campaign *appsv1beta1.UpgradeCampaign, poolName string) *appsv1beta1.PoolUpgrade {
+	spec := campaign.Spec.DeepCopy()
+	return &appsv1beta1.PoolUpgrade{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            poolUpgradeName(campaign, poolName),
+			Labels:          map[string]string{LabelUpgradeCampaign: campaign.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(campaign, controllerKind)},
+		},
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			NodePool:            poolName,
+			Steps:               spec.Steps,
+			MaxUnavailable:      spec.Budget.MaxNodesPerPool,
+			Drain:               spec.Drain,
+			DrainTimeoutSeconds: spec.DrainTimeoutSeconds,
+			MaintenanceWindows:  spec.MaintenanceWindows,
+			PrePull:             spec.PrePull,
+			Paused:              spec.Paused,
+		},
+	}
+}
+
+// poolRolloutStatusOf returns the progress of pool from its PoolUpgrade.
+func poolRolloutStatusOf(poolName string, upgrade *appsv1beta1.PoolUpgrade) appsv1beta1.PoolRolloutStatus {
+	status := appsv1beta1.PoolRolloutStatus{
+		NodePool:      poolName,
+		PoolUpgrade:   upgrade.Name,
+		Phase:         upgrade.Status.Phase,
+		TotalNodes:    upgrade.Status.TotalNodes,
+		UpgradedNodes: upgrade.Status.UpgradedNodes,
+	}
+	for _, ns := range upgrade.Status.Nodes {
+		if ns.Phase == appsv1beta1.NodeUpgradeFailed {
+			status.FailedNodes++
+		}
+	}
+	return status
+}
+
+// isPoolUpgradeFinished returns true if the PoolUpgrade is completed or failed.
+func isPoolUpgradeFinished(upgrade *appsv1beta1.PoolUpgrade) bool {
+	return upgrade.Status.Phase == appsv1beta1.PoolUpgradeCompleted || upgrade.Status.Phase == appsv1beta1.PoolUpgradeFailed
+}
+
+func getMaxConcurrentPools(budget *appsv1beta1.RolloutBudget) int32 {
+	if budget.MaxConcurrentPools == nil || *budget.MaxConcurrentPools < 1 {
+		return 1
+	}
+	return *budget.MaxConcurrentPools
+}
+
+func getFailureThreshold(budget *appsv1beta1.RolloutBudget) int32 {
+	if budget.FailureThreshold == nil || *budget.FailureThreshold < 1 {
+		return 1
+	}
+	return *budget.FailureThreshold
+}
+
+// updateFailurePause pauses campaign when the nodes failed since the last acknowledgement reach the failure
+// threshold, and clears the pause once the spec of campaign is changed after it, e.g. the campaign is paused and
+// unpaused or the threshold is raised. The failures before the pause is cleared are acknowledged, so they don't
+// pause the campaign again. It returns true if the campaign is paused by failures in this call.
+func updateFailurePause(campaign *appsv1beta1.UpgradeCampaign, failedNodes, threshold int32) bool {
+	status := &campaign.Status
+	if status.FailurePaused && campaign.Generation != status.FailurePausedGeneration {
+		status.FailurePaused = false
+		status.FailurePausedGeneration = 0
+		status.AcknowledgedFailedNodes = failedNodes
+	}
+	if status.AcknowledgedFailedNodes > failedNodes {
+		status.AcknowledgedFailedNodes = failedNodes
+	}
+	if status.FailurePaused || failedNodes-status.AcknowledgedFailedNodes < threshold {
+		return false
+	}
+	status.FailurePaused = true
+	status.FailurePausedGeneration = campaign.Generation
+	return true
+}
+
+// targetRevisionOf returns the hash of upgrade steps, which identifies the build nodes are upgraded to.
+func targetRevisionOf(spec *appsv1beta1.UpgradeCampaignSpec) string {
+	data, _ := json.Marshal(spec.Steps)
+	h := fnv.New32a()
+	h.Write(data)
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// summarizeNodes counts the nodes of pools by upgrade state and collects the failures of nodes. The sizes
+// of pools which haven't reported their nodes by PoolUpgrades are got from poolSizes.
+func summarizeNodes(pools []string, upgrades map[string]*appsv1beta1.PoolUpgrade, poolSizes map[string]int32) (appsv1beta1.CampaignNodeCounts, []appsv1beta1.NodeUpgradeFailure) {
+	var counts appsv1beta1.CampaignNodeCounts
+	var failures []appsv1beta1.NodeUpgradeFailure
+	for _, poolName := range pools {
+		total := poolSizes[poolName]
+		var started int32
+		if upgrade, ok := upgrades[poolName]; ok {
+			if upgrade.Status.TotalNodes != 0 {
+				total = upgrade.Status.TotalNodes
+			}
+			for _, ns := range upgrade.Status.Nodes {
+				started++
+				switch ns.Phase {
+				case appsv1beta1.NodeUpgradeSucceeded:
+					counts.Succeeded++
+				case appsv1beta1.NodeUpgradeFailed:
+					counts.Failed++
+					if len(failures) < maxReportedFailures {
+						failures = append(failures, appsv1beta1.NodeUpgradeFailure{NodePool: poolName, NodeName: ns.NodeName, Reason: ns.Message})
+					}
+				default:
+					counts.Upgrading++
+				}
+			}
+		}
+		// the nodes which are removed from the pool after upgrading are still counted
+		if total < started {
+			total = started
+		}
+		counts.Total += total
+		counts.Pending += total - started
+	}
+	return counts, failures
+}
+
+// estimateCompletionTime estimates when the remaining nodes are upgraded by the rate of nodes upgraded
+// since start, nil if no node is upgraded yet or no node is remaining.
+func estimateCompletionTime(start *metav1.Time, counts appsv1beta1.CampaignNodeCounts, now time.Time) *metav1.Time {
+	remaining := counts.Pending + counts.Upgrading
+	if start == nil || counts.Succeeded == 0 || remaining == 0 {
+		return nil
+	}
+	elapsed := now.Sub(start.Time)
+	eta := metav1.NewTime(now.Add(elapsed * time.Duration(remaining) / time.Duration(counts.Succeeded)).Truncate(time.Minute))
+	return &eta
+}
+
+// campaignsOfPool returns the requests of campaigns which upgrade the pool and are not finished.
+func campaignsOfPool(campaigns []appsv1beta1.UpgradeCampaign, pool string) []reconcile.Request {
+	var requests []reconcile.Request
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		if campaign.Status.Phase == appsv1beta1.UpgradeCampaignCompleted || campaign.Status.Phase == appsv1beta1.UpgradeCampaignFailed {
+			continue
+		}
+		for _, poolName := range campaign.Spec.NodePools {
+			if poolName == pool {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: campaign.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgradecampaign
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestRenderPoolUpgrade(t *testing.T) {
+	maxNodes := intstr.FromString("20%")
+	campaign := &appsv1beta1.UpgradeCampaign{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade-v1.3.0", UID: "uid"},
+		Spec: appsv1beta1.UpgradeCampaignSpec{
+			NodePools: []string{"hangzhou", "beijing"},
+			Steps: []appsv1beta1.PoolUpgradeStep{
+				{Name: "yurthub", Image: "openyurt/node-servant:v1.3.0", Args: []string{"upgrade", "yurthub"}},
+			},
+			Drain:  true,
+			Budget: appsv1beta1.RolloutBudget{MaxNodesPerPool: &maxNodes},
+		},
+	}
+
+	upgrade := renderPoolUpgrade(campaign, "hangzhou")
+	if upgrade.Name != poolUpgradeName(campaign, "hangzhou") || len(upgrade.Name) > 63 {
+		t.Errorf("unexpected name %s", upgrade.Name)
+	}
+	if upgrade.Labels[LabelUpgradeCampaign] != campaign.Name {
+		t.Errorf("unexpected labels %v", upgrade.Labels)
+	}
+	if !metav1.IsControlledBy(upgrade, campaign) {
+		t.Errorf("expect PoolUpgrade is controlled by campaign")
+	}
+	if upgrade.Spec.NodePool != "hangzhou" || !upgrade.Spec.Drain || len(upgrade.Spec.Steps) != 1 {
+		t.Errorf("unexpected spec %v", upgrade.Spec)
+	}
+	if upgrade.Spec.MaxUnavailable == nil || upgrade.Spec.MaxUnavailable.String() != "20%" {
+		t.Errorf("expect maxUnavailable 20%%, but got %v", upgrade.Spec.MaxUnavailable)
+	}
+
+	if poolUpgradeName(campaign, "hangzhou") == poolUpgradeName(campaign, "beijing") {
+		t.Errorf("expect different names for different pools")
+	}
+}
+
+func TestPoolRolloutStatusOf(t *testing.T) {
+	upgrade := &appsv1beta1.PoolUpgrade{
+		ObjectMeta: metav1.ObjectMeta{Name: "upgrade-hangzhou"},
+		Status: appsv1beta1.PoolUpgradeStatus{
+			Phase:         appsv1beta1.PoolUpgradeRunning,
+			TotalNodes:    4,
+			UpgradedNodes: 1,
+			Nodes: []appsv1beta1.NodeUpgradeStatus{
+				{NodeName: "node-a", Phase: appsv1beta1.NodeUpgradeSucceeded},
+				{NodeName: "node-b", Phase: appsv1beta1.NodeUpgradeFailed},
+				{NodeName: "node-c", Phase: appsv1beta1.NodeUpgradeFailed},
+				{NodeName: "node-d", Phase: appsv1beta1.NodeUpgradeUpgrading},
+			},
+		},
+	}
+
+	status := poolRolloutStatusOf("hangzhou", upgrade)
+	expect := appsv1beta1.PoolRolloutStatus{
+		NodePool:      "hangzhou",
+		PoolUpgrade:   "upgrade-hangzhou",
+		Phase:         appsv1beta1.PoolUpgradeRunning,
+		TotalNodes:    4,
+		UpgradedNodes: 1,
+		FailedNodes:   2,
+	}
+	if status != expect {
+		t.Errorf("expect %v, but got %v", expect, status)
+	}
+}
+
+func TestGetBudget(t *testing.T) {
+	zero, three := int32(0), int32(3)
+	testcases := map[string]struct {
+		budget          appsv1beta1.RolloutBudget
+		expectPools     int32
+		expectThreshold int32
+	}{
+		"default": {
+			expectPools:     1,
+			expectThreshold: 1,
+		},
+		"specified": {
+			budget:          appsv1beta1.RolloutBudget{MaxConcurrentPools: &three, FailureThreshold: &three},
+			expectPools:     3,
+			expectThreshold: 3,
+		},
+		"at least one": {
+			budget:          appsv1beta1.RolloutBudget{MaxConcurrentPools: &zero, FailureThreshold: &zero},
+			expectPools:     1,
+			expectThreshold: 1,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if n := getMaxConcurrentPools(&tc.budget); n != tc.expectPools {
+				t.Errorf("expect %d concurrent pools, but got %d", tc.expectPools, n)
+			}
+			if n := getFailureThreshold(&tc.budget); n != tc.expectThreshold {
+				t.Errorf("expect failure threshold %d, but got %d", tc.expectThreshold, n)
+			}
+		})
+	}
+}
+
+func TestUpdateFailurePause(t *testing.T) {
+	campaign := &appsv1beta1.UpgradeCampaign{}
+	campaign.Generation = 1
+
+	if updateFailurePause(campaign, 1, 2) || campaign.Status.FailurePaused {
+		t.Fatalf("campaign should not be paused below the failure threshold")
+	}
+	if !updateFailurePause(campaign, 2, 2) || !campaign.Status.FailurePaused {
+		t.Fatalf("campaign should be paused when the failure threshold is reached")
+	}
+	if updateFailurePause(campaign, 3, 2) || !campaign.Status.FailurePaused {
+		t.Fatalf("campaign should stay paused until its spec is changed")
+	}
+
+	// the campaign is paused and unpaused by hand
+	campaign.Generation = 3
+	if updateFailurePause(campaign, 3, 2) || campaign.Status.FailurePaused {
+		t.Fatalf("failure pause should be cleared after the spec is changed")
+	}
+	if campaign.Status.AcknowledgedFailedNodes != 3 {
+		t.Errorf("expect 3 acknowledged failed nodes, but got %d", campaign.Status.AcknowledgedFailedNodes)
+	}
+	if updateFailurePause(campaign, 4, 2) || campaign.Status.FailurePaused {
+		t.Fatalf("acknowledged failures should not pause the campaign again")
+	}
+	if !updateFailurePause(campaign, 5, 2) || campaign.Status.FailurePausedGeneration != 3 {
+		t.Fatalf("campaign should be paused by new failures, status %#v", campaign.Status)
+	}
+}
+
+func TestSummarizeNodes(t *testing.T) {
+	upgrades := map[string]*appsv1beta1.PoolUpgrade{
+		"hangzhou": {
+			Status: appsv1beta1.PoolUpgradeStatus{
+				TotalNodes: 4,
+				Nodes: []appsv1beta1.NodeUpgradeStatus{
+					{NodeName: "node1", Phase: appsv1beta1.NodeUpgradeSucceeded},
+					{NodeName: "node2", Phase: appsv1beta1.NodeUpgradeFailed, Message: "yurthub is not ready"},
+					{NodeName: "node3", Phase: appsv1beta1.NodeUpgradeDraining},
+				},
+			},
+		},
+		// the nodes are not reported by PoolUpgrade yet
+		"beijing": {},
+	}
+	poolSizes := map[string]int32{"beijing": 2, "shanghai": 3}
+
+	counts, failures := summarizeNodes([]string{"hangzhou", "beijing", "shanghai"}, upgrades, poolSizes)
+	expectCounts := appsv1beta1.CampaignNodeCounts{Total: 9, Pending: 6, Upgrading: 1, Succeeded: 1, Failed: 1}
+	if counts != expectCounts {
+		t.Errorf("expect %v, but got %v", expectCounts, counts)
+	}
+	expectFailures := []appsv1beta1.NodeUpgradeFailure{{NodePool: "hangzhou", NodeName: "node2", Reason: "yurthub is not ready"}}
+	if !reflect.DeepEqual(failures, expectFailures) {
+		t.Errorf("expect %v, but got %v", expectFailures, failures)
+	}
+}
+
+func TestEstimateCompletionTime(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	start := metav1.NewTime(now.Add(-time.Hour))
+	testcases := map[string]struct {
+		start  *metav1.Time
+		counts appsv1beta1.CampaignNodeCounts
+		expect *time.Time
+	}{
+		"not started": {
+			counts: appsv1beta1.CampaignNodeCounts{Total: 4, Pending: 4},
+		},
+		"no node upgraded": {
+			start:  &start,
+			counts: appsv1beta1.CampaignNodeCounts{Total: 4, Pending: 3, Upgrading: 1},
+		},
+		"all nodes finished": {
+			start:  &start,
+			counts: appsv1beta1.CampaignNodeCounts{Total: 4, Succeeded: 3, Failed: 1},
+		},
+		"estimated by rate": {
+			start:  &start,
+			counts: appsv1beta1.CampaignNodeCounts{Total: 4, Pending: 1, Upgrading: 1, Succeeded: 2},
+			expect: func() *time.Time { t := now.Add(time.Hour); return &t }(),
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			eta := estimateCompletionTime(tc.start, tc.counts, now)
+			if tc.expect == nil {
+				if eta != nil {
+					t.Errorf("expect no eta, but got %v", eta)
+				}
+				return
+			}
+			if eta == nil || !eta.Time.Equal(*tc.expect) {
+				t.Errorf("expect eta %v, but got %v", *tc.expect, eta)
+			}
+		})
+	}
+}
+
+func TestTargetRevisionOf(t *testing.T) {
+	spec := &appsv1beta1.UpgradeCampaignSpec{
+		Steps: []appsv1beta1.PoolUpgradeStep{{Name: "yurthub", Image: "openyurt/node-servant:v1.3.0"}},
+	}
+	revision := targetRevisionOf(spec)
+	spec.NodePools = []string{"hangzhou"}
+	if targetRevisionOf(spec) != revision {
+		t.Errorf("expect revision not changed by pools")
+	}
+	spec.Steps[0].Image = "openyurt/node-servant:v1.3.1"
+	if targetRevisionOf(spec) == revision {
+		t.Errorf("expect revision changed by steps")
+	}
+}
+
+func TestCampaignsOfPool(t *testing.T) {
+	campaigns := []appsv1beta1.UpgradeCampaign{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "running"},
+			Spec:       appsv1beta1.UpgradeCampaignSpec{NodePools: []string{"hangzhou", "beijing"}},
+			Status:     appsv1beta1.UpgradeCampaignStatus{Phase: appsv1beta1.UpgradeCampaignRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-pools"},
+			Spec:       appsv1beta1.UpgradeCampaignSpec{NodePools: []string{"shanghai"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "completed"},
+			Spec:       appsv1beta1.UpgradeCampaignSpec{NodePools: []string{"beijing"}},
+			Status:     appsv1beta1.UpgradeCampaignStatus{Phase: appsv1beta1.UpgradeCampaignCompleted},
+		},
+	}
+
+	expect := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "running"}}}
+	if requests := campaignsOfPool(campaigns, "beijing"); !reflect.DeepEqual(requests, expect) {
+		t.Errorf("expect requests %v, but got %v", expect, requests)
+	}
+	if requests := campaignsOfPool(campaigns, "shenzhen"); len(requests) != 0 {
+		t.Errorf("expect no requests, but got %v", requests)
+	}
+}
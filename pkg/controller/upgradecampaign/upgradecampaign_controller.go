@@ -0,0 +1,310 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgradecampaign
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openyurtio/openyurt/cmd/yurt-manager/app/config"
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	utilclient "github.com/openyurtio/openyurt/pkg/util/client"
+	utildiscovery "github.com/openyurtio/openyurt/pkg/util/discovery"
+)
+
+var (
+	concurrentReconciles = 3
+	controllerKind       = appsv1beta1.GroupVersion.WithKind("UpgradeCampaign")
+)
+
+const (
+	controllerName = "UpgradeCampaign-controller"
+
+	campaignEventReason = "UpgradeCampaign"
+)
+
+func Format(format string, args ...interface{}) string {
+	s := fmt.Sprintf(format, args...)
+	return fmt.Sprintf("%s: %s", controllerName, s)
+}
+
+// ReconcileUpgradeCampaign upgrades the pools of campaign by PoolUpgrades, honoring the rollout budget.
+type ReconcileUpgradeCampaign struct {
+	client.Client
+	recorder record.EventRecorder
+}
+
+var _ reconcile.Reconciler = &ReconcileUpgradeCampaign{}
+
+// Add creates a new UpgradeCampaign Controller and adds it to the Manager with default RBAC. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(c *config.CompletedConfig, mgr manager.Manager) error {
+	if !utildiscovery.DiscoverGVK(controllerKind) {
+		klog.Errorf(Format("DiscoverGVK error"))
+		return nil
+	}
+
+	return add(mgr, newReconciler(c, mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(_ *config.CompletedConfig, mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileUpgradeCampaign{
+		Client:   utilclient.NewClientFromManager(mgr, controllerName),
+		recorder: mgr.GetEventRecorderFor(controllerName),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New(controllerName, mgr, controller.Options{
+		Reconciler: r, MaxConcurrentReconciles: concurrentReconciles,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to UpgradeCampaign
+	err = c.Watch(&source.Kind{Type: &appsv1beta1.UpgradeCampaign{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to PoolUpgrades owned by UpgradeCampaign
+	err = c.Watch(&source.Kind{Type: &appsv1beta1.PoolUpgrade{}}, &handler.EnqueueRequestForOwner{
+		OwnerType: &appsv1beta1.UpgradeCampaign{}, IsController: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Nodes, so the node counts and ETA of campaigns follow the progress of nodes
+	return c.Watch(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(mapNodeToUpgradeCampaigns(mgr.GetClient())))
+}
+
+// mapNodeToUpgradeCampaigns returns a MapFunc which enqueues the running UpgradeCampaigns containing the pool of node.
+func mapNodeToUpgradeCampaigns(c client.Reader) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		pool := obj.GetLabels()[apps.LabelDesiredNodePool]
+		if len(pool) == 0 {
+			return nil
+		}
+		var campaignList appsv1beta1.UpgradeCampaignList
+		if err := c.List(context.TODO(), &campaignList); err != nil {
+			klog.Errorf(Format("could not list UpgradeCampaigns for node %s, %v", obj.GetName(), err))
+			return nil
+		}
+		return campaignsOfPool(campaignList.Items, pool)
+	}
+}
+
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=upgradecampaigns,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=upgradecampaigns/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=poolupgrades,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile starts upgrading the pools of campaign in order when the number of upgrading pools is less than
+// maxConcurrentPools, and pauses the campaign when the failure threshold is reached.
+func (r *ReconcileUpgradeCampaign) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	klog.V(4).Infof(Format("Reconcile UpgradeCampaign %s", req.Name))
+
+	var campaign appsv1beta1.UpgradeCampaign
+	if err := r.Get(ctx, req.NamespacedName, &campaign); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if campaign.DeletionTimestamp != nil ||
+		campaign.Status.Phase == appsv1beta1.UpgradeCampaignCompleted || campaign.Status.Phase == appsv1beta1.UpgradeCampaignFailed {
+		return ctrl.Result{}, nil
+	}
+
+	var upgradeList appsv1beta1.PoolUpgradeList
+	if err := r.List(ctx, &upgradeList, client.MatchingLabels{LabelUpgradeCampaign: campaign.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+	upgrades := make(map[string]*appsv1beta1.PoolUpgrade, len(upgradeList.Items))
+	for i := range upgradeList.Items {
+		upgrade := &upgradeList.Items[i]
+		if metav1.IsControlledBy(upgrade, &campaign) {
+			upgrades[upgrade.Spec.NodePool] = upgrade
+		}
+	}
+
+	oldStatus := campaign.Status.DeepCopy()
+	if err := r.syncUpgradeCampaign(ctx, &campaign, upgrades); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.summarizeUpgradeCampaign(ctx, &campaign, upgrades); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !reflect.DeepEqual(oldStatus, &campaign.Status) {
+		if err := r.Status().Update(ctx, &campaign); err != nil {
+			klog.Errorf(Format("could not update status of UpgradeCampaign %s, %v", campaign.Name, err))
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// syncUpgradeCampaign summarizes the progress of pools, keeps the PoolUpgrades paused as the campaign,
+// and starts new pools if allowed.
+func (r *ReconcileUpgradeCampaign) syncUpgradeCampaign(ctx context.Context, campaign *appsv1beta1.UpgradeCampaign, upgrades map[string]*appsv1beta1.PoolUpgrade) error {
+	status := &campaign.Status
+	budget := &campaign.Spec.Budget
+
+	var active, upgradedPools, failedPools, failedNodes int32
+	status.Pools = nil
+	for _, poolName := range campaign.Spec.NodePools {
+		upgrade, ok := upgrades[poolName]
+		if !ok {
+			continue
+		}
+		ps := poolRolloutStatusOf(poolName, upgrade)
+		status.Pools = append(status.Pools, ps)
+		failedNodes += ps.FailedNodes
+		switch ps.Phase {
+		case appsv1beta1.PoolUpgradeCompleted:
+			upgradedPools++
+		case appsv1beta1.PoolUpgradeFailed:
+			failedPools++
+		default:
+			active++
+		}
+	}
+	status.TotalPools = int32(len(campaign.Spec.NodePools))
+	status.UpgradedPools = upgradedPools
+	status.FailedNodes = failedNodes
+
+	threshold := getFailureThreshold(budget)
+	justPaused := updateFailurePause(campaign, failedNodes, threshold)
+	paused := campaign.Spec.Paused || status.FailurePaused
+	for _, upgrade := range upgrades {
+		if err := r.pausePoolUpgrade(ctx, upgrade, paused); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case paused:
+		message := "campaign is paused"
+		if status.FailurePaused {
+			message = fmt.Sprintf("%d nodes failed upgrading, failure threshold %d is reached, pause and unpause the campaign to resume",
+				failedNodes-status.AcknowledgedFailedNodes, threshold)
+			if justPaused {
+				r.recorder.Eventf(campaign, corev1.EventTypeWarning, campaignEventReason, "campaign is paused, %s", message)
+			}
+		}
+		status.Phase = appsv1beta1.UpgradeCampaignPaused
+		status.Message = message
+		return nil
+	case len(upgrades) == len(campaign.Spec.NodePools) && active == 0:
+		if failedPools != 0 {
+			status.Phase = appsv1beta1.UpgradeCampaignFailed
+			status.Message = fmt.Sprintf("upgrade of %d pools failed", failedPools)
+			r.recorder.Event(campaign, corev1.EventTypeWarning, campaignEventReason, status.Message)
+			return nil
+		}
+		status.Phase = appsv1beta1.UpgradeCampaignCompleted
+		status.Message = fmt.Sprintf("%d pools are upgraded", upgradedPools)
+		r.recorder.Event(campaign, corev1.EventTypeNormal, campaignEventReason, status.Message)
+		return nil
+	}
+
+	status.Phase = appsv1beta1.UpgradeCampaignRunning
+	status.Message = fmt.Sprintf("%d pools are upgrading", active)
+	maxConcurrentPools := getMaxConcurrentPools(budget)
+	for _, poolName := range campaign.Spec.NodePools {
+		if active >= maxConcurrentPools {
+			break
+		}
+		if _, ok := upgrades[poolName]; ok {
+			continue
+		}
+		upgrade := renderPoolUpgrade(campaign, poolName)
+		if err := r.Create(ctx, upgrade); err != nil && !apierrors.IsAlreadyExists(err) {
+			klog.Errorf(Format("could not create PoolUpgrade for pool %s, %v", poolName, err))
+			return err
+		}
+		klog.Infof(Format("start upgrading pool %s for UpgradeCampaign %s", poolName, campaign.Name))
+		upgrades[poolName] = upgrade
+		status.Pools = append(status.Pools, poolRolloutStatusOf(poolName, upgrade))
+		active++
+	}
+	return nil
+}
+
+// summarizeUpgradeCampaign records the target revision, node counts, failures and estimated completion
+// time of campaign, so the progress of fleet is seen at a glance.
+func (r *ReconcileUpgradeCampaign) summarizeUpgradeCampaign(ctx context.Context, campaign *appsv1beta1.UpgradeCampaign, upgrades map[string]*appsv1beta1.PoolUpgrade) error {
+	poolSizes := make(map[string]int32)
+	for _, poolName := range campaign.Spec.NodePools {
+		if upgrade, ok := upgrades[poolName]; ok && upgrade.Status.TotalNodes != 0 {
+			continue
+		}
+		var pool appsv1beta1.NodePool
+		if err := r.Get(ctx, types.NamespacedName{Name: poolName}, &pool); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		poolSizes[poolName] = pool.Status.ReadyNodeNum + pool.Status.UnreadyNodeNum
+	}
+
+	status := &campaign.Status
+	status.TargetRevision = targetRevisionOf(&campaign.Spec)
+	status.Nodes, status.Failures = summarizeNodes(campaign.Spec.NodePools, upgrades, poolSizes)
+	if status.StartTime == nil && len(upgrades) != 0 {
+		now := metav1.Now()
+		status.StartTime = &now
+	}
+	status.EstimatedCompletionTime = estimateCompletionTime(status.StartTime, status.Nodes, time.Now())
+	return nil
+}
+
+// pausePoolUpgrade pauses or resumes the PoolUpgrade which is not finished.
+func (r *ReconcileUpgradeCampaign) pausePoolUpgrade(ctx context.Context, upgrade *appsv1beta1.PoolUpgrade, paused bool) error {
+	if isPoolUpgradeFinished(upgrade) || upgrade.Spec.Paused == paused {
+		return nil
+	}
+	patch := client.MergeFrom(upgrade.DeepCopy())
+	upgrade.Spec.Paused = paused
+	if err := r.Patch(ctx, upgrade, patch); err != nil {
+		klog.Errorf(Format("could not set paused %v for PoolUpgrade %s, %v", paused, upgrade.Name, err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// HookFailurePolicy defines how a failed hook is handled
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyFail aborts the upgrade if pre-upgrade hook fails, and rolls back the upgrade
+	// if post-upgrade hook fails.
+	HookFailurePolicyFail HookFailurePolicy = "Fail"
+	// HookFailurePolicyIgnore logs the failure of hook and goes on.
+	HookFailurePolicyIgnore HookFailurePolicy = "Ignore"
+)
+
+// Hook is a shell command executed on the node before or after upgrading a static pod,
+// so the stateful components can quiesce before being replaced, or resume after upgrade.
+// A container can be exec into by the command, for example with crictl exec.
+type Hook struct {
+	Name          string
+	Command       string
+	Timeout       time.Duration
+	FailurePolicy HookFailurePolicy
+}
+
+// Run executes the hook, an error is returned only if the hook fails with failure policy Fail.
+func (h *Hook) Run() error {
+	if h == nil || len(h.Command) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+	klog.Infof("run %s hook: %s", h.Name, h.Command)
+	out, err := exec.CommandContext(ctx, "/bin/sh", "-c", h.Command).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", h.Timeout)
+	}
+	if err == nil {
+		klog.Infof("%s hook succeeded, output: %s", h.Name, string(out))
+		return nil
+	}
+
+	if h.FailurePolicy == HookFailurePolicyIgnore {
+		klog.Warningf("%s hook failed and is ignored, %v, output: %s", h.Name, err, string(out))
+		return nil
+	}
+	return fmt.Errorf("%s hook failed, %w, output: %s", h.Name, err, string(out))
+}
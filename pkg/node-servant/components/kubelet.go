@@ -87,6 +87,28 @@ func (op *kubeletOperator) UndoRedirectTrafficToYurtHub() error {
 	return nil
 }
 
+// IsTrafficRedirectedToYurtHub checks whether kubelet visits yurtHub as apiServer, and whether
+// the revised kubelet.conf is up to date. kubeadm-flags.env may be rewritten by other tooling like
+// kubeadm upgrade, then kubelet visits apiServer directly after restarted.
+func (op *kubeletOperator) IsTrafficRedirectedToYurtHub() (redirected bool, upToDate bool, err error) {
+	content, err := os.ReadFile(kubeAdmFlagsEnvFile)
+	if err != nil {
+		return false, false, err
+	}
+	if !strings.Contains(string(content), op.getAppendSetting()) {
+		return false, false, nil
+	}
+
+	conf, err := os.ReadFile(op.getYurthubKubeletConf())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, false, nil
+		}
+		return true, false, err
+	}
+	return true, string(conf) == constants.KubeletConfForNode, nil
+}
+
 func (op *kubeletOperator) writeYurthubKubeletConfig() (string, error) {
 	err := os.MkdirAll(op.openyurtDir, dirMode)
 	if err != nil {
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+)
+
+// UpgradeStage is a stage of component upgrade on the node
+type UpgradeStage string
+
+const (
+	// UpgradeStaged means the new manifest is prepared and the previous one is backed up.
+	UpgradeStaged UpgradeStage = "Staged"
+	// UpgradeApplied means the new manifest is applied and the component is restarting.
+	UpgradeApplied UpgradeStage = "Applied"
+	// UpgradeVerified means the upgraded component passed probation.
+	UpgradeVerified UpgradeStage = "Verified"
+	// UpgradeFailed means the upgrade is aborted or rolled back.
+	UpgradeFailed UpgradeStage = "Failed"
+
+	// NodeConditionYurtHubUpgrade is the node condition which records the progress of yurthub upgrade.
+	NodeConditionYurtHubUpgrade corev1.NodeConditionType = "YurtHubUpgrade"
+
+	progressEventSource  = "node-servant"
+	progressFlushTimeout = time.Minute
+	progressRetryPeriod  = 5 * time.Second
+)
+
+// ProgressReporter publishes the upgrade progress as a node condition and events. The reports go through
+// yurthub, which caches events when the node is offline; the reports which can't be sent, like the ones
+// while yurthub is restarting, are buffered and sent with the next report or by Flush.
+// All methods of a nil ProgressReporter are no-ops, and reporting never fails the upgrade.
+type ProgressReporter struct {
+	client        kubernetes.Interface
+	nodeName      string
+	conditionType corev1.NodeConditionType
+	pendingEvents []*corev1.Event
+	pendingCond   *corev1.NodeCondition
+}
+
+// NewProgressReporter creates a ProgressReporter which reports through yurthub with the kubeconfig of kubelet.
+func NewProgressReporter(nodeName string, conditionType corev1.NodeConditionType) (*ProgressReporter, error) {
+	kubeconfig := filepath.Join(constants.OpenyurtDir, constants.KubeletKubeConfigFileName)
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s, %w", kubeconfig, err)
+	}
+	cfg.Timeout = 10 * time.Second
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &ProgressReporter{
+		client:        client,
+		nodeName:      nodeName,
+		conditionType: conditionType,
+	}, nil
+}
+
+// Report publishes the stage with message, the condition is True when verified, False when failed
+// and Unknown otherwise.
+func (r *ProgressReporter) Report(stage UpgradeStage, message string) {
+	if r == nil {
+		return
+	}
+	now := metav1.Now()
+	status, eventType := corev1.ConditionUnknown, corev1.EventTypeNormal
+	switch stage {
+	case UpgradeVerified:
+		status = corev1.ConditionTrue
+	case UpgradeFailed:
+		status, eventType = corev1.ConditionFalse, corev1.EventTypeWarning
+	}
+
+	r.pendingCond = &corev1.NodeCondition{
+		Type:               r.conditionType,
+		Status:             status,
+		Reason:             string(stage),
+		Message:            message,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	}
+	r.pendingEvents = append(r.pendingEvents, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: r.nodeName + ".",
+			Namespace:    metav1.NamespaceDefault,
+		},
+		// the uid of node events is the node name, the same as kubelet does
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: r.nodeName, UID: types.UID(r.nodeName)},
+		Reason:         string(r.conditionType) + string(stage),
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: progressEventSource, Host: r.nodeName},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	})
+	if err := r.send(); err != nil {
+		klog.Warningf("failed to report %s of %s, it will be retried, %v", stage, r.conditionType, err)
+	}
+}
+
+// Flush retries sending the buffered reports until they are sent or timeout.
+func (r *ProgressReporter) Flush() {
+	if r == nil {
+		return
+	}
+	err := wait.PollImmediate(progressRetryPeriod, progressFlushTimeout, func() (bool, error) {
+		return r.send() == nil, nil
+	})
+	if err != nil {
+		klog.Errorf("failed to report %s progress in %v, %d events are dropped", r.conditionType, progressFlushTimeout, len(r.pendingEvents))
+	}
+}
+
+// send sends the buffered events in order and the latest condition.
+func (r *ProgressReporter) send() error {
+	ctx := context.Background()
+	for len(r.pendingEvents) != 0 {
+		if _, err := r.client.CoreV1().Events(metav1.NamespaceDefault).Create(ctx, r.pendingEvents[0], metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		r.pendingEvents = r.pendingEvents[1:]
+	}
+	if r.pendingCond == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.NodeCondition{*r.pendingCond},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := r.client.CoreV1().Nodes().PatchStatus(ctx, r.nodeName, data); err != nil {
+		return err
+	}
+	r.pendingCond = nil
+	return nil
+}
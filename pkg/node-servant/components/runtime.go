@@ -17,6 +17,7 @@ limitations under the License.
 package components
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	goruntime "runtime"
@@ -41,6 +42,7 @@ type ContainerRuntimeForImage interface {
 	IsDocker() bool
 	PullImage(image string) error
 	ImageExists(image string) (bool, error)
+	RepoDigests(image string) ([]string, error)
 }
 
 // CRIRuntime is a struct that interfaces with the CRI
@@ -127,6 +129,36 @@ func (runtime *DockerRuntime) ImageExists(image string) (bool, error) {
 	return err == nil, nil
 }
 
+// RepoDigests returns the repo digests of the image, like docker.io/openyurt/node-servant@sha256:...
+func (runtime *CRIRuntime) RepoDigests(image string) ([]string, error) {
+	out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "inspecti", "-o", "json", image).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect image %s", image)
+	}
+	var info struct {
+		Status struct {
+			RepoDigests []string `json:"repoDigests"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode image %s", image)
+	}
+	return info.Status.RepoDigests, nil
+}
+
+// RepoDigests returns the repo digests of the image, like docker.io/openyurt/node-servant@sha256:...
+func (runtime *DockerRuntime) RepoDigests(image string) ([]string, error) {
+	out, err := runtime.exec.Command("docker", "inspect", "--format", "{{json .RepoDigests}}", image).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect image %s", image)
+	}
+	var digests []string
+	if err := json.Unmarshal(out, &digests); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode image %s", image)
+	}
+	return digests, nil
+}
+
 // detectCRISocketImpl is separated out only for test purposes, DON'T call it directly, use DetectCRISocket instead
 func detectCRISocketImpl(isSocket func(string) bool) (string, error) {
 	foundCRISockets := []string{}
@@ -17,6 +17,7 @@ limitations under the License.
 package components
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,8 +28,10 @@ import (
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
 	"github.com/openyurtio/openyurt/pkg/util/templates"
@@ -42,8 +45,18 @@ import (
 const (
 	hubHealthzCheckFrequency = 10 * time.Second
 	fileMode                 = 0666
+	yurthubContainerName     = "yurt-hub"
+	yurthubUpgradeMarker     = "yurthub-upgrade.json"
 )
 
+// upgradeMarker is persisted before the manifest of yurthub is replaced, so the upgrade interrupted
+// by a crash or reboot of node-servant is resumed or rolled back from the backup by the next run.
+type upgradeMarker struct {
+	Image      string `json:"image"`
+	OldImage   string `json:"oldImage"`
+	BackupPath string `json:"backupPath"`
+}
+
 type yurtHubOperator struct {
 	apiServerAddr             string
 	yurthubImage              string
@@ -102,6 +115,258 @@ func (op *yurtHubOperator) Install() error {
 	return hubHealthcheck(op.yurthubHealthCheckTimeout)
 }
 
+// Upgrade replaces the image of yurthub in static pod manifest, and supervises the new yurthub
+// during probation. the previous manifest is restored if the new yurthub is not ready within
+// the health check timeout, or becomes unhealthy before probation ends, because a broken yurthub
+// severs the node from the cloud. The upgrade is aborted if preHook fails, and rolled back if
+// postHook fails after probation, unless the failure policy of hook is Ignore. postHook is also
+// run when the upgrade is aborted or rolled back for other reasons, so the components quiesced by
+// preHook are resumed with the previous yurthub. The progress is
+// published by reporter. The upgrade interrupted before is resumed if it's for the same image,
+// otherwise it's rolled back before upgrading.
+func (op *yurtHubOperator) Upgrade(probation time.Duration, preHook, postHook *Hook, reporter *ProgressReporter) (err error) {
+	yurthubYamlPath := getYurthubYaml(enutil.GetPodManifestPath())
+	marker, err := loadUpgradeMarker()
+	if err != nil {
+		return err
+	}
+	if marker != nil {
+		// the manifest may not be replaced yet if the upgrade is interrupted right after the marker is saved
+		if marker.Image == op.yurthubImage && manifestImage(yurthubYamlPath) == marker.Image {
+			klog.Infof("UpgradeYurthub: resume the interrupted upgrade from %s to %s", marker.OldImage, marker.Image)
+			defer func() {
+				if err != nil {
+					reporter.Report(UpgradeFailed, err.Error())
+				}
+				reporter.Flush()
+			}()
+			return op.verifyUpgrade(marker, func() error {
+				if err := hubHealthcheck(op.yurthubHealthCheckTimeout); err != nil {
+					return fmt.Errorf("yurthub is not ready, %w", err)
+				}
+				return probeYurthub(probation)
+			}, probation, postHook, reporter)
+		}
+		klog.Infof("UpgradeYurthub: roll back the interrupted upgrade from %s to %s", marker.OldImage, marker.Image)
+		if err := op.rollback(marker); err != nil {
+			return err
+		}
+	}
+
+	current, err := os.ReadFile(yurthubYamlPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s, %w", yurthubYamlPath, err)
+	}
+	upgraded, oldImage, err := replaceYurthubImage(current, op.yurthubImage)
+	if err != nil {
+		return err
+	}
+	if oldImage == op.yurthubImage {
+		klog.Infof("UpgradeYurthub: yurthub is already %s, skip upgrade", op.yurthubImage)
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			reporter.Report(UpgradeFailed, err.Error())
+		}
+		reporter.Flush()
+	}()
+
+	if err := preHook.Run(); err != nil {
+		// the hook may have quiesced some components before failing
+		resumeAfterAbort(postHook)
+		return fmt.Errorf("abort yurthub upgrade to %s, %w", op.yurthubImage, err)
+	}
+	applied := false
+	defer func() {
+		if err != nil && !applied {
+			resumeAfterAbort(postHook)
+		}
+	}()
+
+	// the backup is not put into /etc/kubernetes/manifests, otherwise kubelet will start it
+	if err := enutil.EnsureDir(getYurthubConf()); err != nil {
+		return err
+	}
+	backupPath := getYurthubBackupYaml()
+	if err := os.WriteFile(backupPath, current, fileMode); err != nil {
+		return fmt.Errorf("failed to back up %s, %w", yurthubYamlPath, err)
+	}
+	marker = &upgradeMarker{Image: op.yurthubImage, OldImage: oldImage, BackupPath: backupPath}
+	if err := saveUpgradeMarker(marker); err != nil {
+		return fmt.Errorf("failed to save upgrade marker, %w", err)
+	}
+	reporter.Report(UpgradeStaged, fmt.Sprintf("yurthub %s is staged, %s is backed up", op.yurthubImage, oldImage))
+	if err := os.WriteFile(yurthubYamlPath, upgraded, fileMode); err != nil {
+		return err
+	}
+	// postHook is run by verifyUpgrade from now on
+	applied = true
+	klog.Infof("UpgradeYurthub: upgrade yurthub from %s to %s", oldImage, op.yurthubImage)
+	reporter.Report(UpgradeApplied, fmt.Sprintf("yurthub is upgraded from %s to %s", oldImage, op.yurthubImage))
+
+	return op.verifyUpgrade(marker, func() error {
+		return superviseYurthub(op.yurthubHealthCheckTimeout, probation)
+	}, probation, postHook, reporter)
+}
+
+// verifyUpgrade supervises the upgraded yurthub and runs postHook, the manifest is rolled back from
+// the backup of marker if either of them fails, and postHook is run after rolling back if it hasn't
+// run yet. The marker and backup are removed when it's done.
+func (op *yurtHubOperator) verifyUpgrade(marker *upgradeMarker, supervise func() error, probation time.Duration, postHook *Hook, reporter *ProgressReporter) error {
+	err := supervise()
+	supervised := err == nil
+	if supervised {
+		err = postHook.Run()
+	}
+	if err != nil {
+		klog.Errorf("UpgradeYurthub: yurthub upgrade to %s failed, roll back to %s, %v", marker.Image, marker.OldImage, err)
+		rollbackErr := op.rollback(marker)
+		if !supervised {
+			resumeAfterAbort(postHook)
+		}
+		if rollbackErr != nil {
+			return rollbackErr
+		}
+		return fmt.Errorf("yurthub upgrade to %s is rolled back to %s, %w", marker.Image, marker.OldImage, err)
+	}
+
+	removeUpgradeMarker(marker)
+	klog.Infof("UpgradeYurthub: yurthub %s passed probation of %v", marker.Image, probation)
+	reporter.Report(UpgradeVerified, fmt.Sprintf("yurthub %s passed probation of %v", marker.Image, probation))
+	return nil
+}
+
+// resumeAfterAbort runs postHook after the upgrade is aborted or rolled back, the failure is only
+// logged because the upgrade has failed already.
+func resumeAfterAbort(postHook *Hook) {
+	if err := postHook.Run(); err != nil {
+		klog.Errorf("UpgradeYurthub: failed to resume after the upgrade is aborted, %v", err)
+	}
+}
+
+// rollback restores the manifest of yurthub from the backup of marker, and waits for the previous
+// yurthub to be ready. The marker is kept if the manifest is not restored, so it's retried by the next run.
+func (op *yurtHubOperator) rollback(marker *upgradeMarker) error {
+	backup, err := os.ReadFile(marker.BackupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s of yurthub %s, %w", marker.BackupPath, marker.OldImage, err)
+	}
+	if err := os.WriteFile(getYurthubYaml(enutil.GetPodManifestPath()), backup, fileMode); err != nil {
+		return fmt.Errorf("failed to roll back yurthub to %s, %w", marker.OldImage, err)
+	}
+	removeUpgradeMarker(marker)
+	if err := hubHealthcheck(op.yurthubHealthCheckTimeout); err != nil {
+		return fmt.Errorf("yurthub %s is unhealthy after roll back, %w", marker.OldImage, err)
+	}
+	return nil
+}
+
+func loadUpgradeMarker() (*upgradeMarker, error) {
+	data, err := os.ReadFile(getYurthubUpgradeMarker())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read upgrade marker, %w", err)
+	}
+	marker := &upgradeMarker{}
+	if err := json.Unmarshal(data, marker); err != nil {
+		return nil, fmt.Errorf("failed to decode upgrade marker %s, %w", getYurthubUpgradeMarker(), err)
+	}
+	return marker, nil
+}
+
+// saveUpgradeMarker writes the marker atomically, so a partial marker is never left by a crash.
+func saveUpgradeMarker(marker *upgradeMarker) error {
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+	path := getYurthubUpgradeMarker()
+	if err := os.WriteFile(path+".tmp", data, fileMode); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// removeUpgradeMarker removes the marker before the backup, so the marker never refers to a missing backup.
+func removeUpgradeMarker(marker *upgradeMarker) {
+	if err := os.Remove(getYurthubUpgradeMarker()); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("UpgradeYurthub: failed to remove upgrade marker, %v", err)
+		return
+	}
+	if err := os.Remove(marker.BackupPath); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("UpgradeYurthub: failed to remove %s, %v", marker.BackupPath, err)
+	}
+}
+
+// manifestImage returns the image of yurt-hub container in the manifest, empty if it's unknown.
+func manifestImage(manifestPath string) string {
+	manifest, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return ""
+	}
+	_, image, err := replaceYurthubImage(manifest, "")
+	if err != nil {
+		return ""
+	}
+	return image
+}
+
+// replaceYurthubImage returns the manifest with the image of yurt-hub container replaced, and the former image.
+func replaceYurthubImage(manifest []byte, image string) ([]byte, string, error) {
+	pod := &corev1.Pod{}
+	if err := yaml.Unmarshal(manifest, pod); err != nil {
+		return nil, "", fmt.Errorf("failed to decode yurthub manifest, %w", err)
+	}
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name != yurthubContainerName {
+			continue
+		}
+		oldImage := pod.Spec.Containers[i].Image
+		pod.Spec.Containers[i].Image = image
+		data, err := yaml.Marshal(pod)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, oldImage, nil
+	}
+	return nil, "", fmt.Errorf("container %s is not found in yurthub manifest", yurthubContainerName)
+}
+
+// superviseYurthub waits the previous yurthub to exit and the new yurthub to be ready, then checks
+// the new yurthub is healthy throughout probation.
+func superviseYurthub(timeout, probation time.Duration) error {
+	if err := waitUntilYurthubExit(timeout, time.Second); err != nil {
+		return fmt.Errorf("previous yurthub is not stopped, %w", err)
+	}
+	if err := hubHealthcheck(timeout); err != nil {
+		return fmt.Errorf("yurthub is not ready, %w", err)
+	}
+	return probeYurthub(probation)
+}
+
+// probeYurthub checks the yurthub is healthy throughout probation.
+func probeYurthub(probation time.Duration) error {
+	serverHealthzURL, err := url.Parse(fmt.Sprintf("http://%s", constants.ServerHealthzServer))
+	if err != nil {
+		return err
+	}
+	serverHealthzURL.Path = constants.ServerHealthzURLPath
+
+	err = wait.Poll(hubHealthzCheckFrequency, probation, func() (bool, error) {
+		if _, err := pingClusterHealthz(http.DefaultClient, serverHealthzURL.String()); err != nil {
+			return false, fmt.Errorf("yurthub is unhealthy during probation, %w", err)
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil
+	}
+	return err
+}
+
 // UnInstall remove yaml and configs of yurthub
 func (op *yurtHubOperator) UnInstall() error {
 	// 1. remove the yurt-hub.yaml to delete the yurt-hub
@@ -150,6 +415,14 @@ func getYurthubYaml(podManifestPath string) string {
 	return filepath.Join(podManifestPath, constants.YurthubYamlName)
 }
 
+func getYurthubBackupYaml() string {
+	return filepath.Join(getYurthubConf(), constants.YurthubYamlName+".bak")
+}
+
+func getYurthubUpgradeMarker() string {
+	return filepath.Join(getYurthubConf(), yurthubUpgradeMarker)
+}
+
 func getYurthubConf() string {
 	return filepath.Join(token.DefaultRootDir, projectinfo.GetHubName())
 }
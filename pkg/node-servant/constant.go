@@ -29,6 +29,9 @@ const (
 	// ConfigControlPlaneJobNameBase is the prefix of the config control-plane ServantJob name
 	ConfigControlPlaneJobNameBase = "config-control-plane"
 
+	// UpgradeYurthubJobNameBase is the prefix of the upgrade yurthub ServantJob name
+	UpgradeYurthubJobNameBase = "node-servant-upgrade-yurthub"
+
 	// ConvertServantJobTemplate defines the node convert servant job in yaml format
 	ConvertServantJobTemplate = `
 apiVersion: batch/v1
@@ -193,4 +196,38 @@ spec:
         - mountPath: /openyurt
           name: host-root
 `
+
+	// UpgradeYurthubJobTemplate defines the yurthub upgrade servant job in yaml format, the args of
+	// container are set by RenderNodeServantJob, so the hooks are not interpreted by a shell.
+	UpgradeYurthubJobTemplate = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.jobName}}
+  namespace: kube-system
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      hostPID: true
+      hostNetwork: true
+      restartPolicy: Never
+      nodeName: {{.nodeName}}
+      volumes:
+      - name: host-root
+        hostPath:
+          path: /
+          type: Directory
+      containers:
+      - name: node-servant
+        image: {{.node_servant_image}}
+        imagePullPolicy: IfNotPresent
+        command:
+        - /usr/local/bin/entry.sh
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - mountPath: /openyurt
+          name: host-root
+`
 )
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	bsdiffMagic      = "BSDIFF40"
+	bsdiffHeaderSize = 32
+)
+
+var errCorruptPatch = errors.New("corrupt bsdiff patch")
+
+// bspatch reconstructs the new file from the old file and a patch in the format of bsdiff 4.x.
+// The patch is composed of a header and the bzip2 compressed control, diff and extra blocks.
+// The size of new file in header is not trusted, it's rejected if it's larger than maxSize.
+func bspatch(old, patch []byte, maxSize int64) ([]byte, error) {
+	if len(patch) < bsdiffHeaderSize || string(patch[:8]) != bsdiffMagic {
+		return nil, fmt.Errorf("%w, invalid header", errCorruptPatch)
+	}
+	ctrlLen := offtin(patch[8:16])
+	diffLen := offtin(patch[16:24])
+	newSize := offtin(patch[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 ||
+		ctrlLen > int64(len(patch)-bsdiffHeaderSize) || diffLen > int64(len(patch)-bsdiffHeaderSize)-ctrlLen {
+		return nil, fmt.Errorf("%w, invalid block size", errCorruptPatch)
+	}
+	if newSize > maxSize {
+		return nil, fmt.Errorf("%w, size of new file %d exceeds the limit %d", errCorruptPatch, newSize, maxSize)
+	}
+
+	ctrlEnd := bsdiffHeaderSize + ctrlLen
+	diffEnd := ctrlEnd + diffLen
+	ctrl := bzip2.NewReader(bytes.NewReader(patch[bsdiffHeaderSize:ctrlEnd]))
+	diff := bzip2.NewReader(bytes.NewReader(patch[ctrlEnd:diffEnd]))
+	extra := bzip2.NewReader(bytes.NewReader(patch[diffEnd:]))
+
+	newData := make([]byte, newSize)
+	var oldPos, newPos int64
+	buf := make([]byte, 24)
+	for newPos < newSize {
+		if _, err := io.ReadFull(ctrl, buf); err != nil {
+			return nil, fmt.Errorf("%w, %v", errCorruptPatch, err)
+		}
+		diffSize, extraSize, seek := offtin(buf[0:8]), offtin(buf[8:16]), offtin(buf[16:24])
+		if diffSize < 0 || extraSize < 0 || diffSize > newSize-newPos || extraSize > newSize-newPos-diffSize {
+			return nil, fmt.Errorf("%w, invalid control", errCorruptPatch)
+		}
+
+		// the diff block is added bytewise to the old data
+		if _, err := io.ReadFull(diff, newData[newPos:newPos+diffSize]); err != nil {
+			return nil, fmt.Errorf("%w, %v", errCorruptPatch, err)
+		}
+		for i := int64(0); i < diffSize; i++ {
+			if oldPos+i >= 0 && oldPos+i < int64(len(old)) {
+				newData[newPos+i] += old[oldPos+i]
+			}
+		}
+		newPos += diffSize
+		oldPos += diffSize
+
+		// the extra block is copied as it is
+		if _, err := io.ReadFull(extra, newData[newPos:newPos+extraSize]); err != nil {
+			return nil, fmt.Errorf("%w, %v", errCorruptPatch, err)
+		}
+		newPos += extraSize
+		oldPos += seek
+	}
+	return newData, nil
+}
+
+// offtin decodes the sign-magnitude little endian integer of bsdiff.
+func offtin(b []byte) int64 {
+	v := binary.LittleEndian.Uint64(b)
+	n := int64(v &^ (1 << 63))
+	if v&(1<<63) != 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// the bzip2 compressed blocks of the patch which turns "hello world" into "hallo world!",
+// the control is one tuple of diff 11 bytes, extra 1 byte and seek 0.
+const (
+	testCtrlBlock  = "425a68393141592653596cea4cd7000005400068082000219a68334d1da544e2ee48a70a120d9d499ae0"
+	testDiffBlock  = "425a68393141592653599a16fe4f0000024000c2000004200021981984e9a1772453850909a16fe4f0"
+	testExtraBlock = "425a68393141592653592d15eb1c00000010002000200021184682ee48a70a1205a2bd6380"
+)
+
+// offtout encodes the sign-magnitude little endian integer of bsdiff.
+func offtout(n int64) []byte {
+	b := make([]byte, 8)
+	v := uint64(n)
+	if n < 0 {
+		v = uint64(-n) | 1<<63
+	}
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func newTestPatch(t *testing.T, magic string, ctrlLen, diffLen, newSize int64) []byte {
+	var blocks [][]byte
+	for _, block := range []string{testCtrlBlock, testDiffBlock, testExtraBlock} {
+		data, err := hex.DecodeString(block)
+		if err != nil {
+			t.Fatalf("failed to decode block, %v", err)
+		}
+		blocks = append(blocks, data)
+	}
+	if ctrlLen < 0 {
+		ctrlLen = int64(len(blocks[0]))
+	}
+	if diffLen < 0 {
+		diffLen = int64(len(blocks[1]))
+	}
+
+	patch := append([]byte(magic), offtout(ctrlLen)...)
+	patch = append(patch, offtout(diffLen)...)
+	patch = append(patch, offtout(newSize)...)
+	return append(patch, bytes.Join(blocks, nil)...)
+}
+
+func TestBspatch(t *testing.T) {
+	old := []byte("hello world")
+	tests := []struct {
+		name    string
+		patch   []byte
+		maxSize int64
+		want    []byte
+		wantErr error
+	}{
+		{
+			name:    "valid patch",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, 12),
+			maxSize: defaultMaxTargetSize,
+			want:    []byte("hallo world!"),
+		},
+		{
+			name:    "new size equals the limit",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, 12),
+			maxSize: 12,
+			want:    []byte("hallo world!"),
+		},
+		{
+			name:    "new size exceeds the limit",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, 12),
+			maxSize: 11,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "huge new size is not allocated",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, 1<<62),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "negative new size",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, -12),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "invalid magic",
+			patch:   newTestPatch(t, "BSDIFF39", -1, -1, 12),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "truncated header",
+			patch:   []byte(bsdiffMagic),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "control block beyond patch",
+			patch:   newTestPatch(t, bsdiffMagic, 1<<20, -1, 12),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "diff block beyond patch",
+			patch:   newTestPatch(t, bsdiffMagic, -1, 1<<62, 12),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "control beyond new size",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, 5),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+		{
+			name:    "control exhausted before new size",
+			patch:   newTestPatch(t, bsdiffMagic, -1, -1, 20),
+			maxSize: defaultMaxTargetSize,
+			wantErr: errCorruptPatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bspatch(old, tt.patch, tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("bspatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("bspatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
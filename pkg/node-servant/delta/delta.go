@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// deltaApplier reconstructs a file from the current version on the node and a delta, so only the
+// delta is transferred over the constrained links.
+type deltaApplier struct {
+	Options
+}
+
+// NewDeltaApplierWithOptions creates deltaApplier
+func NewDeltaApplierWithOptions(o *Options) *deltaApplier {
+	return &deltaApplier{
+		*o,
+	}
+}
+
+// Do is used for the apply-delta job
+// shall be implemented as idempotent, can execute multiple times with no side-affect.
+func (d *deltaApplier) Do() error {
+	if current, err := os.ReadFile(d.output); err == nil && digestOf(current) == d.targetDigest {
+		klog.Infof("%s is already %s, skip applying delta", d.output, d.targetDigest)
+		return nil
+	}
+
+	base, err := os.ReadFile(d.base)
+	if err != nil {
+		return err
+	}
+	if digest := digestOf(base); digest != d.baseDigest {
+		return fmt.Errorf("digest of base %s is %s, the delta is made against %s", d.base, digest, d.baseDigest)
+	}
+
+	patch, err := d.loadDelta()
+	if err != nil {
+		return err
+	}
+	target, err := bspatch(base, patch, d.maxTargetSize)
+	if err != nil {
+		return err
+	}
+	if digest := digestOf(target); digest != d.targetDigest {
+		return fmt.Errorf("digest of reconstructed file is %s, but %s is expected", digest, d.targetDigest)
+	}
+	klog.Infof("%s of %d bytes is reconstructed from %d bytes delta", d.output, len(target), len(patch))
+
+	info, err := os.Stat(d.base)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(d.output, target, info.Mode())
+}
+
+// loadDelta reads the delta from local path or downloads it from url.
+func (d *deltaApplier) loadDelta() ([]byte, error) {
+	if !strings.HasPrefix(d.delta, "http://") && !strings.HasPrefix(d.delta, "https://") {
+		return os.ReadFile(d.delta)
+	}
+
+	client := &http.Client{Timeout: d.downloadTimeout}
+	resp, err := client.Get(d.delta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download delta %s, %w", d.delta, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download delta %s, status code %d", d.delta, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return sha256Prefix + hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomically writes data into a temporary file in the same directory and renames it to path,
+// so the file is never left half written, and running binaries keep the old file by their inode.
+func writeFileAtomically(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".delta-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delta
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	// defaultDownloadTimeout defines the default timeout of downloading the delta, which is small
+	// enough for the metered and slow links
+	defaultDownloadTimeout = 10 * time.Minute
+	// defaultMaxTargetSize defines the default limit of the reconstructed file, the new file is
+	// built in memory, so the size in delta is never allocated beyond it
+	defaultMaxTargetSize = 1 << 30
+	sha256Prefix         = "sha256:"
+)
+
+// Options has the information that required by apply-delta operation
+type Options struct {
+	base            string
+	delta           string
+	output          string
+	baseDigest      string
+	targetDigest    string
+	downloadTimeout time.Duration
+	maxTargetSize   int64
+}
+
+// NewApplyDeltaOptions creates a new Options
+func NewApplyDeltaOptions() *Options {
+	return &Options{
+		downloadTimeout: defaultDownloadTimeout,
+		maxTargetSize:   defaultMaxTargetSize,
+	}
+}
+
+// Complete completes all the required options.
+func (o *Options) Complete() {
+	if len(o.output) == 0 {
+		o.output = o.base
+	}
+}
+
+// Validate validates Options
+func (o *Options) Validate() error {
+	if len(o.base) == 0 || len(o.delta) == 0 {
+		return fmt.Errorf("base and delta must be specified")
+	}
+	for _, digest := range []string{o.baseDigest, o.targetDigest} {
+		if !strings.HasPrefix(digest, sha256Prefix) || len(digest) != len(sha256Prefix)+64 {
+			return fmt.Errorf("digest %q is invalid, it must be in the format of sha256:<hex>", digest)
+		}
+	}
+	if o.downloadTimeout <= 0 {
+		return fmt.Errorf("download timeout must be positive")
+	}
+	if o.maxTargetSize <= 0 {
+		return fmt.Errorf("max target size must be positive")
+	}
+	return nil
+}
+
+// AddFlags sets flags.
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.base, "base", o.base, "The path of the current file on the node which the delta is made against.")
+	fs.StringVar(&o.delta, "delta", o.delta, "The path or http(s) url of the bsdiff delta.")
+	fs.StringVar(&o.output, "output", o.output, "The path of the reconstructed file, the base file is replaced if not specified.")
+	fs.StringVar(&o.baseDigest, "base-digest", o.baseDigest, "The sha256 digest of base file, like sha256:<hex>.")
+	fs.StringVar(&o.targetDigest, "target-digest", o.targetDigest, "The sha256 digest of the reconstructed file, like sha256:<hex>.")
+	fs.DurationVar(&o.downloadTimeout, "download-timeout", o.downloadTimeout, "The timeout of downloading the delta.")
+	fs.Int64Var(&o.maxTargetSize, "max-target-size", o.maxTargetSize, "The max size in bytes of the reconstructed file, the delta is rejected if it declares a larger file. Set it to the size of target file if it's known.")
+}
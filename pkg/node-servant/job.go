@@ -52,6 +52,9 @@ func RenderNodeServantJob(action string, renderCtx map[string]string, nodeName s
 	case "config-control-plane":
 		servantJobTemplate = ConfigControlPlaneJobTemplate
 		jobBaseName = ConfigControlPlaneJobNameBase
+	case "upgrade-yurthub":
+		servantJobTemplate = UpgradeYurthubJobTemplate
+		jobBaseName = UpgradeYurthubJobNameBase
 	}
 
 	tmplCtx["jobName"] = jobBaseName + "-" + nodeName
@@ -70,9 +73,34 @@ func RenderNodeServantJob(action string, renderCtx map[string]string, nodeName s
 		return nil, fmt.Errorf("fail to assert node-servant job")
 	}
 
+	if action == "upgrade-yurthub" {
+		srvJob.Spec.Template.Spec.Containers[0].Args = upgradeYurthubArgs(tmplCtx)
+	}
 	return srvJob, nil
 }
 
+// upgradeYurthubArgs returns the args of node-servant for upgrading yurthub, every flag is passed
+// as a separate arg, so the hooks are passed to node-servant as they are.
+func upgradeYurthubArgs(tmplCtx map[string]string) []string {
+	args := []string{"upgrade", "yurthub",
+		"--node-name=" + tmplCtx["nodeName"],
+		"--yurthub-image=" + tmplCtx["yurthub_image"],
+	}
+	for _, opt := range []struct{ key, flag string }{
+		{"yurthub_healthcheck_timeout", "yurthub-healthcheck-timeout"},
+		{"probation_period", "probation-period"},
+		{"pre_upgrade_hook", "pre-upgrade-hook"},
+		{"post_upgrade_hook", "post-upgrade-hook"},
+		{"hook_timeout", "hook-timeout"},
+		{"hook_failure_policy", "hook-failure-policy"},
+	} {
+		if len(tmplCtx[opt.key]) != 0 {
+			args = append(args, fmt.Sprintf("--%s=%s", opt.flag, tmplCtx[opt.key]))
+		}
+	}
+	return args
+}
+
 // YamlToObject deserializes object in yaml format to a runtime.Object
 func YamlToObject(yamlContent []byte) (k8sruntime.Object, error) {
 	decode := serializer.NewCodecFactory(scheme.Scheme).UniversalDeserializer().Decode
@@ -95,6 +123,9 @@ func validate(action string, tmplCtx map[string]string, nodeName string) error {
 	case "revert":
 		keysMustHave := []string{"node_servant_image"}
 		return checkKeys(keysMustHave, tmplCtx)
+	case "upgrade-yurthub":
+		keysMustHave := []string{"node_servant_image", "yurthub_image"}
+		return checkKeys(keysMustHave, tmplCtx)
 	case "preflight-convert", "config-control-plane":
 		keysMustHave := []string{"node_servant_image"}
 		return checkKeys(keysMustHave, tmplCtx)
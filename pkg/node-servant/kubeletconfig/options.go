@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeletconfig
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+const (
+	defaultSyncPeriod = 30 * time.Second
+	// maintenanceFileName is the file in openyurt dir which switches kubelet back to the cloud
+	maintenanceFileName = "hub-maintenance"
+)
+
+// Options has the information that required by reconcile-kubelet operation
+type Options struct {
+	openyurtDir     string
+	syncPeriod      time.Duration
+	maintenanceFile string
+	hubHealthzAddr  string
+}
+
+// NewReconcileKubeletOptions creates a new Options
+func NewReconcileKubeletOptions() *Options {
+	return &Options{
+		syncPeriod:     defaultSyncPeriod,
+		hubHealthzAddr: fmt.Sprintf("127.0.0.1:%d", util.YurtHubPort),
+	}
+}
+
+// Complete completes all the required options.
+func (o *Options) Complete() {
+	o.openyurtDir = os.Getenv("OPENYURT_DIR")
+	if o.openyurtDir == "" {
+		o.openyurtDir = constants.OpenyurtDir
+	}
+	if len(o.maintenanceFile) == 0 {
+		o.maintenanceFile = filepath.Join(o.openyurtDir, maintenanceFileName)
+	}
+}
+
+// Validate validates Options
+func (o *Options) Validate() error {
+	if o.syncPeriod <= 0 {
+		return fmt.Errorf("sync period must be positive")
+	}
+	if _, _, err := net.SplitHostPort(o.hubHealthzAddr); err != nil {
+		return fmt.Errorf("yurthub healthz address %s is invalid, %w", o.hubHealthzAddr, err)
+	}
+	return nil
+}
+
+// AddFlags sets flags.
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.syncPeriod, "sync-period", o.syncPeriod, "The period of checking kubelet config.")
+	fs.StringVar(&o.maintenanceFile, "maintenance-file", o.maintenanceFile, "kubelet is switched back to the cloud while this file exists, e.g. during yurthub maintenance. Defaults to <openyurt dir>/hub-maintenance.")
+	fs.StringVar(&o.hubHealthzAddr, "yurthub-healthz-addr", o.hubHealthzAddr, "The address of yurthub server for checking yurthub is healthy before redirecting kubelet to it.")
+}
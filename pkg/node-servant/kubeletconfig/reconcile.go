@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeletconfig
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/components"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+)
+
+const verifyTimeout = 5 * time.Second
+
+type kubeletOperator interface {
+	RedirectTrafficToYurtHub() error
+	UndoRedirectTrafficToYurtHub() error
+	IsTrafficRedirectedToYurtHub() (bool, bool, error)
+}
+
+// kubeletReconciler keeps kubelet pointed at yurthub, the wiring done at join drifts when kubelet
+// config is updated by other tooling. kubelet is switched back to the cloud while the maintenance
+// file exists, and it's redirected to yurthub again once the file is removed and yurthub is healthy.
+type kubeletReconciler struct {
+	Options
+	op kubeletOperator
+}
+
+// NewKubeletReconcilerWithOptions creates kubeletReconciler
+func NewKubeletReconcilerWithOptions(o *Options) *kubeletReconciler {
+	return &kubeletReconciler{
+		Options: *o,
+		op:      components.NewKubeletOperator(o.openyurtDir),
+	}
+}
+
+// Run reconciles kubelet config periodically until stopCh is closed
+func (r *kubeletReconciler) Run(stopCh <-chan struct{}) {
+	klog.Infof("start reconciling kubelet config every %v", r.syncPeriod)
+	wait.Until(func() {
+		if err := r.reconcile(); err != nil {
+			klog.Errorf("failed to reconcile kubelet config, %v", err)
+		}
+	}, r.syncPeriod, stopCh)
+}
+
+func (r *kubeletReconciler) reconcile() error {
+	redirected, upToDate, err := r.op.IsTrafficRedirectedToYurtHub()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(r.maintenanceFile); err == nil {
+		if !redirected {
+			return nil
+		}
+		klog.Infof("maintenance file %s exists, switch kubelet back to the cloud", r.maintenanceFile)
+		return r.op.UndoRedirectTrafficToYurtHub()
+	}
+
+	if redirected && upToDate {
+		return nil
+	}
+	// kubelet can't reach apiServer at all if it's redirected to a yurthub which is not serving
+	if err := r.verifyYurtHub(); err != nil {
+		return fmt.Errorf("kubelet is not redirected to yurthub, %w", err)
+	}
+	klog.Infof("kubelet config drifted(redirected: %v, up to date: %v), redirect kubelet to yurthub", redirected, upToDate)
+	return r.op.RedirectTrafficToYurtHub()
+}
+
+// verifyYurtHub checks the port in kubelet.conf for yurthub is served and yurthub is healthy.
+func (r *kubeletReconciler) verifyYurtHub() error {
+	config, err := clientcmd.Load([]byte(constants.KubeletConfForNode))
+	if err != nil {
+		return err
+	}
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok || config.Clusters[kubeContext.Cluster] == nil {
+		return fmt.Errorf("cluster of kubelet.conf for yurthub is not found")
+	}
+	server, err := url.Parse(config.Clusters[kubeContext.Cluster].Server)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", server.Host, verifyTimeout)
+	if err != nil {
+		return fmt.Errorf("yurthub proxy port %s is not served, %w", server.Host, err)
+	}
+	conn.Close()
+
+	client := &http.Client{Timeout: verifyTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://%s/v1/healthz", r.hubHealthzAddr))
+	if err != nil {
+		return fmt.Errorf("failed to check yurthub healthz, %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("yurthub is not healthy, healthz status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node_servant
+
+import (
+	"strings"
+	"time"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+// PrePullImages returns the images of steps and the extra images of pre-pull without duplicates.
+func PrePullImages(upgrade *appsv1beta1.PoolUpgrade) []string {
+	var images []string
+	seen := make(map[string]bool)
+	add := func(image string) {
+		if len(image) != 0 && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	for _, step := range upgrade.Spec.Steps {
+		add(step.Image)
+	}
+	if upgrade.Spec.PrePull != nil {
+		for _, image := range upgrade.Spec.PrePull.Images {
+			add(image)
+		}
+	}
+	return images
+}
+
+// PrePullArgs returns the args of node-servant in the pre-pull container, the p2p mirror which is
+// chosen for each node is not included. They are also used for signing the pre-pull container offline.
+func PrePullArgs(upgrade *appsv1beta1.PoolUpgrade) []string {
+	args := []string{"pull-image", "--images=" + strings.Join(PrePullImages(upgrade), ",")}
+	if interval := upgrade.Spec.PrePull.PullIntervalSeconds; interval > 0 {
+		args = append(args, "--pull-interval="+(time.Duration(interval)*time.Second).String())
+	}
+	return args
+}
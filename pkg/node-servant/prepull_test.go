@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node_servant
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestPrePullImages(t *testing.T) {
+	upgrade := &appsv1beta1.PoolUpgrade{
+		Spec: appsv1beta1.PoolUpgradeSpec{
+			Steps: []appsv1beta1.PoolUpgradeStep{
+				{Name: "yurthub", Image: "openyurt/node-servant:v1.3.0"},
+				{Name: "kubelet", Image: "openyurt/node-servant:v1.3.0"},
+				{Name: "os-hook", Image: "example/os-hook:v1"},
+			},
+			PrePull: &appsv1beta1.PoolUpgradePrePull{
+				Images: []string{"openyurt/yurthub:v1.3.0", "example/os-hook:v1"},
+			},
+		},
+	}
+
+	images := PrePullImages(upgrade)
+	expect := []string{"openyurt/node-servant:v1.3.0", "example/os-hook:v1", "openyurt/yurthub:v1.3.0"}
+	if !reflect.DeepEqual(images, expect) {
+		t.Errorf("expect images %v, but got %v", expect, images)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// containerdCertsDir is the config_path of containerd registry hosts, containerd resolves the
+	// images of registry by the hosts in <containerdCertsDir>/<registry>/hosts.toml.
+	containerdCertsDir = "/etc/containerd/certs.d"
+	// containerdConfigFile is the config file of containerd, which sets the config_path of registry hosts
+	containerdConfigFile = "/etc/containerd/config.toml"
+	// containerdDefaultConfigPath is the config_path used by containerd if it's not set in the config
+	// of version 3, the config of older versions has no default config_path.
+	containerdDefaultConfigPath = "/etc/containerd/certs.d:/etc/docker/certs.d"
+	hostsFileName               = "hosts.toml"
+	managedHostsHeader          = "# managed by node-servant, images are fetched from the p2p mirror and then the registry"
+
+	defaultRegistry     = "docker.io"
+	defaultRegistryHost = "https://registry-1.docker.io"
+)
+
+// registryOf returns the registry of image, images without registry are from docker.io.
+func registryOf(image string) string {
+	i := strings.IndexRune(image, '/')
+	if i < 0 {
+		return defaultRegistry
+	}
+	domain := image[:i]
+	if !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		return defaultRegistry
+	}
+	return domain
+}
+
+// renderHostsToml renders the registry hosts of containerd, the mirror is tried first and the
+// registry itself is the fallback.
+func renderHostsToml(registry, mirror string) string {
+	server := "https://" + registry
+	if registry == defaultRegistry {
+		server = defaultRegistryHost
+	}
+	return fmt.Sprintf(`%s
+server = %q
+
+[host.%q]
+  capabilities = ["pull", "resolve"]
+`, managedHostsHeader, server, mirror)
+}
+
+// ensureMirror configures containerd to fetch the images of registry from mirror, the hosts which
+// are not written by node-servant are left as they are.
+func ensureMirror(certsDir, registry, mirror string) error {
+	hostsFile := filepath.Join(certsDir, registry, hostsFileName)
+	content := renderHostsToml(registry, mirror)
+	current, err := os.ReadFile(hostsFile)
+	switch {
+	case err == nil && string(current) == content:
+		return nil
+	case err == nil && !strings.HasPrefix(string(current), managedHostsHeader):
+		klog.Warningf("%s is not managed by node-servant, images of %s are not fetched from p2p mirror %s", hostsFile, registry, mirror)
+		return nil
+	case err != nil && !os.IsNotExist(err):
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(hostsFile), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(hostsFile, []byte(content), 0644); err != nil {
+		return err
+	}
+	klog.Infof("images of %s are fetched from p2p mirror %s", registry, mirror)
+	return nil
+}
+
+// containerdConfigPaths returns the dirs of registry hosts set by config_path in the containerd
+// config file, empty if registry hosts are not enabled.
+func containerdConfigPaths(configFile string) ([]string, error) {
+	var config struct {
+		Version int `toml:"version"`
+		Plugins map[string]struct {
+			Registry struct {
+				ConfigPath string `toml:"config_path"`
+			} `toml:"registry"`
+		} `toml:"plugins"`
+	}
+	if _, err := toml.DecodeFile(configFile, &config); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to decode containerd config %s, %w", configFile, err)
+	}
+
+	var configPath string
+	for _, plugin := range config.Plugins {
+		if len(plugin.Registry.ConfigPath) != 0 {
+			configPath = plugin.Registry.ConfigPath
+		}
+	}
+	if len(configPath) == 0 && config.Version >= 3 {
+		configPath = containerdDefaultConfigPath
+	}
+	if len(configPath) == 0 {
+		return nil, nil
+	}
+	return filepath.SplitList(configPath), nil
+}
+
+// verifyConfigPath checks the registry hosts in certsDir are used by containerd.
+func verifyConfigPath(configFile, certsDir string) error {
+	paths, err := containerdConfigPaths(configFile)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if filepath.Clean(path) == filepath.Clean(certsDir) {
+			return nil
+		}
+	}
+	return fmt.Errorf("config_path of registry in %s is %v, which doesn't include %s", configFile, paths, certsDir)
+}
+
+// removeMirrors removes the registry hosts written by node-servant except the ones of registries,
+// and the dirs of them if they become empty.
+func removeMirrors(certsDir string, registries sets.String) error {
+	entries, err := os.ReadDir(certsDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || registries.Has(entry.Name()) {
+			continue
+		}
+		hostsFile := filepath.Join(certsDir, entry.Name(), hostsFileName)
+		current, err := os.ReadFile(hostsFile)
+		if err != nil || !strings.HasPrefix(string(current), managedHostsHeader) {
+			continue
+		}
+		if err := os.Remove(hostsFile); err != nil {
+			return err
+		}
+		// the dir may have the certificates of registry, which are left as they are
+		os.Remove(filepath.Dir(hostsFile))
+		klog.Infof("images of %s are not fetched from p2p mirror any more", entry.Name())
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestContainerdConfigPaths(t *testing.T) {
+	testcases := map[string]struct {
+		config string
+		paths  []string
+	}{
+		"config path of version 2": {
+			config: `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+			paths: []string{"/etc/containerd/certs.d"},
+		},
+		"config path is not set in version 2": {
+			config: `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["https://registry-1.docker.io"]
+`,
+		},
+		"default config path of version 3": {
+			config: `version = 3
+[plugins."io.containerd.cri.v1.images"]
+  snapshotter = "overlayfs"
+`,
+			paths: []string{"/etc/containerd/certs.d", "/etc/docker/certs.d"},
+		},
+		"no config file": {},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "config.toml")
+			if len(tc.config) != 0 {
+				if err := os.WriteFile(configFile, []byte(tc.config), 0644); err != nil {
+					t.Fatalf("failed to write config, %v", err)
+				}
+			}
+			paths, err := containerdConfigPaths(configFile)
+			if err != nil {
+				t.Fatalf("failed to get config paths, %v", err)
+			}
+			if !reflect.DeepEqual(paths, tc.paths) {
+				t.Errorf("expect config paths %v, but got %v", tc.paths, paths)
+			}
+		})
+	}
+}
+
+func TestRemoveMirrors(t *testing.T) {
+	certsDir := t.TempDir()
+	for _, registry := range []string{"docker.io", "quay.io"} {
+		if err := ensureMirror(certsDir, registry, "http://127.0.0.1:5001"); err != nil {
+			t.Fatalf("failed to ensure mirror, %v", err)
+		}
+	}
+	// hosts which are not written by node-servant
+	unmanaged := filepath.Join(certsDir, "ghcr.io", hostsFileName)
+	os.MkdirAll(filepath.Dir(unmanaged), 0755)
+	if err := os.WriteFile(unmanaged, []byte(`server = "https://ghcr.io"`), 0644); err != nil {
+		t.Fatalf("failed to write hosts, %v", err)
+	}
+
+	if err := removeMirrors(certsDir, sets.NewString("docker.io")); err != nil {
+		t.Fatalf("failed to remove mirrors, %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(certsDir, "docker.io", hostsFileName)); err != nil {
+		t.Errorf("expect mirror of docker.io is kept, %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(certsDir, "quay.io")); !os.IsNotExist(err) {
+		t.Errorf("expect mirror of quay.io is removed, %v", err)
+	}
+	if _, err := os.Stat(unmanaged); err != nil {
+		t.Errorf("expect unmanaged hosts are kept, %v", err)
+	}
+}
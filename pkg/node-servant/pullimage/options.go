@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/components"
+)
+
+// Options has the information that required by pull-image operation
+type Options struct {
+	images       []string
+	criSocket    string
+	pullInterval time.Duration
+	p2pMirror    string
+}
+
+// NewPullImageOptions creates a new Options
+func NewPullImageOptions() *Options {
+	return &Options{}
+}
+
+// Complete completes all the required options.
+func (o *Options) Complete() error {
+	if len(o.criSocket) != 0 {
+		return nil
+	}
+	criSocket, err := components.DetectCRISocket()
+	if err != nil {
+		return err
+	}
+	o.criSocket = criSocket
+	return nil
+}
+
+// Validate validates Options
+func (o *Options) Validate() error {
+	if len(o.images) == 0 {
+		return fmt.Errorf("images are empty")
+	}
+	for _, image := range o.images {
+		if len(image) == 0 || strings.ContainsAny(image, " \t\n") {
+			return fmt.Errorf("image %q is invalid", image)
+		}
+	}
+	if o.pullInterval < 0 {
+		return fmt.Errorf("pull interval must not be negative")
+	}
+	if len(o.p2pMirror) != 0 {
+		u, err := url.Parse(o.p2pMirror)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("p2p mirror %q must be a http or https address", o.p2pMirror)
+		}
+	}
+	return nil
+}
+
+// AddFlags sets flags.
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&o.images, "images", o.images, "The images pulled on the node, images pinned by digest like image@sha256:... are verified after pulling.")
+	fs.StringVar(&o.criSocket, "cri-socket", o.criSocket, "The path to the CRI socket, detected automatically if not specified.")
+	fs.StringVar(&o.p2pMirror, "p2p-mirror", o.p2pMirror, "The address of p2p image mirror on the node, like http://127.0.0.1:5001. Images are fetched from the peers through it and then the registry, only containerd is supported.")
+	fs.DurationVar(&o.pullInterval, "pull-interval", o.pullInterval, "The pause between pulling two images, which leaves bandwidth of slow links for the workloads.")
+}
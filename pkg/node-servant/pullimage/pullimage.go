@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullimage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	utilsexec "k8s.io/utils/exec"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/components"
+)
+
+// imagePuller pulls the images on the node ahead of upgrades
+type imagePuller struct {
+	Options
+}
+
+// NewPullerWithOptions creates imagePuller
+func NewPullerWithOptions(o *Options) *imagePuller {
+	return &imagePuller{
+		*o,
+	}
+}
+
+// Do is used for the pull-image job
+// shall be implemented as idempotent, can execute multiple times with no side-affect.
+func (p *imagePuller) Do() error {
+	runtime, err := components.NewContainerRuntimeForImage(utilsexec.New(), p.criSocket)
+	if err != nil {
+		return err
+	}
+	p.configureMirror()
+
+	for i, image := range p.images {
+		if i != 0 && p.pullInterval > 0 {
+			time.Sleep(p.pullInterval)
+		}
+		if err := pullImage(runtime, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pullImage pulls the image if it doesn't exist, and verifies the image is present on the node
+// with the pinned digest.
+func pullImage(runtime components.ContainerRuntimeForImage, image string) error {
+	exists, err := runtime.ImageExists(image)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		klog.Infof("pulling image %s", image)
+		start := time.Now()
+		if err := runtime.PullImage(image); err != nil {
+			return fmt.Errorf("failed to pull image %s, %w", image, err)
+		}
+		klog.Infof("image %s is pulled in %v", image, time.Since(start).Round(time.Second))
+	} else {
+		klog.Infof("image %s already exists", image)
+	}
+	return verifyImage(runtime, image)
+}
+
+// verifyImage checks the image exists, and the digest of image is the pinned one if image is
+// referenced by digest.
+func verifyImage(runtime components.ContainerRuntimeForImage, image string) error {
+	exists, err := runtime.ImageExists(image)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("image %s doesn't exist after pulling", image)
+	}
+
+	index := strings.LastIndex(image, "@")
+	if index < 0 {
+		return nil
+	}
+	digest := image[index+1:]
+	digests, err := runtime.RepoDigests(image)
+	if err != nil {
+		return err
+	}
+	for _, d := range digests {
+		if strings.HasSuffix(d, "@"+digest) {
+			klog.Infof("image %s is verified", image)
+			return nil
+		}
+	}
+	return fmt.Errorf("digest %s of image %s is not found in repo digests %v", digest, image, digests)
+}
+
+// configureMirror configures the p2p mirror for the registries of images, the images are pulled from
+// the registries directly if the mirror can't be configured. The mirror configured for the other
+// registries is removed, so the mirror isn't used any more after it's disabled for the pool.
+func (p *imagePuller) configureMirror() {
+	if !strings.Contains(p.criSocket, "containerd") {
+		if len(p.p2pMirror) != 0 {
+			klog.Warningf("p2p mirror %s is only supported by containerd, images are pulled from registries", p.p2pMirror)
+		}
+		return
+	}
+
+	registries := sets.NewString()
+	if len(p.p2pMirror) != 0 {
+		if err := verifyConfigPath(containerdConfigFile, containerdCertsDir); err != nil {
+			klog.Warningf("p2p mirror %s is not used by containerd, images are pulled from registries, %v", p.p2pMirror, err)
+		} else {
+			for _, image := range p.images {
+				registries.Insert(registryOf(image))
+			}
+		}
+	}
+	for _, registry := range registries.List() {
+		if err := ensureMirror(containerdCertsDir, registry, p.p2pMirror); err != nil {
+			klog.Warningf("failed to configure p2p mirror for registry %s, %v", registry, err)
+		}
+	}
+	if err := removeMirrors(containerdCertsDir, registries); err != nil {
+		klog.Warningf("failed to remove p2p mirror of registries, %v", err)
+	}
+}
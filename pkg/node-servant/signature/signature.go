@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// FleetPublicKeyFile is the ed25519 public key of fleet in PEM format. The key is provisioned on
+	// the node out of band, so it can't be replaced by the payloads it verifies. The containers of
+	// upgrade jobs and the OTA payloads must be signed by the fleet key if the file exists.
+	// The private key is kept offline, only the signatures made by `yurtadm sign` are delivered
+	// to the cluster.
+	FleetPublicKeyFile = "/etc/openyurt/fleet-signing-key.pub"
+
+	// ContainerSignaturesAnnotation is the annotation of pod which records the signatures of its
+	// containers in json, like {"yurthub":"<base64 signature>"}. The signatures are copied from the
+	// PoolUpgrade by the poolupgrade controller, and verified by yurthub before kubelet runs the pod.
+	ContainerSignaturesAnnotation = "apps.openyurt.io/container-signatures"
+
+	// NodeServantEntry is the entrypoint of node-servant image.
+	NodeServantEntry = "/usr/local/bin/entry.sh"
+)
+
+// publicKeyFile is the path of fleet public key, it's only changed in tests.
+var publicKeyFile = FleetPublicKeyFile
+
+// unsignedFlags are excluded from the signed message, they are set by the controller for each node
+// separately, so a container is signed once for all nodes. node-name is only used for publishing
+// progress, and the images fetched through p2p-mirror are verified by the digests in signed args.
+var unsignedFlags = map[string]bool{
+	"node-name":  true,
+	"p2p-mirror": true,
+}
+
+// Enforced checks whether the commands and OTA payloads are required to be signed on the node
+func Enforced() bool {
+	_, err := os.Stat(publicKeyFile)
+	return err == nil
+}
+
+// VerifyPodContainers verifies the signatures of all containers in pod, which are recorded in the
+// ContainerSignaturesAnnotation. Nothing is verified if the public key is not provisioned on the node.
+func VerifyPodContainers(pod *corev1.Pod) error {
+	key, err := loadPublicKey()
+	if key == nil || err != nil {
+		return err
+	}
+
+	sigs := map[string]string{}
+	if data, ok := pod.Annotations[ContainerSignaturesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &sigs); err != nil {
+			return fmt.Errorf("invalid container signatures, %w", err)
+		}
+	}
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			c := &containers[i]
+			sig := sigs[c.Name]
+			if len(sig) == 0 {
+				return fmt.Errorf("container %s is not signed, but signature is required by %s", c.Name, publicKeyFile)
+			}
+			if err := verify(key, ContainerMessage(c.Image, c.Command, c.Args), sig); err != nil {
+				return fmt.Errorf("container %s: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// VerifyPayload verifies the signature of OTA payload by the fleet public key, nothing is verified
+// if the public key is not provisioned on the node.
+func VerifyPayload(payload []byte, sig string) error {
+	key, err := loadPublicKey()
+	if key == nil || err != nil {
+		return err
+	}
+	if len(sig) == 0 {
+		return fmt.Errorf("payload is not signed, but signature is required by %s", publicKeyFile)
+	}
+	return verify(key, payload, sig)
+}
+
+// ContainerMessage returns the signed message of container, which is the image, the command and
+// the args except the unsigned flags, one per line. The command and args are separated by "--". e.g.
+//
+//	openyurt/node-servant@sha256:...
+//	/usr/local/bin/entry.sh
+//	--
+//	upgrade
+//	yurthub
+//	--yurthub-image=openyurt/yurthub@sha256:...
+//
+// the signature can be made by `yurtadm sign`, or by
+// `openssl pkeyutl -sign -rawin -inkey fleet.key -in message | base64 -w0`.
+func ContainerMessage(image string, command, args []string) []byte {
+	lines := append([]string{image}, command...)
+	lines = append(lines, "--")
+	for i := 0; i < len(args); i++ {
+		name, hasValue := flagName(args[i])
+		if unsignedFlags[name] {
+			// skip the value of flag in the form of "--flag value"
+			if !hasValue && i+1 < len(args) {
+				i++
+			}
+			continue
+		}
+		lines = append(lines, args[i])
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// SignContainer returns the base64 encoded signature of container.
+func SignContainer(key ed25519.PrivateKey, image string, command, args []string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, ContainerMessage(image, command, args)))
+}
+
+// ContainerSignatures returns the value of ContainerSignaturesAnnotation for the signatures of
+// containers, the containers without signature are omitted.
+func ContainerSignatures(sigs map[string]string) string {
+	signed := make(map[string]string, len(sigs))
+	for name, sig := range sigs {
+		if len(sig) != 0 {
+			signed[name] = sig
+		}
+	}
+	// the keys of map are sorted by json, so the annotation is stable
+	data, _ := json.Marshal(signed)
+	return string(data)
+}
+
+// SignPayload returns the base64 encoded signature of OTA payload.
+func SignPayload(key ed25519.PrivateKey, payload []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+}
+
+// TemplatePayload returns the OTA payload of pod template owned by the workload namespace/name,
+// which is the workload and the sha256 digest of template.
+func TemplatePayload(namespace, name string, template *corev1.PodTemplateSpec) ([]byte, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	return []byte(fmt.Sprintf("%s/%s\nsha256:%s", namespace, name, hex.EncodeToString(digest[:]))), nil
+}
+
+// LoadSigningKey loads the ed25519 private key of fleet in PKCS#8 PEM format, it's only used by
+// the offline signing tool.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fleet signing key, %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("fleet signing key is not in PEM format")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fleet signing key, %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("fleet signing key must be ed25519, got %T", key)
+	}
+	return edKey, nil
+}
+
+// flagName returns the name of flag in arg, and whether the value is set in the arg.
+func flagName(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "--") {
+		return "", false
+	}
+	name := strings.TrimPrefix(arg, "--")
+	if i := strings.Index(name, "="); i >= 0 {
+		return name[:i], true
+	}
+	return name, false
+}
+
+// loadPublicKey returns nil if the fleet public key is not provisioned on the node
+func loadPublicKey() (ed25519.PublicKey, error) {
+	keyData, err := os.ReadFile(publicKeyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read fleet public key, %w", err)
+	}
+	return parsePublicKey(keyData)
+}
+
+func parsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("fleet public key is not in PEM format")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fleet public key, %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("fleet public key must be ed25519, got %T", key)
+	}
+	return edKey, nil
+}
+
+func verify(key ed25519.PublicKey, message []byte, sig string) error {
+	sigData, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("signature is not base64 encoded, %w", err)
+	}
+	if !ed25519.Verify(key, message, sigData) {
+		return errors.New("signature is not verified by fleet public key, the container or payload may be tampered")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// provisionKey generates the fleet key, and writes the public key into a temp file as the
+// fleet public key on the node.
+func provisionKey(t *testing.T) ed25519.PrivateKey {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to marshal public key, %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "fleet-signing-key.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatalf("failed to write public key, %v", err)
+	}
+	publicKeyFile = path
+	t.Cleanup(func() { publicKeyFile = FleetPublicKeyFile })
+	return key
+}
+
+func TestContainerMessage(t *testing.T) {
+	testcases := map[string]struct {
+		command []string
+		args    []string
+		expect  string
+	}{
+		"unsigned flags are excluded": {
+			command: []string{NodeServantEntry},
+			args:    []string{"pull-image", "--node-name=node1", "--images=yurthub@sha256:abc", "--p2p-mirror=http://127.0.0.1:5001"},
+			expect:  "node-servant:v1\n" + NodeServantEntry + "\n--\npull-image\n--images=yurthub@sha256:abc",
+		},
+		"unsigned flags with separate values": {
+			args:   []string{"upgrade", "yurthub", "--node-name", "node1", "--probation-period=10m"},
+			expect: "node-servant:v1\n--\nupgrade\nyurthub\n--probation-period=10m",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if got := string(ContainerMessage("node-servant:v1", tc.command, tc.args)); got != tc.expect {
+				t.Errorf("expect message %q, but got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestVerifyPodContainers(t *testing.T) {
+	newPod := func(sigs map[string]string, hook string) *corev1.Pod {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "yurthub", Image: "node-servant:v1", Command: []string{NodeServantEntry}, Args: []string{"upgrade", "yurthub"}}},
+			Containers:     []corev1.Container{{Name: "os-hook", Image: "os-hook:v1", Args: []string{"--hook=" + hook}}},
+		}}
+		if sigs != nil {
+			pod.Annotations = map[string]string{ContainerSignaturesAnnotation: ContainerSignatures(sigs)}
+		}
+		return pod
+	}
+	if err := VerifyPodContainers(newPod(nil, "reboot")); err != nil {
+		t.Fatalf("expect unsigned pod is allowed without fleet public key, but got %v", err)
+	}
+
+	key := provisionKey(t)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	sigs := map[string]string{
+		"yurthub": SignContainer(key, "node-servant:v1", []string{NodeServantEntry}, []string{"upgrade", "yurthub"}),
+		"os-hook": SignContainer(key, "os-hook:v1", nil, []string{"--hook=reboot"}),
+	}
+	testcases := map[string]struct {
+		pod   *corev1.Pod
+		isErr bool
+	}{
+		"all containers signed": {
+			pod: newPod(sigs, "reboot"),
+		},
+		"unsigned pod": {
+			pod:   newPod(nil, "reboot"),
+			isErr: true,
+		},
+		"one container unsigned": {
+			pod:   newPod(map[string]string{"yurthub": sigs["yurthub"]}, "reboot"),
+			isErr: true,
+		},
+		"tampered args": {
+			pod:   newPod(sigs, "rm -rf /"),
+			isErr: true,
+		},
+		"signed by other key": {
+			pod: newPod(map[string]string{
+				"yurthub": sigs["yurthub"],
+				"os-hook": SignContainer(otherKey, "os-hook:v1", nil, []string{"--hook=reboot"}),
+			}, "reboot"),
+			isErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if err := VerifyPodContainers(tc.pod); (err != nil) != tc.isErr {
+				t.Errorf("expect error %v, but got %v", tc.isErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyPayload(t *testing.T) {
+	template := &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v2"}}}}
+	payload, err := TemplatePayload("kube-system", "app", template)
+	if err != nil {
+		t.Fatalf("failed to get payload, %v", err)
+	}
+	if err := VerifyPayload(payload, ""); err != nil {
+		t.Fatalf("expect unsigned payload is allowed without fleet public key, but got %v", err)
+	}
+
+	key := provisionKey(t)
+	sig := SignPayload(key, payload)
+	if err := VerifyPayload(payload, sig); err != nil {
+		t.Errorf("expect payload is verified, but got %v", err)
+	}
+	if err := VerifyPayload(payload, ""); err == nil {
+		t.Errorf("expect unsigned payload is rejected")
+	}
+
+	template.Spec.Containers[0].Image = "evil:latest"
+	tampered, _ := TemplatePayload("kube-system", "app", template)
+	if err := VerifyPayload(tampered, sig); err == nil {
+		t.Errorf("expect tampered payload is rejected")
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key, %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "fleet.key")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+
+	got, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("failed to load signing key, %v", err)
+	}
+	if !key.Equal(got) {
+		t.Errorf("loaded key is different from the written one")
+	}
+
+	os.WriteFile(path, []byte("not a key"), 0600)
+	if _, err := LoadSigningKey(path); err == nil {
+		t.Errorf("expect error for invalid signing key")
+	}
+}
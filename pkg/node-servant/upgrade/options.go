@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/components"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+)
+
+const (
+	// defaultYurthubHealthCheckTimeout defines the default timeout for the new yurthub to be ready
+	defaultYurthubHealthCheckTimeout = 2 * time.Minute
+	// defaultProbationPeriod defines the default period in which the new yurthub must keep healthy
+	defaultProbationPeriod = 5 * time.Minute
+	// defaultHookTimeout defines the default timeout of pre-upgrade and post-upgrade hooks
+	defaultHookTimeout = time.Minute
+)
+
+// Options has the information that required by upgrade operation
+type Options struct {
+	nodeName                  string
+	yurthubImage              string
+	yurthubHealthCheckTimeout time.Duration
+	probationPeriod           time.Duration
+	preUpgradeHook            string
+	postUpgradeHook           string
+	hookTimeout               time.Duration
+	hookFailurePolicy         string
+}
+
+// NewUpgradeOptions creates a new Options
+func NewUpgradeOptions() *Options {
+	return &Options{
+		nodeName:                  os.Getenv("NODE_NAME"),
+		yurthubHealthCheckTimeout: defaultYurthubHealthCheckTimeout,
+		probationPeriod:           defaultProbationPeriod,
+		hookTimeout:               defaultHookTimeout,
+		hookFailurePolicy:         string(components.HookFailurePolicyFail),
+	}
+}
+
+// Validate validates Options
+func (o *Options) Validate() error {
+	if len(o.yurthubImage) == 0 {
+		return fmt.Errorf("yurthub image is empty")
+	}
+	if strings.ContainsAny(o.yurthubImage, " \t\n") {
+		return fmt.Errorf("yurthub image %q is invalid", o.yurthubImage)
+	}
+	// the tag of image can be moved after the command is signed
+	if signature.Enforced() && !strings.Contains(o.yurthubImage, "@sha256:") {
+		return fmt.Errorf("yurthub image %q must be pinned by digest when commands are signed", o.yurthubImage)
+	}
+	if o.yurthubHealthCheckTimeout <= 0 || o.probationPeriod <= 0 {
+		return fmt.Errorf("yurthub health check timeout and probation period must be positive")
+	}
+	if o.hookTimeout <= 0 {
+		return fmt.Errorf("hook timeout must be positive")
+	}
+	switch components.HookFailurePolicy(o.hookFailurePolicy) {
+	case components.HookFailurePolicyFail, components.HookFailurePolicyIgnore:
+	default:
+		return fmt.Errorf("hook failure policy must be %s or %s, got %s",
+			components.HookFailurePolicyFail, components.HookFailurePolicyIgnore, o.hookFailurePolicy)
+	}
+	return nil
+}
+
+// hooks returns the pre-upgrade and post-upgrade hooks
+func (o *Options) hooks() (*components.Hook, *components.Hook) {
+	newHook := func(name, command string) *components.Hook {
+		return &components.Hook{
+			Name:          name,
+			Command:       command,
+			Timeout:       o.hookTimeout,
+			FailurePolicy: components.HookFailurePolicy(o.hookFailurePolicy),
+		}
+	}
+	return newHook("pre-upgrade", o.preUpgradeHook), newHook("post-upgrade", o.postUpgradeHook)
+}
+
+// AddFlags sets flags.
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.nodeName, "node-name", o.nodeName, "The name of node, the upgrade progress is published as condition and events of node. Defaults to env NODE_NAME, and no progress is published if empty.")
+	fs.StringVar(&o.yurthubImage, "yurthub-image", o.yurthubImage, "The yurthub image upgraded to.")
+	fs.DurationVar(&o.yurthubHealthCheckTimeout, "yurthub-healthcheck-timeout", o.yurthubHealthCheckTimeout, "The timeout for the new yurthub to be ready.")
+	fs.DurationVar(&o.probationPeriod, "probation-period", o.probationPeriod, "The period in which the new yurthub must keep healthy, otherwise yurthub is rolled back.")
+	fs.StringVar(&o.preUpgradeHook, "pre-upgrade-hook", o.preUpgradeHook, "The shell command executed on the node before upgrade, the upgrade is aborted if it fails.")
+	fs.StringVar(&o.postUpgradeHook, "post-upgrade-hook", o.postUpgradeHook, "The shell command executed on the node after probation, the upgrade is rolled back if it fails. It's also executed after the upgrade is aborted or rolled back, so the components quiesced by pre-upgrade hook are resumed.")
+	fs.DurationVar(&o.hookTimeout, "hook-timeout", o.hookTimeout, "The timeout of pre-upgrade and post-upgrade hooks.")
+	fs.StringVar(&o.hookFailurePolicy, "hook-failure-policy", o.hookFailurePolicy, "The policy when a hook fails, Fail or Ignore.")
+}
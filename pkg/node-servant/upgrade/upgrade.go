@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package upgrade
+
+import (
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/components"
+	"github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+// nodeUpgrader do the yurthub upgrade job
+type nodeUpgrader struct {
+	Options
+}
+
+// NewUpgraderWithOptions creates nodeUpgrader
+func NewUpgraderWithOptions(o *Options) *nodeUpgrader {
+	return &nodeUpgrader{
+		*o,
+	}
+}
+
+// Do is used for the upgrade job
+// shall be implemented as idempotent, can execute multiple times with no side-affect.
+func (n *nodeUpgrader) Do() error {
+	op := components.NewYurthubOperator("", n.yurthubImage, "",
+		util.WorkingModeEdge, n.yurthubHealthCheckTimeout, true, true) // only image and timeout are used here
+	preHook, postHook := n.hooks()
+	return op.Upgrade(n.probationPeriod, preHook, postHook, n.progressReporter())
+}
+
+// progressReporter returns nil if the progress can't be published, the upgrade goes on without reports.
+func (n *nodeUpgrader) progressReporter() *components.ProgressReporter {
+	if len(n.nodeName) == 0 {
+		return nil
+	}
+	reporter, err := components.NewProgressReporter(n.nodeName, components.NodeConditionYurtHubUpgrade)
+	if err != nil {
+		klog.Warningf("upgrade progress of node %s is not published, %v", n.nodeName, err)
+		return nil
+	}
+	return reporter
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egresspolicy
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+// bandwidths are the bytes per second of bandwidth classes, the unlimited class is not listed.
+var bandwidths = map[appsv1beta1.EgressBandwidthClass]int64{
+	appsv1beta1.EgressBandwidthStandard: 10 * 1000 * 1000 / 8,
+	appsv1beta1.EgressBandwidthLow:      1000 * 1000 / 8,
+}
+
+// Destination is a parsed destination of egress policy, it's either a cidr or a domain with an optional port.
+type Destination struct {
+	cidr *net.IPNet
+	// domain is in lower case, and the subdomains are matched if it starts with "*."
+	domain string
+	// port is 0 if all ports are matched
+	port int
+}
+
+// ParseDestination parses a destination like 10.0.0.0/8, 10.1.2.3, registry.example.com:443 or *.example.com.
+func ParseDestination(dest string) (*Destination, error) {
+	if strings.Contains(dest, "/") {
+		_, cidr, err := net.ParseCIDR(dest)
+		if err != nil {
+			return nil, fmt.Errorf("destination %q is not a valid cidr, %w", dest, err)
+		}
+		return &Destination{cidr: cidr}, nil
+	}
+	if ip := net.ParseIP(dest); ip != nil {
+		bits := 8 * net.IPv4len
+		if ip.To4() == nil {
+			bits = 8 * net.IPv6len
+		}
+		return &Destination{cidr: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+	}
+
+	d := &Destination{domain: strings.ToLower(dest)}
+	if host, port, err := net.SplitHostPort(dest); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("port of destination %q is invalid", dest)
+		}
+		d.domain, d.port = strings.ToLower(host), p
+	}
+	if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(d.domain, "*.")); len(errs) != 0 {
+		return nil, fmt.Errorf("destination %q is not a valid cidr or domain, %s", dest, strings.Join(errs, ", "))
+	}
+	return d, nil
+}
+
+// MatchesIP checks the destination matches ip and port
+func (d *Destination) MatchesIP(ip net.IP, port int) bool {
+	return d.cidr != nil && d.cidr.Contains(ip) && d.matchesPort(port)
+}
+
+// MatchesDomain checks the destination matches domain and port, a wildcard domain *.example.com
+// matches the subdomains of example.com but not example.com itself.
+func (d *Destination) MatchesDomain(domain string, port int) bool {
+	if len(d.domain) == 0 || !d.matchesPort(port) {
+		return false
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if strings.HasPrefix(d.domain, "*.") {
+		return strings.HasSuffix(domain, d.domain[1:])
+	}
+	return domain == d.domain
+}
+
+func (d *Destination) matchesPort(port int) bool {
+	return d.port == 0 || d.port == port
+}
+
+// Validate checks the destinations and bandwidth class of policy
+func Validate(policy *appsv1beta1.NodePoolEgressPolicy) error {
+	for _, dest := range policy.Destinations {
+		if _, err := ParseDestination(dest); err != nil {
+			return err
+		}
+	}
+	if _, err := Bandwidth(policy.BandwidthClass); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Bandwidth returns the bytes per second of bandwidth class, it's 0 if the bandwidth is unlimited.
+func Bandwidth(class appsv1beta1.EgressBandwidthClass) (int64, error) {
+	if len(class) == 0 || class == appsv1beta1.EgressBandwidthUnlimited {
+		return 0, nil
+	}
+	bandwidth, ok := bandwidths[class]
+	if !ok {
+		return 0, fmt.Errorf("bandwidth class %s is not supported", class)
+	}
+	return bandwidth, nil
+}
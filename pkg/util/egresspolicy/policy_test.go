@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egresspolicy
+
+import (
+	"net"
+	"testing"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestDestination(t *testing.T) {
+	testcases := map[string]struct {
+		dest       string
+		err        bool
+		ip         string
+		domain     string
+		port       int
+		matchesIP  bool
+		matchesDNS bool
+	}{
+		"cidr": {
+			dest:      "10.0.0.0/8",
+			ip:        "10.1.2.3",
+			port:      80,
+			matchesIP: true,
+		},
+		"single ip": {
+			dest:      "192.168.1.1",
+			ip:        "192.168.1.2",
+			port:      80,
+			matchesIP: false,
+		},
+		"domain with port": {
+			dest:       "Registry.example.com:443",
+			domain:     "registry.example.com.",
+			port:       443,
+			matchesDNS: true,
+		},
+		"domain with other port": {
+			dest:   "registry.example.com:443",
+			domain: "registry.example.com",
+			port:   80,
+		},
+		"wildcard domain": {
+			dest:       "*.example.com",
+			domain:     "oss.cn.example.com",
+			port:       443,
+			matchesDNS: true,
+		},
+		"wildcard domain doesn't match parent": {
+			dest:   "*.example.com",
+			domain: "example.com",
+			port:   443,
+		},
+		"invalid cidr": {
+			dest: "10.0.0.0/33",
+			err:  true,
+		},
+		"invalid port": {
+			dest: "example.com:http",
+			err:  true,
+		},
+		"invalid domain": {
+			dest: "exa_mple.com",
+			err:  true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			d, err := ParseDestination(tc.dest)
+			if (err != nil) != tc.err {
+				t.Fatalf("expect error %v, but got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(tc.ip) != 0 && d.MatchesIP(net.ParseIP(tc.ip), tc.port) != tc.matchesIP {
+				t.Errorf("expect ip %s matched %v", tc.ip, tc.matchesIP)
+			}
+			if len(tc.domain) != 0 && d.MatchesDomain(tc.domain, tc.port) != tc.matchesDNS {
+				t.Errorf("expect domain %s matched %v", tc.domain, tc.matchesDNS)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(&appsv1beta1.NodePoolEgressPolicy{Destinations: []string{"10.0.0.0/8"}, BandwidthClass: appsv1beta1.EgressBandwidthLow}); err != nil {
+		t.Errorf("expect valid policy, but got %v", err)
+	}
+	if err := Validate(&appsv1beta1.NodePoolEgressPolicy{BandwidthClass: "Gigabit"}); err == nil {
+		t.Errorf("expect error for unsupported bandwidth class, but got nil")
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenancewindow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	// the zoneinfo files may not exist in the images of controllers
+	_ "time/tzdata"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+// MaxDuration is the maximum duration of a maintenance window.
+const MaxDuration = 7 * 24 * time.Hour
+
+// maxLookahead bounds the search for the next start of a window.
+const maxLookahead = 366 * 24 * time.Hour
+
+// field is the range of a cron field
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Schedule is a parsed cron schedule with five fields: minute, hour, day of month, month and day of week.
+type Schedule struct {
+	bits [5]uint64
+	// the day is matched by either day of month or day of week if both of them are restricted
+	domRestricted bool
+	dowRestricted bool
+}
+
+// ParseSchedule parses the cron schedule, every field supports *, numbers, ranges(a-b), steps(*/n, a-b/n)
+// and comma separated lists of them. 0 and 7 are both Sunday in day of week.
+func ParseSchedule(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("schedule %q should have %d fields, got %d", spec, len(fields), len(parts))
+	}
+
+	s := &Schedule{}
+	for i, part := range parts {
+		bits, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of schedule %q, %v", fields[i].name, spec, err)
+		}
+		s.bits[i] = bits
+	}
+	// sunday is matched by both 0 and 7
+	if s.bits[4]&(1<<7) != 0 {
+		s.bits[4] |= 1
+	}
+	s.domRestricted = parts[2] != "*"
+	s.dowRestricted = parts[4] != "*"
+	return s, nil
+}
+
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			rng, step = item[:i], n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rng)
+			}
+			low, high = n, n
+			// a/n means from a to the max
+			if step != 1 {
+				high = f.max
+			}
+		}
+		if low < f.min || high > f.max || low > high {
+			return 0, fmt.Errorf("%q is out of range [%d, %d]", item, f.min, f.max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches returns whether t is a start time of the schedule in minutes.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.bits[0]&(1<<uint(t.Minute())) == 0 || s.bits[1]&(1<<uint(t.Hour())) == 0 || s.bits[3]&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	return s.dayMatches(t)
+}
+
+// Next returns the earliest start time of the schedule strictly after t in the location of t, the
+// unmatched months, days and hours are skipped as a whole. Zero time is returned if the schedule
+// doesn't start within maxLookahead.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(maxLookahead)
+	next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	for next.Before(limit) {
+		prev := next
+		switch {
+		case s.bits[3]&(1<<uint(next.Month())) == 0:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, loc)
+		case s.bits[1]&(1<<uint(next.Hour())) == 0:
+			// hours are skipped by elapsed time, the wall clock may repeat an hour at daylight saving changes
+			next = next.Add(time.Duration(60-next.Minute()) * time.Minute)
+		case s.bits[0]&(1<<uint(next.Minute())) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+		if !next.After(prev) {
+			next = prev.Add(time.Minute)
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatched := s.bits[2]&(1<<uint(t.Day())) != 0
+	dowMatched := s.bits[4]&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatched || dowMatched
+	}
+	return domMatched && dowMatched
+}
+
+// Validate checks the schedule, duration and time zone of window.
+func Validate(w *appsv1beta1.NodePoolMaintenanceWindow) error {
+	if _, err := ParseSchedule(w.Schedule); err != nil {
+		return err
+	}
+	if w.Duration.Duration < time.Minute || w.Duration.Duration > MaxDuration {
+		return fmt.Errorf("duration %v should be between 1m and %v", w.Duration.Duration, MaxDuration)
+	}
+	if _, err := time.LoadLocation(w.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone %q, %v", w.TimeZone, err)
+	}
+	return nil
+}
+
+// InWindows returns whether now is in any of the windows, the windows are checked in minutes:
+// now is in a window if the window started at a minute within its duration before now.
+func InWindows(windows []appsv1beta1.NodePoolMaintenanceWindow, now time.Time) (bool, error) {
+	for i := range windows {
+		start, err := firstOpenStart(&windows[i], now)
+		if err != nil {
+			return false, err
+		}
+		if !start.IsZero() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Closed returns whether the software of nodes is not allowed to be changed now by the windows of
+// pool. It's never closed if the pool has no windows, and always closed if any window is invalid,
+// so the software of nodes is never changed unexpectedly.
+func Closed(windows []appsv1beta1.NodePoolMaintenanceWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return false
+	}
+	in, err := InWindows(windows, now)
+	return err != nil || !in
+}
+
+// firstOpenStart returns the earliest start of window which is still open at now, zero time is
+// returned if the window is not open.
+func firstOpenStart(w *appsv1beta1.NodePoolMaintenanceWindow, now time.Time) (time.Time, error) {
+	if err := Validate(w); err != nil {
+		return time.Time{}, err
+	}
+	schedule, _ := ParseSchedule(w.Schedule)
+	loc, _ := time.LoadLocation(w.TimeZone)
+
+	// the window is open if it started after now - duration and not after now
+	start := schedule.Next(now.Add(-w.Duration.Duration).In(loc))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, nil
+	}
+	return start, nil
+}
+
+// NextBoundary returns the earliest time after now at which any of the windows starts or ends, the
+// result of InWindows may only change at the boundaries. Zero time is returned if no window is open
+// now or starts within a year.
+func NextBoundary(windows []appsv1beta1.NodePoolMaintenanceWindow, now time.Time) (time.Time, error) {
+	var next time.Time
+	earlier := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for i := range windows {
+		w := &windows[i]
+		// the earliest end of the window which is open now
+		start, err := firstOpenStart(w, now)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !start.IsZero() {
+			earlier(start.Add(w.Duration.Duration))
+		}
+		// the next start of window
+		schedule, _ := ParseSchedule(w.Schedule)
+		loc, _ := time.LoadLocation(w.TimeZone)
+		if start := schedule.Next(now.In(loc)); !start.IsZero() {
+			earlier(start)
+		}
+	}
+	return next, nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenancewindow
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "0 2 * * 6"},
+		{spec: "*/15 1-3,22 1,15 * 1-5"},
+		{spec: "30 0 * * 7"},
+		{spec: "0 2 * *", wantErr: true},
+		{spec: "60 2 * * *", wantErr: true},
+		{spec: "0 5-3 * * *", wantErr: true},
+		{spec: "0 */0 * * *", wantErr: true},
+		{spec: "0 2 * * mon", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			_, err := ParseSchedule(tc.spec)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("expect error %v, but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{spec: "0 2 * * 6", t: time.Date(2023, 7, 1, 2, 0, 0, 0, time.UTC), want: true},
+		{spec: "0 2 * * 6", t: time.Date(2023, 7, 2, 2, 0, 0, 0, time.UTC), want: false},
+		{spec: "0 2 * * 6", t: time.Date(2023, 7, 1, 2, 1, 0, 0, time.UTC), want: false},
+		{spec: "*/15 * * * *", t: time.Date(2023, 7, 1, 9, 45, 0, 0, time.UTC), want: true},
+		{spec: "0 0 * * 7", t: time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC), want: true},
+		// day of month or day of week is matched if both of them are restricted
+		{spec: "0 0 15 * 1", t: time.Date(2023, 7, 15, 0, 0, 0, 0, time.UTC), want: true},
+		{spec: "0 0 15 * 1", t: time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC), want: true},
+		{spec: "0 0 15 * 1", t: time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec+" "+tc.t.String(), func(t *testing.T) {
+			s, err := ParseSchedule(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Matches(tc.t); got != tc.want {
+				t.Errorf("expect %v, but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	newYork, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		spec string
+		t    time.Time
+		want time.Time
+	}{
+		{spec: "0 2 * * 6", t: time.Date(2023, 7, 1, 2, 0, 0, 0, time.UTC), want: time.Date(2023, 7, 8, 2, 0, 0, 0, time.UTC)},
+		{spec: "0 2 * * 6", t: time.Date(2023, 7, 1, 1, 59, 30, 0, time.UTC), want: time.Date(2023, 7, 1, 2, 0, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", t: time.Date(2023, 7, 1, 9, 46, 0, 0, time.UTC), want: time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)},
+		{spec: "30 0 29 2 *", t: time.Date(2023, 7, 1, 0, 0, 0, 0, shanghai), want: time.Date(2024, 2, 29, 0, 30, 0, 0, shanghai)},
+		{spec: "0 0 15 * 1", t: time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC), want: time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC)},
+		// the wall clock skips 02:xx when daylight saving time starts
+		{spec: "30 2,3 * * *", t: time.Date(2023, 3, 12, 0, 0, 0, 0, newYork), want: time.Date(2023, 3, 12, 3, 30, 0, 0, newYork)},
+		// February 30th never comes
+		{spec: "0 0 30 2 *", t: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec+" "+tc.t.String(), func(t *testing.T) {
+			s, err := ParseSchedule(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(tc.t); !got.Equal(tc.want) {
+				t.Errorf("expect %v, but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestInWindows(t *testing.T) {
+	// 02:00-04:00 every Saturday in Shanghai, which is 18:00-20:00 every Friday in UTC
+	windows := []appsv1beta1.NodePoolMaintenanceWindow{{
+		Schedule: "0 2 * * 6",
+		Duration: metav1.Duration{Duration: 2 * time.Hour},
+		TimeZone: "Asia/Shanghai",
+	}}
+	tests := []struct {
+		name    string
+		windows []appsv1beta1.NodePoolMaintenanceWindow
+		now     time.Time
+		want    bool
+		wantErr bool
+	}{
+		{name: "start of window", windows: windows, now: time.Date(2023, 6, 30, 18, 0, 0, 0, time.UTC), want: true},
+		{name: "in window", windows: windows, now: time.Date(2023, 6, 30, 19, 59, 59, 0, time.UTC), want: true},
+		{name: "end of window", windows: windows, now: time.Date(2023, 6, 30, 20, 0, 0, 0, time.UTC), want: false},
+		{name: "before window", windows: windows, now: time.Date(2023, 6, 30, 17, 59, 0, 0, time.UTC), want: false},
+		{name: "no window", now: time.Date(2023, 6, 30, 19, 0, 0, 0, time.UTC), want: false},
+		{
+			name: "invalid time zone",
+			windows: []appsv1beta1.NodePoolMaintenanceWindow{{
+				Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Mars/Olympus",
+			}},
+			now:     time.Date(2023, 6, 30, 19, 0, 0, 0, time.UTC),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := InWindows(tc.windows, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expect error %v, but got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expect %v, but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNextBoundary(t *testing.T) {
+	saturday := appsv1beta1.NodePoolMaintenanceWindow{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 2 * time.Hour}, TimeZone: "UTC"}
+	daily := appsv1beta1.NodePoolMaintenanceWindow{Schedule: "30 3 * * *", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Asia/Shanghai"}
+	tests := []struct {
+		name    string
+		windows []appsv1beta1.NodePoolMaintenanceWindow
+		now     time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "next start of closed window",
+			windows: []appsv1beta1.NodePoolMaintenanceWindow{saturday},
+			// Friday
+			now:  time.Date(2023, 6, 30, 19, 10, 30, 0, time.UTC),
+			want: time.Date(2023, 7, 1, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "end of open window",
+			windows: []appsv1beta1.NodePoolMaintenanceWindow{saturday},
+			now:     time.Date(2023, 7, 1, 3, 0, 0, 0, time.UTC),
+			want:    time.Date(2023, 7, 1, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "earliest boundary of windows",
+			windows: []appsv1beta1.NodePoolMaintenanceWindow{saturday, daily},
+			// 03:30 in Asia/Shanghai is 19:30 UTC of the day before
+			now:  time.Date(2023, 6, 30, 19, 10, 0, 0, time.UTC),
+			want: time.Date(2023, 6, 30, 19, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "invalid window",
+			windows: []appsv1beta1.NodePoolMaintenanceWindow{{
+				Schedule: "0 2 * *", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "UTC",
+			}},
+			now:     time.Date(2023, 6, 30, 19, 0, 0, 0, time.UTC),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NextBoundary(tc.windows, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expect error %v, but got %v", tc.wantErr, err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("expect %v, but got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -96,12 +96,51 @@ func validate(g *v1alpha1.Gateway) error {
 				errList = append(errList, field.Invalid(fldPath, ep.PublicIP, "the 'publicIP' field must be a validate IP address"))
 			}
 		}
+		if len(ep.PublicIPs) != 0 {
+			fldPath := field.NewPath("spec").Child(fmt.Sprintf("endpoints[%d]", i)).Child("publicIPs")
+			if g.Spec.ExposeType == v1alpha1.ExposeTypeLoadBalancer {
+				errList = append(errList, field.Invalid(fldPath, ep.PublicIPs, fmt.Sprintf("the 'publicIPs' field must not be set when spec.exposeType = %s", v1alpha1.ExposeTypeLoadBalancer)))
+			}
+			if err := validateDualStackIPs(ep.PublicIPs); err != nil {
+				errList = append(errList, field.Invalid(fldPath, ep.PublicIPs, err.Error()))
+			} else if ep.PublicIP != "" && ep.PublicIP != ep.PublicIPs[0] {
+				errList = append(errList, field.Invalid(fldPath, ep.PublicIPs, "the first address of 'publicIPs' field must be the 'publicIP' field"))
+			}
+		}
 		if len(ep.NodeName) == 0 {
 			fldPath := field.NewPath("spec").Child(fmt.Sprintf("endpoints[%d]", i)).Child("nodeName")
 			errList = append(errList, field.Invalid(fldPath, ep.NodeName, "the 'nodeName' field must not be empty"))
 		}
 	}
 
+	if g.Spec.MaxActiveEndpoints < 0 {
+		fldPath := field.NewPath("spec").Child("maxActiveEndpoints")
+		errList = append(errList, field.Invalid(fldPath, g.Spec.MaxActiveEndpoints, "the 'maxActiveEndpoints' field must not be negative"))
+	}
+
+	peerNames := make(map[string]bool, len(g.Spec.Peers))
+	for i, p := range g.Spec.Peers {
+		fldPath := field.NewPath("spec").Child(fmt.Sprintf("peers[%d]", i))
+		switch {
+		case len(p.Name) == 0:
+			errList = append(errList, field.Invalid(fldPath.Child("name"), p.Name, "the 'name' field must not be empty"))
+		case p.Name == g.Name:
+			errList = append(errList, field.Invalid(fldPath.Child("name"), p.Name, "the gateway must not be a peer of itself"))
+		case peerNames[p.Name]:
+			errList = append(errList, field.Duplicate(fldPath.Child("name"), p.Name))
+		}
+		peerNames[p.Name] = true
+
+		switch p.Topology {
+		case "", v1alpha1.TopologyHubAndSpoke, v1alpha1.TopologyDirect:
+		default:
+			errList = append(errList, field.NotSupported(fldPath.Child("topology"), p.Topology,
+				[]string{string(v1alpha1.TopologyHubAndSpoke), string(v1alpha1.TopologyDirect)}))
+		}
+	}
+
+	errList = append(errList, validateSubnetMappings(g.Spec.SubnetMappings, field.NewPath("spec").Child("subnetMappings"))...)
+
 	if errList != nil {
 		return apierrors.NewInvalid(
 			schema.GroupKind{Group: v1alpha1.SchemeGroupVersion.Group, Kind: g.Kind},
@@ -120,3 +159,74 @@ func validateIP(ip string) error {
 	}
 	return fmt.Errorf("invalid ip address: %s", ip)
 }
+
+// validateDualStackIPs checks the addresses are valid, and there is at most one address for each IP family.
+func validateDualStackIPs(ips []string) error {
+	if len(ips) > 2 {
+		return fmt.Errorf("at most one address for each IP family is allowed")
+	}
+	var hasIPv4, hasIPv6 bool
+	for _, ip := range ips {
+		s := net.ParseIP(ip)
+		switch {
+		case s == nil:
+			return fmt.Errorf("invalid ip address: %s", ip)
+		case s.To4() != nil:
+			if hasIPv4 {
+				return fmt.Errorf("at most one address for each IP family is allowed")
+			}
+			hasIPv4 = true
+		default:
+			if hasIPv6 {
+				return fmt.Errorf("at most one address for each IP family is allowed")
+			}
+			hasIPv6 = true
+		}
+	}
+	return nil
+}
+
+// validateSubnetMappings checks the subnets of each mapping have the same size, and the subnets
+// or the mapped subnets of different mappings don't overlap.
+func validateSubnetMappings(mappings []v1alpha1.SubnetMapping, fldPath *field.Path) field.ErrorList {
+	var errList field.ErrorList
+	var subnets, mappedSubnets []*net.IPNet
+	for i, m := range mappings {
+		idxPath := fldPath.Child(fmt.Sprintf("[%d]", i))
+		_, subnet, err := net.ParseCIDR(m.Subnet)
+		if err != nil {
+			errList = append(errList, field.Invalid(idxPath.Child("subnet"), m.Subnet, "the 'subnet' field must be a valid CIDR"))
+		}
+		_, mappedSubnet, err := net.ParseCIDR(m.MappedSubnet)
+		if err != nil {
+			errList = append(errList, field.Invalid(idxPath.Child("mappedSubnet"), m.MappedSubnet, "the 'mappedSubnet' field must be a valid CIDR"))
+		}
+		if subnet == nil || mappedSubnet == nil {
+			continue
+		}
+
+		ones, bits := subnet.Mask.Size()
+		mappedOnes, mappedBits := mappedSubnet.Mask.Size()
+		if ones != mappedOnes || bits != mappedBits {
+			errList = append(errList, field.Invalid(idxPath.Child("mappedSubnet"), m.MappedSubnet, "the 'mappedSubnet' field must have the same IP family and prefix length as the 'subnet' field"))
+		}
+		if overlapsAny(subnets, subnet) {
+			errList = append(errList, field.Invalid(idxPath.Child("subnet"), m.Subnet, "the 'subnet' field must not overlap with the subnets of other mappings"))
+		}
+		if overlapsAny(mappedSubnets, mappedSubnet) {
+			errList = append(errList, field.Invalid(idxPath.Child("mappedSubnet"), m.MappedSubnet, "the 'mappedSubnet' field must not overlap with the mapped subnets of other mappings"))
+		}
+		subnets = append(subnets, subnet)
+		mappedSubnets = append(mappedSubnets, mappedSubnet)
+	}
+	return errList
+}
+
+func overlapsAny(subnets []*net.IPNet, subnet *net.IPNet) bool {
+	for _, s := range subnets {
+		if s.Contains(subnet.IP) || subnet.Contains(s.IP) {
+			return true
+		}
+	}
+	return false
+}
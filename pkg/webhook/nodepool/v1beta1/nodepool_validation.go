@@ -19,16 +19,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/openyurtio/openyurt/pkg/apis/apps"
 	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/util/egresspolicy"
+	"github.com/openyurtio/openyurt/pkg/util/maintenancewindow"
 )
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type.
@@ -99,9 +104,142 @@ func validateNodePoolSpec(spec *appsv1beta1.NodePoolSpec) field.ErrorList {
 	if allErrs := validateNodePoolSpecAnnotations(spec.Annotations); allErrs != nil {
 		return allErrs
 	}
+
+	switch spec.ConflictPolicy {
+	case "", appsv1beta1.ConflictPolicyForce, appsv1beta1.ConflictPolicyMerge, appsv1beta1.ConflictPolicyIgnore:
+	default:
+		return field.ErrorList([]*field.Error{
+			field.NotSupported(field.NewPath("spec").Child("conflictPolicy"), spec.ConflictPolicy,
+				[]string{string(appsv1beta1.ConflictPolicyForce), string(appsv1beta1.ConflictPolicyMerge), string(appsv1beta1.ConflictPolicyIgnore)})})
+	}
+
+	if spec.PodTolerationSeconds != nil && *spec.PodTolerationSeconds < 0 {
+		return field.ErrorList([]*field.Error{
+			field.Invalid(field.NewPath("spec").Child("podTolerationSeconds"), *spec.PodTolerationSeconds, "should not be negative")})
+	}
+
+	if allErrs := validateNodePoolScaling(spec.Scaling); allErrs != nil {
+		return allErrs
+	}
+
+	if allErrs := validateNodePoolQuota(spec.Quota); allErrs != nil {
+		return allErrs
+	}
+
+	if spec.Decommission != nil && spec.Decommission.DrainTimeoutSeconds < 0 {
+		return field.ErrorList([]*field.Error{
+			field.Invalid(field.NewPath("spec").Child("decommission", "drainTimeoutSeconds"), spec.Decommission.DrainTimeoutSeconds, "should not be negative")})
+	}
+
+	for i := range spec.MaintenanceWindows {
+		if err := maintenancewindow.Validate(&spec.MaintenanceWindows[i]); err != nil {
+			return field.ErrorList([]*field.Error{
+				field.Invalid(field.NewPath("spec").Child("maintenanceWindows").Index(i), spec.MaintenanceWindows[i], err.Error())})
+		}
+	}
+
+	if spec.Egress != nil {
+		if err := egresspolicy.Validate(spec.Egress); err != nil {
+			return field.ErrorList([]*field.Error{
+				field.Invalid(field.NewPath("spec").Child("egress"), spec.Egress, err.Error())})
+		}
+	}
 	return nil
 }
 
+// quotaResources are the resources which are supported by the quota of nodepool.
+var quotaResources = sets.NewString(
+	string(corev1.ResourceRequestsCPU),
+	string(corev1.ResourceRequestsMemory),
+	string(corev1.ResourceLimitsCPU),
+	string(corev1.ResourceLimitsMemory),
+	string(corev1.ResourceCPU),
+	string(corev1.ResourceMemory),
+	string(corev1.ResourcePods),
+)
+
+// containerResources are the resources which are supported by the default LimitRange of nodepool quota.
+var containerResources = sets.NewString(
+	string(corev1.ResourceCPU),
+	string(corev1.ResourceMemory),
+)
+
+// validateNodePoolQuota validates the quota config of nodepool.
+func validateNodePoolQuota(quota *appsv1beta1.NodePoolQuota) field.ErrorList {
+	if quota == nil {
+		return nil
+	}
+	fldPath := field.NewPath("spec").Child("quota")
+	allErrs := field.ErrorList{}
+	if quota.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(quota.NamespaceSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaceSelector"), quota.NamespaceSelector, err.Error()))
+		}
+	}
+	if len(quota.Hard) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("hard"), "at least one resource limit is required"))
+	}
+	allErrs = append(allErrs, validateResourceList(fldPath.Child("hard"), quota.Hard, quotaResources)...)
+	allErrs = append(allErrs, validateResourceList(fldPath.Child("namespaceHard"), quota.NamespaceHard, quotaResources)...)
+	allErrs = append(allErrs, validateResourceList(fldPath.Child("defaultRequests"), quota.DefaultRequests, containerResources)...)
+	allErrs = append(allErrs, validateResourceList(fldPath.Child("defaultLimits"), quota.DefaultLimits, containerResources)...)
+	for name, request := range quota.DefaultRequests {
+		if limit, ok := quota.DefaultLimits[name]; ok && request.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("defaultRequests").Key(string(name)), request.String(), "should not be greater than the default limit"))
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return allErrs
+}
+
+// validateResourceList validates that the resources of list are supported and not negative.
+func validateResourceList(fldPath *field.Path, list corev1.ResourceList, supported sets.String) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for name, quantity := range list {
+		if !supported.Has(string(name)) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Key(string(name)), name, supported.List()))
+		} else if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(string(name)), quantity.String(), "should not be negative"))
+		}
+	}
+	return allErrs
+}
+
+// validateNodePoolScaling validates the scaling config of nodepool.
+func validateNodePoolScaling(scaling *appsv1beta1.NodePoolScaling) field.ErrorList {
+	if scaling == nil {
+		return nil
+	}
+	fldPath := field.NewPath("spec").Child("scaling")
+	allErrs := field.ErrorList{}
+	if len(scaling.Provisioner.URL) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("provisioner", "url"), "provisioner webhook url is required"))
+	} else if u, err := url.Parse(scaling.Provisioner.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("provisioner", "url"), scaling.Provisioner.URL, "should be a valid http or https url"))
+	}
+	if scaling.MinNodes < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minNodes"), scaling.MinNodes, "should not be negative"))
+	}
+	if scaling.MaxNodes < 0 || (scaling.MaxNodes > 0 && scaling.MaxNodes < scaling.MinNodes) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxNodes"), scaling.MaxNodes, "should be 0 or not less than minNodes"))
+	}
+	if scaling.ScaleDownUtilizationThreshold < 0 || scaling.ScaleDownUtilizationThreshold > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("scaleDownUtilizationThreshold"), scaling.ScaleDownUtilizationThreshold, "should be in range [0, 100]"))
+	}
+	if scaling.CooldownSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("cooldownSeconds"), scaling.CooldownSeconds, "should not be negative"))
+	}
+	if scaling.Provisioner.TimeoutSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("provisioner", "timeoutSeconds"), scaling.Provisioner.TimeoutSeconds, "should not be negative"))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return allErrs
+}
+
 // validateNodePoolSpecUpdate tests if required fields in the NodePool spec are set.
 func validateNodePoolSpecUpdate(spec, oldSpec *appsv1beta1.NodePoolSpec) field.ErrorList {
 	if allErrs := validateNodePoolSpec(spec); allErrs != nil {
@@ -113,6 +251,11 @@ func validateNodePoolSpecUpdate(spec, oldSpec *appsv1beta1.NodePoolSpec) field.E
 			field.Invalid(field.NewPath("spec").Child("type"),
 				spec.Annotations, "pool type can't be changed")})
 	}
+
+	if oldSpec.Decommission != nil && spec.Decommission == nil {
+		return field.ErrorList([]*field.Error{
+			field.Forbidden(field.NewPath("spec").Child("decommission"), "decommission can't be cancelled")})
+	}
 	return nil
 }
 
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.openyurt.io,resources=nodepools,verbs=get;list;watch
+
+// Default satisfies the defaulting webhook interface.
+// The target nodepool of pods is specified by apps.openyurt.io/target-nodepool label of namespace,
+// the nodeSelector of the pool and the tolerations of pool taints are injected into the pods, so
+// application teams can target sites without knowing the labels and taints of nodepool.
+func (webhook *PodHandler) Default(ctx context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a Pod but got a %T", obj))
+	}
+	if !needInjectPoolConstraints(pod) {
+		return nil
+	}
+
+	var ns corev1.Namespace
+	if err := webhook.Client.Get(ctx, types.NamespacedName{Name: pod.Namespace}, &ns); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	poolName := ns.Labels[apps.LabelTargetNodePool]
+	if len(poolName) == 0 {
+		return nil
+	}
+
+	var np appsv1beta1.NodePool
+	if err := webhook.Client.Get(ctx, types.NamespacedName{Name: poolName}, &np); err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.Warningf("target nodepool %s of namespace %s is not found", poolName, ns.Name)
+			return nil
+		}
+		return err
+	}
+
+	injectPoolConstraints(pod, &np)
+	klog.V(4).Infof("inject scheduling constraints of nodepool %s into pod %s/%s", poolName, pod.Namespace, pod.Name)
+	return nil
+}
+
+// needInjectPoolConstraints returns false for the pods which have been targeted explicitly,
+// DaemonSet pods and static pods.
+func needInjectPoolConstraints(pod *corev1.Pod) bool {
+	if len(pod.Spec.NodeName) != 0 || len(pod.Annotations[corev1.MirrorPodAnnotationKey]) != 0 {
+		return false
+	}
+	for i := range pod.OwnerReferences {
+		if pod.OwnerReferences[i].Kind == "DaemonSet" {
+			return false
+		}
+	}
+	if _, ok := pod.Spec.NodeSelector[apps.LabelCurrentNodePool]; ok {
+		return false
+	}
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil &&
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				if expr.Key == apps.LabelCurrentNodePool {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// injectPoolConstraints adds the nodeSelector of pool and the tolerations of pool taints into the pod.
+func injectPoolConstraints(pod *corev1.Pod, np *appsv1beta1.NodePool) {
+	if pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = make(map[string]string)
+	}
+	pod.Spec.NodeSelector[apps.LabelCurrentNodePool] = np.Name
+
+	for i := range np.Spec.Taints {
+		taint := &np.Spec.Taints[i]
+		if isTaintTolerated(pod.Spec.Tolerations, taint) {
+			continue
+		}
+		toleration := corev1.Toleration{
+			Key:      taint.Key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    taint.Value,
+			Effect:   taint.Effect,
+		}
+		if len(taint.Value) == 0 {
+			toleration.Operator = corev1.TolerationOpExists
+		}
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, toleration)
+	}
+}
+
+func isTaintTolerated(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
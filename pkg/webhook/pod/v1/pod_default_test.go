@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+func TestNeedInjectPoolConstraints(t *testing.T) {
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		expect bool
+	}{
+		{
+			name:   "normal pod",
+			pod:    &corev1.Pod{},
+			expect: true,
+		},
+		{
+			name:   "pod with node name",
+			pod:    &corev1.Pod{Spec: corev1.PodSpec{NodeName: "node1"}},
+			expect: false,
+		},
+		{
+			name: "daemonset pod",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}},
+			}},
+			expect: false,
+		},
+		{
+			name:   "pod with pool node selector",
+			pod:    &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: map[string]string{apps.LabelCurrentNodePool: "hangzhou"}}},
+			expect: false,
+		},
+		{
+			name: "pod with pool node affinity",
+			pod: &corev1.Pod{Spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      apps.LabelCurrentNodePool,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"hangzhou", "beijing"},
+						}},
+					}},
+				},
+			}}}},
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needInjectPoolConstraints(tt.pod); got != tt.expect {
+				t.Errorf("expect %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestInjectPoolConstraints(t *testing.T) {
+	np := &appsv1beta1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "hangzhou"},
+		Spec: appsv1beta1.NodePoolSpec{
+			Taints: []corev1.Taint{
+				{Key: "site", Value: "hangzhou", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "edge", Effect: corev1.TaintEffectNoExecute},
+				{Key: "dedicated", Value: "iot", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+			Tolerations: []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpExists},
+			},
+		},
+	}
+
+	injectPoolConstraints(pod, np)
+	expectSelector := map[string]string{"kubernetes.io/os": "linux", apps.LabelCurrentNodePool: "hangzhou"}
+	if !reflect.DeepEqual(expectSelector, pod.Spec.NodeSelector) {
+		t.Errorf("expect node selector %v, but got %v", expectSelector, pod.Spec.NodeSelector)
+	}
+	expectTolerations := []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpExists},
+		{Key: "site", Operator: corev1.TolerationOpEqual, Value: "hangzhou", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "edge", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	}
+	if !reflect.DeepEqual(expectTolerations, pod.Spec.Tolerations) {
+		t.Errorf("expect tolerations %v, but got %v", expectTolerations, pod.Spec.Tolerations)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/openyurtio/openyurt/pkg/webhook/util"
+)
+
+// SetupWebhookWithManager sets up Pod webhooks. 	mutate path, validatepath, error
+func (webhook *PodHandler) SetupWebhookWithManager(mgr ctrl.Manager) (string, string, error) {
+	// init
+	webhook.Client = mgr.GetClient()
+	webhook.Recorder = mgr.GetEventRecorderFor("yurt-manager-pod-webhook")
+
+	gvk, err := apiutil.GVKForObject(&corev1.Pod{}, mgr.GetScheme())
+	if err != nil {
+		return "", "", err
+	}
+	// pods of system namespaces are excluded, so control plane is not blocked by the webhooks
+	util.SetNamespaceSelector(util.GenerateMutatePath(gvk), util.NonSystemNamespaceSelector())
+	util.SetNamespaceSelector(util.GenerateValidatePath(gvk), util.NonSystemNamespaceSelector())
+	// the validating webhook is registered by hand, because it returns warnings for the pods
+	validatingWebhook, err := util.NewValidatingWebhookWithWarnings(mgr.GetScheme(), &corev1.Pod{}, webhook)
+	if err != nil {
+		return "", "", err
+	}
+	mgr.GetWebhookServer().Register(util.GenerateValidatePath(gvk), validatingWebhook)
+
+	return util.GenerateMutatePath(gvk),
+		util.GenerateValidatePath(gvk),
+		ctrl.NewWebhookManagedBy(mgr).
+			For(&corev1.Pod{}).
+			WithDefaulter(webhook).
+			Complete()
+}
+
+// failurePolicy is ignore, so creating pods will not be blocked when yurt-manager is unavailable.
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,groups="",resources=pods,verbs=create,versions=v1,name=m.v1.pod.kb.io,sideEffects=None,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate--v1-pod,mutating=false,failurePolicy=ignore,groups="",resources=pods,verbs=create,versions=v1,name=v.v1.pod.kb.io,sideEffects=None,admissionReviewVersions=v1
+
+// PodHandler implements a defaulting webhook for Pod, which injects the scheduling constraints
+// of the target nodepool of namespace into the pods, and a validating webhook which warns about
+// the pods that are scheduled onto the nodepools without ready nodes.
+type PodHandler struct {
+	Client client.Client
+	// Recorder records the warnings as events of the controller of pods, because the warnings
+	// are only shown to the controller which creates the pods.
+	Recorder record.EventRecorder
+}
+
+var _ webhook.CustomDefaulter = &PodHandler{}
+var _ util.CustomValidatorWithWarnings = &PodHandler{}
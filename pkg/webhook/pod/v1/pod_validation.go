@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+)
+
+// NodePoolUnavailable is the reason of events for the pods which target the nodepools without ready nodes.
+const NodePoolUnavailable = "NodePoolUnavailable"
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type.
+// Pods are never rejected, the mistakes are reported by warnings.
+func (webhook *PodHandler) ValidateCreate(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (webhook *PodHandler) ValidateUpdate(_ context.Context, _, _ runtime.Object) error {
+	return nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type.
+func (webhook *PodHandler) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+// WarningsOnCreate warns if the pod can only be scheduled onto the nodepools which don't exist or have
+// no ready nodes, because the pod will be pending silently.
+func (webhook *PodHandler) WarningsOnCreate(ctx context.Context, obj runtime.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	pools := targetNodePools(pod)
+	if pools.Len() == 0 {
+		return nil
+	}
+
+	var missing, notReady []string
+	for _, name := range pools.List() {
+		var np appsv1beta1.NodePool
+		if err := webhook.Client.Get(ctx, types.NamespacedName{Name: name}, &np); err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.Errorf("could not get nodepool %s, %v", name, err)
+				return nil
+			}
+			missing = append(missing, name)
+			continue
+		}
+		if np.Status.ReadyNodeNum != 0 {
+			return nil
+		}
+		notReady = append(notReady, name)
+	}
+
+	var warnings []string
+	if len(missing) != 0 {
+		warnings = append(warnings, fmt.Sprintf("nodepool %v doesn't exist, the pod will be pending until the nodepool is created", missing))
+	}
+	if len(notReady) != 0 {
+		warnings = append(warnings, fmt.Sprintf("nodepool %v has no ready nodes, the pod will be pending until nodes of the pool are ready", notReady))
+	}
+	webhook.recordWarnings(pod, warnings)
+	return warnings
+}
+
+// recordWarnings records the warnings as events of the controller of pod, because the pods of workloads are
+// created by controllers and the warnings are never shown to users. The pod has not been created yet, so the
+// warnings of the pods without controller are only returned to the client.
+func (webhook *PodHandler) recordWarnings(pod *corev1.Pod, warnings []string) {
+	owner := metav1.GetControllerOf(pod)
+	if webhook.Recorder == nil || owner == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Namespace:  pod.Namespace,
+		Name:       owner.Name,
+		UID:        owner.UID,
+	}
+	for _, warning := range warnings {
+		webhook.Recorder.Event(ref, corev1.EventTypeWarning, NodePoolUnavailable, warning)
+	}
+}
+
+// targetNodePools returns the nodepools which the pod is restricted to by node selector or required node affinity,
+// an empty set is returned if the pod is not restricted to any nodepools.
+func targetNodePools(pod *corev1.Pod) sets.String {
+	if pool, ok := pod.Spec.NodeSelector[apps.LabelCurrentNodePool]; ok {
+		return sets.NewString(pool)
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil ||
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return sets.NewString()
+	}
+
+	// node selector terms are ORed, so the pod is restricted only if every term restricts the nodepool
+	pools := sets.NewString()
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		restricted := false
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == apps.LabelCurrentNodePool && expr.Operator == corev1.NodeSelectorOpIn {
+				pools.Insert(expr.Values...)
+				restricted = true
+			}
+		}
+		if !restricted {
+			return sets.NewString()
+		}
+	}
+	return pools
+}
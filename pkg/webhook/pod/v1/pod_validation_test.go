@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+)
+
+func podWithAffinity(terms ...corev1.NodeSelectorTerm) *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+				},
+			},
+		},
+	}
+}
+
+func TestTargetNodePools(t *testing.T) {
+	poolTerm := func(pools ...string) corev1.NodeSelectorTerm {
+		return corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: apps.LabelCurrentNodePool, Operator: corev1.NodeSelectorOpIn, Values: pools},
+		}}
+	}
+	zoneTerm := corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+		{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"zone-a"}},
+	}}
+
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		expect []string
+	}{
+		{
+			name:   "pod without constraints",
+			pod:    &corev1.Pod{},
+			expect: []string{},
+		},
+		{
+			name:   "node selector",
+			pod:    &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: map[string]string{apps.LabelCurrentNodePool: "hangzhou"}}},
+			expect: []string{"hangzhou"},
+		},
+		{
+			name:   "node affinity",
+			pod:    podWithAffinity(poolTerm("hangzhou", "beijing"), poolTerm("shanghai")),
+			expect: []string{"beijing", "hangzhou", "shanghai"},
+		},
+		{
+			name:   "node affinity with term not restricting pool",
+			pod:    podWithAffinity(poolTerm("hangzhou"), zoneTerm),
+			expect: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pools := targetNodePools(tt.pod).List(); !reflect.DeepEqual(pools, tt.expect) {
+				t.Errorf("expect pools %v, but got %v", tt.expect, pools)
+			}
+		})
+	}
+}
+
+func TestRecordWarnings(t *testing.T) {
+	isController := true
+	recorder := record.NewFakeRecorder(10)
+	webhook := &PodHandler{Recorder: recorder}
+
+	webhook.recordWarnings(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}, []string{"no ready nodes"})
+	if len(recorder.Events) != 0 {
+		t.Errorf("expect no events for pod without controller, but got %d", len(recorder.Events))
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "foo", Controller: &isController}},
+	}}
+	webhook.recordWarnings(pod, []string{"no ready nodes"})
+	if event := <-recorder.Events; event != "Warning NodePoolUnavailable no ready nodes" {
+		t.Errorf("expect warning event of controller, but got %q", event)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/filter/servicetopology"
+)
+
+const (
+	// AnnotationNamespaceServiceTopologySelector is the label selector of services in the namespace,
+	// only the selected services are applied with the service topology policy of namespace.
+	// all services in the namespace are selected if not specified.
+	AnnotationNamespaceServiceTopologySelector = "openyurt.io/topologyKeys-selector"
+)
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Default satisfies the defaulting webhook interface.
+// The service topology policy of namespace is specified by openyurt.io/topologyKeys annotation
+// of namespace, and it is applied to the services that are not annotated explicitly when they are created.
+func (webhook *ServiceHandler) Default(ctx context.Context, obj runtime.Object) error {
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a Service but got a %T", obj))
+	}
+	if _, ok := svc.Annotations[servicetopology.AnnotationServiceTopologyKey]; ok {
+		return nil
+	}
+
+	var ns corev1.Namespace
+	if err := webhook.Client.Get(ctx, types.NamespacedName{Name: svc.Namespace}, &ns); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	topologyKeys, ok := namespaceTopologyKeys(&ns, svc)
+	if !ok {
+		return nil
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = make(map[string]string)
+	}
+	svc.Annotations[servicetopology.AnnotationServiceTopologyKey] = topologyKeys
+	klog.V(4).Infof("apply service topology %s of namespace %s to service %s", topologyKeys, ns.Name, svc.Name)
+	return nil
+}
+
+// namespaceTopologyKeys returns the service topology of namespace if the service is selected by the policy.
+func namespaceTopologyKeys(ns *corev1.Namespace, svc *corev1.Service) (string, bool) {
+	topologyKeys := ns.Annotations[servicetopology.AnnotationServiceTopologyKey]
+	if len(topologyKeys) == 0 {
+		return "", false
+	}
+
+	if s, ok := ns.Annotations[AnnotationNamespaceServiceTopologySelector]; ok {
+		selector, err := labels.Parse(s)
+		if err != nil {
+			klog.Errorf("invalid service topology selector %q of namespace %s, %v", s, ns.Name, err)
+			return "", false
+		}
+		if !selector.Matches(labels.Set(svc.Labels)) {
+			return "", false
+		}
+	}
+	return topologyKeys, true
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/filter/servicetopology"
+)
+
+func TestNamespaceTopologyKeys(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "svc1",
+			Namespace: "ns1",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+	}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expectKeys  string
+		expectOk    bool
+	}{
+		{
+			name: "namespace without policy",
+		},
+		{
+			name:        "namespace level policy",
+			annotations: map[string]string{servicetopology.AnnotationServiceTopologyKey: servicetopology.AnnotationServiceTopologyValueNodePool},
+			expectKeys:  servicetopology.AnnotationServiceTopologyValueNodePool,
+			expectOk:    true,
+		},
+		{
+			name: "label level policy selects service",
+			annotations: map[string]string{
+				servicetopology.AnnotationServiceTopologyKey: servicetopology.AnnotationServiceTopologyValueNode,
+				AnnotationNamespaceServiceTopologySelector:   "app in (nginx, redis)",
+			},
+			expectKeys: servicetopology.AnnotationServiceTopologyValueNode,
+			expectOk:   true,
+		},
+		{
+			name: "label level policy does not select service",
+			annotations: map[string]string{
+				servicetopology.AnnotationServiceTopologyKey: servicetopology.AnnotationServiceTopologyValueNode,
+				AnnotationNamespaceServiceTopologySelector:   "app=redis",
+			},
+		},
+		{
+			name: "invalid selector",
+			annotations: map[string]string{
+				servicetopology.AnnotationServiceTopologyKey: servicetopology.AnnotationServiceTopologyValueNode,
+				AnnotationNamespaceServiceTopologySelector:   "app in nginx",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Annotations: tt.annotations}}
+			keys, ok := namespaceTopologyKeys(ns, svc)
+			if keys != tt.expectKeys || ok != tt.expectOk {
+				t.Errorf("expect (%q, %v), but got (%q, %v)", tt.expectKeys, tt.expectOk, keys, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/openyurtio/openyurt/pkg/webhook/util"
+)
+
+// SetupWebhookWithManager sets up Service webhooks. 	mutate path, validatepath, error
+func (webhook *ServiceHandler) SetupWebhookWithManager(mgr ctrl.Manager) (string, string, error) {
+	// init
+	webhook.Client = mgr.GetClient()
+
+	gvk, err := apiutil.GVKForObject(&corev1.Service{}, mgr.GetScheme())
+	if err != nil {
+		return "", "", err
+	}
+	return util.GenerateMutatePath(gvk),
+		util.GenerateValidatePath(gvk),
+		ctrl.NewWebhookManagedBy(mgr).
+			For(&corev1.Service{}).
+			WithDefaulter(webhook).
+			WithValidator(webhook).
+			Complete()
+}
+
+// failurePolicy is ignore, so creating services will not be blocked when yurt-manager is unavailable.
+// only create is mutated, so the service topology annotation removed by users is not added back on update.
+// +kubebuilder:webhook:path=/mutate--v1-service,mutating=true,failurePolicy=ignore,groups="",resources=services,verbs=create,versions=v1,name=m.v1.service.kb.io,sideEffects=None,admissionReviewVersions=v1
+
+// ServiceHandler implements a defaulting webhook for Service, which applies the service topology
+// policy of namespace to the services.
+type ServiceHandler struct {
+	Client client.Client
+}
+
+var _ webhook.CustomDefaulter = &ServiceHandler{}
+var _ webhook.CustomValidator = &ServiceHandler{}
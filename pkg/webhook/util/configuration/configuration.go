@@ -64,6 +64,9 @@ func Ensure(kubeClient clientset.Interface, handlers map[string]struct{}, caBund
 			klog.Warningf("Ignore webhook for %s in configuration", path)
 			continue
 		}
+		if selector := webhookutil.GetNamespaceSelector(path); selector != nil {
+			wh.NamespaceSelector = selector
+		}
 		if wh.ClientConfig.Service != nil {
 			wh.ClientConfig.Service.Namespace = webhookutil.GetNamespace()
 			wh.ClientConfig.Service.Name = webhookutil.GetServiceName()
@@ -89,6 +92,9 @@ func Ensure(kubeClient clientset.Interface, handlers map[string]struct{}, caBund
 			klog.Warningf("Ignore webhook for %s in configuration", path)
 			continue
 		}
+		if selector := webhookutil.GetNamespaceSelector(path); selector != nil {
+			wh.NamespaceSelector = selector
+		}
 		if wh.ClientConfig.Service != nil {
 			wh.ClientConfig.Service.Namespace = webhookutil.GetNamespace()
 			wh.ClientConfig.Service.Name = webhookutil.GetServiceName()
@@ -20,8 +20,12 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 )
 
@@ -84,3 +88,36 @@ func GenerateValidatePath(gvk schema.GroupVersionKind) string {
 	return "/validate-" + strings.ReplaceAll(gvk.Group, ".", "-") + "-" +
 		gvk.Version + "-" + strings.ToLower(gvk.Kind)
 }
+
+var (
+	namespaceSelectorsLock sync.RWMutex
+	// namespaceSelectors are keyed by webhook path, they are set into the webhook configurations
+	// by yurt-manager because namespace selector can't be specified by kubebuilder markers.
+	namespaceSelectors = map[string]*metav1.LabelSelector{}
+)
+
+// SetNamespaceSelector sets the namespace selector of the webhook for path.
+func SetNamespaceSelector(path string, selector *metav1.LabelSelector) {
+	namespaceSelectorsLock.Lock()
+	defer namespaceSelectorsLock.Unlock()
+	namespaceSelectors[path] = selector
+}
+
+// GetNamespaceSelector returns the namespace selector of the webhook for path, nil is returned if not set.
+func GetNamespaceSelector(path string) *metav1.LabelSelector {
+	namespaceSelectorsLock.RLock()
+	defer namespaceSelectorsLock.RUnlock()
+	return namespaceSelectors[path]
+}
+
+// NonSystemNamespaceSelector selects the namespaces except for the system namespaces, so the pods of
+// control plane and system components are not intercepted, and not affected when the webhook is unavailable.
+func NonSystemNamespaceSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      corev1.LabelMetadataName,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   sets.NewString(metav1.NamespaceSystem, metav1.NamespacePublic, corev1.NamespaceNodeLease, GetNamespace()).List(),
+		}},
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// CustomValidatorWithWarnings is a CustomValidator which also returns warnings for the admitted objects,
+// the warnings are shown to the clients, like kubectl, for the mistakes which are not rejected.
+type CustomValidatorWithWarnings interface {
+	admission.CustomValidator
+	// WarningsOnCreate returns the warnings for creating the valid object.
+	WarningsOnCreate(ctx context.Context, obj runtime.Object) []string
+}
+
+// NewValidatingWebhookWithWarnings returns a validating webhook for the type of obj, which is used
+// instead of the webhook built by WithValidator because CustomValidator can't return warnings.
+func NewValidatingWebhookWithWarnings(scheme *runtime.Scheme, obj runtime.Object, validator CustomValidatorWithWarnings) (*admission.Webhook, error) {
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return &admission.Webhook{
+		Handler: &validatorWithWarnings{obj: obj, validator: validator, decoder: decoder},
+	}, nil
+}
+
+type validatorWithWarnings struct {
+	obj       runtime.Object
+	validator CustomValidatorWithWarnings
+	decoder   *admission.Decoder
+}
+
+// Handle validates the object in the request, and adds warnings to the response if allowed.
+func (h *validatorWithWarnings) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := h.obj.DeepCopyObject()
+
+	var err error
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := h.decoder.DecodeRaw(req.Object, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// namespace of the object may be empty on creation, it is set from the request
+		if accessor, err := meta.Accessor(obj); err == nil && len(accessor.GetNamespace()) == 0 {
+			accessor.SetNamespace(req.Namespace)
+		}
+		if err = h.validator.ValidateCreate(ctx, obj); err == nil {
+			return admission.Allowed("").WithWarnings(h.validator.WarningsOnCreate(ctx, obj)...)
+		}
+	case admissionv1.Update:
+		oldObj := h.obj.DeepCopyObject()
+		if err := h.decoder.DecodeRaw(req.Object, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := h.decoder.DecodeRaw(req.OldObject, oldObj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = h.validator.ValidateUpdate(ctx, oldObj, obj)
+	case admissionv1.Delete:
+		// the object to delete is in OldObject
+		if err := h.decoder.DecodeRaw(req.OldObject, obj); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = h.validator.ValidateDelete(ctx, obj)
+	}
+
+	if err != nil {
+		var apiStatus apierrors.APIStatus
+		if errors.As(err, &apiStatus) {
+			status := apiStatus.Status()
+			return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+		}
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+var _ admission.Handler = &validatorWithWarnings{}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+)
+
+// NewDefaultJoinConfiguration returns a JoinConfiguration with default values,
+// the defaults are the same as the defaults of yurtadm join flags.
+func NewDefaultJoinConfiguration() *JoinConfiguration {
+	cfg := &JoinConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: SchemeGroupVersion,
+			Kind:       JoinConfigurationKind,
+		},
+	}
+	SetDefaultsJoinConfiguration(cfg)
+	return cfg
+}
+
+// SetDefaultsJoinConfiguration sets default values for the empty fields of JoinConfiguration.
+func SetDefaultsJoinConfiguration(cfg *JoinConfiguration) {
+	if len(cfg.NodeRegistration.NodeType) == 0 {
+		cfg.NodeRegistration.NodeType = constants.EdgeNode
+	}
+	if len(cfg.NodeRegistration.CRISocket) == 0 {
+		cfg.NodeRegistration.CRISocket = constants.DefaultDockerCRISocket
+	}
+	if len(cfg.PauseImage) == 0 {
+		cfg.PauseImage = constants.PauseImagePath
+	}
+	if len(cfg.YurtHub.Image) == 0 {
+		cfg.YurtHub.Image = fmt.Sprintf("%s/%s:%s", constants.DefaultOpenYurtImageRegistry, constants.Yurthub, constants.DefaultOpenYurtVersion)
+	}
+	if len(cfg.YurtHub.ServerAddr) == 0 {
+		cfg.YurtHub.ServerAddr = constants.DefaultYurtHubServerAddr
+	}
+	if len(cfg.Discovery.UnsealKey) == 0 {
+		cfg.Discovery.UnsealKey = constants.DefaultUnsealKeyPath
+	}
+	if len(cfg.KubernetesResourceServer) == 0 {
+		cfg.KubernetesResourceServer = constants.DefaultKubernetesResourceServer
+	}
+	if cfg.VerifyTimeout.Duration == 0 {
+		cfg.VerifyTimeout.Duration = constants.DefaultVerifyTimeout
+	}
+}
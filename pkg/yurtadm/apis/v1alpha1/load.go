@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LoadJoinConfiguration reads JoinConfiguration from the yaml or json file, unknown fields
+// are rejected in order to catch typos in config file.
+func LoadJoinConfiguration(path string) (*JoinConfiguration, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file %s, %w", path, err)
+	}
+	return DecodeJoinConfiguration(content)
+}
+
+// DecodeJoinConfiguration decodes JoinConfiguration and sets defaults for it.
+func DecodeJoinConfiguration(content []byte) (*JoinConfiguration, error) {
+	cfg := &JoinConfiguration{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, fmt.Errorf("could not decode JoinConfiguration, %w", err)
+	}
+	if cfg.APIVersion != SchemeGroupVersion {
+		return nil, fmt.Errorf("apiVersion %q is not supported, only %q is supported", cfg.APIVersion, SchemeGroupVersion)
+	}
+	if cfg.Kind != JoinConfigurationKind {
+		return nil, fmt.Errorf("kind %q is not supported, only %q is supported", cfg.Kind, JoinConfigurationKind)
+	}
+	SetDefaultsJoinConfiguration(cfg)
+	return cfg, nil
+}
+
+// MarshalJoinConfiguration returns JoinConfiguration in yaml format.
+func MarshalJoinConfiguration(cfg *JoinConfiguration) ([]byte, error) {
+	return yaml.Marshal(cfg)
+}
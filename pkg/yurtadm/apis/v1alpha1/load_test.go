@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+)
+
+func TestDecodeJoinConfiguration(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expectErr bool
+		check     func(cfg *JoinConfiguration) bool
+	}{
+		{
+			name: "valid config with defaults",
+			content: `apiVersion: yurtadm.openyurt.io/v1alpha1
+kind: JoinConfiguration
+apiServerEndpoint: 1.2.3.4:6443
+token: abcdef.0123456789abcdef
+nodeRegistration:
+  labels:
+    openyurt.io/site: hangzhou
+`,
+			check: func(cfg *JoinConfiguration) bool {
+				return cfg.APIServerEndpoint == "1.2.3.4:6443" &&
+					cfg.NodeRegistration.NodeType == constants.EdgeNode &&
+					cfg.YurtHub.ServerAddr == constants.DefaultYurtHubServerAddr &&
+					reflect.DeepEqual(cfg.NodeRegistration.Labels, map[string]string{"openyurt.io/site": "hangzhou"})
+			},
+		},
+		{
+			name: "unsupported version",
+			content: `apiVersion: yurtadm.openyurt.io/v1beta1
+kind: JoinConfiguration
+`,
+			expectErr: true,
+		},
+		{
+			name: "unknown field",
+			content: `apiVersion: yurtadm.openyurt.io/v1alpha1
+kind: JoinConfiguration
+tokens: abcdef.0123456789abcdef
+`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := DecodeJoinConfiguration([]byte(tt.content))
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expect error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to decode, %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config %#v", cfg)
+			}
+		})
+	}
+}
+
+func TestMarshalDefaultJoinConfiguration(t *testing.T) {
+	b, err := MarshalJoinConfiguration(NewDefaultJoinConfiguration())
+	if err != nil {
+		t.Fatalf("failed to marshal, %v", err)
+	}
+	cfg, err := DecodeJoinConfiguration(b)
+	if err != nil {
+		t.Fatalf("default config should be decoded, %v", err)
+	}
+	if !reflect.DeepEqual(cfg, NewDefaultJoinConfiguration()) {
+		t.Errorf("expect %#v, but got %#v", NewDefaultJoinConfiguration(), cfg)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// GroupName is the group name of yurtadm configuration
+	GroupName = "yurtadm.openyurt.io"
+	// Version is the version of yurtadm configuration
+	Version = "v1alpha1"
+	// JoinConfigurationKind is the kind of JoinConfiguration
+	JoinConfigurationKind = "JoinConfiguration"
+)
+
+// SchemeGroupVersion is group version used to identify yurtadm configuration
+var SchemeGroupVersion = GroupName + "/" + Version
+
+// JoinConfiguration contains all the settings of yurtadm join, each field corresponds
+// to a flag of yurtadm join with the same meaning.
+type JoinConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// APIServerEndpoint is the address of kube-apiserver, multiple addresses are separated by comma.
+	APIServerEndpoint string `json:"apiServerEndpoint"`
+	// Token is used for both discovery-token and tls-bootstrap-token.
+	Token string `json:"token"`
+	// Discovery contains the settings for validating the cluster info.
+	Discovery Discovery `json:"discovery"`
+	// NodeRegistration contains the settings for registering the node.
+	NodeRegistration NodeRegistration `json:"nodeRegistration"`
+	// YurtHub contains the settings of yurthub on the node.
+	YurtHub YurtHub `json:"yurthub"`
+	// PauseImage is the image of pause container.
+	PauseImage string `json:"pauseImage"`
+	// KubernetesResourceServer is the address for downloading k8s node resources.
+	KubernetesResourceServer string `json:"kubernetesResourceServer"`
+	// ReuseCNIBin specifies whether to reuse local CNI binaries or to download new ones.
+	ReuseCNIBin bool `json:"reuseCNIBin"`
+	// KubeletConfigPatch is the path to a KubeletConfiguration fragment merged into the kubelet config generated by kubeadm.
+	KubeletConfigPatch string `json:"kubeletConfigPatch"`
+	// IgnorePreflightErrors is a list of checks whose errors will be shown as warnings.
+	IgnorePreflightErrors []string `json:"ignorePreflightErrors,omitempty"`
+	// Provider contains the settings of provisioning provider.
+	Provider Provider `json:"provider"`
+	// Verify specifies whether to verify the node works after joining.
+	Verify bool `json:"verify"`
+	// VerifyTimeout is the timeout of each check in verify phase.
+	VerifyTimeout metav1.Duration `json:"verifyTimeout"`
+}
+
+// Discovery contains the settings for validating the cluster info.
+type Discovery struct {
+	// CACertHashes validates that the root CA public key matches one of the hashes (format: "<type>:<value>").
+	CACertHashes []string `json:"caCertHashes,omitempty"`
+	// UnsafeSkipCAVerification allows joining without CACertHashes pinning.
+	UnsafeSkipCAVerification bool `json:"unsafeSkipCAVerification"`
+	// SealedCredential is the path of single-use credential sealed to the site or device key, it is used instead of Token.
+	SealedCredential string `json:"sealedCredential"`
+	// UnsealKey is the path of site or device private key for unsealing SealedCredential.
+	UnsealKey string `json:"unsealKey"`
+}
+
+// NodeRegistration contains the settings for registering the node.
+type NodeRegistration struct {
+	// Name is the node name, hostname will be used if not specified.
+	Name string `json:"name"`
+	// NodeType is the type of node, edge or cloud.
+	NodeType string `json:"nodeType"`
+	// CRISocket is the path to the CRI socket.
+	CRISocket string `json:"criSocket"`
+	// Labels are the labels of node.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// YurtHub contains the settings of yurthub on the node.
+type YurtHub struct {
+	// Image is the image of yurthub.
+	Image string `json:"image"`
+	// ServerAddr is the address of yurthub server(not proxy server).
+	ServerAddr string `json:"serverAddr"`
+	// Organizations are the extra organizations of yurthub client certificate.
+	Organizations string `json:"organizations"`
+	// ImageGCHighThreshold is the percent of image fs usage after which yurthub collects images instead of kubelet,
+	// image gc of yurthub is disabled if it is 0.
+	ImageGCHighThreshold int `json:"imageGCHighThreshold"`
+	// ImageFsPath is the path which images of container runtime are stored in.
+	ImageFsPath string `json:"imageFsPath"`
+}
+
+// Provider contains the settings of provisioning provider.
+type Provider struct {
+	// Name is the name of provisioning provider.
+	Name string `json:"name"`
+	// Config is the config of provisioning provider.
+	Config string `json:"config"`
+}
@@ -0,0 +1,369 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+// metadataName is the first entry of archive, it records where the sources are restored to.
+const metadataName = "backup.json"
+
+// source is a file or directory of node state captured in the backup archive,
+// the entries of source are named with its name as prefix.
+type source struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type metadata struct {
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Hostname  string    `json:"hostname"`
+	Sources   []source  `json:"sources"`
+}
+
+// backupTo writes the sources into an archive encrypted to pub and signed by signKey, the archive is
+// written to a temporary file first, so a failed backup never leaves a broken archive at path.
+func backupTo(path string, sources []source, pub *rsa.PublicKey, signKey *rsa.PrivateKey) (*metadata, error) {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpPath)
+
+	meta, err := func() (*metadata, error) {
+		defer f.Close()
+		sw := newSignWriter(f)
+		ew, err := newEncryptWriter(sw, pub)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := writeArchive(ew, sources)
+		if err != nil {
+			return nil, err
+		}
+		if err := ew.Close(); err != nil {
+			return nil, err
+		}
+		if err := sw.Sign(signKey); err != nil {
+			return nil, err
+		}
+		return meta, f.Sync()
+	}()
+	if err != nil {
+		return nil, err
+	}
+	return meta, os.Rename(tmpPath, path)
+}
+
+// restoreFrom verifies the signature by verifyKey and the whole archive before anything is restored, so
+// a forged, corrupted or truncated archive never replaces the existing node state. the restored paths
+// should be empty unless force is set.
+func restoreFrom(path string, key *rsa.PrivateKey, verifyKey *rsa.PublicKey, force bool) (*metadata, error) {
+	// the archive is read by the same file for all passes, so it can't be replaced after verified
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	size, err := verifySignature(f, verifyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	read := func(extract bool) (*metadata, error) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		dr, err := newDecryptReader(io.LimitReader(f, size), key)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := readArchive(dr, extract)
+		if err != nil {
+			return nil, err
+		}
+		// read the rest of archive for authenticating the final chunk
+		if _, err := io.Copy(io.Discard, dr); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	}
+
+	meta, err := read(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range meta.Sources {
+		if err := prepareTarget(src, force); err != nil {
+			return nil, err
+		}
+	}
+	klog.Infof("[restore] backup archive created on %s at %s is verified", meta.Hostname, meta.CreatedAt.Format(time.RFC3339))
+	return read(true)
+}
+
+// prepareTarget removes the existing state at path of src if force is set.
+func prepareTarget(src source, force bool) error {
+	info, err := os.Lstat(src.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(src.Path)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+	}
+	if !force {
+		return fmt.Errorf("%s(%s) is not empty, use --force to replace it", src.Name, src.Path)
+	}
+	klog.Infof("[restore] replacing %s(%s)", src.Name, src.Path)
+	return os.RemoveAll(src.Path)
+}
+
+// writeArchive writes the sources into w as tar.gz, the sources which don't exist are skipped.
+func writeArchive(w io.Writer, sources []source) (*metadata, error) {
+	hostname, _ := os.Hostname()
+	meta := &metadata{
+		Version:   projectinfo.Get().GitVersion,
+		CreatedAt: time.Now().UTC(),
+		Hostname:  hostname,
+	}
+	for _, src := range sources {
+		if _, err := os.Lstat(src.Path); err != nil {
+			if os.IsNotExist(err) {
+				klog.Warningf("[backup] %s(%s) doesn't exist, skip it", src.Name, src.Path)
+				continue
+			}
+			return nil, err
+		}
+		meta.Sources = append(meta.Sources, src)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: metadataName, Mode: 0600, Size: int64(len(content)), ModTime: meta.CreatedAt}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return nil, err
+	}
+
+	for _, src := range meta.Sources {
+		klog.Infof("[backup] capturing %s from %s", src.Name, src.Path)
+		if err := addSource(tw, src); err != nil {
+			return nil, fmt.Errorf("could not capture %s, %w", src.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return meta, gw.Close()
+}
+
+func addSource(tw *tar.Writer, src source) error {
+	return filepath.Walk(src.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		link := ""
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular(), info.IsDir():
+		default:
+			klog.Warningf("[backup] %s is not a regular file, skip it", path)
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src.Path, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = src.Name
+		if rel != "." {
+			hdr.Name = src.Name + "/" + filepath.ToSlash(rel)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// readArchive reads the tar.gz archive from r. the entries are only verified if extract is false,
+// otherwise they are restored to the paths of their sources.
+func readArchive(r io.Reader, extract bool) (*metadata, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Name != metadataName {
+		return nil, fmt.Errorf("the first entry of backup archive should be %s, but got %s", metadataName, hdr.Name)
+	}
+	meta := &metadata{}
+	if err := json.NewDecoder(tr).Decode(meta); err != nil {
+		return nil, fmt.Errorf("could not decode %s, %w", metadataName, err)
+	}
+	sources := make(map[string]source, len(meta.Sources))
+	for _, src := range meta.Sources {
+		if !filepath.IsAbs(src.Path) {
+			return nil, fmt.Errorf("path %s of %s is not absolute", src.Path, src.Name)
+		}
+		sources[src.Name] = source{Name: src.Name, Path: filepath.Clean(src.Path)}
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return meta, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		name, rel, _ := strings.Cut(hdr.Name, "/")
+		src, ok := sources[name]
+		if !ok {
+			return nil, fmt.Errorf("entry %s doesn't belong to any source", hdr.Name)
+		}
+		target := filepath.Join(src.Path, filepath.FromSlash(rel))
+		if target != src.Path && !strings.HasPrefix(target, src.Path+string(os.PathSeparator)) {
+			return nil, fmt.Errorf("entry %s is out of %s", hdr.Name, src.Path)
+		}
+
+		if !extract {
+			if _, err := io.Copy(io.Discard, tr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := extractEntry(tr, hdr, src.Path, target); err != nil {
+			return nil, fmt.Errorf("could not restore %s, %w", target, err)
+		}
+	}
+}
+
+// extractEntry restores the entry at target under root. the entry is never written through a symlink,
+// because the symlinks restored by the previous entries may point out of root.
+func extractEntry(tr *tar.Reader, hdr *tar.Header, root, target string) error {
+	if err := checkNoSymlink(root, target); err != nil {
+		return err
+	}
+	mode := os.FileMode(hdr.Mode).Perm()
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, mode); err != nil {
+			return err
+		}
+		return os.Chmod(target, mode)
+	case tar.TypeSymlink:
+		return os.Symlink(hdr.Linkname, target)
+	case tar.TypeReg:
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, mode); err != nil {
+			return err
+		}
+		return os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	default:
+		return fmt.Errorf("type %c of entry %s is not supported", hdr.Typeflag, hdr.Name)
+	}
+}
+
+// checkNoSymlink checks that none of the existing paths from root(exclusive) to target(inclusive) is a symlink.
+func checkNoSymlink(root, target string) error {
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == "." {
+		return err
+	}
+	path := root
+	for _, elem := range strings.Split(rel, string(os.PathSeparator)) {
+		path = filepath.Join(path, elem)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink, entries can't be restored through it", path)
+		}
+	}
+	return nil
+}
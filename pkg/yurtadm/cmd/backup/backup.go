@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/sealedcredential"
+)
+
+// backupOptions defines all the options exposed via flags by yurtadm backup.
+type backupOptions struct {
+	outputPath    string
+	publicKey     string
+	signingKey    string
+	yurthubDir    string
+	diskCachePath string
+}
+
+// newBackupOptions returns a struct ready for being used for creating cmd backup flags.
+func newBackupOptions() *backupOptions {
+	return &backupOptions{
+		outputPath:    fmt.Sprintf("yurtadm-backup-%s.bak", time.Now().Format("20060102150405")),
+		yurthubDir:    constants.YurtHubWorkdir,
+		diskCachePath: constants.YurtHubCacheDir,
+	}
+}
+
+// NewCmdBackup returns "yurtadm backup" command.
+func NewCmdBackup(out io.Writer) *cobra.Command {
+	o := newBackupOptions()
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the OpenYurt state of the node into an encrypted archive",
+		Long: "Capture the yurthub cache, pki, pool-coordinator certificates, kubeconfig files, yurthub manifest, kubelet config and cluster ca of the node " +
+			"into an archive encrypted to the site or device public key and signed by the signing key. The archive can be restored on a replacement device " +
+			"by 'yurtadm restore' without connecting to the cloud. It's recommended to stop kubelet and yurthub before backup, " +
+			"so the captured cache is consistent.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			meta, err := o.run()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%d sources of node state are backed up into %s\n", len(meta.Sources), o.outputPath)
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+
+	addBackupFlags(cmd.Flags(), o)
+	return cmd
+}
+
+// addBackupFlags adds backup flags bound to the options to the specified flagset
+func addBackupFlags(flagSet *flag.FlagSet, o *backupOptions) {
+	flagSet.StringVarP(
+		&o.outputPath, constants.BackupOutput, "o", o.outputPath,
+		"The path of the generated backup archive, it's usually on an external media.",
+	)
+	flagSet.StringVar(
+		&o.publicKey, constants.SealPublicKey, o.publicKey,
+		"Path to the rsa public key(or certificate) of site or device which the backup archive is encrypted to.",
+	)
+	flagSet.StringVar(
+		&o.signingKey, constants.BackupSigningKey, o.signingKey,
+		"Path to the rsa private key which the backup archive is signed by, the archive is only restored with the matching public key.",
+	)
+	flagSet.StringVar(
+		&o.yurthubDir, constants.YurtHubRootDir, o.yurthubDir,
+		"The root directory of yurthub which contains pki and kubeconfig files.",
+	)
+	flagSet.StringVar(
+		&o.diskCachePath, constants.YurtHubDiskCachePath, o.diskCachePath,
+		"The path of yurthub disk cache.",
+	)
+}
+
+// sources returns all the node state captured by backup.
+func (o *backupOptions) sources() []source {
+	return []source{
+		{Name: "yurthub", Path: o.yurthubDir},
+		{Name: "cache", Path: o.diskCachePath},
+		{Name: "openyurt", Path: constants.OpenyurtDir},
+		{Name: "manifest", Path: filepath.Join(constants.StaticPodPath, constants.YurthubStaticPodFileName)},
+		{Name: "kubelet-conf", Path: constants.KubeConfigPath},
+		{Name: "kubelet-pki", Path: filepath.Join(constants.KubeletWorkdir, "pki")},
+		// kubelet can't start on a replacement device without the config written by kubeadm join
+		{Name: "kubelet-config", Path: filepath.Join(constants.KubeletWorkdir, constants.KubeletConfigFileName)},
+		{Name: "kubeadm-flags", Path: filepath.Join(constants.KubeletWorkdir, "kubeadm-flags.env")},
+		{Name: "kubelet-service-conf", Path: constants.KubeletServiceConfPath},
+		{Name: "ca", Path: filepath.Join(constants.DefaultCertificatesDir, "ca.crt")},
+	}
+}
+
+func (o *backupOptions) run() (*metadata, error) {
+	if len(o.publicKey) == 0 {
+		return nil, errors.New("public key is empty, so unable to encrypt backup archive")
+	}
+	content, err := os.ReadFile(o.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := sealedcredential.ParsePublicKey(content)
+	if err != nil {
+		return nil, err
+	}
+	// the public key is not a secret, so the archive is signed to prove who created it
+	if len(o.signingKey) == 0 {
+		return nil, errors.New("signing key is empty, so unable to sign backup archive")
+	}
+	content, err = os.ReadFile(o.signingKey)
+	if err != nil {
+		return nil, err
+	}
+	signKey, err := sealedcredential.ParsePrivateKey(content)
+	if err != nil {
+		return nil, err
+	}
+	return backupTo(o.outputPath, o.sources(), pub, signKey)
+}
+
+// restoreOptions defines all the options exposed via flags by yurtadm restore.
+type restoreOptions struct {
+	unsealKey string
+	verifyKey string
+	force     bool
+}
+
+// NewCmdRestore returns "yurtadm restore" command.
+func NewCmdRestore(out io.Writer) *cobra.Command {
+	o := &restoreOptions{
+		unsealKey: constants.DefaultUnsealKeyPath,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "restore [backup-archive]",
+		Short: "Restore the OpenYurt state of the node from an archive generated by 'yurtadm backup'",
+		Long: "Verify the signature of backup archive, decrypt it with the site or device private key, and restore the node state " +
+			"to the paths where it was captured. kubelet and yurthub should be stopped before restore, and they work " +
+			"with the restored state without connecting to the cloud when they are started.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			meta, err := o.run(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "node state backed up on %s at %s is restored\n", meta.Hostname, meta.CreatedAt.Format(time.RFC3339))
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+
+	addRestoreFlags(cmd.Flags(), o)
+	return cmd
+}
+
+// addRestoreFlags adds restore flags bound to the options to the specified flagset
+func addRestoreFlags(flagSet *flag.FlagSet, o *restoreOptions) {
+	flagSet.StringVar(
+		&o.unsealKey, constants.UnsealKey, o.unsealKey,
+		"Path to the rsa private key of site or device which the backup archive is encrypted to.",
+	)
+	flagSet.StringVar(
+		&o.verifyKey, constants.BackupVerifyKey, o.verifyKey,
+		"Path to the rsa public key(or certificate) which the signature of backup archive is verified by.",
+	)
+	flagSet.BoolVar(
+		&o.force, constants.RestoreForce, o.force,
+		"Replace the existing node state, restore fails if any restored path is not empty without this flag.",
+	)
+}
+
+func (o *restoreOptions) run(archivePath string) (*metadata, error) {
+	content, err := os.ReadFile(o.unsealKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := sealedcredential.ParsePrivateKey(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.verifyKey) == 0 {
+		return nil, errors.New("verify key is empty, so unable to verify backup archive")
+	}
+	content, err = os.ReadFile(o.verifyKey)
+	if err != nil {
+		return nil, err
+	}
+	verifyKey, err := sealedcredential.ParsePublicKey(content)
+	if err != nil {
+		return nil, err
+	}
+	return restoreFrom(archivePath, key, verifyKey, o.force)
+}
@@ -0,0 +1,342 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSources(t *testing.T, root string) []source {
+	hubDir := filepath.Join(root, "yurthub")
+	cacheDir := filepath.Join(root, "cache")
+	manifest := filepath.Join(root, "manifests", "yurthub.yaml")
+	files := map[string][]byte{
+		filepath.Join(hubDir, "pki", "yurthub-current.pem"):            []byte("cert"),
+		filepath.Join(hubDir, "poolcoordinator", "coordinator-ca.crt"): []byte("ca"),
+		filepath.Join(cacheDir, "kubelet", "pods", "default", "foo"):   bytes.Repeat([]byte("pod"), chunkSize),
+		manifest: []byte("kind: Pod"),
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir, %v", err)
+		}
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatalf("failed to write file, %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(hubDir, "pki", "yurthub-current.pem"), filepath.Join(hubDir, "pki", "yurthub.pem")); err != nil {
+		t.Fatalf("failed to create symlink, %v", err)
+	}
+
+	return []source{
+		{Name: "yurthub", Path: hubDir},
+		{Name: "cache", Path: cacheDir},
+		{Name: "manifest", Path: manifest},
+		{Name: "missing", Path: filepath.Join(root, "missing")},
+	}
+}
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+	return key
+}
+
+func snapshot(t *testing.T, sources []source) map[string]string {
+	state := make(map[string]string)
+	for _, src := range sources {
+		filepath.Walk(src.Path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				link, _ := os.Readlink(path)
+				state[path] = "link:" + link
+			case info.Mode().IsRegular():
+				content, _ := os.ReadFile(path)
+				state[path] = info.Mode().String() + ":" + string(content)
+			default:
+				state[path] = info.Mode().String()
+			}
+			return nil
+		})
+	}
+	return state
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	root := t.TempDir()
+	sources := newTestSources(t, root)
+	key, signKey := newTestKey(t), newTestKey(t)
+	archive := filepath.Join(t.TempDir(), "node.bak")
+
+	want := snapshot(t, sources)
+	meta, err := backupTo(archive, sources, &key.PublicKey, signKey)
+	if err != nil {
+		t.Fatalf("failed to backup, %v", err)
+	}
+	if len(meta.Sources) != 3 {
+		t.Errorf("expect 3 sources backed up, but got %v", meta.Sources)
+	}
+	content, _ := os.ReadFile(archive)
+	if bytes.Contains(content, []byte("yurthub-current.pem")) {
+		t.Errorf("backup archive is not encrypted")
+	}
+
+	if _, err := restoreFrom(archive, key, &signKey.PublicKey, false); err == nil {
+		t.Errorf("expect restore fails when node state exists")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "yurthub", "stale"), []byte("stale"), 0600); err != nil {
+		t.Fatalf("failed to write file, %v", err)
+	}
+	if _, err := restoreFrom(archive, key, &signKey.PublicKey, true); err != nil {
+		t.Fatalf("failed to restore with force, %v", err)
+	}
+	if got := snapshot(t, sources); !equalState(got, want) {
+		t.Errorf("expect restored state %v, but got %v", want, got)
+	}
+
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatalf("failed to remove node state, %v", err)
+	}
+	if _, err := restoreFrom(archive, key, &signKey.PublicKey, false); err != nil {
+		t.Fatalf("failed to restore on replacement device, %v", err)
+	}
+	if got := snapshot(t, sources); !equalState(got, want) {
+		t.Errorf("expect restored state %v, but got %v", want, got)
+	}
+}
+
+func equalState(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRestoreInvalidArchive(t *testing.T) {
+	root := t.TempDir()
+	sources := newTestSources(t, root)
+	key, signKey := newTestKey(t), newTestKey(t)
+	archive := filepath.Join(t.TempDir(), "node.bak")
+	if _, err := backupTo(archive, sources, &key.PublicKey, signKey); err != nil {
+		t.Fatalf("failed to backup, %v", err)
+	}
+	content, _ := os.ReadFile(archive)
+
+	tampered := append([]byte{}, content...)
+	tampered[len(tampered)/2] ^= 0xff
+	// an archive encrypted to the same public key by others
+	forged := filepath.Join(t.TempDir(), "forged.bak")
+	if _, err := backupTo(forged, sources, &key.PublicKey, newTestKey(t)); err != nil {
+		t.Fatalf("failed to backup, %v", err)
+	}
+	forgedContent, _ := os.ReadFile(forged)
+	tests := map[string]struct {
+		content   []byte
+		key       *rsa.PrivateKey
+		verifyKey *rsa.PublicKey
+		err       error
+	}{
+		"wrong key": {
+			content:   content,
+			key:       newTestKey(t),
+			verifyKey: &signKey.PublicKey,
+		},
+		"truncated archive": {
+			content:   content[:len(content)-10],
+			key:       key,
+			verifyKey: &signKey.PublicKey,
+		},
+		"tampered archive": {
+			content:   tampered,
+			key:       key,
+			verifyKey: &signKey.PublicKey,
+		},
+		"forged archive": {
+			content:   forgedContent,
+			key:       key,
+			verifyKey: &signKey.PublicKey,
+		},
+		"unsigned archive": {
+			content:   content[:len(content)-4-signKey.Size()],
+			key:       key,
+			verifyKey: &signKey.PublicKey,
+		},
+	}
+
+	want := snapshot(t, sources)
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "node.bak")
+			if err := os.WriteFile(path, tt.content, 0600); err != nil {
+				t.Fatalf("failed to write archive, %v", err)
+			}
+			_, err := restoreFrom(path, tt.key, tt.verifyKey, true)
+			if err == nil {
+				t.Fatalf("expect restore fails")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("expect error %v, but got %v", tt.err, err)
+			}
+			if got := snapshot(t, sources); !equalState(got, want) {
+				t.Errorf("node state is changed by invalid archive")
+			}
+		})
+	}
+}
+
+// writeTestArchive writes an archive with the entries of sources, it's signed by signKey.
+func writeTestArchive(t *testing.T, path string, key, signKey *rsa.PrivateKey, sources []source, entries func(tw *tar.Writer)) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive, %v", err)
+	}
+	defer f.Close()
+	sw := newSignWriter(f)
+	ew, err := newEncryptWriter(sw, &key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create encrypt writer, %v", err)
+	}
+	gw := gzip.NewWriter(ew)
+	tw := tar.NewWriter(gw)
+	meta, _ := json.Marshal(&metadata{Sources: sources})
+	tw.WriteHeader(&tar.Header{Name: metadataName, Mode: 0600, Size: int64(len(meta)), Typeflag: tar.TypeReg})
+	tw.Write(meta)
+	entries(tw)
+	tw.Close()
+	gw.Close()
+	ew.Close()
+	if err := sw.Sign(signKey); err != nil {
+		t.Fatalf("failed to sign archive, %v", err)
+	}
+}
+
+func TestRestoreEntryOutOfSource(t *testing.T) {
+	evil := []byte("evil")
+	tests := map[string]struct {
+		entries func(tw *tar.Writer, outside string)
+		err     string
+	}{
+		"entry with parent path": {
+			entries: func(tw *tar.Writer, _ string) {
+				tw.WriteHeader(&tar.Header{Name: "yurthub/../evil", Mode: 0600, Size: int64(len(evil)), Typeflag: tar.TypeReg})
+				tw.Write(evil)
+			},
+			err: "out of",
+		},
+		"entry through restored symlink dir": {
+			entries: func(tw *tar.Writer, outside string) {
+				tw.WriteHeader(&tar.Header{Name: "yurthub/pki", Linkname: outside, Typeflag: tar.TypeSymlink})
+				tw.WriteHeader(&tar.Header{Name: "yurthub/pki/evil", Mode: 0600, Size: int64(len(evil)), Typeflag: tar.TypeReg})
+				tw.Write(evil)
+			},
+			err: "symlink",
+		},
+		"entry overwriting restored symlink": {
+			entries: func(tw *tar.Writer, outside string) {
+				tw.WriteHeader(&tar.Header{Name: "yurthub/evil", Linkname: filepath.Join(outside, "evil"), Typeflag: tar.TypeSymlink})
+				tw.WriteHeader(&tar.Header{Name: "yurthub/evil", Mode: 0600, Size: int64(len(evil)), Typeflag: tar.TypeReg})
+				tw.Write(evil)
+			},
+			err: "symlink",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			outside := filepath.Join(root, "outside")
+			if err := os.MkdirAll(outside, 0755); err != nil {
+				t.Fatalf("failed to create dir, %v", err)
+			}
+			key, signKey := newTestKey(t), newTestKey(t)
+			archive := filepath.Join(t.TempDir(), "node.bak")
+			sources := []source{{Name: "yurthub", Path: filepath.Join(root, "yurthub")}}
+			writeTestArchive(t, archive, key, signKey, sources, func(tw *tar.Writer) { tt.entries(tw, outside) })
+
+			_, err := restoreFrom(archive, key, &signKey.PublicKey, false)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expect entry out of source is rejected, but got %v", err)
+			}
+			for _, path := range []string{filepath.Join(root, "evil"), filepath.Join(outside, "evil")} {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("entry out of source should not be restored to %s", path)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptChunks(t *testing.T) {
+	key := newTestKey(t)
+	plaintext := make([]byte, 2*chunkSize+100)
+	rand.Read(plaintext)
+
+	var buf bytes.Buffer
+	ew, err := newEncryptWriter(&buf, &key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create encrypt writer, %v", err)
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("failed to write, %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("failed to close, %v", err)
+	}
+	content := buf.Bytes()
+
+	dr, err := newDecryptReader(bytes.NewReader(content), key)
+	if err != nil {
+		t.Fatalf("failed to create decrypt reader, %v", err)
+	}
+	got, err := io.ReadAll(dr)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("expect plaintext is decrypted, but got %d bytes, %v", len(got), err)
+	}
+
+	// drop the final chunk
+	chunk := 5 + chunkSize + 16
+	header := len(archiveMagic) + 4 + key.Size()
+	dr, err = newDecryptReader(bytes.NewReader(content[:header+2*chunk]), key)
+	if err != nil {
+		t.Fatalf("failed to create decrypt reader, %v", err)
+	}
+	if _, err := io.ReadAll(dr); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expect error %v, but got %v", ErrTruncated, err)
+	}
+}
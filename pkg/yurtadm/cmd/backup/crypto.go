@@ -0,0 +1,302 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	// archiveMagic is written at the beginning of every encrypted archive.
+	archiveMagic = "YURTBAK2"
+	// signatureContext is hashed before the archive, so the signature can't be used for other content
+	// signed by the same key.
+	signatureContext = "openyurt-backup-signature-v2\x00"
+	// keyLabel is the label of rsa-oaep for wrapping the data key.
+	keyLabel = "openyurt-backup-v1"
+	// chunkSize is the max size of plaintext sealed in one chunk.
+	chunkSize = 64 * 1024
+
+	chunkFinal byte = 1
+)
+
+// ErrTruncated is returned when the archive ends before its final chunk.
+var ErrTruncated = errors.New("backup archive is truncated")
+
+// encryptWriter seals the written data in chunks by aes-256-gcm with a random data key,
+// the data key is wrapped by the rsa public key and written in the header of archive.
+// every chunk is authenticated with its sequence number and whether it is the final chunk,
+// so reordered, dropped or truncated chunks are detected when the archive is decrypted.
+type encryptWriter struct {
+	w     io.Writer
+	gcm   cipher.AEAD
+	seq   uint64
+	buf   []byte
+	close bool
+}
+
+func newEncryptWriter(w io.Writer, pub *rsa.PublicKey) (*encryptWriter, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, []byte(keyLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(archiveMagic)+4, len(archiveMagic)+4+len(encryptedKey))
+	copy(header, archiveMagic)
+	binary.BigEndian.PutUint32(header[len(archiveMagic):], uint32(len(encryptedKey)))
+	header = append(header, encryptedKey...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, gcm: gcm, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.close {
+		return 0, errors.New("write to closed backup archive")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		if len(e.buf) == chunkSize {
+			if err := e.seal(0); err != nil {
+				return n - len(p), err
+			}
+		}
+		m := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+m]
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// Close seals the buffered data as the final chunk, it doesn't close the underlying writer.
+func (e *encryptWriter) Close() error {
+	if e.close {
+		return nil
+	}
+	e.close = true
+	return e.seal(chunkFinal)
+}
+
+func (e *encryptWriter) seal(flag byte) error {
+	ciphertext := e.gcm.Seal(nil, e.nonce(), e.buf, []byte{flag})
+	chunkHeader := make([]byte, 5)
+	chunkHeader[0] = flag
+	binary.BigEndian.PutUint32(chunkHeader[1:], uint32(len(ciphertext)))
+	if _, err := e.w.Write(chunkHeader); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(ciphertext); err != nil {
+		return err
+	}
+	e.seq++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// nonce is the sequence number of chunk, it is never reused because the data key is
+// generated for every archive.
+func (e *encryptWriter) nonce() []byte {
+	nonce := make([]byte, e.gcm.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], e.seq)
+	return nonce
+}
+
+// decryptReader opens the chunks written by encryptWriter.
+type decryptReader struct {
+	r     *bufio.Reader
+	gcm   cipher.AEAD
+	seq   uint64
+	buf   []byte
+	final bool
+}
+
+func newDecryptReader(r io.Reader, key *rsa.PrivateKey) (*decryptReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(archiveMagic)+4)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("could not read header of backup archive, %w", err)
+	}
+	if string(header[:len(archiveMagic)]) != archiveMagic {
+		return nil, errors.New("file is not a backup archive or its version is not supported")
+	}
+	keyLen := binary.BigEndian.Uint32(header[len(archiveMagic):])
+	if int(keyLen) != key.Size() {
+		return nil, errors.New("backup archive is not encrypted to this key")
+	}
+	encryptedKey := make([]byte, keyLen)
+	if _, err := io.ReadFull(br, encryptedKey); err != nil {
+		return nil, fmt.Errorf("could not read data key of backup archive, %w", err)
+	}
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, encryptedKey, []byte(keyLabel))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data key, the backup archive is not encrypted to this key, %w", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: br, gcm: gcm}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	chunkHeader := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, chunkHeader); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	flag := chunkHeader[0]
+	size := binary.BigEndian.Uint32(chunkHeader[1:])
+	if flag&^chunkFinal != 0 || int(size) > chunkSize+d.gcm.Overhead() {
+		return errors.New("backup archive is corrupted")
+	}
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(d.r, ciphertext); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+
+	nonce := make([]byte, d.gcm.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], d.seq)
+	plaintext, err := d.gcm.Open(nil, nonce, ciphertext, []byte{flag})
+	if err != nil {
+		return fmt.Errorf("could not decrypt backup archive, it is corrupted or tampered, %w", err)
+	}
+	d.seq++
+	d.buf = plaintext
+	if flag == chunkFinal {
+		d.final = true
+		if _, err := d.r.Peek(1); err != io.EOF {
+			return errors.New("backup archive has unexpected data after the final chunk")
+		}
+	}
+	return nil
+}
+
+// signWriter hashes the encrypted archive written through it, and appends the rsa-pss signature of
+// the archive and the length of signature as the trailer. the archive is encrypted to the public key
+// of site or device, which is not a secret, so the signature proves the archive is created by the
+// holder of signing key rather than anyone holding the public key.
+type signWriter struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func newSignWriter(w io.Writer) *signWriter {
+	h := sha256.New()
+	h.Write([]byte(signatureContext))
+	return &signWriter{w: w, h: h}
+}
+
+func (s *signWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.h.Write(p[:n])
+	return n, err
+}
+
+// Sign writes the trailer, nothing should be written after it.
+func (s *signWriter) Sign(key *rsa.PrivateKey) error {
+	sig, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, s.h.Sum(nil), nil)
+	if err != nil {
+		return err
+	}
+	trailer := make([]byte, len(sig)+4)
+	copy(trailer, sig)
+	binary.BigEndian.PutUint32(trailer[len(sig):], uint32(len(sig)))
+	_, err = s.w.Write(trailer)
+	return err
+}
+
+// verifySignature verifies the trailer of archive in f by pub, and returns the size of signed archive.
+func verifySignature(f io.ReadSeeker, pub *rsa.PublicKey) (int64, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	size := end - 4 - int64(pub.Size())
+	if size < 0 {
+		return 0, errors.New("backup archive is not signed")
+	}
+	trailer := make([]byte, pub.Size()+4)
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if _, err := io.ReadFull(f, trailer); err != nil {
+		return 0, err
+	}
+	if int(binary.BigEndian.Uint32(trailer[pub.Size():])) != pub.Size() {
+		return 0, errors.New("backup archive is not signed by this key")
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	h := sha256.New()
+	h.Write([]byte(signatureContext))
+	if _, err := io.Copy(h, io.LimitReader(f, size)); err != nil {
+		return 0, err
+	}
+	if err := rsa.VerifyPSS(pub, crypto.SHA256, h.Sum(nil), trailer[:pub.Size()], nil); err != nil {
+		return 0, fmt.Errorf("signature of backup archive is not verified, it's forged or tampered, %w", err)
+	}
+	return size, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -26,9 +26,14 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/backup"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/config"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/credential"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/diagnose"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/docs"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/reset"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/sign"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/token"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/yurtinit"
 )
@@ -49,6 +54,12 @@ func NewYurtadmCommand() *cobra.Command {
 	cmds.AddCommand(join.NewCmdJoin(os.Stdin, os.Stdout, os.Stderr))
 	cmds.AddCommand(reset.NewCmdReset(os.Stdin, os.Stdout, os.Stderr))
 	cmds.AddCommand(token.NewCmdToken(os.Stdin, os.Stdout, os.Stderr))
+	cmds.AddCommand(config.NewCmdConfig(os.Stdout))
+	cmds.AddCommand(credential.NewCmdCredential(os.Stdout))
+	cmds.AddCommand(diagnose.NewCmdDiagnose(os.Stdout))
+	cmds.AddCommand(backup.NewCmdBackup(os.Stdout))
+	cmds.AddCommand(backup.NewCmdRestore(os.Stdout))
+	cmds.AddCommand(sign.NewCmdSign(os.Stdout))
 	cmds.AddCommand(docs.NewDocsCmd(cmds))
 
 	klog.InitFlags(nil)
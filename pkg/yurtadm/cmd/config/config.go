@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/apis/v1alpha1"
+)
+
+// NewCmdConfig returns "yurtadm config" command.
+func NewCmdConfig(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage configuration files of yurtadm",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newCmdConfigPrintDefault(out))
+	return cmd
+}
+
+// newCmdConfigPrintDefault returns "yurtadm config print-default" command.
+func newCmdConfigPrintDefault(out io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:     "print-default",
+		Aliases: []string{"print-defaults"},
+		Short:   "Print default JoinConfiguration that can be used for 'yurtadm join --config'",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := v1alpha1.MarshalJoinConfiguration(v1alpha1.NewDefaultJoinConfiguration())
+			if err != nil {
+				return err
+			}
+			_, err = out.Write(b)
+			return err
+		},
+	}
+}
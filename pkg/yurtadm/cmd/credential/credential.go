@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	"k8s.io/klog/v2"
+
+	yurtconstants "github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/provider"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/sealedcredential"
+)
+
+type sealOptions struct {
+	publicKey    string
+	token        string
+	caCertHashes []string
+	nodeName     string
+	nodeType     string
+	ttl          time.Duration
+	output       string
+}
+
+// NewCmdCredential returns "yurtadm credential" command.
+func NewCmdCredential(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "credential",
+		Short: "Manage sealed credentials for joining nodes",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newCmdCredentialSeal(out))
+	return cmd
+}
+
+// newCmdCredentialSeal returns "yurtadm credential seal" command.
+func newCmdCredentialSeal(out io.Writer) *cobra.Command {
+	o := &sealOptions{
+		caCertHashes: make([]string, 0),
+		ttl:          yurtconstants.DefaultSealedCredentialTTL,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "seal [api-server-endpoint]",
+		Short: "Seal the join token to a site or device public key, the sealed credential can be used for 'yurtadm join --sealed-credential' only once",
+		Long: "Seal a dedicated bootstrap token to a site or device public key. The bootstrap token is created in the cluster " +
+			"and expires together with the sealed credential, it is claimed by the node which joins with the credential " +
+			"and removed after the node is joined, so the credential can be used only once.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeconfig, err := cmd.Flags().GetString("kubeconfig")
+			if err != nil {
+				return err
+			}
+			return o.run(args[0], kubeconfig, out)
+		},
+	}
+
+	addSealFlags(cmd.Flags(), o)
+	return cmd
+}
+
+func addSealFlags(flagSet *flag.FlagSet, o *sealOptions) {
+	flagSet.StringVar(
+		&o.publicKey, yurtconstants.SealPublicKey, o.publicKey,
+		"Path to the rsa public key(or certificate) of site or device which the credential is sealed to.",
+	)
+	flagSet.StringVar(
+		&o.token, yurtconstants.TokenStr, o.token,
+		"Bootstrap token sealed in the credential, it is generated if not specified. The token should not exist in the cluster, it is created with the ttl of the credential.",
+	)
+	flagSet.StringSliceVar(
+		&o.caCertHashes, yurtconstants.TokenDiscoveryCAHash, o.caCertHashes,
+		"For token-based discovery, validate that the root CA public key matches this hash (format: \"<type>:<value>\").",
+	)
+	flagSet.StringVar(
+		&o.nodeName, yurtconstants.NodeName, o.nodeName,
+		"Specify the node name that the credential is used for.",
+	)
+	flagSet.StringVar(
+		&o.nodeType, yurtconstants.NodeType, o.nodeType,
+		"Specify the node type that the credential is used for. edge or cloud.",
+	)
+	flagSet.DurationVar(
+		&o.ttl, yurtconstants.SealTTL, o.ttl,
+		"The duration before the sealed credential is expired.",
+	)
+	flagSet.StringVarP(
+		&o.output, yurtconstants.DiagnoseOutput, "o", o.output,
+		"Path of the sealed credential, it is written to stdout if not specified.",
+	)
+}
+
+func (o *sealOptions) run(serverAddr, kubeconfig string, out io.Writer) error {
+	if len(o.publicKey) == 0 {
+		return errors.New("public key is empty, so unable to seal credential")
+	}
+	if o.ttl <= 0 {
+		return fmt.Errorf("ttl(%s) should be positive", o.ttl)
+	}
+	if len(o.token) == 0 {
+		token, err := bootstraputil.GenerateBootstrapToken()
+		if err != nil {
+			return fmt.Errorf("could not generate bootstrap token, %w", err)
+		}
+		o.token = token
+	}
+	params := provider.JoinParameters{
+		ServerAddr:   serverAddr,
+		Token:        o.token,
+		CaCertHashes: o.caCertHashes,
+		NodeName:     o.nodeName,
+		NodeType:     o.nodeType,
+	}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	pub, err := os.ReadFile(o.publicKey)
+	if err != nil {
+		return err
+	}
+	cred := sealedcredential.NewCredential(params, o.ttl)
+	sealed, err := sealedcredential.Seal(cred, pub)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClientSet(kubeconfig)
+	if err != nil {
+		return err
+	}
+	if err := sealedcredential.CreateToken(client, cred); err != nil {
+		return fmt.Errorf("could not create bootstrap token for sealed credential, %w", err)
+	}
+
+	if len(o.output) == 0 {
+		_, err = fmt.Fprintln(out, string(sealed))
+		return err
+	}
+	if err := os.WriteFile(o.output, sealed, 0600); err != nil {
+		return err
+	}
+	klog.Infof("credential %s is sealed into %s, it expires at %s", cred.ID, o.output, cred.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// newClientSet returns the client for kubeconfig, the default loading rules are used if kubeconfig is not specified.
+func newClientSet(kubeconfig string) (kubernetes.Interface, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load kubeconfig, %w", err)
+	}
+	return kubernetes.NewForConfig(cfg)
+}
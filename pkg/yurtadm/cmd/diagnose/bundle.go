@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"regexp"
+	"time"
+)
+
+const redacted = "<redacted>"
+
+var (
+	// privateKeyBlock matches pem blocks like RSA PRIVATE KEY, EC PRIVATE KEY and PRIVATE KEY
+	privateKeyBlock = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+	// bootstrapToken matches the bootstrap token format: [a-z0-9]{6}.[a-z0-9]{16}
+	bootstrapToken = regexp.MustCompile(`\b[a-z0-9]{6}\.[a-z0-9]{16}\b`)
+	// bearerToken matches the Authorization header with bearer token
+	bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-_=.]+`)
+	// sensitiveField matches the fields like token: xxx, --join-token=xxx and client-key-data: xxx
+	sensitiveField = regexp.MustCompile(`(?i)((?:token|client-key-data|password|passwd)["']?\s*[:=]\s*["']?)[^\s"',]+`)
+)
+
+// sanitize removes credentials from the collected content.
+func sanitize(content []byte) []byte {
+	content = privateKeyBlock.ReplaceAll(content, []byte(redacted))
+	content = bearerToken.ReplaceAll(content, []byte("${1}"+redacted))
+	content = sensitiveField.ReplaceAll(content, []byte("${1}"+redacted))
+	content = bootstrapToken.ReplaceAll(content, []byte(redacted))
+	return content
+}
+
+// bundle is a tar.gz archive that holds the collected files.
+type bundle struct {
+	file *os.File
+	gw   *gzip.Writer
+	tw   *tar.Writer
+}
+
+func newBundle(path string) (*bundle, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	gw := gzip.NewWriter(f)
+	return &bundle{
+		file: f,
+		gw:   gw,
+		tw:   tar.NewWriter(gw),
+	}, nil
+}
+
+func (b *bundle) add(name string, content []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(content)
+	return err
+}
+
+func (b *bundle) close() error {
+	if err := b.tw.Close(); err != nil {
+		b.file.Close()
+		return err
+	}
+	if err := b.gw.Close(); err != nil {
+		b.file.Close()
+		return err
+	}
+	return b.file.Close()
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/initsystem"
+)
+
+// collector gathers one kind of diagnostic information, the returned map
+// is keyed by the file name inside the collector's directory of the bundle.
+type collector interface {
+	Name() string
+	Collect() (map[string][]byte, error)
+}
+
+// runCommand executes the command with timeout and returns the combined output,
+// a missing binary or a failed command is reported in the output instead of an error
+// so that the bundle still shows what was attempted.
+func runCommand(timeout time.Duration, name string, args ...string) []byte {
+	if _, err := exec.LookPath(name); err != nil {
+		return []byte(fmt.Sprintf("command %s is not found, %v\n", name, err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		out = append(out, []byte(fmt.Sprintf("\ncommand %s %s failed, %v\n", name, strings.Join(args, " "), err))...)
+	}
+	return out
+}
+
+// tailLines returns the last n lines of content.
+func tailLines(content []byte, n int) []byte {
+	if n <= 0 {
+		return content
+	}
+	lines := bytes.Split(bytes.TrimRight(content, "\n"), []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
+
+// yurthubLogCollector collects logs of yurthub static pod from kubelet pod log dir.
+type yurthubLogCollector struct {
+	lines   int
+	timeout time.Duration
+}
+
+func (c *yurthubLogCollector) Name() string {
+	return "yurthub-logs"
+}
+
+func (c *yurthubLogCollector) Collect() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	logFiles, err := filepath.Glob(filepath.Join(constants.YurthubPodLogDirPattern, "*", "*.log"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range logFiles {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			files[filepath.Base(f)+".error"] = []byte(err.Error())
+			continue
+		}
+		// keep pod uid and container restart count in the name to distinguish log files
+		name := strings.ReplaceAll(strings.TrimPrefix(f, "/var/log/pods/"), "/", "_")
+		files[name] = tailLines(content, c.lines)
+	}
+
+	if len(files) == 0 {
+		files["crictl-ps.txt"] = runCommand(c.timeout, "crictl", "ps", "-a", "--name", "yurt-hub")
+	}
+	return files, nil
+}
+
+// cacheStatsCollector collects statistics of yurthub disk cache, only the layout
+// and sizes are collected, cached objects are never copied into the bundle.
+type cacheStatsCollector struct {
+	cachePath string
+}
+
+func (c *cacheStatsCollector) Name() string {
+	return "yurthub-cache"
+}
+
+func (c *cacheStatsCollector) Collect() (map[string][]byte, error) {
+	// the layout of disk cache is {component}/{resource.version.group}/{namespace}/{name}
+	type stat struct {
+		objects int
+		bytes   int64
+	}
+	stats := make(map[string]*stat)
+	err := filepath.Walk(c.cachePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(c.cachePath, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(rel, string(filepath.Separator), 3)
+		key := parts[0]
+		if len(parts) > 1 {
+			key = filepath.Join(parts[0], parts[1])
+		}
+		if _, ok := stats[key]; !ok {
+			stats[key] = &stat{}
+		}
+		stats[key].objects++
+		stats[key].bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "cache path: %s\n", c.cachePath)
+	fmt.Fprintf(&buf, "%-80s %10s %14s\n", "COMPONENT/RESOURCE", "OBJECTS", "BYTES")
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%-80s %10d %14d\n", k, stats[k].objects, stats[k].bytes)
+	}
+	return map[string][]byte{"stats.txt": buf.Bytes()}, nil
+}
+
+// yurthubEndpointCollector collects outputs of yurthub healthz and metrics endpoints.
+type yurthubEndpointCollector struct {
+	server  string
+	timeout time.Duration
+}
+
+func (c *yurthubEndpointCollector) Name() string {
+	return "yurthub-endpoints"
+}
+
+func (c *yurthubEndpointCollector) Collect() (map[string][]byte, error) {
+	client := &http.Client{Timeout: c.timeout}
+	files := make(map[string][]byte)
+	for name, path := range map[string]string{
+		"healthz.txt": constants.ServerHealthzURLPath,
+		"metrics.txt": "/metrics",
+	} {
+		url := fmt.Sprintf("http://%s:%d%s", c.server, constants.YurtHubServerPort, path)
+		resp, err := client.Get(url)
+		if err != nil {
+			files[name] = []byte(fmt.Sprintf("GET %s failed, %v\n", url, err))
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			files[name] = []byte(fmt.Sprintf("read response of %s failed, %v\n", url, err))
+			continue
+		}
+		files[name] = append([]byte(fmt.Sprintf("GET %s: %s\n", url, resp.Status)), body...)
+	}
+	return files, nil
+}
+
+// kubeletCollector collects kubelet service status, logs and configuration.
+type kubeletCollector struct {
+	lines   int
+	timeout time.Duration
+}
+
+func (c *kubeletCollector) Name() string {
+	return "kubelet"
+}
+
+func (c *kubeletCollector) Collect() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	initSystem, err := initsystem.GetInitSystem()
+	if err != nil {
+		files["status.txt"] = []byte(err.Error())
+	} else {
+		files["status.txt"] = []byte(fmt.Sprintf("kubelet active: %v\n", initSystem.ServiceIsActive("kubelet")))
+	}
+
+	files["systemctl-status.txt"] = runCommand(c.timeout, "systemctl", "status", "kubelet", "--no-pager")
+	files["journal.log"] = runCommand(c.timeout, "journalctl", "-u", "kubelet", "--no-pager", "-n", strconv.Itoa(c.lines))
+
+	for name, path := range map[string]string{
+		"config.yaml":       filepath.Join(constants.KubeletWorkdir, "config.yaml"),
+		"kubeadm-flags.env": filepath.Join(constants.KubeletWorkdir, "kubeadm-flags.env"),
+		"kubelet.conf":      constants.KubeConfigPath,
+	} {
+		if content, err := os.ReadFile(path); err == nil {
+			files[name] = content
+		}
+	}
+	return files, nil
+}
+
+// networkCollector collects addresses, routes and iptables rules of the node.
+type networkCollector struct {
+	timeout time.Duration
+}
+
+func (c *networkCollector) Name() string {
+	return "network"
+}
+
+func (c *networkCollector) Collect() (map[string][]byte, error) {
+	return map[string][]byte{
+		"ip-addr.txt":      runCommand(c.timeout, "ip", "addr"),
+		"ip-route.txt":     runCommand(c.timeout, "ip", "route"),
+		"iptables-nat.txt": runCommand(c.timeout, "iptables-save", "-t", "nat"),
+		"iptables.txt":     runCommand(c.timeout, "iptables-save", "-t", "filter"),
+	}, nil
+}
+
+// certsCollector collects metadata of certificates, private keys are never collected.
+type certsCollector struct {
+	dirs []string
+}
+
+func (c *certsCollector) Name() string {
+	return "certs"
+}
+
+func (c *certsCollector) Collect() (map[string][]byte, error) {
+	var buf bytes.Buffer
+	for _, dir := range c.dirs {
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			if ext := filepath.Ext(path); ext != ".crt" && ext != ".pem" {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Fprintf(&buf, "%s: %v\n\n", path, err)
+				return nil
+			}
+			buf.WriteString(certsMetadata(path, content))
+			return nil
+		})
+	}
+	return map[string][]byte{"metadata.txt": buf.Bytes()}, nil
+}
+
+// certsMetadata returns the description of certificates in the pem content,
+// non certificate blocks(like private keys) are skipped.
+func certsMetadata(path string, content []byte) string {
+	var buf bytes.Buffer
+	now := time.Now()
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			fmt.Fprintf(&buf, "%s: failed to parse certificate, %v\n\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&buf, "file: %s\n", path)
+		fmt.Fprintf(&buf, "  subject: %s\n", cert.Subject.String())
+		fmt.Fprintf(&buf, "  issuer: %s\n", cert.Issuer.String())
+		fmt.Fprintf(&buf, "  serial: %s\n", cert.SerialNumber.String())
+		fmt.Fprintf(&buf, "  notBefore: %s\n", cert.NotBefore.UTC().Format(time.RFC3339))
+		fmt.Fprintf(&buf, "  notAfter: %s\n", cert.NotAfter.UTC().Format(time.RFC3339))
+		fmt.Fprintf(&buf, "  expired: %v\n", now.After(cert.NotAfter))
+		if len(cert.DNSNames) != 0 {
+			fmt.Fprintf(&buf, "  dnsNames: %s\n", strings.Join(cert.DNSNames, ","))
+		}
+		if len(cert.IPAddresses) != 0 {
+			ips := make([]string, 0, len(cert.IPAddresses))
+			for _, ip := range cert.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+			fmt.Fprintf(&buf, "  ipAddresses: %s\n", strings.Join(ips, ","))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
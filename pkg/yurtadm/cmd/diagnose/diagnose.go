@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+)
+
+// diagnoseOptions defines all the options exposed via flags by yurtadm diagnose.
+type diagnoseOptions struct {
+	outputPath    string
+	yurthubServer string
+	yurthubDir    string
+	diskCachePath string
+	logLines      int
+	timeout       time.Duration
+}
+
+// newDiagnoseOptions returns a struct ready for being used for creating cmd diagnose flags.
+func newDiagnoseOptions() *diagnoseOptions {
+	return &diagnoseOptions{
+		outputPath:    fmt.Sprintf("yurtadm-diagnose-%s.tar.gz", time.Now().Format("20060102150405")),
+		yurthubServer: constants.DefaultYurtHubServerAddr,
+		yurthubDir:    constants.YurtHubWorkdir,
+		diskCachePath: constants.YurtHubCacheDir,
+		logLines:      constants.DefaultDiagnoseLogLines,
+		timeout:       10 * time.Second,
+	}
+}
+
+// NewCmdDiagnose returns "yurtadm diagnose" command.
+func NewCmdDiagnose(out io.Writer) *cobra.Command {
+	o := newDiagnoseOptions()
+
+	cmd := &cobra.Command{
+		Use:   "diagnose",
+		Short: "Collect a sanitized support bundle of the node's OpenYurt components",
+		Long: "Collect yurthub logs, cache stats, health endpoint output, kubelet status, network/iptables state and certificates " +
+			"metadata into a tar.gz bundle. Bootstrap tokens, bearer tokens and private keys are redacted before they are written.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d := newDiagnoser(o)
+			if err := d.Run(); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "support bundle is written to %s\n", o.outputPath)
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+
+	addDiagnoseFlags(cmd.Flags(), o)
+	return cmd
+}
+
+// addDiagnoseFlags adds diagnose flags bound to the options to the specified flagset
+func addDiagnoseFlags(flagSet *flag.FlagSet, o *diagnoseOptions) {
+	flagSet.StringVarP(
+		&o.outputPath, constants.DiagnoseOutput, "o", o.outputPath,
+		"The path of the generated support bundle.",
+	)
+	flagSet.StringVar(
+		&o.yurthubServer, constants.YurtHubServerAddr, o.yurthubServer,
+		"Sets the address for yurthub server addr",
+	)
+	flagSet.StringVar(
+		&o.yurthubDir, constants.YurtHubRootDir, o.yurthubDir,
+		"The root directory of yurthub which contains pki and kubeconfig files.",
+	)
+	flagSet.StringVar(
+		&o.diskCachePath, constants.YurtHubDiskCachePath, o.diskCachePath,
+		"The path of yurthub disk cache.",
+	)
+	flagSet.IntVar(
+		&o.logLines, constants.DiagnoseLogLines, o.logLines,
+		"The number of most recent log lines collected for each component.",
+	)
+	flagSet.DurationVar(
+		&o.timeout, constants.DiagnoseTimeout, o.timeout,
+		"The timeout for each command or http request issued during collection.",
+	)
+}
+
+// diagnoser runs all collectors and writes their results into a support bundle.
+type diagnoser struct {
+	*diagnoseOptions
+	collectors []collector
+}
+
+func newDiagnoser(o *diagnoseOptions) *diagnoser {
+	return &diagnoser{
+		diagnoseOptions: o,
+		collectors: []collector{
+			&yurthubLogCollector{lines: o.logLines, timeout: o.timeout},
+			&cacheStatsCollector{cachePath: o.diskCachePath},
+			&yurthubEndpointCollector{server: o.yurthubServer, timeout: o.timeout},
+			&kubeletCollector{lines: o.logLines, timeout: o.timeout},
+			&networkCollector{timeout: o.timeout},
+			&certsCollector{dirs: []string{o.yurthubDir, constants.KubeletWorkdir, constants.DefaultCertificatesDir}},
+		},
+	}
+}
+
+// Run executes every collector, a failed collector is recorded in the bundle
+// instead of aborting the whole collection.
+func (d *diagnoser) Run() error {
+	b, err := newBundle(d.outputPath)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range d.collectors {
+		klog.Infof("[diagnose] collecting %s", c.Name())
+		files, err := c.Collect()
+		if err != nil {
+			klog.Warningf("[diagnose] collector %s failed, %v", c.Name(), err)
+			if files == nil {
+				files = make(map[string][]byte)
+			}
+			files["error.txt"] = []byte(err.Error())
+		}
+
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := b.add(fmt.Sprintf("%s/%s", c.Name(), name), sanitize(files[name])); err != nil {
+				b.close()
+				return err
+			}
+		}
+	}
+
+	return b.close()
+}
@@ -18,9 +18,14 @@ limitations under the License.
 package join
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -31,11 +36,15 @@ import (
 	"k8s.io/klog/v2"
 
 	kubeconfigutil "github.com/openyurtio/openyurt/pkg/util/kubeconfig"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/apis/v1alpha1"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/joindata"
 	yurtphases "github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/phases"
 	yurtconstants "github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/dryrun"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/util/edgenode"
 	yurtadmutil "github.com/openyurtio/openyurt/pkg/yurtadm/util/kubernetes"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/provider"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/sealedcredential"
 )
 
 type joinOptions struct {
@@ -52,7 +61,18 @@ type joinOptions struct {
 	nodeLabels               string
 	kubernetesResourceServer string
 	yurthubServer            string
+	yurthubImageGCThreshold  int
+	yurthubImageFsPath       string
 	reuseCNIBin              bool
+	dryRun                   bool
+	provider                 string
+	providerConfig           string
+	config                   string
+	kubeletConfigPatch       string
+	sealedCredential         string
+	unsealKey                string
+	verify                   bool
+	verifyTimeout            time.Duration
 }
 
 // newJoinOptions returns a struct ready for being used for creating cmd join flags.
@@ -68,6 +88,8 @@ func newJoinOptions() *joinOptions {
 		kubernetesResourceServer: yurtconstants.DefaultKubernetesResourceServer,
 		yurthubServer:            yurtconstants.DefaultYurtHubServerAddr,
 		reuseCNIBin:              false,
+		unsealKey:                yurtconstants.DefaultUnsealKeyPath,
+		verifyTimeout:            yurtconstants.DefaultVerifyTimeout,
 	}
 }
 
@@ -86,6 +108,14 @@ func NewCmdJoin(in io.Reader, out io.Writer, outErr io.Writer) *cobra.Command {
 		Use:   "join [api-server-endpoint]",
 		Short: "Run this on any machine you wish to join an existing cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(joinOptions.config) != 0 {
+				cfg, err := v1alpha1.LoadJoinConfiguration(joinOptions.config)
+				if err != nil {
+					return err
+				}
+				args = applyJoinConfiguration(cmd.Flags(), joinOptions, args, cfg)
+			}
+
 			o, err := newJoinData(args, joinOptions)
 			if err != nil {
 				return err
@@ -106,6 +136,11 @@ func NewCmdJoin(in io.Reader, out io.Writer, outErr io.Writer) *cobra.Command {
 
 // addJoinConfigFlags adds join flags bound to the config to the specified flagset
 func addJoinConfigFlags(flagSet *flag.FlagSet, joinOptions *joinOptions) {
+	flagSet.StringVar(
+		&joinOptions.config, yurtconstants.Config, joinOptions.config,
+		"Path to a yurtadm JoinConfiguration file, flags specified on command line take precedence over the settings in file. "+
+			"Use 'yurtadm config print-default' to print the default JoinConfiguration.",
+	)
 	flagSet.StringVar(
 		&joinOptions.token, yurtconstants.TokenStr, "",
 		"Use this token for both discovery-token and tls-bootstrap-token when those values are not provided.",
@@ -158,10 +193,59 @@ func addJoinConfigFlags(flagSet *flag.FlagSet, joinOptions *joinOptions) {
 		&joinOptions.yurthubServer, yurtconstants.YurtHubServerAddr, joinOptions.yurthubServer,
 		"Sets the address for yurthub server addr",
 	)
+	flagSet.IntVar(
+		&joinOptions.yurthubImageGCThreshold, yurtconstants.YurtHubImageGCHighThreshold, joinOptions.yurthubImageGCThreshold,
+		fmt.Sprintf("The percent of image fs usage after which yurthub collects the unused images instead of kubelet, it should be in (%d, 100]. "+
+			"Kubelet image gc is disabled by --image-gc-high-threshold=100 on edge node, and the images of pods cached for kubelet are protected "+
+			"while the cloud is unreachable. Kubelet still removes unused images when it evicts pods under disk pressure, the cached pod images "+
+			"are not protected in this case. Image gc of yurthub is disabled if set to 0.", yurtconstants.YurtHubImageGCLowThreshold),
+	)
+	flagSet.StringVar(
+		&joinOptions.yurthubImageFsPath, yurtconstants.YurtHubImageFsPath, joinOptions.yurthubImageFsPath,
+		fmt.Sprintf("The path which images of container runtime are stored in, it is mounted into yurthub for image gc. "+
+			"If not specified, %s is used for docker and %s for the other runtimes.", yurtconstants.DefaultDockerImageFsPath, yurtconstants.DefaultContainerdImageFsPath),
+	)
 	flagSet.BoolVar(
 		&joinOptions.reuseCNIBin, yurtconstants.ReuseCNIBin, false,
 		"Whether to reuse local CNI binaries or to download new ones",
 	)
+	flagSet.BoolVar(
+		&joinOptions.dryRun, yurtconstants.DryRun, false,
+		"Don't apply any changes; just output in json format what files, services, manifests and network settings would be changed.",
+	)
+	flagSet.BoolVar(
+		&joinOptions.verify, yurtconstants.Verify, false,
+		"Verify the node works after joining: wait for the node to become Ready, yurthub to be healthy, the node to be added into "+
+			"the desired node pool and a smoke pod to run. The command fails if any check is not passed.",
+	)
+	flagSet.DurationVar(
+		&joinOptions.verifyTimeout, yurtconstants.VerifyTimeout, joinOptions.verifyTimeout,
+		"The timeout of each check in verify phase.",
+	)
+	flagSet.StringVar(
+		&joinOptions.kubeletConfigPatch, yurtconstants.KubeletConfigPatch, joinOptions.kubeletConfigPatch,
+		"Path to a KubeletConfiguration fragment(like eviction thresholds, reserved resources and image gc settings) which is merged "+
+			"into the kubelet config generated by kubeadm, the merged config is validated against the kubelet config schema.",
+	)
+	flagSet.StringVar(
+		&joinOptions.provider, yurtconstants.Provider, joinOptions.provider,
+		fmt.Sprintf("The provisioning provider used to fetch join parameters(like server address and token) from site infrastructure, one of %s. "+
+			"Parameters specified on command line take precedence over parameters from provider.", strings.Join(provider.Names(), ",")),
+	)
+	flagSet.StringVar(
+		&joinOptions.providerConfig, yurtconstants.ProviderConfig, joinOptions.providerConfig,
+		"The config of provisioning provider, the parameters file path for cloud-init provider, the kernel command line path for metal provider, "+
+			"and the plugin command for exec provider.",
+	)
+	flagSet.StringVar(
+		&joinOptions.sealedCredential, yurtconstants.SealedCredential, joinOptions.sealedCredential,
+		"Path to a single-use credential sealed to the site or device key by 'yurtadm credential seal', it is unsealed locally and used instead of a reusable bootstrap token. "+
+			"The credential is claimed by this node before joining, and its bootstrap token and file are removed after the node is joined.",
+	)
+	flagSet.StringVar(
+		&joinOptions.unsealKey, yurtconstants.UnsealKey, joinOptions.unsealKey,
+		"Path to the site or device private key for unsealing the sealed credential.",
+	)
 }
 
 func newJoinerWithJoinData(o *joinData, in io.Reader, out io.Writer, outErr io.Writer) *nodeJoiner {
@@ -177,6 +261,47 @@ func newJoinerWithJoinData(o *joinData, in io.Reader, out io.Writer, outErr io.W
 func (nodeJoiner *nodeJoiner) Run() error {
 	joinData := nodeJoiner.joinData
 
+	if joinData.dryRun {
+		plan, err := yurtphases.PlanJoin(joinData)
+		if err != nil {
+			return err
+		}
+		if joinData.sealedCredential != nil {
+			plan.Changes = append([]dryrun.Change{
+				{Kind: dryrun.KindFile, Operation: dryrun.OperationWrite, Path: filepath.Join(yurtconstants.SealedCredentialUsedDir, joinData.sealedCredential.ID), Reason: "record the sealed credential as used after the node is joined"},
+				{Kind: dryrun.KindFile, Operation: dryrun.OperationRemove, Path: joinData.sealedCredentialPath, Reason: "remove the sealed credential after the node is joined"},
+			}, plan.Changes...)
+		}
+		if joinData.verify {
+			if err := yurtphases.PlanVerify(plan, joinData); err != nil {
+				return err
+			}
+		}
+		return plan.Print(nodeJoiner.outWriter)
+	}
+
+	// the sealed credential is claimed on the server before joining, and it is consumed only after the
+	// node is joined successfully, so a failed join can be retried with the same credential on this node.
+	if joinData.sealedCredential != nil {
+		if err := sealedcredential.Claim(joinData.clientSet, joinData.sealedCredential, joinData.NodeRegistration().Name); err != nil {
+			return errors.Wrapf(err, "failed to claim sealed credential %s", joinData.sealedCredential.ID)
+		}
+	}
+
+	if err := nodeJoiner.join(); err != nil {
+		return err
+	}
+
+	if joinData.sealedCredential != nil {
+		consumeSealedCredential(joinData)
+	}
+	return nil
+}
+
+// join runs the phases of joining the node.
+func (nodeJoiner *nodeJoiner) join() error {
+	joinData := nodeJoiner.joinData
+
 	if err := yurtphases.RunPrepare(joinData); err != nil {
 		return err
 	}
@@ -189,6 +314,12 @@ func (nodeJoiner *nodeJoiner) Run() error {
 		return err
 	}
 
+	if joinData.verify {
+		if err := yurtphases.RunVerify(joinData, joinData.verifyTimeout); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -207,14 +338,48 @@ type joinData struct {
 	nodeLabels               map[string]string
 	kubernetesResourceServer string
 	yurthubServer            string
+	yurthubImageGCThreshold  int
+	yurthubImageFsPath       string
 	reuseCNIBin              bool
+	dryRun                   bool
+	kubeletConfigPatch       []byte
+	sealedCredentialPath     string
+	sealedCredential         *sealedcredential.Credential
+	verify                   bool
+	verifyTimeout            time.Duration
 }
 
 // newJoinData returns a new joinData struct to be used for the execution of the kubeadm join workflow.
 // This func takes care of validating joinOptions passed to the command, and then it converts
 // options into the internal JoinData type that is used as input all the phases in the kubeadm join workflow
 func newJoinData(args []string, opt *joinOptions) (*joinData, error) {
-	// if an APIServerEndpoint from which to retrieve cluster information was not provided, unset the Discovery.BootstrapToken object
+	// the sealed credential is unsealed first, and its join parameters(api server endpoint, token and
+	// ca cert hashes) are applied to the options which are not specified on command line.
+	var cred *sealedcredential.Credential
+	if len(opt.sealedCredential) != 0 {
+		if len(opt.token) != 0 {
+			return nil, errors.New("join token and sealed credential can not be specified at the same time")
+		}
+		var err error
+		cred, err = unsealCredential(opt.sealedCredential, opt.unsealKey)
+		if err != nil {
+			return nil, err
+		}
+		args = applyProviderParameters(opt, args, &cred.JoinParameters)
+	}
+
+	if len(opt.provider) != 0 {
+		params, err := fetchProviderParameters(opt.provider, opt.providerConfig)
+		if err != nil {
+			return nil, err
+		}
+		args = applyProviderParameters(opt, args, params)
+		// parameters missing from provider may be completed by command line, so they are validated after merging
+		if err := mergedJoinParameters(opt, args).Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid join parameters from provider %s and command line", opt.provider)
+		}
+	}
+
 	var apiServerEndpoint string
 	if len(args) == 0 {
 		return nil, errors.New("apiServer endpoint is empty")
@@ -246,6 +411,17 @@ func newJoinData(args []string, opt *joinOptions) (*joinData, error) {
 		ignoreErrors.Insert(opt.ignorePreflightErrors[i])
 	}
 
+	if opt.yurthubImageGCThreshold != 0 && (opt.yurthubImageGCThreshold <= yurtconstants.YurtHubImageGCLowThreshold || opt.yurthubImageGCThreshold > 100) {
+		return nil, errors.Errorf("yurthub image gc high threshold(%d) should be 0 or in (%d, 100]", opt.yurthubImageGCThreshold, yurtconstants.YurtHubImageGCLowThreshold)
+	}
+	imageFsPath := opt.yurthubImageFsPath
+	if len(imageFsPath) == 0 {
+		imageFsPath = yurtconstants.DefaultContainerdImageFsPath
+		if opt.criSocket == yurtconstants.DefaultDockerCRISocket {
+			imageFsPath = yurtconstants.DefaultDockerImageFsPath
+		}
+	}
+
 	// Either use specified nodename or get hostname from OS envs
 	name, err := edgenode.GetHostname(opt.nodeName)
 	if err != nil {
@@ -254,16 +430,18 @@ func newJoinData(args []string, opt *joinOptions) (*joinData, error) {
 	}
 
 	data := &joinData{
-		apiServerEndpoint:     apiServerEndpoint,
-		token:                 opt.token,
-		tlsBootstrapCfg:       nil,
-		ignorePreflightErrors: ignoreErrors,
-		pauseImage:            opt.pauseImage,
-		yurthubImage:          opt.yurthubImage,
-		yurthubServer:         opt.yurthubServer,
-		caCertHashes:          opt.caCertHashes,
-		organizations:         opt.organizations,
-		nodeLabels:            make(map[string]string),
+		apiServerEndpoint:       apiServerEndpoint,
+		token:                   opt.token,
+		tlsBootstrapCfg:         nil,
+		ignorePreflightErrors:   ignoreErrors,
+		pauseImage:              opt.pauseImage,
+		yurthubImage:            opt.yurthubImage,
+		yurthubServer:           opt.yurthubServer,
+		caCertHashes:            opt.caCertHashes,
+		yurthubImageGCThreshold: opt.yurthubImageGCThreshold,
+		yurthubImageFsPath:      imageFsPath,
+		organizations:           opt.organizations,
+		nodeLabels:              make(map[string]string),
 		joinNodeData: &joindata.NodeRegistration{
 			Name:          name,
 			WorkingMode:   opt.nodeType,
@@ -272,6 +450,11 @@ func newJoinData(args []string, opt *joinOptions) (*joinData, error) {
 		},
 		kubernetesResourceServer: opt.kubernetesResourceServer,
 		reuseCNIBin:              opt.reuseCNIBin,
+		dryRun:                   opt.dryRun,
+		sealedCredentialPath:     opt.sealedCredential,
+		sealedCredential:         cred,
+		verify:                   opt.verify,
+		verifyTimeout:            opt.verifyTimeout,
 	}
 
 	// parse node labels
@@ -287,6 +470,15 @@ func newJoinData(args []string, opt *joinOptions) (*joinData, error) {
 		}
 	}
 
+	// validate kubelet config patch before any change is made on the node
+	if len(opt.kubeletConfigPatch) != 0 {
+		patch, err := yurtadmutil.LoadKubeletConfigPatch(opt.kubeletConfigPatch)
+		if err != nil {
+			return nil, err
+		}
+		data.kubeletConfigPatch = patch
+	}
+
 	// get tls bootstrap config
 	cfg, err := yurtadmutil.RetrieveBootstrapConfig(data)
 	if err != nil {
@@ -314,6 +506,179 @@ func newJoinData(args []string, opt *joinOptions) (*joinData, error) {
 	return data, nil
 }
 
+// applyJoinConfiguration sets the join options from JoinConfiguration except for the flags
+// specified on command line, and returns the args with api server endpoint.
+func applyJoinConfiguration(flagSet *flag.FlagSet, opt *joinOptions, args []string, cfg *v1alpha1.JoinConfiguration) []string {
+	if len(args) == 0 && len(cfg.APIServerEndpoint) != 0 {
+		args = []string{cfg.APIServerEndpoint}
+	}
+
+	setString := func(name string, target *string, value string) {
+		if !flagSet.Changed(name) {
+			*target = value
+		}
+	}
+	setString(yurtconstants.TokenStr, &opt.token, cfg.Token)
+	setString(yurtconstants.NodeType, &opt.nodeType, cfg.NodeRegistration.NodeType)
+	setString(yurtconstants.NodeName, &opt.nodeName, cfg.NodeRegistration.Name)
+	setString(yurtconstants.NodeCRISocket, &opt.criSocket, cfg.NodeRegistration.CRISocket)
+	setString(yurtconstants.Organizations, &opt.organizations, cfg.YurtHub.Organizations)
+	setString(yurtconstants.PauseImage, &opt.pauseImage, cfg.PauseImage)
+	setString(yurtconstants.YurtHubImage, &opt.yurthubImage, cfg.YurtHub.Image)
+	setString(yurtconstants.KubernetesResourceServer, &opt.kubernetesResourceServer, cfg.KubernetesResourceServer)
+	setString(yurtconstants.YurtHubServerAddr, &opt.yurthubServer, cfg.YurtHub.ServerAddr)
+	setString(yurtconstants.Provider, &opt.provider, cfg.Provider.Name)
+	setString(yurtconstants.ProviderConfig, &opt.providerConfig, cfg.Provider.Config)
+	setString(yurtconstants.YurtHubImageFsPath, &opt.yurthubImageFsPath, cfg.YurtHub.ImageFsPath)
+	setString(yurtconstants.KubeletConfigPatch, &opt.kubeletConfigPatch, cfg.KubeletConfigPatch)
+	setString(yurtconstants.SealedCredential, &opt.sealedCredential, cfg.Discovery.SealedCredential)
+	setString(yurtconstants.UnsealKey, &opt.unsealKey, cfg.Discovery.UnsealKey)
+
+	if !flagSet.Changed(yurtconstants.TokenDiscoveryCAHash) {
+		opt.caCertHashes = cfg.Discovery.CACertHashes
+	}
+	if !flagSet.Changed(yurtconstants.TokenDiscoverySkipCAHash) {
+		opt.unsafeSkipCAVerification = cfg.Discovery.UnsafeSkipCAVerification
+	}
+	if !flagSet.Changed(yurtconstants.IgnorePreflightErrors) {
+		opt.ignorePreflightErrors = cfg.IgnorePreflightErrors
+	}
+	if !flagSet.Changed(yurtconstants.ReuseCNIBin) {
+		opt.reuseCNIBin = cfg.ReuseCNIBin
+	}
+	if !flagSet.Changed(yurtconstants.YurtHubImageGCHighThreshold) {
+		opt.yurthubImageGCThreshold = cfg.YurtHub.ImageGCHighThreshold
+	}
+	if !flagSet.Changed(yurtconstants.Verify) {
+		opt.verify = cfg.Verify
+	}
+	if !flagSet.Changed(yurtconstants.VerifyTimeout) {
+		opt.verifyTimeout = cfg.VerifyTimeout.Duration
+	}
+	if !flagSet.Changed(yurtconstants.NodeLabels) && len(cfg.NodeRegistration.Labels) != 0 {
+		opt.nodeLabels = labelsToString(cfg.NodeRegistration.Labels)
+	}
+	return args
+}
+
+// labelsToString converts labels to k1=v1,k2=v2 format in the order of keys.
+func labelsToString(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// fetchProviderParameters fetches join parameters from the specified provisioning provider.
+func fetchProviderParameters(name, config string) (*provider.JoinParameters, error) {
+	p, err := provider.New(name, config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), yurtconstants.DefaultProviderTimeout)
+	defer cancel()
+	params, err := p.FetchJoinParameters(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch join parameters from provider %s", p.Name())
+	}
+	klog.Infof("join parameters are fetched from provider %s", p.Name())
+	return params, nil
+}
+
+// unsealCredential unseals the sealed credential with the site or device private key, and
+// rejects the credential that is expired or has been used on this node.
+func unsealCredential(credPath, keyPath string) (*sealedcredential.Credential, error) {
+	sealed, err := os.ReadFile(credPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read sealed credential %s", credPath)
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read unseal key %s", keyPath)
+	}
+	cred, err := sealedcredential.Unseal(sealed, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unseal credential %s", credPath)
+	}
+	if sealedcredential.IsConsumed(yurtconstants.SealedCredentialUsedDir, cred) {
+		return nil, errors.Wrapf(sealedcredential.ErrConsumed, "failed to use credential %s", credPath)
+	}
+	if err := cred.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid join parameters in sealed credential %s", credPath)
+	}
+	klog.Infof("sealed credential %s(expires at %s) is unsealed", cred.ID, cred.ExpiresAt.Format(time.RFC3339))
+	return cred, nil
+}
+
+// consumeSealedCredential revokes the bootstrap token of sealed credential after the node is joined,
+// records the credential as used and removes the credential file. The node has been joined, so failures
+// are only logged, and the claim on the server still rejects other nodes until the token expires.
+func consumeSealedCredential(data *joinData) {
+	cred := data.sealedCredential
+	if err := sealedcredential.Revoke(data.clientSet, cred, data.NodeRegistration().Name); err != nil {
+		klog.Warningf("failed to revoke bootstrap token of sealed credential %s, it is kept until expiration(%s), %v", cred.ID, cred.ExpiresAt.Format(time.RFC3339), err)
+	}
+	if err := sealedcredential.Consume(yurtconstants.SealedCredentialUsedDir, cred); err != nil {
+		klog.Warningf("failed to record sealed credential %s as used, %v", cred.ID, err)
+	}
+	if err := os.Remove(data.sealedCredentialPath); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("failed to remove sealed credential %s, %v", data.sealedCredentialPath, err)
+	}
+}
+
+// mergedJoinParameters returns the join parameters after the parameters from provider are applied to options.
+func mergedJoinParameters(opt *joinOptions, args []string) *provider.JoinParameters {
+	params := &provider.JoinParameters{Token: opt.token}
+	if len(args) != 0 {
+		params.ServerAddr = args[0]
+	}
+	return params
+}
+
+// applyProviderParameters fills the join options that are not specified on command line with
+// parameters from provider, and returns the args with api server endpoint.
+func applyProviderParameters(opt *joinOptions, args []string, params *provider.JoinParameters) []string {
+	defaults := newJoinOptions()
+	if len(args) == 0 {
+		args = []string{params.ServerAddr}
+	}
+	if len(opt.token) == 0 {
+		opt.token = params.Token
+	}
+	if len(opt.caCertHashes) == 0 && len(params.CaCertHashes) != 0 && !opt.unsafeSkipCAVerification {
+		opt.caCertHashes = params.CaCertHashes
+	}
+	if len(opt.nodeName) == 0 {
+		opt.nodeName = params.NodeName
+	}
+	if len(params.NodeType) != 0 && opt.nodeType == defaults.nodeType {
+		opt.nodeType = params.NodeType
+	}
+	if len(opt.organizations) == 0 {
+		opt.organizations = params.Organizations
+	}
+	if len(params.YurtHubImage) != 0 && opt.yurthubImage == defaults.yurthubImage {
+		opt.yurthubImage = params.YurtHubImage
+	}
+	if len(params.PauseImage) != 0 && opt.pauseImage == defaults.pauseImage {
+		opt.pauseImage = params.PauseImage
+	}
+	if len(params.CRISocket) != 0 && opt.criSocket == defaults.criSocket {
+		opt.criSocket = params.CRISocket
+	}
+	if len(opt.nodeLabels) == 0 && len(params.NodeLabels) != 0 {
+		opt.nodeLabels = labelsToString(params.NodeLabels)
+	}
+	return args
+}
+
 // ServerAddr returns the public address of kube-apiserver.
 func (j *joinData) ServerAddr() string {
 	return j.apiServerEndpoint
@@ -378,3 +743,19 @@ func (j *joinData) KubernetesResourceServer() string {
 func (j *joinData) ReuseCNIBin() bool {
 	return j.reuseCNIBin
 }
+
+// KubeletConfigPatch returns the KubeletConfiguration fragment in json format.
+func (j *joinData) KubeletConfigPatch() []byte {
+	return j.kubeletConfigPatch
+}
+
+// YurtHubImageGCHighThreshold returns the percent of image fs usage after which yurthub collects images,
+// 0 means image gc of yurthub is disabled.
+func (j *joinData) YurtHubImageGCHighThreshold() int {
+	return j.yurthubImageGCThreshold
+}
+
+// YurtHubImageFsPath returns the path which images of container runtime are stored in.
+func (j *joinData) YurtHubImageFsPath() string {
+	return j.yurthubImageFsPath
+}
@@ -23,6 +23,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
@@ -30,8 +31,10 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd/api"
 
+	"github.com/openyurtio/openyurt/pkg/yurtadm/apis/v1alpha1"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/joindata"
 	yurtconstants "github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/provider"
 )
 
 const (
@@ -57,6 +60,8 @@ func TestNewJoinOptions(t *testing.T) {
 				kubernetesResourceServer: yurtconstants.DefaultKubernetesResourceServer,
 				yurthubServer:            yurtconstants.DefaultYurtHubServerAddr,
 				reuseCNIBin:              false,
+				unsealKey:                yurtconstants.DefaultUnsealKeyPath,
+				verifyTimeout:            yurtconstants.DefaultVerifyTimeout,
 			},
 		},
 	}
@@ -670,3 +675,105 @@ func TestKubernetesResourceServer(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyProviderParameters(t *testing.T) {
+	params := &provider.JoinParameters{
+		ServerAddr:   "1.2.3.4:6443",
+		Token:        "abcdef.0123456789abcdef",
+		CaCertHashes: []string{"sha256:123"},
+		NodeType:     yurtconstants.CloudNode,
+		NodeLabels:   map[string]string{"b": "2", "a": "1"},
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		token       string
+		expectArgs  []string
+		expectToken string
+	}{
+		{
+			name:        "parameters from provider",
+			expectArgs:  []string{"1.2.3.4:6443"},
+			expectToken: "abcdef.0123456789abcdef",
+		},
+		{
+			name:        "command line takes precedence",
+			args:        []string{"5.6.7.8:6443"},
+			token:       "123456.0123456789abcdef",
+			expectArgs:  []string{"5.6.7.8:6443"},
+			expectToken: "123456.0123456789abcdef",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := newJoinOptions()
+			opt.token = tt.token
+			args := applyProviderParameters(opt, tt.args, params)
+			if !reflect.DeepEqual(tt.expectArgs, args) {
+				t.Errorf("expect args %v, but got %v", tt.expectArgs, args)
+			}
+			if opt.token != tt.expectToken {
+				t.Errorf("expect token %s, but got %s", tt.expectToken, opt.token)
+			}
+			if opt.nodeType != yurtconstants.CloudNode {
+				t.Errorf("expect node type %s, but got %s", yurtconstants.CloudNode, opt.nodeType)
+			}
+			if opt.nodeLabels != "a=1,b=2" {
+				t.Errorf("expect node labels a=1,b=2, but got %s", opt.nodeLabels)
+			}
+		})
+	}
+}
+
+func TestMergedJoinParametersValidate(t *testing.T) {
+	// provider only knows the server address, the token comes from command line
+	params := &provider.JoinParameters{ServerAddr: "1.2.3.4:6443"}
+	opt := newJoinOptions()
+	if err := mergedJoinParameters(opt, applyProviderParameters(opt, nil, params)).Validate(); err == nil {
+		t.Errorf("expect error when token is neither provided by provider nor command line")
+	}
+
+	opt = newJoinOptions()
+	opt.token = "abcdef.0123456789abcdef"
+	if err := mergedJoinParameters(opt, applyProviderParameters(opt, nil, params)).Validate(); err != nil {
+		t.Errorf("expect merged parameters valid, but got %v", err)
+	}
+}
+
+func TestApplyJoinConfiguration(t *testing.T) {
+	cfg := v1alpha1.NewDefaultJoinConfiguration()
+	cfg.APIServerEndpoint = "1.2.3.4:6443"
+	cfg.Token = "abcdef.0123456789abcdef"
+	cfg.NodeRegistration.NodeType = yurtconstants.CloudNode
+	cfg.NodeRegistration.Labels = map[string]string{"a": "1"}
+	cfg.Discovery.UnsafeSkipCAVerification = true
+	cfg.Verify = true
+	cfg.VerifyTimeout.Duration = time.Minute
+	cfg.YurtHub.ImageGCHighThreshold = 90
+
+	opt := newJoinOptions()
+	flagSet := flag.NewFlagSet("join", flag.ContinueOnError)
+	addJoinConfigFlags(flagSet, opt)
+	if err := flagSet.Parse([]string{"--token=123456.0123456789abcdef", "--verify-timeout=2m"}); err != nil {
+		t.Fatalf("failed to parse flags, %v", err)
+	}
+
+	args := applyJoinConfiguration(flagSet, opt, nil, cfg)
+	if !reflect.DeepEqual(args, []string{"1.2.3.4:6443"}) {
+		t.Errorf("expect api server endpoint from config, but got %v", args)
+	}
+	if opt.token != "123456.0123456789abcdef" {
+		t.Errorf("expect token from command line, but got %s", opt.token)
+	}
+	if opt.nodeType != yurtconstants.CloudNode || opt.nodeLabels != "a=1" || !opt.unsafeSkipCAVerification {
+		t.Errorf("expect settings from config, but got %#v", opt)
+	}
+	if !opt.verify || opt.verifyTimeout != 2*time.Minute {
+		t.Errorf("expect verify from config and verify timeout from command line, but got %v, %v", opt.verify, opt.verifyTimeout)
+	}
+	if opt.yurthubImageGCThreshold != 90 {
+		t.Errorf("expect yurthub image gc high threshold from config, but got %d", opt.yurthubImageGCThreshold)
+	}
+}
@@ -44,4 +44,7 @@ type YurtJoinData interface {
 	IgnorePreflightErrors() sets.String
 	KubernetesResourceServer() string
 	ReuseCNIBin() bool
+	KubeletConfigPatch() []byte
+	YurtHubImageGCHighThreshold() int
+	YurtHubImageFsPath() string
 }
@@ -23,20 +23,49 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"k8s.io/klog/v2"
+
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/joindata"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	yurtadmutil "github.com/openyurtio/openyurt/pkg/yurtadm/util/kubernetes"
 )
 
-// RunJoinNode executes the node join process.
+// RunJoinNode executes the node join process. If the user provides a KubeletConfiguration fragment,
+// kubelet is held from starting while kubeadm join runs, and the fragment is merged into the kubelet
+// config once kubeadm writes it, so kubelet never starts with the unpatched config. kubeadm join waits
+// for the node registered by kubelet, so the patch is applied while kubeadm join is running.
 func RunJoinNode(data joindata.YurtJoinData, out io.Writer, outErr io.Writer) error {
+	patch := data.KubeletConfigPatch()
+	if len(patch) != 0 {
+		if err := yurtadmutil.HoldKubelet(); err != nil {
+			return err
+		}
+	} else if err := yurtadmutil.ReleaseKubelet(); err != nil {
+		return err
+	}
+
 	kubeadmJoinConfigFilePath := filepath.Join(constants.KubeletWorkdir, constants.KubeadmJoinConfigFileName)
 	kubeadmCmd := exec.Command("kubeadm", "join", fmt.Sprintf("--config=%s", kubeadmJoinConfigFilePath))
 	kubeadmCmd.Stdout = out
 	kubeadmCmd.Stderr = outErr
 
-	if err := kubeadmCmd.Run(); err != nil {
+	if err := kubeadmCmd.Start(); err != nil {
 		return err
 	}
+	if len(patch) == 0 {
+		return kubeadmCmd.Wait()
+	}
 
-	return nil
+	klog.Info("[join-node] Merging kubelet config patch")
+	exited := make(chan struct{})
+	patchErr := make(chan error, 1)
+	go func() {
+		patchErr <- yurtadmutil.ApplyKubeletConfigPatch(patch, exited)
+	}()
+	err := kubeadmCmd.Wait()
+	close(exited)
+	if perr := <-patchErr; perr != nil && err == nil {
+		err = fmt.Errorf("failed to merge kubelet config patch, %w", perr)
+	}
+	return err
 }
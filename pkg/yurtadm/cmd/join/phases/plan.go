@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/joindata"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/dryrun"
+	yurtadmutil "github.com/openyurtio/openyurt/pkg/yurtadm/util/kubernetes"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/system"
+)
+
+// PlanJoin returns the changes that RunPrepare, RunJoinNode and RunPostCheck would make on the node,
+// in the same order as they are executed. Nothing is written to the node.
+func PlanJoin(data joindata.YurtJoinData) (*dryrun.Plan, error) {
+	plan := dryrun.NewPlan("join")
+	// bootstrap token is written into yurthub manifest and kubeadm join config
+	plan.Mask(data.JoinToken())
+
+	// prepare phase
+	staticPodsPath := filepath.Join(constants.KubeletConfigureDir, constants.ManifestsSubDirName)
+	plan.Remove(dryrun.KindDirectory, staticPodsPath)
+	system.PlanSystemSettings(plan)
+	if err := yurtadmutil.PlanKubernetesComponents(plan, data); err != nil {
+		return nil, err
+	}
+	yurtadmutil.PlanKubeletConfigs(plan)
+
+	yurthubYaml, err := renderYurthubStaticYaml(data)
+	if err != nil {
+		return nil, err
+	}
+	plan.WriteFile(dryrun.KindManifest, filepath.Join(staticPodsPath, constants.YurthubStaticPodFileName), 0600, yurthubYaml)
+
+	if err := yurtadmutil.PlanKubeadmJoinConfigs(plan, data); err != nil {
+		return nil, err
+	}
+
+	// kubelet is held from starting until the kubelet config patch is merged
+	if len(data.KubeletConfigPatch()) != 0 {
+		plan.Remove(dryrun.KindFile, filepath.Join(constants.KubeletWorkdir, constants.KubeletConfigFileName))
+		plan.Remove(dryrun.KindFile, filepath.Join(constants.KubeletWorkdir, constants.KubeadmFlagsEnvFileName))
+		plan.WriteFile(dryrun.KindService, constants.KubeletHoldConfPath, 0600, constants.KubeletHoldUnitConfig)
+		plan.Run("bash", "-c", constants.DaemonReload)
+	}
+
+	// join node phase, kubeadm(not yurtadm) writes kubelet config and starts kubelet service.
+	// post check phase only reads the node status, so it makes no change.
+	plan.Add(dryrun.Change{
+		Kind:      dryrun.KindCommand,
+		Operation: dryrun.OperationRun,
+		Command:   []string{"kubeadm", "join", fmt.Sprintf("--config=%s", filepath.Join(constants.KubeletWorkdir, constants.KubeadmJoinConfigFileName))},
+		Reason:    "kubeadm writes /var/lib/kubelet/config.yaml, /var/lib/kubelet/kubeadm-flags.env, /etc/kubernetes/pki/ca.crt and starts kubelet service",
+	})
+
+	if len(data.KubeletConfigPatch()) != 0 {
+		plan.Add(dryrun.Change{
+			Kind:      dryrun.KindFile,
+			Operation: dryrun.OperationWrite,
+			Path:      filepath.Join(constants.KubeletWorkdir, constants.KubeletConfigFileName),
+			Content:   string(data.KubeletConfigPatch()),
+			Reason:    "merge the kubelet config patch into the kubelet config written by kubeadm before kubelet starts",
+		})
+		plan.Remove(dryrun.KindService, constants.KubeletHoldConfPath)
+		plan.Run("bash", "-c", constants.DaemonReload)
+		plan.Run("bash", "-c", constants.RestartKubeletSvc)
+	}
+	return plan, nil
+}
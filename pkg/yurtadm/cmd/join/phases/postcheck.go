@@ -61,12 +61,17 @@ func checkKubeletStatus() error {
 
 // checkYurthubHealthz check if YurtHub is healthy.
 func checkYurthubHealthz(joinData joindata.YurtJoinData) error {
+	return waitYurthubHealthz(joinData, 300*time.Second)
+}
+
+// waitYurthubHealthz waits YurtHub to be healthy before timeout.
+func waitYurthubHealthz(joinData joindata.YurtJoinData, timeout time.Duration) error {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", fmt.Sprintf("%s:10267", joinData.YurtHubServer()), constants.ServerHealthzURLPath), nil)
 	if err != nil {
 		return err
 	}
 	client := &http.Client{}
-	return wait.PollImmediate(time.Second*5, 300*time.Second, func() (bool, error) {
+	return wait.PollImmediate(time.Second*5, timeout, func() (bool, error) {
 		resp, err := client.Do(req)
 		if err != nil {
 			return false, nil
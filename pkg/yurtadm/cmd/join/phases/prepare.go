@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -93,6 +94,20 @@ func addYurthubStaticYaml(data joindata.YurtJoinData, podManifestPath string) er
 		}
 	}
 
+	yurthubTemplate, err := renderYurthubStaticYaml(data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(podManifestPath, constants.YurthubStaticPodFileName), []byte(yurthubTemplate), 0600); err != nil {
+		return err
+	}
+	klog.Info("[join-node] Add hub agent static yaml is ok")
+	return nil
+}
+
+// renderYurthubStaticYaml returns the content of YurtHub static yaml for worker node.
+func renderYurthubStaticYaml(data joindata.YurtJoinData) (string, error) {
 	// There can be multiple master IP addresses
 	serverAddrs := strings.Split(data.ServerAddr(), ",")
 	for i := 0; i < len(serverAddrs); i++ {
@@ -108,16 +123,17 @@ func addYurthubStaticYaml(data joindata.YurtJoinData, podManifestPath string) er
 		"workingMode":          data.NodeRegistration().WorkingMode,
 		"organizations":        data.NodeRegistration().Organizations,
 		"yurthubServerAddr":    data.YurtHubServer(),
+		"criSocketDir":         filepath.Dir(strings.TrimPrefix(data.NodeRegistration().CRISocket, "unix://")),
+		"imageFsPath":          data.YurtHubImageFsPath(),
 	}
-
-	yurthubTemplate, err := templates.SubsituteTemplate(constants.YurthubTemplate, ctx)
-	if err != nil {
-		return err
+	// tpm is mounted if the node has one, so yurthub can unseal cache encryption keys from it
+	if info, err := os.Stat(constants.TPMDevice); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		ctx["tpmDevice"] = constants.TPMDevice
 	}
-
-	if err := os.WriteFile(filepath.Join(podManifestPath, constants.YurthubStaticPodFileName), []byte(yurthubTemplate), 0600); err != nil {
-		return err
+	if data.YurtHubImageGCHighThreshold() != 0 && data.NodeRegistration().WorkingMode == constants.EdgeNode {
+		ctx["imageGCHighThreshold"] = strconv.Itoa(data.YurtHubImageGCHighThreshold())
+		ctx["imageGCCRISocket"] = "unix://" + strings.TrimPrefix(data.NodeRegistration().CRISocket, "unix://")
 	}
-	klog.Info("[join-node] Add hub agent static yaml is ok")
-	return nil
+
+	return templates.SubsituteTemplate(constants.YurthubTemplate, ctx)
 }
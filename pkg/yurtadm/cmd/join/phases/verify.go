@@ -0,0 +1,185 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	kubeconfigutil "github.com/openyurtio/openyurt/pkg/util/kubeconfig"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/joindata"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/dryrun"
+)
+
+const (
+	// verifyPodName is the name of smoke static pod, the name of its mirror pod is verifyPodName-<node name>
+	verifyPodName     = "yurtadm-verify"
+	verifyPodFileName = "yurtadm-verify.yaml"
+	verifyPollPeriod  = 5 * time.Second
+)
+
+// RunVerify verifies the node works after joining: the node becomes Ready, yurthub is healthy,
+// the node is added into the desired node pool and a smoke pod runs on the node. An error is
+// returned if any check is not passed before timeout, so automation gets a definitive join result.
+func RunVerify(data joindata.YurtJoinData, timeout time.Duration) error {
+	client, err := kubeconfigutil.ClientSetFromFile(constants.KubeConfigPath)
+	if err != nil {
+		return fmt.Errorf("could not create client with kubelet kubeconfig, %w", err)
+	}
+	nodeName := data.NodeRegistration().Name
+
+	klog.Infof("[verify] waiting node %s to be ready", nodeName)
+	if err := waitNodeReady(client, nodeName, timeout); err != nil {
+		return fmt.Errorf("node %s is not ready, %w", nodeName, err)
+	}
+
+	klog.Infof("[verify] waiting yurthub to be healthy")
+	if err := waitYurthubHealthz(data, timeout); err != nil {
+		return fmt.Errorf("yurthub is not healthy, %w", err)
+	}
+
+	if pool := data.NodeLabels()[apps.LabelDesiredNodePool]; len(pool) != 0 {
+		klog.Infof("[verify] waiting node %s to be added into node pool %s", nodeName, pool)
+		if err := waitNodePoolLabel(client, nodeName, pool, timeout); err != nil {
+			return fmt.Errorf("node %s is not added into node pool %s, %w", nodeName, pool, err)
+		}
+	}
+
+	klog.Infof("[verify] waiting smoke pod to run on node %s", nodeName)
+	if err := runSmokePod(client, data, timeout); err != nil {
+		return fmt.Errorf("smoke pod is not running on node %s, %w", nodeName, err)
+	}
+
+	klog.Infof("[verify] node %s is verified", nodeName)
+	return nil
+}
+
+// PlanVerify records the changes that RunVerify would make on the node.
+func PlanVerify(plan *dryrun.Plan, data joindata.YurtJoinData) error {
+	content, err := renderSmokePod(data)
+	if err != nil {
+		return err
+	}
+	manifest := filepath.Join(constants.StaticPodPath, verifyPodFileName)
+	plan.WriteFile(dryrun.KindManifest, manifest, 0600, string(content))
+	plan.Remove(dryrun.KindManifest, manifest)
+	return nil
+}
+
+func waitNodeReady(client kubernetes.Interface, nodeName string, timeout time.Duration) error {
+	return wait.PollImmediate(verifyPollPeriod, timeout, func() (bool, error) {
+		node, err := client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			klog.V(2).Infof("could not get node %s, %v", nodeName, err)
+			return false, nil
+		}
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == v1.NodeReady {
+				return cond.Status == v1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+func waitNodePoolLabel(client kubernetes.Interface, nodeName, pool string, timeout time.Duration) error {
+	return wait.PollImmediate(verifyPollPeriod, timeout, func() (bool, error) {
+		node, err := client.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			klog.V(2).Infof("could not get node %s, %v", nodeName, err)
+			return false, nil
+		}
+		return node.Labels[apps.LabelCurrentNodePool] == pool, nil
+	})
+}
+
+// runSmokePod runs a static pod with pause image on the node, because node credential is only
+// permitted to read the mirror pods of static pods, and removes it after it is running.
+func runSmokePod(client kubernetes.Interface, data joindata.YurtJoinData, timeout time.Duration) error {
+	content, err := renderSmokePod(data)
+	if err != nil {
+		return err
+	}
+	manifest := filepath.Join(constants.StaticPodPath, verifyPodFileName)
+	if err := os.WriteFile(manifest, content, 0600); err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.Remove(manifest); err != nil && !os.IsNotExist(err) {
+			klog.Warningf("could not remove smoke pod manifest %s, %v", manifest, err)
+		}
+	}()
+
+	mirrorPodName := fmt.Sprintf("%s-%s", verifyPodName, data.NodeRegistration().Name)
+	return wait.PollImmediate(verifyPollPeriod, timeout, func() (bool, error) {
+		pod, err := client.CoreV1().Pods(metav1.NamespaceSystem).Get(context.TODO(), mirrorPodName, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.V(2).Infof("could not get pod %s, %v", mirrorPodName, err)
+			}
+			return false, nil
+		}
+		if pod.Status.Phase == v1.PodFailed {
+			return false, fmt.Errorf("pod %s is failed, %s", mirrorPodName, pod.Status.Message)
+		}
+		return pod.Status.Phase == v1.PodRunning, nil
+	})
+}
+
+func renderSmokePod(data joindata.YurtJoinData) ([]byte, error) {
+	pod := &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      verifyPodName,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				"k8s-app": verifyPodName,
+			},
+		},
+		Spec: v1.PodSpec{
+			HostNetwork: true,
+			Containers: []v1.Container{
+				{
+					Name:  "pause",
+					Image: data.PauseImage(),
+				},
+			},
+			Tolerations: []v1.Toleration{
+				{
+					Operator: v1.TolerationOpExists,
+				},
+			},
+		},
+	}
+	return yaml.Marshal(pod)
+}
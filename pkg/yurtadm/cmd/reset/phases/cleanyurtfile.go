@@ -24,15 +24,18 @@ import (
 	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
 )
 
+// yurtFiles are the files and directories created by yurtadm join
+var yurtFiles = []string{constants.KubeletWorkdir,
+	constants.YurttunnelAgentWorkdir,
+	constants.YurttunnelServerWorkdir,
+	constants.YurtHubWorkdir,
+	constants.KubeletSvcPath,
+	constants.KubeletServiceFilepath,
+	constants.KubeletConfigureDir,
+	constants.SysctlK8sConfig}
+
 func RunCleanYurtFile() error {
-	for _, file := range []string{constants.KubeletWorkdir,
-		constants.YurttunnelAgentWorkdir,
-		constants.YurttunnelServerWorkdir,
-		constants.YurtHubWorkdir,
-		constants.KubeletSvcPath,
-		constants.KubeletServiceFilepath,
-		constants.KubeletConfigureDir,
-		constants.SysctlK8sConfig} {
+	for _, file := range yurtFiles {
 		if err := os.RemoveAll(file); err != nil {
 			klog.Warningf("Clean file %s fail: %v, please clean it manually.", file, err)
 		}
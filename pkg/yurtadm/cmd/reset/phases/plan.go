@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package phases
+
+import (
+	"os"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/reset/resetdata"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/dryrun"
+)
+
+// PlanReset returns the changes that RunResetNode and RunCleanYurtFile would make on the node.
+func PlanReset(data resetdata.YurtResetData) *dryrun.Plan {
+	plan := dryrun.NewPlan("reset")
+	plan.Add(dryrun.Change{
+		Kind:      dryrun.KindCommand,
+		Operation: dryrun.OperationRun,
+		Command:   append([]string{"kubeadm"}, kubeadmResetArgs(data)...),
+		Reason:    "kubeadm stops kubelet service, removes containers, static pod manifests, etcd data and certificates",
+	})
+
+	for _, file := range yurtFiles {
+		info, err := os.Stat(file)
+		if err != nil {
+			// file that doesn't exist needs no change
+			continue
+		}
+		if info.IsDir() {
+			plan.Remove(dryrun.KindDirectory, file)
+		} else {
+			plan.Remove(dryrun.KindFile, file)
+		}
+	}
+	return plan
+}
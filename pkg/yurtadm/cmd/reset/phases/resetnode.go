@@ -34,12 +34,7 @@ func RunResetNode(data resetdata.YurtResetData, in io.Reader, out io.Writer, out
 		return err
 	}
 
-	kubeadmCmd := exec.Command("kubeadm", "reset",
-		"--cert-dir="+data.CertificatesDir(),
-		"--cri-socket="+data.CRISocketPath(),
-		"--force="+strconv.FormatBool(data.ForceReset()),
-		"--ignore-preflight-errors="+strings.Join(data.IgnorePreflightErrors(), ","),
-	)
+	kubeadmCmd := exec.Command("kubeadm", kubeadmResetArgs(data)...)
 	kubeadmCmd.Stdin = in
 	kubeadmCmd.Stdout = out
 	kubeadmCmd.Stderr = outErr
@@ -50,3 +45,13 @@ func RunResetNode(data resetdata.YurtResetData, in io.Reader, out io.Writer, out
 
 	return nil
 }
+
+// kubeadmResetArgs returns the args of kubeadm reset command
+func kubeadmResetArgs(data resetdata.YurtResetData) []string {
+	return []string{"reset",
+		"--cert-dir=" + data.CertificatesDir(),
+		"--cri-socket=" + data.CRISocketPath(),
+		"--force=" + strconv.FormatBool(data.ForceReset()),
+		"--ignore-preflight-errors=" + strings.Join(data.IgnorePreflightErrors(), ","),
+	}
+}
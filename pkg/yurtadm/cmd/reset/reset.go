@@ -33,6 +33,7 @@ type resetOptions struct {
 	criSocketPath         string
 	forceReset            bool
 	ignorePreflightErrors []string
+	dryRun                bool
 }
 
 // resetData defines all the runtime information used when running the kubeadm reset workflow;
@@ -42,6 +43,7 @@ type resetData struct {
 	criSocketPath         string
 	forceReset            bool
 	ignorePreflightErrors []string
+	dryRun                bool
 }
 
 // newResetOptions returns a struct ready for being used for creating cmd join flags.
@@ -59,6 +61,7 @@ func newResetData(options *resetOptions) (*resetData, error) {
 		criSocketPath:         options.criSocketPath,
 		forceReset:            options.forceReset,
 		ignorePreflightErrors: options.ignorePreflightErrors,
+		dryRun:                options.dryRun,
 	}, nil
 }
 
@@ -80,6 +83,10 @@ func AddResetFlags(flagSet *flag.FlagSet, resetOptions *resetOptions) {
 		&resetOptions.ignorePreflightErrors, constants.IgnorePreflightErrors, resetOptions.ignorePreflightErrors,
 		"A list of checks whose errors will be shown as warnings. Example: 'IsPrivilegedUser,Swap'. Value 'all' ignores errors from all checks.",
 	)
+	flagSet.BoolVar(
+		&resetOptions.dryRun, constants.DryRun, false,
+		"Don't apply any changes; just output in json format what commands would be run and what files would be removed.",
+	)
 }
 
 type nodeReseter struct {
@@ -102,6 +109,10 @@ func newReseterWithResetData(o *resetData, in io.Reader, out io.Writer, outErr i
 func (nodeReseter *nodeReseter) Run() error {
 	resetData := nodeReseter.resetData
 
+	if resetData.dryRun {
+		return yurtphases.PlanReset(resetData).Print(nodeReseter.outWriter)
+	}
+
 	if err := yurtphases.RunResetNode(resetData, nodeReseter.inReader, nodeReseter.outWriter, nodeReseter.outErrWriter); err != nil {
 		return err
 	}
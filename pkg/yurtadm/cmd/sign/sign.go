@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sign
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater"
+	nodeservant "github.com/openyurtio/openyurt/pkg/node-servant"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+	yurtconstants "github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+)
+
+type signOptions struct {
+	filename   string
+	signingKey string
+	output     string
+}
+
+// NewCmdSign returns "yurtadm sign" command.
+func NewCmdSign(out io.Writer) *cobra.Command {
+	o := &signOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign a PoolUpgrade or DaemonSet manifest offline by the fleet signing key",
+		Long: `Sign a PoolUpgrade or DaemonSet manifest offline by the fleet signing key, the private key never
+enters the cluster. The nodes which are provisioned with the fleet public key only run the signed
+upgrade steps and OTA upgrades.
+
+The steps and the puller container of PoolUpgrade are signed in their signature fields. The pod
+template of DaemonSet is signed in the apps.openyurt.io/ota-payload-signature annotation, it
+should be fetched from the cluster by 'kubectl get -o yaml' so the defaulted fields are signed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.run(out)
+		},
+	}
+
+	addSignFlags(cmd.Flags(), o)
+	return cmd
+}
+
+func addSignFlags(flagSet *flag.FlagSet, o *signOptions) {
+	flagSet.StringVarP(
+		&o.filename, yurtconstants.SignFilename, "f", o.filename,
+		"Path of the PoolUpgrade or DaemonSet manifest in yaml or json.",
+	)
+	flagSet.StringVar(
+		&o.signingKey, yurtconstants.SignKey, o.signingKey,
+		"Path of the fleet ed25519 private key in PKCS#8 PEM format.",
+	)
+	flagSet.StringVarP(
+		&o.output, yurtconstants.SignOutput, "o", o.output,
+		"Path of the signed manifest, it is written to stdout if not specified.",
+	)
+}
+
+func (o *signOptions) run(out io.Writer) error {
+	if len(o.filename) == 0 {
+		return errors.New("manifest is not specified, so unable to sign")
+	}
+	if len(o.signingKey) == 0 {
+		return errors.New("signing key is not specified, so unable to sign")
+	}
+	key, err := signature.LoadSigningKey(o.signingKey)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(o.filename)
+	if err != nil {
+		return err
+	}
+
+	signed, err := Sign(data, key)
+	if err != nil {
+		return err
+	}
+	if len(o.output) == 0 {
+		_, err = out.Write(signed)
+		return err
+	}
+	return os.WriteFile(o.output, signed, 0644)
+}
+
+// Sign signs the PoolUpgrade or DaemonSet manifest by key, and returns the signed manifest in yaml.
+func Sign(data []byte, key ed25519.PrivateKey) ([]byte, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := appsv1beta1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	obj, _, err := serializer.NewCodecFactory(scheme).UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := obj.(type) {
+	case *appsv1beta1.PoolUpgrade:
+		signPoolUpgrade(v, key)
+	case *appsv1.DaemonSet:
+		if err := signDaemonSet(v, key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%T is not supported, only PoolUpgrade and DaemonSet can be signed", obj)
+	}
+	return yaml.Marshal(obj)
+}
+
+// signPoolUpgrade signs the containers of upgrade steps and pre-pull, which are run on the nodes.
+func signPoolUpgrade(upgrade *appsv1beta1.PoolUpgrade, key ed25519.PrivateKey) {
+	for i := range upgrade.Spec.Steps {
+		step := &upgrade.Spec.Steps[i]
+		step.Signature = signature.SignContainer(key, step.Image, step.Command, step.Args)
+	}
+	if upgrade.Spec.PrePull != nil {
+		prePull := upgrade.Spec.PrePull
+		prePull.Signature = signature.SignContainer(key, prePull.PullerImage, []string{signature.NodeServantEntry}, nodeservant.PrePullArgs(upgrade))
+	}
+}
+
+// signDaemonSet signs the pod template of daemonset, which is the OTA payload verified by yurthub.
+func signDaemonSet(ds *appsv1.DaemonSet, key ed25519.PrivateKey) error {
+	payload, err := signature.TemplatePayload(ds.Namespace, ds.Name, &ds.Spec.Template)
+	if err != nil {
+		return err
+	}
+	if ds.Annotations == nil {
+		ds.Annotations = map[string]string{}
+	}
+	ds.Annotations[daemonpodupdater.OTAPayloadSignatureAnnotation] = signature.SignPayload(key, payload)
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/yaml"
+
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater"
+	nodeservant "github.com/openyurtio/openyurt/pkg/node-servant"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+)
+
+const poolUpgradeManifest = `apiVersion: apps.openyurt.io/v1beta1
+kind: PoolUpgrade
+metadata:
+  name: upgrade-hangzhou
+spec:
+  nodePool: hangzhou
+  prePull:
+    pullerImage: openyurt/node-servant:v1.3.0
+  steps:
+  - name: yurthub
+    image: openyurt/node-servant:v1.3.0
+    command: ["/usr/local/bin/entry.sh"]
+    args: ["upgrade", "yurthub", "--yurthub-image=openyurt/yurthub@sha256:0123"]
+`
+
+const daemonSetManifest = `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: app
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: app:v2
+`
+
+func verify(t *testing.T, pub ed25519.PublicKey, message []byte, sig string) {
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil || !ed25519.Verify(pub, message, raw) {
+		t.Errorf("expect signature %q verified for %q", sig, message)
+	}
+}
+
+func TestSign(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+
+	data, err := Sign([]byte(poolUpgradeManifest), key)
+	if err != nil {
+		t.Fatalf("failed to sign PoolUpgrade, %v", err)
+	}
+	upgrade := &appsv1beta1.PoolUpgrade{}
+	if err := yaml.Unmarshal(data, upgrade); err != nil {
+		t.Fatalf("failed to decode signed PoolUpgrade, %v", err)
+	}
+	if upgrade.Kind != "PoolUpgrade" || upgrade.Name != "upgrade-hangzhou" {
+		t.Errorf("unexpected signed PoolUpgrade %s/%s", upgrade.Kind, upgrade.Name)
+	}
+	step := upgrade.Spec.Steps[0]
+	verify(t, pub, signature.ContainerMessage(step.Image, step.Command, step.Args), step.Signature)
+	prePull := upgrade.Spec.PrePull
+	verify(t, pub, signature.ContainerMessage(prePull.PullerImage, []string{signature.NodeServantEntry}, nodeservant.PrePullArgs(upgrade)), prePull.Signature)
+
+	data, err = Sign([]byte(daemonSetManifest), key)
+	if err != nil {
+		t.Fatalf("failed to sign DaemonSet, %v", err)
+	}
+	ds := &appsv1.DaemonSet{}
+	if err := yaml.Unmarshal(data, ds); err != nil {
+		t.Fatalf("failed to decode signed DaemonSet, %v", err)
+	}
+	payload, err := signature.TemplatePayload(ds.Namespace, ds.Name, &ds.Spec.Template)
+	if err != nil {
+		t.Fatalf("failed to get payload, %v", err)
+	}
+	verify(t, pub, payload, ds.Annotations[daemonpodupdater.OTAPayloadSignatureAnnotation])
+
+	if _, err := Sign([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n"), key); err == nil {
+		t.Errorf("expect error for unsupported kind")
+	}
+}
@@ -16,6 +16,8 @@ limitations under the License.
 
 package constants
 
+import "time"
+
 const (
 	Hostname                 = "/etc/hostname"
 	SysctlK8sConfig          = "/etc/sysctl.d/k8s.conf"
@@ -23,7 +25,10 @@ const (
 	KubeletConfigureDir      = "/etc/kubernetes"
 	KubeletWorkdir           = "/var/lib/kubelet"
 	YurtHubWorkdir           = "/var/lib/yurthub"
+	YurtHubCacheDir          = "/etc/kubernetes/cache"
 	OpenyurtDir              = "/var/lib/openyurt"
+	SealedCredentialUsedDir  = "/var/lib/openyurt/sealed-credentials"
+	DefaultUnsealKeyPath     = "/etc/openyurt/pki/device.key"
 	YurttunnelAgentWorkdir   = "/var/lib/yurttunnel-agent"
 	YurttunnelServerWorkdir  = "/var/lib/yurttunnel-server"
 	KubeConfigPath           = "/etc/kubernetes/kubelet.conf"
@@ -31,17 +36,28 @@ const (
 	KubeCniVersion           = "v0.8.0"
 	KubeletServiceFilepath   = "/etc/systemd/system/kubelet.service"
 	KubeletServiceConfPath   = "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf"
+	KubeletHoldConfPath      = "/etc/systemd/system/kubelet.service.d/20-yurtadm-hold.conf"
 	KubeletSvcPath           = "/usr/lib/systemd/system/kubelet.service.d/10-kubeadm.conf"
 	YurthubStaticPodFileName = "yurthub.yaml"
 	PauseImagePath           = "registry.cn-hangzhou.aliyuncs.com/google_containers/pause:3.2"
 	DefaultCertificatesDir   = "/etc/kubernetes/pki"
 	DefaultDockerCRISocket   = "/var/run/dockershim.sock"
+	DefaultDockerImageFsPath = "/var/lib/docker"
+	TPMDevice                = "/dev/tpmrm0"
 	YurthubYamlName          = "yurt-hub.yaml"
+	// DefaultContainerdImageFsPath is the image fs path used by the runtimes other than docker
+	DefaultContainerdImageFsPath = "/var/lib/containerd"
+	// YurtHubImageGCLowThreshold is the default percent of image fs usage which yurthub collects images to
+	YurtHubImageGCLowThreshold = 80
 	// ManifestsSubDirName defines directory name to store manifests
 	ManifestsSubDirName = "manifests"
 	// KubeletKubeConfigFileName defines the file name for the kubeconfig that the control-plane kubelet will use for talking
 	// to the API server
 	KubeletKubeConfigFileName = "kubelet.conf"
+	// KubeletConfigFileName defines the file name of kubelet config which is written by kubeadm
+	KubeletConfigFileName = "config.yaml"
+	// KubeadmFlagsEnvFileName defines the file name of kubelet flags which is written by kubeadm after kubelet config
+	KubeadmFlagsEnvFileName = "kubeadm-flags.env"
 	// KubeadmConfigConfigMap specifies in what ConfigMap in the kube-system namespace the `kubeadm init` configuration should be stored
 	KubeadmConfigConfigMap = "kubeadm-config"
 	// ClusterConfigurationConfigMapKey specifies in what ConfigMap key the cluster configuration should be stored
@@ -99,13 +115,69 @@ const (
 	YurtHubServerAddr = "yurthub-server-addr"
 	// ReuseCNIBin flag sets whether to reuse local CNI binaries or not.
 	ReuseCNIBin = "reuse-cni-bin"
+	// YurtHubImageGCHighThreshold flag sets the percent of image fs usage after which yurthub collects images instead of kubelet.
+	YurtHubImageGCHighThreshold = "yurthub-image-gc-high-threshold"
+	// YurtHubImageFsPath flag sets the path which images of container runtime are stored in.
+	YurtHubImageFsPath = "yurthub-image-fs-path"
+	// KubeletConfigPatch flag sets the path of KubeletConfiguration fragment merged into kubelet config.
+	KubeletConfigPatch = "kubelet-config-patch"
+	// Config flag sets the path of yurtadm configuration file.
+	Config = "config"
+	// DryRun flag sets whether to only output the changes that would be made.
+	DryRun = "dry-run"
+	// Provider flag sets the provisioning provider for fetching join parameters.
+	Provider = "provider"
+	// ProviderConfig flag sets the config of provisioning provider.
+	ProviderConfig = "provider-config"
+	// Verify flag sets whether to verify the node works after joining.
+	Verify = "verify"
+	// VerifyTimeout flag sets the timeout of each check in verify phase.
+	VerifyTimeout = "verify-timeout"
+	// SealedCredential flag sets the path of sealed credential used for joining instead of bootstrap token.
+	SealedCredential = "sealed-credential"
+	// UnsealKey flag sets the path of site or device private key for unsealing the sealed credential.
+	UnsealKey = "unseal-key"
+	// SealPublicKey flag sets the path of site or device public key which the credential is sealed to.
+	SealPublicKey = "public-key"
+	// SealTTL flag sets the duration that the sealed credential is valid for.
+	SealTTL = "ttl"
+	// YurtHubRootDir flag sets the root directory of yurthub.
+	YurtHubRootDir = "yurthub-root-dir"
+	// YurtHubDiskCachePath flag sets the disk cache path of yurthub.
+	YurtHubDiskCachePath = "yurthub-disk-cache-path"
+	// DiagnoseOutput flag sets the path of the support bundle generated by yurtadm diagnose.
+	DiagnoseOutput = "output"
+	// DiagnoseLogLines flag sets the number of log lines collected for each component.
+	DiagnoseLogLines = "log-lines"
+	// DiagnoseTimeout flag sets the timeout of each command or request issued by yurtadm diagnose.
+	DiagnoseTimeout = "timeout"
+	// BackupOutput flag sets the path of the backup archive generated by yurtadm backup.
+	BackupOutput = "output"
+	// BackupSigningKey flag sets the path of rsa private key which the backup archive is signed by.
+	BackupSigningKey = "signing-key"
+	// BackupVerifyKey flag sets the path of rsa public key which the signature of backup archive is verified by.
+	BackupVerifyKey = "verify-key"
+	// RestoreForce flag sets whether to replace the existing node state when restoring a backup archive.
+	RestoreForce = "force"
+	// SignFilename flag sets the path of PoolUpgrade or DaemonSet manifest which is signed by yurtadm sign.
+	SignFilename = "filename"
+	// SignKey flag sets the path of fleet ed25519 private key which the manifest is signed by.
+	SignKey = "signing-key"
+	// SignOutput flag sets the path of signed manifest generated by yurtadm sign.
+	SignOutput = "output"
 
 	ServerHealthzServer          = "127.0.0.1:10267"
+	YurtHubServerPort            = 10267
 	ServerHealthzURLPath         = "/v1/healthz"
 	DefaultOpenYurtImageRegistry = "registry.cn-hangzhou.aliyuncs.com/openyurt"
 	DefaultOpenYurtVersion       = "latest"
 	Yurthub                      = "yurthub"
 	DefaultYurtHubServerAddr     = "127.0.0.1"
+	DefaultDiagnoseLogLines      = 1000
+	DefaultProviderTimeout       = 2 * time.Minute
+	DefaultSealedCredentialTTL   = 24 * time.Hour
+	DefaultVerifyTimeout         = 5 * time.Minute
+	YurthubPodLogDirPattern      = "/var/log/pods/kube-system_yurt-hub-*"
 	DirMode                      = 0755
 	KubeletServiceContent        = `
 [Unit]
@@ -130,6 +202,13 @@ EnvironmentFile=-/var/lib/kubelet/kubeadm-flags.env
 EnvironmentFile=-/etc/default/kubelet
 ExecStart=
 ExecStart=/usr/bin/kubelet $KUBELET_KUBECONFIG_ARGS $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS $KUBELET_EXTRA_ARGS
+`
+
+	// KubeletHoldUnitConfig fails the start of kubelet until the drop-in is removed, systemd retries
+	// starting kubelet every RestartSec.
+	KubeletHoldUnitConfig = `
+[Service]
+ExecStartPre=/bin/sh -c 'echo "kubelet is held until yurtadm merges the kubelet config patch"; exit 1'
 `
 
 	KubeletConfForNode = `
@@ -178,6 +257,9 @@ nodeRegistration:
     {{- if .containerRuntimeEndpoint}}
     container-runtime-endpoint: {{.containerRuntimeEndpoint}}
     {{end}}
+    {{- if .imageGCHighThreshold}}
+    image-gc-high-threshold: "{{.imageGCHighThreshold}}"
+    {{end}}
 `
 
 	YurthubTemplate = `
@@ -202,6 +284,32 @@ spec:
     hostPath:
       path: /var/lib/kubelet/pki
       type: Directory
+  - name: cri-socket-dir
+    hostPath:
+      path: {{.criSocketDir}}
+      type: DirectoryOrCreate
+  - name: image-fs
+    hostPath:
+      path: {{.imageFsPath}}
+      type: DirectoryOrCreate
+  - name: openyurt-etc
+    hostPath:
+      path: /etc/openyurt
+      type: DirectoryOrCreate
+  - name: host-cgroup
+    hostPath:
+      path: /sys/fs/cgroup
+      type: Directory
+  - name: secrets-tmpfs
+    hostPath:
+      path: /run/yurthub
+      type: DirectoryOrCreate
+  {{- if .tpmDevice }}
+  - name: tpm
+    hostPath:
+      path: {{.tpmDevice}}
+      type: CharDevice
+  {{- end }}
   containers:
   - name: yurt-hub
     image: {{.image}}
@@ -213,12 +321,30 @@ spec:
       mountPath: /etc/kubernetes
     - name: pem-dir
       mountPath: /var/lib/kubelet/pki
+    - name: cri-socket-dir
+      mountPath: {{.criSocketDir}}
+    - name: image-fs
+      mountPath: {{.imageFsPath}}
+      readOnly: true
+    - name: openyurt-etc
+      mountPath: /etc/openyurt
+      readOnly: true
+    - name: host-cgroup
+      mountPath: /host/sys/fs/cgroup
+      readOnly: true
+    - name: secrets-tmpfs
+      mountPath: /run/yurthub
+    {{- if .tpmDevice }}
+    - name: tpm
+      mountPath: {{.tpmDevice}}
+    {{- end }}
     command:
     - yurthub
     - --v=2
     - --bind-address={{.yurthubServerAddr}}
     - --server-addr={{.kubernetesServerAddr}}
     - --node-name=$(NODE_NAME)
+    - --bpf-cgroup-path=/host/sys/fs/cgroup
     - --join-token={{.joinToken}}
     - --working-mode={{.workingMode}}
       {{if .enableDummyIf }}
@@ -230,6 +356,11 @@ spec:
       {{if .organizations }}
     - --hub-cert-organizations={{.organizations}}
       {{end}}
+      {{if .imageGCHighThreshold }}
+    - --image-gc-high-threshold={{.imageGCHighThreshold}}
+    - --image-gc-cri-socket={{.imageGCCRISocket}}
+    - --image-fs-path={{.imageFsPath}}
+      {{end}}
     livenessProbe:
       httpGet:
         host: {{.yurthubServerAddr}}
@@ -246,7 +377,7 @@ spec:
         memory: 300Mi
     securityContext:
       capabilities:
-        add: ["NET_ADMIN", "NET_RAW"]
+        add: ["NET_ADMIN", "NET_RAW", "BPF", "SYS_ADMIN"]
     env:
     - name: NODE_NAME
       valueFrom:
@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Kind is the kind of node resource that a change applies to.
+type Kind string
+
+// Operation is the operation of a change.
+type Operation string
+
+const (
+	KindFile      Kind = "File"
+	KindDirectory Kind = "Directory"
+	KindManifest  Kind = "Manifest"
+	KindService   Kind = "Service"
+	KindNetwork   Kind = "Network"
+	KindSystem    Kind = "System"
+	KindCommand   Kind = "Command"
+
+	OperationWrite    Operation = "Write"
+	OperationRemove   Operation = "Remove"
+	OperationDownload Operation = "Download"
+	OperationExtract  Operation = "Extract"
+	OperationRun      Operation = "Run"
+	OperationDisable  Operation = "Disable"
+	OperationSkip     Operation = "Skip"
+
+	// MaskedValue replaces the secrets in the printed plan.
+	MaskedValue = "******"
+)
+
+// Change describes a single change that yurtadm would make on the node.
+type Change struct {
+	Kind      Kind      `json:"kind"`
+	Operation Operation `json:"operation"`
+	Path      string    `json:"path,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Mode      string    `json:"mode,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Command   []string  `json:"command,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Plan is the ordered list of changes that would be made by a yurtadm command,
+// it is printed in json format so provisioning pipelines can audit it before execution.
+type Plan struct {
+	Command string   `json:"command"`
+	Changes []Change `json:"changes"`
+	secrets []string
+}
+
+// NewPlan returns an empty plan for the specified command.
+func NewPlan(command string) *Plan {
+	return &Plan{
+		Command: command,
+		Changes: make([]Change, 0),
+	}
+}
+
+// Add appends changes to the plan.
+func (p *Plan) Add(changes ...Change) {
+	p.Changes = append(p.Changes, changes...)
+}
+
+// WriteFile records that a file would be written with the content.
+func (p *Plan) WriteFile(kind Kind, path string, mode os.FileMode, content string) {
+	p.Add(Change{Kind: kind, Operation: OperationWrite, Path: path, Mode: fmt.Sprintf("%04o", mode), Content: content})
+}
+
+// Remove records that a file or directory would be removed.
+func (p *Plan) Remove(kind Kind, path string) {
+	p.Add(Change{Kind: kind, Operation: OperationRemove, Path: path})
+}
+
+// Run records that a command would be executed.
+func (p *Plan) Run(command ...string) {
+	p.Add(Change{Kind: KindCommand, Operation: OperationRun, Command: command})
+}
+
+// Mask records the secrets(like bootstrap token) which are masked when the plan is printed,
+// so the plan can be audited without leaking the secrets into logs of provisioning pipelines.
+func (p *Plan) Mask(secrets ...string) {
+	for _, secret := range secrets {
+		if len(secret) != 0 {
+			p.secrets = append(p.secrets, secret)
+		}
+	}
+}
+
+// masked returns a copy of plan in which the secrets are replaced with MaskedValue.
+func (p *Plan) masked() *Plan {
+	if len(p.secrets) == 0 {
+		return p
+	}
+	pairs := make([]string, 0, 2*len(p.secrets))
+	for _, secret := range p.secrets {
+		pairs = append(pairs, secret, MaskedValue)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	masked := &Plan{Command: p.Command, Changes: make([]Change, 0, len(p.Changes))}
+	for _, change := range p.Changes {
+		change.Source = replacer.Replace(change.Source)
+		change.Content = replacer.Replace(change.Content)
+		change.Reason = replacer.Replace(change.Reason)
+		if len(change.Command) != 0 {
+			command := make([]string, len(change.Command))
+			for i := range change.Command {
+				command[i] = replacer.Replace(change.Command[i])
+			}
+			change.Command = command
+		}
+		masked.Changes = append(masked.Changes, change)
+	}
+	return masked
+}
+
+// Print writes the plan into w in json format, the secrets are masked.
+func (p *Plan) Print(w io.Writer) error {
+	b, err := json.MarshalIndent(p.masked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanPrint(t *testing.T) {
+	plan := NewPlan("join")
+	plan.Remove(KindDirectory, "/etc/kubernetes/manifests")
+	plan.WriteFile(KindNetwork, "/proc/sys/net/ipv4/ip_forward", 0644, "1")
+	plan.Run("kubeadm", "join", "--config=/var/lib/kubelet/kubeadm-join.conf")
+
+	var buf bytes.Buffer
+	if err := plan.Print(&buf); err != nil {
+		t.Fatalf("failed to print plan, %v", err)
+	}
+
+	got := &Plan{}
+	if err := json.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatalf("plan output is not valid json, %v", err)
+	}
+
+	expect := &Plan{
+		Command: "join",
+		Changes: []Change{
+			{Kind: KindDirectory, Operation: OperationRemove, Path: "/etc/kubernetes/manifests"},
+			{Kind: KindNetwork, Operation: OperationWrite, Path: "/proc/sys/net/ipv4/ip_forward", Mode: "0644", Content: "1"},
+			{Kind: KindCommand, Operation: OperationRun, Command: []string{"kubeadm", "join", "--config=/var/lib/kubelet/kubeadm-join.conf"}},
+		},
+	}
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("expect plan %v, but got %v", expect, got)
+	}
+}
+
+func TestPlanPrintMasksSecrets(t *testing.T) {
+	token := "abcdef.0123456789abcdef"
+	plan := NewPlan("join")
+	plan.Mask(token, "")
+	plan.WriteFile(KindManifest, "/etc/kubernetes/manifests/yurt-hub.yaml", 0600, "--join-token="+token)
+	plan.Run("kubeadm", "join", "--token", token)
+
+	var buf bytes.Buffer
+	if err := plan.Print(&buf); err != nil {
+		t.Fatalf("failed to print plan, %v", err)
+	}
+	if strings.Contains(buf.String(), token) {
+		t.Errorf("expect token masked, but got %s", buf.String())
+	}
+
+	got := &Plan{}
+	if err := json.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatalf("plan output is not valid json, %v", err)
+	}
+	if got.Changes[0].Content != "--join-token="+MaskedValue || got.Changes[1].Command[3] != MaskedValue {
+		t.Errorf("expect token replaced with %s, but got %v", MaskedValue, got.Changes)
+	}
+	if plan.Changes[1].Command[3] != token {
+		t.Errorf("expect plan not changed by printing, but got %v", plan.Changes[1].Command)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/edgenode"
+)
+
+const (
+	kubeletConfigAPIVersion = "kubelet.config.k8s.io/v1beta1"
+	kubeletConfigKind       = "KubeletConfiguration"
+)
+
+// LoadKubeletConfigPatch reads a KubeletConfiguration fragment in yaml or json format, and returns
+// it in json format after validating it against the kubelet config schema.
+func LoadKubeletConfigPatch(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kubelet config patch %s, %w", path, err)
+	}
+	patch, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert kubelet config patch %s to json, %w", path, err)
+	}
+	if _, err := decodeKubeletConfiguration(patch, true); err != nil {
+		return nil, fmt.Errorf("invalid kubelet config patch %s, %w", path, err)
+	}
+	return patch, nil
+}
+
+// MergeKubeletConfig merges the json patch into the kubelet config(yaml format) generated by kubeadm,
+// fields in patch override the generated ones and maps(like evictionHard) are merged by keys.
+func MergeKubeletConfig(base, patch []byte) ([]byte, error) {
+	baseJSON, err := yaml.YAMLToJSON(base)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := jsonpatch.MergePatch(baseJSON, patch)
+	if err != nil {
+		return nil, fmt.Errorf("could not merge kubelet config patch, %w", err)
+	}
+	if _, err := decodeKubeletConfiguration(merged, false); err != nil {
+		return nil, fmt.Errorf("invalid merged kubelet config, %w", err)
+	}
+	return yaml.JSONToYAML(merged)
+}
+
+// HoldKubelet prevents kubelet from starting until ApplyKubeletConfigPatch is done, so kubelet started by
+// kubeadm join never runs with the unpatched config. The kubelet config files of the previous join are
+// removed, so only the files written by the coming kubeadm join are patched.
+func HoldKubelet() error {
+	for _, name := range []string{constants.KubeletConfigFileName, constants.KubeadmFlagsEnvFileName} {
+		if err := os.Remove(filepath.Join(constants.KubeletWorkdir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(constants.KubeletHoldConfPath), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(constants.KubeletHoldConfPath, []byte(constants.KubeletHoldUnitConfig), 0600); err != nil {
+		return err
+	}
+	klog.Infof("kubelet is held by %s until kubelet config patch is merged", constants.KubeletHoldConfPath)
+	return edgenode.Exec(exec.Command("bash", "-c", constants.DaemonReload))
+}
+
+// ReleaseKubelet removes the hold of kubelet left by an interrupted join, it makes no change if kubelet is not held.
+func ReleaseKubelet() error {
+	if err := os.Remove(constants.KubeletHoldConfPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return edgenode.Exec(exec.Command("bash", "-c", constants.DaemonReload))
+}
+
+// ApplyKubeletConfigPatch waits for kubeadm to write the kubelet config, merges the json patch into the
+// config, and then releases and starts kubelet which is held by HoldKubelet. An error is returned if
+// the config is not written before stopCh is closed.
+func ApplyKubeletConfigPatch(patch []byte, stopCh <-chan struct{}) error {
+	// kubeadm writes the kubelet flags after the kubelet config, so the config is complete once the flags exist
+	flagsPath := filepath.Join(constants.KubeletWorkdir, constants.KubeadmFlagsEnvFileName)
+	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		_, err := os.Stat(flagsPath)
+		return err == nil, nil
+	}, stopCh); err != nil {
+		// kubeadm may exit right after writing the config
+		if _, err := os.Stat(flagsPath); err != nil {
+			return fmt.Errorf("kubelet config is not written before kubeadm exits, %w", err)
+		}
+	}
+
+	kubeletConfigPath := filepath.Join(constants.KubeletWorkdir, constants.KubeletConfigFileName)
+	info, err := os.Stat(kubeletConfigPath)
+	if err != nil {
+		return err
+	}
+	base, err := os.ReadFile(kubeletConfigPath)
+	if err != nil {
+		return err
+	}
+	merged, err := MergeKubeletConfig(base, patch)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(kubeletConfigPath, merged, info.Mode()); err != nil {
+		return err
+	}
+	klog.Infof("kubelet config patch is merged into %s", kubeletConfigPath)
+
+	if err := ReleaseKubelet(); err != nil {
+		return err
+	}
+	return edgenode.Exec(exec.Command("bash", "-c", constants.RestartKubeletSvc))
+}
+
+// decodeKubeletConfiguration decodes json into KubeletConfiguration strictly, so unknown or mistyped
+// fields are rejected. apiVersion and kind are optional for a fragment.
+func decodeKubeletConfiguration(content []byte, fragment bool) (*kubeletconfigv1beta1.KubeletConfiguration, error) {
+	cfg := &kubeletconfigv1beta1.KubeletConfiguration{}
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, err
+	}
+	if fragment && len(cfg.APIVersion) == 0 && len(cfg.Kind) == 0 {
+		return cfg, nil
+	}
+	if cfg.APIVersion != kubeletConfigAPIVersion || cfg.Kind != kubeletConfigKind {
+		return nil, fmt.Errorf("expect %s %s, but got %s %s", kubeletConfigAPIVersion, kubeletConfigKind, cfg.APIVersion, cfg.Kind)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+const generatedKubeletConfig = `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+cgroupDriver: systemd
+clusterDNS:
+- 10.96.0.10
+evictionHard:
+  memory.available: 100Mi
+imageGCHighThresholdPercent: 85
+`
+
+func TestMergeKubeletConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		expectErr bool
+		check     func(cfg *kubeletconfigv1beta1.KubeletConfiguration) bool
+	}{
+		{
+			name: "merge eviction thresholds and image gc",
+			patch: `evictionHard:
+  nodefs.available: 5%
+imageGCHighThresholdPercent: 70
+systemReserved:
+  cpu: 100m
+`,
+			check: func(cfg *kubeletconfigv1beta1.KubeletConfiguration) bool {
+				return cfg.EvictionHard["memory.available"] == "100Mi" &&
+					cfg.EvictionHard["nodefs.available"] == "5%" &&
+					*cfg.ImageGCHighThresholdPercent == 70 &&
+					cfg.SystemReserved["cpu"] == "100m" &&
+					cfg.CgroupDriver == "systemd"
+			},
+		},
+		{
+			name:      "unknown field",
+			patch:     "evictionHardd:\n  nodefs.available: 5%\n",
+			expectErr: true,
+		},
+		{
+			name:      "mistyped field",
+			patch:     "imageGCHighThresholdPercent: high\n",
+			expectErr: true,
+		},
+		{
+			name:      "wrong kind",
+			patch:     "apiVersion: kubelet.config.k8s.io/v1beta1\nkind: KubeProxyConfiguration\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "patch.yaml")
+			if err := os.WriteFile(path, []byte(tt.patch), 0600); err != nil {
+				t.Fatalf("failed to write patch, %v", err)
+			}
+			patch, err := LoadKubeletConfigPatch(path)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expect error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load patch, %v", err)
+			}
+
+			merged, err := MergeKubeletConfig([]byte(generatedKubeletConfig), patch)
+			if err != nil {
+				t.Fatalf("failed to merge, %v", err)
+			}
+			cfg := &kubeletconfigv1beta1.KubeletConfiguration{}
+			if err := yaml.Unmarshal(merged, cfg); err != nil {
+				t.Fatalf("failed to unmarshal merged config, %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected merged config %s", string(merged))
+			}
+		})
+	}
+}
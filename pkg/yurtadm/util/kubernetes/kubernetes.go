@@ -35,6 +35,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog/v2"
 
@@ -46,6 +47,7 @@ import (
 	"github.com/openyurtio/openyurt/pkg/yurtadm/cmd/join/joindata"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/util"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/dryrun"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/util/edgenode"
 )
 
@@ -104,15 +106,36 @@ func RunJobAndCleanup(cliSet *kubernetes.Clientset, job *batchv1.Job, timeout, p
 
 // CheckAndInstallKubelet install kubelet and kubernetes-cni, skip install if they exist.
 func CheckAndInstallKubelet(kubernetesResourceServer, clusterVersion string) error {
-	if strings.Contains(clusterVersion, "-") {
-		clusterVersion = strings.Split(clusterVersion, "-")[0]
+	clusterVersion = trimClusterVersion(clusterVersion)
+	klog.Infof("Check and install kubelet %s", clusterVersion)
+	kubeletExist, err := IsKubeletInstalled(clusterVersion)
+	if err != nil {
+		return err
 	}
 
-	klog.Infof("Check and install kubelet %s", clusterVersion)
+	if !kubeletExist {
+		//download and install kubelet
+		packageUrl := fmt.Sprintf(constants.KubeletUrlFormat, kubernetesResourceServer, clusterVersion, runtime.GOARCH)
+		savePath := fmt.Sprintf("%s/kubelet", constants.TmpDownloadDir)
+		klog.V(1).Infof("Download kubelet from: %s", packageUrl)
+		if err := util.DownloadFile(packageUrl, savePath, 3); err != nil {
+			return fmt.Errorf("download kubelet fail: %w", err)
+		}
+		if err := edgenode.CopyFile(savePath, "/usr/bin/kubelet", constants.DirMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsKubeletInstalled checks whether kubelet with the same minor version of cluster is installed,
+// an error is returned if the installed kubelet is inconsistent with cluster version.
+func IsKubeletInstalled(clusterVersion string) (bool, error) {
+	clusterVersion = trimClusterVersion(clusterVersion)
 	if clusterVersion == "" {
-		return ErrClusterVersionEmpty
+		return false, ErrClusterVersionEmpty
 	}
-	kubeletExist := false
 	if _, err := exec.LookPath("kubelet"); err == nil {
 		if b, err := exec.Command("kubelet", "--version").CombinedOutput(); err == nil {
 			kubeletVersion := strings.Split(string(b), " ")[1]
@@ -120,37 +143,31 @@ func CheckAndInstallKubelet(kubernetesResourceServer, clusterVersion string) err
 			klog.Infof("kubelet --version: %s", kubeletVersion)
 			v1, err := version.NewVersion(kubeletVersion)
 			if err != nil {
-				return err
+				return false, err
 			}
 			v2, err := version.NewVersion(clusterVersion)
 			if err != nil {
-				return err
+				return false, err
 			}
 			s1 := v1.Segments()
 			s2 := v2.Segments()
 			if s1[0] == s2[0] && s1[1] == s2[1] {
 				klog.Infof("Kubelet %s already exist, skip install.", clusterVersion)
-				kubeletExist = true
+				return true, nil
 			} else {
-				return fmt.Errorf("The existing kubelet version %s of the node is inconsistent with cluster version %s, please clean it. ", kubeletVersion, clusterVersion)
+				return false, fmt.Errorf("The existing kubelet version %s of the node is inconsistent with cluster version %s, please clean it. ", kubeletVersion, clusterVersion)
 			}
 		}
 	}
+	return false, nil
+}
 
-	if !kubeletExist {
-		//download and install kubelet
-		packageUrl := fmt.Sprintf(constants.KubeletUrlFormat, kubernetesResourceServer, clusterVersion, runtime.GOARCH)
-		savePath := fmt.Sprintf("%s/kubelet", constants.TmpDownloadDir)
-		klog.V(1).Infof("Download kubelet from: %s", packageUrl)
-		if err := util.DownloadFile(packageUrl, savePath, 3); err != nil {
-			return fmt.Errorf("download kubelet fail: %w", err)
-		}
-		if err := edgenode.CopyFile(savePath, "/usr/bin/kubelet", constants.DirMode); err != nil {
-			return err
-		}
+// trimClusterVersion removes the suffix of cluster version, like v1.22.3-aliyun.1
+func trimClusterVersion(clusterVersion string) string {
+	if strings.Contains(clusterVersion, "-") {
+		clusterVersion = strings.Split(clusterVersion, "-")[0]
 	}
-
-	return nil
+	return clusterVersion
 }
 
 // CheckAndInstallKubernetesCni install kubernetes-cni, skip install if they exist.
@@ -195,56 +212,63 @@ type clientVersion struct {
 
 // CheckAndInstallKubeadm install kubeadm, skip install if it exist.
 func CheckAndInstallKubeadm(kubernetesResourceServer, clusterVersion string) error {
-	if strings.Contains(clusterVersion, "-") {
-		clusterVersion = strings.Split(clusterVersion, "-")[0]
+	clusterVersion = trimClusterVersion(clusterVersion)
+	klog.Infof("Check and install kubeadm %s", clusterVersion)
+	kubeadmExist, err := IsKubeadmInstalled(clusterVersion)
+	if err != nil {
+		return err
 	}
 
-	klog.Infof("Check and install kubeadm %s", clusterVersion)
+	if !kubeadmExist {
+		// download and install kubeadm
+		packageUrl := fmt.Sprintf(constants.KubeadmUrlFormat, kubernetesResourceServer, clusterVersion, runtime.GOARCH)
+		savePath := fmt.Sprintf("%s/kubeadm", TmpDownloadDir)
+		klog.V(1).Infof("Download kubeadm from %s", packageUrl)
+		if err := util.DownloadFile(packageUrl, savePath, 3); err != nil {
+			return fmt.Errorf("download kubeadm fail: %w", err)
+		}
+		if err := edgenode.CopyFile(savePath, "/usr/bin/kubeadm", constants.DirMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsKubeadmInstalled checks whether kubeadm with the same minor version of cluster is installed,
+// an error is returned if the installed kubeadm is inconsistent with cluster version.
+func IsKubeadmInstalled(clusterVersion string) (bool, error) {
+	clusterVersion = trimClusterVersion(clusterVersion)
 	if clusterVersion == "" {
-		return ErrClusterVersionEmpty
+		return false, ErrClusterVersionEmpty
 	}
-	kubeadmExist := false
 	if _, err := exec.LookPath("kubeadm"); err == nil {
 		if b, err := exec.Command("kubeadm", "version", "-o", "json").CombinedOutput(); err == nil {
 			klog.V(1).InfoS("kubeadm", "version", string(b))
 			info := kubeadmVersion{}
 			if err := json.Unmarshal(b, &info); err != nil {
-				return fmt.Errorf("can't get the existing kubeadm version: %w", err)
+				return false, fmt.Errorf("can't get the existing kubeadm version: %w", err)
 			}
 			kubeadmVersion := info.ClientVersion.GitVersion
 			v1, err := version.NewVersion(kubeadmVersion)
 			if err != nil {
-				return err
+				return false, err
 			}
 			v2, err := version.NewVersion(clusterVersion)
 			if err != nil {
-				return err
+				return false, err
 			}
 			s1 := v1.Segments()
 			s2 := v2.Segments()
 			if s1[0] == s2[0] && s1[1] == s2[1] {
 				klog.Infof("Kubeadm %s already exist, skip install.", clusterVersion)
-				kubeadmExist = true
+				return true, nil
 			} else {
-				return fmt.Errorf("The existing kubeadm version %s of the node is inconsistent with cluster version %s, please clean it. ", kubeadmVersion, clusterVersion)
+				return false, fmt.Errorf("The existing kubeadm version %s of the node is inconsistent with cluster version %s, please clean it. ", kubeadmVersion, clusterVersion)
 			}
 		}
 	}
-
-	if !kubeadmExist {
-		// download and install kubeadm
-		packageUrl := fmt.Sprintf(constants.KubeadmUrlFormat, kubernetesResourceServer, clusterVersion, runtime.GOARCH)
-		savePath := fmt.Sprintf("%s/kubeadm", TmpDownloadDir)
-		klog.V(1).Infof("Download kubeadm from %s", packageUrl)
-		if err := util.DownloadFile(packageUrl, savePath, 3); err != nil {
-			return fmt.Errorf("download kubeadm fail: %w", err)
-		}
-		if err := edgenode.CopyFile(savePath, "/usr/bin/kubeadm", constants.DirMode); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return false, nil
 }
 
 // SetKubeletService configure kubelet service.
@@ -364,6 +388,19 @@ func SetKubeadmJoinConfig(data joindata.YurtJoinData) error {
 		}
 	}
 
+	kubeadmJoinTemplate, err := RenderKubeadmJoinConfig(data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(kubeadmJoinConfigFilePath, []byte(kubeadmJoinTemplate), constants.DirMode); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RenderKubeadmJoinConfig returns the content of JoinConfiguration that kubeadm will use for joining.
+func RenderKubeadmJoinConfig(data joindata.YurtJoinData) (string, error) {
 	nodeReg := data.NodeRegistration()
 	KubeadmJoinDiscoveryFilePath := filepath.Join(constants.KubeletWorkdir, constants.KubeadmJoinDiscoveryFileName)
 	ctx := map[string]interface{}{
@@ -381,14 +418,19 @@ func SetKubeadmJoinConfig(data joindata.YurtJoinData) error {
 		ctx["containerRuntime"] = "remote"
 		ctx["containerRuntimeEndpoint"] = nodeReg.CRISocket
 	}
+	// kubelet image gc is disabled on edge node, yurthub collects images instead, so the images of
+	// cached pods are not removed while the cloud is unreachable.
+	if data.YurtHubImageGCHighThreshold() != 0 && nodeReg.WorkingMode == constants.EdgeNode {
+		ctx["imageGCHighThreshold"] = "100"
+	}
 
 	v1, err := version.NewVersion(data.KubernetesVersion())
 	if err != nil {
-		return err
+		return "", err
 	}
 	v2, err := version.NewVersion("v1.22.0")
 	if err != nil {
-		return err
+		return "", err
 	}
 	// This is to adapt the apiVersion of JoinConfiguration
 	// https://kubernetes.io/docs/reference/config-api/kubeadm-config.v1beta3/
@@ -398,15 +440,7 @@ func SetKubeadmJoinConfig(data joindata.YurtJoinData) error {
 		ctx["apiVersion"] = "kubeadm.k8s.io/v1beta3"
 	}
 
-	kubeadmJoinTemplate, err := templates.SubsituteTemplate(constants.KubeadmJoinConf, ctx)
-	if err != nil {
-		return err
-	}
-
-	if err := os.WriteFile(kubeadmJoinConfigFilePath, []byte(kubeadmJoinTemplate), constants.DirMode); err != nil {
-		return err
-	}
-	return nil
+	return templates.SubsituteTemplate(constants.KubeadmJoinConf, ctx)
 }
 
 // constructNodeLabels make up node labels string
@@ -434,18 +468,11 @@ func constructNodeLabels(nodeLabels map[string]string, workingMode, edgeWorkerLa
 }
 
 func SetDiscoveryConfig(data joindata.YurtJoinData) error {
-	cfg, err := token.RetrieveValidatedConfigInfo(nil, &token.BootstrapData{
-		ServerAddr:   data.ServerAddr(),
-		JoinToken:    data.JoinToken(),
-		CaCertHashes: data.CaCertHashes(),
-	})
+	cfg, err := RetrieveDiscoveryConfig(data)
 	if err != nil {
 		return err
 	}
 
-	cluster := kubeconfigutil.GetClusterFromKubeConfig(cfg)
-	cluster.Server = fmt.Sprintf("https://%s", strings.Split(data.ServerAddr(), ",")[0])
-
 	discoveryConfigFilePath := filepath.Join(constants.KubeletWorkdir, constants.KubeadmJoinDiscoveryFileName)
 	if err := kubeconfigutil.WriteToDisk(discoveryConfigFilePath, cfg); err != nil {
 		return pkgerrors.Wrap(err, "couldn't save discovery.conf to disk")
@@ -454,6 +481,22 @@ func SetDiscoveryConfig(data joindata.YurtJoinData) error {
 	return nil
 }
 
+// RetrieveDiscoveryConfig returns the validated cluster info that kubeadm will use as --discovery-file.
+func RetrieveDiscoveryConfig(data joindata.YurtJoinData) (*clientcmdapi.Config, error) {
+	cfg, err := token.RetrieveValidatedConfigInfo(nil, &token.BootstrapData{
+		ServerAddr:   data.ServerAddr(),
+		JoinToken:    data.JoinToken(),
+		CaCertHashes: data.CaCertHashes(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := kubeconfigutil.GetClusterFromKubeConfig(cfg)
+	cluster.Server = fmt.Sprintf("https://%s", strings.Split(data.ServerAddr(), ",")[0])
+	return cfg, nil
+}
+
 // RetrieveBootstrapConfig get clientcmdapi config by bootstrap token
 func RetrieveBootstrapConfig(data joindata.YurtJoinData) (*clientcmdapi.Config, error) {
 	cfg, err := token.RetrieveValidatedConfigInfo(nil, &token.BootstrapData{
@@ -475,3 +518,71 @@ func RetrieveBootstrapConfig(data joindata.YurtJoinData) (*clientcmdapi.Config,
 		data.JoinToken(),
 	), nil
 }
+
+// PlanKubernetesComponents records the changes made by CheckAndInstallKubelet, CheckAndInstallKubeadm and
+// CheckAndInstallKubernetesCni into plan without installing anything.
+func PlanKubernetesComponents(plan *dryrun.Plan, data joindata.YurtJoinData) error {
+	clusterVersion := trimClusterVersion(data.KubernetesVersion())
+	for _, component := range []struct {
+		name        string
+		urlFormat   string
+		isInstalled func(string) (bool, error)
+	}{
+		{name: "kubelet", urlFormat: constants.KubeletUrlFormat, isInstalled: IsKubeletInstalled},
+		{name: "kubeadm", urlFormat: constants.KubeadmUrlFormat, isInstalled: IsKubeadmInstalled},
+	} {
+		installed, err := component.isInstalled(clusterVersion)
+		if err != nil {
+			return err
+		}
+		binPath := filepath.Join("/usr/bin", component.name)
+		if installed {
+			plan.Add(dryrun.Change{Kind: dryrun.KindFile, Operation: dryrun.OperationSkip, Path: binPath,
+				Reason: fmt.Sprintf("%s %s already exists", component.name, clusterVersion)})
+			continue
+		}
+		plan.Add(dryrun.Change{Kind: dryrun.KindFile, Operation: dryrun.OperationDownload, Path: binPath,
+			Mode:   fmt.Sprintf("%04o", constants.DirMode),
+			Source: fmt.Sprintf(component.urlFormat, data.KubernetesResourceServer(), clusterVersion, runtime.GOARCH)})
+	}
+
+	if data.ReuseCNIBin() {
+		plan.Add(dryrun.Change{Kind: dryrun.KindDirectory, Operation: dryrun.OperationSkip, Path: constants.KubeCniDir,
+			Reason: "reuse local CNI binaries"})
+		return nil
+	}
+	cniUrl := fmt.Sprintf(constants.CniUrlFormat, constants.KubeCniVersion, runtime.GOARCH, constants.KubeCniVersion)
+	savePath := fmt.Sprintf("%s/cni-plugins-linux-%s-%s.tgz", constants.TmpDownloadDir, runtime.GOARCH, constants.KubeCniVersion)
+	if _, err := os.Stat(savePath); errors.Is(err, os.ErrNotExist) {
+		plan.Add(dryrun.Change{Kind: dryrun.KindFile, Operation: dryrun.OperationDownload, Path: savePath, Source: cniUrl})
+	}
+	plan.Add(dryrun.Change{Kind: dryrun.KindDirectory, Operation: dryrun.OperationExtract, Path: constants.KubeCniDir, Source: savePath})
+	return nil
+}
+
+// PlanKubeletConfigs records the changes made by SetKubeletService, SetKubeletUnitConfig and SetKubeletConfigForNode into plan.
+func PlanKubeletConfigs(plan *dryrun.Plan) {
+	plan.WriteFile(dryrun.KindService, constants.KubeletServiceFilepath, 0644, constants.KubeletServiceContent)
+	plan.WriteFile(dryrun.KindService, constants.KubeletServiceConfPath, 0600, constants.KubeletUnitConfig)
+	plan.WriteFile(dryrun.KindFile, filepath.Join(constants.KubeletConfigureDir, constants.KubeletKubeConfigFileName), constants.DirMode, constants.KubeletConfForNode)
+}
+
+// PlanKubeadmJoinConfigs records the changes made by SetDiscoveryConfig and SetKubeadmJoinConfig into plan.
+func PlanKubeadmJoinConfigs(plan *dryrun.Plan, data joindata.YurtJoinData) error {
+	cfg, err := RetrieveDiscoveryConfig(data)
+	if err != nil {
+		return err
+	}
+	discoveryConfig, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return err
+	}
+	plan.WriteFile(dryrun.KindFile, filepath.Join(constants.KubeletWorkdir, constants.KubeadmJoinDiscoveryFileName), 0600, string(discoveryConfig))
+
+	joinConfig, err := RenderKubeadmJoinConfig(data)
+	if err != nil {
+		return err
+	}
+	plan.WriteFile(dryrun.KindFile, filepath.Join(constants.KubeletWorkdir, constants.KubeadmJoinConfigFileName), constants.DirMode, joinConfig)
+	return nil
+}
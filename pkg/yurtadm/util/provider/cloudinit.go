@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	CloudInitProviderName = "cloud-init"
+	// DefaultCloudInitParametersPath is the default path of join parameters file, it is
+	// usually written by write_files module of cloud-init user data.
+	DefaultCloudInitParametersPath = "/run/openyurt/join-parameters.yaml"
+)
+
+// cloudInitProvider reads join parameters from a yaml or json file which is prepared by cloud-init.
+type cloudInitProvider struct {
+	path string
+}
+
+func newCloudInitProvider(config string) (Provider, error) {
+	if len(config) == 0 {
+		config = DefaultCloudInitParametersPath
+	}
+	return &cloudInitProvider{path: config}, nil
+}
+
+func (p *cloudInitProvider) Name() string {
+	return CloudInitProviderName
+}
+
+func (p *cloudInitProvider) FetchJoinParameters(_ context.Context) (*JoinParameters, error) {
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read join parameters file %s, %w", p.path, err)
+	}
+	return decodeJoinParameters(content)
+}
+
+// decodeJoinParameters decodes join parameters in yaml or json format.
+func decodeJoinParameters(content []byte) (*JoinParameters, error) {
+	params := &JoinParameters{}
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), len(content)).Decode(params); err != nil {
+		return nil, fmt.Errorf("could not decode join parameters, %w", err)
+	}
+	return params, nil
+}
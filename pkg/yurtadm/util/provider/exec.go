@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	ExecProviderName = "exec"
+)
+
+// execProvider runs an executable plugin(usually shipped by vendor OS agents) which prints
+// join parameters in yaml or json format to stdout.
+type execProvider struct {
+	command []string
+}
+
+func newExecProvider(config string) (Provider, error) {
+	command := strings.Fields(config)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("the executable of %s provider is not specified", ExecProviderName)
+	}
+	return &execProvider{command: command}, nil
+}
+
+func (p *execProvider) Name() string {
+	return ExecProviderName
+}
+
+func (p *execProvider) FetchJoinParameters(ctx context.Context) (*JoinParameters, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("provider plugin %s failed, %w, stderr: %s", p.command[0], err, stderr.String())
+	}
+	return decodeJoinParameters(stdout.Bytes())
+}
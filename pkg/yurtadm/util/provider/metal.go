@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	MetalProviderName = "metal"
+	// DefaultKernelCmdlinePath is the path of kernel command line, which iPXE scripts append parameters to.
+	DefaultKernelCmdlinePath = "/proc/cmdline"
+	// kernelCmdlinePrefix is the prefix of openyurt parameters in kernel command line, like openyurt.token=xxx
+	kernelCmdlinePrefix = "openyurt."
+	// kernelCmdlineURLKey specifies the url of site provisioning server which serves the join parameters,
+	// the parameters in kernel command line take precedence over the parameters from url.
+	kernelCmdlineURLKey = "url"
+)
+
+// metalProvider reads join parameters from kernel command line which is set by iPXE/metal
+// provisioning systems, and optionally from the provisioning server specified by openyurt.url.
+type metalProvider struct {
+	cmdlinePath string
+	client      *http.Client
+}
+
+func newMetalProvider(config string) (Provider, error) {
+	if len(config) == 0 {
+		config = DefaultKernelCmdlinePath
+	}
+	return &metalProvider{
+		cmdlinePath: config,
+		client:      &http.Client{},
+	}, nil
+}
+
+func (p *metalProvider) Name() string {
+	return MetalProviderName
+}
+
+func (p *metalProvider) FetchJoinParameters(ctx context.Context) (*JoinParameters, error) {
+	content, err := os.ReadFile(p.cmdlinePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kernel command line %s, %w", p.cmdlinePath, err)
+	}
+
+	args := parseKernelCmdline(string(content))
+	params := &JoinParameters{}
+	if url, ok := args[kernelCmdlineURLKey]; ok {
+		if params, err = p.fetchFromURL(ctx, url); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range args {
+		switch key {
+		case "server-addr":
+			params.ServerAddr = value
+		case "token":
+			params.Token = value
+		case "discovery-token-ca-cert-hash":
+			params.CaCertHashes = strings.Split(value, ",")
+		case "node-name":
+			params.NodeName = value
+		case "node-type":
+			params.NodeType = value
+		case "node-labels":
+			if params.NodeLabels == nil {
+				params.NodeLabels = make(map[string]string)
+			}
+			for _, label := range strings.Split(value, ",") {
+				if kv := strings.SplitN(label, "=", 2); len(kv) == 2 {
+					params.NodeLabels[kv[0]] = kv[1]
+				}
+			}
+		case "organizations":
+			params.Organizations = value
+		}
+	}
+	return params, nil
+}
+
+func (p *metalProvider) fetchFromURL(ctx context.Context, url string) (*JoinParameters, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch join parameters from %s, %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch join parameters from %s, status: %s", url, resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJoinParameters(content)
+}
+
+// parseKernelCmdline returns the openyurt parameters in kernel command line with prefix trimmed.
+func parseKernelCmdline(cmdline string) map[string]string {
+	args := make(map[string]string)
+	for _, field := range strings.Fields(cmdline) {
+		if !strings.HasPrefix(field, kernelCmdlinePrefix) {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(field, kernelCmdlinePrefix), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		args[kv[0]] = kv[1]
+	}
+	return args
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// JoinParameters are the parameters for joining a node that a provider fetches from site infrastructure.
+// Empty fields are ignored, so a provider only needs to fill in what it knows.
+type JoinParameters struct {
+	ServerAddr    string            `json:"serverAddr,omitempty"`
+	Token         string            `json:"token,omitempty"`
+	CaCertHashes  []string          `json:"caCertHashes,omitempty"`
+	NodeName      string            `json:"nodeName,omitempty"`
+	NodeType      string            `json:"nodeType,omitempty"`
+	NodeLabels    map[string]string `json:"nodeLabels,omitempty"`
+	Organizations string            `json:"organizations,omitempty"`
+	YurtHubImage  string            `json:"yurthubImage,omitempty"`
+	PauseImage    string            `json:"pauseImage,omitempty"`
+	CRISocket     string            `json:"criSocket,omitempty"`
+}
+
+// Provider fetches join parameters and secrets from site infrastructure(like cloud-init,
+// iPXE/metal provisioning systems or vendor OS agents), so nodes can be joined without
+// hand-passed tokens.
+type Provider interface {
+	// Name returns the name of provider
+	Name() string
+	// FetchJoinParameters fetches join parameters for this node
+	FetchJoinParameters(ctx context.Context) (*JoinParameters, error)
+}
+
+// Factory creates a provider with the provider specific config, such as file path,
+// url or executable path.
+type Factory func(config string) (Provider, error)
+
+var (
+	lock      sync.Mutex
+	factories = make(map[string]Factory)
+)
+
+// Register registers a provider factory with the specified name.
+func Register(name string, factory Factory) {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("provider %s is registered more than once", name))
+	}
+	factories[name] = factory
+}
+
+// New creates the provider with the specified name and config.
+func New(name, config string) (Provider, error) {
+	lock.Lock()
+	factory, ok := factories[name]
+	lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not supported, only %s are supported", name, strings.Join(Names(), ","))
+	}
+	return factory(config)
+}
+
+// Names returns the sorted names of registered providers.
+func Names() []string {
+	lock.Lock()
+	defer lock.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the parameters are enough for joining a node.
+func (p *JoinParameters) Validate() error {
+	if len(p.ServerAddr) == 0 {
+		return fmt.Errorf("server address is not provided")
+	}
+	if len(p.Token) == 0 {
+		return fmt.Errorf("join token is not provided")
+	}
+	return nil
+}
+
+func init() {
+	Register(CloudInitProviderName, newCloudInitProvider)
+	Register(MetalProviderName, newMetalProvider)
+	Register(ExecProviderName, newExecProvider)
+}
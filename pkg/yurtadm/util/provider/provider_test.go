@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCloudInitProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "join-parameters.yaml")
+	content := `serverAddr: 1.2.3.4:6443
+token: abcdef.0123456789abcdef
+caCertHashes:
+- sha256:123
+nodeLabels:
+  openyurt.io/site: hangzhou
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write file, %v", err)
+	}
+
+	p, err := New(CloudInitProviderName, path)
+	if err != nil {
+		t.Fatalf("failed to create provider, %v", err)
+	}
+	params, err := p.FetchJoinParameters(context.Background())
+	if err != nil {
+		t.Fatalf("failed to fetch join parameters, %v", err)
+	}
+
+	expect := &JoinParameters{
+		ServerAddr:   "1.2.3.4:6443",
+		Token:        "abcdef.0123456789abcdef",
+		CaCertHashes: []string{"sha256:123"},
+		NodeLabels:   map[string]string{"openyurt.io/site": "hangzhou"},
+	}
+	if !reflect.DeepEqual(expect, params) {
+		t.Errorf("expect %#v, but got %#v", expect, params)
+	}
+}
+
+func TestMetalProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"serverAddr": "1.2.3.4:6443", "token": "abcdef.0123456789abcdef", "nodeType": "cloud"}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cmdline string
+		expect  *JoinParameters
+	}{
+		{
+			name:    "parameters in kernel command line",
+			cmdline: "BOOT_IMAGE=/vmlinuz ro openyurt.server-addr=1.2.3.4:6443 openyurt.token=abcdef.0123456789abcdef openyurt.node-labels=a=b,c=d quiet",
+			expect: &JoinParameters{
+				ServerAddr: "1.2.3.4:6443",
+				Token:      "abcdef.0123456789abcdef",
+				NodeLabels: map[string]string{"a": "b", "c": "d"},
+			},
+		},
+		{
+			name:    "parameters from provisioning server",
+			cmdline: fmt.Sprintf("ro openyurt.url=%s openyurt.node-name=foo", server.URL),
+			expect: &JoinParameters{
+				ServerAddr: "1.2.3.4:6443",
+				Token:      "abcdef.0123456789abcdef",
+				NodeType:   "cloud",
+				NodeName:   "foo",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cmdline")
+			if err := os.WriteFile(path, []byte(tt.cmdline), 0600); err != nil {
+				t.Fatalf("failed to write file, %v", err)
+			}
+			p, err := New(MetalProviderName, path)
+			if err != nil {
+				t.Fatalf("failed to create provider, %v", err)
+			}
+			params, err := p.FetchJoinParameters(context.Background())
+			if err != nil {
+				t.Fatalf("failed to fetch join parameters, %v", err)
+			}
+			if !reflect.DeepEqual(tt.expect, params) {
+				t.Errorf("expect %#v, but got %#v", tt.expect, params)
+			}
+		})
+	}
+}
+
+func TestExecProvider(t *testing.T) {
+	if _, err := New(ExecProviderName, ""); err == nil {
+		t.Errorf("expect error when executable is not specified")
+	}
+
+	p, err := New(ExecProviderName, `echo {"serverAddr":"1.2.3.4:6443","token":"abcdef.0123456789abcdef"}`)
+	if err != nil {
+		t.Fatalf("failed to create provider, %v", err)
+	}
+	params, err := p.FetchJoinParameters(context.Background())
+	if err != nil {
+		t.Fatalf("failed to fetch join parameters, %v", err)
+	}
+	if err := params.Validate(); err != nil {
+		t.Errorf("expect valid join parameters, but got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("unknown", ""); err == nil {
+		t.Errorf("expect error for unknown provider")
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sealedcredential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/provider"
+)
+
+const (
+	// EnvelopeVersion is the version of sealed credential envelope
+	EnvelopeVersion = "v1"
+	// Algorithm is the algorithm for sealing credential, the data key of AES-256-GCM
+	// is encrypted by RSA-OAEP-SHA256 with the site or device public key.
+	Algorithm = "RSA-OAEP-SHA256+A256GCM"
+)
+
+var (
+	ErrExpired  = errors.New("sealed credential is expired")
+	ErrConsumed = errors.New("sealed credential has already been used")
+)
+
+// Credential is the content of a sealed credential.
+type Credential struct {
+	// ID identifies the credential, it is used for rejecting the second use of the same credential
+	ID string `json:"id"`
+	// ExpiresAt is the time after which the credential can not be used
+	ExpiresAt time.Time `json:"expiresAt"`
+	// JoinParameters contains the server address, token and other parameters for joining
+	provider.JoinParameters
+}
+
+// envelope is the serialized format of a sealed credential.
+type envelope struct {
+	Version      string `json:"version"`
+	Algorithm    string `json:"algorithm"`
+	EncryptedKey []byte `json:"encryptedKey"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// NewCredential returns a credential with random id which expires after ttl.
+func NewCredential(params provider.JoinParameters, ttl time.Duration) *Credential {
+	return &Credential{
+		ID:             uuid.New().String(),
+		ExpiresAt:      time.Now().Add(ttl).UTC(),
+		JoinParameters: params,
+	}
+}
+
+// Seal encrypts the credential to the public key in pem format.
+func Seal(cred *Credential, publicKeyPEM []byte) ([]byte, error) {
+	pub, err := ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, []byte(Algorithm))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&envelope{
+		Version:      EnvelopeVersion,
+		Algorithm:    Algorithm,
+		EncryptedKey: encryptedKey,
+		Nonce:        nonce,
+		Ciphertext:   gcm.Seal(nil, nonce, plaintext, []byte(EnvelopeVersion)),
+	})
+}
+
+// Unseal decrypts the sealed credential with the private key in pem format, and checks
+// whether the credential is expired.
+func Unseal(sealed, privateKeyPEM []byte) (*Credential, error) {
+	key, err := ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	env := &envelope{}
+	if err := json.Unmarshal(sealed, env); err != nil {
+		return nil, fmt.Errorf("could not decode sealed credential, %w", err)
+	}
+	if env.Version != EnvelopeVersion || env.Algorithm != Algorithm {
+		return nil, fmt.Errorf("sealed credential version %s with algorithm %s is not supported", env.Version, env.Algorithm)
+	}
+
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, env.EncryptedKey, []byte(Algorithm))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt data key, the credential is not sealed to this key, %w", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, []byte(EnvelopeVersion))
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt sealed credential, %w", err)
+	}
+
+	cred := &Credential{}
+	if err := json.Unmarshal(plaintext, cred); err != nil {
+		return nil, fmt.Errorf("could not decode credential, %w", err)
+	}
+	if len(cred.ID) == 0 {
+		return nil, fmt.Errorf("credential id is empty")
+	}
+	if time.Now().After(cred.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return cred, nil
+}
+
+// Consume records the credential as used in dir, ErrConsumed is returned if it has been used before.
+func Consume(dir string, cred *Credential) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, cred.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return ErrConsumed
+		}
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// IsConsumed checks whether the credential has been used.
+func IsConsumed(dir string, cred *Credential) bool {
+	_, err := os.Stat(filepath.Join(dir, cred.ID))
+	return err == nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParsePublicKey parses the rsa public key or certificate in pem format.
+func ParsePublicKey(content []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no pem data is found in public key")
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return pub, nil
+		}
+		return nil, fmt.Errorf("only rsa public key is supported")
+	}
+	if pub, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return pub, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key, %w", err)
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("only rsa public key is supported")
+	}
+	return pub, nil
+}
+
+// ParsePrivateKey parses the rsa private key in pkcs1 or pkcs8 pem format.
+func ParsePrivateKey(content []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no pem data is found in private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key, %w", err)
+	}
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("only rsa private key is supported")
+	}
+	return priv, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sealedcredential
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/provider"
+)
+
+func newKeyPair(t *testing.T) ([]byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key, %v", err)
+	}
+	priv := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer})
+	return pub, priv
+}
+
+func TestSealAndUnseal(t *testing.T) {
+	pub, priv := newKeyPair(t)
+	_, otherPriv := newKeyPair(t)
+	params := provider.JoinParameters{
+		ServerAddr:   "1.2.3.4:6443",
+		Token:        "abcdef.0123456789abcdef",
+		CaCertHashes: []string{"sha256:123456"},
+		NodeName:     "foo",
+	}
+
+	sealed, err := Seal(NewCredential(params, time.Hour), pub)
+	if err != nil {
+		t.Fatalf("failed to seal credential, %v", err)
+	}
+
+	cred, err := Unseal(sealed, priv)
+	if err != nil {
+		t.Fatalf("failed to unseal credential, %v", err)
+	}
+	if !reflect.DeepEqual(cred.JoinParameters, params) {
+		t.Errorf("expect join parameters %v, but got %v", params, cred.JoinParameters)
+	}
+
+	if _, err := Unseal(sealed, otherPriv); err == nil {
+		t.Errorf("expect error when unsealing with another key, but got nil")
+	}
+
+	expired, err := Seal(NewCredential(params, -time.Minute), pub)
+	if err != nil {
+		t.Fatalf("failed to seal credential, %v", err)
+	}
+	if _, err := Unseal(expired, priv); !errors.Is(err, ErrExpired) {
+		t.Errorf("expect error %v, but got %v", ErrExpired, err)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	dir := t.TempDir()
+	cred := NewCredential(provider.JoinParameters{}, time.Hour)
+
+	if IsConsumed(dir, cred) {
+		t.Errorf("credential should not be consumed before use")
+	}
+	if err := Consume(dir, cred); err != nil {
+		t.Fatalf("failed to consume credential, %v", err)
+	}
+	if !IsConsumed(dir, cred) {
+		t.Errorf("credential should be consumed after use")
+	}
+	if err := Consume(dir, cred); !errors.Is(err, ErrConsumed) {
+		t.Errorf("expect error %v, but got %v", ErrConsumed, err)
+	}
+}
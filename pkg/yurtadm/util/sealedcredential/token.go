@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sealedcredential
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+
+	bootstraptokenv1 "github.com/openyurtio/openyurt/pkg/util/kubernetes/kubeadm/app/apis/bootstraptoken/v1"
+	kubeadmconstants "github.com/openyurtio/openyurt/pkg/util/kubernetes/kubeadm/app/constants"
+)
+
+const (
+	// CredentialIDAnnotation records the id of sealed credential on the bootstrap token created for it.
+	CredentialIDAnnotation = "openyurt.io/sealed-credential-id"
+	// ClaimedByAnnotation records the node which is joining with the sealed credential.
+	ClaimedByAnnotation = "openyurt.io/sealed-credential-claimed-by"
+)
+
+// CreateToken creates a dedicated bootstrap token for the credential, the token expires together with
+// the credential and is removed by the token cleaner of kube-controller-manager after that. The token is
+// also permitted to claim and revoke its own secret, so the credential can be used by only one node.
+func CreateToken(client kubernetes.Interface, cred *Credential) error {
+	token, err := bootstraptokenv1.NewBootstrapTokenString(cred.Token)
+	if err != nil {
+		return err
+	}
+	secret := bootstraptokenv1.BootstrapTokenToSecret(&bootstraptokenv1.BootstrapToken{
+		Token:       token,
+		Description: fmt.Sprintf("bootstrap token for sealed credential %s", cred.ID),
+		Expires:     &metav1.Time{Time: cred.ExpiresAt},
+		Usages:      kubeadmconstants.DefaultTokenUsages,
+		Groups:      kubeadmconstants.DefaultTokenGroups,
+	})
+	secret.Annotations = map[string]string{CredentialIDAnnotation: cred.ID}
+	secret, err = client.CoreV1().Secrets(metav1.NamespaceSystem).Create(context.TODO(), secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("bootstrap token %s already exists, only a new token can be sealed", token.ID)
+	} else if err != nil {
+		return err
+	}
+
+	// role and role binding are owned by the token secret, so they are collected when the token is removed
+	ownerReferences := []metav1.OwnerReference{{APIVersion: "v1", Kind: "Secret", Name: secret.Name, UID: secret.UID}}
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: secret.Name, Namespace: metav1.NamespaceSystem, OwnerReferences: ownerReferences},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{secret.Name},
+			Verbs:         []string{"get", "update", "delete"},
+		}},
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: secret.Name, Namespace: metav1.NamespaceSystem, OwnerReferences: ownerReferences},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: role.Name},
+		Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: bootstrapapi.BootstrapUserPrefix + token.ID}},
+	}
+	if _, err = client.RbacV1().Roles(metav1.NamespaceSystem).Create(context.TODO(), role, metav1.CreateOptions{}); err == nil {
+		_, err = client.RbacV1().RoleBindings(metav1.NamespaceSystem).Create(context.TODO(), binding, metav1.CreateOptions{})
+	}
+	if err != nil {
+		// the token can not be claimed without the role, so it is removed
+		if delErr := client.CoreV1().Secrets(metav1.NamespaceSystem).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{}); delErr != nil {
+			return fmt.Errorf("could not grant bootstrap token %s, %v, and could not remove it, %v", token.ID, err, delErr)
+		}
+		return fmt.Errorf("could not grant bootstrap token %s, %w", token.ID, err)
+	}
+	return nil
+}
+
+// Claim marks the bootstrap token of the credential as claimed by the node before joining. The claim is
+// an update with the resource version of the token secret, so only one node wins even if the credential
+// is used on several nodes at the same time. The node which holds the claim can claim it again for retrying.
+func Claim(client kubernetes.Interface, cred *Credential, nodeName string) error {
+	secret, err := getTokenSecret(client, cred)
+	if err != nil {
+		return err
+	}
+	if claimedBy, ok := secret.Annotations[ClaimedByAnnotation]; ok {
+		if claimedBy == nodeName {
+			return nil
+		}
+		return fmt.Errorf("%w by node %s", ErrConsumed, claimedBy)
+	}
+
+	secret.Annotations[ClaimedByAnnotation] = nodeName
+	if _, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		if apierrors.IsConflict(err) {
+			return fmt.Errorf("%w, it is claimed by another node", ErrConsumed)
+		}
+		return err
+	}
+	return nil
+}
+
+// Revoke removes the bootstrap token of the credential claimed by the node after it is joined,
+// and the credential can not be used any more.
+func Revoke(client kubernetes.Interface, cred *Credential, nodeName string) error {
+	secret, err := getTokenSecret(client, cred)
+	if err != nil {
+		return err
+	}
+	if claimedBy := secret.Annotations[ClaimedByAnnotation]; claimedBy != nodeName {
+		return fmt.Errorf("bootstrap token of sealed credential %s is claimed by node %q instead of %s", cred.ID, claimedBy, nodeName)
+	}
+	return client.CoreV1().Secrets(metav1.NamespaceSystem).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &secret.UID, ResourceVersion: &secret.ResourceVersion},
+	})
+}
+
+// getTokenSecret returns the bootstrap token secret created for the credential, ErrConsumed is returned
+// if the token has been revoked or removed after expiration.
+func getTokenSecret(client kubernetes.Interface, cred *Credential) (*corev1.Secret, error) {
+	token, err := bootstraptokenv1.NewBootstrapTokenString(cred.Token)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Get(context.TODO(), bootstraputil.BootstrapTokenSecretName(token.ID), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w, its bootstrap token %s is removed", ErrConsumed, token.ID)
+	} else if err != nil {
+		return nil, err
+	}
+	if secret.Annotations[CredentialIDAnnotation] != cred.ID {
+		return nil, fmt.Errorf("bootstrap token %s is not created for sealed credential %s", token.ID, cred.ID)
+	}
+	return secret, nil
+}
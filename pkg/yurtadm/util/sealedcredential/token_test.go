@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sealedcredential
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/provider"
+)
+
+func TestClaimAndRevoke(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cred := NewCredential(provider.JoinParameters{Token: "abcdef.0123456789abcdef"}, time.Hour)
+
+	if err := Claim(client, cred, "foo"); !errors.Is(err, ErrConsumed) {
+		t.Errorf("expect error %v before token is created, but got %v", ErrConsumed, err)
+	}
+	if err := CreateToken(client, cred); err != nil {
+		t.Fatalf("failed to create token, %v", err)
+	}
+	if err := CreateToken(client, NewCredential(cred.JoinParameters, time.Hour)); err == nil {
+		t.Errorf("expect error when sealing an existing token, but got nil")
+	}
+
+	secret, err := client.CoreV1().Secrets(metav1.NamespaceSystem).Get(context.TODO(), "bootstrap-token-abcdef", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get token secret, %v", err)
+	}
+	if expiration := string(secret.Data[bootstrapapi.BootstrapTokenExpirationKey]); expiration != cred.ExpiresAt.Format(time.RFC3339) {
+		t.Errorf("expect token expires at %s, but got %s", cred.ExpiresAt.Format(time.RFC3339), expiration)
+	}
+	binding, err := client.RbacV1().RoleBindings(metav1.NamespaceSystem).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get role binding, %v", err)
+	}
+	if binding.Subjects[0].Name != "system:bootstrap:abcdef" || binding.OwnerReferences[0].Name != secret.Name {
+		t.Errorf("role binding %v is not bound to token", binding)
+	}
+
+	if err := Claim(client, cred, "foo"); err != nil {
+		t.Fatalf("failed to claim credential, %v", err)
+	}
+	if err := Claim(client, cred, "foo"); err != nil {
+		t.Errorf("expect the same node can claim again, but got %v", err)
+	}
+	if err := Claim(client, cred, "bar"); !errors.Is(err, ErrConsumed) {
+		t.Errorf("expect error %v when claimed by another node, but got %v", ErrConsumed, err)
+	}
+	if err := Revoke(client, cred, "bar"); err == nil {
+		t.Errorf("expect error when revoked by another node, but got nil")
+	}
+
+	if err := Revoke(client, cred, "foo"); err != nil {
+		t.Fatalf("failed to revoke credential, %v", err)
+	}
+	if err := Claim(client, cred, "foo"); !errors.Is(err, ErrConsumed) {
+		t.Errorf("expect error %v after revoked, but got %v", ErrConsumed, err)
+	}
+}
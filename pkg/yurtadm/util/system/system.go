@@ -25,6 +25,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/pkg/yurtadm/constants"
+	"github.com/openyurtio/openyurt/pkg/yurtadm/util/dryrun"
 	"github.com/openyurtio/openyurt/pkg/yurtadm/util/edgenode"
 )
 
@@ -75,3 +76,17 @@ func SetSELinux() error {
 	selinux.SetDisabled()
 	return nil
 }
+
+// PlanSystemSettings records the changes made by SetIpv4Forward, SetBridgeSetting and SetSELinux into plan.
+func PlanSystemSettings(plan *dryrun.Plan) {
+	plan.WriteFile(dryrun.KindNetwork, ip_forward, 0644, "1")
+	plan.WriteFile(dryrun.KindFile, constants.SysctlK8sConfig, 0644, kubernetsBridgeSetting)
+	if exist, _ := edgenode.FileExists(bridgenf); !exist {
+		plan.Run("bash", "-c", "modprobe br-netfilter")
+	}
+	plan.WriteFile(dryrun.KindNetwork, bridgenf, 0644, "1")
+	plan.WriteFile(dryrun.KindNetwork, bridgenf6, 0644, "1")
+	if selinux.GetEnabled() {
+		plan.Add(dryrun.Change{Kind: dryrun.KindSystem, Operation: dryrun.OperationDisable, Path: "selinux"})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cachemanager
 
 import (
+	"errors"
 	"strings"
 	"sync"
 
@@ -26,6 +27,8 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
 
@@ -36,7 +39,11 @@ const (
 type CacheAgent struct {
 	sync.Mutex
 	agents sets.String
-	store  StorageWrapper
+	// wildcardAgents are the components which are cached only because of agent "*",
+	// their cache should be cleaned up when "*" is removed from cache agents. They are
+	// saved in store, so the cache is still cleaned up if "*" is removed after restart.
+	wildcardAgents sets.String
+	store          StorageWrapper
 }
 
 func NewCacheAgents(informerFactory informers.SharedInformerFactory, store StorageWrapper) *CacheAgent {
@@ -44,6 +51,7 @@ func NewCacheAgents(informerFactory informers.SharedInformerFactory, store Stora
 		agents: sets.NewString(util.DefaultCacheAgents...),
 		store:  store,
 	}
+	ca.wildcardAgents = ca.loadWildcardAgents()
 	configmapInformer := informerFactory.Core().V1().ConfigMaps().Informer()
 	configmapInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    ca.addConfigmap,
@@ -56,9 +64,65 @@ func NewCacheAgents(informerFactory informers.SharedInformerFactory, store Stora
 }
 
 func (ca *CacheAgent) HasAny(items ...string) bool {
+	ca.Lock()
+	defer ca.Unlock()
 	return ca.agents.HasAny(items...)
 }
 
+// Match checks the responses of component should be cached or not, and records the
+// component if it is matched only by agent "*".
+func (ca *CacheAgent) Match(comp string) bool {
+	ca.Lock()
+	defer ca.Unlock()
+	if ca.agents.Has(comp) {
+		return true
+	} else if !ca.agents.Has("*") {
+		return false
+	}
+
+	if ca.wildcardAgents == nil {
+		ca.wildcardAgents = sets.NewString()
+	}
+	if !ca.wildcardAgents.Has(comp) {
+		ca.wildcardAgents.Insert(comp)
+		ca.saveWildcardAgents()
+	}
+	return true
+}
+
+// loadWildcardAgents loads the wildcard agents saved in store before restart.
+func (ca *CacheAgent) loadWildcardAgents() sets.String {
+	agents := sets.NewString()
+	if ca.store == nil {
+		return agents
+	}
+	buf, err := ca.store.GetClusterInfo(storage.ClusterInfoKey{ClusterInfoType: storage.WildcardAgentsInfo})
+	if err != nil {
+		if !errors.Is(err, storage.ErrStorageNotFound) {
+			klog.Errorf("failed to load wildcard cache agents, %v", err)
+		}
+		return agents
+	}
+	for _, agent := range strings.Split(string(buf), sepForAgent) {
+		if len(agent) != 0 {
+			agents.Insert(agent)
+		}
+	}
+	klog.Infof("load wildcard cache agents %v", agents.List())
+	return agents
+}
+
+// saveWildcardAgents saves the wildcard agents into store with the lock held.
+func (ca *CacheAgent) saveWildcardAgents() {
+	if ca.store == nil {
+		return
+	}
+	content := []byte(strings.Join(ca.wildcardAgents.List(), sepForAgent))
+	if err := ca.store.SaveClusterInfo(storage.ClusterInfoKey{ClusterInfoType: storage.WildcardAgentsInfo}, content); err != nil {
+		klog.Errorf("failed to save wildcard cache agents, %v", err)
+	}
+}
+
 func (ca *CacheAgent) addConfigmap(obj interface{}) {
 	cfg, ok := obj.(*corev1.ConfigMap)
 	if !ok {
@@ -117,6 +181,11 @@ func (ca *CacheAgent) updateCacheAgents(cacheAgents, action string) sets.String
 
 	// get deleted and added agents
 	deletedAgents := ca.agents.Difference(newAgents)
+	if deletedAgents.Has("*") {
+		deletedAgents = deletedAgents.Union(ca.wildcardAgents.Difference(newAgents))
+		ca.wildcardAgents = sets.NewString()
+		ca.saveWildcardAgents()
+	}
 	ca.agents = newAgents
 
 	klog.Infof("current cache agents: %v after %s, deleted agents: %v", ca.agents, action, deletedAgents)
@@ -135,6 +204,7 @@ func (ca *CacheAgent) deleteAgentCache(deletedAgents sets.String) {
 			} else {
 				klog.Infof("cleanup cache for agent(%s) successfully", components[i])
 			}
+			metrics.Metrics.DeleteCachedObjects(components[i])
 		}
 	}
 }
@@ -22,6 +22,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
 
@@ -85,3 +86,49 @@ func TestUpdateCacheAgents(t *testing.T) {
 		})
 	}
 }
+
+func TestWildcardCacheAgents(t *testing.T) {
+	m := &CacheAgent{
+		agents: sets.NewString(util.DefaultCacheAgents...),
+	}
+	m.updateCacheAgents("*,agent1", "")
+
+	for _, comp := range []string{"agent1", "agent2", "kubelet"} {
+		if !m.Match(comp) {
+			t.Errorf("expect %s is matched by cache agents", comp)
+		}
+	}
+
+	deletedAgents := m.updateCacheAgents("agent1", "")
+	if expect := sets.NewString("*", "agent2"); !deletedAgents.Equal(expect) {
+		t.Errorf("Got deleted agents: %v, expect agents: %v", deletedAgents, expect)
+	}
+	if m.Match("agent2") {
+		t.Errorf("expect agent2 is not matched by cache agents")
+	}
+}
+
+func TestWildcardCacheAgentsAfterRestart(t *testing.T) {
+	dStorage, err := disk.NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	store := NewStorageWrapper(dStorage)
+
+	m := &CacheAgent{agents: sets.NewString(util.DefaultCacheAgents...), store: store}
+	m.updateCacheAgents("*", "")
+	if !m.Match("agent1") {
+		t.Errorf("expect agent1 is matched by cache agents")
+	}
+
+	// wildcard agents are loaded from store after restart
+	restarted := &CacheAgent{agents: sets.NewString(util.DefaultCacheAgents...), store: store}
+	restarted.wildcardAgents = restarted.loadWildcardAgents()
+	restarted.updateCacheAgents("*", "")
+	if deletedAgents := restarted.updateCacheAgents("", ""); !deletedAgents.Equal(sets.NewString("*", "agent1")) {
+		t.Errorf("Got deleted agents: %v, expect agents: %v", deletedAgents, sets.NewString("*", "agent1"))
+	}
+	if agents := restarted.loadWildcardAgents(); agents.Len() != 0 {
+		t.Errorf("expect wildcard agents are cleared in store, but got %v", agents)
+	}
+}
@@ -46,6 +46,7 @@ import (
 
 	hubmeta "github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/meta"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/serializer"
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
@@ -131,14 +132,24 @@ func (cm *cacheManager) QueryCache(req *http.Request) (runtime.Object, error) {
 		return nil, fmt.Errorf("failed to QueryCache for getting non-resource request %s", util.ReqString(req))
 	}
 
+	var obj runtime.Object
+	var err error
 	switch info.Verb {
 	case "list":
-		return cm.queryListObject(req)
+		obj, err = cm.queryListObject(req)
 	case "get", "patch", "update":
-		return cm.queryOneObject(req)
+		obj, err = cm.queryOneObject(req)
 	default:
 		return nil, fmt.Errorf("failed to QueryCache, unsupported verb %s of request %s", info.Verb, util.ReqString(req))
 	}
+
+	comp, _ := util.ClientComponentFrom(ctx)
+	if err != nil || obj == nil {
+		metrics.Metrics.IncCacheMisses(comp, info.Verb, info.Resource)
+	} else {
+		metrics.Metrics.IncCacheHits(comp, info.Verb, info.Resource)
+	}
+	return obj, err
 }
 
 // TODO: Consider if we need accelerate the list query with in-memory cache. Currently, we only
@@ -386,6 +397,7 @@ func (cm *cacheManager) saveWatchObject(ctx context.Context, info *apirequest.Re
 		klog.Infof("%s watch %s: %s get %d objects(add:%d/update:%d/del:%d)", comp, info.Resource, info.Path, addObjCnt+updateObjCnt+delObjCnt, addObjCnt, updateObjCnt, delObjCnt)
 	}()
 
+	canCache, _ := util.ReqCanCacheFrom(ctx)
 	for {
 		watchType, obj, err := d.Decode()
 		if err != nil {
@@ -393,6 +405,12 @@ func (cm *cacheManager) saveWatchObject(ctx context.Context, info *apirequest.Re
 			return err
 		}
 
+		// the component may be removed from cache agents after the watch started, keep
+		// decoding events without caching them, because the response is read through a pipe.
+		if !canCache && !cm.cacheAgents.HasAny("*", comp) {
+			continue
+		}
+
 		switch watchType {
 		case watch.Added, watch.Modified, watch.Deleted:
 			name, err := accessor.Name(obj)
@@ -422,14 +440,16 @@ func (cm *cacheManager) saveWatchObject(ctx context.Context, info *apirequest.Re
 
 			switch watchType {
 			case watch.Added, watch.Modified:
-				err = cm.storeObjectWithKey(key, obj)
+				err = cm.storeObjectWithKey(comp, info.Resource, key, obj)
 				if watchType == watch.Added {
 					addObjCnt++
 				} else {
 					updateObjCnt++
 				}
 			case watch.Deleted:
-				err = cm.storage.Delete(key)
+				if err = cm.storage.Delete(key); err == nil {
+					metrics.Metrics.DecCachedObjects(comp, info.Resource)
+				}
 				delObjCnt++
 			default:
 				// impossible go to here
@@ -508,7 +528,7 @@ func (cm *cacheManager) saveListObject(ctx context.Context, info *apirequest.Req
 			Group:     info.APIGroup,
 			Version:   info.APIVersion,
 		})
-		return cm.storeObjectWithKey(key, items[0])
+		return cm.storeObjectWithKey(comp, info.Resource, key, items[0])
 	} else {
 		// list all objects or with fieldselector/labelselector
 		objs := make(map[storage.Key]runtime.Object)
@@ -532,12 +552,22 @@ func (cm *cacheManager) saveListObject(ctx context.Context, info *apirequest.Req
 			})
 			objs[key] = items[i]
 		}
-		// if no objects in cloud cluster(objs is empty), it will clean the old files in the path of rootkey
-		return cm.storage.ReplaceComponentList(comp, schema.GroupVersionResource{
+		gvr := schema.GroupVersionResource{
 			Group:    info.APIGroup,
 			Version:  info.APIVersion,
 			Resource: info.Resource,
-		}, info.Namespace, objs)
+		}
+		// if no objects in cloud cluster(objs is empty), it will clean the old files in the path of rootkey
+		if err := cm.storage.ReplaceComponentList(comp, gvr, info.Namespace, objs); err != nil {
+			return err
+		}
+		// the list may be limited in a namespace, so count the objects of all namespaces in storage
+		if keys, err := cm.storage.ListResourceKeysOfComponent(comp, gvr); err == nil {
+			metrics.Metrics.SetCachedObjects(comp, info.Resource, len(keys))
+		} else if err == storage.ErrStorageNotFound {
+			metrics.Metrics.SetCachedObjects(comp, info.Resource, 0)
+		}
+		return nil
 	}
 }
 
@@ -595,7 +625,7 @@ func (cm *cacheManager) saveOneObject(ctx context.Context, info *apirequest.Requ
 		klog.Errorf("failed to update the DynamicRESTMapper %v", err)
 	}
 
-	err = cm.storeObjectWithKey(key, obj)
+	err = cm.storeObjectWithKey(comp, info.Resource, key, obj)
 	if err != nil {
 		klog.Errorf("failed to store object %s, %v", key.Key(), err)
 		return err
@@ -617,7 +647,7 @@ func (cm *cacheManager) saveOneObject(ctx context.Context, info *apirequest.Requ
 	return nil
 }
 
-func (cm *cacheManager) storeObjectWithKey(key storage.Key, obj runtime.Object) error {
+func (cm *cacheManager) storeObjectWithKey(comp, resource string, key storage.Key, obj runtime.Object) error {
 	accessor := meta.NewAccessor()
 	if isNotAssignedPod(obj) {
 		ns, _ := accessor.Namespace(obj)
@@ -646,6 +676,7 @@ func (cm *cacheManager) storeObjectWithKey(key storage.Key, obj runtime.Object)
 			}
 			return fmt.Errorf("failed to create obj of key: %s, %v", key.Key(), err)
 		}
+		metrics.Metrics.IncCachedObjects(comp, resource)
 	case storage.ErrStorageAccessConflict:
 		klog.V(2).Infof("skip to cache watch event because key(%s) is under processing", key)
 		return nil
@@ -718,12 +749,9 @@ func (cm *cacheManager) CanCacheFor(req *http.Request) bool {
 	if ok && canCache {
 		// request with Edge-Cache header, continue verification
 	} else {
-		cm.RLock()
-		if !cm.cacheAgents.HasAny("*", comp) {
-			cm.RUnlock()
+		if !cm.cacheAgents.Match(comp) {
 			return false
 		}
-		cm.RUnlock()
 	}
 
 	info, ok := apirequest.RequestInfoFrom(ctx)
@@ -29,6 +29,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	v1 "k8s.io/api/core/v1"
 	nodev1beta1 "k8s.io/api/node/v1beta1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -166,6 +167,50 @@ func TestCacheGetResponse(t *testing.T) {
 				kind: "Pod",
 			},
 		},
+		"cache response for get pod in cbor": {
+			group:   "",
+			version: "v1",
+			keyBuildInfo: storage.KeyBuildInfo{
+				Component: "kubelet",
+				Resources: "pods",
+				Namespace: "default",
+				Name:      "mypod1",
+				Group:     "",
+				Version:   "v1",
+			},
+			inputObj: runtime.Object(&v1.Pod{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Pod",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "mypod1",
+					Namespace:       "default",
+					ResourceVersion: "1",
+				},
+				Spec: v1.PodSpec{
+					NodeName: "node1",
+				},
+			}),
+			userAgent:  "kubelet",
+			accept:     serializer.ContentTypeCBOR,
+			verb:       "GET",
+			path:       "/api/v1/namespaces/default/pods/mypod1",
+			resource:   "pods",
+			namespaced: true,
+			expectResult: struct {
+				err  error
+				rv   string
+				name string
+				ns   string
+				kind string
+			}{
+				rv:   "1",
+				name: "mypod1",
+				ns:   "default",
+				kind: "Pod",
+			},
+		},
 		"cache response for get pod2": {
 			group:   "",
 			version: "v1",
@@ -3332,3 +3377,101 @@ func TestIsListRequestWithNameFieldSelector(t *testing.T) {
 }
 
 // TODO: in-memory cache unit tests
+
+func TestCacheMetrics(t *testing.T) {
+	dir := t.TempDir()
+	dStorage, err := disk.NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	sWrapper := NewStorageWrapper(dStorage)
+	serializerM := serializer.NewSerializerManager()
+	restRESTMapperMgr, err := hubmeta.NewRESTMapperManager(dir)
+	if err != nil {
+		t.Fatalf("failed to create RESTMapper manager, %v", err)
+	}
+	yurtCM := NewCacheManager(sWrapper, serializerM, restRESTMapperMgr, fakeSharedInformerFactory)
+
+	key, err := sWrapper.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: "pods",
+		Namespace: "default",
+		Name:      "mypod1",
+		Version:   "v1",
+	})
+	if err != nil {
+		t.Fatalf("failed to get key, %v", err)
+	}
+	writtenBytes := gatherMetric(t, "cache_written_bytes_counter", map[string]string{"storage": dStorage.Name()})
+	if err := sWrapper.Create(key, &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "mypod1",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+	}); err != nil {
+		t.Fatalf("failed to create pod, %v", err)
+	}
+	if got := gatherMetric(t, "cache_written_bytes_counter", map[string]string{"storage": dStorage.Name()}); got <= writtenBytes {
+		t.Errorf("expect written bytes increased from %v, but got %v", writtenBytes, got)
+	}
+
+	labels := map[string]string{"client": "kubelet", "verb": "get", "resource": "pods"}
+	hits := gatherMetric(t, "cache_hits_counter", labels)
+	misses := gatherMetric(t, "cache_misses_counter", labels)
+	resolver := newTestRequestInfoResolver()
+	for _, path := range []string{"/api/v1/namespaces/default/pods/mypod1", "/api/v1/namespaces/default/pods/mypod2"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("User-Agent", "kubelet")
+		req.Header.Set("Accept", "application/json")
+		req.RemoteAddr = "127.0.0.1"
+
+		var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = yurtCM.QueryCache(req)
+		})
+		handler = proxyutil.WithRequestClientComponent(handler)
+		handler = filters.WithRequestInfo(handler, resolver)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := gatherMetric(t, "cache_hits_counter", labels); got != hits+1 {
+		t.Errorf("expect cache hits %v, but got %v", hits+1, got)
+	}
+	if got := gatherMetric(t, "cache_misses_counter", labels); got != misses+1 {
+		t.Errorf("expect cache misses %v, but got %v", misses+1, got)
+	}
+}
+
+// gatherMetric returns the value of hub metric with name and labels, 0 is returned if the metric is not found.
+func gatherMetric(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics, %v", err)
+	}
+	fullName := fmt.Sprintf("node_%s_%s", strings.ReplaceAll(projectinfo.GetHubName(), "-", "_"), name)
+	for _, family := range families {
+		if family.GetName() != fullName {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			matched := 0
+			for _, lp := range m.GetLabel() {
+				if labels[lp.GetName()] == lp.GetValue() {
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+	}
+	return 0
+}
@@ -28,7 +28,9 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
 )
 
 // StorageWrapper is wrapper for storage.Store interface
@@ -53,13 +55,22 @@ type storageWrapper struct {
 	sync.RWMutex
 	store             storage.Store
 	backendSerializer runtime.Serializer
+	compression       compression.Codec
 }
 
 // NewStorageWrapper create a StorageWrapper object
 func NewStorageWrapper(storage storage.Store) StorageWrapper {
+	return NewStorageWrapperWithCompression(storage, compression.None)
+}
+
+// NewStorageWrapperWithCompression create a StorageWrapper object which compresses
+// the objects with codec before they are stored. the objects are decompressed by their
+// format when read, so the objects stored with any codec or without compression can be read.
+func NewStorageWrapperWithCompression(storage storage.Store, codec compression.Codec) StorageWrapper {
 	return &storageWrapper{
 		store:             storage,
 		backendSerializer: json.NewSerializerWithOptions(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, json.SerializerOptions{}),
+		compression:       codec,
 	}
 }
 
@@ -80,18 +91,20 @@ func (sw *storageWrapper) GetStorage() storage.Store {
 // will be created. for example: for disk storage,
 // a directory that indicates the key will be created.
 func (sw *storageWrapper) Create(key storage.Key, obj runtime.Object) error {
-	var buf bytes.Buffer
+	var content []byte
 	if obj != nil {
-		if err := sw.backendSerializer.Encode(obj, &buf); err != nil {
+		var err error
+		if content, err = sw.encode(obj); err != nil {
 			klog.Errorf("failed to encode object in create for %s, %v", key.Key(), err)
 			return err
 		}
 	}
 
-	if err := sw.store.Create(key, buf.Bytes()); err != nil {
+	if err := sw.store.Create(key, content); err != nil {
 		return err
 	}
 
+	metrics.Metrics.AddCacheWrittenBytes(sw.store.Name(), len(content))
 	return nil
 }
 
@@ -108,6 +121,10 @@ func (sw *storageWrapper) Get(key storage.Key) (runtime.Object, error) {
 	} else if len(b) == 0 {
 		return nil, nil
 	}
+	if b, err = compression.Decompress(b); err != nil {
+		klog.Errorf("could not decompress object for %s, %v", key.Key(), err)
+		return nil, err
+	}
 	//get the gvk from json data
 	gvk, err := json.DefaultMetaFactory.Interpret(b)
 	if err != nil {
@@ -144,6 +161,12 @@ func (sw *storageWrapper) List(key storage.Key) ([]runtime.Object, error) {
 	if len(bb) == 0 {
 		return objects, nil
 	}
+	for i := range bb {
+		if bb[i], err = compression.Decompress(bb[i]); err != nil {
+			klog.Errorf("could not decompress objects for %s, %v", key.Key(), err)
+			return nil, err
+		}
+	}
 	//get the gvk from json data
 	gvk, err := json.DefaultMetaFactory.Interpret(bb[0])
 	if err != nil {
@@ -173,14 +196,18 @@ func (sw *storageWrapper) List(key storage.Key) ([]runtime.Object, error) {
 
 // Update update runtime object in backend storage
 func (sw *storageWrapper) Update(key storage.Key, obj runtime.Object, rv uint64) (runtime.Object, error) {
-	var buf bytes.Buffer
-	if err := sw.backendSerializer.Encode(obj, &buf); err != nil {
+	content, err := sw.encode(obj)
+	if err != nil {
 		klog.Errorf("failed to encode object in update for %s, %v", key.Key(), err)
 		return nil, err
 	}
 
-	if buf, err := sw.store.Update(key, buf.Bytes(), rv); err != nil {
+	if buf, err := sw.store.Update(key, content, rv); err != nil {
 		if err == storage.ErrUpdateConflict {
+			buf, dErr := compression.Decompress(buf)
+			if dErr != nil {
+				return nil, fmt.Errorf("failed to decompress existing obj of key %s, %v", key.Key(), dErr)
+			}
 			obj, _, dErr := sw.backendSerializer.Decode(buf, nil, nil)
 			if dErr != nil {
 				return nil, fmt.Errorf("failed to decode existing obj of key %s, %v", key.Key(), dErr)
@@ -190,23 +217,28 @@ func (sw *storageWrapper) Update(key storage.Key, obj runtime.Object, rv uint64)
 		return nil, err
 	}
 
+	metrics.Metrics.AddCacheWrittenBytes(sw.store.Name(), len(content))
 	return obj, nil
 }
 
 func (sw *storageWrapper) ReplaceComponentList(component string, gvr schema.GroupVersionResource, namespace string, objs map[storage.Key]runtime.Object) error {
-	var buf bytes.Buffer
 	contents := make(map[storage.Key][]byte, len(objs))
 	for key, obj := range objs {
-		if err := sw.backendSerializer.Encode(obj, &buf); err != nil {
+		content, err := sw.encode(obj)
+		if err != nil {
 			klog.Errorf("failed to encode object in update for %s, %v", key.Key(), err)
 			return err
 		}
-		contents[key] = make([]byte, len(buf.Bytes()))
-		copy(contents[key], buf.Bytes())
-		buf.Reset()
+		contents[key] = content
 	}
 
-	return sw.store.ReplaceComponentList(component, gvr, namespace, contents)
+	if err := sw.store.ReplaceComponentList(component, gvr, namespace, contents); err != nil {
+		return err
+	}
+	for _, content := range contents {
+		metrics.Metrics.AddCacheWrittenBytes(sw.store.Name(), len(content))
+	}
+	return nil
 }
 
 // DeleteCollection will delete all objects under rootKey
@@ -221,3 +253,12 @@ func (sw *storageWrapper) SaveClusterInfo(key storage.ClusterInfoKey, content []
 func (sw *storageWrapper) GetClusterInfo(key storage.ClusterInfoKey) ([]byte, error) {
 	return sw.store.GetClusterInfo(key)
 }
+
+// encode serializes obj and compresses it with the codec of wrapper
+func (sw *storageWrapper) encode(obj runtime.Object) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := sw.backendSerializer.Encode(obj, &buf); err != nil {
+		return nil, err
+	}
+	return compression.Compress(sw.compression, buf.Bytes())
+}
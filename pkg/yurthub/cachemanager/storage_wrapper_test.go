@@ -33,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
 )
 
@@ -269,3 +270,76 @@ func TestStorageWrapper(t *testing.T) {
 		}
 	})
 }
+
+func TestStorageWrapperWithCompression(t *testing.T) {
+	dStorage, err := disk.NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	plainWrapper := NewStorageWrapper(dStorage)
+	gzipWrapper := NewStorageWrapperWithCompression(dStorage, compression.Gzip)
+	zstdWrapper := NewStorageWrapperWithCompression(dStorage, compression.Zstd)
+
+	keyOf := func(name string) storage.Key {
+		key, err := plainWrapper.KeyFunc(storage.KeyBuildInfo{
+			Component: "kubelet",
+			Resources: "pods",
+			Namespace: "default",
+			Name:      name,
+			Version:   "v1",
+		})
+		if err != nil {
+			t.Fatalf("failed to create key, %v", err)
+		}
+		return key
+	}
+	podOf := func(name, rv string) *v1.Pod {
+		pod := testPod.DeepCopy()
+		pod.Name = name
+		pod.ResourceVersion = rv
+		return pod
+	}
+
+	// objects cached before upgrade are not compressed
+	if err := plainWrapper.Create(keyOf("plain"), podOf("plain", "1")); err != nil {
+		t.Fatalf("failed to create obj, %v", err)
+	}
+	if err := gzipWrapper.Create(keyOf("gzip"), podOf("gzip", "1")); err != nil {
+		t.Fatalf("failed to create obj, %v", err)
+	}
+	raw, err := dStorage.Get(keyOf("gzip"))
+	if err != nil {
+		t.Fatalf("failed to get raw content, %v", err)
+	}
+	if raw[0] == '{' {
+		t.Errorf("object is not compressed")
+	}
+
+	objs, err := zstdWrapper.List(keyOf(""))
+	if err != nil {
+		t.Fatalf("failed to list objs, %v", err)
+	}
+	if len(objs) != 2 {
+		t.Errorf("expect 2 objects for mixed compressed and uncompressed keys, but got %d", len(objs))
+	}
+
+	// rv of compressed object is checked when it's updated
+	if _, err := zstdWrapper.Update(keyOf("gzip"), podOf("gzip", "3"), 3); err != nil {
+		t.Fatalf("failed to update obj, %v", err)
+	}
+	obj, err := plainWrapper.Update(keyOf("gzip"), podOf("gzip", "2"), 2)
+	if err != storage.ErrUpdateConflict {
+		t.Fatalf("expect ErrUpdateConflict, but got %v", err)
+	}
+	if rv, _ := meta.NewAccessor().ResourceVersion(obj); rv != "3" {
+		t.Errorf("expect existing object with rv 3, but got %s", rv)
+	}
+
+	obj, err = plainWrapper.Get(keyOf("gzip"))
+	if err != nil {
+		t.Fatalf("failed to get obj, %v", err)
+	}
+	if rv, _ := meta.NewAccessor().ResourceVersion(obj); rv != "3" {
+		t.Errorf("expect object with rv 3, but got %s", rv)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+)
+
+const (
+	caCertName     = "local-ca.crt"
+	caKeyName      = "local-ca.key"
+	servingCertKey = "redirect-server.pem"
+)
+
+// Files are the paths of node-local CA and the serving cert signed by it.
+type Files struct {
+	// CAFile is the cert of node-local CA, the clients which are redirected to hub agent
+	// should be configured to trust it.
+	CAFile string
+	// CertFile contains both the serving cert and its private key.
+	CertFile string
+}
+
+// EnsureServingCert ensures a serving cert for ips and dnsNames which is signed by a CA only known to the
+// node. The CA is created once and kept in dir, so the clients configured to trust it keep working when
+// the serving cert is recreated for other ips and dnsNames.
+//
+// The serving cert of hub agent is signed by the cluster CA, so it must never contain the addresses of
+// kube-apiserver, otherwise the node can impersonate kube-apiserver to all clients of the cluster.
+func EnsureServingCert(dir string, ips []net.IP, dnsNames []string) (*Files, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	files := &Files{
+		CAFile:   filepath.Join(dir, caCertName),
+		CertFile: filepath.Join(dir, servingCertKey),
+	}
+
+	caCert, caKey, err := ensureCA(files.CAFile, filepath.Join(dir, caKeyName))
+	if err != nil {
+		return nil, err
+	}
+
+	if valid, err := isServingCertValid(files.CertFile, caCert, ips, dnsNames, time.Now()); err != nil {
+		klog.Warningf("serving cert %s will be recreated, %v", files.CertFile, err)
+	} else if valid {
+		return files, nil
+	}
+
+	certPEM, keyPEM, err := newServingCert(caCert, caKey, ips, dnsNames)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(files.CertFile, append(certPEM, keyPEM...)); err != nil {
+		return nil, err
+	}
+	klog.Infof("serving cert %s for %v %v is signed by node-local CA %s", files.CertFile, ips, dnsNames, files.CAFile)
+	return files, nil
+}
+
+func ensureCA(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	certs, certErr := certutil.CertsFromFile(certFile)
+	key, keyErr := keyutil.PrivateKeyFromFile(keyFile)
+	if certErr == nil && keyErr == nil && len(certs) != 0 {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("key of node-local CA %s is not a signer", keyFile)
+		}
+		return certs[0], signer, nil
+	}
+	if !os.IsNotExist(certErr) && !os.IsNotExist(keyErr) {
+		return nil, nil, fmt.Errorf("failed to load node-local CA, %v, %v", certErr, keyErr)
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	hostname, _ := os.Hostname()
+	caCert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: fmt.Sprintf("yurthub-local-ca@%s", hostname)}, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeFile(keyFile, keyPEM); err != nil {
+		return nil, nil, err
+	}
+	if err := writeFile(certFile, encodeCert(caCert)); err != nil {
+		return nil, nil, err
+	}
+	klog.Infof("node-local CA %s is created", certFile)
+	return caCert, caKey, nil
+}
+
+// isServingCertValid checks the serving cert is signed by caCert for exactly ips and dnsNames, and it's not expired.
+func isServingCertValid(file string, caCert *x509.Certificate, ips []net.IP, dnsNames []string, now time.Time) (bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	certs, err := certutil.ParseCertsPEM(data)
+	if err != nil {
+		return false, err
+	}
+	if _, err := keyutil.ParsePrivateKeyPEM(data); err != nil {
+		return false, err
+	}
+
+	cert := certs[0]
+	if err := cert.CheckSignatureFrom(caCert); err != nil {
+		return false, nil
+	}
+	if now.After(cert.NotAfter) {
+		return false, nil
+	}
+	if len(cert.IPAddresses) != len(ips) || len(cert.DNSNames) != len(dnsNames) {
+		return false, nil
+	}
+	for i := range ips {
+		if !cert.IPAddresses[i].Equal(ips[i]) {
+			return false, nil
+		}
+	}
+	for i := range dnsNames {
+		if cert.DNSNames[i] != dnsNames[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// newServingCert creates a serving cert which is valid as long as the CA.
+func newServingCert(caCert *x509.Certificate, caKey crypto.Signer, ips []net.IP, dnsNames []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := cryptorand.Int(cryptorand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpl := x509.Certificate{
+		Subject:      pkix.Name{CommonName: "yurthub-redirect-server"},
+		IPAddresses:  ips,
+		DNSNames:     dnsNames,
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-time.Minute).UTC(),
+		NotAfter:     caCert.NotAfter,
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(cert), keyPEM, nil
+}
+
+func encodeCert(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: cert.Raw})
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localca
+
+import (
+	"crypto/x509"
+	"net"
+	"os"
+	"testing"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestEnsureServingCert(t *testing.T) {
+	dir := t.TempDir()
+	ips := []net.IP{net.ParseIP("10.0.0.1")}
+
+	files, err := EnsureServingCert(dir, ips, []string{"apiserver.example.com"})
+	if err != nil {
+		t.Fatalf("failed to ensure serving cert, %v", err)
+	}
+	caCert := loadCert(t, files.CAFile)
+	cert := loadCert(t, files.CertFile)
+	if !caCert.IsCA {
+		t.Errorf("expect %s to be a CA", files.CAFile)
+	}
+	verifyCert(t, cert, caCert, "10.0.0.1")
+	verifyCert(t, cert, caCert, "apiserver.example.com")
+
+	// the serving cert is kept if the addresses don't change
+	if _, err := EnsureServingCert(dir, ips, []string{"apiserver.example.com"}); err != nil {
+		t.Fatalf("failed to ensure serving cert, %v", err)
+	}
+	if got := loadCert(t, files.CertFile); !got.Equal(cert) {
+		t.Errorf("expect serving cert to be kept")
+	}
+
+	// the serving cert is recreated by the same CA if the addresses change
+	if _, err := EnsureServingCert(dir, []net.IP{net.ParseIP("10.0.0.2")}, nil); err != nil {
+		t.Fatalf("failed to ensure serving cert, %v", err)
+	}
+	if got := loadCert(t, files.CAFile); !got.Equal(caCert) {
+		t.Errorf("expect node-local CA to be kept")
+	}
+	cert = loadCert(t, files.CertFile)
+	verifyCert(t, cert, caCert, "10.0.0.2")
+	if len(cert.IPAddresses) != 1 || len(cert.DNSNames) != 0 {
+		t.Errorf("expect serving cert only for 10.0.0.2, but got %v %v", cert.IPAddresses, cert.DNSNames)
+	}
+
+	// the serving cert is never trusted by the system roots or other CAs
+	otherDir := t.TempDir()
+	otherFiles, err := EnsureServingCert(otherDir, ips, nil)
+	if err != nil {
+		t.Fatalf("failed to ensure serving cert, %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(loadCert(t, otherFiles.CAFile))
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool}); err == nil {
+		t.Errorf("expect serving cert not to be verified by the CA of other node")
+	}
+
+	info, err := os.Stat(files.CertFile)
+	if err != nil {
+		t.Fatalf("failed to stat serving cert, %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expect serving cert with key to be 0600, but got %v", info.Mode().Perm())
+	}
+}
+
+func loadCert(t *testing.T, file string) *x509.Certificate {
+	t.Helper()
+	certs, err := certutil.CertsFromFile(file)
+	if err != nil {
+		t.Fatalf("failed to load cert %s, %v", file, err)
+	}
+	return certs[0]
+}
+
+func verifyCert(t *testing.T, cert, caCert *x509.Certificate, host string) {
+	t.Helper()
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, DNSName: host}); err != nil {
+		t.Errorf("expect serving cert to be verified for %s, %v", host, err)
+	}
+}
@@ -44,8 +44,10 @@ import (
 	certfactory "github.com/openyurtio/openyurt/pkg/util/certmanager/factory"
 	"github.com/openyurtio/openyurt/pkg/util/certmanager/store"
 	kubeconfigutil "github.com/openyurtio/openyurt/pkg/util/kubeconfig"
+	"github.com/openyurtio/openyurt/pkg/util/pubkeypin"
 	"github.com/openyurtio/openyurt/pkg/util/token"
 	hubCert "github.com/openyurtio/openyurt/pkg/yurthub/certificate"
+	"github.com/openyurtio/openyurt/pkg/yurthub/compat"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
 
@@ -69,6 +71,7 @@ type CertificateManagerConfiguration struct {
 	NodeName                 string
 	JoinToken                string
 	CaCertHashes             []string
+	CAFile                   string
 	YurtHubCertOrganizations []string
 	CertIPs                  []net.IP
 	RemoteServers            []*url.URL
@@ -79,6 +82,7 @@ type yurtHubCertManager struct {
 	client                     clientset.Interface
 	remoteServers              []*url.URL
 	caCertHashes               []string
+	caFile                     string
 	apiServerClientCertManager certificate.Manager
 	hubServerCertManager       certificate.Manager
 	apiServerClientCertStore   certificate.FileStore
@@ -105,6 +109,7 @@ func NewYurtHubCertManager(cfg *CertificateManagerConfiguration) (hubCert.YurtCe
 		hubName:       projectinfo.GetHubName(),
 		joinToken:     cfg.JoinToken,
 		caCertHashes:  cfg.CaCertHashes,
+		caFile:        cfg.CAFile,
 		dialer:        util.NewDialer("hub certificate manager"),
 	}
 
@@ -388,29 +393,66 @@ func (ycm *yurtHubCertManager) newHubServerCertificateManager(fileStore certific
 }
 
 func (ycm *yurtHubCertManager) retrieveHubBootstrapConfig(joinToken string) (*clientcmdapi.Config, error) {
-	// retrieve bootstrap config info from cluster-info configmap by bootstrap token
 	serverAddr := findActiveRemoteServer(ycm.remoteServers).Host
-	if cfg, err := token.RetrieveValidatedConfigInfo(ycm.client, &token.BootstrapData{
+	if len(ycm.caFile) != 0 {
+		return ycm.retrieveHubBootstrapConfigFromCAFile(serverAddr, ycm.caFile, joinToken)
+	}
+
+	// retrieve bootstrap config info from cluster-info configmap by bootstrap token
+	cfg, err := token.RetrieveValidatedConfigInfo(ycm.client, &token.BootstrapData{
 		ServerAddr:   serverAddr,
 		JoinToken:    joinToken,
 		CaCertHashes: ycm.caCertHashes,
-	}); err != nil {
+	})
+	if err != nil {
+		// lightweight distributions like k3s and k0s don't publish cluster-info configmap,
+		// the CA file written on the node by their agents is used instead.
+		if caFile := compat.DetectCAFile(); len(caFile) != 0 {
+			klog.Warningf("couldn't retrieve bootstrap config info, %v, use CA file %s instead", err, caFile)
+			return ycm.retrieveHubBootstrapConfigFromCAFile(serverAddr, caFile, joinToken)
+		}
 		return nil, errors.Wrap(err, "couldn't retrieve bootstrap config info")
-	} else {
-		clusterInfo := kubeconfigutil.GetClusterFromKubeConfig(cfg)
-		tlsBootstrapCfg := kubeconfigutil.CreateWithToken(
-			fmt.Sprintf("https://%s", serverAddr),
-			"kubernetes",
-			"token-bootstrap-client",
-			clusterInfo.CertificateAuthorityData,
-			joinToken,
-		)
-		if err = kubeconfigutil.WriteToDisk(ycm.getBootstrapConfFile(), tlsBootstrapCfg); err != nil {
-			return nil, errors.Wrap(err, "couldn't save bootstrap-hub.conf to disk")
+	}
+	clusterInfo := kubeconfigutil.GetClusterFromKubeConfig(cfg)
+	return ycm.writeHubBootstrapConfig(serverAddr, clusterInfo.CertificateAuthorityData, joinToken)
+}
+
+// retrieveHubBootstrapConfigFromCAFile creates bootstrap config with the CA in caFile, the CA is
+// validated against the ca cert hashes if they are specified.
+func (ycm *yurtHubCertManager) retrieveHubBootstrapConfigFromCAFile(serverAddr, caFile, joinToken string) (*clientcmdapi.Config, error) {
+	caData, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read CA file %s", caFile)
+	}
+	certs, err := certutil.ParseCertsPEM(caData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse CA file %s", caFile)
+	}
+	pubKeyPins := pubkeypin.NewSet()
+	if err := pubKeyPins.Allow(ycm.caCertHashes...); err != nil {
+		return nil, err
+	}
+	if !pubKeyPins.Empty() {
+		if err := pubKeyPins.CheckAny(certs); err != nil {
+			return nil, errors.Wrapf(err, "CA in %s is not pinned", caFile)
 		}
+	}
+	return ycm.writeHubBootstrapConfig(serverAddr, caData, joinToken)
+}
 
-		return tlsBootstrapCfg, nil
+func (ycm *yurtHubCertManager) writeHubBootstrapConfig(serverAddr string, caData []byte, joinToken string) (*clientcmdapi.Config, error) {
+	tlsBootstrapCfg := kubeconfigutil.CreateWithToken(
+		fmt.Sprintf("https://%s", serverAddr),
+		"kubernetes",
+		"token-bootstrap-client",
+		caData,
+		joinToken,
+	)
+	if err := kubeconfigutil.WriteToDisk(ycm.getBootstrapConfFile(), tlsBootstrapCfg); err != nil {
+		return nil, errors.Wrap(err, "couldn't save bootstrap-hub.conf to disk")
 	}
+
+	return tlsBootstrapCfg, nil
 }
 
 func createHubConfig(tlsBootstrapCfg *clientcmdapi.Config, pemPath string) *clientcmdapi.Config {
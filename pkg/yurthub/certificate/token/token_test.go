@@ -26,8 +26,11 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
+	certutil "k8s.io/client-go/util/cert"
 
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	kubeconfigutil "github.com/openyurtio/openyurt/pkg/util/kubeconfig"
+	"github.com/openyurtio/openyurt/pkg/util/pubkeypin"
 	"github.com/openyurtio/openyurt/pkg/yurthub/certificate/token/testdata"
 )
 
@@ -308,3 +311,52 @@ func TestReady(t *testing.T) {
 
 	os.RemoveAll(rootDir)
 }
+
+func TestRetrieveHubBootstrapConfigFromCAFile(t *testing.T) {
+	caFile := "./testdata/ca.crt"
+	caData, err := os.ReadFile(caFile)
+	if err != nil {
+		t.Fatalf("failed to read %s, %v", caFile, err)
+	}
+	certs, err := certutil.ParseCertsPEM(caData)
+	if err != nil {
+		t.Fatalf("failed to parse %s, %v", caFile, err)
+	}
+
+	testcases := map[string]struct {
+		caCertHashes []string
+		expectErr    bool
+	}{
+		"ca is not pinned": {},
+		"ca is pinned": {
+			caCertHashes: []string{pubkeypin.Hash(certs[0])},
+		},
+		"ca mismatches pinned hash": {
+			caCertHashes: []string{"sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+			expectErr:    true,
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			ycm := &yurtHubCertManager{
+				hubRunDir:    t.TempDir(),
+				hubName:      projectinfo.GetHubName(),
+				caCertHashes: tc.caCertHashes,
+			}
+			cfg, err := ycm.retrieveHubBootstrapConfigFromCAFile("127.0.0.1:6443", caFile, joinToken)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expect error %v, but got %v", tc.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			cluster := kubeconfigutil.GetClusterFromKubeConfig(cfg)
+			if cluster.Server != "https://127.0.0.1:6443" || string(cluster.CertificateAuthorityData) != string(caData) {
+				t.Errorf("unexpected cluster in bootstrap config, %#v", cluster)
+			}
+			if _, err := os.Stat(ycm.getBootstrapConfFile()); err != nil {
+				t.Errorf("expect bootstrap config is saved, %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	"k8s.io/klog/v2"
+)
+
+// Distribution is the kubernetes distribution of the cloud control plane
+type Distribution string
+
+const (
+	DistributionUpstream Distribution = "upstream"
+	DistributionK3s      Distribution = "k3s"
+	DistributionRKE2     Distribution = "rke2"
+	DistributionK0s      Distribution = "k0s"
+)
+
+// WellKnownCAFiles are the CA files of cloud kube-apiservers which are written on the node by the
+// agents of lightweight distributions, these distributions don't publish the cluster-info configmap
+// for token bootstrapping.
+var WellKnownCAFiles = map[Distribution]string{
+	DistributionK3s:  "/var/lib/rancher/k3s/agent/server-ca.crt",
+	DistributionRKE2: "/var/lib/rancher/rke2/agent/server-ca.crt",
+	DistributionK0s:  "/var/lib/k0s/pki/ca.crt",
+}
+
+// DetectCAFile returns the well known CA file which exists on the node, empty string is returned
+// if the node is not joined by the agent of lightweight distributions.
+func DetectCAFile() string {
+	for _, d := range []Distribution{DistributionK3s, DistributionRKE2, DistributionK0s} {
+		if _, err := os.Stat(WellKnownCAFiles[d]); err == nil {
+			return WellKnownCAFiles[d]
+		}
+	}
+	return ""
+}
+
+// DetectDistribution detects the distribution by the build metadata of git version, like v1.26.4+k3s1.
+func DetectDistribution(gitVersion string) Distribution {
+	i := strings.Index(gitVersion, "+")
+	if i == -1 {
+		return DistributionUpstream
+	}
+	metadata := gitVersion[i+1:]
+	for _, d := range []Distribution{DistributionK3s, DistributionRKE2, DistributionK0s} {
+		if strings.HasPrefix(metadata, string(d)) {
+			return d
+		}
+	}
+	return DistributionUpstream
+}
+
+// Check is a check of compatibility self-test
+type Check struct {
+	Name     string
+	Required bool
+	Passed   bool
+	Message  string
+}
+
+// Report is the result of compatibility self-test against the cloud control plane
+type Report struct {
+	Distribution  Distribution
+	ServerVersion string
+	// NodePoolsServed reports the nodepools of apps.openyurt.io are served or not, the nodepool
+	// informer never syncs if the crd of yurt-manager is not installed.
+	NodePoolsServed bool
+	// ClusterInfoPublished reports the cluster-info configmap in kube-public is published or not
+	ClusterInfoPublished bool
+	Checks               []Check
+}
+
+// apiCheck requires one of the group versions is served
+type apiCheck struct {
+	name          string
+	required      bool
+	groupVersions []string
+	usage         string
+}
+
+var apiChecks = []apiCheck{
+	{name: "leases", required: true, groupVersions: []string{"coordination.k8s.io/v1"}, usage: "node heartbeats"},
+	{name: "certificatesigningrequests", required: true, groupVersions: []string{"certificates.k8s.io/v1", "certificates.k8s.io/v1beta1"}, usage: "certificates bootstrapping and rotation"},
+	{name: "endpointslices", groupVersions: []string{"discovery.k8s.io/v1", "discovery.k8s.io/v1beta1"}, usage: "service topology filter"},
+	{name: "nodepools", groupVersions: []string{"apps.openyurt.io/v1alpha1"}, usage: "service topology filter by nodepool"},
+}
+
+// SelfTest checks the cloud control plane serves the APIs and discovery data yurthub depends on.
+// An error is returned if the control plane can't be reached.
+func SelfTest(client kubernetes.Interface) (*Report, error) {
+	version, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server version, %w", err)
+	}
+	report := &Report{
+		Distribution:  DetectDistribution(version.GitVersion),
+		ServerVersion: version.GitVersion,
+	}
+
+	groups, err := client.Discovery().ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server groups, %w", err)
+	}
+	served := sets.NewString()
+	for _, g := range groups.Groups {
+		for _, v := range g.Versions {
+			served.Insert(v.GroupVersion)
+		}
+	}
+	for _, c := range apiChecks {
+		check := Check{Name: c.name, Required: c.required, Passed: served.HasAny(c.groupVersions...)}
+		if check.Passed {
+			check.Message = fmt.Sprintf("%s is served for %s", c.name, c.usage)
+		} else {
+			check.Message = fmt.Sprintf("none of %s is served, %s is unavailable", strings.Join(c.groupVersions, ", "), c.usage)
+		}
+		if c.name == "nodepools" {
+			report.NodePoolsServed = check.Passed
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	check := Check{Name: bootstrapapi.ConfigMapClusterInfo}
+	_, err = client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(context.Background(), bootstrapapi.ConfigMapClusterInfo, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		check.Passed, check.Message = true, "token bootstrapping by cluster-info is supported"
+	case apierrors.IsNotFound(err):
+		check.Message = "cluster-info is not published, CA of cloud is read from --ca-file or the well known CA file of distribution for token bootstrapping"
+	default:
+		check.Message = fmt.Sprintf("failed to get cluster-info, %v", err)
+	}
+	report.ClusterInfoPublished = check.Passed
+	report.Checks = append(report.Checks, check)
+	return report, nil
+}
+
+// Err returns an error if any required check is failed
+func (r *Report) Err() error {
+	var failed []string
+	for _, c := range r.Checks {
+		if c.Required && !c.Passed {
+			failed = append(failed, c.Message)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cloud control plane %s(%s) is not compatible: %s", r.Distribution, r.ServerVersion, strings.Join(failed, "; "))
+}
+
+// Log logs the result of checks
+func (r *Report) Log() {
+	klog.Infof("compatibility self-test against %s control plane %s", r.Distribution, r.ServerVersion)
+	for _, c := range r.Checks {
+		if c.Passed {
+			klog.V(2).Infof("compatibility check %s passed, %s", c.Name, c.Message)
+		} else {
+			klog.Warningf("compatibility check %s failed, %s", c.Name, c.Message)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compat
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDetectDistribution(t *testing.T) {
+	testcases := map[string]Distribution{
+		"v1.22.3":           DistributionUpstream,
+		"v1.26.4+k3s1":      DistributionK3s,
+		"v1.26.4+rke2r1":    DistributionRKE2,
+		"v1.27.2+k0s":       DistributionK0s,
+		"v1.24.1+vmware.1":  DistributionUpstream,
+		"v1.25.0-rc.1+k3s1": DistributionK3s,
+	}
+	for gitVersion, expected := range testcases {
+		if got := DetectDistribution(gitVersion); got != expected {
+			t.Errorf("expect %s for %s, but got %s", expected, gitVersion, got)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	clusterInfo := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-info", Namespace: metav1.NamespacePublic},
+	}
+	testcases := map[string]struct {
+		gitVersion      string
+		groupVersions   []string
+		objects         []runtime.Object
+		distribution    Distribution
+		nodePoolsServed bool
+		clusterInfo     bool
+		expectErr       bool
+	}{
+		"upstream control plane": {
+			gitVersion:      "v1.22.3",
+			groupVersions:   []string{"coordination.k8s.io/v1", "certificates.k8s.io/v1", "discovery.k8s.io/v1", "apps.openyurt.io/v1alpha1"},
+			objects:         []runtime.Object{clusterInfo},
+			distribution:    DistributionUpstream,
+			nodePoolsServed: true,
+			clusterInfo:     true,
+		},
+		"k3s without cluster-info and nodepools": {
+			gitVersion:    "v1.26.4+k3s1",
+			groupVersions: []string{"coordination.k8s.io/v1", "certificates.k8s.io/v1", "discovery.k8s.io/v1"},
+			distribution:  DistributionK3s,
+		},
+		"control plane without leases": {
+			gitVersion:    "v1.13.0",
+			groupVersions: []string{"certificates.k8s.io/v1beta1"},
+			distribution:  DistributionUpstream,
+			expectErr:     true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tc.objects...)
+			discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+			discovery.FakedServerVersion = &version.Info{GitVersion: tc.gitVersion}
+			for _, gv := range tc.groupVersions {
+				discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: gv})
+			}
+
+			report, err := SelfTest(client)
+			if err != nil {
+				t.Fatalf("failed to run self-test, %v", err)
+			}
+			if report.Distribution != tc.distribution {
+				t.Errorf("expect distribution %s, but got %s", tc.distribution, report.Distribution)
+			}
+			if report.NodePoolsServed != tc.nodePoolsServed {
+				t.Errorf("expect nodepools served %v, but got %v", tc.nodePoolsServed, report.NodePoolsServed)
+			}
+			if report.ClusterInfoPublished != tc.clusterInfo {
+				t.Errorf("expect cluster-info published %v, but got %v", tc.clusterInfo, report.ClusterInfoPublished)
+			}
+			if (report.Err() != nil) != tc.expectErr {
+				t.Errorf("expect error %v, but got %v", tc.expectErr, report.Err())
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialprovider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// CredentialProviderPath is the path which yurthub serves the credential provider requests on
+	CredentialProviderPath = "/v1/credential-provider"
+	// CredentialProviderSocket is the name of unix socket in the root dir of yurthub which serves the
+	// credential provider requests, the registry credentials are only served on this socket, so only
+	// the processes with access to the root dir of yurthub on the node can get them.
+	CredentialProviderSocket = "credential-provider.sock"
+)
+
+// RunPlugin forwards the CredentialProviderRequest of kubelet in stdin to yurthub on the unix
+// socket, and writes the CredentialProviderResponse into stdout.
+func RunPlugin(socket string, stdin io.Reader, stdout io.Writer) error {
+	request, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credential provider request, %w", err)
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	// the host is ignored, the request is always sent to the unix socket
+	resp, err := client.Post("http://yurthub"+CredentialProviderPath, "application/json", bytes.NewReader(request))
+	if err != nil {
+		return fmt.Errorf("failed to request yurthub for registry credentials, %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response of yurthub, %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("yurthub responded %d, %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, err = stdout.Write(body)
+	return err
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialprovider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	credentialproviderv1alpha1 "k8s.io/kubelet/pkg/apis/credentialprovider/v1alpha1"
+)
+
+const (
+	syncPeriod           = 5 * time.Minute
+	defaultCacheDuration = 5 * time.Minute
+	credentialsFileName  = "credentials.json"
+	dockerHubRegistry    = "docker.io"
+)
+
+// supportedAPIVersions are the api versions of kubelet credential provider, the requests and
+// responses of them are in the same format.
+var supportedAPIVersions = sets.NewString(
+	"credentialprovider.kubelet.k8s.io/v1alpha1",
+	"credentialprovider.kubelet.k8s.io/v1beta1",
+	"credentialprovider.kubelet.k8s.io/v1",
+)
+
+// Provider serves the registry credentials in docker config secrets for the kubelet image
+// credential provider plugin, so private registry pulls work at the edge without baking
+// credentials into every node. The credentials are synced from the cloud periodically and
+// saved into dir, so they are served when the cloud is unreachable or yurthub restarts.
+type Provider struct {
+	client  kubernetes.Interface
+	secrets []string
+	dir     string
+	sync.RWMutex
+	// auths are keyed by registry host
+	auths map[string]credentialproviderv1alpha1.AuthConfig
+}
+
+// NewProvider creates a Provider for secrets in format namespace/name, the secrets should be
+// kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg secrets.
+func NewProvider(client kubernetes.Interface, secrets []string, dir string) *Provider {
+	p := &Provider{
+		client:  client,
+		secrets: secrets,
+		dir:     dir,
+		auths:   make(map[string]credentialproviderv1alpha1.AuthConfig),
+	}
+	p.load()
+	return p
+}
+
+// Run syncs the credentials periodically until stopCh is closed
+func (p *Provider) Run(stopCh <-chan struct{}) {
+	wait.Until(p.sync, syncPeriod, stopCh)
+}
+
+// sync keeps the saved credentials if any secret can't be got, so the credentials of the secret
+// are not lost during the network outage.
+func (p *Provider) sync() {
+	auths := make(map[string]credentialproviderv1alpha1.AuthConfig)
+	for _, key := range p.secrets {
+		ns, name, err := splitSecretKey(key)
+		if err != nil {
+			klog.Errorf("registry credential secret %s is invalid, %v", key, err)
+			continue
+		}
+		secret, err := p.client.CoreV1().Secrets(ns).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			klog.Errorf("failed to get registry credential secret %s, %v", key, err)
+			return
+		}
+		secretAuths, err := parseDockerConfigSecret(secret)
+		if err != nil {
+			klog.Errorf("failed to parse registry credential secret %s, %v", key, err)
+			continue
+		}
+		// the former secret wins if registries are duplicated
+		for registry, auth := range secretAuths {
+			if _, ok := auths[registry]; !ok {
+				auths[registry] = auth
+			}
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if reflect.DeepEqual(auths, p.auths) {
+		return
+	}
+	p.auths = auths
+	klog.Infof("credentials of %d registries are synced", len(auths))
+	p.save()
+}
+
+func splitSecretKey(key string) (string, string, error) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("secret should be in format namespace/name")
+	}
+	return parts[0], parts[1], nil
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// parseDockerConfigSecret returns the credentials in secret which are keyed by registry host
+func parseDockerConfigSecret(secret *corev1.Secret) (map[string]credentialproviderv1alpha1.AuthConfig, error) {
+	entries := map[string]dockerConfigEntry{}
+	switch {
+	case len(secret.Data[corev1.DockerConfigJsonKey]) != 0:
+		var config struct {
+			Auths map[string]dockerConfigEntry `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, err
+		}
+		entries = config.Auths
+	case len(secret.Data[corev1.DockerConfigKey]) != 0:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &entries); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("no docker config in secret")
+	}
+
+	auths := make(map[string]credentialproviderv1alpha1.AuthConfig, len(entries))
+	for registry, entry := range entries {
+		auth := credentialproviderv1alpha1.AuthConfig{Username: entry.Username, Password: entry.Password}
+		if len(entry.Auth) != 0 {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("auth of registry %s is invalid, %w", registry, err)
+			}
+			userPass := strings.SplitN(string(decoded), ":", 2)
+			if len(userPass) != 2 {
+				return nil, fmt.Errorf("auth of registry %s should be in format username:password", registry)
+			}
+			auth.Username, auth.Password = userPass[0], userPass[1]
+		}
+		auths[normalizeRegistry(registry)] = auth
+	}
+	return auths, nil
+}
+
+// normalizeRegistry strips the scheme and path of registry in docker config,
+// e.g. https://index.docker.io/v1/ is normalized to docker.io
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry = strings.SplitN(registry, "/", 2)[0]
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// registryOf returns the registry host of image, docker.io is the registry of the images without host.
+func registryOf(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 || (!strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost") {
+		return dockerHubRegistry
+	}
+	return normalizeRegistry(parts[0])
+}
+
+// matches checks the registry host matches the pattern in docker config, the pattern can be a
+// wildcard domain like *.example.com.
+func matches(pattern, registry string) bool {
+	if pattern == registry {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(registry, pattern[1:])
+	}
+	return false
+}
+
+// Lookup returns the credentials for pulling image, keyed by the registry host. The exact pattern
+// of registry is preferred, otherwise the longest matched wildcard pattern is used.
+func (p *Provider) Lookup(image string) map[string]credentialproviderv1alpha1.AuthConfig {
+	registry := registryOf(image)
+	p.RLock()
+	defer p.RUnlock()
+
+	matched := ""
+	for pattern := range p.auths {
+		if !matches(pattern, registry) {
+			continue
+		}
+		if pattern == registry {
+			matched = pattern
+			break
+		}
+		// patterns of the same length are compared for a stable choice
+		if len(pattern) > len(matched) || (len(pattern) == len(matched) && pattern < matched) {
+			matched = pattern
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	auth := p.auths[matched]
+	result := map[string]credentialproviderv1alpha1.AuthConfig{registry: auth}
+	// kubelet matches the images without host by index.docker.io
+	if registry == dockerHubRegistry {
+		result["index.docker.io"] = auth
+	}
+	return result
+}
+
+// ServeHTTP handles the CredentialProviderRequest of kubelet plugin, and writes back the
+// CredentialProviderResponse in the same api version.
+func (p *Provider) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var request credentialproviderv1alpha1.CredentialProviderRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode credential provider request, %v", err), http.StatusBadRequest)
+		return
+	}
+	if !supportedAPIVersions.Has(request.APIVersion) || request.Kind != "CredentialProviderRequest" {
+		http.Error(w, fmt.Sprintf("%s %s is not supported", request.APIVersion, request.Kind), http.StatusBadRequest)
+		return
+	}
+	if len(request.Image) == 0 {
+		http.Error(w, "image is not specified", http.StatusBadRequest)
+		return
+	}
+
+	response := credentialproviderv1alpha1.CredentialProviderResponse{
+		TypeMeta:      metav1.TypeMeta{APIVersion: request.APIVersion, Kind: "CredentialProviderResponse"},
+		CacheKeyType:  credentialproviderv1alpha1.RegistryPluginCacheKeyType,
+		CacheDuration: &metav1.Duration{Duration: defaultCacheDuration},
+		Auth:          p.Lookup(request.Image),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&response); err != nil {
+		klog.Errorf("failed to write credential provider response for image %s, %v", request.Image, err)
+	}
+}
+
+// save writes the credentials into dir with the lock held, the file is only readable by yurthub.
+func (p *Provider) save() {
+	data, err := json.Marshal(p.auths)
+	if err != nil {
+		klog.Errorf("failed to encode registry credentials, %v", err)
+		return
+	}
+	if err := os.MkdirAll(p.dir, 0700); err != nil {
+		klog.Errorf("failed to create dir for registry credentials, %v", err)
+		return
+	}
+	path := filepath.Join(p.dir, credentialsFileName)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		klog.Errorf("failed to save registry credentials, %v", err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		klog.Errorf("failed to save registry credentials, %v", err)
+	}
+}
+
+func (p *Provider) load() {
+	data, err := os.ReadFile(filepath.Join(p.dir, credentialsFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("failed to load registry credentials, %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &p.auths); err != nil {
+		klog.Errorf("failed to decode registry credentials, %v", err)
+		return
+	}
+	klog.Infof("credentials of %d registries are loaded", len(p.auths))
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialprovider
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	credentialproviderv1alpha1 "k8s.io/kubelet/pkg/apis/credentialprovider/v1alpha1"
+)
+
+func newDockerConfigSecret(name string, auths map[string]string) *corev1.Secret {
+	entries := map[string]dockerConfigEntry{}
+	for registry, userPass := range auths {
+		entries[registry] = dockerConfigEntry{Auth: base64.StdEncoding.EncodeToString([]byte(userPass))}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"auths": entries})
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: name},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: data},
+	}
+}
+
+func TestParseDockerConfigSecret(t *testing.T) {
+	testcases := map[string]struct {
+		secret *corev1.Secret
+		result map[string]credentialproviderv1alpha1.AuthConfig
+		isErr  bool
+	}{
+		"dockerconfigjson with auth": {
+			secret: newDockerConfigSecret("regcred", map[string]string{"https://index.docker.io/v1/": "foo:bar"}),
+			result: map[string]credentialproviderv1alpha1.AuthConfig{"docker.io": {Username: "foo", Password: "bar"}},
+		},
+		"dockercfg with username and password": {
+			secret: &corev1.Secret{Data: map[string][]byte{
+				corev1.DockerConfigKey: []byte(`{"registry.example.com:5000":{"username":"foo","password":"bar"}}`),
+			}},
+			result: map[string]credentialproviderv1alpha1.AuthConfig{"registry.example.com:5000": {Username: "foo", Password: "bar"}},
+		},
+		"invalid auth": {
+			secret: newDockerConfigSecret("regcred", map[string]string{"registry.example.com": "foo"}),
+			isErr:  true,
+		},
+		"no docker config": {
+			secret: &corev1.Secret{Data: map[string][]byte{"token": []byte("xxx")}},
+			isErr:  true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			result, err := parseDockerConfigSecret(tc.secret)
+			if (err != nil) != tc.isErr {
+				t.Fatalf("expect error %v, but got %v", tc.isErr, err)
+			}
+			if !tc.isErr && !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("expect %v, but got %v", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestRegistryOf(t *testing.T) {
+	testcases := map[string]string{
+		"nginx":                                "docker.io",
+		"library/nginx:1.21":                   "docker.io",
+		"docker.io/library/nginx":              "docker.io",
+		"index.docker.io/library/nginx":        "docker.io",
+		"localhost/nginx":                      "localhost",
+		"registry.example.com:5000/app/web:v1": "registry.example.com:5000",
+	}
+
+	for image, registry := range testcases {
+		if got := registryOf(image); got != registry {
+			t.Errorf("expect registry %s of image %s, but got %s", registry, image, got)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	p := &Provider{auths: map[string]credentialproviderv1alpha1.AuthConfig{
+		"docker.io":          {Username: "hub"},
+		"*.example.com":      {Username: "wildcard"},
+		"*.eu.example.com":   {Username: "eu"},
+		"app.eu.example.com": {Username: "app"},
+		"foo.myregistry":     {Username: "foo"},
+	}}
+
+	testcases := map[string]map[string]credentialproviderv1alpha1.AuthConfig{
+		"nginx": {
+			"docker.io":       {Username: "hub"},
+			"index.docker.io": {Username: "hub"},
+		},
+		"registry.example.com/app":    {"registry.example.com": {Username: "wildcard"}},
+		"example.com/app":             nil,
+		"registry.eu.example.com/app": {"registry.eu.example.com": {Username: "eu"}},
+		"app.eu.example.com/app":      {"app.eu.example.com": {Username: "app"}},
+		"foo.myregistry/app":          {"foo.myregistry": {Username: "foo"}},
+		"bar.myregistry/app":          nil,
+	}
+
+	for image, result := range testcases {
+		if got := p.Lookup(image); !reflect.DeepEqual(got, result) {
+			t.Errorf("expect %v for image %s, but got %v", result, image, got)
+		}
+	}
+}
+
+func TestSyncAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	client := fake.NewSimpleClientset(newDockerConfigSecret("regcred", map[string]string{"registry.example.com": "foo:bar"}))
+	p := NewProvider(client, []string{"kube-system/regcred", "kube-system/notfound"}, dir)
+
+	// credentials are kept if any secret can't be got
+	p.sync()
+	if len(p.Lookup("registry.example.com/app")) != 0 {
+		t.Fatalf("expect no credentials synced when secret is not found")
+	}
+
+	p.secrets = []string{"kube-system/regcred"}
+	p.sync()
+	if len(p.Lookup("registry.example.com/app")) != 1 {
+		t.Fatalf("expect credentials of registry.example.com synced")
+	}
+
+	// credentials are loaded from the disk when cloud is unreachable
+	offline := NewProvider(fake.NewSimpleClientset(), []string{"kube-system/regcred"}, dir)
+	offline.sync()
+	if got := offline.Lookup("registry.example.com/app"); !reflect.DeepEqual(got, p.Lookup("registry.example.com/app")) {
+		t.Errorf("expect credentials loaded from disk, but got %v", got)
+	}
+}
+
+func TestServeHTTPAndRunPlugin(t *testing.T) {
+	p := &Provider{auths: map[string]credentialproviderv1alpha1.AuthConfig{
+		"registry.example.com": {Username: "foo", Password: "bar"},
+	}}
+	socket := filepath.Join(t.TempDir(), CredentialProviderSocket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on %s, %v", socket, err)
+	}
+	server := &httptest.Server{Listener: listener, Config: &http.Server{Handler: p}}
+	server.Start()
+	defer server.Close()
+
+	testcases := map[string]struct {
+		request string
+		isErr   bool
+		auth    map[string]credentialproviderv1alpha1.AuthConfig
+	}{
+		"v1alpha1 request": {
+			request: `{"apiVersion":"credentialprovider.kubelet.k8s.io/v1alpha1","kind":"CredentialProviderRequest","image":"registry.example.com/app:v1"}`,
+			auth:    map[string]credentialproviderv1alpha1.AuthConfig{"registry.example.com": {Username: "foo", Password: "bar"}},
+		},
+		"image without credentials": {
+			request: `{"apiVersion":"credentialprovider.kubelet.k8s.io/v1alpha1","kind":"CredentialProviderRequest","image":"nginx"}`,
+		},
+		"unsupported api version": {
+			request: `{"apiVersion":"credentialprovider.kubelet.k8s.io/v2","kind":"CredentialProviderRequest","image":"nginx"}`,
+			isErr:   true,
+		},
+		"no image": {
+			request: `{"apiVersion":"credentialprovider.kubelet.k8s.io/v1alpha1","kind":"CredentialProviderRequest"}`,
+			isErr:   true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			var out bytes.Buffer
+			err := RunPlugin(socket, strings.NewReader(tc.request), &out)
+			if (err != nil) != tc.isErr {
+				t.Fatalf("expect error %v, but got %v", tc.isErr, err)
+			}
+			if tc.isErr {
+				return
+			}
+
+			var response credentialproviderv1alpha1.CredentialProviderResponse
+			if err := json.Unmarshal(out.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response, %v", err)
+			}
+			if response.APIVersion != "credentialprovider.kubelet.k8s.io/v1alpha1" || response.Kind != "CredentialProviderResponse" {
+				t.Errorf("unexpected type meta %v", response.TypeMeta)
+			}
+			if response.CacheKeyType != credentialproviderv1alpha1.RegistryPluginCacheKeyType {
+				t.Errorf("expect cache key type registry, but got %s", response.CacheKeyType)
+			}
+			if len(response.Auth) != len(tc.auth) || (len(tc.auth) != 0 && !reflect.DeepEqual(response.Auth, tc.auth)) {
+				t.Errorf("expect auth %v, but got %v", tc.auth, response.Auth)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, CredentialProviderPath, strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expect status 400 for invalid request, but got %d", rec.Code)
+	}
+}
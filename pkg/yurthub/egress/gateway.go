@@ -0,0 +1,331 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	appsv1beta1 "github.com/openyurtio/openyurt/pkg/apis/apps/v1beta1"
+	"github.com/openyurtio/openyurt/pkg/util/egresspolicy"
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+const (
+	defaultSyncPeriod = 30 * time.Second
+	handshakeTimeout  = 10 * time.Second
+	dialTimeout       = 30 * time.Second
+	// copyBufferSize is also the burst of bandwidth limiter
+	copyBufferSize = 32 * 1024
+)
+
+var errNotSynced = errors.New("egress policy of node is not synced")
+
+// policy is the compiled egress policy of nodepool
+type policy struct {
+	destinations []*egresspolicy.Destination
+	// limiter is nil if the bandwidth is unlimited
+	limiter *rate.Limiter
+}
+
+// Gateway is a SOCKS5 proxy on the node for the cloud-bound traffic of pods, the destinations and bandwidth
+// are restricted by the egress policy of nodepool, which is published on the node by annotation
+// nodepool.openyurt.io/egress-policy. The node is got from the local cache of kubelet, so the policy is
+// still enforced when the cloud is unreachable.
+// The clients are authenticated by username and password. The gateway runs in the host network, so the
+// loopback, link-local and node addresses are always rejected, like yurthub proxy and kubelet on the node.
+type Gateway struct {
+	bindAddr    string
+	nodeName    string
+	store       cachemanager.StorageWrapper
+	syncPeriod  time.Duration
+	resolver    *net.Resolver
+	dialer      *net.Dialer
+	credentials map[string]string
+	// isNodeLocal returns true for the addresses which are never allowed, it's only changed in tests
+	isNodeLocal func(ip net.IP) bool
+
+	sync.RWMutex
+	synced bool
+	// annotation is the egress policy annotation which policy is compiled from
+	annotation string
+	// policy is nil if the nodepool has no egress policy, all traffic is rejected
+	policy *policy
+}
+
+// NewGateway creates a Gateway serving on bindAddr for the clients in credentials, which maps username to
+// password. All traffic is rejected until the node is found in local cache.
+func NewGateway(bindAddr, nodeName string, store cachemanager.StorageWrapper, credentials map[string]string) *Gateway {
+	return &Gateway{
+		bindAddr:    bindAddr,
+		nodeName:    nodeName,
+		store:       store,
+		syncPeriod:  defaultSyncPeriod,
+		resolver:    net.DefaultResolver,
+		dialer:      &net.Dialer{Timeout: dialTimeout},
+		credentials: credentials,
+		isNodeLocal: isNodeLocal,
+	}
+}
+
+// LoadCredentials loads the credentials of egress gateway from file, which has one username:password per line.
+func LoadCredentials(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials of egress gateway, %w", err)
+	}
+	credentials := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, password, ok := strings.Cut(line, ":")
+		if !ok || len(username) == 0 || len(password) == 0 || len(username) > 255 || len(password) > 255 {
+			return nil, fmt.Errorf("invalid credential in %s, username:password is expected", path)
+		}
+		credentials[username] = password
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("no credential is found in %s", path)
+	}
+	return credentials, nil
+}
+
+// isNodeLocal returns true for the loopback, link-local and unspecified addresses, and the addresses of node.
+func isNodeLocal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		// the node addresses are unknown, so nothing is allowed
+		klog.Errorf("failed to get addresses of node, %v", err)
+		return true
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run syncs the egress policy and serves the gateway until stopCh is closed
+func (g *Gateway) Run(stopCh <-chan struct{}) {
+	listener, err := net.Listen("tcp", g.bindAddr)
+	if err != nil {
+		klog.Errorf("failed to listen on %s for egress gateway, %v", g.bindAddr, err)
+		return
+	}
+	go func() {
+		<-stopCh
+		listener.Close()
+	}()
+	go g.syncPolicy(stopCh)
+	g.serve(listener)
+}
+
+func (g *Gateway) syncPolicy(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(g.syncPeriod)
+	defer ticker.Stop()
+	for {
+		g.sync()
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync keeps the last policy if the node can't be got from local cache or the policy is invalid.
+func (g *Gateway) sync() {
+	node, err := g.cachedNode()
+	if err != nil {
+		if err != storage.ErrStorageNotFound {
+			klog.Errorf("failed to get node %s from local cache for egress policy, %v", g.nodeName, err)
+		}
+		return
+	}
+
+	annotation := node.Annotations[apps.AnnotationEgressPolicy]
+	g.RLock()
+	unchanged := g.synced && g.annotation == annotation
+	g.RUnlock()
+	if unchanged {
+		return
+	}
+
+	p, err := compilePolicy(annotation)
+	if err != nil {
+		klog.Errorf("egress policy %q of node %s is invalid, %v", annotation, g.nodeName, err)
+		return
+	}
+	g.Lock()
+	defer g.Unlock()
+	g.synced, g.annotation, g.policy = true, annotation, p
+	if p == nil {
+		klog.Infof("egress traffic of pods is rejected, nodepool of node %s has no egress policy", g.nodeName)
+	} else {
+		klog.Infof("egress traffic of pods is restricted by policy %s", annotation)
+	}
+}
+
+func (g *Gateway) cachedNode() (*corev1.Node, error) {
+	key, err := g.store.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: "nodes",
+		Version:   "v1",
+		Name:      g.nodeName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	obj, err := g.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a node", obj)
+	}
+	return node, nil
+}
+
+// compilePolicy returns nil if there is no egress policy
+func compilePolicy(annotation string) (*policy, error) {
+	if len(annotation) == 0 {
+		return nil, nil
+	}
+	var egress appsv1beta1.NodePoolEgressPolicy
+	if err := json.Unmarshal([]byte(annotation), &egress); err != nil {
+		return nil, err
+	}
+
+	p := &policy{}
+	for _, dest := range egress.Destinations {
+		d, err := egresspolicy.ParseDestination(dest)
+		if err != nil {
+			return nil, err
+		}
+		p.destinations = append(p.destinations, d)
+	}
+	bandwidth, err := egresspolicy.Bandwidth(egress.BandwidthClass)
+	if err != nil {
+		return nil, err
+	}
+	if bandwidth != 0 {
+		p.limiter = rate.NewLimiter(rate.Limit(bandwidth), copyBufferSize)
+	}
+	return p, nil
+}
+
+func (g *Gateway) currentPolicy() (*policy, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if !g.synced {
+		return nil, errNotSynced
+	}
+	return g.policy, nil
+}
+
+// resolve returns the address to dial for host and port. A domain is resolved, all its addresses are allowed
+// if it's allowed by a domain destination, otherwise the first address allowed by cidr destinations is dialed.
+// Nothing is allowed if there is no policy, and the node local addresses are never dialed, so a domain
+// resolved to them is rejected as well.
+func (g *Gateway) resolve(ctx context.Context, p *policy, host string, port int) (string, error) {
+	if p == nil {
+		return "", errNotAllowed
+	}
+
+	var ips []net.IP
+	domainAllowed := false
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		for _, d := range p.destinations {
+			if d.MatchesDomain(host, port) {
+				domainAllowed = true
+				break
+			}
+		}
+		resolved, err := g.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s, %w", host, err)
+		}
+		for i := range resolved {
+			ips = append(ips, resolved[i].IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if g.isNodeLocal(ip) {
+			continue
+		}
+		if domainAllowed {
+			return net.JoinHostPort(ip.String(), fmt.Sprint(port)), nil
+		}
+		for _, d := range p.destinations {
+			if d.MatchesIP(ip, port) {
+				return net.JoinHostPort(ip.String(), fmt.Sprint(port)), nil
+			}
+		}
+	}
+	return "", errNotAllowed
+}
+
+// copyLimited copies from src to dst, the bandwidth is limited by limiter if it's not nil.
+func copyLimited(dst io.Writer, src io.Reader, limiter *rate.Limiter) error {
+	buf := make([]byte, copyBufferSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if limiter != nil {
+				if wErr := limiter.WaitN(context.Background(), n); wErr != nil {
+					return wErr
+				}
+			}
+			if _, wErr := dst.Write(buf[:n]); wErr != nil {
+				return wErr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
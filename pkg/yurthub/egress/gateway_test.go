@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/proxy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+)
+
+func cacheNode(t *testing.T, store cachemanager.StorageWrapper, annotations map[string]string) {
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Annotations: annotations},
+	}
+	key, _ := store.KeyFunc(storage.KeyBuildInfo{Component: "kubelet", Resources: "nodes", Version: "v1", Name: "node1"})
+	_ = store.Delete(key)
+	if err := store.Create(key, node); err != nil {
+		t.Fatalf("failed to cache node, %v", err)
+	}
+}
+
+func TestGateway(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer backend.Close()
+
+	ds, err := disk.NewDiskStorage(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	store := cachemanager.NewStorageWrapper(ds)
+	g := NewGateway("127.0.0.1:0", "node1", store, map[string]string{"app": "secret"})
+	// the backend listens on loopback, only the addresses of node are rejected in test
+	g.isNodeLocal = func(ip net.IP) bool { return ip.Equal(net.ParseIP("192.168.0.1")) }
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen, %v", err)
+	}
+	defer listener.Close()
+	go g.serve(listener)
+
+	newClient := func(auth *proxy.Auth) *http.Client {
+		dialer, err := proxy.SOCKS5("tcp", listener.Addr().String(), auth, proxy.Direct)
+		if err != nil {
+			t.Fatalf("failed to create socks5 dialer, %v", err)
+		}
+		return &http.Client{Transport: &http.Transport{Dial: dialer.Dial, DisableKeepAlives: true}}
+	}
+	client := newClient(&proxy.Auth{User: "app", Password: "secret"})
+	get := func() (string, error) {
+		resp, err := client.Get(backend.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	testcases := []struct {
+		name        string
+		annotations map[string]string
+		allowed     bool
+	}{
+		{
+			name:    "node is not cached",
+			allowed: false,
+		},
+		{
+			name:    "nodepool has no egress policy",
+			allowed: false,
+		},
+		{
+			name:        "destination is allowed",
+			annotations: map[string]string{apps.AnnotationEgressPolicy: `{"destinations":["127.0.0.0/8"],"bandwidthClass":"Low"}`},
+			allowed:     true,
+		},
+		{
+			name:        "destination is not allowed",
+			annotations: map[string]string{apps.AnnotationEgressPolicy: `{"destinations":["10.0.0.0/8","*.example.com"]}`},
+			allowed:     false,
+		},
+		{
+			name:        "invalid policy keeps the last policy",
+			annotations: map[string]string{apps.AnnotationEgressPolicy: `{"destinations":["10.0.0.0/33"]}`},
+			allowed:     false,
+		},
+	}
+
+	for i, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if i != 0 {
+				cacheNode(t, store, tc.annotations)
+			}
+			g.sync()
+			body, err := get()
+			if tc.allowed && (err != nil || body != "hello") {
+				t.Errorf("expect traffic allowed, but got %q, %v", body, err)
+			}
+			if !tc.allowed && err == nil {
+				t.Errorf("expect traffic rejected, but got %q", body)
+			}
+		})
+	}
+
+	for name, auth := range map[string]*proxy.Auth{
+		"client without credential": nil,
+		"client with wrong password": {User: "app", Password: "wrong"},
+		"unknown client":             {User: "other", Password: "secret"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if resp, err := newClient(auth).Get(backend.URL); err == nil {
+				resp.Body.Close()
+				t.Errorf("expect client is rejected")
+			}
+		})
+	}
+}
+
+func TestResolveNodeLocal(t *testing.T) {
+	g := NewGateway("127.0.0.1:0", "node1", nil, nil)
+	p, err := compilePolicy(`{"destinations":["0.0.0.0/0","localhost"]}`)
+	if err != nil {
+		t.Fatalf("failed to compile policy, %v", err)
+	}
+
+	for _, host := range []string{"127.0.0.1", "169.254.169.254", "0.0.0.0", "::1", "localhost"} {
+		if addr, err := g.resolve(context.TODO(), p, host, 10261); err != errNotAllowed {
+			t.Errorf("expect node local %s rejected, but got %s, %v", host, addr, err)
+		}
+	}
+	if addr, err := g.resolve(context.TODO(), p, "203.0.113.1", 443); err != nil || addr != "203.0.113.1:443" {
+		t.Errorf("expect 203.0.113.1 allowed, but got %s, %v", addr, err)
+	}
+	if _, err := g.resolve(context.TODO(), nil, "203.0.113.1", 443); err != errNotAllowed {
+		t.Errorf("expect traffic rejected without policy, but got %v", err)
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	os.WriteFile(path, []byte("# pods of app\napp:secret\n\nmonitor:pass:word\n"), 0600)
+	credentials, err := LoadCredentials(path)
+	if err != nil {
+		t.Fatalf("failed to load credentials, %v", err)
+	}
+	if len(credentials) != 2 || credentials["app"] != "secret" || credentials["monitor"] != "pass:word" {
+		t.Errorf("unexpected credentials %v", credentials)
+	}
+
+	for _, content := range []string{"", "app\n", ":secret\n"} {
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := LoadCredentials(path); err == nil {
+			t.Errorf("expect error for credentials %q", content)
+		}
+	}
+}
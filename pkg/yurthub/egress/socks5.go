@@ -0,0 +1,272 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+// the constants of SOCKS5 protocol in RFC 1928, only CONNECT command with username/password
+// authentication in RFC 1929 is supported.
+const (
+	socks5Version = 0x05
+
+	methodUserPass     = 0x02
+	methodNoAcceptable = 0xff
+
+	userPassVersion = 0x01
+	authSucceeded   = 0x00
+	authFailed      = 0x01
+
+	cmdConnect = 0x01
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	repSucceeded           = 0x00
+	repGeneralFailure      = 0x01
+	repNotAllowed          = 0x02
+	repHostUnreachable     = 0x04
+	repConnectionRefused   = 0x05
+	repCommandNotSupported = 0x07
+	repAddressNotSupported = 0x08
+)
+
+var errNotAllowed = errors.New("destination is not allowed by egress policy")
+
+func (g *Gateway) serve(listener net.Listener) {
+	klog.Infof("egress gateway is serving on %s", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			klog.Infof("egress gateway on %s is stopped, %v", listener.Addr(), err)
+			return
+		}
+		go g.handleConn(conn)
+	}
+}
+
+func (g *Gateway) handleConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := g.negotiate(conn); err != nil {
+		klog.V(4).Infof("failed to negotiate with %s, %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	host, port, rep, err := readRequest(conn)
+	if err != nil {
+		klog.V(4).Infof("invalid request from %s, %v", conn.RemoteAddr(), err)
+		if rep != repSucceeded {
+			writeReply(conn, rep, nil)
+		}
+		return
+	}
+
+	p, err := g.currentPolicy()
+	if err != nil {
+		klog.Warningf("reject egress traffic from %s to %s, %v", conn.RemoteAddr(), net.JoinHostPort(host, fmt.Sprint(port)), err)
+		writeReply(conn, repNotAllowed, nil)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	addr, err := g.resolve(ctx, p, host, port)
+	if err != nil {
+		klog.Warningf("reject egress traffic from %s to %s, %v", conn.RemoteAddr(), net.JoinHostPort(host, fmt.Sprint(port)), err)
+		if err == errNotAllowed {
+			writeReply(conn, repNotAllowed, nil)
+		} else {
+			writeReply(conn, repHostUnreachable, nil)
+		}
+		return
+	}
+
+	upstream, err := g.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		klog.V(2).Infof("failed to dial %s for %s, %v", addr, conn.RemoteAddr(), err)
+		if strings.Contains(err.Error(), "refused") {
+			writeReply(conn, repConnectionRefused, nil)
+		} else {
+			writeReply(conn, repHostUnreachable, nil)
+		}
+		return
+	}
+	defer upstream.Close()
+	if err := writeReply(conn, repSucceeded, upstream.LocalAddr()); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	klog.V(4).Infof("egress traffic from %s to %s is allowed", conn.RemoteAddr(), addr)
+
+	var limiter *rate.Limiter
+	if p != nil {
+		limiter = p.limiter
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	pipe := func(dst, src net.Conn) {
+		defer wg.Done()
+		if err := copyLimited(dst, src, limiter); err != nil {
+			klog.V(4).Infof("egress traffic from %s to %s is interrupted, %v", conn.RemoteAddr(), addr, err)
+		}
+		// close write side so the peer gets EOF, and the other direction is closed by peer
+		if tcpConn, ok := dst.(*net.TCPConn); ok {
+			tcpConn.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+	go pipe(upstream, conn)
+	go pipe(conn, upstream)
+	wg.Wait()
+}
+
+// negotiate selects username/password authentication method and authenticates the client
+func (g *Gateway) negotiate(conn net.Conn) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return fmt.Errorf("socks version %d is not supported", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return err
+	}
+	for _, m := range methods {
+		if m == methodUserPass {
+			if _, err := conn.Write([]byte{socks5Version, methodUserPass}); err != nil {
+				return err
+			}
+			return g.authenticate(conn)
+		}
+	}
+	conn.Write([]byte{socks5Version, methodNoAcceptable})
+	return errors.New("no acceptable authentication method")
+}
+
+// authenticate reads the username and password of client, and checks them against the credentials
+func (g *Gateway) authenticate(conn net.Conn) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != userPassVersion {
+		return fmt.Errorf("username/password authentication version %d is not supported", header[0])
+	}
+	username := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, username); err != nil {
+		return err
+	}
+	size := make([]byte, 1)
+	if _, err := io.ReadFull(conn, size); err != nil {
+		return err
+	}
+	password := make([]byte, size[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return err
+	}
+
+	expected, ok := g.credentials[string(username)]
+	if !ok || subtle.ConstantTimeCompare([]byte(expected), password) != 1 {
+		conn.Write([]byte{userPassVersion, authFailed})
+		return fmt.Errorf("authentication of user %q is failed", username)
+	}
+	_, err := conn.Write([]byte{userPassVersion, authSucceeded})
+	return err
+}
+
+// readRequest returns the destination of CONNECT request, and the reply code if the request is not supported.
+func readRequest(conn net.Conn) (string, int, byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", 0, repSucceeded, err
+	}
+	if header[0] != socks5Version {
+		return "", 0, repSucceeded, fmt.Errorf("socks version %d is not supported", header[0])
+	}
+	if header[1] != cmdConnect {
+		return "", 0, repCommandNotSupported, fmt.Errorf("command %d is not supported", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case atypIPv4, atypIPv6:
+		size := net.IPv4len
+		if header[3] == atypIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", 0, repSucceeded, err
+		}
+		host = net.IP(ip).String()
+	case atypDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", 0, repSucceeded, err
+		}
+		domain := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", 0, repSucceeded, err
+		}
+		host = string(domain)
+	default:
+		return "", 0, repAddressNotSupported, fmt.Errorf("address type %d is not supported", header[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", 0, repSucceeded, err
+	}
+	return host, int(binary.BigEndian.Uint16(port)), repSucceeded, nil
+}
+
+// writeReply writes the reply with bound address, the address is 0.0.0.0:0 if it's not a tcp address.
+func writeReply(conn net.Conn, rep byte, bound net.Addr) error {
+	reply := []byte{socks5Version, rep, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0}
+	if addr, ok := bound.(*net.TCPAddr); ok {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			reply = append(reply[:4], ip4...)
+		} else {
+			reply = append(append(reply[:3], atypIPv6), addr.IP.To16()...)
+		}
+		reply = append(reply, byte(addr.Port>>8), byte(addr.Port))
+	}
+	_, err := conn.Write(reply)
+	return err
+}
@@ -0,0 +1,405 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
+	"github.com/openyurtio/openyurt/pkg/yurthub/webhooks"
+)
+
+const (
+	uploadPeriod     = 5 * time.Second
+	uploadBatchSize  = 100
+	maxPendingEvents = 5000
+	eventsFileName   = "events.json"
+)
+
+// pendingEvent is an event which is not uploaded to the cloud yet, Occurrences is the number of
+// the events aggregated into it while the cloud is unreachable, and Webhooks are the admission
+// webhooks of the cloud which are deferred until the event is uploaded.
+type pendingEvent struct {
+	Event       *corev1.Event `json:"event"`
+	Occurrences int32         `json:"occurrences"`
+	Webhooks    []string      `json:"webhooks,omitempty"`
+}
+
+// Aggregator accepts the events of local components when the cloud is unreachable, the similar
+// events are aggregated into one event with count, and the events are saved into dir, so they are
+// uploaded in batches with the original timestamps when the cloud is reachable again.
+type Aggregator struct {
+	dir     string
+	matcher *webhooks.Matcher
+	sync.Mutex
+	// events are keyed by the aggregate key of event
+	events map[string]*pendingEvent
+	dirty  bool
+}
+
+// NewAggregator creates an Aggregator and loads the pending events in dir, the admission webhooks
+// deferred by the events are recorded by matcher if it's not nil.
+func NewAggregator(dir string, matcher *webhooks.Matcher) *Aggregator {
+	a := &Aggregator{
+		dir:     dir,
+		matcher: matcher,
+		events:  make(map[string]*pendingEvent),
+	}
+	a.load()
+	return a
+}
+
+// Run uploads the pending events until stopCh is closed, the events are uploaded by the client
+// of healthy cloud servers which is created by getRestConfig, so they never go back to the local proxy.
+func (a *Aggregator) Run(getRestConfig func(needHealthyServer bool) *restclient.Config, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(uploadPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			a.flush()
+			return
+		case <-ticker.C:
+			if a.pending() != 0 {
+				if cfg := getRestConfig(true); cfg != nil {
+					if client, err := kubernetes.NewForConfig(cfg); err != nil {
+						klog.Errorf("failed to create client for uploading events, %v", err)
+					} else {
+						a.upload(client)
+					}
+				}
+			}
+			a.flush()
+		}
+	}
+}
+
+func (a *Aggregator) pending() int {
+	a.Lock()
+	defer a.Unlock()
+	return len(a.events)
+}
+
+// Events returns the pending events which are not uploaded to the cloud yet
+func (a *Aggregator) Events() []*corev1.Event {
+	a.Lock()
+	defer a.Unlock()
+	events := make([]*corev1.Event, 0, len(a.events))
+	for _, pe := range a.events {
+		events = append(events, pe.Event.DeepCopy())
+	}
+	return events
+}
+
+// aggregateKey returns the key of similar events, which is the same as the key used by
+// the event correlator of client-go.
+func aggregateKey(event *corev1.Event) string {
+	return strings.Join([]string{event.Source.Component, event.Source.Host,
+		event.InvolvedObject.Kind, event.InvolvedObject.Namespace, event.InvolvedObject.Name,
+		string(event.InvolvedObject.UID), event.InvolvedObject.FieldPath,
+		event.Type, event.Reason, event.Message}, "")
+}
+
+// Record aggregates event into the pending events, and returns the aggregated event.
+func (a *Aggregator) Record(event *corev1.Event) *corev1.Event {
+	event = event.DeepCopy()
+	if event.Count == 0 {
+		event.Count = 1
+	}
+	if event.FirstTimestamp.IsZero() {
+		event.FirstTimestamp = event.LastTimestamp
+		if event.FirstTimestamp.IsZero() {
+			event.FirstTimestamp = metav1.NewTime(time.Now())
+			if !event.EventTime.IsZero() {
+				event.FirstTimestamp = metav1.NewTime(event.EventTime.Time)
+			}
+		}
+	}
+	if event.LastTimestamp.IsZero() {
+		event.LastTimestamp = event.FirstTimestamp
+	}
+
+	deferred := a.matchWebhooks(event, admissionregistrationv1.Create)
+	a.Lock()
+	defer a.Unlock()
+	a.dirty = true
+	key := aggregateKey(event)
+	if pe, ok := a.events[key]; ok {
+		pe.Webhooks = mergeWebhooks(pe.Webhooks, deferred)
+		pe.Occurrences++
+		pe.Event.Count++
+		if event.FirstTimestamp.Before(&pe.Event.FirstTimestamp) {
+			pe.Event.FirstTimestamp = event.FirstTimestamp
+		}
+		if pe.Event.LastTimestamp.Before(&event.LastTimestamp) {
+			pe.Event.LastTimestamp = event.LastTimestamp
+		}
+		return pe.Event.DeepCopy()
+	}
+
+	if len(a.events) >= maxPendingEvents {
+		a.evictOldest()
+	}
+	a.events[key] = &pendingEvent{Event: event, Occurrences: 1, Webhooks: deferred}
+	return event.DeepCopy()
+}
+
+// matchWebhooks returns the admission webhooks which would be called by the cloud for the write of event.
+func (a *Aggregator) matchWebhooks(event *corev1.Event, operation admissionregistrationv1.OperationType) []string {
+	if a.matcher == nil {
+		return nil
+	}
+	return a.matcher.Match(corev1.SchemeGroupVersion.WithResource("events"), event.Namespace, operation, event.Labels)
+}
+
+func mergeWebhooks(webhooks, deferred []string) []string {
+	for _, name := range deferred {
+		found := false
+		for i := range webhooks {
+			if webhooks[i] == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			webhooks = append(webhooks, name)
+		}
+	}
+	return webhooks
+}
+
+// DeferredWebhooks returns the admission webhooks deferred by the pending event.
+func (a *Aggregator) DeferredWebhooks(namespace, name string) []string {
+	a.Lock()
+	defer a.Unlock()
+	for _, pe := range a.events {
+		if pe.Event.Namespace == namespace && pe.Event.Name == name {
+			return append([]string(nil), pe.Webhooks...)
+		}
+	}
+	return nil
+}
+
+// evictOldest drops the event which has not occurred for the longest time, it's called with the lock held.
+func (a *Aggregator) evictOldest() {
+	var oldestKey string
+	var oldest *pendingEvent
+	for key, pe := range a.events {
+		if oldest == nil || pe.Event.LastTimestamp.Before(&oldest.Event.LastTimestamp) {
+			oldestKey, oldest = key, pe
+		}
+	}
+	if oldest != nil {
+		klog.Warningf("pending events exceed %d, drop event %s/%s", maxPendingEvents, oldest.Event.Namespace, oldest.Event.Name)
+		delete(a.events, oldestKey)
+	}
+}
+
+// Patch applies the strategic merge patch of event recorder to the pending event, the patch is
+// aggregated as an occurrence of the event. nil is returned if the event is not pending,
+// so the recorder creates the event instead.
+func (a *Aggregator) Patch(namespace, name string, patch []byte) (*corev1.Event, error) {
+	a.Lock()
+	defer a.Unlock()
+	for _, pe := range a.events {
+		if pe.Event.Namespace != namespace || pe.Event.Name != name {
+			continue
+		}
+
+		original, err := json.Marshal(pe.Event)
+		if err != nil {
+			return nil, err
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, patch, &corev1.Event{})
+		if err != nil {
+			return nil, err
+		}
+		var event corev1.Event
+		if err := json.Unmarshal(patched, &event); err != nil {
+			return nil, err
+		}
+
+		a.dirty = true
+		pe.Webhooks = mergeWebhooks(pe.Webhooks, a.matchWebhooks(pe.Event, admissionregistrationv1.Update))
+		pe.Occurrences++
+		pe.Event.Count++
+		if pe.Event.LastTimestamp.Before(&event.LastTimestamp) {
+			pe.Event.LastTimestamp = event.LastTimestamp
+		}
+		return pe.Event.DeepCopy(), nil
+	}
+	return nil, nil
+}
+
+// upload uploads a batch of the pending events in order of their first timestamps. The events
+// which already exist in the cloud are updated with the occurrences, and the events which
+// can't be uploaded are kept for the next round unless they are rejected by the cloud.
+func (a *Aggregator) upload(client kubernetes.Interface) {
+	a.Lock()
+	batch := make([]pendingEvent, 0, len(a.events))
+	for _, pe := range a.events {
+		batch = append(batch, pendingEvent{Event: pe.Event.DeepCopy(), Occurrences: pe.Occurrences, Webhooks: pe.Webhooks})
+	}
+	a.Unlock()
+
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].Event.FirstTimestamp.Before(&batch[j].Event.FirstTimestamp)
+	})
+	if len(batch) > uploadBatchSize {
+		batch = batch[:uploadBatchSize]
+	}
+
+	uploaded := 0
+	for i := range batch {
+		err := uploadEvent(client, &batch[i])
+		if err != nil && !isRejected(err) {
+			klog.Errorf("failed to upload event %s/%s, %v", batch[i].Event.Namespace, batch[i].Event.Name, err)
+			break
+		}
+		if err != nil {
+			// the event is re-validated by the admission chain of the cloud when it's uploaded, the
+			// rejects of events which deferred admission webhooks are flagged for operators.
+			if len(batch[i].Webhooks) != 0 {
+				klog.Warningf("event %s/%s recorded while the cloud is unreachable is rejected when it's replayed and dropped, deferred admission webhooks: %s, %v",
+					batch[i].Event.Namespace, batch[i].Event.Name, strings.Join(batch[i].Webhooks, ","), err)
+			} else {
+				klog.Errorf("event %s/%s is rejected by the cloud and dropped, %v", batch[i].Event.Namespace, batch[i].Event.Name, err)
+			}
+			metrics.Metrics.IncReplayedWritesRejected("events", len(batch[i].Webhooks) != 0)
+		} else {
+			uploaded++
+		}
+		a.done(&batch[i])
+	}
+	if uploaded != 0 {
+		klog.Infof("%d events recorded while the cloud is unreachable are uploaded", uploaded)
+	}
+}
+
+func uploadEvent(client kubernetes.Interface, pe *pendingEvent) error {
+	event := pe.Event.DeepCopy()
+	event.ResourceVersion = ""
+	_, err := client.CoreV1().Events(event.Namespace).Create(context.Background(), event, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.CoreV1().Events(event.Namespace).Get(context.Background(), event.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Count += pe.Occurrences
+	if existing.LastTimestamp.Before(&event.LastTimestamp) {
+		existing.LastTimestamp = event.LastTimestamp
+	}
+	_, err = client.CoreV1().Events(event.Namespace).Update(context.Background(), existing, metav1.UpdateOptions{})
+	return err
+}
+
+// isRejected checks the event is rejected by the cloud, it will never be accepted by retrying.
+func isRejected(err error) bool {
+	return apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) || apierrors.IsForbidden(err) ||
+		apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err)
+}
+
+// done removes the uploaded event from the pending events, the occurrences aggregated during
+// uploading are kept for the next round.
+func (a *Aggregator) done(uploaded *pendingEvent) {
+	a.Lock()
+	defer a.Unlock()
+	a.dirty = true
+	key := aggregateKey(uploaded.Event)
+	pe, ok := a.events[key]
+	if !ok {
+		return
+	}
+	if pe.Occurrences <= uploaded.Occurrences {
+		delete(a.events, key)
+		return
+	}
+	pe.Occurrences -= uploaded.Occurrences
+}
+
+// flush saves the pending events into dir if they are changed
+func (a *Aggregator) flush() {
+	a.Lock()
+	defer a.Unlock()
+	if !a.dirty {
+		return
+	}
+
+	events := make([]*pendingEvent, 0, len(a.events))
+	for _, pe := range a.events {
+		events = append(events, pe)
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		klog.Errorf("failed to encode pending events, %v", err)
+		return
+	}
+	if err := os.MkdirAll(a.dir, 0700); err != nil {
+		klog.Errorf("failed to create dir for pending events, %v", err)
+		return
+	}
+	path := filepath.Join(a.dir, eventsFileName)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		klog.Errorf("failed to save pending events, %v", err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		klog.Errorf("failed to save pending events, %v", err)
+		return
+	}
+	a.dirty = false
+}
+
+func (a *Aggregator) load() {
+	data, err := os.ReadFile(filepath.Join(a.dir, eventsFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("failed to load pending events, %v", err)
+		}
+		return
+	}
+	var events []*pendingEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		klog.Errorf("failed to decode pending events, %v", err)
+		return
+	}
+	for _, pe := range events {
+		if pe.Event != nil {
+			a.events[aggregateKey(pe.Event)] = pe
+		}
+	}
+	klog.Infof("%d pending events are loaded", len(a.events))
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/webhooks"
+)
+
+var baseTime = time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+
+func newEvent(name, reason string, offset time.Duration) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default", Name: name},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "nginx", UID: "uid-1"},
+		Reason:         reason,
+		Message:        reason + " of nginx",
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "kubelet", Host: "node1"},
+		FirstTimestamp: metav1.NewTime(baseTime.Add(offset)),
+		LastTimestamp:  metav1.NewTime(baseTime.Add(offset)),
+	}
+}
+
+func TestRecordAndPatch(t *testing.T) {
+	a := NewAggregator(t.TempDir(), nil)
+	a.Record(newEvent("nginx.1", "BackOff", 0))
+	event := a.Record(newEvent("nginx.2", "BackOff", time.Minute))
+	a.Record(newEvent("nginx.3", "Pulled", 2*time.Minute))
+
+	if len(a.events) != 2 {
+		t.Fatalf("expect 2 pending events, but got %d", len(a.events))
+	}
+	if event.Name != "nginx.1" || event.Count != 2 || !event.LastTimestamp.Equal(&metav1.Time{Time: baseTime.Add(time.Minute)}) ||
+		!event.FirstTimestamp.Equal(&metav1.Time{Time: baseTime}) {
+		t.Errorf("unexpected aggregated event %#v", event)
+	}
+
+	patched, err := a.Patch("default", "nginx.1", []byte(`{"count":10,"lastTimestamp":"2023-03-01T08:05:00Z"}`))
+	if err != nil {
+		t.Fatalf("failed to patch event, %v", err)
+	}
+	if patched.Count != 3 || !patched.LastTimestamp.Equal(&metav1.Time{Time: baseTime.Add(5 * time.Minute)}) {
+		t.Errorf("unexpected patched event %#v", patched)
+	}
+	if a.events[aggregateKey(patched)].Occurrences != 3 {
+		t.Errorf("expect 3 occurrences, but got %d", a.events[aggregateKey(patched)].Occurrences)
+	}
+
+	if patched, err := a.Patch("default", "unknown", []byte(`{}`)); err != nil || patched != nil {
+		t.Errorf("expect nil for the event which is not pending, but got %v, %v", patched, err)
+	}
+}
+
+func TestFlushAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	a := NewAggregator(dir, nil)
+	a.Record(newEvent("nginx.1", "BackOff", 0))
+	a.Record(newEvent("nginx.2", "BackOff", time.Minute))
+	a.flush()
+
+	loaded := NewAggregator(dir, nil)
+	if len(loaded.events) != 1 {
+		t.Fatalf("expect 1 pending event loaded, but got %d", len(loaded.events))
+	}
+	for _, pe := range loaded.events {
+		if pe.Occurrences != 2 || pe.Event.Count != 2 {
+			t.Errorf("unexpected loaded event %#v", pe)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	existing := newEvent("nginx.1", "BackOff", -time.Hour)
+	existing.Count = 5
+	client := fake.NewSimpleClientset(existing)
+
+	a := NewAggregator(t.TempDir(), nil)
+	a.Record(newEvent("nginx.1", "BackOff", 0))
+	a.Record(newEvent("nginx.2", "BackOff", time.Minute))
+	a.Record(newEvent("nginx.3", "Pulled", 2*time.Minute))
+	a.upload(client)
+
+	if len(a.events) != 0 {
+		t.Errorf("expect all events uploaded, but %d are pending", len(a.events))
+	}
+	event, err := client.CoreV1().Events("default").Get(context.TODO(), "nginx.1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get event, %v", err)
+	}
+	if event.Count != 7 || !event.LastTimestamp.Equal(&metav1.Time{Time: baseTime.Add(time.Minute)}) {
+		t.Errorf("expect existing event updated with occurrences, but got %#v", event)
+	}
+	event, err = client.CoreV1().Events("default").Get(context.TODO(), "nginx.3", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get event, %v", err)
+	}
+	if !event.FirstTimestamp.Equal(&metav1.Time{Time: baseTime.Add(2 * time.Minute)}) {
+		t.Errorf("expect timestamps of event kept, but got %v", event.FirstTimestamp)
+	}
+}
+
+func TestUploadFailure(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "events", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		event := action.(clienttesting.CreateAction).GetObject().(*corev1.Event)
+		if event.Name == "nginx.1" {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "default")
+		}
+		return true, nil, errors.New("connection refused")
+	})
+
+	a := NewAggregator(t.TempDir(), nil)
+	a.Record(newEvent("nginx.1", "BackOff", 0))
+	a.Record(newEvent("nginx.2", "Pulled", time.Minute))
+	a.upload(client)
+
+	if len(a.events) != 1 {
+		t.Fatalf("expect 1 pending event, but got %d", len(a.events))
+	}
+	for _, pe := range a.events {
+		if pe.Event.Name != "nginx.2" {
+			t.Errorf("expect event nginx.2 kept for retrying, but got %s", pe.Event.Name)
+		}
+	}
+}
+
+func TestDeferredWebhooks(t *testing.T) {
+	dir := t.TempDir()
+	data := `[{"name":"events.policy.io","rules":[{"operations":["CREATE"],"apiGroups":[""],"apiVersions":["v1"],"resources":["events"]}]}]`
+	if err := os.WriteFile(filepath.Join(dir, "webhooks.json"), []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write webhooks, %v", err)
+	}
+
+	a := NewAggregator(t.TempDir(), webhooks.NewMatcher(dir))
+	a.Record(newEvent("nginx.1", "BackOff", 0))
+	if deferred := a.DeferredWebhooks("default", "nginx.1"); !reflect.DeepEqual(deferred, []string{"events.policy.io"}) {
+		t.Errorf("expect webhook events.policy.io deferred, but got %v", deferred)
+	}
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "events", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "nginx.1", errors.New("admission webhook \"events.policy.io\" denied the request"))
+	})
+	a.upload(client)
+	if len(a.events) != 0 {
+		t.Errorf("expect rejected event dropped, but %d are pending", len(a.events))
+	}
+}
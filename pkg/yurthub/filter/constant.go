@@ -37,6 +37,10 @@ const (
 	// in order to make NodePort will not be listened by kube-proxy component in specified NodePool.
 	NodePortIsolationName = "nodeportisolation"
 
+	// ContainerSignatureFilterName filter is used to discard the pods of upgrade jobs and node-servant
+	// on kubelet list/watch pod request, if their containers are not signed by the fleet key.
+	ContainerSignatureFilterName = "containersignature"
+
 	// SkipDiscardServiceAnnotation is annotation used by LB service.
 	// If end users want to use specified LB service at the edge side,
 	// End users should add annotation["openyurt.io/skip-discard"]="true" for LB service.
@@ -54,5 +58,6 @@ var (
 		ServiceTopologyFilterName:     "kube-proxy, coredns, nginx-ingress-controller",
 		InClusterConfigFilterName:     "kubelet",
 		NodePortIsolationName:         "kube-proxy",
+		ContainerSignatureFilterName:  "kubelet",
 	}
 )
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containersignature
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+	"github.com/openyurtio/openyurt/pkg/yurthub/filter"
+)
+
+// Register registers a filter
+func Register(filters *filter.Filters) {
+	filters.Register(filter.ContainerSignatureFilterName, func() (filter.ObjectFilter, error) {
+		return &containerSignatureFilter{
+			enforced: signature.Enforced,
+			verify:   signature.VerifyPodContainers,
+		}, nil
+	})
+}
+
+type containerSignatureFilter struct {
+	enforced func() bool
+	verify   func(pod *v1.Pod) error
+}
+
+func (csf *containerSignatureFilter) Name() string {
+	return filter.ContainerSignatureFilterName
+}
+
+func (csf *containerSignatureFilter) SupportedResourceAndVerbs() map[string]sets.String {
+	return map[string]sets.String{
+		"pods": sets.NewString("list", "watch"),
+	}
+}
+
+func (csf *containerSignatureFilter) Filter(obj runtime.Object, _ <-chan struct{}) runtime.Object {
+	if !csf.enforced() {
+		return obj
+	}
+
+	switch v := obj.(type) {
+	case *v1.PodList:
+		var podNew []v1.Pod
+		for i := range v.Items {
+			if pod := csf.discardUnsignedPod(&v.Items[i]); pod != nil {
+				podNew = append(podNew, *pod)
+			}
+		}
+		v.Items = podNew
+		return v
+	case *v1.Pod:
+		return csf.discardUnsignedPod(v)
+	default:
+		return v
+	}
+}
+
+// discardUnsignedPod discards the pod if it needs signatures but any of its containers is not signed
+// by the fleet key, so kubelet never runs it.
+func (csf *containerSignatureFilter) discardUnsignedPod(pod *v1.Pod) *v1.Pod {
+	if !needSignatures(pod) {
+		return pod
+	}
+	if err := csf.verify(pod); err != nil {
+		klog.Errorf("pod(%s/%s) is discarded in containerSignatureFilter, %v", pod.Namespace, pod.Name, err)
+		return nil
+	}
+	return pod
+}
+
+// needSignatures returns true for the pods of upgrade jobs, which always record the signatures of
+// containers even if they are empty, and the pods which run node-servant.
+func needSignatures(pod *v1.Pod) bool {
+	if _, ok := pod.Annotations[signature.ContainerSignaturesAnnotation]; ok {
+		return true
+	}
+	for _, containers := range [][]v1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			if len(containers[i].Command) != 0 && containers[i].Command[0] == signature.NodeServantEntry {
+				return true
+			}
+		}
+	}
+	return false
+}
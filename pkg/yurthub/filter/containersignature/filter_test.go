@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containersignature
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
+	"github.com/openyurtio/openyurt/pkg/yurthub/filter"
+)
+
+func TestName(t *testing.T) {
+	csf := &containerSignatureFilter{}
+	if csf.Name() != filter.ContainerSignatureFilterName {
+		t.Errorf("expect %s, but got %s", filter.ContainerSignatureFilterName, csf.Name())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	newPod := func(name string, annotations map[string]string, command ...string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", Annotations: annotations},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "c", Image: "image", Command: command}}},
+		}
+	}
+	signed := map[string]string{signature.ContainerSignaturesAnnotation: `{"c":"valid"}`}
+	unsigned := map[string]string{signature.ContainerSignaturesAnnotation: `{}`}
+	verify := func(pod *corev1.Pod) error {
+		if pod.Annotations[signature.ContainerSignaturesAnnotation] != signed[signature.ContainerSignaturesAnnotation] {
+			return errors.New("not signed")
+		}
+		return nil
+	}
+
+	testcases := map[string]struct {
+		enforced bool
+		pods     []corev1.Pod
+		expect   []string
+	}{
+		"unsigned pods are discarded": {
+			enforced: true,
+			pods: []corev1.Pod{
+				newPod("signed-upgrade", signed),
+				newPod("unsigned-upgrade", unsigned),
+				newPod("unsigned-node-servant", nil, signature.NodeServantEntry),
+				newPod("app", nil, "/app"),
+			},
+			expect: []string{"signed-upgrade", "app"},
+		},
+		"nothing is discarded without fleet public key": {
+			enforced: false,
+			pods: []corev1.Pod{
+				newPod("unsigned-upgrade", unsigned),
+				newPod("unsigned-node-servant", nil, signature.NodeServantEntry),
+			},
+			expect: []string{"unsigned-upgrade", "unsigned-node-servant"},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			csf := &containerSignatureFilter{
+				enforced: func() bool { return tc.enforced },
+				verify:   verify,
+			}
+			list := csf.Filter(&corev1.PodList{Items: tc.pods}, nil).(*corev1.PodList)
+			var names []string
+			for i := range list.Items {
+				names = append(names, list.Items[i].Name)
+			}
+			if len(names) != len(tc.expect) {
+				t.Fatalf("expect pods %v, but got %v", tc.expect, names)
+			}
+			for i := range names {
+				if names[i] != tc.expect[i] {
+					t.Errorf("expect pods %v, but got %v", tc.expect, names)
+				}
+			}
+
+			for i := range tc.pods {
+				pod := tc.pods[i]
+				obj := csf.Filter(&pod, nil)
+				kept := obj.(*corev1.Pod) != nil
+				if expectKept := contains(tc.expect, pod.Name); kept != expectKept {
+					t.Errorf("expect pod %s kept %v, but got %v", pod.Name, expectKept, kept)
+				}
+			}
+		})
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/filter"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/bolt"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 	nodepoolv1alpha1 "github.com/openyurtio/yurt-app-manager-api/pkg/yurtappmanager/apis/apps/v1alpha1"
@@ -119,7 +120,7 @@ func (stf *serviceTopologyFilter) SetNodeName(nodeName string) error {
 // TODO: should use disk storage as parameter instead of StorageWrapper
 // we can internally construct a new StorageWrapper with passed-in disk storage
 func (stf *serviceTopologyFilter) SetStorageWrapper(s cachemanager.StorageWrapper) error {
-	if s.Name() != disk.StorageName {
+	if s.Name() != disk.StorageName && s.Name() != bolt.StorageName {
 		return fmt.Errorf("serviceTopologyFilter can only support disk storage currently, cannot use %s", s.Name())
 	}
 
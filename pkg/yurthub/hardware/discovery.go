@@ -0,0 +1,238 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hardware
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// AnnotationHardwareReport is added to the node by yurthub, the value is the json of Report.
+	AnnotationHardwareReport = "node.openyurt.io/hardware-report"
+
+	DiskClassNVMe = "nvme"
+	DiskClassSSD  = "ssd"
+	DiskClassHDD  = "hdd"
+)
+
+var (
+	// gpuVendors are the pci vendor ids of gpu
+	gpuVendors = map[string]string{
+		"0x10de": "nvidia",
+		"0x1002": "amd",
+		"0x8086": "intel",
+	}
+	// npuVendors are the pci vendor ids of processing accelerators
+	npuVendors = map[string]string{
+		"0x19e5": "ascend",
+		"0x1e60": "hailo",
+		"0x1ac1": "edgetpu",
+	}
+	// npuDevices are the device file prefixes of npu which are not attached by pci
+	npuDevices = map[string]string{
+		"davinci": "ascend",
+		"apex_":   "edgetpu",
+	}
+	// modemDrivers are the drivers of usb cellular modems
+	modemDrivers = map[string]string{
+		"qmi_wwan":       "qmi",
+		"cdc_mbim":       "mbim",
+		"cdc_ether":      "ecm",
+		"rndis_host":     "rndis",
+		"option":         "serial",
+		"huawei_cdc_ncm": "ncm",
+	}
+	// diskClassRank is used for choosing the fastest disk class of node
+	diskClassRank = map[string]int{
+		DiskClassHDD:  1,
+		DiskClassSSD:  2,
+		DiskClassNVMe: 3,
+	}
+)
+
+// Report is the hardware attributes of node detected by yurthub.
+type Report struct {
+	// Arch is the cpu architecture of node, like amd64 and arm64.
+	Arch string `json:"arch"`
+	// GPU is the vendor of gpu, like nvidia, amd and intel.
+	GPU string `json:"gpu,omitempty"`
+	// GPUCount is the number of gpus of the vendor.
+	GPUCount int `json:"gpuCount,omitempty"`
+	// NPU is the vendor of npu, like ascend, hailo and edgetpu.
+	NPU string `json:"npu,omitempty"`
+	// NPUCount is the number of npus of the vendor.
+	NPUCount int `json:"npuCount,omitempty"`
+	// Modem is the type of cellular modem, which is named after the protocol, like qmi and mbim.
+	Modem string `json:"modem,omitempty"`
+	// DiskClass is the fastest class of disks on the node, nvme, ssd or hdd.
+	DiskClass string `json:"diskClass,omitempty"`
+}
+
+// Discoverer detects the hardware of node from sysfs and devfs.
+type Discoverer struct {
+	// sysRoot and devRoot are /sys and /dev except for testing
+	sysRoot string
+	devRoot string
+}
+
+// NewDiscoverer creates a Discoverer for the host.
+func NewDiscoverer() *Discoverer {
+	return &Discoverer{sysRoot: "/sys", devRoot: "/dev"}
+}
+
+// Discover detects the hardware of node, the attributes which can't be detected are left empty.
+func (d *Discoverer) Discover() *Report {
+	r := &Report{Arch: runtime.GOARCH}
+	r.GPU, r.GPUCount, r.NPU, r.NPUCount = d.discoverPCIDevices()
+	if len(r.NPU) == 0 {
+		r.NPU, r.NPUCount = d.discoverNPUDevices()
+	}
+	r.Modem = d.discoverModem()
+	r.DiskClass = d.discoverDiskClass()
+	return r
+}
+
+// discoverPCIDevices detects the gpus and npus attached by pci, and returns the vendor which
+// has the most devices for each kind.
+func (d *Discoverer) discoverPCIDevices() (string, int, string, int) {
+	gpus := make(map[string]int)
+	npus := make(map[string]int)
+	devices, _ := filepath.Glob(filepath.Join(d.sysRoot, "bus/pci/devices/*"))
+	for _, dev := range devices {
+		class := readFile(filepath.Join(dev, "class"))
+		vendor := readFile(filepath.Join(dev, "vendor"))
+		switch {
+		// 0x0300xx is vga compatible controller, 0x0302xx is 3d controller
+		case strings.HasPrefix(class, "0x0300") || strings.HasPrefix(class, "0x0302"):
+			if name, ok := gpuVendors[vendor]; ok {
+				gpus[name]++
+			}
+		// 0x1200xx is processing accelerator
+		case strings.HasPrefix(class, "0x1200"):
+			if name, ok := npuVendors[vendor]; ok {
+				npus[name]++
+			}
+		}
+	}
+	gpu, gpuCount := mostDevices(gpus)
+	npu, npuCount := mostDevices(npus)
+	return gpu, gpuCount, npu, npuCount
+}
+
+// discoverNPUDevices detects the npus by the device files, which are exposed by the drivers
+// of npus integrated in the soc.
+func (d *Discoverer) discoverNPUDevices() (string, int) {
+	npus := make(map[string]int)
+	entries, _ := os.ReadDir(d.devRoot)
+	for _, entry := range entries {
+		for prefix, name := range npuDevices {
+			if suffix := strings.TrimPrefix(entry.Name(), prefix); suffix != entry.Name() && isDigits(suffix) {
+				npus[name]++
+			}
+		}
+	}
+	return mostDevices(npus)
+}
+
+// discoverModem detects the cellular modem by the drivers of wwan network interfaces and usb interfaces.
+func (d *Discoverer) discoverModem() string {
+	links, _ := filepath.Glob(filepath.Join(d.sysRoot, "class/net/*/device/driver"))
+	usbLinks, _ := filepath.Glob(filepath.Join(d.sysRoot, "class/usbmisc/*/device/driver"))
+	for _, link := range append(links, usbLinks...) {
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		if modem, ok := modemDrivers[filepath.Base(target)]; ok {
+			return modem
+		}
+	}
+
+	// the network interfaces of modems which are not driven by usb, like pcie modems
+	uevents, _ := filepath.Glob(filepath.Join(d.sysRoot, "class/net/*/uevent"))
+	for _, uevent := range uevents {
+		if strings.Contains(readFile(uevent), "DEVTYPE=wwan") {
+			return "wwan"
+		}
+	}
+	return ""
+}
+
+// discoverDiskClass returns the fastest class of physical disks.
+func (d *Discoverer) discoverDiskClass() string {
+	var diskClass string
+	disks, _ := filepath.Glob(filepath.Join(d.sysRoot, "block/*"))
+	for _, disk := range disks {
+		name := filepath.Base(disk)
+		// skip virtual block devices
+		if _, err := os.Stat(filepath.Join(disk, "device")); err != nil {
+			continue
+		}
+		class := DiskClassSSD
+		if strings.HasPrefix(name, "nvme") {
+			class = DiskClassNVMe
+		} else if readFile(filepath.Join(disk, "queue/rotational")) == "1" {
+			class = DiskClassHDD
+		}
+		if diskClassRank[class] > diskClassRank[diskClass] {
+			diskClass = class
+		}
+	}
+	return diskClass
+}
+
+// mostDevices returns the name which has the most devices, names are sorted for a stable result.
+func mostDevices(devices map[string]int) (string, int) {
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var most string
+	var count int
+	for _, name := range names {
+		if devices[name] > count {
+			most, count = name, devices[name]
+		}
+	}
+	return most, count
+}
+
+func readFile(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hardware
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for path, content := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("could not create dir, %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file, %v", err)
+		}
+	}
+}
+
+func symlink(t *testing.T, root, target, link string) {
+	link = filepath.Join(root, link)
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatalf("could not create dir, %v", err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("could not create symlink, %v", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	testcases := map[string]struct {
+		sysFiles map[string]string
+		devFiles []string
+		drivers  map[string]string
+		expect   *Report
+	}{
+		"no hardware": {
+			expect: &Report{Arch: runtime.GOARCH},
+		},
+		"gpus, pci npu and disks": {
+			sysFiles: map[string]string{
+				"bus/pci/devices/0000:01:00.0/class":  "0x030000\n",
+				"bus/pci/devices/0000:01:00.0/vendor": "0x10de\n",
+				"bus/pci/devices/0000:02:00.0/class":  "0x030200\n",
+				"bus/pci/devices/0000:02:00.0/vendor": "0x10de\n",
+				"bus/pci/devices/0000:00:02.0/class":  "0x030000\n",
+				"bus/pci/devices/0000:00:02.0/vendor": "0x8086\n",
+				"bus/pci/devices/0000:03:00.0/class":  "0x120000\n",
+				"bus/pci/devices/0000:03:00.0/vendor": "0x1e60\n",
+				"bus/pci/devices/0000:04:00.0/class":  "0x020000\n",
+				"bus/pci/devices/0000:04:00.0/vendor": "0x10de\n",
+				"block/sda/device/model":              "disk",
+				"block/sda/queue/rotational":          "1\n",
+				"block/sdb/device/model":              "disk",
+				"block/sdb/queue/rotational":          "0\n",
+				"block/loop0/queue/rotational":        "0\n",
+			},
+			expect: &Report{Arch: runtime.GOARCH, GPU: "nvidia", GPUCount: 2, NPU: "hailo", NPUCount: 1, DiskClass: DiskClassSSD},
+		},
+		"soc npu, usb modem and nvme": {
+			sysFiles: map[string]string{
+				"block/nvme0n1/device/model":     "disk",
+				"block/mmcblk0/device/model":     "disk",
+				"block/mmcblk0/queue/rotational": "0\n",
+				"class/net/eth0/uevent":          "INTERFACE=eth0\n",
+			},
+			devFiles: []string{"davinci0", "davinci1", "davinci_manager"},
+			drivers: map[string]string{
+				"class/net/wwan0/device/driver": "../../../bus/usb/drivers/qmi_wwan",
+			},
+			expect: &Report{Arch: runtime.GOARCH, NPU: "ascend", NPUCount: 2, Modem: "qmi", DiskClass: DiskClassNVMe},
+		},
+		"pcie modem": {
+			sysFiles: map[string]string{
+				"class/net/wwan0/uevent": "DEVTYPE=wwan\nINTERFACE=wwan0\n",
+			},
+			expect: &Report{Arch: runtime.GOARCH, Modem: "wwan"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			root := t.TempDir()
+			d := &Discoverer{sysRoot: filepath.Join(root, "sys"), devRoot: filepath.Join(root, "dev")}
+			writeFiles(t, d.sysRoot, tc.sysFiles)
+			devFiles := make(map[string]string)
+			for _, f := range tc.devFiles {
+				devFiles[f] = ""
+			}
+			writeFiles(t, d.devRoot, devFiles)
+			for link, target := range tc.drivers {
+				symlink(t, d.sysRoot, target, link)
+			}
+
+			report := d.Discover()
+			if !reflect.DeepEqual(report, tc.expect) {
+				t.Errorf("expect report %#v, but got %#v", tc.expect, report)
+			}
+		})
+	}
+}
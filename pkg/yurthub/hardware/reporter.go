@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the License);
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an AS IS BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hardware
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/rest"
+)
+
+var (
+	defaultReportInterval = 10 * time.Minute
+)
+
+// Reporter reports the hardware of node to the node annotation periodically,
+// and the annotation is converted into node labels by yurt-manager.
+type Reporter struct {
+	discoverer        *Discoverer
+	restConfigManager *rest.RestConfigManager
+	nodeName          string
+	lastReport        string
+	stopCh            <-chan struct{}
+}
+
+// NewReporter creates a *Reporter object
+func NewReporter(nodeName string, restConfigManager *rest.RestConfigManager, stopCh <-chan struct{}) *Reporter {
+	return &Reporter{
+		discoverer:        NewDiscoverer(),
+		restConfigManager: restConfigManager,
+		nodeName:          nodeName,
+		stopCh:            stopCh,
+	}
+}
+
+// Run starts Reporter, the hardware is discovered every time because devices like
+// modems and usb accelerators can be plugged in at runtime.
+func (r *Reporter) Run() {
+	go wait.JitterUntil(func() {
+		report, err := json.Marshal(r.discoverer.Discover())
+		if err != nil {
+			klog.Errorf("could not marshal hardware report, %v", err)
+			return
+		}
+		if string(report) == r.lastReport {
+			return
+		}
+
+		cfg := r.restConfigManager.GetRestConfig(true)
+		if cfg == nil {
+			klog.Errorf("could not get rest config, so skip reporting hardware")
+			return
+		}
+		kubeClient, err := clientset.NewForConfig(cfg)
+		if err != nil {
+			klog.Errorf("could not new kube client, %v", err)
+			return
+		}
+
+		if err := r.patchNode(kubeClient, string(report)); err != nil {
+			klog.Errorf("could not report hardware %s of node %s, %v", report, r.nodeName, err)
+			return
+		}
+		klog.Infof("hardware %s of node %s is reported", report, r.nodeName)
+		r.lastReport = string(report)
+	}, defaultReportInterval, 0.1, true, r.stopCh)
+}
+
+func (r *Reporter) patchNode(kubeClient clientset.Interface, report string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				AnnotationHardwareReport: report,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = kubeClient.CoreV1().Nodes().Patch(context.Background(), r.nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package healthchecker
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -29,7 +30,10 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/cmd/yurthub/app/config"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/utils"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/nodeproblem"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
 )
 
@@ -56,6 +60,10 @@ type coordinatorHealthChecker struct {
 	coordinatorProber        BackendProber
 	latestLease              *coordinationv1.Lease
 	heartbeatInterval        int
+	nodeProblemRelay         *nodeproblem.Relay
+	nodeName                 string
+	// clientCert returns the client certificate of node, which signs the relayed node conditions
+	clientCert func() *tls.Certificate
 }
 
 // NewCoordinatorHealthChecker returns a health checker for verifying pool coordinator status.
@@ -63,6 +71,11 @@ func NewCoordinatorHealthChecker(cfg *config.YurtHubConfiguration, checkerClient
 	chc := &coordinatorHealthChecker{
 		cloudServerHealthChecker: cloudServerHealthChecker,
 		heartbeatInterval:        cfg.HeartbeatIntervalSeconds,
+		nodeProblemRelay:         cfg.NodeProblemRelay,
+		nodeName:                 cfg.NodeName,
+	}
+	if cfg.CertManager != nil {
+		chc.clientCert = cfg.CertManager.GetAPIServerClientCert
 	}
 	chc.coordinatorProber = newProber(checkerClient,
 		cfg.CoordinatorServerURL.String(),
@@ -115,14 +128,43 @@ func (chc *coordinatorHealthChecker) getLastNodeLease() *coordinationv1.Lease {
 				chc.latestLease.Annotations = make(map[string]string)
 			}
 			chc.latestLease.Annotations[DelegateHeartBeat] = "true"
+			// node conditions are relayed to cloud with the delegated node lease, and they are signed by
+			// the node, so the delegates can't change them.
+			if conditions, signature := chc.nodeProblemConditions(); len(conditions) != 0 {
+				chc.latestLease.Annotations[constant.NodeConditionsAnnotation] = conditions
+				chc.latestLease.Annotations[constant.NodeConditionsSignatureAnnotation] = signature
+			} else {
+				delete(chc.latestLease.Annotations, constant.NodeConditionsAnnotation)
+				delete(chc.latestLease.Annotations, constant.NodeConditionsSignatureAnnotation)
+			}
 		} else {
 			delete(chc.latestLease.Annotations, DelegateHeartBeat)
+			delete(chc.latestLease.Annotations, constant.NodeConditionsAnnotation)
+			delete(chc.latestLease.Annotations, constant.NodeConditionsSignatureAnnotation)
 		}
 	}
 
 	return chc.latestLease
 }
 
+// nodeProblemConditions returns the pending node conditions and their signature, no conditions are
+// returned if they can't be signed, because the unsigned conditions are rejected by cloud.
+func (chc *coordinatorHealthChecker) nodeProblemConditions() (string, string) {
+	if chc.nodeProblemRelay == nil || chc.clientCert == nil {
+		return "", ""
+	}
+	conditions := chc.nodeProblemRelay.Annotation()
+	if len(conditions) == 0 {
+		return "", ""
+	}
+	signature, err := utils.SignNodeConditions(chc.clientCert(), chc.nodeName, conditions, time.Now())
+	if err != nil {
+		klog.Errorf("failed to sign node conditions of %s, %v", chc.nodeName, err)
+		return "", ""
+	}
+	return conditions, signature
+}
+
 // NewCloudAPIServerHealthChecker returns a health checker for verifying cloud kube-apiserver status.
 func NewCloudAPIServerHealthChecker(cfg *config.YurtHubConfiguration, healthCheckerClients map[string]kubernetes.Interface, stopCh <-chan struct{}) (MultipleBackendsHealthChecker, error) {
 	if len(healthCheckerClients) == 0 {
@@ -248,6 +290,8 @@ func (hc *cloudAPIServerHealthChecker) setLastNodeLease(lease *coordinationv1.Le
 func (hc *cloudAPIServerHealthChecker) getLastNodeLease() *coordinationv1.Lease {
 	if hc.latestLease != nil {
 		delete(hc.latestLease.Annotations, DelegateHeartBeat)
+		delete(hc.latestLease.Annotations, constant.NodeConditionsAnnotation)
+		delete(hc.latestLease.Annotations, constant.NodeConditionsSignatureAnnotation)
 	}
 	return hc.latestLease
 }
@@ -0,0 +1,33 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagegc
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// fsUsage returns the used bytes and capacity of file system which path is on
+func fsUsage(path string) (uint64, uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("failed to stat file system of %s, %w", path, err)
+	}
+	capacity := st.Blocks * uint64(st.Bsize)
+	return capacity - st.Bfree*uint64(st.Bsize), capacity, nil
+}
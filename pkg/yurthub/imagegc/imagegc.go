@@ -0,0 +1,246 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagegc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+const gcPeriod = time.Minute
+
+// Manager collects the unused images as kubelet image gc does, kubelet image gc should be disabled by
+// --image-gc-high-threshold=100 on the node(yurtadm join sets it when yurthub image gc is enabled).
+// While the cloud is unreachable, the images of pods cached for kubelet are never collected, because
+// kubelet restarts these pods from the cache and can't pull their images again. A catch-up gc runs once
+// the cloud is reachable again.
+//
+// Kubelet still removes all unused images when it evicts pods under disk pressure, regardless of
+// --image-gc-high-threshold, and the images of cached pods are not protected in this case. The eviction
+// thresholds of imagefs should be set below the high threshold of yurthub, so yurthub collects images
+// before kubelet does.
+type Manager struct {
+	runtime       Runtime
+	imageFsPath   string
+	highThreshold int
+	lowThreshold  int
+	listPods      func() ([]*corev1.Pod, error)
+	usage         func(path string) (used, capacity uint64, err error)
+	offline       bool
+}
+
+// NewManager creates a Manager which starts collecting images when the usage of imageFsPath exceeds
+// highThreshold percent, and collects images until the usage is below lowThreshold percent.
+func NewManager(runtime Runtime, imageFsPath string, highThreshold, lowThreshold int, store cachemanager.StorageWrapper) *Manager {
+	return &Manager{
+		runtime:       runtime,
+		imageFsPath:   imageFsPath,
+		highThreshold: highThreshold,
+		lowThreshold:  lowThreshold,
+		listPods: func() ([]*corev1.Pod, error) {
+			return listCachedPods(store)
+		},
+		usage: fsUsage,
+	}
+}
+
+// listCachedPods lists the pods of kubelet in local cache
+func listCachedPods(store cachemanager.StorageWrapper) ([]*corev1.Pod, error) {
+	podsKey, err := store.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: "pods",
+		Version:   "v1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	objs, err := store.List(podsKey)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// Run collects images periodically until stopCh is closed, isHealthy reports the cloud is reachable or not.
+func (m *Manager) Run(isHealthy func() bool, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(gcPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			healthy := isHealthy()
+			catchUp := healthy && m.offline
+			m.offline = !healthy
+			if err := m.gc(!healthy, catchUp); err != nil {
+				klog.Errorf("failed to collect images, %v", err)
+			}
+		}
+	}
+}
+
+// gc collects the unused images, the images of cached pods are protected if offline. All images
+// collectable are removed until the usage is below the low threshold for catch-up gc, even though
+// the usage doesn't exceed the high threshold.
+func (m *Manager) gc(offline, catchUp bool) error {
+	used, capacity, err := m.usage(m.imageFsPath)
+	if err != nil {
+		return err
+	}
+	if capacity == 0 {
+		return fmt.Errorf("capacity of %s is zero", m.imageFsPath)
+	}
+	if !catchUp && used*100 < uint64(m.highThreshold)*capacity {
+		return nil
+	}
+	target := capacity * uint64(m.lowThreshold) / 100
+	if used <= target {
+		return nil
+	}
+
+	candidates, err := m.collectableImages(offline)
+	if err != nil {
+		return err
+	}
+	// remove the large images first, so less images need to be pulled again
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Size > candidates[j].Size
+	})
+
+	klog.Infof("start collecting images, usage of %s is %d/%d, offline: %v, catch-up: %v", m.imageFsPath, used, capacity, offline, catchUp)
+	for _, img := range candidates {
+		if used <= target {
+			break
+		}
+		if err := m.runtime.RemoveImage(img.ID); err != nil {
+			klog.Errorf("failed to collect image %s, %v", img.ID, err)
+			continue
+		}
+		klog.Infof("image %s(%v) is collected", img.ID, img.RepoTags)
+		if img.Size > used {
+			used = 0
+		} else {
+			used -= img.Size
+		}
+	}
+	if used > target {
+		klog.Warningf("usage of %s is still above %d%% after collecting images", m.imageFsPath, m.lowThreshold)
+	}
+	return nil
+}
+
+// collectableImages returns the images which are not used by containers, and the images of cached
+// pods are excluded if offline.
+func (m *Manager) collectableImages(offline bool) ([]Image, error) {
+	images, err := m.runtime.ListImages()
+	if err != nil {
+		return nil, err
+	}
+	inUse, err := m.runtime.ListImagesInUse()
+	if err != nil {
+		return nil, err
+	}
+
+	protected := make(map[string]struct{})
+	if offline {
+		pods, err := m.listPods()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cached pods for protecting images, %w", err)
+		}
+		for _, pod := range pods {
+			for _, ref := range podImages(pod) {
+				protected[ref] = struct{}{}
+			}
+		}
+	}
+
+	candidates := make([]Image, 0, len(images))
+	for _, img := range images {
+		if _, ok := inUse[img.ID]; ok {
+			continue
+		}
+		if isProtected(img, protected) {
+			klog.V(4).Infof("image %s(%v) of cached pods is protected while offline", img.ID, img.RepoTags)
+			continue
+		}
+		candidates = append(candidates, img)
+	}
+	return candidates, nil
+}
+
+func isProtected(img Image, protected map[string]struct{}) bool {
+	if _, ok := protected[img.ID]; ok {
+		return true
+	}
+	for _, ref := range append(append([]string{}, img.RepoTags...), img.RepoDigests...) {
+		if _, ok := protected[normalizeImage(ref)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// podImages returns the images of all containers in pod, and the image ids reported in its status.
+func podImages(pod *corev1.Pod) []string {
+	var refs []string
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, c := range containers {
+		refs = append(refs, normalizeImage(c.Image))
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if len(s.ImageID) != 0 {
+			// docker-pullable:// prefix is reported by dockershim
+			refs = append(refs, normalizeImage(strings.TrimPrefix(s.ImageID, "docker-pullable://")))
+		}
+	}
+	return refs
+}
+
+// normalizeImage converts the image reference into full format like docker.io/library/nginx:latest,
+// so the references in pod spec can be matched with the repo tags of images.
+func normalizeImage(ref string) string {
+	if strings.HasPrefix(ref, "sha256:") {
+		return ref
+	}
+	name := ref
+	if i := strings.Index(name, "/"); i == -1 {
+		name = "docker.io/library/" + name
+	} else if domain := name[:i]; !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		name = "docker.io/" + name
+	}
+	lastPart := name[strings.LastIndex(name, "/")+1:]
+	if !strings.Contains(lastPart, ":") && !strings.Contains(lastPart, "@") {
+		name += ":latest"
+	}
+	return name
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagegc
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeRuntime struct {
+	images  []Image
+	inUse   map[string]struct{}
+	removed []string
+}
+
+func (r *fakeRuntime) ListImages() ([]Image, error) {
+	return r.images, nil
+}
+
+func (r *fakeRuntime) ListImagesInUse() (map[string]struct{}, error) {
+	return r.inUse, nil
+}
+
+func (r *fakeRuntime) RemoveImage(id string) error {
+	r.removed = append(r.removed, id)
+	return nil
+}
+
+func TestGC(t *testing.T) {
+	images := []Image{
+		{ID: "sha256:nginx", RepoTags: []string{"docker.io/library/nginx:latest"}, Size: 30},
+		{ID: "sha256:busybox", RepoTags: []string{"docker.io/library/busybox:1.28"}, Size: 10},
+		{ID: "sha256:pause", RepoTags: []string{"registry.k8s.io/pause:3.5"}, Size: 1},
+		{ID: "sha256:app", RepoTags: []string{"registry.example.com/app:v1"}, Size: 20},
+	}
+	pods := []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/app:v2"}}},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", ImageID: "sha256:app"}},
+			},
+		},
+	}
+
+	testcases := map[string]struct {
+		used     uint64
+		offline  bool
+		catchUp  bool
+		expected []string
+	}{
+		"usage below high threshold": {
+			used: 80,
+		},
+		"collect images until usage below low threshold": {
+			used:     95,
+			expected: []string{"sha256:nginx"},
+		},
+		"protect images of cached pods while offline": {
+			used:     95,
+			offline:  true,
+			expected: []string{"sha256:busybox"},
+		},
+		"catch-up gc after reconnected": {
+			used:     75,
+			catchUp:  true,
+			expected: []string{"sha256:nginx"},
+		},
+		"catch-up gc when usage below low threshold": {
+			used:    60,
+			catchUp: true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			runtime := &fakeRuntime{
+				images: images,
+				inUse:  map[string]struct{}{"sha256:pause": {}},
+			}
+			m := &Manager{
+				runtime:       runtime,
+				highThreshold: 85,
+				lowThreshold:  70,
+				listPods: func() ([]*corev1.Pod, error) {
+					return pods, nil
+				},
+				usage: func(string) (uint64, uint64, error) {
+					return tc.used, 100, nil
+				},
+			}
+			if err := m.gc(tc.offline, tc.catchUp); err != nil {
+				t.Fatalf("failed to gc, %v", err)
+			}
+			sort.Strings(runtime.removed)
+			if !reflect.DeepEqual(runtime.removed, tc.expected) {
+				t.Errorf("expect removed images %v, but got %v", tc.expected, runtime.removed)
+			}
+		})
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	testcases := map[string]string{
+		"nginx":                             "docker.io/library/nginx:latest",
+		"openyurt/yurthub:v1.2":             "docker.io/openyurt/yurthub:v1.2",
+		"localhost/app":                     "localhost/app:latest",
+		"registry:5000/app":                 "registry:5000/app:latest",
+		"registry.k8s.io/pause:3.5":         "registry.k8s.io/pause:3.5",
+		"docker.io/library/nginx@sha256:ab": "docker.io/library/nginx@sha256:ab",
+		"sha256:ab":                         "sha256:ab",
+	}
+	for ref, expected := range testcases {
+		if got := normalizeImage(ref); got != expected {
+			t.Errorf("expect %s for %s, but got %s", expected, ref, got)
+		}
+	}
+}
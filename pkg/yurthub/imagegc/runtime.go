@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagegc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const (
+	// criTimeout is the timeout of each request to the container runtime
+	criTimeout = 2 * time.Minute
+	// maxMsgSize is the max size of grpc messages, which is the same as kubelet
+	maxMsgSize = 1024 * 1024 * 16
+)
+
+// Image is an image in the container runtime
+type Image struct {
+	ID          string
+	RepoTags    []string
+	RepoDigests []string
+	Size        uint64
+}
+
+// Runtime is the container runtime which images are collected from
+type Runtime interface {
+	// ListImages lists all images in the runtime
+	ListImages() ([]Image, error)
+	// ListImagesInUse lists the ids of images used by containers, including the exited ones
+	ListImagesInUse() (map[string]struct{}, error)
+	RemoveImage(id string) error
+}
+
+// criRuntime works with the runtime through the cri grpc api
+type criRuntime struct {
+	imageClient   runtimeapi.ImageServiceClient
+	runtimeClient runtimeapi.RuntimeServiceClient
+}
+
+// NewCRIRuntime creates a Runtime which works with the runtime at criSocket through the cri grpc api,
+// criSocket should be a unix socket endpoint, e.g. unix:///run/containerd/containerd.sock
+func NewCRIRuntime(criSocket string) (Runtime, error) {
+	if strings.Contains(criSocket, "://") && !strings.HasPrefix(criSocket, "unix://") {
+		return nil, fmt.Errorf("cri socket %s is not a unix socket endpoint", criSocket)
+	}
+	addr := strings.TrimPrefix(criSocket, "unix://")
+
+	// the connection is set up lazily, so the runtime can be started after yurthub
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMsgSize)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to cri socket %s, %w", criSocket, err)
+	}
+	return &criRuntime{
+		imageClient:   runtimeapi.NewImageServiceClient(conn),
+		runtimeClient: runtimeapi.NewRuntimeServiceClient(conn),
+	}, nil
+}
+
+func (r *criRuntime) ListImages() ([]Image, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), criTimeout)
+	defer cancel()
+	resp, err := r.imageClient.ListImages(ctx, &runtimeapi.ListImagesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images, %w", err)
+	}
+
+	images := make([]Image, 0, len(resp.Images))
+	for _, img := range resp.Images {
+		images = append(images, Image{ID: img.Id, RepoTags: img.RepoTags, RepoDigests: img.RepoDigests, Size: img.Size_})
+	}
+	return images, nil
+}
+
+func (r *criRuntime) ListImagesInUse() (map[string]struct{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), criTimeout)
+	defer cancel()
+	// containers are listed without filter, so the exited ones are included
+	resp, err := r.runtimeClient.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers, %w", err)
+	}
+
+	inUse := make(map[string]struct{}, len(resp.Containers))
+	for _, c := range resp.Containers {
+		inUse[c.ImageRef] = struct{}{}
+	}
+	return inUse, nil
+}
+
+func (r *criRuntime) RemoveImage(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), criTimeout)
+	defer cancel()
+	if _, err := r.imageClient.RemoveImage(ctx, &runtimeapi.RemoveImageRequest{Image: &runtimeapi.ImageSpec{Image: id}}); err != nil {
+		return fmt.Errorf("failed to remove image %s, %w", id, err)
+	}
+	return nil
+}
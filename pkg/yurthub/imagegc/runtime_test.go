@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagegc
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+type fakeCRIServer struct {
+	runtimeapi.UnimplementedImageServiceServer
+	runtimeapi.UnimplementedRuntimeServiceServer
+	images  []*runtimeapi.Image
+	removed []string
+}
+
+func (s *fakeCRIServer) ListImages(context.Context, *runtimeapi.ListImagesRequest) (*runtimeapi.ListImagesResponse, error) {
+	return &runtimeapi.ListImagesResponse{Images: s.images}, nil
+}
+
+func (s *fakeCRIServer) RemoveImage(_ context.Context, req *runtimeapi.RemoveImageRequest) (*runtimeapi.RemoveImageResponse, error) {
+	s.removed = append(s.removed, req.Image.Image)
+	return &runtimeapi.RemoveImageResponse{}, nil
+}
+
+func (s *fakeCRIServer) ListContainers(context.Context, *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	return &runtimeapi.ListContainersResponse{Containers: []*runtimeapi.Container{
+		{Id: "c1", ImageRef: "sha256:aaa", State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+		{Id: "c2", ImageRef: "sha256:bbb", State: runtimeapi.ContainerState_CONTAINER_EXITED},
+	}}, nil
+}
+
+func TestCRIRuntime(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "cri.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on %s, %v", socket, err)
+	}
+	fake := &fakeCRIServer{images: []*runtimeapi.Image{
+		{Id: "sha256:aaa", RepoTags: []string{"busybox:1.36"}, Size_: 100},
+		{Id: "sha256:ccc", RepoDigests: []string{"nginx@sha256:ccc"}, Size_: 200},
+	}}
+	srv := grpc.NewServer()
+	runtimeapi.RegisterImageServiceServer(srv, fake)
+	runtimeapi.RegisterRuntimeServiceServer(srv, fake)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	if _, err := NewCRIRuntime("tcp://127.0.0.1:10010"); err == nil {
+		t.Errorf("expect error for cri socket which is not a unix socket")
+	}
+	runtime, err := NewCRIRuntime("unix://" + socket)
+	if err != nil {
+		t.Fatalf("failed to create cri runtime, %v", err)
+	}
+
+	images, err := runtime.ListImages()
+	if err != nil {
+		t.Fatalf("failed to list images, %v", err)
+	}
+	expectImages := []Image{
+		{ID: "sha256:aaa", RepoTags: []string{"busybox:1.36"}, Size: 100},
+		{ID: "sha256:ccc", RepoDigests: []string{"nginx@sha256:ccc"}, Size: 200},
+	}
+	if !reflect.DeepEqual(images, expectImages) {
+		t.Errorf("expect images %v, but got %v", expectImages, images)
+	}
+
+	inUse, err := runtime.ListImagesInUse()
+	if err != nil {
+		t.Fatalf("failed to list images in use, %v", err)
+	}
+	expectInUse := map[string]struct{}{"sha256:aaa": {}, "sha256:bbb": {}}
+	if !reflect.DeepEqual(inUse, expectInUse) {
+		t.Errorf("expect images in use %v, but got %v", expectInUse, inUse)
+	}
+
+	if err := runtime.RemoveImage("sha256:ccc"); err != nil {
+		t.Fatalf("failed to remove image, %v", err)
+	}
+	if !reflect.DeepEqual(fake.removed, []string{"sha256:ccc"}) {
+		t.Errorf("expect image sha256:ccc is removed, but got %v", fake.removed)
+	}
+}
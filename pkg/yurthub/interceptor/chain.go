@@ -0,0 +1,259 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interceptor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+// Factory creates a registered Interceptor
+type Factory func() (Interceptor, error)
+
+var (
+	registryLock sync.Mutex
+	registry     = make(map[string]Factory)
+)
+
+// Register registers the factory of interceptor with name, it's called in init() of the
+// packages which are imported by a customized yurthub binary.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, found := registry[name]; found {
+		klog.Warningf("interceptor %q has already registered", name)
+		return
+	}
+	registry[name] = factory
+	klog.V(2).Infof("interceptor %s registered successfully", name)
+}
+
+const (
+	// FailurePolicyIgnore lets the requests go through unchanged when an interceptor fails.
+	FailurePolicyIgnore = "Ignore"
+	// FailurePolicyFail rejects the requests when an interceptor fails, it's used when the
+	// interceptors must not be bypassed, e.g. authentication.
+	FailurePolicyFail = "Fail"
+)
+
+// Chain runs the interceptors in order for the requests of yurthub proxy.
+type Chain struct {
+	interceptors  []Interceptor
+	failurePolicy string
+}
+
+// NewChain creates the interceptors in specs, a spec is the name of a registered interceptor,
+// or name=path for the interceptor which runs the executable at path as subprocess. The subprocess
+// must decide a request in execTimeout, and the failures of interceptors are handled by failurePolicy.
+func NewChain(specs []string, execTimeout time.Duration, failurePolicy string) (*Chain, error) {
+	if failurePolicy != FailurePolicyIgnore && failurePolicy != FailurePolicyFail {
+		return nil, fmt.Errorf("failure policy %q is not supported, it must be %s or %s", failurePolicy, FailurePolicyIgnore, FailurePolicyFail)
+	}
+	chain := &Chain{failurePolicy: failurePolicy}
+	for _, spec := range specs {
+		name, path := spec, ""
+		if i := strings.Index(spec, "="); i >= 0 {
+			name, path = spec[:i], spec[i+1:]
+			if len(path) == 0 {
+				return nil, fmt.Errorf("path of interceptor %s is empty", name)
+			}
+		}
+		if len(name) == 0 {
+			return nil, fmt.Errorf("name of interceptor %q is empty", spec)
+		}
+
+		if len(path) != 0 {
+			chain.interceptors = append(chain.interceptors, NewExecInterceptor(name, path, execTimeout))
+			continue
+		}
+
+		registryLock.Lock()
+		factory, found := registry[name]
+		registryLock.Unlock()
+		if !found {
+			return nil, fmt.Errorf("interceptor %s has not registered", name)
+		}
+		ins, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create interceptor %s, %w", name, err)
+		}
+		chain.interceptors = append(chain.interceptors, ins)
+	}
+	return chain, nil
+}
+
+// Start starts all interceptors in the chain
+func (c *Chain) Start(stopCh <-chan struct{}) error {
+	for _, ins := range c.interceptors {
+		if err := ins.Start(stopCh); err != nil {
+			return fmt.Errorf("failed to start interceptor %s, %w", ins.Name(), err)
+		}
+		klog.Infof("interceptor %s is started", ins.Name())
+	}
+	return nil
+}
+
+// WithInterceptors runs the interceptors of chain before handler. When an interceptor fails, the
+// request is rejected or the interceptor is skipped according to the failure policy of chain.
+func WithInterceptors(handler http.Handler, chain *Chain) http.Handler {
+	if chain == nil || len(chain.interceptors) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ireq := newRequest(req)
+		responseHeaders := make(map[string]string)
+		for _, ins := range chain.interceptors {
+			start := time.Now()
+			decision, err := ins.Intercept(ireq)
+			duration := time.Since(start).Milliseconds()
+			if err != nil && chain.failurePolicy == FailurePolicyIgnore {
+				metrics.Metrics.ObserveInterceptor(ins.Name(), "ignored", duration)
+				klog.Errorf("interceptor %s failed for request %s, skip it, %v", ins.Name(), hubutil.ReqString(req), err)
+				continue
+			}
+			if err != nil {
+				metrics.Metrics.ObserveInterceptor(ins.Name(), "failed", duration)
+				klog.Errorf("interceptor %s failed for request %s, %v", ins.Name(), hubutil.ReqString(req), err)
+				util.Err(apierrors.NewServiceUnavailable(fmt.Sprintf("interceptor %s failed, %v", ins.Name(), err)), w, req)
+				return
+			}
+			if decision == nil {
+				metrics.Metrics.ObserveInterceptor(ins.Name(), "passed", duration)
+				continue
+			}
+
+			if decision.Response != nil {
+				metrics.Metrics.ObserveInterceptor(ins.Name(), "responded", duration)
+				klog.V(4).Infof("interceptor %s responded %d for request %s", ins.Name(), decision.Response.StatusCode, hubutil.ReqString(req))
+				writeResponse(w, decision.Response, responseHeaders)
+				return
+			}
+
+			metrics.Metrics.ObserveInterceptor(ins.Name(), "modified", duration)
+			for k, v := range decision.RequestHeaders {
+				if len(v) == 0 {
+					req.Header.Del(k)
+					ireq.Header.Del(k)
+				} else {
+					req.Header.Set(k, v)
+					ireq.Header.Set(k, v)
+				}
+			}
+			for k, v := range decision.ResponseHeaders {
+				responseHeaders[k] = v
+			}
+		}
+
+		if len(responseHeaders) != 0 {
+			w = &headerResponseWriter{ResponseWriter: w, headers: responseHeaders}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func newRequest(req *http.Request) *Request {
+	ireq := &Request{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Header:     req.Header.Clone(),
+		RemoteAddr: req.RemoteAddr,
+	}
+	if comp, ok := hubutil.ClientComponentFrom(req.Context()); ok {
+		ireq.Component = comp
+	}
+	if info, ok := apirequest.RequestInfoFrom(req.Context()); ok && info != nil {
+		ireq.Verb = info.Verb
+		ireq.APIGroup = info.APIGroup
+		ireq.APIVersion = info.APIVersion
+		ireq.Resource = info.Resource
+		ireq.Subresource = info.Subresource
+		ireq.Namespace = info.Namespace
+		ireq.Name = info.Name
+	}
+	return ireq
+}
+
+func writeResponse(w http.ResponseWriter, resp *Response, headers map[string]string) {
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	for k, v := range resp.Header {
+		w.Header().Set(k, v)
+	}
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(resp.Body); err != nil {
+		klog.Errorf("failed to write response of interceptor, %v", err)
+	}
+}
+
+// headerResponseWriter sets the response headers of interceptors when the header is written,
+// so they are not overwritten by the headers of the cloud response.
+type headerResponseWriter struct {
+	http.ResponseWriter
+	headers     map[string]string
+	wroteHeader bool
+}
+
+func (w *headerResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		for k, v := range w.headers {
+			w.ResponseWriter.Header().Set(k, v)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *headerResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush is needed by watch requests
+func (w *headerResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is needed by upgrade requests, e.g. exec and port-forward
+func (w *headerResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijack")
+	}
+	return hijacker.Hijack()
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type fakeInterceptor struct {
+	name     string
+	decision *Decision
+	err      error
+	requests []*Request
+}
+
+func (f *fakeInterceptor) Name() string {
+	return f.name
+}
+
+func (f *fakeInterceptor) Start(<-chan struct{}) error {
+	return nil
+}
+
+func (f *fakeInterceptor) Intercept(req *Request) (*Decision, error) {
+	f.requests = append(f.requests, req)
+	return f.decision, f.err
+}
+
+func newListPodsRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/default/pods", nil)
+	return req.WithContext(apirequest.WithRequestInfo(req.Context(), &apirequest.RequestInfo{
+		IsResourceRequest: true,
+		Verb:              "list",
+		APIVersion:        "v1",
+		Resource:          "pods",
+		Namespace:         "default",
+	}))
+}
+
+func TestWithInterceptors(t *testing.T) {
+	testcases := map[string]struct {
+		interceptors  []Interceptor
+		failurePolicy string
+		statusCode    int
+		proxied       bool
+		reqHeaders    map[string]string
+		respHeaders   map[string]string
+	}{
+		"interceptors pass the request": {
+			interceptors: []Interceptor{&fakeInterceptor{name: "foo"}, &fakeInterceptor{name: "bar"}},
+			statusCode:   http.StatusOK,
+			proxied:      true,
+		},
+		"interceptors modify headers": {
+			interceptors: []Interceptor{
+				&fakeInterceptor{name: "foo", decision: &Decision{
+					RequestHeaders:  map[string]string{"X-Tenant": "edge", "Authorization": ""},
+					ResponseHeaders: map[string]string{"X-Intercepted-By": "foo"},
+				}},
+			},
+			statusCode:  http.StatusOK,
+			proxied:     true,
+			reqHeaders:  map[string]string{"X-Tenant": "edge", "Authorization": ""},
+			respHeaders: map[string]string{"X-Intercepted-By": "foo"},
+		},
+		"interceptor responds the request": {
+			interceptors: []Interceptor{
+				&fakeInterceptor{name: "foo", decision: &Decision{Response: &Response{StatusCode: http.StatusUnauthorized, Body: []byte("denied")}}},
+				&fakeInterceptor{name: "bar", err: errors.New("should not be called")},
+			},
+			statusCode: http.StatusUnauthorized,
+		},
+		"interceptor fails": {
+			interceptors:  []Interceptor{&fakeInterceptor{name: "foo", err: errors.New("boom")}},
+			failurePolicy: FailurePolicyFail,
+			statusCode:    http.StatusServiceUnavailable,
+		},
+		"failed interceptor is ignored": {
+			interceptors: []Interceptor{
+				&fakeInterceptor{name: "foo", err: errors.New("boom")},
+				&fakeInterceptor{name: "bar", decision: &Decision{ResponseHeaders: map[string]string{"X-Intercepted-By": "bar"}}},
+			},
+			failurePolicy: FailurePolicyIgnore,
+			statusCode:    http.StatusOK,
+			proxied:       true,
+			respHeaders:   map[string]string{"X-Intercepted-By": "bar"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			proxied := false
+			var proxiedReq *http.Request
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				proxied = true
+				proxiedReq = req
+				w.Header().Set("X-Intercepted-By", "cloud")
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := newListPodsRequest()
+			req.Header.Set("Authorization", "Bearer xxx")
+			rec := httptest.NewRecorder()
+			WithInterceptors(handler, &Chain{interceptors: tc.interceptors, failurePolicy: tc.failurePolicy}).ServeHTTP(rec, req)
+
+			if rec.Code != tc.statusCode {
+				t.Errorf("expect status %d, but got %d", tc.statusCode, rec.Code)
+			}
+			if proxied != tc.proxied {
+				t.Fatalf("expect proxied %v, but got %v", tc.proxied, proxied)
+			}
+			for k, v := range tc.reqHeaders {
+				if got := proxiedReq.Header.Get(k); got != v {
+					t.Errorf("expect request header %s=%q, but got %q", k, v, got)
+				}
+			}
+			for k, v := range tc.respHeaders {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expect response header %s=%q, but got %q", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInterceptRequestInfo(t *testing.T) {
+	f := &fakeInterceptor{name: "foo"}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	WithInterceptors(handler, &Chain{interceptors: []Interceptor{f}}).ServeHTTP(httptest.NewRecorder(), newListPodsRequest())
+
+	if len(f.requests) != 1 {
+		t.Fatalf("expect 1 intercepted request, but got %d", len(f.requests))
+	}
+	req := f.requests[0]
+	if req.Method != http.MethodGet || req.Verb != "list" || req.Resource != "pods" || req.Namespace != "default" {
+		t.Errorf("unexpected intercepted request %#v", req)
+	}
+}
+
+func TestNewChain(t *testing.T) {
+	Register("fake", func() (Interceptor, error) {
+		return &fakeInterceptor{name: "fake"}, nil
+	})
+
+	testcases := map[string]struct {
+		specs         []string
+		failurePolicy string
+		names         []string
+		isErr         bool
+	}{
+		"registered and exec interceptors": {
+			specs: []string{"fake", "vendor=/opt/vendor/interceptor"},
+			names: []string{"fake", "vendor"},
+		},
+		"interceptor not registered": {
+			specs: []string{"unknown"},
+			isErr: true,
+		},
+		"empty path": {
+			specs: []string{"vendor="},
+			isErr: true,
+		},
+		"empty name": {
+			specs: []string{"=/opt/vendor/interceptor"},
+			isErr: true,
+		},
+		"unknown failure policy": {
+			specs:         []string{"fake"},
+			failurePolicy: "Retry",
+			isErr:         true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			failurePolicy := tc.failurePolicy
+			if len(failurePolicy) == 0 {
+				failurePolicy = FailurePolicyFail
+			}
+			chain, err := NewChain(tc.specs, time.Second, failurePolicy)
+			if (err != nil) != tc.isErr {
+				t.Fatalf("expect error %v, but got %v", tc.isErr, err)
+			}
+			if tc.isErr {
+				return
+			}
+			if len(chain.interceptors) != len(tc.names) {
+				t.Fatalf("expect %d interceptors, but got %d", len(tc.names), len(chain.interceptors))
+			}
+			for i, name := range tc.names {
+				if chain.interceptors[i].Name() != name {
+					t.Errorf("expect interceptor %s, but got %s", name, chain.interceptors[i].Name())
+				}
+			}
+		})
+	}
+}
+
+func TestExecInterceptor(t *testing.T) {
+	script := `while read line; do
+  id=$(echo "$line" | sed 's/^{"id":\([0-9]*\).*/\1/')
+  case "$line" in
+    *'"verb":"delete"'*) echo "{\"id\":$id,\"decision\":{\"response\":{\"statusCode\":403,\"body\":\"ZGVuaWVk\"}}}" ;;
+    *'"verb":"watch"'*) (sleep 1; echo "{\"id\":$id,\"decision\":{}}") & ;;
+    *'"verb":"crash"'*) exit 1 ;;
+    *) echo "{\"id\":$id,\"decision\":{\"requestHeaders\":{\"X-Vendor\":\"yes\"}}}" ;;
+  esac
+done`
+	e := NewExecInterceptor("vendor", "/bin/sh", 500*time.Millisecond, "-c", script)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := e.Start(stopCh); err != nil {
+		t.Fatalf("failed to start interceptor, %v", err)
+	}
+
+	decision, err := e.Intercept(&Request{Verb: "list"})
+	if err != nil || decision == nil || decision.RequestHeaders["X-Vendor"] != "yes" {
+		t.Errorf("unexpected decision %#v, %v", decision, err)
+	}
+
+	decision, err = e.Intercept(&Request{Verb: "delete"})
+	if err != nil || decision == nil || decision.Response == nil || decision.Response.StatusCode != http.StatusForbidden || string(decision.Response.Body) != "denied" {
+		t.Errorf("unexpected decision %#v, %v", decision, err)
+	}
+
+	// a slow request times out without blocking the other requests
+	slowErr := make(chan error, 1)
+	go func() {
+		_, err := e.Intercept(&Request{Verb: "watch"})
+		slowErr <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	decision, err = e.Intercept(&Request{Verb: "get"})
+	if err != nil || decision == nil || decision.RequestHeaders["X-Vendor"] != "yes" {
+		t.Errorf("expect request decided while the slow one is pending, but got %#v, %v", decision, err)
+	}
+	if err := <-slowErr; err == nil {
+		t.Errorf("expect error if subprocess doesn't respond in time")
+	}
+	// the late decision of timed out request is dropped
+	time.Sleep(time.Second)
+
+	// the pending request fails as soon as subprocess crashes, and the subprocess is restarted
+	start := time.Now()
+	if _, err := e.Intercept(&Request{Verb: "crash"}); err == nil || time.Since(start) >= 500*time.Millisecond {
+		t.Errorf("expect request failed immediately when subprocess crashes, but got %v after %v", err, time.Since(start))
+	}
+	if err := wait.PollImmediate(100*time.Millisecond, 5*time.Second, func() (bool, error) {
+		decision, err := e.Intercept(&Request{Verb: "get"})
+		return err == nil && decision != nil && decision.RequestHeaders["X-Vendor"] == "yes", nil
+	}); err != nil {
+		t.Errorf("expect subprocess restarted after crash, %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interceptor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultExecTimeout is the default timeout of subprocess for deciding a request
+	DefaultExecTimeout = time.Second
+	// execRestartPeriod is the period for restarting the subprocess after it exits
+	execRestartPeriod = time.Second
+)
+
+// execMessage is a line of json exchanged with the subprocess, a Request is sent with a unique
+// id, and the Decision of request is sent back with the same id.
+type execMessage struct {
+	ID       uint64    `json:"id"`
+	Request  *Request  `json:"request,omitempty"`
+	Decision *Decision `json:"decision,omitempty"`
+}
+
+// execInterceptor runs the interceptor as a subprocess. yurthub writes {"id":1,"request":{...}} as a
+// line into stdin of the subprocess for every request, and the subprocess writes back
+// {"id":1,"decision":{...}} as a line into stdout. The requests are multiplexed over the pipes, so the
+// subprocess is able to decide requests concurrently and in any order, and a slow request doesn't
+// block the others. The subprocess is restarted when it exits.
+type execInterceptor struct {
+	name    string
+	path    string
+	args    []string
+	timeout time.Duration
+
+	// writeLock serializes the requests written into stdin
+	writeLock sync.Mutex
+	sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	nextID  uint64
+	pending map[uint64]chan *Decision
+	stopped bool
+}
+
+// NewExecInterceptor creates an Interceptor which runs the executable at path as subprocess, a
+// request fails if the subprocess doesn't decide it in timeout.
+func NewExecInterceptor(name, path string, timeout time.Duration, args ...string) Interceptor {
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	return &execInterceptor{
+		name:    name,
+		path:    path,
+		args:    args,
+		timeout: timeout,
+		pending: make(map[uint64]chan *Decision),
+	}
+}
+
+func (e *execInterceptor) Name() string {
+	return e.name
+}
+
+// Start starts the subprocess, and restarts it until stopCh is closed if it exits.
+func (e *execInterceptor) Start(stopCh <-chan struct{}) error {
+	dispatched, err := e.startProcess()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		e.waitProcess(dispatched)
+		wait.Until(func() {
+			dispatched, err := e.startProcess()
+			if err != nil {
+				klog.Errorf("failed to restart subprocess of interceptor %s, %v", e.name, err)
+				return
+			}
+			klog.Infof("subprocess of interceptor %s is restarted", e.name)
+			e.waitProcess(dispatched)
+		}, execRestartPeriod, stopCh)
+	}()
+
+	go func() {
+		<-stopCh
+		e.Lock()
+		defer e.Unlock()
+		e.stopped = true
+		if e.cmd != nil {
+			e.stdin.Close()
+			if err := e.cmd.Process.Kill(); err != nil {
+				klog.V(4).Infof("failed to kill subprocess of interceptor %s, %v", e.name, err)
+			}
+		}
+		klog.Infof("interceptor %s is stopped", e.name)
+	}()
+	return nil
+}
+
+// startProcess starts the subprocess and the goroutine dispatching its decisions, the returned
+// channel is closed when stdout of subprocess is closed.
+func (e *execInterceptor) startProcess() (<-chan struct{}, error) {
+	e.Lock()
+	defer e.Unlock()
+	if e.stopped {
+		return nil, fmt.Errorf("interceptor is stopped")
+	}
+
+	cmd := exec.Command(e.path, e.args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s, %w", e.path, err)
+	}
+	e.cmd, e.stdin = cmd, stdin
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		e.dispatch(bufio.NewReader(stdout))
+	}()
+	return dispatched, nil
+}
+
+// waitProcess waits for the subprocess to exit, the pending requests fail as soon as it exits.
+// The decisions in stdout are dispatched before waiting, because stdout is closed by Wait.
+func (e *execInterceptor) waitProcess(dispatched <-chan struct{}) {
+	<-dispatched
+	e.Lock()
+	cmd := e.cmd
+	e.Unlock()
+	err := cmd.Wait()
+
+	e.Lock()
+	defer e.Unlock()
+	if !e.stopped {
+		klog.Errorf("subprocess of interceptor %s exits, %v", e.name, err)
+	}
+	e.cmd, e.stdin = nil, nil
+	for id, ch := range e.pending {
+		close(ch)
+		delete(e.pending, id)
+	}
+}
+
+// dispatch reads the decisions from stdout of subprocess and delivers them to the pending requests,
+// the decisions of requests which are timed out are dropped.
+func (e *execInterceptor) dispatch(stdout *bufio.Reader) {
+	for {
+		line, err := stdout.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var msg execMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			klog.Errorf("failed to decode message of interceptor %s, %v", e.name, err)
+			continue
+		}
+
+		e.Lock()
+		ch, ok := e.pending[msg.ID]
+		delete(e.pending, msg.ID)
+		e.Unlock()
+		if !ok {
+			klog.V(4).Infof("decision %d of interceptor %s is dropped", msg.ID, e.name)
+			continue
+		}
+		ch <- msg.Decision
+	}
+}
+
+func (e *execInterceptor) Intercept(req *Request) (*Decision, error) {
+	e.Lock()
+	if e.stopped {
+		e.Unlock()
+		return nil, fmt.Errorf("interceptor is stopped")
+	}
+	if e.stdin == nil {
+		e.Unlock()
+		return nil, fmt.Errorf("subprocess is not running")
+	}
+	e.nextID++
+	id, stdin := e.nextID, e.stdin
+	ch := make(chan *Decision, 1)
+	e.pending[id] = ch
+	e.Unlock()
+
+	data, err := json.Marshal(&execMessage{ID: id, Request: req})
+	if err == nil {
+		e.writeLock.Lock()
+		_, err = stdin.Write(append(data, '\n'))
+		e.writeLock.Unlock()
+	}
+	if err != nil {
+		e.cancel(id)
+		return nil, fmt.Errorf("failed to write request to subprocess, %w", err)
+	}
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	select {
+	case decision, ok := <-ch:
+		if !ok {
+			return nil, fmt.Errorf("subprocess exits before deciding the request")
+		}
+		if decision == nil || (decision.Response == nil && len(decision.RequestHeaders) == 0 && len(decision.ResponseHeaders) == 0) {
+			return nil, nil
+		}
+		return decision, nil
+	case <-timer.C:
+		e.cancel(id)
+		return nil, fmt.Errorf("subprocess doesn't respond in %v", e.timeout)
+	}
+}
+
+func (e *execInterceptor) cancel(id uint64) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.pending, id)
+}
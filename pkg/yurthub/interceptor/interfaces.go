@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interceptor
+
+import (
+	"net/http"
+)
+
+// Interceptor intercepts the requests in the proxy chain of yurthub, it's the extension point for
+// vendors to extend yurthub without forking, e.g. custom authentication or protocol translation.
+// The interceptors are registered by Register in a customized yurthub binary, or run as
+// subprocesses which talk with yurthub through stdin and stdout.
+// Intercept may be called concurrently for the requests of proxy.
+type Interceptor interface {
+	// Name returns the name of interceptor, which is used in logs and metrics.
+	Name() string
+	// Start is called once before yurthub serves requests, the resources of interceptor
+	// should be released when stopCh is closed.
+	Start(stopCh <-chan struct{}) error
+	// Intercept is called for every request in order of the interceptors, a nil Decision lets the
+	// request go through unchanged, and the request is rejected if an error is returned.
+	Intercept(req *Request) (*Decision, error)
+}
+
+// Request is the request for interceptors, the body is not included because requests are streamed.
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	Header      http.Header `json:"header,omitempty"`
+	RemoteAddr  string      `json:"remoteAddr,omitempty"`
+	Component   string      `json:"component,omitempty"`
+	Verb        string      `json:"verb,omitempty"`
+	APIGroup    string      `json:"apiGroup,omitempty"`
+	APIVersion  string      `json:"apiVersion,omitempty"`
+	Resource    string      `json:"resource,omitempty"`
+	Subresource string      `json:"subresource,omitempty"`
+	Namespace   string      `json:"namespace,omitempty"`
+	Name        string      `json:"name,omitempty"`
+}
+
+// Decision is the result of intercepting a request.
+type Decision struct {
+	// Response is written to the client directly if it's set, and the request is not proxied.
+	Response *Response `json:"response,omitempty"`
+	// RequestHeaders are set into the request before it's proxied, the header is deleted if the value is empty.
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+	// ResponseHeaders are set into the response of the request.
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+}
+
+// Response is the response which is written by interceptor.
+type Response struct {
+	StatusCode int               `json:"statusCode"`
+	Header     map[string]string `json:"header,omitempty"`
+	// Body is the body of response, it's base64 encoded in json, so any content(like protobuf)
+	// can be responded by the subprocess.
+	Body []byte `json:"body,omitempty"`
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serializer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// ContentTypeCBOR is the media type of objects encoded in CBOR
+	ContentTypeCBOR = "application/cbor"
+	// ContentTypeCBORSequence is the media type of watch events streamed as a CBOR sequence
+	ContentTypeCBORSequence = "application/cbor-seq"
+)
+
+// selfDescribedCBOR is the tag 55799 which is prefixed to the objects encoded by kube-apiserver,
+// it makes the CBOR data distinguishable from other formats.
+var selfDescribedCBOR = []byte{0xd9, 0xd9, 0xf7}
+
+var (
+	cborEncMode, _ = cbor.EncOptions{Sort: cbor.SortBytewiseLexical}.EncMode()
+	cborDecMode, _ = cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+		IntDec:         cbor.IntDecConvertSigned,
+	}.DecMode()
+)
+
+// SerializerInfoFunc returns the serializers of media types which are not provided by client-go,
+// the objects are created by creater and typed by typer when they are decoded.
+type SerializerInfoFunc func(creater runtime.ObjectCreater, typer runtime.ObjectTyper) []runtime.SerializerInfo
+
+// pluggableSerializers are appended to the supported media types of both the scheme
+// and the unstructured negotiated serializers.
+var pluggableSerializers = []SerializerInfoFunc{
+	cborSerializerInfos,
+}
+
+func pluggableSerializerInfos(creater runtime.ObjectCreater, typer runtime.ObjectTyper) []runtime.SerializerInfo {
+	var infos []runtime.SerializerInfo
+	for _, fn := range pluggableSerializers {
+		infos = append(infos, fn(creater, typer)...)
+	}
+	return infos
+}
+
+// cborSerializerInfos returns the serializers for CBOR, watch events are streamed by the same
+// serializer for both media types because the CBOR data items are self-delimiting.
+func cborSerializerInfos(creater runtime.ObjectCreater, typer runtime.ObjectTyper) []runtime.SerializerInfo {
+	s := &cborSerializer{creater: creater, typer: typer}
+	stream := &runtime.StreamSerializerInfo{
+		Serializer: s,
+		Framer:     cborFramer{},
+	}
+	return []runtime.SerializerInfo{
+		{
+			MediaType:        ContentTypeCBOR,
+			MediaTypeType:    "application",
+			MediaTypeSubType: "cbor",
+			Serializer:       s,
+			StreamSerializer: stream,
+		},
+		{
+			MediaType:        ContentTypeCBORSequence,
+			MediaTypeType:    "application",
+			MediaTypeSubType: "cbor-seq",
+			Serializer:       s,
+			StreamSerializer: stream,
+		},
+	}
+}
+
+// cborSerializer converts objects from and to the unstructured content, and encodes the content
+// in CBOR. The object of WatchEvent is kept as raw bytes so it can be decoded by the embedded decoder.
+type cborSerializer struct {
+	creater runtime.ObjectCreater
+	typer   runtime.ObjectTyper
+}
+
+func (s *cborSerializer) Identifier() runtime.Identifier {
+	return "cbor"
+}
+
+func (s *cborSerializer) Encode(obj runtime.Object, w io.Writer) error {
+	content, err := toCBORContent(obj)
+	if err != nil {
+		return err
+	}
+
+	data, err := cborEncMode.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(selfDescribedCBOR); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func toCBORContent(obj runtime.Object) (interface{}, error) {
+	switch t := obj.(type) {
+	case *metav1.WatchEvent:
+		content := map[string]interface{}{"type": t.Type}
+		if len(t.Object.Raw) != 0 {
+			// the object has been encoded by the embedded encoder
+			content["object"] = cbor.RawMessage(bytes.TrimPrefix(t.Object.Raw, selfDescribedCBOR))
+		} else if t.Object.Object != nil {
+			objContent, err := toCBORContent(t.Object.Object)
+			if err != nil {
+				return nil, err
+			}
+			content["object"] = objContent
+		}
+		return content, nil
+	case runtime.Unstructured:
+		return t.UnstructuredContent(), nil
+	default:
+		return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	}
+}
+
+func (s *cborSerializer) Decode(data []byte, defaults *schema.GroupVersionKind, into runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	data = bytes.TrimPrefix(data, selfDescribedCBOR)
+	if event, ok := into.(*metav1.WatchEvent); ok {
+		return decodeCBORWatchEvent(data, event)
+	}
+
+	var content interface{}
+	if err := cborDecMode.Unmarshal(data, &content); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode cbor data, %w", err)
+	}
+	obj, ok := normalizeCBORContent(content).(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("cbor data is not an object, but %T", content)
+	}
+
+	actual := (&unstructured.Unstructured{Object: obj}).GroupVersionKind()
+	if defaults != nil {
+		if len(actual.Kind) == 0 {
+			actual.Kind = defaults.Kind
+		}
+		if len(actual.Version) == 0 && len(actual.Group) == 0 {
+			actual.Group = defaults.Group
+			actual.Version = defaults.Version
+		}
+	}
+	if len(actual.Kind) == 0 {
+		return nil, &actual, runtime.NewMissingKindErr(string(data))
+	}
+	if len(actual.Version) == 0 {
+		return nil, &actual, runtime.NewMissingVersionErr(string(data))
+	}
+
+	if into == nil {
+		created, err := s.creater.New(actual)
+		if err != nil {
+			return nil, &actual, err
+		}
+		into = created
+	}
+
+	if u, ok := into.(runtime.Unstructured); ok {
+		u.SetUnstructuredContent(obj)
+		return into, &actual, nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, into); err != nil {
+		return nil, &actual, err
+	}
+	into.GetObjectKind().SetGroupVersionKind(actual)
+	return into, &actual, nil
+}
+
+func decodeCBORWatchEvent(data []byte, event *metav1.WatchEvent) (runtime.Object, *schema.GroupVersionKind, error) {
+	var content struct {
+		Type   string          `cbor:"type"`
+		Object cbor.RawMessage `cbor:"object"`
+	}
+	if err := cborDecMode.Unmarshal(data, &content); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode cbor watch event, %w", err)
+	}
+	event.Type = content.Type
+	event.Object = runtime.RawExtension{Raw: []byte(content.Object)}
+	gvk := metav1.SchemeGroupVersion.WithKind("WatchEvent")
+	return event, &gvk, nil
+}
+
+// normalizeCBORContent converts the decoded values into the types of unstructured content
+// which is decoded from json, so they can be converted into typed objects.
+func normalizeCBORContent(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeCBORContent(e)
+		}
+		return t
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeCBORContent(e)
+		}
+		return t
+	case uint64:
+		if t > math.MaxInt64 {
+			return float64(t)
+		}
+		return int64(t)
+	case float32:
+		return float64(t)
+	case []byte:
+		// byte strings are represented by base64 strings in json
+		return base64.StdEncoding.EncodeToString(t)
+	case cbor.Tag:
+		return normalizeCBORContent(t.Content)
+	default:
+		return v
+	}
+}
+
+// cborFramer writes the CBOR data items as they are, and reads one data item for each frame.
+type cborFramer struct{}
+
+func (cborFramer) NewFrameWriter(w io.Writer) io.Writer {
+	return w
+}
+
+func (cborFramer) NewFrameReader(r io.ReadCloser) io.ReadCloser {
+	return &cborFrameReader{
+		r:       r,
+		decoder: cborDecMode.NewDecoder(r),
+	}
+}
+
+type cborFrameReader struct {
+	r         io.ReadCloser
+	decoder   *cbor.Decoder
+	remaining []byte
+}
+
+// Read decodes the next data item into data, it returns io.ErrShortBuffer if data is too small,
+// and the rest of the data item will be returned by the following reads.
+func (r *cborFrameReader) Read(data []byte) (int, error) {
+	if len(r.remaining) > 0 {
+		n := copy(data, r.remaining)
+		r.remaining = r.remaining[n:]
+		if len(r.remaining) > 0 {
+			return n, io.ErrShortBuffer
+		}
+		return n, nil
+	}
+
+	var m cbor.RawMessage
+	if err := r.decoder.Decode(&m); err != nil {
+		return 0, err
+	}
+
+	n := copy(data, m)
+	if n < len(m) {
+		r.remaining = m[n:]
+		return n, io.ErrShortBuffer
+	}
+	return n, nil
+}
+
+func (r *cborFrameReader) Close() error {
+	return r.r.Close()
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serializer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestCBORSerializer(t *testing.T) {
+	sm := NewSerializerManager()
+	pod := &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			Containers: []v1.Container{
+				{Name: "nginx", Image: "nginx", Ports: []v1.ContainerPort{{ContainerPort: 80}}},
+			},
+		},
+	}
+	crd := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps.openyurt.io/v1alpha1",
+			"kind":       "NodePool",
+			"metadata": map[string]interface{}{
+				"name": "hangzhou",
+			},
+			"spec": map[string]interface{}{
+				"type":     "Edge",
+				"replicas": int64(3),
+			},
+		},
+	}
+
+	testcases := map[string]struct {
+		contentType string
+		group       string
+		version     string
+		resource    string
+		obj         runtime.Object
+	}{
+		"typed object": {
+			contentType: ContentTypeCBOR,
+			version:     "v1",
+			resource:    "pods",
+			obj:         pod,
+		},
+		"unstructured object": {
+			contentType: ContentTypeCBOR,
+			group:       "apps.openyurt.io",
+			version:     "v1alpha1",
+			resource:    "nodepools",
+			obj:         crd,
+		},
+		"typed object in cbor sequence": {
+			contentType: ContentTypeCBORSequence,
+			version:     "v1",
+			resource:    "pods",
+			obj:         pod,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			s := sm.CreateSerializer(tc.contentType, tc.group, tc.version, tc.resource)
+			data, err := s.Encode(tc.obj)
+			if err != nil {
+				t.Fatalf("failed to encode object, %v", err)
+			}
+			if !bytes.HasPrefix(data, selfDescribedCBOR) {
+				t.Errorf("encoded data is not self described cbor, %v", data)
+			}
+
+			obj, err := s.Decode(data)
+			if err != nil {
+				t.Fatalf("failed to decode object, %v", err)
+			}
+			assertObjectEqual(t, tc.obj, obj)
+
+			buf := &bytes.Buffer{}
+			for _, eventType := range []watch.EventType{watch.Added, watch.Modified} {
+				if _, err := s.WatchEncode(buf, &watch.Event{Type: eventType, Object: tc.obj}); err != nil {
+					t.Fatalf("failed to encode watch event, %v", err)
+				}
+			}
+
+			decoder, err := s.WatchDecoder(io.NopCloser(buf))
+			if err != nil {
+				t.Fatalf("failed to create watch decoder, %v", err)
+			}
+			for _, eventType := range []watch.EventType{watch.Added, watch.Modified} {
+				gotType, obj, err := decoder.Decode()
+				if err != nil {
+					t.Fatalf("failed to decode watch event, %v", err)
+				}
+				if gotType != eventType {
+					t.Errorf("expect event type %s, but got %s", eventType, gotType)
+				}
+				assertObjectEqual(t, tc.obj, obj)
+			}
+		})
+	}
+}
+
+func TestCBORNegotiation(t *testing.T) {
+	sm := NewSerializerManager()
+	for _, ns := range []runtime.NegotiatedSerializer{sm.NegotiatedSerializer, sm.UnstructuredNegotiatedSerializer} {
+		for _, mediaType := range []string{ContentTypeCBOR, ContentTypeCBORSequence} {
+			info, ok := runtime.SerializerInfoForMediaType(ns.SupportedMediaTypes(), mediaType)
+			if !ok {
+				t.Errorf("media type %s is not supported by %T", mediaType, ns)
+				continue
+			}
+			if info.StreamSerializer == nil {
+				t.Errorf("media type %s of %T can not be streamed", mediaType, ns)
+			}
+		}
+	}
+}
+
+func assertObjectEqual(t *testing.T, expected, got runtime.Object) {
+	t.Helper()
+	if reflect.TypeOf(expected) != reflect.TypeOf(got) {
+		t.Fatalf("expect object of %T, but got %T", expected, got)
+	}
+	expectedContent, err := toCBORContent(expected)
+	if err != nil {
+		t.Fatalf("failed to convert object, %v", err)
+	}
+	gotContent, err := toCBORContent(got)
+	if err != nil {
+		t.Fatalf("failed to convert object, %v", err)
+	}
+	if !reflect.DeepEqual(expectedContent, gotContent) {
+		t.Errorf("expect object %#+v, but got %#+v", expectedContent, gotContent)
+	}
+}
@@ -67,7 +67,10 @@ type SerializerManager struct {
 func NewSerializerManager() *SerializerManager {
 	sm := &SerializerManager{
 		// do not need version conversion, and keep the gvk information
-		NegotiatedSerializer:             WithVersionCodecFactory{CodecFactory: scheme.Codecs},
+		NegotiatedSerializer: WithVersionCodecFactory{
+			CodecFactory:        scheme.Codecs,
+			pluggableMediaTypes: pluggableSerializerInfos(scheme.Scheme, scheme.Scheme),
+		},
 		UnstructuredNegotiatedSerializer: NewUnstructuredNegotiatedSerializer(),
 		ClientNegotiators:                make(map[schema.GroupVersionResource]*yurtClientNegotiator),
 	}
@@ -91,6 +94,18 @@ func (sm *SerializerManager) GetNegotiatedSerializer(gvr schema.GroupVersionReso
 // This wrapper is used while code migrates away from using conversion (such as external clients)
 type WithVersionCodecFactory struct {
 	serializer.CodecFactory
+	// pluggableMediaTypes are the media types supported besides the ones of CodecFactory, like CBOR
+	pluggableMediaTypes []runtime.SerializerInfo
+}
+
+// SupportedMediaTypes returns the media types of CodecFactory and the pluggable serializers.
+func (f WithVersionCodecFactory) SupportedMediaTypes() []runtime.SerializerInfo {
+	if len(f.pluggableMediaTypes) == 0 {
+		return f.CodecFactory.SupportedMediaTypes()
+	}
+	mediaTypes := make([]runtime.SerializerInfo, 0, len(f.CodecFactory.SupportedMediaTypes())+len(f.pluggableMediaTypes))
+	mediaTypes = append(mediaTypes, f.CodecFactory.SupportedMediaTypes()...)
+	return append(mediaTypes, f.pluggableMediaTypes...)
 }
 
 // EncoderForVersion returns an encoder that does not do conversion, but does set the group version kind of the object
@@ -136,7 +151,7 @@ func NewUnstructuredNegotiatedSerializer() runtime.NegotiatedSerializer {
 }
 
 func (s UnstructuredNegotiatedSerializer) SupportedMediaTypes() []runtime.SerializerInfo {
-	return []runtime.SerializerInfo{
+	mediaTypes := []runtime.SerializerInfo{
 		{
 			MediaType:        "application/json",
 			MediaTypeType:    "application",
@@ -158,6 +173,7 @@ func (s UnstructuredNegotiatedSerializer) SupportedMediaTypes() []runtime.Serial
 			Serializer:       json.NewSerializerWithOptions(json.DefaultMetaFactory, s.creator, s.typer, json.SerializerOptions{Yaml: true}),
 		},
 	}
+	return append(mediaTypes, pluggableSerializerInfos(s.creator, s.typer)...)
 }
 
 // EncoderForVersion do nothing, but returns a encoder,
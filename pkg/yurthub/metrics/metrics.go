@@ -17,6 +17,7 @@ limitations under the License.
 package metrics
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -54,6 +55,16 @@ type HubMetrics struct {
 	poolCoordinatorYurthubRoleCollector   *prometheus.GaugeVec
 	poolCoordinatorHealthyStatusCollector *prometheus.GaugeVec
 	poolCoordinatorReadyStatusCollector   *prometheus.GaugeVec
+	interceptorRequestsCounter            *prometheus.CounterVec
+	interceptorLatencyCollector           *prometheus.GaugeVec
+	clockOffsetCollector                  *prometheus.GaugeVec
+	timeSyncStatusCollector               *prometheus.GaugeVec
+	cacheHitsCounter                      *prometheus.CounterVec
+	cacheMissesCounter                    *prometheus.CounterVec
+	cacheStaleServesCounter               *prometheus.CounterVec
+	cacheWrittenBytesCounter              *prometheus.CounterVec
+	cachedObjectsCollector                *prometheus.GaugeVec
+	replayedWritesRejectedCounter         *prometheus.CounterVec
 }
 
 func newHubMetrics() *HubMetrics {
@@ -116,7 +127,7 @@ func newHubMetrics() *HubMetrics {
 			Namespace: namespace,
 			Subsystem: subsystem,
 			Name:      "pool_coordinator_yurthub_role",
-			Help:      "pool coordinator status of yurthub. 1: LeaderHub, 2: FollowerHub 3: Pending",
+			Help:      "pool coordinator status of yurthub. 1: LeaderHub, 2: FollowerHub 3: Pending 4: DelegateHub",
 		},
 		[]string{})
 	poolCoordinatorHealthyStatusCollector := prometheus.NewGaugeVec(
@@ -135,6 +146,86 @@ func newHubMetrics() *HubMetrics {
 			Help:      "pool coordinator ready status 1: ready, 0: notReady",
 		},
 		[]string{})
+	interceptorRequestsCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "interceptor_requests_counter",
+			Help:      "counter of requests intercepted by interceptors in hub agent, result: passed, modified, responded, failed, ignored",
+		},
+		[]string{"interceptor", "result"})
+	interceptorLatencyCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "interceptor_latency_collector",
+			Help:      "collector of latency of interceptors in hub agent(unit: ms)",
+		},
+		[]string{"interceptor"})
+	clockOffsetCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "clock_offset_seconds",
+			Help:      "offset of node clock versus the time source(unit: s), source: apiserver, ntp",
+		},
+		[]string{"source"})
+	timeSyncStatusCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "time_sync_status",
+			Help:      "time synchronization status of node clock 1: synchronized, 0: unsynchronized",
+		},
+		[]string{})
+	cacheHitsCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cache_hits_counter",
+			Help:      "counter of requests which are served by objects in local cache",
+		},
+		[]string{"client", "verb", "resource"})
+	cacheMissesCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cache_misses_counter",
+			Help:      "counter of requests which can not be served by objects in local cache",
+		},
+		[]string{"client", "verb", "resource"})
+	cacheStaleServesCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cache_stale_serves_counter",
+			Help:      "counter of requests which are served by objects in local cache because remote servers are unavailable",
+		},
+		[]string{"client", "verb", "resource"})
+	cacheWrittenBytesCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cache_written_bytes_counter",
+			Help:      "counter of bytes written into cache storage(unit: byte)",
+		},
+		[]string{"storage"})
+	cachedObjectsCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "cached_objects",
+			Help:      "number of objects in local cache for each client and resource",
+		},
+		[]string{"client", "resource"})
+	replayedWritesRejectedCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "replayed_writes_rejected_counter",
+			Help:      "counter of writes accepted while the cloud is unreachable but rejected by the cloud when they are replayed, webhooks_deferred: true, false",
+		},
+		[]string{"resource", "webhooks_deferred"})
 	prometheus.MustRegister(serversHealthyCollector)
 	prometheus.MustRegister(inFlightRequestsCollector)
 	prometheus.MustRegister(inFlightRequestsGauge)
@@ -145,6 +236,16 @@ func newHubMetrics() *HubMetrics {
 	prometheus.MustRegister(poolCoordinatorYurthubRoleCollector)
 	prometheus.MustRegister(poolCoordinatorHealthyStatusCollector)
 	prometheus.MustRegister(poolCoordinatorReadyStatusCollector)
+	prometheus.MustRegister(interceptorRequestsCounter)
+	prometheus.MustRegister(interceptorLatencyCollector)
+	prometheus.MustRegister(clockOffsetCollector)
+	prometheus.MustRegister(timeSyncStatusCollector)
+	prometheus.MustRegister(cacheHitsCounter)
+	prometheus.MustRegister(cacheMissesCounter)
+	prometheus.MustRegister(cacheStaleServesCounter)
+	prometheus.MustRegister(cacheWrittenBytesCounter)
+	prometheus.MustRegister(cachedObjectsCollector)
+	prometheus.MustRegister(replayedWritesRejectedCounter)
 	return &HubMetrics{
 		serversHealthyCollector:               serversHealthyCollector,
 		inFlightRequestsCollector:             inFlightRequestsCollector,
@@ -156,6 +257,16 @@ func newHubMetrics() *HubMetrics {
 		poolCoordinatorHealthyStatusCollector: poolCoordinatorHealthyStatusCollector,
 		poolCoordinatorReadyStatusCollector:   poolCoordinatorReadyStatusCollector,
 		poolCoordinatorYurthubRoleCollector:   poolCoordinatorYurthubRoleCollector,
+		interceptorRequestsCounter:            interceptorRequestsCounter,
+		interceptorLatencyCollector:           interceptorLatencyCollector,
+		clockOffsetCollector:                  clockOffsetCollector,
+		timeSyncStatusCollector:               timeSyncStatusCollector,
+		cacheHitsCounter:                      cacheHitsCounter,
+		cacheMissesCounter:                    cacheMissesCounter,
+		cacheStaleServesCounter:               cacheStaleServesCounter,
+		cacheWrittenBytesCounter:              cacheWrittenBytesCounter,
+		cachedObjectsCollector:                cachedObjectsCollector,
+		replayedWritesRejectedCounter:         replayedWritesRejectedCounter,
 	}
 }
 
@@ -166,6 +277,14 @@ func (hm *HubMetrics) Reset() {
 	hm.closableConnsCollector.Reset()
 	hm.proxyTrafficCollector.Reset()
 	hm.proxyLatencyCollector.Reset()
+	hm.interceptorRequestsCounter.Reset()
+	hm.interceptorLatencyCollector.Reset()
+	hm.cacheHitsCounter.Reset()
+	hm.cacheMissesCounter.Reset()
+	hm.cacheStaleServesCounter.Reset()
+	hm.cacheWrittenBytesCounter.Reset()
+	hm.cachedObjectsCollector.Reset()
+	hm.replayedWritesRejectedCounter.Reset()
 }
 
 func (hm *HubMetrics) ObserveServerHealthy(server string, status int) {
@@ -219,3 +338,54 @@ func (hm *HubMetrics) AddProxyTrafficCollector(client, verb, resource, subresour
 func (hm *HubMetrics) SetProxyLatencyCollector(client, verb, resource, subresource string, latencyType LatencyType, duration int64) {
 	hm.proxyLatencyCollector.WithLabelValues(client, verb, resource, subresource, string(latencyType)).Set(float64(duration))
 }
+
+func (hm *HubMetrics) ObserveInterceptor(interceptor, result string, duration int64) {
+	hm.interceptorRequestsCounter.WithLabelValues(interceptor, result).Inc()
+	hm.interceptorLatencyCollector.WithLabelValues(interceptor).Set(float64(duration))
+}
+
+func (hm *HubMetrics) ObserveClockOffset(source string, offset float64) {
+	hm.clockOffsetCollector.WithLabelValues(source).Set(offset)
+}
+
+func (hm *HubMetrics) ObserveTimeSyncStatus(status int32) {
+	hm.timeSyncStatusCollector.WithLabelValues().Set(float64(status))
+}
+
+func (hm *HubMetrics) IncCacheHits(client, verb, resource string) {
+	hm.cacheHitsCounter.WithLabelValues(client, verb, resource).Inc()
+}
+
+func (hm *HubMetrics) IncCacheMisses(client, verb, resource string) {
+	hm.cacheMissesCounter.WithLabelValues(client, verb, resource).Inc()
+}
+
+func (hm *HubMetrics) IncCacheStaleServes(client, verb, resource string) {
+	hm.cacheStaleServesCounter.WithLabelValues(client, verb, resource).Inc()
+}
+
+func (hm *HubMetrics) AddCacheWrittenBytes(storage string, size int) {
+	if size > 0 {
+		hm.cacheWrittenBytesCounter.WithLabelValues(storage).Add(float64(size))
+	}
+}
+
+func (hm *HubMetrics) SetCachedObjects(client, resource string, cnt int) {
+	hm.cachedObjectsCollector.WithLabelValues(client, resource).Set(float64(cnt))
+}
+
+func (hm *HubMetrics) IncCachedObjects(client, resource string) {
+	hm.cachedObjectsCollector.WithLabelValues(client, resource).Inc()
+}
+
+func (hm *HubMetrics) DecCachedObjects(client, resource string) {
+	hm.cachedObjectsCollector.WithLabelValues(client, resource).Dec()
+}
+
+func (hm *HubMetrics) DeleteCachedObjects(client string) {
+	hm.cachedObjectsCollector.DeletePartialMatch(prometheus.Labels{"client": client})
+}
+
+func (hm *HubMetrics) IncReplayedWritesRejected(resource string, webhooksDeferred bool) {
+	hm.replayedWritesRejectedCounter.WithLabelValues(resource, strconv.FormatBool(webhooksDeferred)).Inc()
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// redirectAddr is an ipv4 address and port in the layout of bpf map, the port is stored the same
+// as user_port field of bpf_sock_addr: in network byte order and widened to 4 bytes.
+type redirectAddr struct {
+	ip   [4]byte
+	port uint16
+}
+
+func (a redirectAddr) bytes() [8]byte {
+	var b [8]byte
+	copy(b[:4], a.ip[:])
+	b[4], b[5] = byte(a.port>>8), byte(a.port)
+	return b
+}
+
+func (a redirectAddr) String() string {
+	return net.JoinHostPort(net.IP(a.ip[:]).String(), strconv.Itoa(int(a.port)))
+}
+
+// redirectProgram is the cgroup/connect4 bpf program attached to cgroup, the connections to
+// the keys of its map are redirected to the values.
+type redirectProgram interface {
+	Update(addr, target redirectAddr) error
+	Delete(addr redirectAddr) error
+	Close() error
+}
+
+// BPFRedirectManager redirects the tcp connections to kube-apiserver on the node to hub agent by a
+// cgroup/connect4 bpf program, so the clients which connect to kube-apiserver directly are served
+// by hub agent transparently. Unlike iptables rules, the program never conflicts with other managers
+// of iptables(kube-proxy, firewalld and cni plugins) and is not removed when the rules are flushed.
+// The connections of hub agent itself are not redirected, and the program is detached automatically
+// when hub agent exits, so the clients connect to kube-apiserver directly when hub agent is not running.
+type BPFRedirectManager struct {
+	cgroupPath string
+	servers    []*url.URL
+	targetIP   net.IP
+	httpPort   uint16
+	httpsPort  uint16
+	lookupIP   func(host string) ([]net.IP, error)
+	newProgram func(cgroupPath string) (redirectProgram, error)
+	program    redirectProgram
+	redirects  map[redirectAddr]redirectAddr
+}
+
+// NewBPFRedirectManager creates a BPFRedirectManager which redirects the connections to servers to
+// targetIP, the connections of https servers are redirected to httpsPort and the others to httpPort.
+// The connections of https servers are not redirected if httpsPort is 0.
+func NewBPFRedirectManager(cgroupPath string, servers []string, targetIP string, httpPort, httpsPort int) *BPFRedirectManager {
+	m := &BPFRedirectManager{
+		cgroupPath: cgroupPath,
+		targetIP:   net.ParseIP(targetIP).To4(),
+		httpPort:   uint16(httpPort),
+		httpsPort:  uint16(httpsPort),
+		lookupIP: func(host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(context.Background(), "ip4", host)
+		},
+		newProgram: newRedirectProgram,
+		redirects:  make(map[redirectAddr]redirectAddr),
+	}
+	for _, server := range servers {
+		server = strings.TrimSpace(server)
+		if len(server) == 0 {
+			continue
+		}
+		u, err := url.Parse(server)
+		if err != nil {
+			klog.Errorf("server %s is invalid, connections to it are not redirected, %v", server, err)
+			continue
+		}
+		m.servers = append(m.servers, u)
+	}
+	return m
+}
+
+// EnsureRules attaches the program if it's not attached, and updates the redirected addresses
+// of servers, the addresses of servers with domain name are resolved every time.
+func (m *BPFRedirectManager) EnsureRules() error {
+	if m.program == nil {
+		program, err := m.newProgram(m.cgroupPath)
+		if err != nil {
+			return fmt.Errorf("failed to attach redirecting bpf program to cgroup %s, %w", m.cgroupPath, err)
+		}
+		klog.Infof("redirecting bpf program is attached to cgroup %s", m.cgroupPath)
+		m.program = program
+	}
+
+	redirects, err := m.desiredRedirects()
+	if err != nil {
+		klog.Warningf("failed to resolve all servers for redirecting, %v", err)
+	}
+	var errs []error
+	for addr, target := range redirects {
+		if current, ok := m.redirects[addr]; ok && current == target {
+			continue
+		}
+		if err := m.program.Update(addr, target); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		klog.Infof("connections to %s are redirected to %s", addr, target)
+		m.redirects[addr] = target
+	}
+	// the stale addresses are kept if the servers can't be resolved
+	if err == nil {
+		for addr := range m.redirects {
+			if _, ok := redirects[addr]; ok {
+				continue
+			}
+			if err := m.program.Delete(addr); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			klog.Infof("connections to %s are not redirected any more", addr)
+			delete(m.redirects, addr)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (m *BPFRedirectManager) desiredRedirects() (map[redirectAddr]redirectAddr, error) {
+	redirects := make(map[redirectAddr]redirectAddr)
+	var errs []error
+	for _, u := range m.servers {
+		target := redirectAddr{port: m.httpPort}
+		port := 80
+		if u.Scheme == "https" {
+			if m.httpsPort == 0 {
+				continue
+			}
+			target.port, port = m.httpsPort, 443
+		}
+		copy(target.ip[:], m.targetIP)
+
+		if p := u.Port(); len(p) != 0 {
+			var err error
+			if port, err = strconv.Atoi(p); err != nil {
+				errs = append(errs, fmt.Errorf("port of server %s is invalid, %w", u.Host, err))
+				continue
+			}
+		}
+		ips := []net.IP{net.ParseIP(u.Hostname())}
+		if ips[0] == nil {
+			var err error
+			if ips, err = m.lookupIP(u.Hostname()); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		for _, ip := range ips {
+			if ip = ip.To4(); ip == nil {
+				continue
+			}
+			addr := redirectAddr{port: uint16(port)}
+			copy(addr.ip[:], ip)
+			redirects[addr] = target
+		}
+	}
+	return redirects, utilerrors.NewAggregate(errs)
+}
+
+// CleanUpRules detaches the program, so the connections are not redirected any more.
+func (m *BPFRedirectManager) CleanUpRules() error {
+	if m.program == nil {
+		return nil
+	}
+	err := m.program.Close()
+	m.program = nil
+	m.redirects = make(map[redirectAddr]redirectAddr)
+	return err
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	bpfMapTypeHash       = 1
+	bpfFuncMapLookupElem = 1
+	bpfFuncCgroupID      = 80
+	bpfPseudoMapFD       = 1
+	bpfMaxRedirects      = 64
+	bpfLogSize           = 64 * 1024
+
+	// offsets of fields in struct bpf_sock_addr
+	sockAddrUserIP4  = 4
+	sockAddrUserPort = 24
+	sockAddrType     = 32
+
+	// selfCgroupRoot is the cgroup v2 mount of hub agent's own cgroup namespace, the cgroup path in
+	// /proc/self/cgroup is relative to it.
+	selfCgroupRoot = "/sys/fs/cgroup"
+)
+
+type bpfInsn struct {
+	code uint8
+	regs uint8
+	off  int16
+	imm  int32
+}
+
+func insn(code, dst, src uint8, off int16, imm int32) bpfInsn {
+	return bpfInsn{code: code, regs: src<<4 | dst, off: off, imm: imm}
+}
+
+// redirectInstructions returns the program which looks up the destination of tcp connections in the
+// map, and rewrites the destination to the value if it's found. the connections of the cgroup of hub
+// agent are skipped, so hub agent still connects to kube-apiserver.
+func redirectInstructions(mapFD int, hubCgroupID uint64) []bpfInsn {
+	const (
+		r0, r1, r2, r6, r10 = 0, 1, 2, 6, 10
+		out                 = 21
+	)
+	return []bpfInsn{
+		/* 0 */ insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, r6, r1, 0, 0),
+		/* 1 */ insn(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, r2, r6, sockAddrType, 0),
+		/* 2 */ insn(unix.BPF_JMP|unix.BPF_JNE|unix.BPF_K, r2, 0, out-3, unix.SOCK_STREAM),
+		/* 3 */ insn(unix.BPF_JMP|unix.BPF_CALL, 0, 0, 0, bpfFuncCgroupID),
+		/* 4 */ insn(unix.BPF_LD|unix.BPF_IMM|unix.BPF_DW, r1, 0, 0, int32(uint32(hubCgroupID))),
+		/* 5 */ insn(0, 0, 0, 0, int32(uint32(hubCgroupID>>32))),
+		/* 6 */ insn(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_X, r0, r1, out-7, 0),
+		/* 7 */ insn(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, r2, r6, sockAddrUserIP4, 0),
+		/* 8 */ insn(unix.BPF_STX|unix.BPF_MEM|unix.BPF_W, r10, r2, -8, 0),
+		/* 9 */ insn(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, r2, r6, sockAddrUserPort, 0),
+		/* 10 */ insn(unix.BPF_STX|unix.BPF_MEM|unix.BPF_W, r10, r2, -4, 0),
+		/* 11 */ insn(unix.BPF_LD|unix.BPF_IMM|unix.BPF_DW, r1, bpfPseudoMapFD, 0, int32(mapFD)),
+		/* 12 */ insn(0, 0, 0, 0, 0),
+		/* 13 */ insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_X, r2, r10, 0, 0),
+		/* 14 */ insn(unix.BPF_ALU64|unix.BPF_ADD|unix.BPF_K, r2, 0, 0, -8),
+		/* 15 */ insn(unix.BPF_JMP|unix.BPF_CALL, 0, 0, 0, bpfFuncMapLookupElem),
+		/* 16 */ insn(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, r0, 0, out-17, 0),
+		/* 17 */ insn(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, r2, r0, 0, 0),
+		/* 18 */ insn(unix.BPF_STX|unix.BPF_MEM|unix.BPF_W, r6, r2, sockAddrUserIP4, 0),
+		/* 19 */ insn(unix.BPF_LDX|unix.BPF_MEM|unix.BPF_W, r2, r0, 4, 0),
+		/* 20 */ insn(unix.BPF_STX|unix.BPF_MEM|unix.BPF_W, r6, r2, sockAddrUserPort, 0),
+		// the connection is always allowed
+		/* 21 */ insn(unix.BPF_ALU64|unix.BPF_MOV|unix.BPF_K, r0, 0, 0, 1),
+		/* 22 */ insn(unix.BPF_JMP|unix.BPF_EXIT, 0, 0, 0, 0),
+	}
+}
+
+type bpfMapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+}
+
+type bpfMapElemAttr struct {
+	mapFD uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+type bpfProgLoadAttr struct {
+	progType           uint32
+	insnCnt            uint32
+	insns              uint64
+	license            uint64
+	logLevel           uint32
+	logSize            uint32
+	logBuf             uint64
+	kernVersion        uint32
+	progFlags          uint32
+	progName           [16]byte
+	progIfindex        uint32
+	expectedAttachType uint32
+}
+
+type bpfLinkCreateAttr struct {
+	progFD     uint32
+	targetFD   uint32
+	attachType uint32
+	flags      uint32
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// cgroupProgram is the redirecting program attached to cgroup by bpf link, the program is detached
+// when the link is closed, including when the process exits.
+type cgroupProgram struct {
+	mapFD  int
+	progFD int
+	linkFD int
+}
+
+func newRedirectProgram(cgroupPath string) (redirectProgram, error) {
+	if err := ensureRootCgroup(cgroupPath); err != nil {
+		return nil, err
+	}
+	hubCgroupID, err := currentCgroupID(selfCgroupRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cgroup of hub agent, %w", err)
+	}
+	p := &cgroupProgram{mapFD: -1, progFD: -1, linkFD: -1}
+	if err := p.load(cgroupPath, hubCgroupID); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *cgroupProgram) load(cgroupPath string, hubCgroupID uint64) error {
+	var err error
+	mapAttr := bpfMapCreateAttr{mapType: bpfMapTypeHash, keySize: 8, valueSize: 8, maxEntries: bpfMaxRedirects}
+	if p.mapFD, err = bpf(unix.BPF_MAP_CREATE, unsafe.Pointer(&mapAttr), unsafe.Sizeof(mapAttr)); err != nil {
+		return fmt.Errorf("failed to create bpf map, %w", err)
+	}
+
+	insns := redirectInstructions(p.mapFD, hubCgroupID)
+	license := []byte("Apache-2.0\x00")
+	log := make([]byte, bpfLogSize)
+	progAttr := bpfProgLoadAttr{
+		progType:           unix.BPF_PROG_TYPE_CGROUP_SOCK_ADDR,
+		insnCnt:            uint32(len(insns)),
+		insns:              uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:            uint64(uintptr(unsafe.Pointer(&license[0]))),
+		logLevel:           1,
+		logSize:            uint32(len(log)),
+		logBuf:             uint64(uintptr(unsafe.Pointer(&log[0]))),
+		expectedAttachType: unix.BPF_CGROUP_INET4_CONNECT,
+	}
+	copy(progAttr.progName[:], "yurthub_connect")
+	p.progFD, err = bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&progAttr), unsafe.Sizeof(progAttr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	if err != nil {
+		if n := strings.IndexByte(string(log), 0); n > 0 {
+			return fmt.Errorf("failed to load bpf program, %w, verifier log: %s", err, strings.TrimSpace(string(log[:n])))
+		}
+		return fmt.Errorf("failed to load bpf program, %w", err)
+	}
+
+	cgroup, err := os.Open(cgroupPath)
+	if err != nil {
+		return err
+	}
+	defer cgroup.Close()
+	linkAttr := bpfLinkCreateAttr{progFD: uint32(p.progFD), targetFD: uint32(cgroup.Fd()), attachType: unix.BPF_CGROUP_INET4_CONNECT}
+	if p.linkFD, err = bpf(unix.BPF_LINK_CREATE, unsafe.Pointer(&linkAttr), unsafe.Sizeof(linkAttr)); err != nil {
+		return fmt.Errorf("failed to attach bpf program, %w", err)
+	}
+	return nil
+}
+
+func (p *cgroupProgram) Update(addr, target redirectAddr) error {
+	key, value := addr.bytes(), target.bytes()
+	attr := bpfMapElemAttr{
+		mapFD: uint32(p.mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+		value: uint64(uintptr(unsafe.Pointer(&value[0]))),
+	}
+	_, err := bpf(unix.BPF_MAP_UPDATE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	runtime.KeepAlive(&value)
+	if err != nil {
+		return fmt.Errorf("failed to redirect %s in bpf map, %w", addr, err)
+	}
+	return nil
+}
+
+func (p *cgroupProgram) Delete(addr redirectAddr) error {
+	key := addr.bytes()
+	attr := bpfMapElemAttr{
+		mapFD: uint32(p.mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+	}
+	_, err := bpf(unix.BPF_MAP_DELETE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	if err != nil && err != syscall.ENOENT {
+		return fmt.Errorf("failed to delete %s from bpf map, %w", addr, err)
+	}
+	return nil
+}
+
+func (p *cgroupProgram) Close() error {
+	for _, fd := range []*int{&p.linkFD, &p.progFD, &p.mapFD} {
+		if *fd >= 0 {
+			unix.Close(*fd)
+			*fd = -1
+		}
+	}
+	return nil
+}
+
+// ensureRootCgroup checks cgroupPath is the root of the host cgroup v2, rather than the cgroup
+// namespace view of a pod, whose connections are only a part of the connections on the node.
+// cgroup.events exists in every cgroup except the root one.
+func ensureRootCgroup(cgroupPath string) error {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(cgroupPath, &fs); err != nil {
+		return fmt.Errorf("failed to stat cgroup %s, %w", cgroupPath, err)
+	}
+	if fs.Type != unix.CGROUP2_SUPER_MAGIC {
+		return fmt.Errorf("%s is not a cgroup v2 mount", cgroupPath)
+	}
+	if _, err := os.Stat(filepath.Join(cgroupPath, "cgroup.events")); err == nil {
+		return fmt.Errorf("%s is not the root of host cgroup v2, mount the host cgroup v2 and set --bpf-cgroup-path to it", cgroupPath)
+	}
+	return nil
+}
+
+// currentCgroupID returns the id of cgroup v2 which the current process belongs to, it's the inode
+// number of the cgroup directory under the cgroup v2 mounted at cgroupRoot. The inode number is the
+// same in all mounts of cgroup v2, so it can be compared with the cgroup ids of the host.
+func currentCgroupID(cgroupRoot string) (uint64, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// the line of cgroup v2 is in format 0::/path
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "0::") {
+			continue
+		}
+		path := strings.TrimPrefix(line, "0::")
+		var st unix.Stat_t
+		if err := unix.Stat(filepath.Join(cgroupRoot, path), &st); err != nil {
+			return 0, err
+		}
+		return st.Ino, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("cgroup v2 is not found in /proc/self/cgroup")
+}
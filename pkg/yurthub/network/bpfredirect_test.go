@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+type fakeRedirectProgram struct {
+	redirects map[string]string
+	closed    bool
+}
+
+func (p *fakeRedirectProgram) Update(addr, target redirectAddr) error {
+	p.redirects[addr.String()] = target.String()
+	return nil
+}
+
+func (p *fakeRedirectProgram) Delete(addr redirectAddr) error {
+	delete(p.redirects, addr.String())
+	return nil
+}
+
+func (p *fakeRedirectProgram) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestRedirectAddrBytes(t *testing.T) {
+	addr := redirectAddr{ip: [4]byte{10, 0, 0, 1}, port: 6443}
+	if got, want := addr.bytes(), [8]byte{10, 0, 0, 1, 0x19, 0x2b, 0, 0}; got != want {
+		t.Errorf("expect bytes %v, but got %v", want, got)
+	}
+}
+
+func TestBPFRedirectManager(t *testing.T) {
+	program := &fakeRedirectProgram{redirects: make(map[string]string)}
+	resolved := map[string][]net.IP{"apiserver.local": {net.ParseIP("10.0.0.2"), net.ParseIP("fd00::2")}}
+	var lookupErr error
+	m := NewBPFRedirectManager("/sys/fs/cgroup", []string{"https://10.0.0.1:6443", " https://apiserver.local", "http://10.0.0.3:8080", "fd00::1"}, "169.254.2.1", 10261, 10268)
+	m.lookupIP = func(host string) ([]net.IP, error) {
+		return resolved[host], lookupErr
+	}
+	m.newProgram = func(cgroupPath string) (redirectProgram, error) {
+		return program, nil
+	}
+
+	if err := m.EnsureRules(); err != nil {
+		t.Fatalf("failed to ensure rules, %v", err)
+	}
+	expected := map[string]string{
+		"10.0.0.1:6443": "169.254.2.1:10268",
+		"10.0.0.2:443":  "169.254.2.1:10268",
+		"10.0.0.3:8080": "169.254.2.1:10261",
+	}
+	if !reflect.DeepEqual(program.redirects, expected) {
+		t.Errorf("expect redirects %v, but got %v", expected, program.redirects)
+	}
+
+	// the stale addresses are kept if servers can't be resolved
+	resolved["apiserver.local"] = nil
+	lookupErr = errors.New("no such host")
+	m.EnsureRules()
+	if !reflect.DeepEqual(program.redirects, expected) {
+		t.Errorf("expect redirects %v, but got %v", expected, program.redirects)
+	}
+
+	resolved["apiserver.local"] = []net.IP{net.ParseIP("10.0.0.4")}
+	lookupErr = nil
+	if err := m.EnsureRules(); err != nil {
+		t.Fatalf("failed to ensure rules, %v", err)
+	}
+	delete(expected, "10.0.0.2:443")
+	expected["10.0.0.4:443"] = "169.254.2.1:10268"
+	if !reflect.DeepEqual(program.redirects, expected) {
+		t.Errorf("expect redirects %v, but got %v", expected, program.redirects)
+	}
+
+	if err := m.CleanUpRules(); err != nil || !program.closed {
+		t.Errorf("expect program is closed, but got %v", err)
+	}
+}
+
+func TestBPFRedirectManagerWithoutHTTPS(t *testing.T) {
+	program := &fakeRedirectProgram{redirects: make(map[string]string)}
+	m := NewBPFRedirectManager("/sys/fs/cgroup", []string{"https://10.0.0.1:6443", "http://10.0.0.3:8080"}, "169.254.2.1", 10261, 0)
+	m.newProgram = func(cgroupPath string) (redirectProgram, error) {
+		return program, nil
+	}
+
+	if err := m.EnsureRules(); err != nil {
+		t.Fatalf("failed to ensure rules, %v", err)
+	}
+	expected := map[string]string{
+		"10.0.0.3:8080": "169.254.2.1:10261",
+	}
+	if !reflect.DeepEqual(program.redirects, expected) {
+		t.Errorf("expect redirects %v, but got %v", expected, program.redirects)
+	}
+}
@@ -0,0 +1,673 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	dnsPort = 53
+	// dnsTimeout is the timeout for exchanging a query with the upstream dns server
+	dnsTimeout = 2 * time.Second
+	// tcpIdleTimeout is the timeout for reading the next query of a tcp connection
+	tcpIdleTimeout = 10 * time.Second
+	// maxCacheTTL caps the ttl of cached answers, so the answers are refreshed from the upstream in time
+	maxCacheTTL = 5 * time.Minute
+	// maxNegativeCacheTTL caps the ttl of cached NXDOMAIN and NODATA answers, so new names are
+	// resolvable soon after created
+	maxNegativeCacheTTL = time.Minute
+	// upstreamRetryInterval is the interval during which the upstream is not queried after it is
+	// unreachable, so queries are answered from cache without waiting for dnsTimeout
+	upstreamRetryInterval = 10 * time.Second
+	// staleTTL is the ttl of answers which are served from expired cache entries or cached services
+	staleTTL = 30
+	// maxDNSCacheEntries is the maximum number of cached questions
+	maxDNSCacheEntries    = 10000
+	defaultUDPPayloadSize = 512
+	maxDNSMessageSize     = 65535
+
+	kubeDNSNamespace   = "kube-system"
+	kubeDNSServiceName = "kube-dns"
+)
+
+type dnsKey struct {
+	name  string
+	qtype dnsmessage.Type
+	class dnsmessage.Class
+}
+
+type dnsEntry struct {
+	msg     dnsmessage.Message
+	expires time.Time
+}
+
+// negative returns true if the entry is a cached NXDOMAIN or NODATA answer.
+func (e *dnsEntry) negative() bool {
+	return e.msg.Header.RCode != dnsmessage.RCodeSuccess || len(e.msg.Answers) == 0
+}
+
+// DNSCache is a node-local dns cache listening on the dummy interface. The queries are forwarded
+// to the cloud dns server and the answers are cached. When the cloud dns server is unreachable,
+// the cached answers are served even if expired, and the names of services in cluster domain are
+// resolved by the services and endpointslices cached by yurthub, so pods on the edge can resolve
+// names during the network outage. The expired answers are served at once and refreshed from
+// the cloud dns server in background, so clients never wait for an unreachable upstream.
+type DNSCache struct {
+	listenAddrs         []string
+	upstream            string
+	clusterDomain       string
+	serviceLister       corelisters.ServiceLister
+	endpointSliceLister discoverylisters.EndpointSliceLister
+	exchange            func(network, server string, req []byte) ([]byte, error)
+	now                 func() time.Time
+	async               func(f func())
+	sync.RWMutex
+	entries map[dnsKey]*dnsEntry
+	// refreshing is the set of expired entries which are being refreshed from upstream
+	refreshing map[dnsKey]bool
+	// upstreamDownUntil is the time before which the upstream is not queried for missed names
+	upstreamDownUntil time.Time
+}
+
+// NewDNSCache creates a DNSCache listening on port 53 of listenIPs. The cluster ip of
+// kube-system/kube-dns service is used as the upstream if upstream is empty.
+func NewDNSCache(listenIPs []string, upstream, clusterDomain string, serviceLister corelisters.ServiceLister,
+	endpointSliceLister discoverylisters.EndpointSliceLister) *DNSCache {
+	if net.ParseIP(upstream) != nil {
+		upstream = net.JoinHostPort(upstream, strconv.Itoa(dnsPort))
+	}
+	var listenAddrs []string
+	for _, ip := range listenIPs {
+		listenAddrs = append(listenAddrs, net.JoinHostPort(ip, strconv.Itoa(dnsPort)))
+	}
+	return &DNSCache{
+		listenAddrs:         listenAddrs,
+		upstream:            upstream,
+		clusterDomain:       strings.ToLower(strings.Trim(clusterDomain, ".")),
+		serviceLister:       serviceLister,
+		endpointSliceLister: endpointSliceLister,
+		exchange:            exchange,
+		now:                 time.Now,
+		async:               func(f func()) { go f() },
+		entries:             make(map[dnsKey]*dnsEntry),
+		refreshing:          make(map[dnsKey]bool),
+	}
+}
+
+// Run serves dns queries over udp and tcp until stopCh is closed, the servers are restarted
+// if they exit unexpectedly, e.g. the dummy interface ip is removed.
+func (c *DNSCache) Run(stopCh <-chan struct{}) {
+	for _, addr := range c.listenAddrs {
+		addr := addr
+		klog.Infof("start dns cache at %s", addr)
+		go wait.Until(func() { c.serveUDP(addr, stopCh) }, 5*time.Second, stopCh)
+		go wait.Until(func() { c.serveTCP(addr, stopCh) }, 5*time.Second, stopCh)
+	}
+}
+
+func (c *DNSCache) serveUDP(listenAddr string, stopCh <-chan struct{}) {
+	conn, err := net.ListenPacket("udp", listenAddr)
+	if err != nil {
+		klog.Errorf("failed to listen udp %s for dns cache, %v", listenAddr, err)
+		return
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stopCh:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxDNSMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-stopCh:
+			default:
+				klog.Errorf("failed to read dns query from udp %s, %v", listenAddr, err)
+			}
+			return
+		}
+		req := make([]byte, n)
+		copy(req, buf[:n])
+		go func() {
+			if resp := c.resolve("udp", req); resp != nil {
+				if _, err := conn.WriteTo(resp, addr); err != nil {
+					klog.V(4).Infof("failed to write dns response to %s, %v", addr, err)
+				}
+			}
+		}()
+	}
+}
+
+func (c *DNSCache) serveTCP(listenAddr string, stopCh <-chan struct{}) {
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		klog.Errorf("failed to listen tcp %s for dns cache, %v", listenAddr, err)
+		return
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stopCh:
+		case <-done:
+		}
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-stopCh:
+			default:
+				klog.Errorf("failed to accept dns connection from tcp %s, %v", listenAddr, err)
+			}
+			return
+		}
+		go c.handleTCPConn(conn)
+	}
+}
+
+func (c *DNSCache) handleTCPConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		req, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+		resp := c.resolve("tcp", req)
+		if resp == nil {
+			return
+		}
+		conn.SetWriteDeadline(time.Now().Add(dnsTimeout))
+		if err := writeTCPMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// resolve returns the response of req, or nil if req is not a valid query.
+func (c *DNSCache) resolve(network string, req []byte) []byte {
+	var query dnsmessage.Message
+	if err := query.Unpack(req); err != nil || query.Header.Response || len(query.Questions) == 0 {
+		return nil
+	}
+	q := query.Questions[0]
+	key := dnsKey{name: strings.ToLower(q.Name.String()), qtype: q.Type, class: q.Class}
+
+	now := c.now()
+	if entry := c.get(key); entry != nil {
+		if now.Before(entry.expires) {
+			return c.pack(network, &query, c.cachedReply(&query, entry, now))
+		}
+		// the expired entry is served without waiting for upstream, and refreshed in background
+		c.refresh(network, key, &query, req)
+		if entry.negative() {
+			// the name may be created during the network outage
+			if reply := c.serviceReply(&query); reply.Header.RCode == dnsmessage.RCodeSuccess {
+				return c.pack(network, &query, reply)
+			}
+		}
+		return c.pack(network, &query, c.cachedReply(&query, entry, now))
+	}
+
+	if c.upstreamDown(now) {
+		return c.pack(network, &query, c.serviceReply(&query))
+	}
+	resp, msg, err := c.query(network, &query, req)
+	if err != nil {
+		klog.V(4).Infof("failed to resolve %s %s by upstream, %v", q.Type, q.Name, err)
+		return c.pack(network, &query, c.serviceReply(&query))
+	}
+	c.store(key, msg, now)
+	return resp
+}
+
+// query exchanges req with the upstream, the upstream is marked as down for upstreamRetryInterval
+// if it's unreachable.
+func (c *DNSCache) query(network string, query *dnsmessage.Message, req []byte) ([]byte, *dnsmessage.Message, error) {
+	server, err := c.upstreamAddr()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.exchange(network, server, req)
+	if err != nil {
+		c.Lock()
+		c.upstreamDownUntil = c.now().Add(upstreamRetryInterval)
+		c.Unlock()
+		return nil, nil, err
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		return nil, nil, err
+	}
+	if msg.Header.ID != query.Header.ID {
+		return nil, nil, fmt.Errorf("response id %d doesn't match query id %d", msg.Header.ID, query.Header.ID)
+	}
+	c.Lock()
+	c.upstreamDownUntil = time.Time{}
+	c.Unlock()
+	return resp, &msg, nil
+}
+
+func (c *DNSCache) upstreamDown(now time.Time) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return now.Before(c.upstreamDownUntil)
+}
+
+// refresh updates the expired entry of key from upstream in background, the entry is kept if
+// the upstream is unreachable or fails to resolve.
+func (c *DNSCache) refresh(network string, key dnsKey, query *dnsmessage.Message, req []byte) {
+	c.Lock()
+	if c.refreshing[key] || c.now().Before(c.upstreamDownUntil) {
+		c.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.Unlock()
+
+	c.async(func() {
+		defer func() {
+			c.Lock()
+			delete(c.refreshing, key)
+			c.Unlock()
+		}()
+		_, msg, err := c.query(network, query, req)
+		if err != nil {
+			klog.V(4).Infof("failed to refresh %s %s from upstream, %v", key.qtype, key.name, err)
+			return
+		}
+		c.store(key, msg, c.now())
+	})
+}
+
+func (c *DNSCache) upstreamAddr() (string, error) {
+	if len(c.upstream) != 0 {
+		return c.upstream, nil
+	}
+	if c.serviceLister == nil {
+		return "", fmt.Errorf("dns cache upstream is not specified")
+	}
+	svc, err := c.serviceLister.Services(kubeDNSNamespace).Get(kubeDNSServiceName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get service %s/%s, %w", kubeDNSNamespace, kubeDNSServiceName, err)
+	}
+	if net.ParseIP(svc.Spec.ClusterIP) == nil {
+		return "", fmt.Errorf("service %s/%s has no cluster ip", kubeDNSNamespace, kubeDNSServiceName)
+	}
+	return net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(dnsPort)), nil
+}
+
+func (c *DNSCache) get(key dnsKey) *dnsEntry {
+	c.RLock()
+	defer c.RUnlock()
+	return c.entries[key]
+}
+
+// store caches the successful and negative answers of upstream, the answers are kept after expired
+// for serving when the upstream is unreachable.
+func (c *DNSCache) store(key dnsKey, msg *dnsmessage.Message, now time.Time) {
+	ttl, ok := cacheTTLOf(msg)
+	if !ok {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxDNSCacheEntries {
+		// drop the expired entries to make room for the new one
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxDNSCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = &dnsEntry{msg: *msg, expires: now.Add(ttl)}
+}
+
+// cacheTTLOf returns the ttl for caching msg. The positive answers are cached by the minimum ttl
+// of answers, and the NXDOMAIN and NODATA answers are cached by the SOA record in authority
+// section like RFC 2308, the negative answers without SOA record are not cached.
+func cacheTTLOf(msg *dnsmessage.Message) (time.Duration, bool) {
+	if msg.Header.Truncated {
+		return 0, false
+	}
+	switch {
+	case msg.Header.RCode == dnsmessage.RCodeSuccess && len(msg.Answers) != 0:
+		ttl := maxCacheTTL
+		for _, rr := range msg.Answers {
+			if d := time.Duration(rr.Header.TTL) * time.Second; d < ttl {
+				ttl = d
+			}
+		}
+		return ttl, true
+	case msg.Header.RCode == dnsmessage.RCodeSuccess || msg.Header.RCode == dnsmessage.RCodeNameError:
+		for _, rr := range msg.Authorities {
+			soa, ok := rr.Body.(*dnsmessage.SOAResource)
+			if !ok {
+				continue
+			}
+			ttl := rr.Header.TTL
+			if soa.MinTTL < ttl {
+				ttl = soa.MinTTL
+			}
+			if d := time.Duration(ttl) * time.Second; d < maxNegativeCacheTTL {
+				return d, true
+			}
+			return maxNegativeCacheTTL, true
+		}
+	}
+	return 0, false
+}
+
+// cachedReply makes up the reply of query from the cached entry, the ttl of answers is the
+// remaining ttl of entry, or staleTTL if expired.
+func (c *DNSCache) cachedReply(query *dnsmessage.Message, entry *dnsEntry, now time.Time) *dnsmessage.Message {
+	ttl := uint32(staleTTL)
+	if remaining := entry.expires.Sub(now); remaining > 0 {
+		ttl = uint32(remaining / time.Second)
+	}
+	msg := &dnsmessage.Message{
+		Header:      entry.msg.Header,
+		Questions:   query.Questions,
+		Answers:     withTTL(entry.msg.Answers, ttl),
+		Authorities: withTTL(entry.msg.Authorities, ttl),
+	}
+	for _, rr := range withTTL(entry.msg.Additionals, ttl) {
+		// the edns options are negotiated with the client of query
+		if rr.Header.Type != dnsmessage.TypeOPT {
+			msg.Additionals = append(msg.Additionals, rr)
+		}
+	}
+	msg.Header.ID = query.Header.ID
+	msg.Header.RecursionDesired = query.Header.RecursionDesired
+	return msg
+}
+
+func withTTL(rrs []dnsmessage.Resource, ttl uint32) []dnsmessage.Resource {
+	if len(rrs) == 0 {
+		return nil
+	}
+	result := make([]dnsmessage.Resource, len(rrs))
+	for i := range rrs {
+		result[i] = rrs[i]
+		result[i].Header.TTL = ttl
+	}
+	return result
+}
+
+// serviceReply resolves the query of <service>.<namespace>.svc.<cluster domain> by the cached
+// services like the cluster dns: the cluster ips of service, the ready endpoints of headless
+// service, and the CNAME of ExternalName service. <hostname>.<service>.<namespace>.svc.<cluster domain>
+// is resolved by the endpoint with the hostname(e.g. statefulset pod) of headless service.
+// The names in cluster domain which can't be resolved are answered with NXDOMAIN, so the
+// clients go on with the next search domain.
+func (c *DNSCache) serviceReply(query *dnsmessage.Message) *dnsmessage.Message {
+	q := query.Questions[0]
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 query.Header.ID,
+			Response:           true,
+			RecursionDesired:   query.Header.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              dnsmessage.RCodeServerFailure,
+		},
+		Questions: query.Questions,
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))
+	if c.serviceLister == nil || q.Class != dnsmessage.ClassINET || !strings.HasSuffix(name, "."+c.clusterDomain) {
+		return msg
+	}
+	msg.Header.RCode = dnsmessage.RCodeNameError
+	parts := strings.Split(strings.TrimSuffix(name, "."+c.clusterDomain), ".")
+	var hostname string
+	if len(parts) == 4 {
+		hostname, parts = parts[0], parts[1:]
+	}
+	if len(parts) != 3 || parts[2] != "svc" {
+		return msg
+	}
+	svc, err := c.serviceLister.Services(parts[1]).Get(parts[0])
+	if err != nil {
+		return msg
+	}
+
+	var ips []net.IP
+	switch {
+	case svc.Spec.Type == corev1.ServiceTypeExternalName:
+		if len(hostname) != 0 {
+			return msg
+		}
+		answers, ok := c.externalNameAnswers(q, svc.Spec.ExternalName)
+		if ok {
+			msg.Header.RCode = dnsmessage.RCodeSuccess
+			msg.Answers = answers
+		}
+		return msg
+	case svc.Spec.ClusterIP == corev1.ClusterIPNone:
+		ips = c.endpointIPsOf(svc, hostname)
+		if len(hostname) != 0 && len(ips) == 0 {
+			return msg
+		}
+	case len(hostname) != 0:
+		// only the endpoints of headless service have dns records
+		return msg
+	default:
+		ips = clusterIPsOf(svc)
+	}
+
+	msg.Header.RCode = dnsmessage.RCodeSuccess
+	for _, ip := range ips {
+		rr := dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: staleTTL},
+		}
+		if ip4 := ip.To4(); ip4 != nil && q.Type == dnsmessage.TypeA {
+			rr.Header.Type = dnsmessage.TypeA
+			body := &dnsmessage.AResource{}
+			copy(body.A[:], ip4)
+			rr.Body = body
+		} else if ip.To4() == nil && q.Type == dnsmessage.TypeAAAA {
+			rr.Header.Type = dnsmessage.TypeAAAA
+			body := &dnsmessage.AAAAResource{}
+			copy(body.AAAA[:], ip.To16())
+			rr.Body = body
+		} else {
+			continue
+		}
+		msg.Answers = append(msg.Answers, rr)
+	}
+	return msg
+}
+
+// externalNameAnswers returns the CNAME of ExternalName service, followed by the cached answers
+// of external name if any, the external name is usually not resolvable when the cloud is unreachable.
+func (c *DNSCache) externalNameAnswers(q dnsmessage.Question, externalName string) ([]dnsmessage.Resource, bool) {
+	target, err := dnsmessage.NewName(strings.ToLower(strings.TrimSuffix(externalName, ".")) + ".")
+	if err != nil {
+		return nil, false
+	}
+	answers := []dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: staleTTL},
+		Body:   &dnsmessage.CNAMEResource{CNAME: target},
+	}}
+	if q.Type == dnsmessage.TypeCNAME {
+		return answers, true
+	}
+	if entry := c.get(dnsKey{name: target.String(), qtype: q.Type, class: q.Class}); entry != nil {
+		answers = append(answers, withTTL(entry.msg.Answers, staleTTL)...)
+	}
+	return answers, true
+}
+
+// endpointIPsOf returns the addresses of ready endpoints of headless service, only the endpoints
+// with the hostname are returned if hostname is specified.
+func (c *DNSCache) endpointIPsOf(svc *corev1.Service, hostname string) []net.IP {
+	if c.endpointSliceLister == nil {
+		return nil
+	}
+	selector := labels.SelectorFromSet(labels.Set{discovery.LabelServiceName: svc.Name})
+	slices, err := c.endpointSliceLister.EndpointSlices(svc.Namespace).List(selector)
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	seen := make(map[string]bool)
+	for _, slice := range slices {
+		if slice.AddressType != discovery.AddressTypeIPv4 && slice.AddressType != discovery.AddressTypeIPv6 {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready && !svc.Spec.PublishNotReadyAddresses {
+				continue
+			}
+			if len(hostname) != 0 && (ep.Hostname == nil || *ep.Hostname != hostname) {
+				continue
+			}
+			for _, addr := range ep.Addresses {
+				if ip := net.ParseIP(addr); ip != nil && !seen[addr] {
+					seen[addr] = true
+					ips = append(ips, ip)
+				}
+			}
+		}
+	}
+	return ips
+}
+
+func clusterIPsOf(svc *corev1.Service) []net.IP {
+	clusterIPs := svc.Spec.ClusterIPs
+	if len(clusterIPs) == 0 {
+		clusterIPs = []string{svc.Spec.ClusterIP}
+	}
+	var ips []net.IP
+	for _, clusterIP := range clusterIPs {
+		if ip := net.ParseIP(clusterIP); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// pack packs msg as the response of query, the response is truncated if it's too large for the
+// udp payload size of query, so the client retries over tcp.
+func (c *DNSCache) pack(network string, query, msg *dnsmessage.Message) []byte {
+	resp, err := msg.Pack()
+	if err != nil {
+		klog.Errorf("failed to pack dns response, %v", err)
+		return nil
+	}
+	if network != "udp" || len(resp) <= udpPayloadSizeOf(query) {
+		return resp
+	}
+	truncated := &dnsmessage.Message{Header: msg.Header, Questions: msg.Questions}
+	truncated.Header.Truncated = true
+	resp, err = truncated.Pack()
+	if err != nil {
+		klog.Errorf("failed to pack truncated dns response, %v", err)
+		return nil
+	}
+	return resp
+}
+
+func udpPayloadSizeOf(query *dnsmessage.Message) int {
+	for _, rr := range query.Additionals {
+		// the class of OPT record is the udp payload size of requestor
+		if rr.Header.Type == dnsmessage.TypeOPT && int(rr.Header.Class) > defaultUDPPayloadSize {
+			return int(rr.Header.Class)
+		}
+	}
+	return defaultUDPPayloadSize
+}
+
+// exchange sends req to the dns server and returns the response.
+func exchange(network, server string, req []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, dnsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsTimeout))
+
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, req); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxDNSMessageSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// readTCPMessage reads a dns message prefixed with its two bytes length.
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
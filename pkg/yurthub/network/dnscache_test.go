@@ -0,0 +1,348 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	corev1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newQuery(t *testing.T, id uint16, name string, qtype dnsmessage.Type) []byte {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	req, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack query, %v", err)
+	}
+	return req
+}
+
+func newAnswer(t *testing.T, req []byte, ip [4]byte, ttl uint32) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(req); err != nil {
+		t.Fatalf("failed to unpack query, %v", err)
+	}
+	msg.Header.Response = true
+	msg.Answers = []dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: ip},
+	}}
+	resp, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack answer, %v", err)
+	}
+	return resp
+}
+
+func newServiceLister(services ...*corev1.Service) corelisters.ServiceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := range services {
+		indexer.Add(services[i])
+	}
+	return corelisters.NewServiceLister(indexer)
+}
+
+func newEndpointSliceLister(slices ...*discovery.EndpointSlice) discoverylisters.EndpointSliceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := range slices {
+		indexer.Add(slices[i])
+	}
+	return discoverylisters.NewEndpointSliceLister(indexer)
+}
+
+func unpackResponse(t *testing.T, resp []byte) *dnsmessage.Message {
+	if resp == nil {
+		t.Fatalf("no response is returned")
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatalf("failed to unpack response, %v", err)
+	}
+	return &msg
+}
+
+func TestDNSCacheResolve(t *testing.T) {
+	lister := newServiceLister(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: kubeDNSNamespace, Name: kubeDNSServiceName},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.10"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.96.0.20", ClusterIPs: []string{"10.96.0.20", "fd00::20"}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "external"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "Example.com"},
+		},
+	)
+	ready, notReady, web0, web1 := true, false, "web-0", "web-1"
+	sliceLister := newEndpointSliceLister(&discovery.EndpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Namespace: "default", Name: "web-abcde", Labels: map[string]string{discovery.LabelServiceName: "web"}},
+		AddressType: discovery.AddressTypeIPv4,
+		Endpoints: []discovery.Endpoint{
+			{Addresses: []string{"10.244.1.10"}, Hostname: &web0, Conditions: discovery.EndpointConditions{Ready: &ready}},
+			{Addresses: []string{"10.244.1.11"}, Hostname: &web1, Conditions: discovery.EndpointConditions{Ready: &notReady}},
+		},
+	})
+	now := time.Now()
+	c := NewDNSCache([]string{"169.254.2.1"}, "", "cluster.local.", lister, sliceLister)
+	c.now = func() time.Time { return now }
+	c.async = func(f func()) { f() }
+
+	upstreamUp := true
+	exchanged := 0
+	c.exchange = func(network, server string, req []byte) ([]byte, error) {
+		if server != "10.96.0.10:53" {
+			t.Errorf("expect upstream 10.96.0.10:53, but got %s", server)
+		}
+		if !upstreamUp {
+			return nil, errors.New("i/o timeout")
+		}
+		exchanged++
+		return newAnswer(t, req, [4]byte{1, 2, 3, 4}, 60), nil
+	}
+
+	// the answer of upstream is cached
+	msg := unpackResponse(t, c.resolve("udp", newQuery(t, 1, "example.com.", dnsmessage.TypeA)))
+	if msg.Header.ID != 1 || len(msg.Answers) != 1 || exchanged != 1 {
+		t.Fatalf("expect the answer of upstream, but got %#v", msg)
+	}
+	msg = unpackResponse(t, c.resolve("udp", newQuery(t, 2, "Example.com.", dnsmessage.TypeA)))
+	if msg.Header.ID != 2 || len(msg.Answers) != 1 || exchanged != 1 {
+		t.Fatalf("expect the cached answer, but got %#v", msg)
+	}
+
+	// the expired answer is served when upstream is unreachable
+	now = now.Add(2 * time.Minute)
+	upstreamUp = false
+	msg = unpackResponse(t, c.resolve("udp", newQuery(t, 3, "example.com.", dnsmessage.TypeA)))
+	if msg.Header.ID != 3 || len(msg.Answers) != 1 || msg.Answers[0].Header.TTL != staleTTL {
+		t.Fatalf("expect the stale answer, but got %#v", msg)
+	}
+	if a := msg.Answers[0].Body.(*dnsmessage.AResource).A; a != [4]byte{1, 2, 3, 4} {
+		t.Errorf("expect stale answer 1.2.3.4, but got %v", a)
+	}
+
+	testcases := map[string]struct {
+		name    string
+		qtype   dnsmessage.Type
+		rcode   dnsmessage.RCode
+		answers int
+	}{
+		"service is resolved by cached service": {
+			name:    "nginx.default.svc.cluster.local.",
+			qtype:   dnsmessage.TypeA,
+			rcode:   dnsmessage.RCodeSuccess,
+			answers: 1,
+		},
+		"ipv6 of service is resolved by cached service": {
+			name:    "nginx.default.svc.cluster.local.",
+			qtype:   dnsmessage.TypeAAAA,
+			rcode:   dnsmessage.RCodeSuccess,
+			answers: 1,
+		},
+		"headless service is resolved by ready endpoints": {
+			name:    "web.default.svc.cluster.local.",
+			qtype:   dnsmessage.TypeA,
+			rcode:   dnsmessage.RCodeSuccess,
+			answers: 1,
+		},
+		"statefulset pod is resolved by hostname of endpoint": {
+			name:    "web-0.web.default.svc.cluster.local.",
+			qtype:   dnsmessage.TypeA,
+			rcode:   dnsmessage.RCodeSuccess,
+			answers: 1,
+		},
+		"not ready statefulset pod": {
+			name:  "web-1.web.default.svc.cluster.local.",
+			qtype: dnsmessage.TypeA,
+			rcode: dnsmessage.RCodeNameError,
+		},
+		"hostname of service with cluster ip": {
+			name:  "web-0.nginx.default.svc.cluster.local.",
+			qtype: dnsmessage.TypeA,
+			rcode: dnsmessage.RCodeNameError,
+		},
+		"external name service is answered with cname": {
+			name:    "external.default.svc.cluster.local.",
+			qtype:   dnsmessage.TypeCNAME,
+			rcode:   dnsmessage.RCodeSuccess,
+			answers: 1,
+		},
+		"external name service is followed by cached answers": {
+			name:    "external.default.svc.cluster.local.",
+			qtype:   dnsmessage.TypeA,
+			rcode:   dnsmessage.RCodeSuccess,
+			answers: 2,
+		},
+		"unknown service in cluster domain": {
+			name:  "nginx.default.svc.cluster.local.default.svc.cluster.local.",
+			qtype: dnsmessage.TypeA,
+			rcode: dnsmessage.RCodeNameError,
+		},
+		"name out of cluster domain is not cached": {
+			name:  "openyurt.io.",
+			qtype: dnsmessage.TypeA,
+			rcode: dnsmessage.RCodeServerFailure,
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			msg := unpackResponse(t, c.resolve("udp", newQuery(t, 4, tc.name, tc.qtype)))
+			if msg.Header.RCode != tc.rcode {
+				t.Errorf("expect rcode %v, but got %v", tc.rcode, msg.Header.RCode)
+			}
+			if len(msg.Answers) != tc.answers {
+				t.Errorf("expect %d answers, but got %d", tc.answers, len(msg.Answers))
+			}
+		})
+	}
+}
+
+func newNameError(t *testing.T, req []byte, minTTL uint32) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(req); err != nil {
+		t.Fatalf("failed to unpack query, %v", err)
+	}
+	msg.Header.Response = true
+	msg.Header.RCode = dnsmessage.RCodeNameError
+	msg.Authorities = []dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("com."), Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 900},
+		Body: &dnsmessage.SOAResource{
+			NS:     dnsmessage.MustNewName("a.gtld-servers.net."),
+			MBox:   dnsmessage.MustNewName("nstld.verisign-grs.com."),
+			MinTTL: minTTL,
+		},
+	}}
+	resp, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack name error, %v", err)
+	}
+	return resp
+}
+
+func TestDNSCacheNegativeAndStale(t *testing.T) {
+	now := time.Now()
+	c := NewDNSCache([]string{"169.254.2.1"}, "10.96.0.10", "cluster.local", nil, nil)
+	c.now = func() time.Time { return now }
+	var refreshes []func()
+	c.async = func(f func()) { refreshes = append(refreshes, f) }
+
+	upstreamUp := true
+	ip := [4]byte{1, 2, 3, 4}
+	exchanged := 0
+	c.exchange = func(network, server string, req []byte) ([]byte, error) {
+		exchanged++
+		if !upstreamUp {
+			return nil, errors.New("i/o timeout")
+		}
+		var query dnsmessage.Message
+		if err := query.Unpack(req); err != nil {
+			t.Fatalf("failed to unpack query, %v", err)
+		}
+		if query.Questions[0].Name.String() == "nonexistent.com." {
+			return newNameError(t, req, 20), nil
+		}
+		return newAnswer(t, req, ip, 60), nil
+	}
+
+	// NXDOMAIN is cached by the minimum ttl of SOA record
+	for id := uint16(1); id <= 2; id++ {
+		msg := unpackResponse(t, c.resolve("udp", newQuery(t, id, "nonexistent.com.", dnsmessage.TypeA)))
+		if msg.Header.RCode != dnsmessage.RCodeNameError || exchanged != 1 {
+			t.Fatalf("expect cached NXDOMAIN after %d exchanges, but got %v", exchanged, msg.Header.RCode)
+		}
+		if id == 2 && (len(msg.Authorities) != 1 || msg.Authorities[0].Header.TTL > 20) {
+			t.Errorf("expect negative ttl less than 20s, but got %#v", msg.Authorities)
+		}
+	}
+
+	// the expired answer is served at once, and refreshed in background
+	c.resolve("udp", newQuery(t, 3, "example.com.", dnsmessage.TypeA))
+	now = now.Add(2 * time.Minute)
+	ip = [4]byte{5, 6, 7, 8}
+	for id := uint16(4); id <= 5; id++ {
+		msg := unpackResponse(t, c.resolve("udp", newQuery(t, id, "example.com.", dnsmessage.TypeA)))
+		if len(msg.Answers) != 1 || msg.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{1, 2, 3, 4} {
+			t.Fatalf("expect the stale answer, but got %#v", msg)
+		}
+	}
+	if len(refreshes) != 1 || exchanged != 2 {
+		t.Fatalf("expect one pending refresh without exchange, but got %d refreshes and %d exchanges", len(refreshes), exchanged)
+	}
+	refreshes[0]()
+	refreshes = nil
+	msg := unpackResponse(t, c.resolve("udp", newQuery(t, 6, "example.com.", dnsmessage.TypeA)))
+	if len(msg.Answers) != 1 || msg.Answers[0].Body.(*dnsmessage.AResource).A != ip {
+		t.Fatalf("expect the refreshed answer, but got %#v", msg)
+	}
+
+	// the upstream is not queried again within upstreamRetryInterval after it's unreachable
+	upstreamUp = false
+	exchanged = 0
+	for id := uint16(7); id <= 9; id++ {
+		msg := unpackResponse(t, c.resolve("udp", newQuery(t, id, "openyurt.io.", dnsmessage.TypeA)))
+		if msg.Header.RCode != dnsmessage.RCodeServerFailure || exchanged != 1 {
+			t.Fatalf("expect SERVFAIL after one exchange, but got %v after %d exchanges", msg.Header.RCode, exchanged)
+		}
+	}
+	now = now.Add(2 * time.Minute)
+	c.resolve("udp", newQuery(t, 10, "example.com.", dnsmessage.TypeA))
+	if len(refreshes) != 1 {
+		t.Fatalf("expect refresh after upstream retry interval, but got %d", len(refreshes))
+	}
+}
+
+func TestDNSCacheTruncate(t *testing.T) {
+	c := NewDNSCache([]string{"169.254.2.1"}, "10.96.0.10", "cluster.local", nil, nil)
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 1},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("example.com."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	msg := &dnsmessage.Message{Header: dnsmessage.Header{ID: 1, Response: true}, Questions: query.Questions}
+	for i := 0; i < 64; i++ {
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, byte(i)}},
+		})
+	}
+
+	if resp := unpackResponse(t, c.pack("udp", &query, msg)); !resp.Header.Truncated || len(resp.Answers) != 0 {
+		t.Errorf("expect truncated udp response, but got %#v", resp.Header)
+	}
+	if resp := unpackResponse(t, c.pack("tcp", &query, msg)); resp.Header.Truncated || len(resp.Answers) != 64 {
+		t.Errorf("expect full tcp response, but got %d answers", len(resp.Answers))
+	}
+}
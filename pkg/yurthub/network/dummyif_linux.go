@@ -22,11 +22,12 @@ import (
 	"strings"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 	"k8s.io/klog/v2"
 )
 
 type DummyInterfaceController interface {
-	EnsureDummyInterface(ifName string, ifIP net.IP) error
+	EnsureDummyInterface(ifName string, ifIPs ...net.IP) error
 	DeleteDummyInterface(ifName string) error
 	ListDummyInterface(ifName string) ([]net.IP, error)
 }
@@ -42,8 +43,8 @@ func NewDummyInterfaceController() DummyInterfaceController {
 	}
 }
 
-// EnsureDummyInterface make sure the dummy net interface with specified name and ip exist
-func (dic *dummyInterfaceController) EnsureDummyInterface(ifName string, ifIP net.IP) error {
+// EnsureDummyInterface make sure the dummy net interface with specified name and ips exist
+func (dic *dummyInterfaceController) EnsureDummyInterface(ifName string, ifIPs ...net.IP) error {
 	link, err := dic.LinkByName(ifName)
 	if err == nil {
 		addrs, err := dic.AddrList(link, 0)
@@ -51,25 +52,27 @@ func (dic *dummyInterfaceController) EnsureDummyInterface(ifName string, ifIP ne
 			return err
 		}
 
-		for _, addr := range addrs {
-			if addr.IP != nil && addr.IP.Equal(ifIP) {
-				return nil
+		for _, ifIP := range ifIPs {
+			if hasAddr(addrs, ifIP) {
+				continue
+			}
+			klog.Infof("ip address for %s interface changed to %s", ifName, ifIP.String())
+			if err := dic.AddrReplace(link, newDummyAddr(ifIP)); err != nil {
+				return err
 			}
 		}
-
-		klog.Infof("ip address for %s interface changed to %s", ifName, ifIP.String())
-		return dic.AddrReplace(link, &netlink.Addr{IPNet: netlink.NewIPNet(ifIP)})
+		return dic.ensureLinkUp(link, ifIPs)
 	}
 
 	if strings.Contains(err.Error(), "Link not found") && link == nil {
-		return dic.addDummyInterface(ifName, ifIP)
+		return dic.addDummyInterface(ifName, ifIPs)
 	}
 
 	return err
 }
 
-// addDummyInterface creates a dummy net interface with the specified name and ip
-func (dic *dummyInterfaceController) addDummyInterface(ifName string, ifIP net.IP) error {
+// addDummyInterface creates a dummy net interface with the specified name and ips
+func (dic *dummyInterfaceController) addDummyInterface(ifName string, ifIPs []net.IP) error {
 	_, err := dic.LinkByName(ifName)
 	if err == nil {
 		return fmt.Errorf("Link %s exists", ifName)
@@ -87,7 +90,45 @@ func (dic *dummyInterfaceController) addDummyInterface(ifName string, ifIP net.I
 	if err != nil {
 		return err
 	}
-	return dic.AddrAdd(link, &netlink.Addr{IPNet: netlink.NewIPNet(ifIP)})
+	for _, ifIP := range ifIPs {
+		if err := dic.AddrAdd(link, newDummyAddr(ifIP)); err != nil {
+			return err
+		}
+	}
+	return dic.ensureLinkUp(link, ifIPs)
+}
+
+// ensureLinkUp sets the dummy interface up if it has ipv6 addresses, because ipv6 addresses
+// can't be used on the interface which is down.
+func (dic *dummyInterfaceController) ensureLinkUp(link netlink.Link, ifIPs []net.IP) error {
+	if link.Attrs().Flags&net.FlagUp != 0 {
+		return nil
+	}
+	for _, ifIP := range ifIPs {
+		if ifIP.To4() == nil {
+			return dic.LinkSetUp(link)
+		}
+	}
+	return nil
+}
+
+func hasAddr(addrs []netlink.Addr, ip net.IP) bool {
+	for _, addr := range addrs {
+		if addr.IP != nil && addr.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// newDummyAddr makes up the address of ip, duplicate address detection is skipped for ipv6
+// address, so it's usable at once instead of being tentative.
+func newDummyAddr(ip net.IP) *netlink.Addr {
+	addr := &netlink.Addr{IPNet: netlink.NewIPNet(ip)}
+	if ip.To4() == nil {
+		addr.Flags = unix.IFA_F_NODAD
+	}
+	return addr
 }
 
 // DeleteDummyInterface delete the dummy net interface with specified name
@@ -24,7 +24,7 @@ import (
 )
 
 type DummyInterfaceController interface {
-	EnsureDummyInterface(ifName string, ifIP net.IP) error
+	EnsureDummyInterface(ifName string, ifIPs ...net.IP) error
 	DeleteDummyInterface(ifName string) error
 	ListDummyInterface(ifName string) ([]net.IP, error)
 }
@@ -37,7 +37,7 @@ func NewDummyInterfaceController() DummyInterfaceController {
 }
 
 // EnsureDummyInterface unimplemented
-func (uic *unsupportedInterfaceController) EnsureDummyInterface(ifName string, ifIP net.IP) error {
+func (uic *unsupportedInterfaceController) EnsureDummyInterface(ifName string, ifIPs ...net.IP) error {
 	return nil
 }
 
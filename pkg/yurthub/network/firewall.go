@@ -0,0 +1,366 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/exec"
+	utilnet "k8s.io/utils/net"
+
+	"github.com/openyurtio/openyurt/cmd/yurthub/app/options"
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+)
+
+// RulesManager sets up the rules for accessing hub agent through the dummy interface.
+type RulesManager interface {
+	EnsureRules() error
+	CleanUpRules() error
+}
+
+// NewRulesManager creates a RulesManager of mode for accessing hub agent at dummyIfIPs:dummyIfPorts
+// and dns cache at dummyIfIPs:53 if dnsCache is true, the rules of ipv6 addresses are set up by
+// ip6tables in iptables modes.
+func NewRulesManager(mode string, dummyIfIPs, dummyIfPorts []string, dnsCache bool) RulesManager {
+	execer := exec.New()
+	if mode == options.FirewallModeAuto {
+		mode = detectFirewallMode(execer, utilnet.IsIPv6String(dummyIfIPs[0]))
+		klog.Infof("firewall mode %s is detected for setting up rules of dummy interface", mode)
+	}
+
+	if mode == options.FirewallModeNftables {
+		return NewNftablesManager(execer, dummyIfIPs, dummyIfPorts, dnsCache)
+	}
+
+	backend := ""
+	switch mode {
+	case options.FirewallModeIptablesLegacy:
+		backend = "legacy"
+	case options.FirewallModeIptablesNft:
+		backend = "nft"
+	}
+	var managers rulesManagers
+	for _, dummyIfIP := range dummyIfIPs {
+		managers = append(managers, NewIptablesManager(execer, backend, dummyIfIP, dummyIfPorts, dnsCache))
+	}
+	return managers
+}
+
+// rulesManagers sets up the rules of all managers, e.g. the iptables and ip6tables rules for dual-stack.
+type rulesManagers []RulesManager
+
+func (rms rulesManagers) EnsureRules() error {
+	var errs []error
+	for _, rm := range rms {
+		if err := rm.EnsureRules(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (rms rulesManagers) CleanUpRules() error {
+	var errs []error
+	for _, rm := range rms {
+		if err := rm.CleanUpRules(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// detectFirewallMode returns the iptables backend which the rules of kube-proxy are in, so the rules
+// of hub agent are evaluated together with them. kube-proxy creates KUBE- chains in both iptables
+// and ipvs mode(the ipvs mode uses iptables for masquerading and filtering too), and kubelet may
+// create a few KUBE- chains in the other backend, so the backend with the most KUBE- rules is used.
+// Native nftables is used if neither kube-proxy rules nor iptables is found but nft is installed.
+func detectFirewallMode(execer exec.Interface, ipv6 bool) string {
+	cmd := "iptables"
+	if ipv6 {
+		cmd = "ip6tables"
+	}
+
+	mode, maxRules := "", 0
+	for _, backend := range []string{"nft", "legacy"} {
+		out, err := execer.Command(fmt.Sprintf("%s-%s-save", cmd, backend)).CombinedOutput()
+		if err != nil {
+			klog.V(4).Infof("failed to run %s-%s-save, %v", cmd, backend, err)
+			continue
+		}
+		if rules := bytes.Count(out, []byte("-A KUBE-")); rules > maxRules {
+			mode, maxRules = fmt.Sprintf("iptables-%s", backend), rules
+		}
+	}
+	if len(mode) != 0 {
+		return mode
+	}
+
+	if _, err := execer.LookPath(cmd); err != nil {
+		if _, err := execer.LookPath("nft"); err == nil {
+			return options.FirewallModeNftables
+		}
+	}
+	return options.FirewallModeIptables
+}
+
+// nftablesRule is a rule for accessing hub agent in the filter base chains of hook, the comment
+// identifies the rule in the chains of other tables.
+type nftablesRule struct {
+	hook    string
+	family  string
+	rule    string
+	comment string
+}
+
+func (r nftablesRule) String() string {
+	return fmt.Sprintf("%s comment %q", r.rule, r.comment)
+}
+
+// NftablesManager sets up the rules for accessing hub agent with native nftables, it's used on the nodes
+// which rely on nftables instead of iptables. An accept verdict only ends the base chain it's in, and the
+// packets still go through the base chains of other tables on the same hook, so the rules are kept at the head
+// of every filter base chain on input and output hooks, e.g. the chains of firewalld and iptables-nft. Nothing
+// is installed if there are no such chains, because the packets are accepted by default.
+type NftablesManager struct {
+	execer exec.Interface
+	// table is the name of inet table created by the older versions of hub agent, it's removed if found.
+	table string
+	// commentPrefix is the prefix of comments of the rules of hub agent.
+	commentPrefix string
+	rules         []nftablesRule
+}
+
+// NewNftablesManager creates a NftablesManager for accessing hub agent at dummyIfIPs:dummyIfPorts
+// and dns cache at dummyIfIPs:53 if dnsCache is true.
+func NewNftablesManager(execer exec.Interface, dummyIfIPs, dummyIfPorts []string, dnsCache bool) *NftablesManager {
+	nm := &NftablesManager{
+		execer:        execer,
+		table:         strings.ReplaceAll(projectinfo.GetHubName(), "-", "_"),
+		commentPrefix: projectinfo.GetHubName() + ": ",
+	}
+	for _, dummyIfIP := range dummyIfIPs {
+		for _, port := range dummyIfPorts {
+			nm.rules = append(nm.rules, nm.makeupRules(dummyIfIP, "tcp", port, "for container access hub agent")...)
+		}
+		if dnsCache {
+			for _, protocol := range []string{"udp", "tcp"} {
+				nm.rules = append(nm.rules, nm.makeupRules(dummyIfIP, protocol, strconv.Itoa(dnsPort), "for container access dns cache")...)
+			}
+		}
+	}
+	return nm
+}
+
+func (nm *NftablesManager) makeupRules(ifIP, protocol, port, comment string) []nftablesRule {
+	family := "ip"
+	if utilnet.IsIPv6String(ifIP) {
+		family = "ip6"
+	}
+	comment = fmt.Sprintf("%s%s at %s/%s", nm.commentPrefix, comment, net.JoinHostPort(ifIP, port), protocol)
+	return []nftablesRule{
+		{"input", family, fmt.Sprintf("%s daddr %s %s dport %s accept", family, ifIP, protocol, port), comment},
+		{"output", family, fmt.Sprintf("%s saddr %s %s sport %s accept", family, ifIP, protocol, port), comment},
+	}
+}
+
+// EnsureRules inserts the rules at the head of filter base chains which don't start with the rules,
+// the stale rules of hub agent in the chains are removed in the same transaction.
+func (nm *NftablesManager) EnsureRules() error {
+	ruleset, err := nm.listRuleset()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	nm.deleteLegacyTable(&b, ruleset)
+	for _, chain := range ruleset.chains {
+		rules := nm.rulesOf(chain)
+		if len(rules) == 0 || nm.startsWithRules(chain, rules) {
+			continue
+		}
+		nm.deleteRules(&b, chain)
+		// every rule is inserted at the head of chain, so they are inserted in reverse order
+		for i := len(rules) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "insert rule %s %s %s %s\n", chain.family, chain.table, chain.name, rules[i])
+		}
+	}
+	return nm.run(b.String())
+}
+
+// CleanUpRules removes the rules of hub agent from all the filter base chains.
+func (nm *NftablesManager) CleanUpRules() error {
+	ruleset, err := nm.listRuleset()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	nm.deleteLegacyTable(&b, ruleset)
+	for _, chain := range ruleset.chains {
+		nm.deleteRules(&b, chain)
+	}
+	return nm.run(b.String())
+}
+
+// rulesOf returns the rules for chain, the chains of ip and ip6 tables only contain the rules of their family.
+func (nm *NftablesManager) rulesOf(chain *nftablesChain) []nftablesRule {
+	var rules []nftablesRule
+	for _, rule := range nm.rules {
+		if rule.hook == chain.hook && (chain.family == "inet" || chain.family == rule.family) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// startsWithRules checks whether chain starts with rules and has no other rules of hub agent.
+func (nm *NftablesManager) startsWithRules(chain *nftablesChain, rules []nftablesRule) bool {
+	owned := 0
+	for _, rule := range chain.rules {
+		if strings.HasPrefix(rule.Comment, nm.commentPrefix) {
+			owned++
+		}
+	}
+	if owned != len(rules) || len(chain.rules) < len(rules) {
+		return false
+	}
+	for i := range rules {
+		if chain.rules[i].Comment != rules[i].comment {
+			return false
+		}
+	}
+	return true
+}
+
+func (nm *NftablesManager) deleteRules(b *strings.Builder, chain *nftablesChain) {
+	for _, rule := range chain.rules {
+		if strings.HasPrefix(rule.Comment, nm.commentPrefix) {
+			fmt.Fprintf(b, "delete rule %s %s %s handle %d\n", chain.family, chain.table, chain.name, rule.Handle)
+		}
+	}
+}
+
+func (nm *NftablesManager) deleteLegacyTable(b *strings.Builder, ruleset *nftablesRuleset) {
+	if ruleset.tables.Has("inet " + nm.table) {
+		fmt.Fprintf(b, "delete table inet %s\n", nm.table)
+	}
+}
+
+// nftablesObject is a table, chain or rule in the json output of nft.
+type nftablesObject struct {
+	Family  string `json:"family"`
+	Table   string `json:"table"`
+	Chain   string `json:"chain"`
+	Name    string `json:"name"`
+	Handle  int    `json:"handle"`
+	Type    string `json:"type"`
+	Hook    string `json:"hook"`
+	Comment string `json:"comment"`
+}
+
+// nftablesChain is a filter base chain on input or output hook in an ip, ip6 or inet table.
+type nftablesChain struct {
+	family string
+	table  string
+	name   string
+	hook   string
+	rules  []nftablesObject
+}
+
+type nftablesRuleset struct {
+	// tables are in the format of "family name"
+	tables sets.String
+	chains []*nftablesChain
+}
+
+// listRuleset lists the ruleset by nft in json format, the rules in the chains are in order.
+func (nm *NftablesManager) listRuleset() (*nftablesRuleset, error) {
+	if err := nm.lookPath(); err != nil {
+		return nil, err
+	}
+	out, err := nm.execer.Command("nft", "-j", "list", "ruleset").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nftables ruleset, %w", err)
+	}
+	return parseNftablesRuleset(out, nm.table)
+}
+
+func parseNftablesRuleset(data []byte, legacyTable string) (*nftablesRuleset, error) {
+	var objects struct {
+		Nftables []struct {
+			Table *nftablesObject `json:"table"`
+			Chain *nftablesObject `json:"chain"`
+			Rule  *nftablesObject `json:"rule"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("failed to decode nftables ruleset, %w", err)
+	}
+
+	ruleset := &nftablesRuleset{tables: sets.NewString()}
+	chains := make(map[string]*nftablesChain)
+	for _, object := range objects.Nftables {
+		switch {
+		case object.Table != nil:
+			ruleset.tables.Insert(object.Table.Family + " " + object.Table.Name)
+		case object.Chain != nil:
+			c := object.Chain
+			if c.Type != "filter" || (c.Hook != "input" && c.Hook != "output") ||
+				(c.Family != "ip" && c.Family != "ip6" && c.Family != "inet") ||
+				(c.Family == "inet" && c.Table == legacyTable) {
+				continue
+			}
+			chain := &nftablesChain{family: c.Family, table: c.Table, name: c.Name, hook: c.Hook}
+			chains[strings.Join([]string{c.Family, c.Table, c.Name}, " ")] = chain
+			ruleset.chains = append(ruleset.chains, chain)
+		case object.Rule != nil:
+			r := object.Rule
+			if chain, ok := chains[strings.Join([]string{r.Family, r.Table, r.Chain}, " ")]; ok {
+				chain.rules = append(chain.rules, *r)
+			}
+		}
+	}
+	return ruleset, nil
+}
+
+func (nm *NftablesManager) lookPath() error {
+	if _, err := nm.execer.LookPath("nft"); err != nil {
+		return fmt.Errorf("nft is required by firewall mode %s but not found, %w", options.FirewallModeNftables, err)
+	}
+	return nil
+}
+
+func (nm *NftablesManager) run(script string) error {
+	if len(script) == 0 {
+		return nil
+	}
+	cmd := nm.execer.Command("nft", "-f", "-")
+	cmd.SetStdin(strings.NewReader(script))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		klog.Errorf("could not run nft script %q, %v: %s", script, err, out)
+		return fmt.Errorf("failed to run nft, %w", err)
+	}
+	return nil
+}
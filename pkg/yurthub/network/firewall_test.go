@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"k8s.io/utils/exec"
+	fakeexec "k8s.io/utils/exec/testing"
+
+	"github.com/openyurtio/openyurt/cmd/yurthub/app/options"
+)
+
+func TestDetectFirewallMode(t *testing.T) {
+	ipvsRules := "*nat\n:KUBE-SERVICES - [0:0]\n-A KUBE-SERVICES -m set --match-set KUBE-CLUSTER-IP dst,dst -j ACCEPT\n-A KUBE-POSTROUTING -j MASQUERADE\n"
+	kubeletRules := "*filter\n:KUBE-FIREWALL - [0:0]\n-A KUBE-FIREWALL -j DROP\n"
+	testcases := map[string]struct {
+		nftOutput    string
+		legacyOutput string
+		lookPath     map[string]bool
+		result       string
+	}{
+		"kube-proxy rules are in nft backend": {
+			nftOutput:    ipvsRules,
+			legacyOutput: kubeletRules,
+			lookPath:     map[string]bool{"iptables": true},
+			result:       options.FirewallModeIptablesNft,
+		},
+		"kube-proxy rules are in legacy backend": {
+			nftOutput:    kubeletRules,
+			legacyOutput: ipvsRules,
+			lookPath:     map[string]bool{"iptables": true},
+			result:       options.FirewallModeIptablesLegacy,
+		},
+		"no kube-proxy rules": {
+			lookPath: map[string]bool{"iptables": true, "nft": true},
+			result:   options.FirewallModeIptables,
+		},
+		"only nft is installed": {
+			lookPath: map[string]bool{"nft": true},
+			result:   options.FirewallModeNftables,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			outputs := []string{tc.nftOutput, tc.legacyOutput}
+			fexec := &fakeexec.FakeExec{
+				LookPathFunc: func(file string) (string, error) {
+					if tc.lookPath[file] {
+						return "/usr/sbin/" + file, nil
+					}
+					return "", errors.New("not found")
+				},
+			}
+			for i := range outputs {
+				output := outputs[i]
+				fexec.CommandScript = append(fexec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+					fcmd := &fakeexec.FakeCmd{
+						CombinedOutputScript: []fakeexec.FakeAction{func() ([]byte, []byte, error) {
+							if len(output) == 0 {
+								return nil, nil, &fakeexec.FakeExitError{Status: 127}
+							}
+							return []byte(output), nil, nil
+						}},
+					}
+					return fakeexec.InitFakeCmd(fcmd, cmd, args...)
+				})
+			}
+
+			if mode := detectFirewallMode(fexec, false); mode != tc.result {
+				t.Errorf("expect firewall mode %s, but got %s", tc.result, mode)
+			}
+		})
+	}
+}
+
+func TestBackendCommand(t *testing.T) {
+	e := &backendExec{backend: "nft"}
+	for cmd, result := range map[string]string{
+		"iptables":          "iptables-nft",
+		"ip6tables-save":    "ip6tables-nft-save",
+		"iptables-restore":  "iptables-nft-restore",
+		"ip6tables-restore": "ip6tables-nft-restore",
+	} {
+		if got := e.backendCommand(cmd); got != result {
+			t.Errorf("expect command %s for %s, but got %s", result, cmd, got)
+		}
+	}
+}
+
+// newNftExec returns the fake exec which lists ruleset and records the scripts run by nft.
+func newNftExec(ruleset string, scripts *[]string) *fakeexec.FakeExec {
+	fexec := &fakeexec.FakeExec{
+		LookPathFunc: func(file string) (string, error) { return "/usr/sbin/" + file, nil },
+	}
+	listCmd := &fakeexec.FakeCmd{
+		OutputScript: []fakeexec.FakeAction{func() ([]byte, []byte, error) { return []byte(ruleset), nil, nil }},
+	}
+	runCmd := &fakeexec.FakeCmd{}
+	runCmd.CombinedOutputScript = []fakeexec.FakeAction{func() ([]byte, []byte, error) {
+		data, _ := io.ReadAll(runCmd.Stdin)
+		*scripts = append(*scripts, string(data))
+		return nil, nil, nil
+	}}
+	fexec.CommandScript = []fakeexec.FakeCommandAction{
+		func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(listCmd, cmd, args...) },
+		func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(runCmd, cmd, args...) },
+	}
+	return fexec
+}
+
+func TestNftablesManagerEnsureRules(t *testing.T) {
+	nm := NewNftablesManager(nil, []string{"169.254.2.1", "fd00::2:1"}, []string{"10261"}, true)
+	inputComment := nm.makeupRules("169.254.2.1", "tcp", "10261", "for container access hub agent")[0].comment
+	ruleset := `{"nftables": [{"metainfo": {"version": "1.0.2"}},
+{"table": {"family": "inet", "name": "yurthub", "handle": 1}},
+{"chain": {"family": "inet", "table": "yurthub", "name": "input", "handle": 1, "type": "filter", "hook": "input", "prio": -1, "policy": "accept"}},
+{"table": {"family": "inet", "name": "firewalld", "handle": 2}},
+{"chain": {"family": "inet", "table": "firewalld", "name": "filter_INPUT", "handle": 1, "type": "filter", "hook": "input", "prio": 10, "policy": "accept"}},
+{"chain": {"family": "inet", "table": "firewalld", "name": "nat_OUTPUT", "handle": 2, "type": "nat", "hook": "output", "prio": -90, "policy": "accept"}},
+{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 5, "comment": "` + inputComment + `"}},
+{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 6}},
+{"table": {"family": "ip", "name": "filter", "handle": 3}},
+{"chain": {"family": "ip", "table": "filter", "name": "OUTPUT", "handle": 1, "type": "filter", "hook": "output", "prio": 0, "policy": "drop"}}
+]}`
+
+	var scripts []string
+	nm.execer = newNftExec(ruleset, &scripts)
+	if err := nm.EnsureRules(); err != nil {
+		t.Fatalf("failed to ensure nftables rules, %v", err)
+	}
+	if len(scripts) != 1 {
+		t.Fatalf("expect one nft script, but got %d", len(scripts))
+	}
+	script := scripts[0]
+	for _, rule := range []string{
+		"delete table inet yurthub\n",
+		"delete rule inet firewalld filter_INPUT handle 5\n",
+		"insert rule inet firewalld filter_INPUT ip daddr 169.254.2.1 tcp dport 10261 accept comment",
+		"insert rule inet firewalld filter_INPUT ip daddr 169.254.2.1 udp dport 53 accept comment",
+		"insert rule inet firewalld filter_INPUT ip6 daddr fd00::2:1 tcp dport 10261 accept comment",
+		"insert rule ip filter OUTPUT ip saddr 169.254.2.1 tcp sport 10261 accept comment",
+	} {
+		if !strings.Contains(script, rule) {
+			t.Errorf("rule %q is not in nftables script:\n%s", rule, script)
+		}
+	}
+	for _, rule := range []string{"nat_OUTPUT", "ip filter OUTPUT ip6", "insert rule inet yurthub"} {
+		if strings.Contains(script, rule) {
+			t.Errorf("unexpected rule %q in nftables script:\n%s", rule, script)
+		}
+	}
+	// rules are inserted in reverse order, so the first rule is inserted last
+	if !strings.HasSuffix(script, "insert rule ip filter OUTPUT "+nm.rulesOf(&nftablesChain{family: "ip", hook: "output"})[0].String()+"\n") {
+		t.Errorf("the first rule is not inserted last:\n%s", script)
+	}
+}
+
+func TestNftablesManagerRulesUpToDate(t *testing.T) {
+	nm := NewNftablesManager(nil, []string{"169.254.2.1"}, []string{"10261"}, false)
+	ruleset := `{"nftables": [
+{"table": {"family": "inet", "name": "firewalld", "handle": 2}},
+{"chain": {"family": "inet", "table": "firewalld", "name": "filter_INPUT", "handle": 1, "type": "filter", "hook": "input", "prio": 10, "policy": "accept"}},
+{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 5, "comment": "` + nm.rules[0].comment + `"}},
+{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 6}}
+]}`
+
+	var scripts []string
+	nm.execer = newNftExec(ruleset, &scripts)
+	if err := nm.EnsureRules(); err != nil {
+		t.Fatalf("failed to ensure nftables rules, %v", err)
+	}
+	if len(scripts) != 0 {
+		t.Errorf("expect no changes for up to date rules, but got %v", scripts)
+	}
+
+	scripts = nil
+	nm.execer = newNftExec(ruleset, &scripts)
+	if err := nm.CleanUpRules(); err != nil {
+		t.Fatalf("failed to clean up nftables rules, %v", err)
+	}
+	if len(scripts) != 1 || scripts[0] != "delete rule inet firewalld filter_INPUT handle 5\n" {
+		t.Errorf("unexpected clean up script %v", scripts)
+	}
+}
+
+func TestNftablesManagerWithoutNft(t *testing.T) {
+	fexec := &fakeexec.FakeExec{
+		LookPathFunc: func(file string) (string, error) { return "", exec.ErrExecutableNotFound },
+	}
+
+	nm := NewNftablesManager(fexec, []string{"169.254.2.1"}, []string{"10261"}, false)
+	if err := nm.EnsureRules(); err == nil || !strings.Contains(err.Error(), "nft is required") {
+		t.Errorf("expect error for missing nft, but got %v", err)
+	}
+}
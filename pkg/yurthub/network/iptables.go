@@ -17,6 +17,9 @@ limitations under the License.
 package network
 
 import (
+	"context"
+	"fmt"
+	"strconv"
 	"strings"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -39,17 +42,26 @@ type IptablesManager struct {
 	rules    []iptablesRule
 }
 
-func NewIptablesManager(dummyIfIP, dummyIfPort string) *IptablesManager {
+// NewIptablesManager creates an IptablesManager for accessing hub agent at dummyIfIP:dummyIfPorts
+// and dns cache at dummyIfIP:53 if dnsCache is true, the rules are set up by the commands of
+// backend(legacy or nft), or by the default iptables commands if backend is empty.
+func NewIptablesManager(execer exec.Interface, backend, dummyIfIP string, dummyIfPorts []string, dnsCache bool) *IptablesManager {
 	protocol := iptables.ProtocolIpv4
 	if utilnet.IsIPv6String(dummyIfIP) {
 		protocol = iptables.ProtocolIpv6
 	}
-	execer := exec.New()
-	iptInterface := iptables.New(execer, protocol)
+	if len(backend) != 0 {
+		execer = &backendExec{Interface: execer, backend: backend}
+	}
 
 	im := &IptablesManager{
-		iptables: iptInterface,
-		rules:    makeupIptablesRules(dummyIfIP, dummyIfPort),
+		iptables: iptables.New(execer, protocol),
+	}
+	for _, port := range dummyIfPorts {
+		im.rules = append(im.rules, makeupIptablesRules(dummyIfIP, port)...)
+	}
+	if dnsCache {
+		im.rules = append(im.rules, makeupDNSIptablesRules(dummyIfIP)...)
 	}
 
 	return im
@@ -64,7 +76,21 @@ func makeupIptablesRules(ifIP, ifPort string) []iptablesRule {
 	}
 }
 
-func (im *IptablesManager) EnsureIptablesRules() error {
+func makeupDNSIptablesRules(ifIP string) []iptablesRule {
+	port := strconv.Itoa(dnsPort)
+	var rules []iptablesRule
+	for _, protocol := range []string{"udp", "tcp"} {
+		rules = append(rules,
+			// accept dns queries to 169.254.2.1:53
+			iptablesRule{iptables.Prepend, iptables.TableFilter, iptables.ChainInput, []string{"-p", protocol, "-m", "comment", "--comment", "for container access dns cache", "--dport", port, "--destination", ifIP, "-j", "ACCEPT"}},
+			// accept dns responses from 169.254.2.1:53
+			iptablesRule{iptables.Prepend, iptables.TableFilter, iptables.ChainOutput, []string{"-p", protocol, "--sport", port, "-s", ifIP, "-j", "ACCEPT"}},
+		)
+	}
+	return rules
+}
+
+func (im *IptablesManager) EnsureRules() error {
 	var errs []error
 	for _, rule := range im.rules {
 		_, err := im.iptables.EnsureRule(rule.pos, rule.table, rule.chain, rule.args...)
@@ -77,7 +103,7 @@ func (im *IptablesManager) EnsureIptablesRules() error {
 	return utilerrors.NewAggregate(errs)
 }
 
-func (im *IptablesManager) CleanUpIptablesRules() error {
+func (im *IptablesManager) CleanUpRules() error {
 	var errs []error
 	for _, rule := range im.rules {
 		err := im.iptables.DeleteRule(rule.table, rule.chain, rule.args...)
@@ -88,3 +114,30 @@ func (im *IptablesManager) CleanUpIptablesRules() error {
 	}
 	return utilerrors.NewAggregate(errs)
 }
+
+// backendExec runs the iptables commands of backend, e.g. iptables-nft-save instead of iptables-save.
+type backendExec struct {
+	exec.Interface
+	backend string
+}
+
+func (e *backendExec) backendCommand(cmd string) string {
+	for _, suffix := range []string{"-save", "-restore"} {
+		if strings.HasSuffix(cmd, suffix) {
+			return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(cmd, suffix), e.backend, suffix)
+		}
+	}
+	return fmt.Sprintf("%s-%s", cmd, e.backend)
+}
+
+func (e *backendExec) Command(cmd string, args ...string) exec.Cmd {
+	return e.Interface.Command(e.backendCommand(cmd), args...)
+}
+
+func (e *backendExec) CommandContext(ctx context.Context, cmd string, args ...string) exec.Cmd {
+	return e.Interface.CommandContext(ctx, e.backendCommand(cmd), args...)
+}
+
+func (e *backendExec) LookPath(file string) (string, error) {
+	return e.Interface.LookPath(e.backendCommand(file))
+}
@@ -19,8 +19,10 @@ package network
 import (
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/cmd/yurthub/app/options"
@@ -32,22 +34,34 @@ const (
 
 type NetworkManager struct {
 	ifController    DummyInterfaceController
-	iptablesManager *IptablesManager
-	dummyIfIP       net.IP
+	firewallManager RulesManager
+	dummyIfIPs      []net.IP
 	dummyIfName     string
 	enableIptables  bool
+	dnsCache        *DNSCache
 }
 
-func NewNetworkManager(options *options.YurtHubOptions) (*NetworkManager, error) {
+// NewNetworkManager creates a NetworkManager, the services and endpointslices of factory are used by the dns cache
+// for discovering the upstream and resolving service names when the cloud is unreachable.
+func NewNetworkManager(options *options.YurtHubOptions, factory informers.SharedInformerFactory) (*NetworkManager, error) {
+	// the dummy interface has both ipv4 and ipv6 addresses for dual-stack
+	dummyIfIPs := options.HubAgentDummyIfIPs
+	if len(dummyIfIPs) == 0 {
+		dummyIfIPs = []string{options.HubAgentDummyIfIP}
+	}
 	m := &NetworkManager{
 		ifController:    NewDummyInterfaceController(),
-		iptablesManager: NewIptablesManager(options.HubAgentDummyIfIP, strconv.Itoa(options.YurtHubProxyPort)),
-		dummyIfIP:       net.ParseIP(options.HubAgentDummyIfIP),
+		firewallManager: newRulesManager(options, dummyIfIPs),
 		dummyIfName:     options.HubAgentDummyIfName,
 		enableIptables:  options.EnableIptables,
 	}
-	// secure port
-	m.iptablesManager.rules = append(m.iptablesManager.rules, makeupIptablesRules(options.HubAgentDummyIfIP, strconv.Itoa(options.YurtHubProxySecurePort))...)
+	for _, ip := range dummyIfIPs {
+		m.dummyIfIPs = append(m.dummyIfIPs, net.ParseIP(ip))
+	}
+	if options.EnableDNSCache {
+		m.dnsCache = NewDNSCache(dummyIfIPs, options.DNSCacheUpstream, options.DNSClusterDomain,
+			factory.Core().V1().Services().Lister(), factory.Discovery().V1().EndpointSlices().Lister())
+	}
 	if err := m.configureNetwork(); err != nil {
 		return nil, err
 	}
@@ -56,6 +70,9 @@ func NewNetworkManager(options *options.YurtHubOptions) (*NetworkManager, error)
 }
 
 func (m *NetworkManager) Run(stopCh <-chan struct{}) {
+	if m.dnsCache != nil {
+		m.dnsCache.Run(stopCh)
+	}
 	go func() {
 		ticker := time.NewTicker(SyncNetworkPeriod * time.Second)
 		defer ticker.Stop()
@@ -64,8 +81,8 @@ func (m *NetworkManager) Run(stopCh <-chan struct{}) {
 			select {
 			case <-stopCh:
 				klog.Infof("exit network manager run goroutine normally")
-				if err := m.iptablesManager.CleanUpIptablesRules(); err != nil {
-					klog.Errorf("failed to cleanup iptables, %v", err)
+				if err := m.firewallManager.CleanUpRules(); err != nil {
+					klog.Errorf("failed to cleanup firewall rules, %v", err)
 				}
 				err := m.ifController.DeleteDummyInterface(m.dummyIfName)
 				if err != nil {
@@ -82,17 +99,33 @@ func (m *NetworkManager) Run(stopCh <-chan struct{}) {
 	}()
 }
 
+// newRulesManager creates the RulesManager of firewall mode, the connections to kube-apiserver are
+// redirected to the ipv4 address of dummy interface in ebpf mode, and the https connections are
+// redirected only if --bpf-redirect-https is enabled.
+func newRulesManager(o *options.YurtHubOptions, dummyIfIPs []string) RulesManager {
+	if o.RedirectsByBPF() {
+		httpsPort := 0
+		if o.RedirectsHTTPSByBPF() {
+			httpsPort = o.YurtHubProxyRedirectSecurePort
+		}
+		return NewBPFRedirectManager(o.BPFCgroupPath, strings.Split(o.ServerAddr, ","), o.RedirectTargetIP(), o.YurtHubProxyPort, httpsPort)
+	}
+
+	ports := []string{strconv.Itoa(o.YurtHubProxyPort), strconv.Itoa(o.YurtHubProxySecurePort)}
+	return NewRulesManager(o.FirewallMode, dummyIfIPs, ports, o.EnableDNSCache)
+}
+
 func (m *NetworkManager) configureNetwork() error {
-	err := m.ifController.EnsureDummyInterface(m.dummyIfName, m.dummyIfIP)
+	err := m.ifController.EnsureDummyInterface(m.dummyIfName, m.dummyIfIPs...)
 	if err != nil {
 		klog.Errorf("ensure dummy interface failed, %v", err)
 		return err
 	}
 
 	if m.enableIptables {
-		err := m.iptablesManager.EnsureIptablesRules()
+		err := m.firewallManager.EnsureRules()
 		if err != nil {
-			klog.Errorf("ensure iptables for dummy interface failed, %v", err)
+			klog.Errorf("ensure firewall rules for dummy interface failed, %v", err)
 			return err
 		}
 	}
@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeproblem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+const (
+	// NodeProblemDetectorComponent is the component name of node-problem-detector
+	NodeProblemDetectorComponent = "node-problem-detector"
+
+	relayPeriod = 10 * time.Second
+)
+
+// IsNodeProblemDetectorRequest checks the request is sent by node-problem-detector
+func IsNodeProblemDetectorRequest(req *http.Request) bool {
+	comp, ok := hubutil.ClientComponentFrom(req.Context())
+	return ok && comp == NodeProblemDetectorComponent
+}
+
+// IsNodeConditionsReport checks the request is the node status patch of node-problem-detector
+func IsNodeConditionsReport(req *http.Request) bool {
+	info, ok := apirequest.RequestInfoFrom(req.Context())
+	if !ok || info == nil || !info.IsResourceRequest {
+		return false
+	}
+	return info.Verb == "patch" && info.Resource == "nodes" && info.Subresource == "status" &&
+		IsNodeProblemDetectorRequest(req)
+}
+
+// Relay accepts the node conditions reported by node-problem-detector when the cloud is unreachable,
+// the conditions are carried by the node lease which is delegated to the cloud by the leader yurthub
+// through pool-coordinator, and patched to the cloud by Relay itself as soon as the cloud is reachable again.
+type Relay struct {
+	nodeName string
+	sync.Mutex
+	conditions map[corev1.NodeConditionType]corev1.NodeCondition
+	// generation is increased when conditions are reported, so the conditions reported
+	// during patching are kept.
+	generation int64
+}
+
+// NewRelay creates a Relay for node
+func NewRelay(nodeName string) *Relay {
+	return &Relay{
+		nodeName:   nodeName,
+		conditions: make(map[corev1.NodeConditionType]corev1.NodeCondition),
+	}
+}
+
+type nodeStatusPatch struct {
+	Status struct {
+		Conditions []corev1.NodeCondition `json:"conditions"`
+	} `json:"status"`
+}
+
+// ServeHTTP records the conditions in node status patch of node-problem-detector
+func (r *Relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	info, _ := apirequest.RequestInfoFrom(req.Context())
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		util.Err(apierrors.NewBadRequest(err.Error()), w, req)
+		return
+	}
+	var patch nodeStatusPatch
+	if err := json.Unmarshal(data, &patch); err != nil {
+		util.Err(apierrors.NewBadRequest(fmt.Sprintf("failed to decode node status patch, %v", err)), w, req)
+		return
+	}
+	if info.Name != r.nodeName {
+		util.Err(apierrors.NewForbidden(corev1.Resource("nodes"), info.Name, fmt.Errorf("only conditions of node %s are relayed", r.nodeName)), w, req)
+		return
+	}
+
+	conditions := r.record(patch.Status.Conditions)
+	klog.Infof("%d conditions reported by %s are recorded for relaying, %s", len(patch.Status.Conditions), NodeProblemDetectorComponent, hubutil.ReqString(req))
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: r.nodeName},
+		Status:     corev1.NodeStatus{Conditions: conditions},
+	}
+	if err := util.WriteObject(http.StatusOK, node, w, req); err != nil {
+		klog.Errorf("failed to write resp for node status patch, %v", err)
+	}
+}
+
+func (r *Relay) record(conditions []corev1.NodeCondition) []corev1.NodeCondition {
+	r.Lock()
+	defer r.Unlock()
+	for _, c := range conditions {
+		r.conditions[c.Type] = c
+	}
+	r.generation++
+	return r.pending()
+}
+
+// pending returns the conditions sorted by type, it's called with the lock held.
+func (r *Relay) pending() []corev1.NodeCondition {
+	conditions := make([]corev1.NodeCondition, 0, len(r.conditions))
+	for _, c := range r.conditions {
+		conditions = append(conditions, c)
+	}
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].Type < conditions[j].Type
+	})
+	return conditions
+}
+
+// Annotation returns the value of constant.NodeConditionsAnnotation for the conditions which are not
+// relayed yet, empty string is returned if no condition is pending.
+func (r *Relay) Annotation() string {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.conditions) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(r.pending())
+	if err != nil {
+		klog.Errorf("failed to encode node conditions, %v", err)
+		return ""
+	}
+	return string(data)
+}
+
+// Run patches the pending conditions to the cloud by the client of healthy cloud servers
+// which is created by getRestConfig until stopCh is closed.
+func (r *Relay) Run(getRestConfig func(needHealthyServer bool) *restclient.Config, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(relayPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			r.Lock()
+			conditions, generation := r.pending(), r.generation
+			r.Unlock()
+			if len(conditions) == 0 {
+				continue
+			}
+
+			cfg := getRestConfig(true)
+			if cfg == nil {
+				continue
+			}
+			client, err := kubernetes.NewForConfig(cfg)
+			if err != nil {
+				klog.Errorf("failed to create client for relaying node conditions, %v", err)
+				continue
+			}
+			if err := PatchNodeConditions(client.CoreV1().Nodes(), r.nodeName, conditions); err != nil {
+				klog.Errorf("failed to relay node conditions of %s, %v", r.nodeName, err)
+				continue
+			}
+			klog.Infof("%d node conditions of %s are relayed to the cloud", len(conditions), r.nodeName)
+
+			r.Lock()
+			if r.generation == generation {
+				r.conditions = make(map[corev1.NodeConditionType]corev1.NodeCondition)
+			}
+			r.Unlock()
+		}
+	}
+}
+
+// DecodeAnnotation returns the conditions in the value of constant.NodeConditionsAnnotation
+func DecodeAnnotation(annotation string) ([]corev1.NodeCondition, error) {
+	var conditions []corev1.NodeCondition
+	if err := json.Unmarshal([]byte(annotation), &conditions); err != nil {
+		return nil, fmt.Errorf("failed to decode node conditions, %w", err)
+	}
+	return conditions, nil
+}
+
+// PatchNodeConditions patches conditions into the status of node, the other conditions of node
+// are kept because conditions are merged by type.
+func PatchNodeConditions(nodes corev1client.NodeInterface, nodeName string, conditions []corev1.NodeCondition) error {
+	var patch nodeStatusPatch
+	patch.Status.Conditions = conditions
+	data, err := json.Marshal(&patch)
+	if err != nil {
+		return err
+	}
+	_, err = nodes.Patch(context.Background(), nodeName, types.StrategicMergePatchType, data, metav1.PatchOptions{}, "status")
+	return err
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeproblem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/fake"
+
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+func newNodeStatusPatchRequest(nodeName, component, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/nodes/"+nodeName+"/status", strings.NewReader(body))
+	ctx := apirequest.WithRequestInfo(req.Context(), &apirequest.RequestInfo{
+		IsResourceRequest: true,
+		Verb:              "patch",
+		APIVersion:        "v1",
+		Resource:          "nodes",
+		Subresource:       "status",
+		Name:              nodeName,
+	})
+	ctx = hubutil.WithClientComponent(ctx, component)
+	return req.WithContext(ctx)
+}
+
+func TestIsNodeConditionsReport(t *testing.T) {
+	testcases := map[string]struct {
+		req    *http.Request
+		expect bool
+	}{
+		"node status patch of node-problem-detector": {
+			req:    newNodeStatusPatchRequest("node1", NodeProblemDetectorComponent, "{}"),
+			expect: true,
+		},
+		"node status patch of kubelet": {
+			req:    newNodeStatusPatchRequest("node1", "kubelet", "{}"),
+			expect: false,
+		},
+		"request without request info": {
+			req:    httptest.NewRequest(http.MethodPatch, "/api/v1/nodes/node1/status", nil),
+			expect: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if got := IsNodeConditionsReport(tc.req); got != tc.expect {
+				t.Errorf("expect %v, but got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRelay("node1")
+	if len(r.Annotation()) != 0 {
+		t.Fatalf("expect no annotation without pending conditions")
+	}
+
+	for _, body := range []string{
+		`{"status":{"conditions":[{"type":"KernelDeadlock","status":"False","reason":"KernelHasNoDeadlock"}]}}`,
+		`{"status":{"conditions":[{"type":"KernelDeadlock","status":"True","reason":"DockerHung"},{"type":"DiskFailure","status":"True"}]}}`,
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, newNodeStatusPatchRequest("node1", NodeProblemDetectorComponent, body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expect status 200, but got %d, %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	conditions, err := DecodeAnnotation(r.Annotation())
+	if err != nil {
+		t.Fatalf("failed to decode annotation, %v", err)
+	}
+	if len(conditions) != 2 || conditions[0].Type != "DiskFailure" || conditions[1].Type != "KernelDeadlock" || conditions[1].Reason != "DockerHung" {
+		t.Errorf("unexpected pending conditions %v", conditions)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, newNodeStatusPatchRequest("node2", NodeProblemDetectorComponent, `{"status":{}}`))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expect status 403 for other nodes, but got %d", rec.Code)
+	}
+}
+
+func TestPatchNodeConditions(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	client := fake.NewSimpleClientset(node)
+	conditions := []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}}
+	if err := PatchNodeConditions(client.CoreV1().Nodes(), "node1", conditions); err != nil {
+		t.Fatalf("failed to patch node conditions, %v", err)
+	}
+
+	patched, err := client.CoreV1().Nodes().Get(context.TODO(), "node1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node, %v", err)
+	}
+	data, _ := json.Marshal(patched.Status.Conditions)
+	if len(patched.Status.Conditions) != 2 {
+		t.Errorf("expect conditions merged by type, but got %s", data)
+	}
+}
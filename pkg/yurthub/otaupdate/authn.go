@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otaupdate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Authenticate wraps the handler of OTA approval, the bearer token of request is verified by TokenReview
+// of the cloud kube-apiserver, and the user must be allowed to patch the node by SubjectAccessReview,
+// the same permission as approving by the node annotations directly. The authenticated user is stored into
+// the request context for the handler.
+func Authenticate(handler OTAHandler) OTAHandler {
+	return func(clientset kubernetes.Interface, nodeName string) http.Handler {
+		auth := bearertoken.New(authenticator.TokenFunc(func(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+			return reviewToken(ctx, clientset, token)
+		}))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, ok, err := auth.AuthenticateRequest(r)
+			if err != nil || !ok {
+				if err != nil {
+					klog.Errorf("Authenticate OTA approval request from %s failed, %v", r.RemoteAddr, err)
+				}
+				WriteErr(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			allowed, err := canPatchNode(r.Context(), clientset, resp.User, nodeName)
+			if err != nil {
+				klog.Errorf("Authorize OTA approval request of %s failed, %v", resp.User.GetName(), err)
+				WriteErr(w, "Authorize request failed", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				WriteErr(w, fmt.Sprintf("User %s is not allowed to patch node %s", resp.User.GetName(), nodeName), http.StatusForbidden)
+				return
+			}
+
+			handler(clientset, nodeName).ServeHTTP(w, r.WithContext(request.WithUser(r.Context(), resp.User)))
+		})
+	}
+}
+
+// reviewToken verifies the bearer token by TokenReview of kube-apiserver.
+func reviewToken(ctx context.Context, clientset kubernetes.Interface, token string) (*authenticator.Response, bool, error) {
+	review, err := clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, false, err
+	}
+	if !review.Status.Authenticated {
+		return nil, false, nil
+	}
+
+	extra := make(map[string][]string, len(review.Status.User.Extra))
+	for k, v := range review.Status.User.Extra {
+		extra[k] = v
+	}
+	return &authenticator.Response{
+		User: &user.DefaultInfo{
+			Name:   review.Status.User.Username,
+			UID:    review.Status.User.UID,
+			Groups: review.Status.User.Groups,
+			Extra:  extra,
+		},
+	}, true, nil
+}
+
+// canPatchNode checks whether the user is allowed to patch the node by SubjectAccessReview.
+func canPatchNode(ctx context.Context, clientset kubernetes.Interface, u user.Info, nodeName string) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(u.GetExtra()))
+	for k, v := range u.GetExtra() {
+		extra[k] = v
+	}
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   u.GetName(),
+			UID:    u.GetUID(),
+			Groups: u.GetGroups(),
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:     "patch",
+				Resource: "nodes",
+				Name:     nodeName,
+			},
+		},
+	}
+	result, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return result.Status.Allowed, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otaupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	tests := map[string]struct {
+		token        string
+		allowed      bool
+		expectedCode int
+		expectedUser string
+	}{
+		"valid token": {
+			token:        "admin-token",
+			allowed:      true,
+			expectedCode: http.StatusOK,
+			expectedUser: "admin",
+		},
+		"no token": {
+			expectedCode: http.StatusUnauthorized,
+		},
+		"invalid token": {
+			token:        "invalid-token",
+			allowed:      true,
+			expectedCode: http.StatusUnauthorized,
+		},
+		"user can not patch node": {
+			token:        "admin-token",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for k, tt := range tests {
+		t.Run(k, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if review.Spec.Token == "admin-token" {
+					review.Status.Authenticated = true
+					review.Status.User.Username = "admin"
+				}
+				return true, review, nil
+			})
+			clientset.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				attrs := sar.Spec.ResourceAttributes
+				sar.Status.Allowed = tt.allowed && sar.Spec.User == "admin" && attrs.Verb == "patch" && attrs.Resource == "nodes" && attrs.Name == "node"
+				return true, sar, nil
+			})
+
+			var gotUser string
+			handler := Authenticate(func(_ kubernetes.Interface, _ string) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if u, ok := request.UserFrom(r.Context()); ok {
+						gotUser = u.GetName()
+					}
+				})
+			})
+
+			req, err := http.NewRequest("POST", "/openyurt.io/v1/ota/approval", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.token) != 0 {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler(clientset, "node").ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedUser, gotUser)
+		})
+	}
+}
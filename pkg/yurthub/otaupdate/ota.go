@@ -18,41 +18,72 @@ package otaupdate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	runtimescheme "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/rest"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
 )
 
+const (
+	defaultApprovalTTL = time.Hour
+)
+
+// signatureEnforced and verifyPayload check the OTA payloads against the fleet public key,
+// they are only changed in tests.
+var (
+	signatureEnforced = signature.Enforced
+	verifyPayload     = signature.VerifyPayload
+)
+
 type OTAHandler func(kubernetes.Interface, string) http.Handler
 
 // GetPods return pod list
 func GetPods(store cachemanager.StorageWrapper) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		podsKey, err := store.KeyFunc(storage.KeyBuildInfo{
-			Component: "kubelet",
-			Resources: "pods",
-			Version:   "v1",
-			Group:     "",
-		})
+		pods, err := listCachedPods(store)
 		if err != nil {
-			klog.Errorf("get pods key failed, %v", err)
-			WriteErr(w, "Get pods key failed", http.StatusInternalServerError)
+			klog.Errorf("Get pod list failed, %v", err)
+			WriteErr(w, "Get pod list failed", http.StatusInternalServerError)
 			return
 		}
-		objs, err := store.List(podsKey)
+
+		podList := new(corev1.PodList)
+		for _, pod := range pods {
+			podList.Items = append(podList.Items, *pod)
+		}
+
+		// Successfully get pod list, response 200
+		data, err := encodePods(podList)
+		if err != nil {
+			klog.Errorf("Encode pod list failed, %v", err)
+			WriteErr(w, "Encode pod list failed", http.StatusInternalServerError)
+		}
+		WriteJSONResponse(w, data)
+	})
+}
+
+// GetUpgradablePods return the list of pods with pending OTA upgrades, the pods are listed
+// from local cache, so the pending upgrades can be queried when edge is disconnected to cloud.
+func GetUpgradablePods(store cachemanager.StorageWrapper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pods, err := listCachedPods(store)
 		if err != nil {
 			klog.Errorf("Get pod list failed, %v", err)
 			WriteErr(w, "Get pod list failed", http.StatusInternalServerError)
@@ -60,26 +91,49 @@ func GetPods(store cachemanager.StorageWrapper) http.Handler {
 		}
 
 		podList := new(corev1.PodList)
-		for _, obj := range objs {
-			pod, ok := obj.(*corev1.Pod)
-			if !ok {
-				klog.Errorf("Get pod list failed, %v", err)
-				WriteErr(w, "Get pod list failed", http.StatusInternalServerError)
-				return
+		for _, pod := range pods {
+			if pod.DeletionTimestamp == nil && daemonpodupdater.IsPodUpdatable(pod) {
+				podList.Items = append(podList.Items, *pod)
 			}
-			podList.Items = append(podList.Items, *pod)
 		}
 
-		// Successfully get pod list, response 200
 		data, err := encodePods(podList)
 		if err != nil {
 			klog.Errorf("Encode pod list failed, %v", err)
 			WriteErr(w, "Encode pod list failed", http.StatusInternalServerError)
+			return
 		}
 		WriteJSONResponse(w, data)
 	})
 }
 
+// listCachedPods lists the pods of kubelet in local cache
+func listCachedPods(store cachemanager.StorageWrapper) ([]*corev1.Pod, error) {
+	podsKey, err := store.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: "pods",
+		Version:   "v1",
+		Group:     "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get pods key failed, %w", err)
+	}
+	objs, err := store.List(podsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object type %T in pods cache", obj)
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
 // UpdatePod update a specifc pod(namespace/podname) to the latest version
 func UpdatePod(clientset kubernetes.Interface, nodeName string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -104,6 +158,69 @@ func UpdatePod(clientset kubernetes.Interface, nodeName string) http.Handler {
 	})
 }
 
+// ApproveUpgrades approves the staged OTA upgrades on the current node until the ttl expires,
+// the approved upgrades are applied by daemonPodUpdater controller. The approver is the user
+// authenticated by Authenticate.
+func ApproveUpgrades(clientset kubernetes.Interface, nodeName string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		approver, ok := request.UserFrom(r.Context())
+		if !ok || len(approver.GetName()) == 0 {
+			WriteErr(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ttl := defaultApprovalTTL
+		if v := r.URL.Query().Get("ttl"); len(v) != 0 {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				WriteErr(w, fmt.Sprintf("Invalid ttl %q", v), http.StatusBadRequest)
+				return
+			}
+			ttl = d
+		}
+
+		until := time.Now().Add(ttl).UTC().Format(time.RFC3339)
+		if err := patchApproval(clientset, nodeName, approver.GetName(), until); err != nil {
+			klog.Errorf("Approve OTA upgrades on node %s failed, %v", nodeName, err)
+			WriteErr(w, "Approve upgrades failed", http.StatusInternalServerError)
+			return
+		}
+
+		klog.Infof("OTA upgrades on node %s are approved by %s from %s until %s", nodeName, approver.GetName(), r.RemoteAddr, until)
+		WriteJSONResponse(w, []byte(fmt.Sprintf("OTA upgrades on node %v are approved until %v", nodeName, until)))
+	})
+}
+
+// RevokeUpgrades revokes the approval of the OTA upgrades on the current node.
+func RevokeUpgrades(clientset kubernetes.Interface, nodeName string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := patchApproval(clientset, nodeName, nil, nil); err != nil {
+			klog.Errorf("Revoke OTA upgrades approval on node %s failed, %v", nodeName, err)
+			WriteErr(w, "Revoke upgrades approval failed", http.StatusInternalServerError)
+			return
+		}
+
+		klog.Infof("OTA upgrades approval on node %s is revoked from %s", nodeName, r.RemoteAddr)
+		WriteJSONResponse(w, []byte(fmt.Sprintf("OTA upgrades approval on node %v is revoked", nodeName)))
+	})
+}
+
+// patchApproval sets the approval annotations of node, the annotations are removed if they are nil.
+func patchApproval(clientset kubernetes.Interface, nodeName string, approver, until interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				daemonpodupdater.OTAApprovedByAnnotation:    approver,
+				daemonpodupdater.OTAApprovedUntilAnnotation: until,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // applyUpdate execute pod update process by deleting pod under OnDelete update strategy
 func applyUpdate(clientset kubernetes.Interface, namespace, podName, nodeName string) (error, bool) {
 	pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
@@ -130,6 +247,14 @@ func applyUpdate(clientset kubernetes.Interface, namespace, podName, nodeName st
 		return nil, false
 	}
 
+	// Pod will not be updated when the new revision isn't signed by the fleet signing key
+	if signatureEnforced() {
+		if err := verifyUpgrade(clientset, pod); err != nil {
+			klog.Errorf("Pod: %v/%v can not be updated, %v", namespace, podName, err)
+			return nil, false
+		}
+	}
+
 	klog.V(5).Infof("Pod: %v/%v is updatable", namespace, podName)
 	err = clientset.CoreV1().Pods(namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
 	if err != nil {
@@ -141,6 +266,28 @@ func applyUpdate(clientset kubernetes.Interface, namespace, podName, nodeName st
 	return nil, true
 }
 
+// verifyUpgrade verifies the signature of the new revision, which is recorded in the daemonset that
+// owns the pod by `yurtadm sign`. The payload is computed from the pod template of daemonset.
+func verifyUpgrade(clientset kubernetes.Interface, pod *corev1.Pod) error {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil || ref.Kind != "DaemonSet" {
+		return fmt.Errorf("pod is not owned by daemonset")
+	}
+	ds, err := clientset.AppsV1().DaemonSets(pod.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if ds.UID != ref.UID {
+		return fmt.Errorf("daemonset %s/%s is not the owner of pod", ds.Namespace, ds.Name)
+	}
+
+	payload, err := signature.TemplatePayload(ds.Namespace, ds.Name, &ds.Spec.Template)
+	if err != nil {
+		return err
+	}
+	return verifyPayload(payload, ds.Annotations[daemonpodupdater.OTAPayloadSignatureAnnotation])
+}
+
 // Derived from kubelet encodePods
 func encodePods(podList *corev1.PodList) (data []byte, err error) {
 	codec := scheme.Codecs.LegacyCodec(runtimescheme.GroupVersion{Group: corev1.GroupName, Version: "v1"})
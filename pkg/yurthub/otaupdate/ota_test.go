@@ -17,17 +17,27 @@ limitations under the License.
 package otaupdate
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/kubernetes/fake"
 
 	"github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater"
+	"github.com/openyurtio/openyurt/pkg/node-servant/signature"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/healthchecker"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/rest"
@@ -109,6 +119,19 @@ func TestGetPods(t *testing.T) {
 
 	expectedCode := http.StatusOK
 	assert.Equal(t, expectedCode, rr.Code)
+
+	req, err = http.NewRequest("GET", "/openyurt.io/v1/ota/pods", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+
+	GetUpgradablePods(sWrapper).ServeHTTP(rr, req)
+
+	assert.Equal(t, expectedCode, rr.Code)
+	assert.Contains(t, rr.Body.String(), updatablePod.Name)
+	assert.NotContains(t, rr.Body.String(), notUpdatablePod.Name)
+	assert.NotContains(t, rr.Body.String(), normalPod.Name)
 }
 
 func TestUpdatePod(t *testing.T) {
@@ -163,6 +186,171 @@ func TestUpdatePod(t *testing.T) {
 
 }
 
+func TestUpdatePodWithSignature(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureEnforced = func() bool { return true }
+	verifyPayload = func(payload []byte, sig string) error {
+		raw, err := base64.StdEncoding.DecodeString(sig)
+		if err != nil || !ed25519.Verify(pub, payload, raw) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+	defer func() {
+		signatureEnforced = signature.Enforced
+		verifyPayload = signature.VerifyPayload
+	}()
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ds",
+			Namespace: metav1.NamespaceDefault,
+			UID:       types.UID("ds-uid"),
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "foo", Image: "foo/bar:v2"}},
+				},
+			},
+		},
+	}
+	payload, err := signature.TemplatePayload(ds.Namespace, ds.Name, &ds.Spec.Template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := ds.DeepCopy()
+	tampered.Spec.Template.Spec.Containers[0].Image = "evil/bar:v2"
+
+	tests := []struct {
+		name         string
+		ds           *appsv1.DaemonSet
+		sig          string
+		podSig       string
+		expectedCode int
+	}{
+		{
+			name:         "signed payload",
+			ds:           ds,
+			sig:          signature.SignPayload(key, payload),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unsigned payload",
+			ds:           ds,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "template changed after signing",
+			ds:           tampered,
+			sig:          signature.SignPayload(key, payload),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "signature on pod is ignored",
+			ds:           ds,
+			podSig:       signature.SignPayload(key, payload),
+			expectedCode: http.StatusForbidden,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds := test.ds.DeepCopy()
+			if len(test.sig) != 0 {
+				ds.Annotations = map[string]string{daemonpodupdater.OTAPayloadSignatureAnnotation: test.sig}
+			}
+			pod := newPodWithCondition("pod", corev1.ConditionTrue)
+			pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))}
+			if len(test.podSig) != 0 {
+				pod.Annotations = map[string]string{daemonpodupdater.OTAPayloadSignatureAnnotation: test.podSig}
+			}
+			clientset := fake.NewSimpleClientset(ds, pod)
+
+			req, err := http.NewRequest("POST", "/openyurt.io/v1/namespaces/default/pods/pod/update", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = mux.SetURLVars(req, map[string]string{"ns": "default", "podname": "pod"})
+			rr := httptest.NewRecorder()
+
+			UpdatePod(clientset, "").ServeHTTP(rr, req)
+
+			assert.Equal(t, test.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestApproveUpgrades(t *testing.T) {
+	tests := []struct {
+		reqURL           string
+		user             user.Info
+		expectedCode     int
+		expectedApprover string
+	}{
+		{
+			reqURL:           "/openyurt.io/v1/ota/approval",
+			user:             &user.DefaultInfo{Name: "admin"},
+			expectedCode:     http.StatusOK,
+			expectedApprover: "admin",
+		},
+		{
+			reqURL:           "/openyurt.io/v1/ota/approval?ttl=2h&approver=someone-else",
+			user:             &user.DefaultInfo{Name: "admin"},
+			expectedCode:     http.StatusOK,
+			expectedApprover: "admin",
+		},
+		{
+			reqURL:       "/openyurt.io/v1/ota/approval?approver=admin",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			reqURL:       "/openyurt.io/v1/ota/approval?ttl=-1h",
+			user:         &user.DefaultInfo{Name: "admin"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		clientset := fake.NewSimpleClientset(node)
+
+		req, err := http.NewRequest("POST", test.reqURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.user != nil {
+			req = req.WithContext(request.WithUser(req.Context(), test.user))
+		}
+		rr := httptest.NewRecorder()
+		ApproveUpgrades(clientset, node.Name).ServeHTTP(rr, req)
+		assert.Equal(t, test.expectedCode, rr.Code)
+
+		got, err := clientset.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, ok := daemonpodupdater.OTAApprovalOf(got, time.Now())
+		assert.Equal(t, test.expectedCode == http.StatusOK, ok)
+		assert.Equal(t, test.expectedApprover, got.Annotations[daemonpodupdater.OTAApprovedByAnnotation])
+
+		if test.expectedCode != http.StatusOK {
+			continue
+		}
+		rr = httptest.NewRecorder()
+		RevokeUpgrades(clientset, node.Name).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		got, err = clientset.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, ok = daemonpodupdater.OTAApprovalOf(got, time.Now())
+		assert.False(t, ok)
+	}
+}
+
 func TestHealthyCheck(t *testing.T) {
 	fakeHealthchecker := healthchecker.NewFakeChecker(false, nil)
 
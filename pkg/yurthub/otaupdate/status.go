@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otaupdate
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater"
+	podutil "github.com/openyurtio/openyurt/pkg/controller/daemonpodupdater/kubernetes"
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+)
+
+// UpgradePhase is the phase of the OTA upgrade of a daemonset pod on the node
+type UpgradePhase string
+
+const (
+	// UpgradePending means a new revision is staged and the upgrade is not triggered
+	UpgradePending UpgradePhase = "Pending"
+	// UpgradeInProgress means the old pod is deleted and the new pod is not ready
+	UpgradeInProgress UpgradePhase = "Upgrading"
+	// UpgradeSucceeded means the pod of the latest revision is ready
+	UpgradeSucceeded UpgradePhase = "Succeeded"
+	// UpgradeFailed means the containers of the new pod can not be started
+	UpgradeFailed UpgradePhase = "Failed"
+)
+
+// failedWaitingReasons are the reasons of waiting containers which can not be recovered without
+// a new revision, the upgrade should be rolled back by updating the daemonset again.
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// UpgradeStatus is the status of the OTA upgrade of a daemonset on the node
+type UpgradeStatus struct {
+	Namespace string       `json:"namespace"`
+	DaemonSet string       `json:"daemonSet"`
+	Pod       string       `json:"pod,omitempty"`
+	Revision  string       `json:"revision,omitempty"`
+	Phase     UpgradePhase `json:"phase"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+// GetUpgradeStatus return the OTA upgrade status of a specific daemonset(namespace/dsname) on
+// the current node, the status is calculated from local cache, so the result of an upgrade and
+// whether it should be rolled back can be queried when edge is disconnected to cloud.
+func GetUpgradeStatus(store cachemanager.StorageWrapper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		namespace := params["ns"]
+		dsName := params["dsname"]
+
+		pods, err := listCachedPods(store)
+		if err != nil {
+			klog.Errorf("Get pod list failed, %v", err)
+			WriteErr(w, "Get pod list failed", http.StatusInternalServerError)
+			return
+		}
+
+		status, ok := upgradeStatusOf(pods, namespace, dsName)
+		if !ok {
+			WriteErr(w, "No pod of daemonset on the node", http.StatusNotFound)
+			return
+		}
+
+		data, err := json.Marshal(status)
+		if err != nil {
+			klog.Errorf("Encode upgrade status failed, %v", err)
+			WriteErr(w, "Encode upgrade status failed", http.StatusInternalServerError)
+			return
+		}
+		WriteJSONResponse(w, data)
+	})
+}
+
+// upgradeStatusOf calculates the upgrade status from the pods of daemonset, ok is false if the
+// daemonset has no pod on the node.
+func upgradeStatusOf(pods []*corev1.Pod, namespace, dsName string) (*UpgradeStatus, bool) {
+	var current *corev1.Pod
+	deleting := false
+	for _, pod := range pods {
+		if pod.Namespace != namespace || !isOwnedByDaemonSet(pod, dsName) {
+			continue
+		}
+		if pod.DeletionTimestamp != nil {
+			deleting = true
+			continue
+		}
+		if current == nil || current.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			current = pod
+		}
+	}
+	if current == nil {
+		if !deleting {
+			return nil, false
+		}
+		return &UpgradeStatus{Namespace: namespace, DaemonSet: dsName, Phase: UpgradeInProgress}, true
+	}
+
+	status := &UpgradeStatus{
+		Namespace: namespace,
+		DaemonSet: dsName,
+		Pod:       current.Name,
+		Revision:  current.Labels[appsv1.DefaultDaemonSetUniqueLabelKey],
+	}
+	switch {
+	case daemonpodupdater.IsPodUpdatable(current):
+		status.Phase = UpgradePending
+	case podutil.IsPodReady(current):
+		status.Phase = UpgradeSucceeded
+	default:
+		status.Phase = UpgradeInProgress
+		if reason := failedReasonOf(current); len(reason) != 0 {
+			status.Phase = UpgradeFailed
+			status.Reason = reason
+		}
+	}
+	return status, true
+}
+
+func isOwnedByDaemonSet(pod *corev1.Pod, dsName string) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" && ref.Name == dsName {
+			return true
+		}
+	}
+	return false
+}
+
+func failedReasonOf(pod *corev1.Pod) string {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && failedWaitingReasons[cs.State.Waiting.Reason] {
+			return cs.State.Waiting.Reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package otaupdate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDaemonSetPod(podName, revision string, created time.Time, needUpgrade, ready bool) *corev1.Pod {
+	pod := newPod(podName)
+	pod.CreationTimestamp = metav1.NewTime(created)
+	pod.Labels = map[string]string{appsv1.DefaultDaemonSetUniqueLabelKey: revision}
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}
+	if needUpgrade {
+		SetPodUpgradeCondition(pod, corev1.ConditionTrue)
+	} else {
+		SetPodUpgradeCondition(pod, corev1.ConditionFalse)
+	}
+	if ready {
+		pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue})
+	}
+	return pod
+}
+
+func TestUpgradeStatusOf(t *testing.T) {
+	now := time.Now()
+	deletingPod := newDaemonSetPod("old", "v1", now.Add(-time.Hour), true, true)
+	deletingPod.DeletionTimestamp = &metav1.Time{Time: now}
+	crashingPod := newDaemonSetPod("new", "v2", now, false, false)
+	crashingPod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}}
+
+	tests := []struct {
+		name           string
+		pods           []*corev1.Pod
+		expectedOK     bool
+		expectedStatus UpgradeStatus
+	}{
+		{
+			name:       "no pod",
+			pods:       []*corev1.Pod{newPod("other")},
+			expectedOK: false,
+		},
+		{
+			name:       "upgrade pending",
+			pods:       []*corev1.Pod{newDaemonSetPod("old", "v1", now, true, true)},
+			expectedOK: true,
+			expectedStatus: UpgradeStatus{
+				Namespace: "default", DaemonSet: "ds", Pod: "old", Revision: "v1", Phase: UpgradePending,
+			},
+		},
+		{
+			name:       "old pod deleting",
+			pods:       []*corev1.Pod{deletingPod},
+			expectedOK: true,
+			expectedStatus: UpgradeStatus{
+				Namespace: "default", DaemonSet: "ds", Phase: UpgradeInProgress,
+			},
+		},
+		{
+			name:       "new pod not ready",
+			pods:       []*corev1.Pod{deletingPod, newDaemonSetPod("new", "v2", now, false, false)},
+			expectedOK: true,
+			expectedStatus: UpgradeStatus{
+				Namespace: "default", DaemonSet: "ds", Pod: "new", Revision: "v2", Phase: UpgradeInProgress,
+			},
+		},
+		{
+			name:       "new pod crashing",
+			pods:       []*corev1.Pod{crashingPod},
+			expectedOK: true,
+			expectedStatus: UpgradeStatus{
+				Namespace: "default", DaemonSet: "ds", Pod: "new", Revision: "v2", Phase: UpgradeFailed, Reason: "CrashLoopBackOff",
+			},
+		},
+		{
+			name:       "new pod ready",
+			pods:       []*corev1.Pod{newDaemonSetPod("new", "v2", now, false, true)},
+			expectedOK: true,
+			expectedStatus: UpgradeStatus{
+				Namespace: "default", DaemonSet: "ds", Pod: "new", Revision: "v2", Phase: UpgradeSucceeded,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, ok := upgradeStatusOf(test.pods, "default", "ds")
+			assert.Equal(t, test.expectedOK, ok)
+			if ok {
+				assert.Equal(t, test.expectedStatus, *status)
+			}
+		})
+	}
+}
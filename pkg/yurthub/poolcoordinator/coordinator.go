@@ -41,6 +41,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/cmd/yurthub/app/config"
+	"github.com/openyurtio/openyurt/pkg/controller/poolcoordinator/constant"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/healthchecker"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/meta"
@@ -51,6 +52,8 @@ import (
 	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator/constants"
 	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator/resources"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/bolt"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/etcd"
 	"github.com/openyurtio/openyurt/pkg/yurthub/transport"
@@ -99,6 +102,8 @@ type coordinator struct {
 	certMgr           *certmanager.CertManager
 	// cloudCAFilePath is the file path of cloud kubernetes cluster CA cert.
 	cloudCAFilePath string
+	// nodeName is recorded in the node leases which are delegated by this yurthub.
+	nodeName string
 	// cloudHealthChecker is health checker of cloud APIServers. It is used to
 	// pick a healthy cloud APIServer to proxy heartbeats.
 	cloudHealthChecker   healthchecker.MultipleBackendsHealthChecker
@@ -146,6 +151,7 @@ func NewCoordinator(
 	coordinator := &coordinator{
 		ctx:                ctx,
 		cloudCAFilePath:    cfg.CertManager.GetCaFile(),
+		nodeName:           cfg.NodeName,
 		cloudHealthChecker: cloudHealthChecker,
 		etcdStorageCfg:     etcdStorageCfg,
 		restConfigMgr:      restMgr,
@@ -247,29 +253,23 @@ func (coordinator *coordinator) Run() {
 					klog.Errorf("failed to create pool scoped cache store and manager, %v", err)
 					continue
 				}
+				// etcd clients of the previous pool cache store should be released when it is replaced
+				needCancelEtcdStorage = true
 
 				nodeLeaseProxyClient, err := coordinator.newNodeLeaseProxyClient()
 				if err != nil {
 					klog.Errorf("cloud not get cloud lease client when becoming leader yurthub, %v", err)
+					cancelEtcdStorage()
 					continue
 				}
 				klog.Infof("coordinator newCloudLeaseClient success.")
 				if err := coordinator.poolCacheSyncManager.EnsureStart(); err != nil {
 					klog.Errorf("failed to sync pool-scoped resource, %v", err)
+					cancelEtcdStorage()
 					continue
 				}
 				klog.Infof("coordinator poolCacheSyncManager has ensure started")
-				coordinator.delegateNodeLeaseManager.EnsureStartWithHandler(cache.FilteringResourceEventHandler{
-					FilterFunc: ifDelegateHeartBeat,
-					Handler: cache.ResourceEventHandlerFuncs{
-						AddFunc: func(obj interface{}) {
-							coordinator.delegateNodeLease(nodeLeaseProxyClient, obj)
-						},
-						UpdateFunc: func(_, newObj interface{}) {
-							coordinator.delegateNodeLease(nodeLeaseProxyClient, newObj)
-						},
-					},
-				})
+				coordinator.delegateNodeLeaseManager.EnsureStartWithHandler(coordinator.delegateNodeLeaseHandler(nodeLeaseProxyClient))
 				coordinator.poolCacheSyncedDetector.EnsureStart()
 
 				if coordinator.needUploadLocalCache {
@@ -279,15 +279,32 @@ func (coordinator *coordinator) Run() {
 						needUploadLocalCache = false
 					}
 				}
-			case FollowerHub:
+			case FollowerHub, DelegateHub:
+				var nodeLeaseProxyClient coordclientset.LeaseInterface
+				if electorStatus == DelegateHub {
+					nodeLeaseProxyClient, err = coordinator.newNodeLeaseProxyClient()
+					if err != nil {
+						klog.Errorf("cloud not get cloud lease client when becoming delegate yurthub, %v", err)
+						continue
+					}
+				}
+
 				poolCacheManager, etcdStorage, cancelEtcdStorage, err = coordinator.buildPoolCacheStore()
 				if err != nil {
 					klog.Errorf("failed to create pool scoped cache store and manager, %v", err)
 					continue
 				}
+				// etcd clients of the previous pool cache store should be released when it is replaced
+				needCancelEtcdStorage = true
 
 				coordinator.poolCacheSyncManager.EnsureStop()
-				coordinator.delegateNodeLeaseManager.EnsureStop()
+				if electorStatus == DelegateHub {
+					// delegate yurthub forwards node leases as the leader yurthub does, so node leases
+					// of larger pools can still be delegated if the leader yurthub is overloaded or lost.
+					coordinator.delegateNodeLeaseManager.EnsureStartWithHandler(coordinator.delegateNodeLeaseHandler(nodeLeaseProxyClient))
+				} else {
+					coordinator.delegateNodeLeaseManager.EnsureStop()
+				}
 				coordinator.poolCacheSyncedDetector.EnsureStart()
 
 				if coordinator.needUploadLocalCache {
@@ -303,8 +320,8 @@ func (coordinator *coordinator) Run() {
 			// after acquire lock to avoid race condition.
 			// Because the caller of IsReady() may be concurrent.
 			coordinator.Lock()
-			if needCancelEtcdStorage {
-				cancelEtcdStorage()
+			if needCancelEtcdStorage && coordinator.cancelEtcdStorage != nil {
+				coordinator.cancelEtcdStorage()
 			}
 			coordinator.electStatus = electorStatus
 			coordinator.poolCacheManager = poolCacheManager
@@ -402,6 +419,23 @@ func (coordinator *coordinator) uploadLocalCache(etcdStore storage.Store) error
 	return nil
 }
 
+// delegateNodeLeaseHandler returns the handler which delegates node leases with DelegateHeartBeat
+// annotation in pool-coordinator to cloud. The node conditions in the annotations of lease are
+// forwarded with the lease, and applied to the node by yurt-controller-manager.
+func (coordinator *coordinator) delegateNodeLeaseHandler(cloudLeaseClient coordclientset.LeaseInterface) cache.FilteringResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: ifDelegateHeartBeat,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				coordinator.delegateNodeLease(cloudLeaseClient, obj)
+			},
+			UpdateFunc: func(_, newObj interface{}) {
+				coordinator.delegateNodeLease(cloudLeaseClient, newObj)
+			},
+		},
+	}
+}
+
 func (coordinator *coordinator) delegateNodeLease(cloudLeaseClient coordclientset.LeaseInterface, obj interface{}) {
 	newLease := obj.(*coordinationv1.Lease)
 	for i := 0; i < leaseDelegateRetryTimes; i++ {
@@ -416,7 +450,18 @@ func (coordinator *coordinator) delegateNodeLease(cloudLeaseClient coordclientse
 			}
 		}
 
-		cloudLease.Annotations = newLease.Annotations
+		// multiple delegates may forward the same node lease, skip it if the lease
+		// has been forwarded by other delegate.
+		if !isNewerRenewTime(newLease.Spec.RenewTime, cloudLease.Spec.RenewTime) {
+			klog.V(4).Infof("lease %s has been delegated by %s, skip it", newLease.Name, cloudLease.Annotations[constant.DelegatedBy])
+			break
+		}
+
+		cloudLease.Annotations = make(map[string]string, len(newLease.Annotations)+1)
+		for k, v := range newLease.Annotations {
+			cloudLease.Annotations[k] = v
+		}
+		cloudLease.Annotations[constant.DelegatedBy] = coordinator.nodeName
 		cloudLease.Spec.RenewTime = newLease.Spec.RenewTime
 		if updatedLease, err := cloudLeaseClient.Update(coordinator.ctx, cloudLease, metav1.UpdateOptions{}); err != nil {
 			klog.Errorf("failed to update lease %s at cloud, %v", newLease.Name, err)
@@ -611,7 +656,13 @@ func (l *localCacheUploader) resourcesToUpload() map[storage.Key][]byte {
 				klog.Errorf("failed to read local cache of key %s, %v", k.Key(), err)
 				continue
 			}
-			buildInfo, err := disk.ExtractKeyBuildInfo(k)
+			// objects in pool cache are not compressed, because their rv are parsed by etcd storage
+			buf, err = compression.Decompress(buf)
+			if err != nil {
+				klog.Errorf("failed to decompress local cache of key %s, %v", k.Key(), err)
+				continue
+			}
+			buildInfo, err := extractKeyBuildInfo(k)
 			if err != nil {
 				klog.Errorf("failed to extract key build info from local cache of key %s, %v", k.Key(), err)
 				continue
@@ -713,6 +764,17 @@ func getRv(objBytes []byte) (uint64, error) {
 	return rv, nil
 }
 
+// isNewerRenewTime returns true if renewTime is later than the current renewTime.
+func isNewerRenewTime(renewTime, current *metav1.MicroTime) bool {
+	if renewTime == nil {
+		return false
+	}
+	if current == nil {
+		return true
+	}
+	return renewTime.After(current.Time)
+}
+
 func ifDelegateHeartBeat(obj interface{}) bool {
 	lease, ok := obj.(*coordinationv1.Lease)
 	if !ok {
@@ -758,3 +820,11 @@ func buildDynamicClientWithUserAgent(proxyAddr string, userAgent string) (dynami
 	}
 	return client, nil
 }
+
+// extractKeyBuildInfo extracts the KeyBuildInfo from the key of local storage, which is either disk or bolt storage.
+func extractKeyBuildInfo(key storage.Key) (*storage.KeyBuildInfo, error) {
+	if buildInfo, err := disk.ExtractKeyBuildInfo(key); err != storage.ErrUnrecognizedKey {
+		return buildInfo, err
+	}
+	return bolt.ExtractKeyBuildInfo(key)
+}
@@ -237,3 +237,77 @@ func TestIfInformerSyncLease(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNewerRenewTime(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		Description string
+		RenewTime   *v1.MicroTime
+		Current     *v1.MicroTime
+		Expect      bool
+	}{
+		{
+			Description: "return false if renew time is nil",
+			Current:     &v1.MicroTime{Time: now},
+			Expect:      false,
+		},
+		{
+			Description: "return true if current renew time is nil",
+			RenewTime:   &v1.MicroTime{Time: now},
+			Expect:      true,
+		},
+		{
+			Description: "return false if lease has been delegated by other delegate",
+			RenewTime:   &v1.MicroTime{Time: now},
+			Current:     &v1.MicroTime{Time: now},
+			Expect:      false,
+		},
+		{
+			Description: "return true if lease is renewed",
+			RenewTime:   &v1.MicroTime{Time: now.Add(time.Second)},
+			Current:     &v1.MicroTime{Time: now},
+			Expect:      true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			got := isNewerRenewTime(c.RenewTime, c.Current)
+			if got != c.Expect {
+				t.Errorf("unexpected value for %s, want: %v, got: %v", c.Description, c.Expect, got)
+			}
+		})
+	}
+}
+
+func TestDelegateSlot(t *testing.T) {
+	he := &HubElector{}
+	canceled := make([]bool, 3)
+	for i := range canceled {
+		i := i
+		he.delegateCancels = append(he.delegateCancels, func() { canceled[i] = true })
+	}
+	he.inDelegateElecting = true
+
+	if !he.acquireDelegateSlot(2) {
+		t.Fatalf("expect slot 2 is acquired")
+	}
+	if !canceled[0] || canceled[1] || !canceled[2] {
+		t.Errorf("expect elections of other slots are canceled, got %v", canceled)
+	}
+	if he.acquireDelegateSlot(1) {
+		t.Errorf("expect at most one slot is held")
+	}
+	if he.releaseDelegateSlot(1) {
+		t.Errorf("expect slot 1 is not released because it is not held")
+	}
+	if !he.releaseDelegateSlot(2) {
+		t.Errorf("expect yurthub turns back to follower after slot 2 is lost")
+	}
+	if he.inDelegateElecting || he.delegateSlot != 0 {
+		t.Errorf("expect delegate election is restarted after slot is lost")
+	}
+	if delegateLockName("yurthub", 2) != "yurthub-delegate-2" {
+		t.Errorf("unexpected delegate lock name %s", delegateLockName("yurthub", 2))
+	}
+}
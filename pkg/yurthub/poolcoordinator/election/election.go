@@ -0,0 +1,334 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ElectionPath is the path of hub server for electing the leader of pool-scoped workloads.
+	ElectionPath = "/v1/elections/{namespace}/{name}"
+	// leaseNamespace is the namespace in pool-coordinator where the leases of elections are stored.
+	leaseNamespace = "kube-system"
+	// leaseNamePrefix is the prefix of the leases of elections, so they don't conflict with other leases.
+	leaseNamePrefix = "pool-election."
+
+	defaultLeaseDurationSeconds = 15
+	maxLeaseDurationSeconds     = 3600
+	requestTimeout              = 5 * time.Second
+)
+
+// Request is the body of election request, the leadership of Identity is acquired or renewed by POST,
+// and released by DELETE.
+type Request struct {
+	Identity             string `json:"identity"`
+	LeaseDurationSeconds int32  `json:"leaseDurationSeconds,omitempty"`
+}
+
+// Status is the state of an election.
+type Status struct {
+	HolderIdentity       string       `json:"holderIdentity"`
+	IsLeader             bool         `json:"isLeader"`
+	LeaseDurationSeconds int32        `json:"leaseDurationSeconds"`
+	AcquireTime          *metav1.Time `json:"acquireTime,omitempty"`
+	RenewTime            *metav1.Time `json:"renewTime,omitempty"`
+	LeaderTransitions    int32        `json:"leaderTransitions"`
+}
+
+// observedLease records when the renew time of a lease is observed by this hub. a lease is expired if it's
+// not renewed for its duration since observed, so the election never depends on the clocks of nodes.
+type observedLease struct {
+	holder     string
+	renewTime  time.Time
+	observedAt time.Time
+}
+
+// Service elects the leaders of pool-scoped workloads by the leases in pool-coordinator, so the leader of
+// a nodepool is elected even when the cloud is unreachable. The workloads acquire and renew leadership by
+// calling POST periodically within the lease duration like the leader election of client-go, every
+// yurthub of the nodepool serves the same elections because they share the pool-coordinator.
+type Service struct {
+	sync.Mutex
+	client   kubernetes.Interface
+	healthy  func() bool
+	observed map[string]observedLease
+	now      func() time.Time
+}
+
+// NewService creates a Service, the elections are unavailable until the pool-coordinator is set by SetBackend.
+func NewService() *Service {
+	return &Service{
+		observed: make(map[string]observedLease),
+		now:      time.Now,
+	}
+}
+
+// SetBackend sets the client of pool-coordinator and the function for checking whether it's healthy.
+func (s *Service) SetBackend(client kubernetes.Interface, healthy func() bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.client = client
+	s.healthy = healthy
+}
+
+func (s *Service) backend() (kubernetes.Interface, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.client == nil || (s.healthy != nil && !s.healthy()) {
+		return nil, errors.New("pool-coordinator is not ready for elections")
+	}
+	return s.client, nil
+}
+
+// ServeHTTP handles GET for the status, POST for acquiring or renewing and DELETE for releasing leadership.
+func (s *Service) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	namespace, name := vars["namespace"], vars["name"]
+	leaseName := leaseNamePrefix + namespace + "." + name
+	if errs := validation.IsDNS1123Label(namespace); len(errs) != 0 {
+		http.Error(w, fmt.Sprintf("namespace %s is invalid, %v", namespace, errs), http.StatusBadRequest)
+		return
+	}
+	if errs := validation.IsDNS1123Subdomain(leaseName); len(errs) != 0 {
+		http.Error(w, fmt.Sprintf("election name %s is invalid, %v", name, errs), http.StatusBadRequest)
+		return
+	}
+
+	r := &Request{}
+	if req.Method == http.MethodGet {
+		r.Identity = req.URL.Query().Get("identity")
+	} else {
+		if err := json.NewDecoder(req.Body).Decode(r); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode election request, %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(r.Identity) == 0 {
+			http.Error(w, "identity of election request is empty", http.StatusBadRequest)
+			return
+		}
+		if r.LeaseDurationSeconds == 0 {
+			r.LeaseDurationSeconds = defaultLeaseDurationSeconds
+		}
+		if r.LeaseDurationSeconds < 0 || r.LeaseDurationSeconds > maxLeaseDurationSeconds {
+			http.Error(w, fmt.Sprintf("lease duration should be in (0, %d] seconds", maxLeaseDurationSeconds), http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, err := s.backend()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	var status *Status
+	switch req.Method {
+	case http.MethodGet:
+		status, err = s.get(ctx, client, leaseName, r.Identity)
+	case http.MethodPost:
+		status, err = s.acquireOrRenew(ctx, client, leaseName, r)
+	case http.MethodDelete:
+		status, err = s.release(ctx, client, leaseName, r.Identity)
+	default:
+		http.Error(w, fmt.Sprintf("method %s is not supported", req.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		klog.Errorf("failed to %s election %s/%s, %v", req.Method, namespace, name, err)
+		code := http.StatusInternalServerError
+		if status, ok := err.(apierrors.APIStatus); ok {
+			code = int(status.Status().Code)
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		klog.Errorf("failed to write election status, %v", err)
+	}
+}
+
+func (s *Service) get(ctx context.Context, client kubernetes.Interface, leaseName, identity string) (*Status, error) {
+	lease, err := client.CoordinationV1().Leases(leaseNamespace).Get(ctx, leaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &Status{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	s.observe(lease)
+	return statusOf(lease, identity), nil
+}
+
+// acquireOrRenew takes the leadership if the lease is not held or expired, and renews it if it's held by the identity.
+func (s *Service) acquireOrRenew(ctx context.Context, client kubernetes.Interface, leaseName string, r *Request) (*Status, error) {
+	leases := client.CoordinationV1().Leases(leaseNamespace)
+	now := metav1.NewMicroTime(s.now())
+	lease, err := leases.Get(ctx, leaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: leaseName, Namespace: leaseNamespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &r.Identity,
+				LeaseDurationSeconds: &r.LeaseDurationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if lease, err = leases.Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			return s.conflictStatus(ctx, client, leaseName, r.Identity, err)
+		}
+		klog.Infof("%s became the leader of election %s", r.Identity, leaseName)
+		s.observe(lease)
+		return statusOf(lease, r.Identity), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	holder := s.observe(lease)
+	if len(holder.holder) != 0 && holder.holder != r.Identity && !s.expired(holder, lease) {
+		return statusOf(lease, r.Identity), nil
+	}
+
+	lease = lease.DeepCopy()
+	if holder.holder != r.Identity {
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions
+		}
+		if len(holder.holder) != 0 {
+			transitions++
+		}
+		lease.Spec.HolderIdentity = &r.Identity
+		lease.Spec.AcquireTime = &now
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.LeaseDurationSeconds = &r.LeaseDurationSeconds
+	lease.Spec.RenewTime = &now
+	updated, err := leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if err != nil {
+		return s.conflictStatus(ctx, client, leaseName, r.Identity, err)
+	}
+	if holder.holder != r.Identity {
+		klog.Infof("%s became the leader of election %s, previous leader is %q", r.Identity, leaseName, holder.holder)
+	}
+	s.observe(updated)
+	return statusOf(updated, r.Identity), nil
+}
+
+// conflictStatus returns the current status if the lease is updated by others at the same time.
+func (s *Service) conflictStatus(ctx context.Context, client kubernetes.Interface, leaseName, identity string, err error) (*Status, error) {
+	if !apierrors.IsConflict(err) && !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return s.get(ctx, client, leaseName, identity)
+}
+
+// release gives up the leadership if it's held by the identity, the followers can take over immediately.
+func (s *Service) release(ctx context.Context, client kubernetes.Interface, leaseName, identity string) (*Status, error) {
+	leases := client.CoordinationV1().Leases(leaseNamespace)
+	lease, err := leases.Get(ctx, leaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &Status{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != identity {
+		s.observe(lease)
+		return statusOf(lease, identity), nil
+	}
+
+	lease = lease.DeepCopy()
+	now := metav1.NewMicroTime(s.now())
+	empty := ""
+	lease.Spec.HolderIdentity = &empty
+	lease.Spec.RenewTime = &now
+	updated, err := leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if err != nil {
+		return s.conflictStatus(ctx, client, leaseName, identity, err)
+	}
+	klog.Infof("%s released the leadership of election %s", identity, leaseName)
+	s.observe(updated)
+	return statusOf(updated, identity), nil
+}
+
+// observe records the holder and renew time of lease, and returns the observed record.
+func (s *Service) observe(lease *coordinationv1.Lease) observedLease {
+	current := observedLease{}
+	if lease.Spec.HolderIdentity != nil {
+		current.holder = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.RenewTime != nil {
+		current.renewTime = lease.Spec.RenewTime.Time
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	last, ok := s.observed[lease.Name]
+	if ok && last.holder == current.holder && last.renewTime.Equal(current.renewTime) {
+		return last
+	}
+	current.observedAt = s.now()
+	s.observed[lease.Name] = current
+	return current
+}
+
+func (s *Service) expired(observed observedLease, lease *coordinationv1.Lease) bool {
+	duration := time.Duration(defaultLeaseDurationSeconds) * time.Second
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return s.now().After(observed.observedAt.Add(duration))
+}
+
+func statusOf(lease *coordinationv1.Lease, identity string) *Status {
+	status := &Status{}
+	if lease.Spec.HolderIdentity != nil {
+		status.HolderIdentity = *lease.Spec.HolderIdentity
+	}
+	status.IsLeader = len(identity) != 0 && status.HolderIdentity == identity
+	if lease.Spec.LeaseDurationSeconds != nil {
+		status.LeaseDurationSeconds = *lease.Spec.LeaseDurationSeconds
+	}
+	if lease.Spec.AcquireTime != nil {
+		status.AcquireTime = &metav1.Time{Time: lease.Spec.AcquireTime.Time}
+	}
+	if lease.Spec.RenewTime != nil {
+		status.RenewTime = &metav1.Time{Time: lease.Spec.RenewTime.Time}
+	}
+	if lease.Spec.LeaseTransitions != nil {
+		status.LeaderTransitions = *lease.Spec.LeaseTransitions
+	}
+	return status
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package election
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestServer(s *Service) *mux.Router {
+	r := mux.NewRouter()
+	r.Handle(ElectionPath, s).Methods("GET", "POST", "DELETE")
+	return r
+}
+
+func doElection(t *testing.T, r *mux.Router, method, path string, body *Request) (int, *Status) {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	status := &Status{}
+	if resp.Code == http.StatusOK {
+		if err := json.Unmarshal(resp.Body.Bytes(), status); err != nil {
+			t.Fatalf("failed to decode status, %v", err)
+		}
+	}
+	return resp.Code, status
+}
+
+func TestElection(t *testing.T) {
+	now := time.Now()
+	s := NewService()
+	s.now = func() time.Time { return now }
+	r := newTestServer(s)
+	path := "/v1/elections/default/camera-gateway"
+
+	if code, _ := doElection(t, r, http.MethodPost, path, &Request{Identity: "a"}); code != http.StatusServiceUnavailable {
+		t.Errorf("expect %d without pool-coordinator, but got %d", http.StatusServiceUnavailable, code)
+	}
+
+	healthy := true
+	s.SetBackend(fake.NewSimpleClientset(), func() bool { return healthy })
+
+	_, status := doElection(t, r, http.MethodPost, path, &Request{Identity: "a", LeaseDurationSeconds: 10})
+	if !status.IsLeader || status.HolderIdentity != "a" {
+		t.Errorf("expect a is the leader, but got %+v", status)
+	}
+
+	_, status = doElection(t, r, http.MethodPost, path, &Request{Identity: "b", LeaseDurationSeconds: 10})
+	if status.IsLeader || status.HolderIdentity != "a" {
+		t.Errorf("expect b is a follower, but got %+v", status)
+	}
+
+	now = now.Add(5 * time.Second)
+	_, status = doElection(t, r, http.MethodPost, path, &Request{Identity: "a", LeaseDurationSeconds: 10})
+	if !status.IsLeader {
+		t.Errorf("expect a renews the leadership, but got %+v", status)
+	}
+
+	// a is not renewed for the lease duration
+	now = now.Add(11 * time.Second)
+	_, status = doElection(t, r, http.MethodPost, path, &Request{Identity: "b", LeaseDurationSeconds: 10})
+	if !status.IsLeader || status.LeaderTransitions != 1 {
+		t.Errorf("expect b takes over the leadership, but got %+v", status)
+	}
+
+	_, status = doElection(t, r, http.MethodGet, path+"?identity=a", nil)
+	if status.IsLeader || status.HolderIdentity != "b" {
+		t.Errorf("expect b is the leader, but got %+v", status)
+	}
+
+	_, status = doElection(t, r, http.MethodDelete, path, &Request{Identity: "b"})
+	if status.HolderIdentity != "" {
+		t.Errorf("expect the leadership is released, but got %+v", status)
+	}
+	_, status = doElection(t, r, http.MethodPost, path, &Request{Identity: "a"})
+	if !status.IsLeader {
+		t.Errorf("expect a takes over the released leadership immediately, but got %+v", status)
+	}
+
+	healthy = false
+	if code, _ := doElection(t, r, http.MethodPost, path, &Request{Identity: "a"}); code != http.StatusServiceUnavailable {
+		t.Errorf("expect %d when pool-coordinator is unhealthy, but got %d", http.StatusServiceUnavailable, code)
+	}
+}
+
+func TestInvalidElectionRequest(t *testing.T) {
+	s := NewService()
+	s.SetBackend(fake.NewSimpleClientset(), nil)
+	r := newTestServer(s)
+
+	tests := map[string]struct {
+		path string
+		body *Request
+	}{
+		"empty identity": {
+			path: "/v1/elections/default/foo",
+			body: &Request{},
+		},
+		"invalid namespace": {
+			path: "/v1/elections/Default/foo",
+			body: &Request{Identity: "a"},
+		},
+		"invalid name": {
+			path: "/v1/elections/default/foo_bar",
+			body: &Request{Identity: "a"},
+		},
+		"too long lease duration": {
+			path: "/v1/elections/default/foo",
+			body: &Request{Identity: "a", LeaseDurationSeconds: maxLeaseDurationSeconds + 1},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if code, _ := doElection(t, r, http.MethodPost, tt.path, tt.body); code != http.StatusBadRequest {
+				t.Errorf("expect %d, but got %d", http.StatusBadRequest, code)
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ package poolcoordinator
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
@@ -34,6 +36,9 @@ const (
 	LeaderHub
 	FollowerHub
 	PendingHub
+	// DelegateHub is a follower which also delegates node leases to cloud,
+	// multiple yurthubs can be elected as delegates by holding one of the delegate slots.
+	DelegateHub
 )
 
 type HubElector struct {
@@ -43,6 +48,16 @@ type HubElector struct {
 	electorStatus               chan int32
 	le                          *leaderelection.LeaderElector
 	inElecting                  bool
+
+	// delegateElectors are used to elect yurthubs into the delegate slots, the leader
+	// occupies the first slot, so there are CoordinatorDelegates-1 electors.
+	delegateElectors []*leaderelection.LeaderElector
+	delegateCancels  []context.CancelFunc
+	// lock protects the following fields which are accessed by the callbacks of electors.
+	lock               sync.Mutex
+	isLeader           bool
+	delegateSlot       int
+	inDelegateElecting bool
 }
 
 func NewHubElector(
@@ -76,10 +91,14 @@ func NewHubElector(
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
 				klog.Infof("yurthub of %s became leader", cfg.NodeName)
+				he.setLeader(true)
+				// the leader delegates node leases by itself, so the delegate slot is released
+				he.stopDelegateElecting()
 				he.electorStatus <- LeaderHub
 			},
 			OnStoppedLeading: func() {
 				klog.Infof("yurthub of %s is no more a leader", cfg.NodeName)
+				he.setLeader(false)
 				he.electorStatus <- FollowerHub
 				he.inElecting = false
 			},
@@ -89,6 +108,14 @@ func NewHubElector(
 		return nil, err
 	}
 	he.le = le
+
+	for slot := 1; slot < cfg.CoordinatorDelegates; slot++ {
+		delegateElector, err := he.newDelegateElector(cfg, slot)
+		if err != nil {
+			return nil, err
+		}
+		he.delegateElectors = append(he.delegateElectors, delegateElector)
+	}
 	he.electorStatus <- PendingHub
 
 	return he, nil
@@ -110,9 +137,11 @@ func (he *HubElector) Run(stopCh <-chan struct{}) {
 				cancel()
 				he.inElecting = false
 			}
+			he.stopDelegateElecting()
 			return
 		case <-intervalTicker.C:
 			if !he.coordinatorHealthChecker.IsHealthy() {
+				he.stopDelegateElecting()
 				if he.inElecting && cancel != nil {
 					cancel()
 					he.inElecting = false
@@ -122,6 +151,7 @@ func (he *HubElector) Run(stopCh <-chan struct{}) {
 			}
 
 			if !he.cloudAPIServerHealthChecker.IsHealthy() {
+				he.stopDelegateElecting()
 				if he.inElecting && cancel != nil {
 					cancel()
 					he.inElecting = false
@@ -136,6 +166,7 @@ func (he *HubElector) Run(stopCh <-chan struct{}) {
 				go he.le.Run(ctx)
 				he.inElecting = true
 			}
+			he.startDelegateElecting()
 		}
 	}
 }
@@ -143,3 +174,130 @@ func (he *HubElector) Run(stopCh <-chan struct{}) {
 func (he *HubElector) StatusChan() chan int32 {
 	return he.electorStatus
 }
+
+// newDelegateElector creates the elector for the delegate slot, the lock of slot is released
+// when the election is canceled, so other yurthubs can take over the slot immediately.
+func (he *HubElector) newDelegateElector(cfg *config.YurtHubConfiguration, slot int) (*leaderelection.LeaderElector, error) {
+	rl, err := resourcelock.New(cfg.LeaderElection.ResourceLock,
+		cfg.LeaderElection.ResourceNamespace,
+		delegateLockName(cfg.LeaderElection.ResourceName, slot),
+		he.coordinatorClient.CoreV1(),
+		he.coordinatorClient.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: cfg.NodeName})
+	if err != nil {
+		return nil, err
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            rl,
+		LeaseDuration:   cfg.LeaderElection.LeaseDuration.Duration,
+		RenewDeadline:   cfg.LeaderElection.RenewDeadline.Duration,
+		RetryPeriod:     cfg.LeaderElection.RetryPeriod.Duration,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				if !he.acquireDelegateSlot(slot) {
+					return
+				}
+				klog.Infof("yurthub of %s became delegate of slot %d", cfg.NodeName, slot)
+				he.electorStatus <- DelegateHub
+			},
+			OnStoppedLeading: func() {
+				if !he.releaseDelegateSlot(slot) {
+					return
+				}
+				klog.Infof("yurthub of %s is no more a delegate of slot %d", cfg.NodeName, slot)
+				he.electorStatus <- FollowerHub
+			},
+		},
+	})
+}
+
+// delegateLockName returns the name of lock for the delegate slot.
+func delegateLockName(resourceName string, slot int) string {
+	return fmt.Sprintf("%s-delegate-%d", resourceName, slot)
+}
+
+// IsLeader returns true if the yurthub is the leader of pool
+func (he *HubElector) IsLeader() bool {
+	he.lock.Lock()
+	defer he.lock.Unlock()
+	return he.isLeader
+}
+
+func (he *HubElector) setLeader(isLeader bool) {
+	he.lock.Lock()
+	defer he.lock.Unlock()
+	he.isLeader = isLeader
+}
+
+// startDelegateElecting starts to compete for all delegate slots if the yurthub is neither
+// a leader nor already in delegate election.
+func (he *HubElector) startDelegateElecting() {
+	he.lock.Lock()
+	defer he.lock.Unlock()
+	if len(he.delegateElectors) == 0 || he.isLeader || he.inDelegateElecting {
+		return
+	}
+
+	he.delegateCancels = make([]context.CancelFunc, len(he.delegateElectors))
+	for i := range he.delegateElectors {
+		ctx, cancel := context.WithCancel(context.TODO())
+		he.delegateCancels[i] = cancel
+		go he.delegateElectors[i].Run(ctx)
+	}
+	he.inDelegateElecting = true
+}
+
+// stopDelegateElecting stops all delegate elections and releases the slot held by the yurthub,
+// the caller is responsible for reporting the new status of yurthub.
+func (he *HubElector) stopDelegateElecting() {
+	he.lock.Lock()
+	defer he.lock.Unlock()
+	he.delegateSlot = 0
+	for _, cancel := range he.delegateCancels {
+		if cancel != nil {
+			cancel()
+		}
+	}
+	he.delegateCancels = nil
+	he.inDelegateElecting = false
+}
+
+// acquireDelegateSlot records the slot held by the yurthub and stops competing for other slots,
+// because a yurthub holds one slot at most. It returns false if the yurthub has become the leader.
+func (he *HubElector) acquireDelegateSlot(slot int) bool {
+	he.lock.Lock()
+	defer he.lock.Unlock()
+	if he.isLeader || he.delegateSlot != 0 {
+		return false
+	}
+	he.delegateSlot = slot
+	for i, cancel := range he.delegateCancels {
+		if i+1 != slot && cancel != nil {
+			cancel()
+			he.delegateCancels[i] = nil
+		}
+	}
+	return true
+}
+
+// releaseDelegateSlot clears the slot held by the yurthub when the slot is lost, so the delegate
+// election will be restarted. It returns true if the yurthub should turn back into follower.
+func (he *HubElector) releaseDelegateSlot(slot int) bool {
+	he.lock.Lock()
+	defer he.lock.Unlock()
+	// the slot is not held or has been released by stopDelegateElecting
+	if he.delegateSlot != slot {
+		return false
+	}
+	he.delegateSlot = 0
+	for _, cancel := range he.delegateCancels {
+		if cancel != nil {
+			cancel()
+		}
+	}
+	he.delegateCancels = nil
+	he.inDelegateElecting = false
+	return !he.isLeader
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/events"
+	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/serializer"
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+// WithEventsAggregation records the events which are created or patched by local components into
+// aggregator when cluster is unhealthy, so they are uploaded when cluster is healthy again.
+func WithEventsAggregation(handler http.Handler, aggregator *events.Aggregator, serializerManager *serializer.SerializerManager) http.Handler {
+	eventGVR := corev1.SchemeGroupVersion.WithResource("events")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, _ := apirequest.RequestInfoFrom(req.Context())
+		if info == nil || info.Resource != "events" || len(info.APIGroup) != 0 || len(info.Subresource) != 0 ||
+			(info.Verb != "create" && info.Verb != "patch") {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			klog.Errorf("failed to read body of request %s when cluster is unhealthy, %v", hubutil.ReqString(req), err)
+			util.Err(apierrors.NewBadRequest(err.Error()), w, req)
+			return
+		}
+
+		if info.Verb == "patch" {
+			patchType := types.PatchType(req.Header.Get("Content-Type"))
+			if patchType != types.StrategicMergePatchType && patchType != types.MergePatchType {
+				req.Body = io.NopCloser(bytes.NewReader(data))
+				handler.ServeHTTP(w, req)
+				return
+			}
+			event, err := aggregator.Patch(info.Namespace, info.Name, data)
+			if err != nil {
+				util.Err(apierrors.NewBadRequest(err.Error()), w, req)
+				return
+			}
+			if event == nil {
+				util.Err(apierrors.NewNotFound(eventGVR.GroupResource(), info.Name), w, req)
+				return
+			}
+			warnDeferredWebhooks(w, aggregator.DeferredWebhooks(event.Namespace, event.Name))
+			if err := util.WriteObject(http.StatusOK, event, w, req); err != nil {
+				klog.Errorf("failed to write resp for event patch when cluster is unhealthy, %v", err)
+			}
+			return
+		}
+
+		var event *corev1.Event
+		if s := createSerializer(req, eventGVR, serializerManager); s != nil {
+			if obj, err := s.Decode(data); err == nil {
+				event, _ = obj.(*corev1.Event)
+			}
+		}
+		if event == nil {
+			klog.Errorf("skip aggregating event for request %s when cluster is unhealthy, failed to decode event", hubutil.ReqString(req))
+			req.Body = io.NopCloser(bytes.NewReader(data))
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if len(event.Namespace) == 0 {
+			event.Namespace = info.Namespace
+		}
+
+		klog.V(4).Infof("aggregate event %s/%s when cluster is unhealthy", event.Namespace, event.Name)
+		event = aggregator.Record(event)
+		warnDeferredWebhooks(w, aggregator.DeferredWebhooks(event.Namespace, event.Name))
+		if err := util.WriteObject(http.StatusCreated, event, w, req); err != nil {
+			klog.Errorf("failed to write resp for event creation when cluster is unhealthy, %v", err)
+		}
+	})
+}
+
+// warnDeferredWebhooks tells the client that the admission webhooks of the cloud are deferred
+// until the write is replayed, so the write may still be rejected.
+func warnDeferredWebhooks(w http.ResponseWriter, webhooks []string) {
+	if len(webhooks) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("the cloud is unreachable, admission webhooks %s are deferred until the write is replayed", strings.Join(webhooks, ","))
+	w.Header().Add("Warning", fmt.Sprintf("299 - %q", msg))
+}
@@ -36,6 +36,7 @@ import (
 
 	manager "github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	hubmeta "github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/meta"
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
 	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
@@ -231,9 +232,19 @@ func (lp *LocalProxy) localReqCache(w http.ResponseWriter, req *http.Request) er
 		return apierrors.NewInternalError(fmt.Errorf("no cache object for %s", hubutil.ReqString(req)))
 	}
 
+	observeStaleServe(req)
 	return util.WriteObject(http.StatusOK, obj, w, req)
 }
 
+// observeStaleServe counts the request which is served by local cache because remote servers are unavailable
+func observeStaleServe(req *http.Request) {
+	info, _ := apirequest.RequestInfoFrom(req.Context())
+	comp, _ := hubutil.ClientComponentFrom(req.Context())
+	if info != nil {
+		metrics.Metrics.IncCacheStaleServes(comp, info.Verb, info.Resource)
+	}
+}
+
 func copyHeader(dst, src http.Header) {
 	for k, vv := range src {
 		if k == "Content-Type" || k == "Content-Length" {
@@ -30,6 +30,7 @@ import (
 
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/filter/manager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
 	"github.com/openyurtio/openyurt/pkg/yurthub/transport"
 	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
@@ -189,6 +190,8 @@ func (pp *PoolCoordinatorProxy) errorHandler(rw http.ResponseWriter, req *http.R
 	if info, ok := apirequest.RequestInfoFrom(ctx); ok {
 		if info.Verb == "get" || info.Verb == "list" {
 			if obj, err := pp.localCacheMgr.QueryCache(req); err == nil {
+				comp, _ := hubutil.ClientComponentFrom(ctx)
+				metrics.Metrics.IncCacheStaleServes(comp, info.Verb, info.Resource)
 				hubutil.WriteObject(http.StatusOK, obj, rw, req)
 				return
 			}
@@ -18,6 +18,7 @@ package proxy
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -36,13 +37,18 @@ import (
 	"github.com/openyurtio/openyurt/cmd/yurthub/app/config"
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/healthchecker"
+	"github.com/openyurtio/openyurt/pkg/yurthub/interceptor"
+	"github.com/openyurtio/openyurt/pkg/yurthub/nodeproblem"
 	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator"
 	coordinatorconstants "github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator/constants"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/local"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/pool"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/remote"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	"github.com/openyurtio/openyurt/pkg/yurthub/resourcemetrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/tenant"
+	"github.com/openyurtio/openyurt/pkg/yurthub/timesync"
+	"github.com/openyurtio/openyurt/pkg/yurthub/trafficprofile"
 	"github.com/openyurtio/openyurt/pkg/yurthub/transport"
 	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
@@ -59,6 +65,13 @@ type yurtReverseProxy struct {
 	isCoordinatorReady            func() bool
 	workingMode                   hubutil.WorkingMode
 	enablePoolCoordinator         bool
+	resourceMetricsCache          *resourcemetrics.Cache
+	nodeProblemRelay              *nodeproblem.Relay
+	eventAggregated               bool
+	interceptors                  *interceptor.Chain
+	certRequestsGuard             *timesync.Monitor
+	trafficProfiles               *trafficprofile.Manager
+	canCacheFor                   func(req *http.Request) bool
 }
 
 // NewYurtReverseProxyHandler creates a http handler for proxying
@@ -119,6 +132,9 @@ func NewYurtReverseProxyHandler(
 			yurtHubCfg.MinRequestTimeout,
 		)
 		localProxy = local.WithFakeTokenInject(localProxy, yurtHubCfg.SerializerManager)
+		if yurtHubCfg.EventAggregator != nil {
+			localProxy = local.WithEventsAggregation(localProxy, yurtHubCfg.EventAggregator, yurtHubCfg.SerializerManager)
+		}
 
 		if yurtHubCfg.EnableCoordinator {
 			poolProxy, err = pool.NewPoolCoordinatorProxy(
@@ -134,6 +150,16 @@ func NewYurtReverseProxyHandler(
 		}
 	}
 
+	var resourceMetricsCache *resourcemetrics.Cache
+	if yurtHubCfg.WorkingMode == hubutil.WorkingModeEdge && len(yurtHubCfg.ResourceMetricsCacheDir) != 0 {
+		var scraper *resourcemetrics.KubeletScraper
+		if len(yurtHubCfg.KubeletResourceMetricsURL) != 0 {
+			scraper = resourcemetrics.NewKubeletScraper(yurtHubCfg.NodeName, yurtHubCfg.KubeletResourceMetricsURL, yurtHubCfg.CertManager.GetAPIServerClientCert)
+		}
+		resourceMetricsCache = resourcemetrics.NewCache(yurtHubCfg.ResourceMetricsCacheDir, scraper)
+		go resourceMetricsCache.Run(cloudHealthChecker.IsHealthy, stopCh)
+	}
+
 	yurtProxy := &yurtReverseProxy{
 		resolver:                      resolver,
 		loadBalancer:                  lb,
@@ -146,6 +172,17 @@ func NewYurtReverseProxyHandler(
 		enablePoolCoordinator:         yurtHubCfg.EnableCoordinator,
 		tenantMgr:                     tenantMgr,
 		workingMode:                   yurtHubCfg.WorkingMode,
+		resourceMetricsCache:          resourceMetricsCache,
+		nodeProblemRelay:              yurtHubCfg.NodeProblemRelay,
+		eventAggregated:               yurtHubCfg.EventAggregator != nil,
+		interceptors:                  yurtHubCfg.Interceptors,
+	}
+	if yurtHubCfg.TrafficProfiles != nil {
+		yurtProxy.trafficProfiles = yurtHubCfg.TrafficProfiles
+		yurtProxy.canCacheFor = localCacheMgr.CanCacheFor
+	}
+	if yurtHubCfg.BlockCertOnClockSkew {
+		yurtProxy.certRequestsGuard = yurtHubCfg.TimeSyncMonitor
 	}
 
 	return yurtProxy.buildHandlerChain(yurtProxy), nil
@@ -162,7 +199,11 @@ func (p *yurtReverseProxy) buildHandlerChain(handler http.Handler) http.Handler
 		handler = util.WithListRequestSelector(handler)
 	}
 	handler = util.WithRequestTraceFull(handler)
+	handler = trafficprofile.WithTrafficProfile(handler, p.trafficProfiles, p.canCacheFor)
+	// interceptors are inside of in-flight limit, so the intercepted requests are limited too
+	handler = interceptor.WithInterceptors(handler, p.interceptors)
 	handler = util.WithMaxInFlightLimit(handler, p.maxRequestsInFlight)
+	handler = timesync.WithCertificateRequestsBlocked(handler, p.certRequestsGuard)
 	handler = util.WithRequestClientComponent(handler)
 
 	if p.enablePoolCoordinator {
@@ -195,6 +236,10 @@ func (p *yurtReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 		p.poolScopedResouceHandler(rw, req)
 	case util.IsSubjectAccessReviewCreateGetRequest(req):
 		p.subjectAccessReviewHandler(rw, req)
+	case p.resourceMetricsCache != nil && resourcemetrics.IsResourceMetricsRequest(req):
+		p.resourceMetricsHandler(rw, req)
+	case p.nodeProblemRelay != nil && nodeproblem.IsNodeConditionsReport(req):
+		p.nodeConditionsHandler(rw, req)
 	default:
 		// For resource request that do not need to be handled by pool-coordinator,
 		// handling the request with cloud apiserver or local cache.
@@ -206,6 +251,26 @@ func (p *yurtReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 	}
 }
 
+// resourceMetricsHandler serves the node and pod metrics from local cache when the cloud is unhealthy,
+// because metrics.k8s.io is not cached by cache manager.
+func (p *yurtReverseProxy) resourceMetricsHandler(rw http.ResponseWriter, req *http.Request) {
+	if p.cloudHealthChecker.IsHealthy() {
+		p.resourceMetricsCache.WithResponseCache(p.loadBalancer).ServeHTTP(rw, req)
+	} else {
+		p.resourceMetricsCache.ServeHTTP(rw, req)
+	}
+}
+
+// nodeConditionsHandler records the node conditions reported by node-problem-detector when the cloud
+// is unhealthy, they are relayed to cloud through pool-coordinator or when the cloud is healthy again.
+func (p *yurtReverseProxy) nodeConditionsHandler(rw http.ResponseWriter, req *http.Request) {
+	if p.cloudHealthChecker.IsHealthy() {
+		p.loadBalancer.ServeHTTP(rw, req)
+	} else {
+		p.nodeProblemRelay.ServeHTTP(rw, req)
+	}
+}
+
 func (p *yurtReverseProxy) handleKubeletLease(rw http.ResponseWriter, req *http.Request) {
 	p.cloudHealthChecker.RenewKubeletLeaseTime()
 	coordinatorHealtChecker := p.coordinatorHealtCheckerGetter()
@@ -222,13 +287,19 @@ func (p *yurtReverseProxy) eventHandler(rw http.ResponseWriter, req *http.Reques
 	if p.cloudHealthChecker.IsHealthy() {
 		p.loadBalancer.ServeHTTP(rw, req)
 		// TODO: We should also consider create the event in pool-coordinator when the cloud is healthy.
-	} else if p.isCoordinatorReady() && p.poolProxy != nil {
+	} else if p.isCoordinatorReady() && p.poolProxy != nil && !p.isAggregatedNodeProblemEvent(req) {
 		p.poolProxy.ServeHTTP(rw, req)
 	} else {
 		p.localProxy.ServeHTTP(rw, req)
 	}
 }
 
+// isAggregatedNodeProblemEvent checks the event of node-problem-detector is aggregated in local proxy,
+// the events in pool-coordinator are not uploaded to cloud, but the node problems should reach cloud.
+func (p *yurtReverseProxy) isAggregatedNodeProblemEvent(req *http.Request) bool {
+	return p.eventAggregated && nodeproblem.IsNodeProblemDetectorRequest(req)
+}
+
 func (p *yurtReverseProxy) poolScopedResouceHandler(rw http.ResponseWriter, req *http.Request) {
 	agent, ok := hubutil.ClientComponentFrom(req.Context())
 	if ok && agent == coordinatorconstants.DefaultPoolScopedUserAgent {
@@ -263,6 +334,10 @@ func (p *yurtReverseProxy) subjectAccessReviewHandler(rw http.ResponseWriter, re
 	} else {
 		if p.cloudHealthChecker.IsHealthy() {
 			p.loadBalancer.ServeHTTP(rw, req)
+		} else if sar := decodeSubjectAccessReview(req); sar != nil && p.resourceMetricsCache != nil && p.resourceMetricsCache.IsScrapeReview(sar) {
+			// kubelet authorizes the scrape of resource metrics by yurthub, which refreshes the metrics cache
+			sar.Status = v1.SubjectAccessReviewStatus{Allowed: true, Reason: "resource metrics are scraped by yurthub when cloud is unreachable"}
+			writeSubjectAccessReview(rw, req, sar)
 		} else {
 			err := errors.New("request is from cloud APIServer but it's currently not healthy")
 			klog.Errorf("could not handle SubjectAccessReview req %s, %v", hubutil.ReqString(req), err)
@@ -272,10 +347,28 @@ func (p *yurtReverseProxy) subjectAccessReviewHandler(rw http.ResponseWriter, re
 }
 
 func isSubjectAccessReviewFromPoolCoordinator(req *http.Request) bool {
+	sav := decodeSubjectAccessReview(req)
+	if sav == nil {
+		return false
+	}
+	for _, g := range sav.Spec.Groups {
+		if g == "openyurt:pool-coordinator" {
+			return true
+		}
+	}
+
+	klog.V(4).Infof("SubjectAccessReview in request %s is not for pool-coordinator, whose group: %s, user: %s",
+		hubutil.ReqString(req), strings.Join(sav.Spec.Groups, ";"), sav.Spec.User)
+	return false
+}
+
+// decodeSubjectAccessReview decodes the SubjectAccessReview in request body, the body is kept for
+// proxying the request.
+func decodeSubjectAccessReview(req *http.Request) *v1.SubjectAccessReview {
 	var buf bytes.Buffer
 	if n, err := buf.ReadFrom(req.Body); err != nil || n == 0 {
 		klog.Errorf("failed to read SubjectAccessReview from request %s, read %d bytes, %v", hubutil.ReqString(req), n, err)
-		return false
+		return nil
 	}
 	req.Body = io.NopCloser(&buf)
 
@@ -288,21 +381,27 @@ func isSubjectAccessReviewFromPoolCoordinator(req *http.Request) bool {
 	got, gvk, err := decoder.Decode(buf.Bytes(), nil, obj)
 	if err != nil {
 		klog.Errorf("failed to decode SubjectAccessReview in request %s, %v", hubutil.ReqString(req), err)
-		return false
+		return nil
 	}
 	if (*gvk) != subjectAccessReviewGVK {
 		klog.Errorf("unexpected gvk: %s in request: %s, want: %s", gvk.String(), hubutil.ReqString(req), subjectAccessReviewGVK.String())
-		return false
+		return nil
 	}
+	return got.(*v1.SubjectAccessReview)
+}
 
-	sav := got.(*v1.SubjectAccessReview)
-	for _, g := range sav.Spec.Groups {
-		if g == "openyurt:pool-coordinator" {
-			return true
-		}
+// writeSubjectAccessReview responds the SubjectAccessReview with its status.
+func writeSubjectAccessReview(rw http.ResponseWriter, req *http.Request, sar *v1.SubjectAccessReview) {
+	sar.APIVersion = v1.SchemeGroupVersion.String()
+	sar.Kind = "SubjectAccessReview"
+	data, err := json.Marshal(sar)
+	if err != nil {
+		util.Err(err, rw, req)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	if _, err := rw.Write(data); err != nil {
+		klog.Errorf("failed to write SubjectAccessReview for %s, %v", hubutil.ReqString(req), err)
 	}
-
-	klog.V(4).Infof("SubjectAccessReview in request %s is not for pool-coordinator, whose group: %s, user: %s",
-		hubutil.ReqString(req), strings.Join(sav.Spec.Groups, ";"), sav.Spec.User)
-	return false
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/filter/manager"
 	"github.com/openyurtio/openyurt/pkg/yurthub/healthchecker"
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
 	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator"
 	coordinatorconstants "github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator/constants"
 	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
@@ -246,6 +247,8 @@ func (lb *loadBalancer) errorHandler(rw http.ResponseWriter, req *http.Request,
 	if info, ok := apirequest.RequestInfoFrom(ctx); ok {
 		if info.Verb == "get" || info.Verb == "list" {
 			if obj, err := lb.localCacheMgr.QueryCache(req); err == nil {
+				comp, _ := hubutil.ClientComponentFrom(ctx)
+				metrics.Metrics.IncCacheStaleServes(comp, info.Verb, info.Resource)
 				hubutil.WriteObject(http.StatusOK, obj, rw, req)
 				return
 			}
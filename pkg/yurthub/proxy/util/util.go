@@ -358,7 +358,7 @@ func WithSaTokenSubstitute(handler http.Handler, tenantMgr tenant.Interface) htt
 
 			if jsonWebToken, err := jwt.ParseSigned(oldToken); err != nil {
 
-				klog.Errorf("invalid bearer token %s, err: %v", oldToken, err)
+				klog.Errorf("invalid bearer token of request %s, err: %v", util.ReqString(req), err)
 			} else {
 				oldClaim := jwt.Claims{}
 
@@ -366,13 +366,17 @@ func WithSaTokenSubstitute(handler http.Handler, tenantMgr tenant.Interface) htt
 
 					if tenantNs, _, err := serviceaccount.SplitUsername(oldClaim.Subject); err == nil {
 
-						if tenantMgr.GetTenantNs() != tenantNs && tenantNs == "kube-system" && tenantMgr.WaitForCacheSync() { // token is not from tenant's namespace
-							req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tenantMgr.GetTenantToken()))
-							klog.V(2).Infof("replace token, old: %s, new: %s", oldToken, tenantMgr.GetTenantToken())
+						// the requests of component are sent by the identity of its tenant
+						comp, _ := util.ClientComponentFrom(req.Context())
+						componentTenantNs := tenantMgr.GetTenantNsFor(comp)
+						if componentTenantNs != tenantNs && tenantNs == "kube-system" && tenantMgr.WaitForCacheSync() { // token is not from tenant's namespace
+							tenantToken := tenantMgr.GetTenantTokenOf(componentTenantNs)
+							req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tenantToken))
+							klog.V(2).Infof("replace token of %s with the token of tenant %s", comp, componentTenantNs)
 						}
 
 					} else {
-						klog.Errorf("failed to parse tenant ns from token, token %s, sub: %s", oldToken, oldClaim.Subject)
+						klog.Errorf("failed to parse tenant ns from token of request %s, sub: %s", util.ReqString(req), oldClaim.Subject)
 					}
 				}
 			}
@@ -0,0 +1,399 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemetrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+const (
+	// GroupName is the group of resource metrics API
+	GroupName = "metrics.k8s.io"
+
+	nodesResource = "nodes"
+	podsResource  = "pods"
+	flushPeriod   = time.Minute
+	// maxResponseSize limits the memory used to capture a response
+	maxResponseSize = 16 * 1024 * 1024
+)
+
+var listKinds = map[string]string{
+	nodesResource: "NodeMetricsList",
+	podsResource:  "PodMetricsList",
+}
+
+// Cache keeps the latest node and pod metrics in the responses of metrics.k8s.io which are proxied
+// to the cloud, and serves the metrics.k8s.io requests from them when the cloud is unreachable, so
+// kubectl top and the autoscaling logic on the edge keep working during autonomy. The metrics of
+// local node and its pods are refreshed by scraping kubelet when the cloud is unreachable, the
+// metrics of other nodes are kept as they were. The metrics are flushed into dir periodically and
+// loaded when yurthub restarts.
+type Cache struct {
+	dir     string
+	scraper *KubeletScraper
+	sync.RWMutex
+	// metrics are keyed by resource and namespace/name
+	metrics map[string]map[string]*unstructured.Unstructured
+	// scrapedPods are the keys of pod metrics in the last scrape of kubelet
+	scrapedPods map[string]struct{}
+	dirty       bool
+}
+
+// NewCache creates a Cache with the metrics flushed into dir before, the metrics of local node are
+// scraped by scraper when the cloud is unreachable if it's not nil.
+func NewCache(dir string, scraper *KubeletScraper) *Cache {
+	c := &Cache{
+		dir:         dir,
+		scraper:     scraper,
+		scrapedPods: make(map[string]struct{}),
+		metrics: map[string]map[string]*unstructured.Unstructured{
+			nodesResource: {},
+			podsResource:  {},
+		},
+	}
+	for resource := range c.metrics {
+		c.load(resource)
+	}
+	return c
+}
+
+// IsResourceMetricsRequest checks the request is a get or list request of node or pod metrics
+func IsResourceMetricsRequest(req *http.Request) bool {
+	info, ok := apirequest.RequestInfoFrom(req.Context())
+	if !ok || !info.IsResourceRequest || info.APIGroup != GroupName || len(info.Subresource) != 0 {
+		return false
+	}
+	if _, ok := listKinds[info.Resource]; !ok {
+		return false
+	}
+	return info.Verb == "get" || info.Verb == "list"
+}
+
+// Run flushes the changed metrics periodically, and scrapes kubelet when the cloud is not healthy
+// until stopCh is closed.
+func (c *Cache) Run(isHealthy func() bool, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(flushPeriod)
+	defer ticker.Stop()
+	scrapeTicker := time.NewTicker(scrapePeriod)
+	defer scrapeTicker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			c.flush()
+			return
+		case <-ticker.C:
+			c.flush()
+		case <-scrapeTicker.C:
+			if c.scraper == nil {
+				continue
+			}
+			if isHealthy() {
+				c.scraper.Reset()
+				continue
+			}
+			c.scrape()
+		}
+	}
+}
+
+// IsScrapeReview checks the SubjectAccessReview authorizes the scrape of kubelet by cache.
+func (c *Cache) IsScrapeReview(sar *authorizationv1.SubjectAccessReview) bool {
+	return c.scraper != nil && c.scraper.IsScrapeReview(sar)
+}
+
+// scrape refreshes the metrics of local node and its pods from kubelet, the labels of metrics are
+// kept from the cached metrics because kubelet doesn't know them.
+func (c *Cache) scrape() {
+	node, pods, err := c.scraper.Scrape()
+	if err != nil {
+		klog.Errorf("failed to scrape resource metrics of kubelet, %v", err)
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	update := func(resource string, obj *unstructured.Unstructured) string {
+		key := keyOf(obj.GetNamespace(), obj.GetName())
+		if cached, ok := c.metrics[resource][key]; ok {
+			obj.SetLabels(cached.GetLabels())
+		}
+		c.metrics[resource][key] = obj
+		return key
+	}
+	if node != nil {
+		update(nodesResource, node)
+	}
+	scraped := make(map[string]struct{}, len(pods))
+	for _, pod := range pods {
+		scraped[update(podsResource, pod)] = struct{}{}
+	}
+	// the pods which are not on the node any more
+	for key := range c.scrapedPods {
+		if _, ok := scraped[key]; !ok {
+			delete(c.metrics[podsResource], key)
+		}
+	}
+	c.scrapedPods = scraped
+	c.dirty = true
+}
+
+// WithResponseCache caches the successful json responses of handler.
+func (c *Cache) WithResponseCache(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cw := &capturingWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(cw, req)
+		if cw.status != http.StatusOK || cw.overflow {
+			return
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			return
+		}
+
+		body := cw.body.Bytes()
+		if w.Header().Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				klog.Errorf("failed to ungzip response of %s, %v", hubutil.ReqString(req), err)
+				return
+			}
+			defer gr.Close()
+			if body, err = io.ReadAll(gr); err != nil {
+				klog.Errorf("failed to ungzip response of %s, %v", hubutil.ReqString(req), err)
+				return
+			}
+		}
+		if err := c.CacheResponse(req, body); err != nil {
+			klog.Errorf("failed to cache resource metrics of %s, %v", hubutil.ReqString(req), err)
+		}
+	})
+}
+
+// CacheResponse caches the metrics in the response body of req. The cached metrics in the scope of
+// list request are replaced by the response if no selector is specified, otherwise the metrics in
+// the response are merged into the cached metrics.
+func (c *Cache) CacheResponse(req *http.Request, body []byte) error {
+	info, _ := apirequest.RequestInfoFrom(req.Context())
+	if info.Verb == "get" {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(body); err != nil {
+			return err
+		}
+		c.Lock()
+		defer c.Unlock()
+		c.metrics[info.Resource][keyOf(obj.GetNamespace(), obj.GetName())] = obj
+		c.dirty = true
+		return nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	if err := list.UnmarshalJSON(body); err != nil {
+		return err
+	}
+	query := req.URL.Query()
+	replace := len(query.Get("labelSelector")) == 0 && len(query.Get("fieldSelector")) == 0
+
+	c.Lock()
+	defer c.Unlock()
+	cached := c.metrics[info.Resource]
+	if replace {
+		for key, obj := range cached {
+			if len(info.Namespace) == 0 || obj.GetNamespace() == info.Namespace {
+				delete(cached, key)
+			}
+		}
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		cached[keyOf(obj.GetNamespace(), obj.GetName())] = obj
+	}
+	c.dirty = true
+	return nil
+}
+
+// ServeHTTP serves the get and list requests of resource metrics from the cached metrics
+func (c *Cache) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	info, _ := apirequest.RequestInfoFrom(req.Context())
+	gr := schema.GroupResource{Group: GroupName, Resource: info.Resource}
+
+	c.RLock()
+	defer c.RUnlock()
+	cached := c.metrics[info.Resource]
+	if info.Verb == "get" {
+		obj, ok := cached[keyOf(info.Namespace, info.Name)]
+		if !ok {
+			util.Err(apierrors.NewNotFound(gr, info.Name), w, req)
+			return
+		}
+		writeJSON(w, req, obj)
+		return
+	}
+
+	selector, err := labels.Parse(req.URL.Query().Get("labelSelector"))
+	if err != nil {
+		util.Err(apierrors.NewBadRequest(err.Error()), w, req)
+		return
+	}
+	keys := make([]string, 0, len(cached))
+	for key, obj := range cached {
+		if len(info.Namespace) != 0 && obj.GetNamespace() != info.Namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, cached[key].Object)
+	}
+	writeJSON(w, req, map[string]interface{}{
+		"apiVersion": schema.GroupVersion{Group: GroupName, Version: info.APIVersion}.String(),
+		"kind":       listKinds[info.Resource],
+		"metadata":   map[string]interface{}{},
+		"items":      items,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, req *http.Request, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		util.Err(apierrors.NewInternalError(err), w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		klog.Errorf("failed to write resource metrics for %s, %v", hubutil.ReqString(req), err)
+	}
+}
+
+func keyOf(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (c *Cache) fileOf(resource string) string {
+	return filepath.Join(c.dir, resource+".json")
+}
+
+// flush writes the metrics into files if they are changed since last flush
+func (c *Cache) flush() {
+	c.Lock()
+	defer c.Unlock()
+	if !c.dirty {
+		return
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		klog.Errorf("failed to create dir for resource metrics, %v", err)
+		return
+	}
+	for resource, cached := range c.metrics {
+		items := make([]map[string]interface{}, 0, len(cached))
+		for _, obj := range cached {
+			items = append(items, obj.Object)
+		}
+		data, err := json.Marshal(items)
+		if err != nil {
+			klog.Errorf("failed to encode %s metrics, %v", resource, err)
+			return
+		}
+		path := c.fileOf(resource)
+		if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+			klog.Errorf("failed to flush %s metrics, %v", resource, err)
+			return
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			klog.Errorf("failed to flush %s metrics, %v", resource, err)
+			return
+		}
+	}
+	c.dirty = false
+}
+
+func (c *Cache) load(resource string) {
+	data, err := os.ReadFile(c.fileOf(resource))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("failed to load %s metrics, %v", resource, err)
+		}
+		return
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		klog.Errorf("failed to decode %s metrics, %v", resource, err)
+		return
+	}
+	for _, item := range items {
+		obj := &unstructured.Unstructured{Object: item}
+		c.metrics[resource][keyOf(obj.GetNamespace(), obj.GetName())] = obj
+	}
+	klog.Infof("%d %s metrics are loaded", len(items), resource)
+}
+
+// capturingWriter keeps a copy of the response body
+type capturingWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (cw *capturingWriter) WriteHeader(status int) {
+	cw.status = status
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *capturingWriter) Write(data []byte) (int, error) {
+	if !cw.overflow {
+		if cw.body.Len()+len(data) > maxResponseSize {
+			cw.overflow = true
+			cw.body.Reset()
+		} else {
+			cw.body.Write(data)
+		}
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+func (cw *capturingWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
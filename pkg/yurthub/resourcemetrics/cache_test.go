@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemetrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	nodeMetricsList = `{"kind":"NodeMetricsList","apiVersion":"metrics.k8s.io/v1beta1","metadata":{},"items":[
+{"metadata":{"name":"node1","labels":{"pool":"hangzhou"}},"timestamp":"2023-06-01T12:00:00Z","window":"20s","usage":{"cpu":"100m","memory":"1Gi"}},
+{"metadata":{"name":"node2","labels":{"pool":"beijing"}},"timestamp":"2023-06-01T12:00:00Z","window":"20s","usage":{"cpu":"200m","memory":"2Gi"}}]}`
+	podMetricsList = `{"kind":"PodMetricsList","apiVersion":"metrics.k8s.io/v1beta1","metadata":{},"items":[
+{"metadata":{"name":"pod1","namespace":"default"},"timestamp":"2023-06-01T12:00:00Z","window":"20s","containers":[]},
+{"metadata":{"name":"pod2","namespace":"kube-system"},"timestamp":"2023-06-01T12:00:00Z","window":"20s","containers":[]}]}`
+)
+
+func newRequest(verb, resource, namespace, name, query string) *http.Request {
+	req, _ := http.NewRequest("GET", "/apis/metrics.k8s.io/v1beta1/"+resource+query, nil)
+	info := &apirequest.RequestInfo{
+		IsResourceRequest: true,
+		Verb:              verb,
+		APIGroup:          GroupName,
+		APIVersion:        "v1beta1",
+		Resource:          resource,
+		Namespace:         namespace,
+		Name:              name,
+	}
+	return req.WithContext(apirequest.WithRequestInfo(req.Context(), info))
+}
+
+// namesOf serves req from cache and returns the names of metrics in response
+func namesOf(t *testing.T, c *Cache, req *http.Request) []string {
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expect status 200, but got %d", w.Code)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode response, %v", err)
+	}
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.Metadata.Name)
+	}
+	return names
+}
+
+func TestCacheResponse(t *testing.T) {
+	c := NewCache(t.TempDir(), nil)
+	if err := c.CacheResponse(newRequest("list", nodesResource, "", "", ""), []byte(nodeMetricsList)); err != nil {
+		t.Fatalf("failed to cache node metrics, %v", err)
+	}
+	if err := c.CacheResponse(newRequest("list", podsResource, "", "", ""), []byte(podMetricsList)); err != nil {
+		t.Fatalf("failed to cache pod metrics, %v", err)
+	}
+
+	testcases := map[string]struct {
+		req    *http.Request
+		expect []string
+	}{
+		"list all nodes": {
+			req:    newRequest("list", nodesResource, "", "", ""),
+			expect: []string{"node1", "node2"},
+		},
+		"list nodes with label selector": {
+			req:    newRequest("list", nodesResource, "", "", "?labelSelector=pool%3Dbeijing"),
+			expect: []string{"node2"},
+		},
+		"list pods in namespace": {
+			req:    newRequest("list", podsResource, "kube-system", "", ""),
+			expect: []string{"pod2"},
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if names := namesOf(t, c, tc.req); !reflect.DeepEqual(names, tc.expect) {
+				t.Errorf("expect %v, but got %v", tc.expect, names)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, newRequest("get", nodesResource, "", "node3", ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expect status 404 for node not cached, but got %d", w.Code)
+	}
+}
+
+func TestCacheResponseReplace(t *testing.T) {
+	c := NewCache(t.TempDir(), nil)
+	if err := c.CacheResponse(newRequest("list", podsResource, "", "", ""), []byte(podMetricsList)); err != nil {
+		t.Fatalf("failed to cache pod metrics, %v", err)
+	}
+
+	// the metrics of pods in the namespace are replaced by the list of namespace
+	empty := `{"kind":"PodMetricsList","apiVersion":"metrics.k8s.io/v1beta1","metadata":{},"items":[]}`
+	if err := c.CacheResponse(newRequest("list", podsResource, "default", "", ""), []byte(empty)); err != nil {
+		t.Fatalf("failed to cache pod metrics, %v", err)
+	}
+	if names := namesOf(t, c, newRequest("list", podsResource, "", "", "")); !reflect.DeepEqual(names, []string{"pod2"}) {
+		t.Errorf("expect [pod2], but got %v", names)
+	}
+
+	// the metrics are merged if selector is specified
+	if err := c.CacheResponse(newRequest("list", podsResource, "", "", "?labelSelector=app%3Dfoo"), []byte(podMetricsList)); err != nil {
+		t.Fatalf("failed to cache pod metrics, %v", err)
+	}
+	if names := namesOf(t, c, newRequest("list", podsResource, "", "", "")); !reflect.DeepEqual(names, []string{"pod1", "pod2"}) {
+		t.Errorf("expect [pod1 pod2], but got %v", names)
+	}
+}
+
+func TestFlushAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache(dir, nil)
+	if err := c.CacheResponse(newRequest("list", nodesResource, "", "", ""), []byte(nodeMetricsList)); err != nil {
+		t.Fatalf("failed to cache node metrics, %v", err)
+	}
+	c.flush()
+
+	loaded := NewCache(dir, nil)
+	if names := namesOf(t, loaded, newRequest("list", nodesResource, "", "", "")); !reflect.DeepEqual(names, []string{"node1", "node2"}) {
+		t.Errorf("expect [node1 node2] loaded, but got %v", names)
+	}
+}
+
+func TestWithResponseCache(t *testing.T) {
+	c := NewCache(t.TempDir(), nil)
+	handler := c.WithResponseCache(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(nodeMetricsList))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest("list", nodesResource, "", "", ""))
+
+	if names := namesOf(t, c, newRequest("list", nodesResource, "", "", "")); !reflect.DeepEqual(names, []string{"node1", "node2"}) {
+		t.Errorf("expect [node1 node2] cached, but got %v", names)
+	}
+}
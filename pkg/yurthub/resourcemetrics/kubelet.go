@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemetrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// DefaultKubeletResourceMetricsURL is the resource metrics endpoint of kubelet on the local node
+	DefaultKubeletResourceMetricsURL = "https://127.0.0.1:10250/metrics/resource"
+
+	scrapePeriod  = 15 * time.Second
+	scrapeTimeout = 10 * time.Second
+
+	nodeCPUUsageMetric         = "node_cpu_usage_seconds_total"
+	nodeMemoryWorkingSetMetric = "node_memory_working_set_bytes"
+	containerCPUUsageMetric    = "container_cpu_usage_seconds_total"
+	containerMemoryMetric      = "container_memory_working_set_bytes"
+)
+
+// KubeletScraper scrapes the resource metrics of kubelet on the local node with the client certificate
+// of node, and converts them into the node and pod metrics of metrics.k8s.io like metrics-server does.
+// The cpu usage is the rate of cumulative cpu time between two scrapes.
+type KubeletScraper struct {
+	nodeName string
+	url      string
+	client   *http.Client
+	// last are the last cpu samples, keyed by node name or namespace/pod/container
+	last map[string]cpuSample
+}
+
+type cpuSample struct {
+	seconds   float64
+	timestamp time.Time
+}
+
+// NewKubeletScraper creates a KubeletScraper for the kubelet of node at url. The serving certificate of
+// kubelet is not verified because it's usually self-signed, and url always points to the local node.
+func NewKubeletScraper(nodeName, url string, clientCert func() *tls.Certificate) *KubeletScraper {
+	return &KubeletScraper{
+		nodeName: nodeName,
+		url:      url,
+		client: &http.Client{
+			Timeout: scrapeTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true, //nolint:gosec
+					GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+						if clientCert != nil {
+							if cert := clientCert(); cert != nil {
+								return cert, nil
+							}
+						}
+						return &tls.Certificate{}, nil
+					},
+				},
+			},
+		},
+		last: make(map[string]cpuSample),
+	}
+}
+
+// IsScrapeReview checks the SubjectAccessReview is sent by kubelet for authorizing the scrape of
+// its own resource metrics by the node identity, which is allowed by yurthub when the cloud is
+// unreachable.
+func (s *KubeletScraper) IsScrapeReview(sar *authorizationv1.SubjectAccessReview) bool {
+	attrs := sar.Spec.ResourceAttributes
+	return sar.Spec.User == "system:node:"+s.nodeName && attrs != nil &&
+		attrs.Verb == "get" && attrs.Group == "" && attrs.Resource == nodesResource &&
+		attrs.Subresource == "metrics" && attrs.Name == s.nodeName
+}
+
+// Reset drops the cpu samples, so the cpu usage is not averaged over the time the scraper is idle.
+func (s *KubeletScraper) Reset() {
+	s.last = make(map[string]cpuSample)
+}
+
+// Scrape returns the metrics of node and the pods on it. The metrics are not returned until the
+// cpu usage can be computed from two samples.
+func (s *KubeletScraper) Scrape() (*unstructured.Unstructured, []*unstructured.Unstructured, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("kubelet responded %d, %s", resp.StatusCode, body)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse resource metrics of kubelet, %w", err)
+	}
+	return s.convert(families, time.Now())
+}
+
+type containerUsage struct {
+	name   string
+	cpu    *resource.Quantity
+	memory *resource.Quantity
+}
+
+// convert converts the metric families of kubelet into node and pod metrics, now is used if
+// the samples have no timestamp.
+func (s *KubeletScraper) convert(families map[string]*dto.MetricFamily, now time.Time) (*unstructured.Unstructured, []*unstructured.Unstructured, error) {
+	var node *unstructured.Unstructured
+	nodeCPU, nodeTime, cpuOK := s.cpuRate(s.nodeName, first(families[nodeCPUUsageMetric]), now)
+	if mem := first(families[nodeMemoryWorkingSetMetric]); cpuOK && mem != nil {
+		node = newMetrics("NodeMetrics", "", s.nodeName, nodeTime)
+		node.Object["usage"] = map[string]interface{}{
+			"cpu":    nodeCPU.String(),
+			"memory": memoryOf(mem).String(),
+		}
+	}
+
+	// containers of pods keyed by namespace/name
+	pods := make(map[string]map[string]*containerUsage)
+	podTimes := make(map[string]time.Time)
+	usageOf := func(m *dto.Metric) (*containerUsage, string) {
+		namespace, pod, container := label(m, "namespace"), label(m, "pod"), label(m, "container")
+		key := keyOf(namespace, pod)
+		if pods[key] == nil {
+			pods[key] = make(map[string]*containerUsage)
+		}
+		if pods[key][container] == nil {
+			pods[key][container] = &containerUsage{name: container}
+		}
+		return pods[key][container], key
+	}
+	if f := families[containerCPUUsageMetric]; f != nil {
+		for _, m := range f.Metric {
+			usage, key := usageOf(m)
+			if cpu, ts, ok := s.cpuRate(key+"/"+usage.name, m, now); ok {
+				usage.cpu = cpu
+				if ts.After(podTimes[key]) {
+					podTimes[key] = ts
+				}
+			}
+		}
+	}
+	if f := families[containerMemoryMetric]; f != nil {
+		for _, m := range f.Metric {
+			usage, _ := usageOf(m)
+			usage.memory = memoryOf(m)
+		}
+	}
+
+	var podMetrics []*unstructured.Unstructured
+	for key, containers := range pods {
+		names := make([]string, 0, len(containers))
+		complete := true
+		for name, usage := range containers {
+			names = append(names, name)
+			complete = complete && usage.cpu != nil && usage.memory != nil
+		}
+		if !complete {
+			continue
+		}
+		sort.Strings(names)
+		items := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			items = append(items, map[string]interface{}{
+				"name": name,
+				"usage": map[string]interface{}{
+					"cpu":    containers[name].cpu.String(),
+					"memory": containers[name].memory.String(),
+				},
+			})
+		}
+		namespace, name := splitKey(key)
+		pod := newMetrics("PodMetrics", namespace, name, podTimes[key])
+		pod.Object["containers"] = items
+		podMetrics = append(podMetrics, pod)
+	}
+	return node, podMetrics, nil
+}
+
+// cpuRate records the cpu sample of key, and returns the cpu usage since the last sample.
+func (s *KubeletScraper) cpuRate(key string, m *dto.Metric, now time.Time) (*resource.Quantity, time.Time, bool) {
+	if m == nil {
+		return nil, time.Time{}, false
+	}
+	sample := cpuSample{seconds: value(m), timestamp: now}
+	if m.TimestampMs != nil {
+		sample.timestamp = time.UnixMilli(m.GetTimestampMs())
+	}
+	last, ok := s.last[key]
+	s.last[key] = sample
+	window := sample.timestamp.Sub(last.timestamp)
+	// the counter is reset if the container is restarted
+	if !ok || window <= 0 || sample.seconds < last.seconds {
+		return nil, time.Time{}, false
+	}
+	nanoCores := int64((sample.seconds - last.seconds) / window.Seconds() * 1e9)
+	return resource.NewScaledQuantity(nanoCores, resource.Nano), sample.timestamp, true
+}
+
+func newMetrics(kind, namespace, name string, timestamp time.Time) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"timestamp": timestamp.UTC().Format(time.RFC3339),
+		"window":    scrapePeriod.String(),
+	}}
+	obj.SetAPIVersion(GroupName + "/v1beta1")
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func first(f *dto.MetricFamily) *dto.Metric {
+	if f == nil || len(f.Metric) == 0 {
+		return nil
+	}
+	return f.Metric[0]
+}
+
+func value(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
+
+func memoryOf(m *dto.Metric) *resource.Quantity {
+	return resource.NewQuantity(int64(value(m)), resource.BinarySI)
+}
+
+func splitKey(key string) (string, string) {
+	parts := strings.SplitN(key, "/", 2)
+	return parts[0], parts[1]
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.Label {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
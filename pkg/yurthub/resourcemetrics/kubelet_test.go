@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemetrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const kubeletMetrics = `# TYPE node_cpu_usage_seconds_total counter
+node_cpu_usage_seconds_total %[1]s %[2]d
+# TYPE node_memory_working_set_bytes gauge
+node_memory_working_set_bytes 1.073741824e+09 %[2]d
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container="nginx",namespace="default",pod="pod1"} %[1]s %[2]d
+# TYPE container_memory_working_set_bytes gauge
+container_memory_working_set_bytes{container="nginx",namespace="default",pod="pod1"} 1.048576e+06 %[2]d
+`
+
+func TestScrapeKubelet(t *testing.T) {
+	samples := []string{
+		fmt.Sprintf(kubeletMetrics, "10", 1685620800000),
+		fmt.Sprintf(kubeletMetrics, "25", 1685620815000),
+	}
+	var scrapes int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, samples[scrapes])
+		scrapes++
+	}))
+	defer server.Close()
+
+	c := NewCache(t.TempDir(), NewKubeletScraper("node1", server.URL, nil))
+	if err := c.CacheResponse(newRequest("list", nodesResource, "", "", ""), []byte(nodeMetricsList)); err != nil {
+		t.Fatalf("failed to cache node metrics, %v", err)
+	}
+	if err := c.CacheResponse(newRequest("list", podsResource, "", "", ""), []byte(podMetricsList)); err != nil {
+		t.Fatalf("failed to cache pod metrics, %v", err)
+	}
+
+	// the cpu usage can't be computed from the first sample
+	c.scrape()
+	if timestamp, _, _ := unstructured.NestedString(c.metrics[nodesResource]["/node1"].Object, "timestamp"); timestamp != "2023-06-01T12:00:00Z" {
+		t.Errorf("expect metrics of node1 are not refreshed, but got timestamp %s", timestamp)
+	}
+
+	c.scrape()
+	node := c.metrics[nodesResource]["/node1"]
+	usage, _, _ := unstructured.NestedStringMap(node.Object, "usage")
+	if !reflect.DeepEqual(usage, map[string]string{"cpu": "1", "memory": "1Gi"}) {
+		t.Errorf("unexpected usage of node1 %v", usage)
+	}
+	if timestamp, _, _ := unstructured.NestedString(node.Object, "timestamp"); timestamp != "2023-06-01T12:00:15Z" {
+		t.Errorf("unexpected timestamp of node1 %s", timestamp)
+	}
+	if labels := node.GetLabels(); labels["pool"] != "hangzhou" {
+		t.Errorf("expect labels of node1 are kept, but got %v", labels)
+	}
+
+	pod := c.metrics[podsResource]["default/pod1"]
+	containers, _, _ := unstructured.NestedSlice(pod.Object, "containers")
+	expect := []interface{}{map[string]interface{}{
+		"name":  "nginx",
+		"usage": map[string]interface{}{"cpu": "1", "memory": "1Mi"},
+	}}
+	if !reflect.DeepEqual(containers, expect) {
+		t.Errorf("expect containers %v, but got %v", expect, containers)
+	}
+	if _, ok := c.metrics[podsResource]["kube-system/pod2"]; !ok {
+		t.Errorf("expect metrics of pods on other nodes are kept")
+	}
+}
+
+func TestIsScrapeReview(t *testing.T) {
+	s := NewKubeletScraper("node1", DefaultKubeletResourceMetricsURL, nil)
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User: "system:node:node1",
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        "get",
+				Resource:    "nodes",
+				Subresource: "metrics",
+				Name:        "node1",
+			},
+		},
+	}
+	if !s.IsScrapeReview(sar) {
+		t.Errorf("expect scrape of node1 is allowed")
+	}
+	sar.Spec.ResourceAttributes.Subresource = "proxy"
+	if s.IsScrapeReview(sar) {
+		t.Errorf("expect nodes/proxy is not allowed")
+	}
+	sar.Spec.ResourceAttributes.Subresource = "metrics"
+	sar.Spec.User = "system:node:node2"
+	if s.IsScrapeReview(sar) {
+		t.Errorf("expect scrape by other node is not allowed")
+	}
+}
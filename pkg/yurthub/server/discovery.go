@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/rest"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+// discoveryReqPaths are the paths of discovery documents, which are requested by kubectl,
+// helm and dynamic clients before they access resources.
+var discoveryReqPaths = []string{"/api", "/api/{version}", "/apis", "/apis/{group}/{version}"}
+
+// openAPIReqPathPrefix is the path prefix of openapi v2 and v3 schemas, which are used by kubectl
+// for validating the objects in apply.
+const openAPIReqPathPrefix = "/openapi/"
+
+// discoveryCacheHandler proxies the discovery requests to the cloud and caches the responses
+// by the media types in Accept header, so the clients negotiating different formats(like aggregated
+// discovery and protobuf openapi) can be served from local cache when cloud-edge line off.
+func discoveryCacheHandler(proxyHandler http.Handler, restMgr *rest.RestConfigManager, sw cachemanager.StorageWrapper, infoType storage.ClusterInfoType) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if restMgr.GetRestConfig(true) == nil {
+			klog.Infof("get %s discovery data from local cache when cloud-edge line off", r.URL.Path)
+			serveCachedDiscovery(sw, infoType, w, r)
+			return
+		}
+		cacheDiscoveryResponse(proxyHandler, sw, infoType, w, r)
+	})
+}
+
+func cacheDiscoveryResponse(proxyHandler http.Handler, sw cachemanager.StorageWrapper, infoType storage.ClusterInfoType, w http.ResponseWriter, r *http.Request) {
+	// the response is decompressed by transport if Accept-Encoding is not specified,
+	// so the data in local cache is not compressed.
+	req := r.Clone(r.Context())
+	req.Header.Del("Accept-Encoding")
+	cw := &cachingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	proxyHandler.ServeHTTP(cw, req)
+
+	if cw.statusCode != http.StatusOK || cw.buf.Len() == 0 || len(cw.Header().Get("Content-Encoding")) != 0 {
+		return
+	}
+	// the response is cached under the Accept header of request, because the servers don't always
+	// respond the negotiated media type in Content-Type(e.g. application/octet-stream for protobuf
+	// openapi). it's also cached under the media type of response if it's accepted, so the clients
+	// accepting it with other fallbacks are served too.
+	contentType := cw.Header().Get("Content-Type")
+	accepted := acceptedMediaTypes(r.Header.Get("Accept"))
+	mediaTypes := []string{strings.Join(accepted, ",")}
+	for _, mediaType := range accepted {
+		if mediaType == normalizeMediaType(contentType) && mediaType != mediaTypes[0] {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	data := append([]byte(contentType+"\n"), cw.buf.Bytes()...)
+	for _, mediaType := range mediaTypes {
+		key := discoveryCacheKey(infoType, r.URL.Path, mediaType)
+		if err := sw.SaveClusterInfo(key, data); err != nil {
+			klog.Errorf("failed to cache discovery data of %s, %v", key.UrlPath, err)
+		}
+	}
+}
+
+// serveCachedDiscovery serves the response cached under the Accept header of request, or the first
+// media type in Accept header which is cached. The response is served with its Content-Type.
+func serveCachedDiscovery(sw cachemanager.StorageWrapper, infoType storage.ClusterInfoType, w http.ResponseWriter, r *http.Request) {
+	accepted := acceptedMediaTypes(r.Header.Get("Accept"))
+	for _, mediaType := range append([]string{strings.Join(accepted, ",")}, accepted...) {
+		data, err := sw.GetClusterInfo(discoveryCacheKey(infoType, r.URL.Path, mediaType))
+		if err == storage.ErrStorageNotFound {
+			continue
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			writeErrResponse(r.URL.Path, err, w)
+			return
+		}
+		contentType, body, found := bytes.Cut(data, []byte("\n"))
+		if !found {
+			continue
+		}
+		if len(contentType) != 0 {
+			w.Header().Set("Content-Type", string(contentType))
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	writeErrResponse(r.URL.Path, storage.ErrStorageNotFound, w)
+}
+
+// discoveryCacheKey ignores the query of request, because the query of openapi v3 schemas
+// is only a hash for invalidating the http caches of clients.
+func discoveryCacheKey(infoType storage.ClusterInfoType, path, mediaType string) storage.ClusterInfoKey {
+	return storage.ClusterInfoKey{
+		ClusterInfoType: infoType,
+		UrlPath:         path + ";" + mediaType,
+	}
+}
+
+// acceptedMediaTypes returns the media types in Accept header by order, the wildcards
+// and empty header are treated as application/json.
+func acceptedMediaTypes(accept string) []string {
+	var mediaTypes []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(accept, ",") {
+		mediaType := normalizeMediaType(t)
+		if len(mediaType) == 0 || mediaType == "*/*" || mediaType == "application/*" {
+			mediaType = "application/json"
+		}
+		if !seen[mediaType] {
+			seen[mediaType] = true
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	return mediaTypes
+}
+
+// normalizeMediaType removes the spaces, quality and charset parameters of media type,
+// other parameters(like the group, version and kind of aggregated discovery) are kept.
+func normalizeMediaType(mediaType string) string {
+	parts := strings.Split(mediaType, ";")
+	normalized := []string{strings.TrimSpace(parts[0])}
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if len(param) == 0 || strings.HasPrefix(param, "q=") || strings.HasPrefix(param, "charset=") {
+			continue
+		}
+		normalized = append(normalized, param)
+	}
+	return strings.Join(normalized, ";")
+}
+
+// cachingResponseWriter copies the body of response for caching it.
+type cachingResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	buf         bytes.Buffer
+}
+
+func (cw *cachingResponseWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.statusCode = code
+		cw.wroteHeader = true
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cachingResponseWriter) Write(b []byte) (int, error) {
+	cw.wroteHeader = true
+	if cw.statusCode == http.StatusOK {
+		cw.buf.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cachingResponseWriter) Flush() {
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+)
+
+const (
+	aggregatedDiscoveryType = "application/json;g=apidiscovery.k8s.io;v=v2beta1;as=APIGroupDiscoveryList"
+	protobufOpenAPIType     = "application/com.github.proto-openapi.spec.v2@v1.0+protobuf"
+)
+
+func TestDiscoveryCache(t *testing.T) {
+	dStorage, err := disk.NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("disk initialize error: %v", err)
+	}
+	sw := cachemanager.NewStorageWrapper(dStorage)
+
+	fakeProxy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get("Accept-Encoding")) != 0 {
+			t.Errorf("expect Accept-Encoding is removed, but got %s", r.Header.Get("Accept-Encoding"))
+		}
+		switch r.URL.Path {
+		case "/apis":
+			w.Header().Set("Content-Type", aggregatedDiscoveryType)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("aggregated apis"))
+		case "/openapi/v2":
+			// the protobuf openapi is responded as octet-stream by kube-apiserver
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("protobuf openapi"))
+		case "/openapi/v3/apis/apps/v1":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte("apps openapi"))
+		case "/api/v1":
+			// the response without Content-Type
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte("core v1"))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("forbidden"))
+		}
+	})
+
+	for _, tc := range []struct {
+		path     string
+		infoType storage.ClusterInfoType
+		accept   string
+	}{
+		{path: "/apis", infoType: storage.DiscoveryInfo, accept: aggregatedDiscoveryType + ",application/json"},
+		{path: "/openapi/v2", infoType: storage.OpenAPIInfo, accept: protobufOpenAPIType},
+		{path: "/openapi/v3/apis/apps/v1?hash=abc", infoType: storage.OpenAPIInfo, accept: "application/json"},
+		{path: "/api/v1", infoType: storage.DiscoveryInfo},
+		{path: "/api", infoType: storage.DiscoveryInfo},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if len(tc.accept) != 0 {
+			req.Header.Set("Accept", tc.accept)
+		}
+		cacheDiscoveryResponse(fakeProxy, sw, tc.infoType, httptest.NewRecorder(), req)
+	}
+
+	testcases := map[string]struct {
+		path        string
+		infoType    storage.ClusterInfoType
+		accept      string
+		statusCode  int
+		contentType string
+		data        string
+	}{
+		"serve aggregated discovery": {
+			path:        "/apis",
+			infoType:    storage.DiscoveryInfo,
+			accept:      aggregatedDiscoveryType + ";q=0.9, application/json",
+			statusCode:  http.StatusOK,
+			contentType: aggregatedDiscoveryType,
+			data:        "aggregated apis",
+		},
+		"serve aggregated discovery with another fallback": {
+			path:        "/apis",
+			infoType:    storage.DiscoveryInfo,
+			accept:      aggregatedDiscoveryType + ",application/yaml",
+			statusCode:  http.StatusOK,
+			contentType: aggregatedDiscoveryType,
+			data:        "aggregated apis",
+		},
+		"media type is not cached": {
+			path:       "/apis",
+			infoType:   storage.DiscoveryInfo,
+			accept:     "application/json",
+			statusCode: http.StatusNotFound,
+		},
+		"serve protobuf openapi with the content type of response": {
+			path:        "/openapi/v2",
+			infoType:    storage.OpenAPIInfo,
+			accept:      protobufOpenAPIType,
+			statusCode:  http.StatusOK,
+			contentType: "application/octet-stream",
+			data:        "protobuf openapi",
+		},
+		"json openapi is not cached": {
+			path:       "/openapi/v2",
+			infoType:   storage.OpenAPIInfo,
+			accept:     "application/json",
+			statusCode: http.StatusNotFound,
+		},
+		"serve response without content type": {
+			path:       "/api/v1",
+			infoType:   storage.DiscoveryInfo,
+			statusCode: http.StatusOK,
+			data:       "core v1",
+		},
+		"serve openapi with another hash": {
+			path:        "/openapi/v3/apis/apps/v1?hash=def",
+			infoType:    storage.OpenAPIInfo,
+			statusCode:  http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			data:        "apps openapi",
+		},
+		"failed response is not cached": {
+			path:       "/api",
+			infoType:   storage.DiscoveryInfo,
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for k, tt := range testcases {
+		t.Run(k, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if len(tt.accept) != 0 {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp := httptest.NewRecorder()
+			serveCachedDiscovery(sw, tt.infoType, resp, req)
+
+			if resp.Code != tt.statusCode {
+				t.Errorf("expect status code %d, but got %d", tt.statusCode, resp.Code)
+			}
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			if contentType := resp.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("expect content type %s, but got %s", tt.contentType, contentType)
+			}
+			if data := resp.Body.String(); data != tt.data {
+				t.Errorf("expect data %s, but got %s", tt.data, data)
+			}
+		})
+	}
+}
+
+func TestAcceptedMediaTypes(t *testing.T) {
+	testcases := map[string]struct {
+		accept string
+		expect []string
+	}{
+		"empty accept": {
+			accept: "",
+			expect: []string{"application/json"},
+		},
+		"wildcards": {
+			accept: "application/json, */*",
+			expect: []string{"application/json"},
+		},
+		"aggregated discovery with fallback": {
+			accept: "application/json;g=apidiscovery.k8s.io;v=v2beta1;as=APIGroupDiscoveryList,application/json;q=0.9",
+			expect: []string{aggregatedDiscoveryType, "application/json"},
+		},
+		"protobuf openapi": {
+			accept: "application/com.github.proto-openapi.spec.v2@v1.0+protobuf",
+			expect: []string{"application/com.github.proto-openapi.spec.v2@v1.0+protobuf"},
+		},
+	}
+
+	for k, tt := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if got := acceptedMediaTypes(tt.accept); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expect media types %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
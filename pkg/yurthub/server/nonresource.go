@@ -49,6 +49,12 @@ func wrapNonResourceHandler(proxyHandler http.Handler, config *config.YurtHubCon
 		wrapMux.Handle(path, localCacheHandler(nonResourceHandler, restMgr, config.StorageWrapper, path)).Methods("GET")
 	}
 
+	// register handler for discovery documents and openapi schemas
+	for _, path := range discoveryReqPaths {
+		wrapMux.Handle(path, discoveryCacheHandler(proxyHandler, restMgr, config.StorageWrapper, storage.DiscoveryInfo)).Methods("GET")
+	}
+	wrapMux.PathPrefix(openAPIReqPathPrefix).Handler(discoveryCacheHandler(proxyHandler, restMgr, config.StorageWrapper, storage.OpenAPIInfo)).Methods("GET")
+
 	// register handler for other requests
 	wrapMux.PathPrefix("/").Handler(proxyHandler)
 	return wrapMux
@@ -25,8 +25,10 @@ import (
 
 	"github.com/openyurtio/openyurt/cmd/yurthub/app/config"
 	"github.com/openyurtio/openyurt/pkg/profile"
+	"github.com/openyurtio/openyurt/pkg/yurthub/credentialprovider"
 	"github.com/openyurtio/openyurt/pkg/yurthub/kubernetes/rest"
 	ota "github.com/openyurtio/openyurt/pkg/yurthub/otaupdate"
+	"github.com/openyurtio/openyurt/pkg/yurthub/poolcoordinator/election"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util"
 )
 
@@ -67,6 +69,40 @@ func RunYurtHubServers(cfg *config.YurtHubConfiguration,
 		}
 	}
 
+	if cfg.DualStackDummyProxyServing != nil {
+		if err := cfg.DualStackDummyProxyServing.Serve(proxyHandler, 0, stopCh); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DualStackSecureProxyServing != nil {
+		if _, err := cfg.DualStackSecureProxyServing.Serve(proxyHandler, 0, stopCh); err != nil {
+			return err
+		}
+	}
+
+	if cfg.RedirectSecureProxyServing != nil {
+		if _, err := cfg.RedirectSecureProxyServing.Serve(proxyHandler, 0, stopCh); err != nil {
+			return err
+		}
+	}
+
+	// start credential provider server on the unix socket for kubelet image credential provider plugin
+	if cfg.CredentialProviderServing != nil && cfg.CredentialProvider != nil {
+		credentialHandler := mux.NewRouter()
+		credentialHandler.Handle(credentialprovider.CredentialProviderPath, cfg.CredentialProvider).Methods("POST")
+		if err := cfg.CredentialProviderServing.Serve(credentialHandler, 0, stopCh); err != nil {
+			return err
+		}
+	}
+
+	// start site view server for serving the snapshot of pool on the site-local network
+	if cfg.SiteViewServing != nil && cfg.SiteView != nil {
+		if _, err := cfg.SiteViewServing.Serve(cfg.SiteView, 0, stopCh); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -78,6 +114,21 @@ func registerHandlers(c *mux.Router, cfg *config.YurtHubConfiguration, rest *res
 	// register handler for health check
 	c.HandleFunc("/v1/healthz", healthz).Methods("GET")
 
+	// register handler for switching over to the standby disaster-recovery cluster
+	if cfg.StandbyManager != nil {
+		c.Handle("/v1/switchover", cfg.StandbyManager).Methods("POST")
+	}
+
+	// register handler for time synchronization status of node
+	if cfg.TimeSyncMonitor != nil {
+		c.Handle("/v1/timesync", cfg.TimeSyncMonitor).Methods("GET")
+	}
+
+	// register handler for electing the leaders of pool-scoped workloads
+	if cfg.PoolElection != nil {
+		c.Handle(election.ElectionPath, cfg.PoolElection).Methods("GET", "POST", "DELETE")
+	}
+
 	// register handler for profile
 	if cfg.EnableProfiling {
 		profile.Install(c)
@@ -88,8 +139,15 @@ func registerHandlers(c *mux.Router, cfg *config.YurtHubConfiguration, rest *res
 
 	// register handler for ota upgrade
 	c.Handle("/pods", ota.GetPods(cfg.StorageWrapper)).Methods("GET")
+	c.Handle("/openyurt.io/v1/ota/pods", ota.GetUpgradablePods(cfg.StorageWrapper)).Methods("GET")
+	c.Handle("/openyurt.io/v1/namespaces/{ns}/daemonsets/{dsname}/upgrade",
+		ota.GetUpgradeStatus(cfg.StorageWrapper)).Methods("GET")
 	c.Handle("/openyurt.io/v1/namespaces/{ns}/pods/{podname}/upgrade",
 		ota.HealthyCheck(rest, cfg.NodeName, ota.UpdatePod)).Methods("POST")
+	c.Handle("/openyurt.io/v1/ota/approval",
+		ota.HealthyCheck(rest, cfg.NodeName, ota.Authenticate(ota.ApproveUpgrades))).Methods("POST")
+	c.Handle("/openyurt.io/v1/ota/approval",
+		ota.HealthyCheck(rest, cfg.NodeName, ota.Authenticate(ota.RevokeUpgrades))).Methods("DELETE")
 }
 
 // healthz returns ok for healthz request
@@ -0,0 +1,385 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package siteview
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+const (
+	// listTimeout is the timeout of listing resources from pool-coordinator
+	listTimeout = 10 * time.Second
+	realm       = "yurthub site view"
+	// redactedValue replaces the values of environment variables in the site view of pods
+	redactedValue = "<redacted>"
+)
+
+// Service serves a read-only snapshot of nodes, pods and events of the pool on the leader yurthub.
+// The resources are listed from pool-coordinator, which holds the caches uploaded by all yurthubs
+// in the pool, and merged with the local cache of the node, the fresher one by resource version wins.
+// The snapshot is served on the same paths as kube-apiserver, so it can be read by kubectl and
+// the clients of kubernetes api, and a summary page is served on / for browsers. The environment
+// variables of pods are redacted, because they may carry the credentials of workloads.
+type Service struct {
+	username       []byte
+	password       []byte
+	localStore     cachemanager.StorageWrapper
+	localEvents    func() []*corev1.Event
+	leaseNamespace string
+	leaseName      string
+	router         *mux.Router
+
+	sync.RWMutex
+	client   kubernetes.Interface
+	isLeader func() bool
+}
+
+// NewService creates a Service authenticated by the basic auth credentials in credentialsFile, which
+// is in format of username:password. localEvents returns the events which are not uploaded to the cloud
+// yet, it can be nil. The leader of pool is recorded in lease leaseNamespace/leaseName of pool-coordinator.
+func NewService(credentialsFile string, localStore cachemanager.StorageWrapper, localEvents func() []*corev1.Event, leaseNamespace, leaseName string) (*Service, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read site view credentials, %w", err)
+	}
+	username, password, found := strings.Cut(strings.TrimSpace(string(data)), ":")
+	if !found || len(username) == 0 || len(password) == 0 {
+		return nil, fmt.Errorf("site view credentials in %s should be in format username:password", credentialsFile)
+	}
+
+	s := &Service{
+		username:       []byte(username),
+		password:       []byte(password),
+		localStore:     localStore,
+		localEvents:    localEvents,
+		leaseNamespace: leaseNamespace,
+		leaseName:      leaseName,
+		router:         mux.NewRouter(),
+	}
+	s.router.HandleFunc("/", s.serveSummary).Methods("GET")
+	s.router.HandleFunc("/api/v1/nodes", s.serveNodes).Methods("GET")
+	s.router.HandleFunc("/api/v1/pods", s.servePods).Methods("GET")
+	s.router.HandleFunc("/api/v1/namespaces/{namespace}/pods", s.servePods).Methods("GET")
+	s.router.HandleFunc("/api/v1/events", s.serveEvents).Methods("GET")
+	s.router.HandleFunc("/api/v1/namespaces/{namespace}/events", s.serveEvents).Methods("GET")
+	return s, nil
+}
+
+// SetBackend sets the client of pool-coordinator and the checker of leader, the snapshot is not
+// served until they are set.
+func (s *Service) SetBackend(client kubernetes.Interface, isLeader func() bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.client = client
+	s.isLeader = isLeader
+}
+
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	if !ok || subtle.ConstantTimeCompare([]byte(username), s.username) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), s.password) != 1 {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.router.ServeHTTP(w, r)
+}
+
+// snapshot is the resources of pool, warnings are the reasons that the resources may be incomplete.
+type snapshot struct {
+	nodes    []corev1.Node
+	pods     []corev1.Pod
+	events   []corev1.Event
+	warnings []string
+}
+
+// backend returns the client of pool-coordinator, or writes the error and returns nil
+// if the snapshot can't be served by this yurthub.
+func (s *Service) backend(w http.ResponseWriter, r *http.Request) kubernetes.Interface {
+	s.RLock()
+	client, isLeader := s.client, s.isLeader
+	s.RUnlock()
+	if client == nil {
+		http.Error(w, "pool coordinator is not ready", http.StatusServiceUnavailable)
+		return nil
+	}
+	if !isLeader() {
+		msg := "site view is served by the leader yurthub of pool"
+		if leader := s.leader(r.Context(), client); len(leader) != 0 {
+			msg = fmt.Sprintf("%s, which is on node %s", msg, leader)
+		}
+		http.Error(w, msg, http.StatusServiceUnavailable)
+		return nil
+	}
+	return client
+}
+
+// leader returns the holder of leader lease, or empty if it can't be got.
+func (s *Service) leader(ctx context.Context, client kubernetes.Interface) string {
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	lease, err := client.CoordinationV1().Leases(s.leaseNamespace).Get(ctx, s.leaseName, metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func (s *Service) serveNodes(w http.ResponseWriter, r *http.Request) {
+	client := s.backend(w, r)
+	if client == nil {
+		return
+	}
+	snap := &snapshot{}
+	s.collectNodes(r.Context(), client, snap)
+	list := &corev1.NodeList{Items: snap.nodes}
+	list.APIVersion, list.Kind = "v1", "NodeList"
+	writeList(w, list, snap.warnings)
+}
+
+func (s *Service) servePods(w http.ResponseWriter, r *http.Request) {
+	client := s.backend(w, r)
+	if client == nil {
+		return
+	}
+	snap := &snapshot{}
+	s.collectPods(r.Context(), client, mux.Vars(r)["namespace"], snap)
+	list := &corev1.PodList{Items: snap.pods}
+	list.APIVersion, list.Kind = "v1", "PodList"
+	writeList(w, list, snap.warnings)
+}
+
+func (s *Service) serveEvents(w http.ResponseWriter, r *http.Request) {
+	client := s.backend(w, r)
+	if client == nil {
+		return
+	}
+	snap := &snapshot{}
+	s.collectEvents(r.Context(), client, mux.Vars(r)["namespace"], snap)
+	list := &corev1.EventList{Items: snap.events}
+	list.APIVersion, list.Kind = "v1", "EventList"
+	writeList(w, list, snap.warnings)
+}
+
+func writeList(w http.ResponseWriter, list runtime.Object, warnings []string) {
+	data, err := json.Marshal(list)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode list, %v", err), http.StatusInternalServerError)
+		return
+	}
+	// warnings are shown by kubectl as the warnings of kube-apiserver
+	for _, warning := range warnings {
+		w.Header().Add("Warning", fmt.Sprintf("299 - %q", warning))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (s *Service) collectNodes(ctx context.Context, client kubernetes.Interface, snap *snapshot) {
+	objs := make(map[string]runtime.Object)
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	if list, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err != nil {
+		snap.warn("failed to list nodes from pool coordinator, %v", err)
+	} else {
+		for i := range list.Items {
+			mergeNewer(objs, &list.Items[i])
+		}
+	}
+	for _, obj := range s.listLocal("nodes", snap) {
+		mergeNewer(objs, obj)
+	}
+
+	for _, key := range sortedKeys(objs) {
+		if node, ok := objs[key].(*corev1.Node); ok {
+			snap.nodes = append(snap.nodes, *node)
+		}
+	}
+}
+
+func (s *Service) collectPods(ctx context.Context, client kubernetes.Interface, namespace string, snap *snapshot) {
+	objs := make(map[string]runtime.Object)
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	if list, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		snap.warn("failed to list pods from pool coordinator, %v", err)
+	} else {
+		for i := range list.Items {
+			mergeNewer(objs, &list.Items[i])
+		}
+	}
+	for _, obj := range s.listLocal("pods", snap) {
+		if pod, ok := obj.(*corev1.Pod); ok && (len(namespace) == 0 || pod.Namespace == namespace) {
+			mergeNewer(objs, pod)
+		}
+	}
+
+	for _, key := range sortedKeys(objs) {
+		if pod, ok := objs[key].(*corev1.Pod); ok {
+			snap.pods = append(snap.pods, *redactPod(pod))
+		}
+	}
+}
+
+func (s *Service) collectEvents(ctx context.Context, client kubernetes.Interface, namespace string, snap *snapshot) {
+	objs := make(map[string]runtime.Object)
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	if list, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		snap.warn("failed to list events from pool coordinator, %v", err)
+	} else {
+		for i := range list.Items {
+			mergeNewer(objs, &list.Items[i])
+		}
+	}
+	// the events which are recorded when the cloud is unreachable
+	if s.localEvents != nil {
+		for _, event := range s.localEvents() {
+			if len(namespace) == 0 || event.Namespace == namespace {
+				mergeNewer(objs, event)
+			}
+		}
+	}
+
+	for _, obj := range objs {
+		if event, ok := obj.(*corev1.Event); ok {
+			snap.events = append(snap.events, *event)
+		}
+	}
+	// the latest events come first
+	sort.SliceStable(snap.events, func(i, j int) bool {
+		return lastSeen(&snap.events[j]).Before(lastSeen(&snap.events[i]))
+	})
+}
+
+// listLocal lists the resources of kubelet in local cache.
+func (s *Service) listLocal(resource string, snap *snapshot) []runtime.Object {
+	if s.localStore == nil {
+		return nil
+	}
+	key, err := s.localStore.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: resource,
+		Version:   "v1",
+	})
+	if err != nil {
+		klog.Errorf("failed to get key of local %s for site view, %v", resource, err)
+		return nil
+	}
+	objs, err := s.localStore.List(key)
+	if err != nil && err != storage.ErrStorageNotFound {
+		snap.warn("failed to list %s from local cache, %v", resource, err)
+	}
+	return objs
+}
+
+// redactPod returns a copy of pod without the values of environment variables and the sources
+// of them, and the last applied configuration which includes them too.
+func redactPod(pod *corev1.Pod) *corev1.Pod {
+	pod = pod.DeepCopy()
+	delete(pod.Annotations, corev1.LastAppliedConfigAnnotation)
+	redactEnv := func(env []corev1.EnvVar) {
+		for i := range env {
+			if len(env[i].Value) != 0 || env[i].ValueFrom != nil {
+				env[i].Value, env[i].ValueFrom = redactedValue, nil
+			}
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		redactEnv(pod.Spec.InitContainers[i].Env)
+		pod.Spec.InitContainers[i].EnvFrom = nil
+	}
+	for i := range pod.Spec.Containers {
+		redactEnv(pod.Spec.Containers[i].Env)
+		pod.Spec.Containers[i].EnvFrom = nil
+	}
+	for i := range pod.Spec.EphemeralContainers {
+		redactEnv(pod.Spec.EphemeralContainers[i].Env)
+		pod.Spec.EphemeralContainers[i].EnvFrom = nil
+	}
+	return pod
+}
+
+func (snap *snapshot) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	klog.Warning(msg)
+	snap.warnings = append(snap.warnings, msg)
+}
+
+// mergeNewer adds obj into objs if there's no object with the same namespace and name in objs,
+// or the object in objs is older than obj.
+func mergeNewer(objs map[string]runtime.Object, obj runtime.Object) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	key := accessor.GetNamespace() + "/" + accessor.GetName()
+	if old, ok := objs[key]; ok && resourceVersion(old) >= resourceVersion(obj) {
+		return
+	}
+	objs[key] = obj
+}
+
+func resourceVersion(obj runtime.Object) uint64 {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return 0
+	}
+	rv, _ := strconv.ParseUint(accessor.GetResourceVersion(), 10, 64)
+	return rv
+}
+
+func sortedKeys(objs map[string]runtime.Object) []string {
+	keys := make([]string, 0, len(objs))
+	for key := range objs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func lastSeen(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package siteview
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+)
+
+func pod(name, nodeName, rv string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, ResourceVersion: rv},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func newTestService(t *testing.T, isLeader bool) *Service {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(credentialsFile, []byte("admin:secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write credentials, %v", err)
+	}
+
+	store, err := disk.NewDiskStorage(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	localStore := cachemanager.NewStorageWrapper(store)
+	// the pod of local node is fresher than the one in pool coordinator
+	localPod := pod("foo", "node1", "20", corev1.PodRunning)
+	key, _ := localStore.KeyFunc(storage.KeyBuildInfo{Component: "kubelet", Resources: "pods", Version: "v1", Namespace: "default", Name: "foo"})
+	if err := localStore.Create(key, localPod); err != nil {
+		t.Fatalf("failed to cache pod, %v", err)
+	}
+
+	localEvents := func() []*corev1.Event {
+		return []*corev1.Event{{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "kube-system", Name: "offline"},
+			Reason:         "Offline",
+			LastTimestamp:  metav1.Now(),
+			InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node1"},
+		}}
+	}
+
+	s, err := NewService(credentialsFile, localStore, localEvents, "kube-system", "yurthub")
+	if err != nil {
+		t.Fatalf("failed to create service, %v", err)
+	}
+
+	holder := "node2"
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		pod("foo", "node1", "10", corev1.PodPending),
+		pod("bar", "node2", "11", corev1.PodRunning),
+		&corev1.Event{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "e1"}, Reason: "Started"},
+		&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "yurthub"},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder},
+		},
+	)
+	s.SetBackend(client, func() bool { return isLeader })
+	return s
+}
+
+func get(s *Service, path string, auth bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if auth {
+		req.SetBasicAuth("admin", "secret")
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestService(t, true)
+	if w := get(s, "/api/v1/nodes", false); w.Code != http.StatusUnauthorized || len(w.Header().Get("WWW-Authenticate")) == 0 {
+		t.Errorf("expect unauthorized with basic auth challenge, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/nodes", nil)
+	req.SetBasicAuth("admin", "wrong")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized for wrong password, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/pods", nil)
+	req.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expect site view is read-only, got %d", w.Code)
+	}
+}
+
+func TestNotLeader(t *testing.T) {
+	s := newTestService(t, false)
+	w := get(s, "/api/v1/pods", true)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "node2") {
+		t.Errorf("expect service unavailable with the leader node, got %d, %s", w.Code, w.Body.String())
+	}
+
+	s.SetBackend(nil, nil)
+	if w := get(s, "/api/v1/pods", true); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect service unavailable without backend, got %d", w.Code)
+	}
+}
+
+func TestServeLists(t *testing.T) {
+	s := newTestService(t, true)
+
+	w := get(s, "/api/v1/pods", true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to list pods, %d, %s", w.Code, w.Body.String())
+	}
+	var pods corev1.PodList
+	if err := json.Unmarshal(w.Body.Bytes(), &pods); err != nil {
+		t.Fatalf("failed to decode pods, %v", err)
+	}
+	if pods.Kind != "PodList" || len(pods.Items) != 2 {
+		t.Fatalf("expect 2 pods in PodList, got %s with %d pods", pods.Kind, len(pods.Items))
+	}
+	for _, p := range pods.Items {
+		if p.Name == "foo" && p.ResourceVersion != "20" {
+			t.Errorf("expect the fresher pod in local cache, got rv %s", p.ResourceVersion)
+		}
+	}
+
+	w = get(s, "/api/v1/nodes", true)
+	var nodes corev1.NodeList
+	if err := json.Unmarshal(w.Body.Bytes(), &nodes); err != nil || len(nodes.Items) != 2 {
+		t.Errorf("expect 2 nodes, got %d, %v", len(nodes.Items), err)
+	}
+
+	w = get(s, "/api/v1/namespaces/kube-system/events", true)
+	var events corev1.EventList
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil || len(events.Items) != 1 || events.Items[0].Reason != "Offline" {
+		t.Errorf("expect the local event in kube-system, got %v, %v", events.Items, err)
+	}
+	w = get(s, "/api/v1/events", true)
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil || len(events.Items) != 2 {
+		t.Errorf("expect 2 events, got %d, %v", len(events.Items), err)
+	}
+}
+
+func TestServeSummary(t *testing.T) {
+	s := newTestService(t, true)
+	w := get(s, "/", true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to get summary, %d, %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"Nodes (2)", "Pods (2)", "node2", "Offline"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expect %q in summary", expected)
+		}
+	}
+}
+
+func TestInvalidCredentials(t *testing.T) {
+	credentialsFile := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credentialsFile, []byte("admin"), 0600); err != nil {
+		t.Fatalf("failed to write credentials, %v", err)
+	}
+	if _, err := NewService(credentialsFile, nil, nil, "kube-system", "yurthub"); err == nil {
+		t.Errorf("expect error for credentials without password")
+	}
+}
+
+func TestRedactPod(t *testing.T) {
+	p := pod("foo", "node1", "10", corev1.PodRunning)
+	p.Annotations = map[string]string{corev1.LastAppliedConfigAnnotation: `{"spec":{}}`, "foo": "bar"}
+	p.Spec.Containers = []corev1.Container{{
+		Name: "app",
+		Env: []corev1.EnvVar{
+			{Name: "PASSWORD", Value: "secret"},
+			{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "token"}}},
+			{Name: "EMPTY"},
+		},
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{}}},
+	}}
+	p.Spec.InitContainers = []corev1.Container{{Name: "init", Env: []corev1.EnvVar{{Name: "KEY", Value: "secret"}}}}
+
+	redacted := redactPod(p)
+	if _, ok := redacted.Annotations[corev1.LastAppliedConfigAnnotation]; ok || redacted.Annotations["foo"] != "bar" {
+		t.Errorf("expect only last applied configuration is removed, but got %v", redacted.Annotations)
+	}
+	env := redacted.Spec.Containers[0].Env
+	if env[0].Value != redactedValue || env[1].Value != redactedValue || env[1].ValueFrom != nil || len(env[2].Value) != 0 {
+		t.Errorf("expect values of env are redacted, but got %v", env)
+	}
+	if redacted.Spec.Containers[0].EnvFrom != nil {
+		t.Errorf("expect envFrom is removed, but got %v", redacted.Spec.Containers[0].EnvFrom)
+	}
+	if redacted.Spec.InitContainers[0].Env[0].Value != redactedValue {
+		t.Errorf("expect env of init container is redacted, but got %v", redacted.Spec.InitContainers[0].Env)
+	}
+	if p.Spec.Containers[0].Env[0].Value != "secret" {
+		t.Errorf("expect original pod is not changed")
+	}
+}
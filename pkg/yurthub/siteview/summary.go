@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package siteview
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// maxSummaryEvents is the number of latest events shown in the summary page
+const maxSummaryEvents = 50
+
+var summaryFuncs = template.FuncMap{
+	"restarts": func(pod corev1.Pod) int32 {
+		var restarts int32
+		for _, cs := range pod.Status.ContainerStatuses {
+			restarts += cs.RestartCount
+		}
+		return restarts
+	},
+	"lastSeen": func(event corev1.Event) string {
+		return lastSeen(&event).Format(time.RFC3339)
+	},
+}
+
+var summaryTemplate = template.Must(template.New("summary").Funcs(summaryFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Site view</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse;margin-bottom:2em}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}.warn{color:#b00}</style>
+</head>
+<body>
+<h1>Site view</h1>
+<p>Snapshot at {{.Time}}</p>
+{{range .Warnings}}<p class="warn">{{.}}</p>{{end}}
+<h2>Nodes ({{len .Nodes}})</h2>
+<table><tr><th>Name</th><th>Ready</th><th>Pods</th></tr>
+{{range .Nodes}}<tr><td>{{.Name}}</td><td>{{.Ready}}</td><td>{{.Pods}}</td></tr>{{end}}
+</table>
+<h2>Pods ({{len .Pods}})</h2>
+<table><tr><th>Namespace</th><th>Name</th><th>Node</th><th>Phase</th><th>Restarts</th></tr>
+{{range .Pods}}<tr><td>{{.Namespace}}</td><td>{{.Name}}</td><td>{{.Spec.NodeName}}</td><td>{{.Status.Phase}}</td><td>{{restarts .}}</td></tr>{{end}}
+</table>
+<h2>Latest events</h2>
+<table><tr><th>Last seen</th><th>Type</th><th>Reason</th><th>Object</th><th>Message</th></tr>
+{{range .Events}}<tr><td>{{lastSeen .}}</td><td>{{.Type}}</td><td>{{.Reason}}</td><td>{{.InvolvedObject.Kind}}/{{.InvolvedObject.Namespace}}/{{.InvolvedObject.Name}}</td><td>{{.Message}}</td></tr>{{end}}
+</table>
+</body>
+</html>
+`))
+
+type nodeSummary struct {
+	Name  string
+	Ready corev1.ConditionStatus
+	Pods  int
+}
+
+// serveSummary serves a page of the snapshot for browsers.
+func (s *Service) serveSummary(w http.ResponseWriter, r *http.Request) {
+	client := s.backend(w, r)
+	if client == nil {
+		return
+	}
+	snap := &snapshot{}
+	s.collectNodes(r.Context(), client, snap)
+	s.collectPods(r.Context(), client, "", snap)
+	s.collectEvents(r.Context(), client, "", snap)
+
+	podsOfNode := make(map[string]int)
+	for i := range snap.pods {
+		podsOfNode[snap.pods[i].Spec.NodeName]++
+	}
+	nodes := make([]nodeSummary, 0, len(snap.nodes))
+	for i := range snap.nodes {
+		ns := nodeSummary{Name: snap.nodes[i].Name, Ready: corev1.ConditionUnknown, Pods: podsOfNode[snap.nodes[i].Name]}
+		for _, cond := range snap.nodes[i].Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				ns.Ready = cond.Status
+			}
+		}
+		nodes = append(nodes, ns)
+	}
+	events := snap.events
+	if len(events) > maxSummaryEvents {
+		events = events[:maxSummaryEvents]
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := summaryTemplate.Execute(w, map[string]interface{}{
+		"Time":     time.Now().Format(time.RFC3339),
+		"Warnings": snap.warnings,
+		"Nodes":    nodes,
+		"Pods":     snap.pods,
+		"Events":   events,
+	})
+	if err != nil {
+		klog.Errorf("failed to render site view summary, %v", err)
+	}
+}
@@ -0,0 +1,411 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standby
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	kubeconfigutil "github.com/openyurtio/openyurt/pkg/util/kubeconfig"
+	"github.com/openyurtio/openyurt/pkg/yurthub/certificate"
+)
+
+const (
+	// ClusterPrimary is the cluster which yurthub is started for by --server-addr
+	ClusterPrimary = "primary"
+	// ClusterStandby is the disaster-recovery cluster which yurthub is started for by --standby-server-addr
+	ClusterStandby = "standby"
+
+	// SwitchoverAnnotation is the annotation of node in the active cluster for triggering the switchover
+	// from the cloud, the value is the cluster which the node is re-homed to.
+	SwitchoverAnnotation = "openyurt.io/switchover-cluster"
+	// SwitchoverFailureAnnotation is the annotation of node in the active cluster which records why
+	// the switchover triggered by SwitchoverAnnotation is not done yet.
+	SwitchoverFailureAnnotation = "openyurt.io/switchover-failure"
+
+	// StandbyDirName is the dir in root dir for the files of standby cluster
+	StandbyDirName = "standby"
+
+	stateFileName   = "dr-state.json"
+	prepareTimeout  = 5 * time.Minute
+	syncPeriod      = 30 * time.Second
+	registerTimeout = 30 * time.Second
+)
+
+// kubeletLabels are the labels in kubernetes.io and k8s.io domains which kubelet is allowed to set
+// on its own node by NodeRestriction admission.
+var kubeletLabels = map[string]struct{}{
+	corev1.LabelHostname:                {},
+	corev1.LabelInstanceType:            {},
+	corev1.LabelInstanceTypeStable:      {},
+	corev1.LabelOSStable:                {},
+	corev1.LabelArchStable:              {},
+	corev1.LabelTopologyZone:            {},
+	corev1.LabelTopologyRegion:          {},
+	corev1.LabelFailureDomainBetaZone:   {},
+	corev1.LabelFailureDomainBetaRegion: {},
+	"beta.kubernetes.io/os":             {},
+	"beta.kubernetes.io/arch":           {},
+}
+
+// Cluster is the control plane which yurthub registers the node to
+type Cluster struct {
+	ServerAddr   string
+	JoinToken    string
+	CACertHashes []string
+	CAFile       string
+	RootDir      string
+}
+
+type state struct {
+	Active     string    `json:"active"`
+	SwitchedAt time.Time `json:"switchedAt"`
+}
+
+// LoadActiveCluster returns the active cluster recorded in rootDir of primary cluster,
+// the primary cluster is active if no switchover happened.
+func LoadActiveCluster(rootDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(rootDir, stateFileName))
+	if os.IsNotExist(err) {
+		return ClusterPrimary, nil
+	} else if err != nil {
+		return "", err
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", fmt.Errorf("failed to decode %s, %w", stateFileName, err)
+	}
+	if s.Active != ClusterPrimary && s.Active != ClusterStandby {
+		return "", fmt.Errorf("active cluster %q is invalid", s.Active)
+	}
+	return s.Active, nil
+}
+
+func saveActiveCluster(rootDir, active string) error {
+	data, err := json.Marshal(&state{Active: active, SwitchedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(rootDir, stateFileName)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Manager keeps the credentials of the dormant cluster ready, and re-homes the node to the dormant
+// cluster when the switchover is triggered by the cloud or locally. yurthub exits after switchover,
+// and serves for the new active cluster after it's restarted.
+type Manager struct {
+	nodeName string
+	stateDir string
+	active   string
+	client   kubernetes.Interface
+	// newDormantClient creates the client of dormant cluster by the kubeconfig of hub, which is
+	// used for registering the node to the dormant cluster before switchover.
+	newDormantClient func(hubConfFile string) (kubernetes.Interface, error)
+	// newCertManager creates the certificate manager of dormant cluster, it's recreated
+	// if the credentials are not ready in prepareTimeout, e.g. the cluster is unreachable.
+	newCertManager func() (certificate.YurtCertificateManager, error)
+	sync.Mutex
+	certManager certificate.YurtCertificateManager
+	preparedAt  time.Time
+	switched    chan struct{}
+	switchOnce  sync.Once
+}
+
+// NewManager creates a Manager for node, the switchover state is saved in stateDir. client is used
+// for watching the switchover annotation of node in the active cluster.
+func NewManager(nodeName, stateDir, active string, client kubernetes.Interface, newCertManager func() (certificate.YurtCertificateManager, error)) *Manager {
+	return &Manager{
+		nodeName:       nodeName,
+		stateDir:       stateDir,
+		active:         active,
+		client:         client,
+		newCertManager: newCertManager,
+		newDormantClient: func(hubConfFile string) (kubernetes.Interface, error) {
+			return kubeconfigutil.ClientSetFromFile(hubConfFile)
+		},
+		switched: make(chan struct{}),
+	}
+}
+
+// Dormant returns the cluster which the node can be re-homed to
+func (m *Manager) Dormant() string {
+	if m.active == ClusterPrimary {
+		return ClusterStandby
+	}
+	return ClusterPrimary
+}
+
+// Switched returns a channel which is closed after the node is re-homed to the dormant cluster
+func (m *Manager) Switched() <-chan struct{} {
+	if m == nil {
+		return nil
+	}
+	return m.switched
+}
+
+// Run prepares the credentials of the dormant cluster and checks the switchover annotation of node
+// until stopCh is closed.
+func (m *Manager) Run(stopCh <-chan struct{}) {
+	go wait.Until(m.prepare, syncPeriod, stopCh)
+	wait.Until(m.checkAnnotation, syncPeriod, stopCh)
+}
+
+// prepare starts the certificate manager of the dormant cluster, which bootstraps and rotates the
+// certificates as the active one does, so the node can be re-homed without the join token.
+func (m *Manager) prepare() {
+	m.Lock()
+	defer m.Unlock()
+	if m.certManager != nil {
+		if time.Since(m.preparedAt) < prepareTimeout || m.certManager.Ready() {
+			return
+		}
+		klog.Warningf("credentials of %s cluster are not ready in %v, recreate certificate manager", m.Dormant(), prepareTimeout)
+		m.certManager.Stop()
+		m.certManager = nil
+	}
+
+	certManager, err := m.newCertManager()
+	if err != nil {
+		klog.Errorf("failed to create certificate manager for %s cluster, %v", m.Dormant(), err)
+		return
+	}
+	certManager.Start()
+	m.certManager, m.preparedAt = certManager, time.Now()
+	klog.Infof("start preparing credentials of %s cluster", m.Dormant())
+}
+
+// Ready checks the credentials of the dormant cluster are ready
+func (m *Manager) Ready() bool {
+	m.Lock()
+	defer m.Unlock()
+	return m.certManager != nil && m.certManager.Ready()
+}
+
+// checkAnnotation switches over if the node in the active cluster is annotated with the dormant
+// cluster. The annotation is kept until the credentials of dormant cluster are ready, and removed
+// before switchover, so the node isn't switched back by it. The failures are recorded in
+// SwitchoverFailureAnnotation of node.
+func (m *Manager) checkAnnotation() {
+	node, err := m.client.CoreV1().Nodes().Get(context.Background(), m.nodeName, metav1.GetOptions{})
+	if err != nil {
+		klog.V(4).Infof("failed to get node %s for checking switchover, %v", m.nodeName, err)
+		return
+	}
+	target, ok := node.Annotations[SwitchoverAnnotation]
+	if !ok || target == m.active {
+		return
+	}
+	if target != m.Dormant() {
+		m.recordFailure(node.Annotations, "", fmt.Sprintf("switchover to cluster %q is not supported, only %s cluster can be switched to", target, m.Dormant()))
+		return
+	}
+	if !m.Ready() {
+		m.recordFailure(node.Annotations, "", fmt.Sprintf("credentials of %s cluster are not ready", target))
+		return
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null}}}`, SwitchoverAnnotation, SwitchoverFailureAnnotation))
+	if _, err := m.client.CoreV1().Nodes().Patch(context.Background(), m.nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.Errorf("failed to remove switchover annotation of node %s, %v", m.nodeName, err)
+		return
+	}
+	if err := m.Switchover(target); err != nil {
+		// restore the switchover annotation, so the switchover is retried
+		m.recordFailure(nil, target, fmt.Sprintf("failed to switch over to %s cluster, %v", target, err))
+	}
+}
+
+// recordFailure records the reason of switchover failure in the annotation of node if it's changed,
+// and restores the switchover annotation to target if it's specified.
+func (m *Manager) recordFailure(annotations map[string]string, target, reason string) {
+	klog.Errorf("node %s is not switched over, %s", m.nodeName, reason)
+	if annotations[SwitchoverFailureAnnotation] == reason {
+		return
+	}
+	failure := map[string]string{SwitchoverFailureAnnotation: reason}
+	if len(target) != 0 {
+		failure[SwitchoverAnnotation] = target
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": failure}})
+	if err != nil {
+		klog.Errorf("failed to marshal switchover failure, %v", err)
+		return
+	}
+	if _, err := m.client.CoreV1().Nodes().Patch(context.Background(), m.nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.Errorf("failed to record switchover failure of node %s, %v", m.nodeName, err)
+	}
+}
+
+// Switchover re-homes the node to the target cluster, the credentials of target cluster must be ready.
+// kubelet registers the node only once after it's started, so the node is registered to the target
+// cluster before switchover, and kubelet keeps updating the status of it through yurthub.
+func (m *Manager) Switchover(target string) error {
+	if target != m.Dormant() {
+		return fmt.Errorf("node can only be switched over to %s cluster", m.Dormant())
+	}
+	m.Lock()
+	certManager := m.certManager
+	m.Unlock()
+	if certManager == nil || !certManager.Ready() {
+		return fmt.Errorf("credentials of %s cluster are not ready", target)
+	}
+	if err := m.registerNode(certManager); err != nil {
+		return fmt.Errorf("failed to register node to %s cluster, %w", target, err)
+	}
+	if err := saveActiveCluster(m.stateDir, target); err != nil {
+		return fmt.Errorf("failed to save switchover state, %w", err)
+	}
+	klog.Infof("node %s is re-homed from %s cluster to %s cluster", m.nodeName, m.active, target)
+	m.switchOnce.Do(func() {
+		close(m.switched)
+	})
+	return nil
+}
+
+// registerNode creates the node in the dormant cluster with the credentials of it. The labels, taints
+// and provider id are copied from the node in the active cluster, and the well-known labels are set
+// if the active cluster is unreachable, which is usually why the node is switched over.
+func (m *Manager) registerNode(certManager certificate.YurtCertificateManager) error {
+	client, err := m.newDormantClient(certManager.GetHubConfFile())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), registerTimeout)
+	defer cancel()
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: m.nodeName,
+			Labels: map[string]string{
+				corev1.LabelHostname:   m.nodeName,
+				corev1.LabelOSStable:   runtime.GOOS,
+				corev1.LabelArchStable: runtime.GOARCH,
+			},
+		},
+	}
+	if active, err := m.client.CoreV1().Nodes().Get(ctx, m.nodeName, metav1.GetOptions{}); err == nil {
+		node = newDormantNode(active)
+	} else {
+		klog.Warningf("failed to get node %s from %s cluster, register it with well-known labels, %v", m.nodeName, m.active, err)
+	}
+	if _, err := client.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// newDormantNode returns the node for registering to the dormant cluster, only the labels which can
+// be set by kubelet are kept, and the taints managed by node controllers are removed.
+func newDormantNode(active *corev1.Node) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   active.Name,
+			Labels: make(map[string]string),
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID:    active.Spec.ProviderID,
+			Unschedulable: active.Spec.Unschedulable,
+		},
+	}
+	for k, v := range active.Labels {
+		if isKubeletLabel(k) {
+			node.Labels[k] = v
+		}
+	}
+	for _, taint := range active.Spec.Taints {
+		if !strings.HasPrefix(taint.Key, "node.kubernetes.io/") && !strings.HasPrefix(taint.Key, "node.cloudprovider.kubernetes.io/") {
+			node.Spec.Taints = append(node.Spec.Taints, taint)
+		}
+	}
+	return node
+}
+
+// isKubeletLabel checks the label can be set by kubelet on its own node.
+func isKubeletLabel(key string) bool {
+	if _, ok := kubeletLabels[key]; ok {
+		return true
+	}
+	namespace := ""
+	if i := strings.Index(key, "/"); i >= 0 {
+		namespace = key[:i]
+	}
+	if strings.HasPrefix(namespace, "kubelet.kubernetes.io") || strings.HasPrefix(namespace, "node.kubernetes.io") {
+		return true
+	}
+	for _, domain := range []string{"kubernetes.io", "k8s.io"} {
+		if namespace == domain || strings.HasSuffix(namespace, "."+domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP triggers the switchover locally by POST /v1/switchover?cluster=<primary|standby>, only the
+// requests from localhost are allowed, because the node is re-homed without any authentication.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !isLocalRequest(req) {
+		http.Error(w, "switchover is only allowed from localhost", http.StatusForbidden)
+		return
+	}
+	target := req.URL.Query().Get("cluster")
+	if len(target) == 0 {
+		target = m.Dormant()
+	}
+	if target == m.active {
+		http.Error(w, fmt.Sprintf("node is already in %s cluster", target), http.StatusConflict)
+		return
+	}
+	if target != m.Dormant() {
+		http.Error(w, fmt.Sprintf("cluster %q is not supported", target), http.StatusBadRequest)
+		return
+	}
+	if err := m.Switchover(target); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "node is re-homed to %s cluster, yurthub is restarting\n", target)
+}
+
+func isLocalRequest(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
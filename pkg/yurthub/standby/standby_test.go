@@ -0,0 +1,271 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standby
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/certificate"
+)
+
+type fakeCertManager struct {
+	certificate.YurtCertificateManager
+	ready   bool
+	started bool
+	stopped bool
+}
+
+func (f *fakeCertManager) Start()      { f.started = true }
+func (f *fakeCertManager) Stop()       { f.stopped = true }
+func (f *fakeCertManager) Ready() bool { return f.ready }
+func (f *fakeCertManager) GetHubConfFile() string {
+	return "yurthub.conf"
+}
+
+func newTestManager(t *testing.T, ready bool, annotations map[string]string) (*Manager, *fakeCertManager) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: annotations},
+	}
+	certManager := &fakeCertManager{ready: ready}
+	m := NewManager("foo", t.TempDir(), ClusterPrimary, fake.NewSimpleClientset(node), func() (certificate.YurtCertificateManager, error) {
+		return certManager, nil
+	})
+	dormantClient := fake.NewSimpleClientset()
+	m.newDormantClient = func(string) (kubernetes.Interface, error) {
+		return dormantClient, nil
+	}
+	return m, certManager
+}
+
+func dormantNode(t *testing.T, m *Manager) (*corev1.Node, error) {
+	client, err := m.newDormantClient("")
+	if err != nil {
+		t.Fatalf("failed to create client of dormant cluster, %v", err)
+	}
+	return client.CoreV1().Nodes().Get(context.TODO(), m.nodeName, metav1.GetOptions{})
+}
+
+func isSwitched(m *Manager) bool {
+	select {
+	case <-m.Switched():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	testcases := map[string]struct {
+		cluster    string
+		remoteAddr string
+		ready      bool
+		statusCode int
+		active     string
+	}{
+		"switch over from remote address": {
+			cluster:    ClusterStandby,
+			remoteAddr: "192.168.0.10:34567",
+			ready:      true,
+			statusCode: http.StatusForbidden,
+			active:     ClusterPrimary,
+		},
+		"switch over to standby cluster": {
+			cluster:    ClusterStandby,
+			ready:      true,
+			statusCode: http.StatusOK,
+			active:     ClusterStandby,
+		},
+		"switch over to dormant cluster by default": {
+			ready:      true,
+			statusCode: http.StatusOK,
+			active:     ClusterStandby,
+		},
+		"credentials of standby cluster are not ready": {
+			cluster:    ClusterStandby,
+			statusCode: http.StatusServiceUnavailable,
+			active:     ClusterPrimary,
+		},
+		"node is already in primary cluster": {
+			cluster:    ClusterPrimary,
+			ready:      true,
+			statusCode: http.StatusConflict,
+			active:     ClusterPrimary,
+		},
+		"unknown cluster": {
+			cluster:    "foo",
+			ready:      true,
+			statusCode: http.StatusBadRequest,
+			active:     ClusterPrimary,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			m, _ := newTestManager(t, tc.ready, nil)
+			m.prepare()
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/switchover?cluster="+tc.cluster, nil)
+			req.RemoteAddr = "127.0.0.1:34567"
+			if len(tc.remoteAddr) != 0 {
+				req.RemoteAddr = tc.remoteAddr
+			}
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, req)
+			if rec.Code != tc.statusCode {
+				t.Errorf("expect status code %d, but got %d: %s", tc.statusCode, rec.Code, rec.Body.String())
+			}
+
+			active, err := LoadActiveCluster(m.stateDir)
+			if err != nil {
+				t.Fatalf("failed to load active cluster, %v", err)
+			}
+			if active != tc.active {
+				t.Errorf("expect active cluster %s, but got %s", tc.active, active)
+			}
+			if isSwitched(m) != (tc.active != ClusterPrimary) {
+				t.Errorf("expect switched %v, but got %v", tc.active != ClusterPrimary, isSwitched(m))
+			}
+			if _, err := dormantNode(t, m); (err == nil) != (tc.active != ClusterPrimary) {
+				t.Errorf("expect node registered %v, but got %v", tc.active != ClusterPrimary, err)
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	m, certManager := newTestManager(t, false, nil)
+	m.prepare()
+	if !certManager.started {
+		t.Fatalf("expect certificate manager of standby cluster is started")
+	}
+
+	// certificate manager is recreated after prepareTimeout
+	m.preparedAt = m.preparedAt.Add(-prepareTimeout)
+	m.prepare()
+	if !certManager.stopped {
+		t.Errorf("expect certificate manager is stopped if credentials are not ready in %v", prepareTimeout)
+	}
+	if m.certManager == nil {
+		t.Errorf("expect certificate manager is recreated")
+	}
+}
+
+func TestCheckAnnotation(t *testing.T) {
+	testcases := map[string]struct {
+		ready       bool
+		target      string
+		switched    bool
+		annotations map[string]string
+	}{
+		"switch over to standby cluster": {
+			ready:       true,
+			target:      ClusterStandby,
+			switched:    true,
+			annotations: map[string]string{},
+		},
+		"credentials are not ready": {
+			ready:    false,
+			target:   ClusterStandby,
+			switched: false,
+			annotations: map[string]string{
+				SwitchoverAnnotation:        ClusterStandby,
+				SwitchoverFailureAnnotation: "credentials of standby cluster are not ready",
+			},
+		},
+		"unsupported cluster": {
+			ready:    true,
+			target:   "foo",
+			switched: false,
+			annotations: map[string]string{
+				SwitchoverAnnotation:        "foo",
+				SwitchoverFailureAnnotation: `switchover to cluster "foo" is not supported, only standby cluster can be switched to`,
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			m, _ := newTestManager(t, tc.ready, map[string]string{SwitchoverAnnotation: tc.target})
+			m.prepare()
+			m.checkAnnotation()
+
+			if isSwitched(m) != tc.switched {
+				t.Fatalf("expect switched %v, but got %v", tc.switched, isSwitched(m))
+			}
+			node, err := m.client.CoreV1().Nodes().Get(context.TODO(), "foo", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get node, %v", err)
+			}
+			if !reflect.DeepEqual(node.Annotations, tc.annotations) && (len(node.Annotations) != 0 || len(tc.annotations) != 0) {
+				t.Errorf("expect annotations %v, but got %v", tc.annotations, node.Annotations)
+			}
+		})
+	}
+}
+
+func TestNewDormantNode(t *testing.T) {
+	active := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+			Labels: map[string]string{
+				corev1.LabelHostname:                    "foo",
+				"node.kubernetes.io/exclude-from-lb":    "true",
+				"node-role.kubernetes.io/control-plane": "",
+				"openyurt.io/is-edge-worker":            "true",
+			},
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: "foo://bar",
+			Taints: []corev1.Taint{
+				{Key: "node.kubernetes.io/unreachable", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "edge", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+
+	node := newDormantNode(active)
+	expectLabels := map[string]string{
+		corev1.LabelHostname:                 "foo",
+		"node.kubernetes.io/exclude-from-lb": "true",
+		"openyurt.io/is-edge-worker":         "true",
+	}
+	if !reflect.DeepEqual(node.Labels, expectLabels) {
+		t.Errorf("expect labels %v, but got %v", expectLabels, node.Labels)
+	}
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Key != "dedicated" {
+		t.Errorf("expect only taint dedicated is kept, but got %v", node.Spec.Taints)
+	}
+	if node.Spec.ProviderID != active.Spec.ProviderID {
+		t.Errorf("expect provider id %s, but got %s", active.Spec.ProviderID, node.Spec.ProviderID)
+	}
+}
+
+func TestSwitchedOfNilManager(t *testing.T) {
+	var m *Manager
+	if m.Switched() != nil {
+		t.Errorf("expect nil channel for nil manager")
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bolt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+type storageKey struct {
+	rootKey bool
+	path    string
+}
+
+func (k storageKey) Key() string {
+	return k.path
+}
+
+func (k storageKey) isRootKey() bool {
+	return k.rootKey
+}
+
+// KeyFunc uses the same key layout as disk storage in enhancement mode:
+// <Component>/<Resource.Version.Group>/<Namespace>/<Name>, or
+// <Component>/<Resource.Version.Group>/<Name>, if there's no namespace provided in info.
+// <Component>/<Resource.Version.Group>/<Namespace>, if there's no name provided in info.
+// <Component>/<Resource.Version.Group>, if there's no namespace and name provided in info.
+func (bs *boltStorage) KeyFunc(info storage.KeyBuildInfo) (storage.Key, error) {
+	if info.Component == "" {
+		return nil, storage.ErrEmptyComponent
+	}
+	if info.Resources == "" {
+		return nil, storage.ErrEmptyResource
+	}
+
+	group := info.Group
+	if info.Group == "" {
+		group = "core"
+	}
+	resource := strings.Join([]string{info.Resources, info.Version, group}, ".")
+
+	var path string
+	if info.Resources == "namespaces" {
+		path = filepath.Join(info.Component, resource, info.Name)
+	} else {
+		path = filepath.Join(info.Component, resource, info.Namespace, info.Name)
+	}
+
+	return storageKey{
+		path:    path,
+		rootKey: info.Name == "",
+	}, nil
+}
+
+// ExtractKeyBuildInfo parses the KeyBuildInfo from the key of an object in bolt storage.
+func ExtractKeyBuildInfo(key storage.Key) (*storage.KeyBuildInfo, error) {
+	sk, ok := key.(storageKey)
+	if !ok {
+		return nil, storage.ErrUnrecognizedKey
+	}
+
+	if sk.isRootKey() {
+		return nil, fmt.Errorf("cannot extract KeyBuildInfo from bolt key %s, root key is unsupported", key.Key())
+	}
+
+	elems := strings.SplitN(key.Key(), "/", 3)
+	if len(elems) < 3 {
+		return nil, fmt.Errorf("cannot parse bolt key %s, invalid format", key.Key())
+	}
+	comp, gvr, namespaceName := elems[0], elems[1], elems[2]
+
+	gvrElems := strings.SplitN(gvr, ".", 3)
+	if len(gvrElems) != 3 {
+		return nil, fmt.Errorf("cannot parse gvr of bolt key %s, invalid format", key.Key())
+	}
+	buildInfo := &storage.KeyBuildInfo{
+		Component: comp,
+		Resources: gvrElems[0],
+		Version:   gvrElems[1],
+		Group:     gvrElems[2],
+	}
+	if buildInfo.Group == "core" {
+		buildInfo.Group = ""
+	}
+
+	if ns, name, found := strings.Cut(namespaceName, "/"); found {
+		buildInfo.Namespace, buildInfo.Name = ns, name
+	} else {
+		buildInfo.Name = namespaceName
+	}
+	return buildInfo, nil
+}
@@ -0,0 +1,443 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bolt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/utils"
+)
+
+const (
+	StorageName = "local-bolt"
+	// DBFileName is the name of the database file in the cache dir
+	DBFileName = "cache.db"
+	// openTimeout is the timeout for waiting the file lock of database,
+	// the lock is held by another yurthub which is still running.
+	openTimeout = 10 * time.Second
+)
+
+var (
+	// objectsBucket holds the objects by their keys.
+	objectsBucket = []byte("objects")
+	// rootsBucket holds the root keys which have been created, so the empty
+	// lists can be told from the lists which have never been cached.
+	rootsBucket = []byte("roots")
+	// clusterInfoBucket holds the cluster info, such as version and apis.
+	clusterInfoBucket = []byte("cluster-info")
+
+	rootMarker = []byte("1")
+)
+
+// boltStorage caches all objects in a single BoltDB file, each write is done in a
+// transaction, so the cache is never left partially written when the node breaks down,
+// and no backup file is needed for recovering it.
+type boltStorage struct {
+	db         *bbolt.DB
+	serializer runtime.Serializer
+}
+
+// NewBoltStorage creates a storage.Store for caching data into the BoltDB file in dir.
+func NewBoltStorage(dir string) (storage.Store, error) {
+	if dir == "" {
+		klog.Infof("disk cache path is empty, set it by default %s", disk.CacheBaseDir)
+		dir = disk.CacheBaseDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache path %s, %v", dir, err)
+	}
+
+	path := filepath.Join(dir, DBFileName)
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt storage at %s, %v", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{objectsBucket, rootsBucket, clusterInfoBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init buckets of bolt storage at %s, %v", path, err)
+	}
+	klog.Infof("yurthub cache is stored in bolt storage at %s", path)
+
+	return &boltStorage{
+		db:         db,
+		serializer: json.NewSerializerWithOptions(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, json.SerializerOptions{}),
+	}, nil
+}
+
+// Name will return the name of this storage
+func (bs *boltStorage) Name() string {
+	return StorageName
+}
+
+// Create will create a new object with content, or record the root key if key is a root key.
+func (bs *boltStorage) Create(key storage.Key, content []byte) error {
+	if err := utils.ValidateKey(key, storageKey{}); err != nil {
+		return err
+	}
+	storageKey := key.(storageKey)
+
+	if !storageKey.isRootKey() && len(content) == 0 {
+		return storage.ErrKeyHasNoContent
+	}
+
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		k := []byte(storageKey.Key())
+		if storageKey.isRootKey() {
+			return tx.Bucket(rootsBucket).Put(k, rootMarker)
+		}
+		objects := tx.Bucket(objectsBucket)
+		if objects.Get(k) != nil {
+			return storage.ErrKeyExists
+		}
+		return objects.Put(k, content)
+	})
+}
+
+// Delete will delete the object specified by key, or all objects under the root key.
+func (bs *boltStorage) Delete(key storage.Key) error {
+	if err := utils.ValidateKey(key, storageKey{}); err != nil {
+		return err
+	}
+	storageKey := key.(storageKey)
+
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		if storageKey.isRootKey() {
+			return deleteTree(tx, storageKey.Key())
+		}
+		return tx.Bucket(objectsBucket).Delete([]byte(storageKey.Key()))
+	})
+}
+
+// Get will get the content of object specified by key.
+// If key points to a root key, return ErrKeyHasNoContent.
+func (bs *boltStorage) Get(key storage.Key) ([]byte, error) {
+	if err := utils.ValidateKey(key, storageKey{}); err != nil {
+		return []byte{}, storage.ErrKeyIsEmpty
+	}
+	storageKey := key.(storageKey)
+
+	var buf []byte
+	err := bs.db.View(func(tx *bbolt.Tx) error {
+		if !storageKey.isRootKey() {
+			if v := tx.Bucket(objectsBucket).Get([]byte(storageKey.Key())); v != nil {
+				buf = copyBytes(v)
+				return nil
+			}
+		}
+		if treeExists(tx, storageKey.Key()) {
+			return storage.ErrKeyHasNoContent
+		}
+		return storage.ErrStorageNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// List will get contents of all objects under the root key.
+// If nothing has been created under the root key, return ErrStorageNotFound.
+func (bs *boltStorage) List(key storage.Key) ([][]byte, error) {
+	if err := utils.ValidateKey(key, storageKey{}); err != nil {
+		return [][]byte{}, err
+	}
+	storageKey := key.(storageKey)
+
+	bb := make([][]byte, 0)
+	err := bs.db.View(func(tx *bbolt.Tx) error {
+		objects := tx.Bucket(objectsBucket)
+		if v := objects.Get([]byte(storageKey.Key())); v != nil {
+			// it is the key of an object, return the object directly
+			bb = append(bb, copyBytes(v))
+			return nil
+		}
+		if !treeExists(tx, storageKey.Key()) {
+			return storage.ErrStorageNotFound
+		}
+		forEachUnder(objects, storageKey.Key(), func(_, v []byte) {
+			bb = append(bb, copyBytes(v))
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bb, nil
+}
+
+// Update will update the object pointed by the key. It will check the rv of
+// stored obj and update it only when the rv in argument is fresher than what is stored.
+// It will return the content that finally stored for the key.
+func (bs *boltStorage) Update(key storage.Key, content []byte, rv uint64) ([]byte, error) {
+	if err := utils.ValidateKV(key, content, storageKey{}); err != nil {
+		return nil, err
+	}
+	storageKey := key.(storageKey)
+
+	if storageKey.isRootKey() {
+		return nil, storage.ErrIsNotObjectKey
+	}
+
+	var old []byte
+	err := bs.db.Update(func(tx *bbolt.Tx) error {
+		objects := tx.Bucket(objectsBucket)
+		k := []byte(storageKey.Key())
+		v := objects.Get(k)
+		if v == nil {
+			return storage.ErrStorageNotFound
+		}
+
+		ok, err := bs.ifFresherThan(v, rv)
+		if err != nil {
+			return fmt.Errorf("failed to get rv of key %s, %v", storageKey.Key(), err)
+		}
+		if !ok {
+			old = copyBytes(v)
+			return storage.ErrUpdateConflict
+		}
+		return objects.Put(k, content)
+	})
+	if err == storage.ErrUpdateConflict {
+		return old, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// ListResourceKeysOfComponent will get keys of all objects of the gvr belonging to the component.
+func (bs *boltStorage) ListResourceKeysOfComponent(component string, gvr schema.GroupVersionResource) ([]storage.Key, error) {
+	rootKey, err := bs.KeyFunc(storage.KeyBuildInfo{
+		Component: component,
+		Resources: gvr.Resource,
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]storage.Key, 0)
+	err = bs.db.View(func(tx *bbolt.Tx) error {
+		if !treeExists(tx, rootKey.Key()) {
+			return storage.ErrStorageNotFound
+		}
+		forEachUnder(tx.Bucket(objectsBucket), rootKey.Key(), func(k, _ []byte) {
+			nn := strings.TrimPrefix(string(k), rootKey.Key()+"/")
+			ns, name, found := strings.Cut(nn, "/")
+			if !found {
+				ns, name = "", nn
+			}
+			// We can ensure that component and resource can't be empty
+			// so ignore the err.
+			key, _ := bs.KeyFunc(storage.KeyBuildInfo{
+				Component: component,
+				Resources: gvr.Resource,
+				Version:   gvr.Version,
+				Group:     gvr.Group,
+				Namespace: ns,
+				Name:      name,
+			})
+			keys = append(keys, key)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ReplaceComponentList will replace all objects under the root key of component, gvr and namespace
+// with contents in one transaction, so the list is either replaced completely or not at all.
+func (bs *boltStorage) ReplaceComponentList(component string, gvr schema.GroupVersionResource, namespace string, contents map[storage.Key][]byte) error {
+	rootKey, err := bs.KeyFunc(storage.KeyBuildInfo{
+		Component: component,
+		Resources: gvr.Resource,
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return err
+	}
+
+	for key := range contents {
+		if !strings.HasPrefix(key.Key(), rootKey.Key()) {
+			return storage.ErrInvalidContent
+		}
+	}
+
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		if err := deleteTree(tx, rootKey.Key()); err != nil {
+			return err
+		}
+		if err := tx.Bucket(rootsBucket).Put([]byte(rootKey.Key()), rootMarker); err != nil {
+			return err
+		}
+		objects := tx.Bucket(objectsBucket)
+		for key, data := range contents {
+			if err := objects.Put([]byte(key.Key()), data); err != nil {
+				return fmt.Errorf("failed to put data of key %s, %v", key.Key(), err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteComponentResources will delete all resources cached for component.
+func (bs *boltStorage) DeleteComponentResources(component string) error {
+	if component == "" {
+		return storage.ErrEmptyComponent
+	}
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		return deleteTree(tx, component)
+	})
+}
+
+func (bs *boltStorage) SaveClusterInfo(key storage.ClusterInfoKey, content []byte) error {
+	k, err := clusterInfoKey(key)
+	if err != nil {
+		return err
+	}
+	return bs.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(clusterInfoBucket).Put([]byte(k), content)
+	})
+}
+
+func (bs *boltStorage) GetClusterInfo(key storage.ClusterInfoKey) ([]byte, error) {
+	k, err := clusterInfoKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	err = bs.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(clusterInfoBucket).Get([]byte(k))
+		if v == nil {
+			return storage.ErrStorageNotFound
+		}
+		buf = copyBytes(v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (bs *boltStorage) ifFresherThan(oldObj []byte, newRV uint64) (bool, error) {
+	// the object may be compressed by storage wrapper
+	oldObj, err := compression.Decompress(oldObj)
+	if err != nil {
+		return false, err
+	}
+	unstructuredObj := &unstructured.Unstructured{}
+	curObj, _, err := bs.serializer.Decode(oldObj, nil, unstructuredObj)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode obj, %v", err)
+	}
+	curRv, err := disk.ObjectResourceVersion(curObj)
+	if err != nil {
+		return false, fmt.Errorf("failed to get rv of obj, %v", err)
+	}
+	return newRV >= curRv, nil
+}
+
+func clusterInfoKey(key storage.ClusterInfoKey) (string, error) {
+	switch key.ClusterInfoType {
+	case storage.APIsInfo, storage.Version, storage.WildcardAgentsInfo:
+		return string(key.ClusterInfoType), nil
+	case storage.APIResourcesInfo, storage.DiscoveryInfo, storage.OpenAPIInfo:
+		return strings.ReplaceAll(key.UrlPath, "/", "_"), nil
+	default:
+		return "", storage.ErrUnknownClusterInfoType
+	}
+}
+
+// treeExists checks whether the root key has been created, or any key has been created under it.
+func treeExists(tx *bbolt.Tx, path string) bool {
+	roots := tx.Bucket(rootsBucket)
+	if roots.Get([]byte(path)) != nil {
+		return true
+	}
+	return hasKeyUnder(roots, path) || hasKeyUnder(tx.Bucket(objectsBucket), path)
+}
+
+// deleteTree deletes the root key and all keys under it.
+func deleteTree(tx *bbolt.Tx, path string) error {
+	for _, name := range [][]byte{objectsBucket, rootsBucket} {
+		b := tx.Bucket(name)
+		var keys [][]byte
+		forEachUnder(b, path, func(k, _ []byte) {
+			keys = append(keys, copyBytes(k))
+		})
+		// keys can't be deleted while iterating the bucket by cursor
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Bucket(rootsBucket).Delete([]byte(path))
+}
+
+func hasKeyUnder(b *bbolt.Bucket, path string) bool {
+	prefix := []byte(path + "/")
+	k, _ := b.Cursor().Seek(prefix)
+	return k != nil && bytes.HasPrefix(k, prefix)
+}
+
+func forEachUnder(b *bbolt.Bucket, path string, fn func(k, v []byte)) {
+	prefix := []byte(path + "/")
+	c := b.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		fn(k, v)
+	}
+}
+
+// copyBytes copies the value out of the transaction, the memory of value is only valid
+// until the transaction is done.
+func copyBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bolt
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+var podsGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+
+func newTestStorage(t *testing.T) *boltStorage {
+	s, err := NewBoltStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create bolt storage, %v", err)
+	}
+	bs := s.(*boltStorage)
+	t.Cleanup(func() { bs.db.Close() })
+	return bs
+}
+
+func podKey(t *testing.T, bs *boltStorage, namespace, name string) storage.Key {
+	key, err := bs.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: "pods",
+		Version:   "v1",
+		Namespace: namespace,
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatalf("failed to get key of pod %s/%s, %v", namespace, name, err)
+	}
+	return key
+}
+
+func podBytes(name string, rv int) []byte {
+	return []byte(fmt.Sprintf(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"%s","namespace":"default","resourceVersion":"%d"}}`, name, rv))
+}
+
+func TestCreateGetDelete(t *testing.T) {
+	bs := newTestStorage(t)
+	key := podKey(t, bs, "default", "foo")
+
+	if _, err := bs.Get(key); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound before creating, got %v", err)
+	}
+	if err := bs.Create(key, nil); err != storage.ErrKeyHasNoContent {
+		t.Fatalf("expect ErrKeyHasNoContent for empty content, got %v", err)
+	}
+	if err := bs.Create(key, podBytes("foo", 1)); err != nil {
+		t.Fatalf("failed to create, %v", err)
+	}
+	if err := bs.Create(key, podBytes("foo", 2)); err != storage.ErrKeyExists {
+		t.Fatalf("expect ErrKeyExists, got %v", err)
+	}
+	buf, err := bs.Get(key)
+	if err != nil || string(buf) != string(podBytes("foo", 1)) {
+		t.Fatalf("unexpected content %s, %v", buf, err)
+	}
+	if _, err := bs.Get(podKey(t, bs, "default", "")); err != storage.ErrKeyHasNoContent {
+		t.Fatalf("expect ErrKeyHasNoContent for root key, got %v", err)
+	}
+
+	if err := bs.Delete(key); err != nil {
+		t.Fatalf("failed to delete, %v", err)
+	}
+	if _, err := bs.Get(key); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound after deleting, got %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	bs := newTestStorage(t)
+	key := podKey(t, bs, "default", "foo")
+
+	if _, err := bs.Update(key, podBytes("foo", 2), 2); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound, got %v", err)
+	}
+	if err := bs.Create(key, podBytes("foo", 2)); err != nil {
+		t.Fatalf("failed to create, %v", err)
+	}
+	old, err := bs.Update(key, podBytes("foo", 1), 1)
+	if err != storage.ErrUpdateConflict || string(old) != string(podBytes("foo", 2)) {
+		t.Fatalf("expect conflict with stored content, got %s, %v", old, err)
+	}
+	if _, err := bs.Update(key, podBytes("foo", 3), 3); err != nil {
+		t.Fatalf("failed to update, %v", err)
+	}
+	if buf, _ := bs.Get(key); string(buf) != string(podBytes("foo", 3)) {
+		t.Fatalf("unexpected content after updating, %s", buf)
+	}
+	if _, err := bs.Update(podKey(t, bs, "default", ""), podBytes("foo", 4), 4); err != storage.ErrIsNotObjectKey {
+		t.Fatalf("expect ErrIsNotObjectKey, got %v", err)
+	}
+}
+
+func TestListAndReplaceComponentList(t *testing.T) {
+	bs := newTestStorage(t)
+	rootKey := podKey(t, bs, "", "")
+
+	if _, err := bs.List(rootKey); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound before caching, got %v", err)
+	}
+	if _, err := bs.ListResourceKeysOfComponent("kubelet", podsGVR); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound before caching, got %v", err)
+	}
+
+	// an empty list is cached
+	if err := bs.ReplaceComponentList("kubelet", podsGVR, "", nil); err != nil {
+		t.Fatalf("failed to replace list, %v", err)
+	}
+	if objs, err := bs.List(rootKey); err != nil || len(objs) != 0 {
+		t.Fatalf("expect empty list, got %d objects, %v", len(objs), err)
+	}
+
+	contents := map[storage.Key][]byte{
+		podKey(t, bs, "default", "foo"):     podBytes("foo", 1),
+		podKey(t, bs, "default", "bar"):     podBytes("bar", 1),
+		podKey(t, bs, "kube-system", "baz"): podBytes("baz", 1),
+	}
+	if err := bs.ReplaceComponentList("kubelet", podsGVR, "", contents); err != nil {
+		t.Fatalf("failed to replace list, %v", err)
+	}
+	if objs, err := bs.List(rootKey); err != nil || len(objs) != 3 {
+		t.Fatalf("expect 3 objects, got %d, %v", len(objs), err)
+	}
+	if objs, err := bs.List(podKey(t, bs, "default", "")); err != nil || len(objs) != 2 {
+		t.Fatalf("expect 2 objects in default namespace, got %d, %v", len(objs), err)
+	}
+
+	// replace the list of default namespace only
+	contents = map[storage.Key][]byte{
+		podKey(t, bs, "default", "qux"): podBytes("qux", 2),
+	}
+	if err := bs.ReplaceComponentList("kubelet", podsGVR, "default", contents); err != nil {
+		t.Fatalf("failed to replace list, %v", err)
+	}
+	keys, err := bs.ListResourceKeysOfComponent("kubelet", podsGVR)
+	if err != nil {
+		t.Fatalf("failed to list keys, %v", err)
+	}
+	got := make([]string, 0, len(keys))
+	for _, k := range keys {
+		got = append(got, k.Key())
+	}
+	sort.Strings(got)
+	expected := []string{"kubelet/pods.v1.core/default/qux", "kubelet/pods.v1.core/kube-system/baz"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expect keys %v, got %v", expected, got)
+	}
+
+	invalid := map[storage.Key][]byte{
+		podKey(t, bs, "kube-system", "foo"): podBytes("foo", 1),
+	}
+	if err := bs.ReplaceComponentList("kubelet", podsGVR, "default", invalid); err != storage.ErrInvalidContent {
+		t.Fatalf("expect ErrInvalidContent, got %v", err)
+	}
+
+	if err := bs.DeleteComponentResources("kubelet"); err != nil {
+		t.Fatalf("failed to delete component resources, %v", err)
+	}
+	if _, err := bs.List(rootKey); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound after deleting component resources, got %v", err)
+	}
+}
+
+func TestClusterInfo(t *testing.T) {
+	bs := newTestStorage(t)
+	key := storage.ClusterInfoKey{ClusterInfoType: storage.APIResourcesInfo, UrlPath: "/apis/apps/v1"}
+
+	if _, err := bs.GetClusterInfo(key); err != storage.ErrStorageNotFound {
+		t.Fatalf("expect ErrStorageNotFound, got %v", err)
+	}
+	for _, content := range []string{"foo", "bar"} {
+		if err := bs.SaveClusterInfo(key, []byte(content)); err != nil {
+			t.Fatalf("failed to save cluster info, %v", err)
+		}
+		if buf, err := bs.GetClusterInfo(key); err != nil || string(buf) != content {
+			t.Fatalf("expect cluster info %s, got %s, %v", content, buf, err)
+		}
+	}
+	if err := bs.SaveClusterInfo(storage.ClusterInfoKey{ClusterInfoType: "unknown"}, []byte("foo")); err != storage.ErrUnknownClusterInfoType {
+		t.Fatalf("expect ErrUnknownClusterInfoType, got %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewBoltStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to create bolt storage, %v", err)
+	}
+	bs := s.(*boltStorage)
+	key := podKey(t, bs, "default", "foo")
+	if err := bs.Create(key, podBytes("foo", 1)); err != nil {
+		t.Fatalf("failed to create, %v", err)
+	}
+	bs.db.Close()
+
+	s, err = NewBoltStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to reopen bolt storage, %v", err)
+	}
+	defer s.(*boltStorage).db.Close()
+	if buf, err := s.Get(key); err != nil || string(buf) != string(podBytes("foo", 1)) {
+		t.Fatalf("unexpected content after reopening, %s, %v", buf, err)
+	}
+}
+
+func TestExtractKeyBuildInfo(t *testing.T) {
+	bs := &boltStorage{}
+	cases := map[string]storage.KeyBuildInfo{
+		"namespaced object": {
+			Component: "kubelet",
+			Resources: "pods",
+			Version:   "v1",
+			Namespace: "default",
+			Name:      "foo",
+		},
+		"cluster scoped object": {
+			Component: "kube-proxy",
+			Resources: "nodepools",
+			Version:   "v1beta1",
+			Group:     "apps.openyurt.io",
+			Name:      "foo",
+		},
+		"namespace": {
+			Component: "kubelet",
+			Resources: "namespaces",
+			Version:   "v1",
+			Name:      "default",
+		},
+	}
+	for name, info := range cases {
+		t.Run(name, func(t *testing.T) {
+			key, err := bs.KeyFunc(info)
+			if err != nil {
+				t.Fatalf("failed to get key, %v", err)
+			}
+			got, err := ExtractKeyBuildInfo(key)
+			if err != nil {
+				t.Fatalf("failed to extract key build info, %v", err)
+			}
+			if !reflect.DeepEqual(*got, info) {
+				t.Errorf("expect %#v, got %#v", info, *got)
+			}
+		})
+	}
+
+	rootKey, _ := bs.KeyFunc(storage.KeyBuildInfo{Component: "kubelet", Resources: "pods", Version: "v1"})
+	if _, err := ExtractKeyBuildInfo(rootKey); err == nil {
+		t.Errorf("expect error for root key")
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressAndDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":"foo"}}`), 100)
+	for _, codec := range []Codec{None, Gzip, Zstd} {
+		t.Run(string(codec), func(t *testing.T) {
+			compressed, err := Compress(codec, data)
+			if err != nil {
+				t.Fatalf("failed to compress, %v", err)
+			}
+			if codec != None && len(compressed) >= len(data) {
+				t.Errorf("data is not compressed by %s, %d >= %d", codec, len(compressed), len(data))
+			}
+			out, err := Decompress(compressed)
+			if err != nil {
+				t.Fatalf("failed to decompress, %v", err)
+			}
+			if !bytes.Equal(out, data) {
+				t.Errorf("decompressed data is not equal to the original")
+			}
+		})
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	testcases := map[string]struct {
+		name   string
+		expect Codec
+		err    bool
+	}{
+		"empty":   {name: "", expect: None},
+		"gzip":    {name: "gzip", expect: Gzip},
+		"zstd":    {name: "zstd", expect: Zstd},
+		"invalid": {name: "lz4", err: true},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			codec, err := ParseCodec(tc.name)
+			if (err != nil) != tc.err {
+				t.Fatalf("expect error %v, but got %v", tc.err, err)
+			}
+			if codec != tc.expect {
+				t.Errorf("expect codec %s, but got %s", tc.expect, codec)
+			}
+		})
+	}
+}
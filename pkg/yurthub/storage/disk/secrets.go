@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+const secretsResource = "secrets"
+
+// secretsInMemoryStorage caches secrets in a dir on tmpfs and other resources in the disk storage,
+// so secrets are held only in memory and never written to disk.
+type secretsInMemoryStorage struct {
+	storage.Store
+	secrets storage.Store
+}
+
+// NewStorageWithSecretsInMemory creates a storage.Store which caches secrets in secretsDir and other
+// resources in dir. secretsDir must be on tmpfs, the cached secrets don't survive the node reboot,
+// and the secrets which have been cached in dir are removed. Note that the pages of tmpfs can be
+// swapped to disk, so a warning is logged if swap is enabled on the node.
+func NewStorageWithSecretsInMemory(dir, secretsDir string) (storage.Store, error) {
+	secrets, err := NewDiskStorage(secretsDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTmpfs(secretsDir); err != nil {
+		return nil, err
+	}
+
+	ds, err := NewDiskStorage(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(dir) == 0 {
+		dir = CacheBaseDir
+	}
+	if err := removeSecrets(dir); err != nil {
+		return nil, fmt.Errorf("failed to remove secrets cached in %s, %w", dir, err)
+	}
+	return &secretsInMemoryStorage{Store: ds, secrets: secrets}, nil
+}
+
+// removeSecrets removes the resource dirs of secrets for all components in baseDir.
+func removeSecrets(baseDir string) error {
+	compDirs, err := os.ReadDir(baseDir)
+	if err != nil {
+		return err
+	}
+	for _, compDir := range compDirs {
+		if !compDir.IsDir() {
+			continue
+		}
+		resDirs, err := os.ReadDir(filepath.Join(baseDir, compDir.Name()))
+		if err != nil {
+			return err
+		}
+		for _, resDir := range resDirs {
+			if !resDir.IsDir() || !isSecretsResource(resDir.Name()) {
+				continue
+			}
+			path := filepath.Join(baseDir, compDir.Name(), resDir.Name())
+			klog.Infof("remove secrets cached on disk %s", path)
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isSecretsResource checks the resource dir is secrets, the dir is secrets.v1.core
+// in enhancement mode, or secrets in old mode.
+func isSecretsResource(resource string) bool {
+	return strings.Split(resource, ".")[0] == secretsResource
+}
+
+func (s *secretsInMemoryStorage) storeOf(key storage.Key) storage.Store {
+	if key == nil {
+		return s.Store
+	}
+	// key is in format of <Component>/<Resource>/...
+	parts := strings.SplitN(key.Key(), "/", 3)
+	if len(parts) >= 2 && isSecretsResource(parts[1]) {
+		return s.secrets
+	}
+	return s.Store
+}
+
+func (s *secretsInMemoryStorage) storeOfResource(resource string) storage.Store {
+	if resource == secretsResource {
+		return s.secrets
+	}
+	return s.Store
+}
+
+func (s *secretsInMemoryStorage) Create(key storage.Key, content []byte) error {
+	return s.storeOf(key).Create(key, content)
+}
+
+func (s *secretsInMemoryStorage) Delete(key storage.Key) error {
+	return s.storeOf(key).Delete(key)
+}
+
+func (s *secretsInMemoryStorage) Get(key storage.Key) ([]byte, error) {
+	return s.storeOf(key).Get(key)
+}
+
+func (s *secretsInMemoryStorage) List(key storage.Key) ([][]byte, error) {
+	return s.storeOf(key).List(key)
+}
+
+func (s *secretsInMemoryStorage) Update(key storage.Key, contents []byte, rv uint64) ([]byte, error) {
+	return s.storeOf(key).Update(key, contents, rv)
+}
+
+func (s *secretsInMemoryStorage) KeyFunc(info storage.KeyBuildInfo) (storage.Key, error) {
+	return s.storeOfResource(info.Resources).KeyFunc(info)
+}
+
+func (s *secretsInMemoryStorage) ListResourceKeysOfComponent(component string, gvr schema.GroupVersionResource) ([]storage.Key, error) {
+	return s.storeOfResource(gvr.Resource).ListResourceKeysOfComponent(component, gvr)
+}
+
+func (s *secretsInMemoryStorage) ReplaceComponentList(component string, gvr schema.GroupVersionResource, namespace string, contents map[storage.Key][]byte) error {
+	return s.storeOfResource(gvr.Resource).ReplaceComponentList(component, gvr, namespace, contents)
+}
+
+func (s *secretsInMemoryStorage) DeleteComponentResources(component string) error {
+	var errs []error
+	for _, store := range []storage.Store{s.Store, s.secrets} {
+		if err := store.DeleteComponentResources(component); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disk
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const procSwapsPath = "/proc/swaps"
+
+// checkTmpfs checks dir is on tmpfs, so the files in it are not written to the file system on disk.
+// The pages of tmpfs can still be swapped out, so a warning is logged if swap is enabled.
+func checkTmpfs(dir string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return fmt.Errorf("failed to stat file system of %s, %w", dir, err)
+	}
+	if st.Type != unix.TMPFS_MAGIC {
+		return fmt.Errorf("%s is not on tmpfs", dir)
+	}
+	if swapEnabled(procSwapsPath) {
+		klog.Warningf("swap is enabled on the node, secrets cached in %s may be swapped to disk", dir)
+	}
+	return nil
+}
+
+// swapEnabled returns true if any swap area is listed in the swaps file, the first line is the header.
+func swapEnabled(swapsFile string) bool {
+	data, err := os.ReadFile(swapsFile)
+	if err != nil {
+		return false
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return len(lines) > 1
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+func TestSecretsInMemoryStorage(t *testing.T) {
+	dir, secretsDir := t.TempDir(), t.TempDir()
+	ds, err := NewDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	secrets, err := NewDiskStorage(secretsDir)
+	if err != nil {
+		t.Fatalf("failed to create secrets storage, %v", err)
+	}
+	s := &secretsInMemoryStorage{Store: ds, secrets: secrets}
+
+	cases := map[string]struct {
+		info storage.KeyBuildInfo
+		dir  string
+	}{
+		"secret is cached in memory": {
+			info: storage.KeyBuildInfo{Component: "kubelet", Resources: "secrets", Version: "v1", Namespace: "default", Name: "foo"},
+			dir:  secretsDir,
+		},
+		"configmap is cached on disk": {
+			info: storage.KeyBuildInfo{Component: "kubelet", Resources: "configmaps", Version: "v1", Namespace: "default", Name: "foo"},
+			dir:  dir,
+		},
+	}
+	for k, tc := range cases {
+		t.Run(k, func(t *testing.T) {
+			key, err := s.KeyFunc(tc.info)
+			if err != nil {
+				t.Fatalf("failed to get key, %v", err)
+			}
+			if err := s.Create(key, []byte(`{"metadata":{"resourceVersion":"1"}}`)); err != nil {
+				t.Fatalf("failed to create %s, %v", key.Key(), err)
+			}
+			if _, err := os.Stat(filepath.Join(tc.dir, key.Key())); err != nil {
+				t.Errorf("expect %s is cached in %s, %v", key.Key(), tc.dir, err)
+			}
+			if _, err := s.Get(key); err != nil {
+				t.Errorf("failed to get %s, %v", key.Key(), err)
+			}
+
+			gvr := schema.GroupVersionResource{Version: tc.info.Version, Resource: tc.info.Resources}
+			keys, err := s.ListResourceKeysOfComponent(tc.info.Component, gvr)
+			if err != nil || len(keys) != 1 {
+				t.Errorf("expect 1 key of %s, but got %v, %v", tc.info.Resources, keys, err)
+			}
+		})
+	}
+
+	if err := s.DeleteComponentResources("kubelet"); err != nil {
+		t.Fatalf("failed to delete resources of kubelet, %v", err)
+	}
+	for _, d := range []string{dir, secretsDir} {
+		if _, err := os.Stat(filepath.Join(d, "kubelet")); !os.IsNotExist(err) {
+			t.Errorf("expect resources of kubelet in %s are deleted", d)
+		}
+	}
+}
+
+func TestRemoveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"kubelet/secrets.v1.core/default", "kubelet/pods.v1.core/default", "kube-proxy/secrets/default"} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatalf("failed to create %s, %v", path, err)
+		}
+	}
+
+	if err := removeSecrets(dir); err != nil {
+		t.Fatalf("failed to remove secrets, %v", err)
+	}
+	for path, exist := range map[string]bool{
+		"kubelet/secrets.v1.core": false,
+		"kube-proxy/secrets":      false,
+		"kubelet/pods.v1.core":    true,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); (err == nil) != exist {
+			t.Errorf("expect %s exists %v, but got %v", path, exist, err)
+		}
+	}
+}
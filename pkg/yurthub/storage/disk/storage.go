@@ -33,6 +33,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
 	"github.com/openyurtio/openyurt/pkg/yurthub/storage/utils"
 	"github.com/openyurtio/openyurt/pkg/yurthub/util/fs"
 )
@@ -424,9 +425,9 @@ func (ds *diskStorage) DeleteComponentResources(component string) error {
 func (ds *diskStorage) SaveClusterInfo(key storage.ClusterInfoKey, content []byte) error {
 	var path string
 	switch key.ClusterInfoType {
-	case storage.APIsInfo, storage.Version:
+	case storage.APIsInfo, storage.Version, storage.WildcardAgentsInfo:
 		path = filepath.Join(ds.baseDir, string(key.ClusterInfoType))
-	case storage.APIResourcesInfo:
+	case storage.APIResourcesInfo, storage.DiscoveryInfo, storage.OpenAPIInfo:
 		translatedURLPath := strings.ReplaceAll(key.UrlPath, "/", "_")
 		path = filepath.Join(ds.baseDir, translatedURLPath)
 	default:
@@ -449,9 +450,9 @@ func (ds *diskStorage) SaveClusterInfo(key storage.ClusterInfoKey, content []byt
 func (ds *diskStorage) GetClusterInfo(key storage.ClusterInfoKey) ([]byte, error) {
 	var path string
 	switch key.ClusterInfoType {
-	case storage.APIsInfo, storage.Version:
+	case storage.APIsInfo, storage.Version, storage.WildcardAgentsInfo:
 		path = filepath.Join(ds.baseDir, string(key.ClusterInfoType))
-	case storage.APIResourcesInfo:
+	case storage.APIResourcesInfo, storage.DiscoveryInfo, storage.OpenAPIInfo:
 		translatedURLPath := strings.ReplaceAll(key.UrlPath, "/", "_")
 		path = filepath.Join(ds.baseDir, translatedURLPath)
 	default:
@@ -582,6 +583,11 @@ func (ds *diskStorage) lockKey(key storageKey) bool {
 
 func (ds *diskStorage) ifFresherThan(oldObj []byte, newRV uint64) (bool, error) {
 	// check resource version
+	// the object may be compressed by storage wrapper
+	oldObj, err := compression.Decompress(oldObj)
+	if err != nil {
+		return false, err
+	}
 	unstructuredObj := &unstructured.Unstructured{}
 	curObj, _, err := ds.serializer.Decode(oldObj, nil, unstructuredObj)
 	if err != nil {
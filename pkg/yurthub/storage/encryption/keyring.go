@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const defaultReloadPeriod = time.Minute
+
+// Key is a key encryption key which wraps the data keys of objects.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyProvider provides the key encryption keys, the first key is the primary key which is used
+// for encryption, and all keys are used for decryption.
+type KeyProvider interface {
+	Name() string
+	Keys() ([]Key, error)
+}
+
+type keyFileProvider struct {
+	path string
+}
+
+// NewKeyFileProvider creates a KeyProvider which reads keys from file, each line of the file is a key in
+// format of <id>:<base64 encoded key>, and the lines starting with # are comments. The keys are rotated
+// by adding the new key in the first line and keeping the old keys until the cached objects are re-encrypted.
+func NewKeyFileProvider(path string) KeyProvider {
+	return &keyFileProvider{path: path}
+}
+
+func (p *keyFileProvider) Name() string {
+	return fmt.Sprintf("key file %s", p.path)
+}
+
+func (p *keyFileProvider) Keys() ([]Key, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption keys, %w", err)
+	}
+	return parseKeys(data)
+}
+
+func parseKeys(data []byte) ([]Key, error) {
+	var keys []Key
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok || len(id) == 0 {
+			return nil, fmt.Errorf("encryption key should be in format of <id>:<base64 encoded key>")
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s is not base64 encoded, %w", id, err)
+		}
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Keyring holds the ciphers of key encryption keys, the keys are reloaded from provider periodically,
+// so the keys can be rotated without restarting yurthub.
+type Keyring struct {
+	provider     KeyProvider
+	reloadPeriod time.Duration
+
+	sync.RWMutex
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a Keyring and loads keys from provider, at least one key should be provided.
+func NewKeyring(provider KeyProvider) (*Keyring, error) {
+	kr := &Keyring{
+		provider:     provider,
+		reloadPeriod: defaultReloadPeriod,
+	}
+	if err := kr.reload(); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+// Run reloads keys until stopCh is closed, the last keys are kept if the keys can't be loaded.
+func (kr *Keyring) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(kr.reloadPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if err := kr.reload(); err != nil {
+				klog.Errorf("failed to reload encryption keys from %s, %v", kr.provider.Name(), err)
+			}
+		}
+	}
+}
+
+func (kr *Keyring) reload() error {
+	keys, err := kr.provider.Keys()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no encryption key is provided by %s", kr.provider.Name())
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for _, key := range keys {
+		if _, ok := aeads[key.ID]; ok {
+			return fmt.Errorf("encryption key %s is duplicated", key.ID)
+		}
+		aead, err := newAEAD(key.Secret)
+		if err != nil {
+			return fmt.Errorf("encryption key %s is invalid, %w", key.ID, err)
+		}
+		aeads[key.ID] = aead
+	}
+
+	kr.Lock()
+	defer kr.Unlock()
+	if kr.primary != keys[0].ID {
+		klog.Infof("cached objects are encrypted by key %s from %s", keys[0].ID, kr.provider.Name())
+	}
+	kr.primary = keys[0].ID
+	kr.aeads = aeads
+	return nil
+}
+
+// Primary returns the id of primary key
+func (kr *Keyring) Primary() string {
+	kr.RLock()
+	defer kr.RUnlock()
+	return kr.primary
+}
+
+// wrap encrypts the data key with the primary key, and returns the id of primary key. The wrapped
+// data key is bound to the id of primary key and context, so it can't be unwrapped for other contexts.
+func (kr *Keyring) wrap(dek, context []byte) (string, []byte, error) {
+	kr.RLock()
+	id, aead := kr.primary, kr.aeads[kr.primary]
+	kr.RUnlock()
+	wrapped, err := seal(aead, dek, wrapAdditionalData(id, context))
+	return id, wrapped, err
+}
+
+// unwrap decrypts the data key with the key of id, context should be the same as the one it's wrapped with.
+func (kr *Keyring) unwrap(id string, wrapped, context []byte) ([]byte, error) {
+	kr.RLock()
+	aead, ok := kr.aeads[id]
+	kr.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("encryption key %s is not found", id)
+	}
+	return open(aead, wrapped, wrapAdditionalData(id, context))
+}
+
+// wrapAdditionalData joins key id and context with a separator which can't be in key id.
+func wrapAdditionalData(id string, context []byte) []byte {
+	data := make([]byte, 0, len(id)+1+len(context))
+	data = append(data, id...)
+	data = append(data, 0)
+	return append(data, context...)
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext, and the random nonce is prepended to the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, data := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, data, additionalData)
+}
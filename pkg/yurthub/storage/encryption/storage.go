@@ -0,0 +1,336 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/compression"
+)
+
+const (
+	envelopeAPIVersion = "cache.yurthub.openyurt.io/v1"
+	envelopeKind       = "EncryptedObject"
+	dataKeySize        = 32
+)
+
+// envelopePrefix is the beginning of encoded envelope, the fields of envelope are encoded
+// in order, so the encrypted objects are recognized without decoding.
+var envelopePrefix = []byte(fmt.Sprintf(`{"apiVersion":%q,"kind":%q`, envelopeAPIVersion, envelopeKind))
+
+// envelope is an object encrypted by a random data key, and the data key is encrypted by the key
+// encryption key in keyring. The resource version of object is kept in plaintext, because it's
+// compared by the backend storage when the object is updated. Both the object and the data key
+// are bound to the storage key of object, so an envelope can't be moved to another key.
+type envelope struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Metadata   envelopeMetadata `json:"metadata"`
+	StorageKey string           `json:"storageKey"`
+	KeyID      string           `json:"keyID"`
+	DataKey    []byte           `json:"dataKey"`
+	Data       []byte           `json:"data"`
+}
+
+type envelopeMetadata struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// encryptedStorage encrypts the objects of resources before they are stored in the backend storage,
+// and the objects of other resources are stored as they are.
+type encryptedStorage struct {
+	storage.Store
+	keyring   *Keyring
+	resources sets.String
+}
+
+// NewEncryptedStorage creates a storage.Store which encrypts the objects of resources in store with keys in keyring.
+// The objects which are cached before encryption is enabled or encrypted by an old key are re-encrypted by the
+// primary key when they are got or listed, so the old keys can be removed after all objects have been read once.
+func NewEncryptedStorage(store storage.Store, keyring *Keyring, resources []string) storage.Store {
+	return &encryptedStorage{
+		Store:     store,
+		keyring:   keyring,
+		resources: sets.NewString(resources...),
+	}
+}
+
+// isEncrypted checks the objects of key should be encrypted, key is in format of
+// <Component>/<Resource>/..., and the resource is like secrets.v1.core in enhancement mode.
+func (es *encryptedStorage) isEncrypted(key storage.Key) bool {
+	if key == nil {
+		return false
+	}
+	parts := strings.SplitN(key.Key(), "/", 3)
+	return len(parts) >= 2 && es.resources.Has(strings.Split(parts[1], ".")[0])
+}
+
+func (es *encryptedStorage) Create(key storage.Key, content []byte) error {
+	if !es.isEncrypted(key) || len(content) == 0 {
+		return es.Store.Create(key, content)
+	}
+	rv, err := resourceVersionOf(content)
+	if err != nil {
+		return err
+	}
+	encrypted, err := es.encrypt(key, content, rv)
+	if err != nil {
+		return err
+	}
+	return es.Store.Create(key, encrypted)
+}
+
+func (es *encryptedStorage) Get(key storage.Key) ([]byte, error) {
+	content, err := es.Store.Get(key)
+	if err != nil || !es.isEncrypted(key) || len(content) == 0 {
+		return content, err
+	}
+
+	plaintext, keyID, rv, err := es.decrypt(key, content)
+	if err != nil {
+		return nil, err
+	}
+	if keyID != es.keyring.Primary() {
+		es.reencrypt(key, plaintext, rv)
+	}
+	return plaintext, nil
+}
+
+// reencrypt encrypts the object with primary key, the object is kept as it is if it has been updated
+// or is being updated, because the updated object is encrypted by primary key.
+func (es *encryptedStorage) reencrypt(key storage.Key, plaintext []byte, rv string) {
+	var err error
+	if len(rv) == 0 {
+		if rv, err = resourceVersionOf(plaintext); err != nil {
+			klog.Errorf("failed to re-encrypt %s, %v", key.Key(), err)
+			return
+		}
+	}
+	version, err := strconv.ParseUint(rv, 10, 64)
+	if err != nil {
+		klog.Errorf("failed to re-encrypt %s, resource version %q is invalid", key.Key(), rv)
+		return
+	}
+	encrypted, err := es.encrypt(key, plaintext, rv)
+	if err != nil {
+		klog.Errorf("failed to re-encrypt %s, %v", key.Key(), err)
+		return
+	}
+	if _, err := es.Store.Update(key, encrypted, version); err != nil {
+		klog.V(4).Infof("skip re-encrypting %s, %v", key.Key(), err)
+		return
+	}
+	klog.V(4).Infof("%s is re-encrypted by key %s", key.Key(), es.keyring.Primary())
+}
+
+func (es *encryptedStorage) List(key storage.Key) ([][]byte, error) {
+	contents, err := es.Store.List(key)
+	if err != nil || !es.isEncrypted(key) {
+		return contents, err
+	}
+
+	stale := false
+	primary := es.keyring.Primary()
+	plaintexts := make([][]byte, 0, len(contents))
+	for i := range contents {
+		plaintext, keyID, _, err := es.decrypt(key, contents[i])
+		if err != nil {
+			klog.Errorf("could not decrypt object under %s, %v", key.Key(), err)
+			continue
+		}
+		stale = stale || keyID != primary
+		plaintexts = append(plaintexts, plaintext)
+	}
+	if stale {
+		es.reencryptUnder(key)
+	}
+	return plaintexts, nil
+}
+
+// reencryptUnder re-encrypts the objects under rootKey which are not encrypted by primary key. The listed
+// contents don't carry their storage keys, so the keys are listed from the backend storage and each object
+// is re-encrypted as it's got.
+func (es *encryptedStorage) reencryptUnder(rootKey storage.Key) {
+	component, gvr, err := parseRootKey(rootKey.Key())
+	if err != nil {
+		klog.Errorf("failed to re-encrypt objects under %s, %v", rootKey.Key(), err)
+		return
+	}
+	keys, err := es.Store.ListResourceKeysOfComponent(component, gvr)
+	if err != nil {
+		klog.Errorf("failed to re-encrypt objects under %s, %v", rootKey.Key(), err)
+		return
+	}
+	for _, key := range keys {
+		if !isUnder(key.Key(), rootKey.Key()) {
+			continue
+		}
+		if _, err := es.Get(key); err != nil {
+			klog.V(4).Infof("skip re-encrypting %s, %v", key.Key(), err)
+		}
+	}
+}
+
+// parseRootKey parses the component and resource from key in format of <Component>/<Resource>/..., and
+// the resource is like secrets.v1.core in enhancement mode.
+func parseRootKey(key string) (string, schema.GroupVersionResource, error) {
+	parts := strings.SplitN(strings.TrimPrefix(key, "/"), "/", 3)
+	if len(parts) < 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", schema.GroupVersionResource{}, fmt.Errorf("key %s has no component or resource", key)
+	}
+	gvr := schema.GroupVersionResource{}
+	elems := strings.SplitN(parts[1], ".", 3)
+	gvr.Resource = elems[0]
+	if len(elems) == 3 {
+		gvr.Version = elems[1]
+		if elems[2] != "core" {
+			gvr.Group = elems[2]
+		}
+	}
+	return parts[0], gvr, nil
+}
+
+// isUnder checks key is rootKey itself or a key under it.
+func isUnder(key, rootKey string) bool {
+	return key == rootKey || strings.HasPrefix(key, strings.TrimSuffix(rootKey, "/")+"/")
+}
+
+func (es *encryptedStorage) Update(key storage.Key, content []byte, rv uint64) ([]byte, error) {
+	if !es.isEncrypted(key) {
+		return es.Store.Update(key, content, rv)
+	}
+	encrypted, err := es.encrypt(key, content, strconv.FormatUint(rv, 10))
+	if err != nil {
+		return nil, err
+	}
+	old, err := es.Store.Update(key, encrypted, rv)
+	if err == storage.ErrUpdateConflict {
+		plaintext, _, _, dErr := es.decrypt(key, old)
+		if dErr != nil {
+			return nil, dErr
+		}
+		return plaintext, err
+	}
+	return old, err
+}
+
+func (es *encryptedStorage) ReplaceComponentList(component string, gvr schema.GroupVersionResource, namespace string, contents map[storage.Key][]byte) error {
+	if !es.resources.Has(gvr.Resource) {
+		return es.Store.ReplaceComponentList(component, gvr, namespace, contents)
+	}
+	encryptedContents := make(map[storage.Key][]byte, len(contents))
+	for key, content := range contents {
+		rv, err := resourceVersionOf(content)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s, %w", key.Key(), err)
+		}
+		encrypted, err := es.encrypt(key, content, rv)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s, %w", key.Key(), err)
+		}
+		encryptedContents[key] = encrypted
+	}
+	return es.Store.ReplaceComponentList(component, gvr, namespace, encryptedContents)
+}
+
+// encrypt encrypts content with a random data key, the data key is encrypted by primary key. The storage
+// key is used as the additional data of both, so the envelope is bound to key.
+func (es *encryptedStorage) encrypt(key storage.Key, content []byte, rv string) ([]byte, error) {
+	dek := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	storageKey := []byte(key.Key())
+	data, err := seal(aead, content, storageKey)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := es.keyring.wrap(dek, storageKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&envelope{
+		APIVersion: envelopeAPIVersion,
+		Kind:       envelopeKind,
+		Metadata:   envelopeMetadata{ResourceVersion: rv},
+		StorageKey: key.Key(),
+		KeyID:      keyID,
+		DataKey:    wrapped,
+		Data:       data,
+	})
+}
+
+// decrypt returns the plaintext of content, the id of key which content is encrypted by and the
+// resource version of object. content is returned without key id if it's not encrypted. key is the
+// storage key of object, or the root key which object is listed from.
+func (es *encryptedStorage) decrypt(key storage.Key, content []byte) ([]byte, string, string, error) {
+	if !bytes.HasPrefix(content, envelopePrefix) {
+		return content, "", "", nil
+	}
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return nil, "", "", fmt.Errorf("failed to decode encrypted object %s, %w", key.Key(), err)
+	}
+	// the storage key in envelope is authenticated by decryption, so an envelope moved from another key is rejected
+	if !isUnder(env.StorageKey, key.Key()) {
+		return nil, "", "", fmt.Errorf("encrypted object %s is stored for key %s", key.Key(), env.StorageKey)
+	}
+	storageKey := []byte(env.StorageKey)
+	dek, err := es.keyring.unwrap(env.KeyID, env.DataKey, storageKey)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to decrypt data key of %s, %w", key.Key(), err)
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, "", "", err
+	}
+	plaintext, err := open(aead, env.Data, storageKey)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to decrypt %s, %w", key.Key(), err)
+	}
+	return plaintext, env.KeyID, env.Metadata.ResourceVersion, nil
+}
+
+// resourceVersionOf returns the resource version of object in content, which may be compressed.
+func resourceVersionOf(content []byte) (string, error) {
+	data, err := compression.Decompress(content)
+	if err != nil {
+		return "", err
+	}
+	var obj struct {
+		Metadata envelopeMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return "", fmt.Errorf("failed to get resource version of object, %w", err)
+	}
+	return obj.Metadata.ResourceVersion, nil
+}
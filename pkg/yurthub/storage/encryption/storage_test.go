@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+)
+
+func newKeyLine(t *testing.T, id string) string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+	return fmt.Sprintf("%s:%s\n", id, base64.StdEncoding.EncodeToString(secret))
+}
+
+func secretContent(name, rv string) []byte {
+	return []byte(fmt.Sprintf(`{"kind":"Secret","apiVersion":"v1","metadata":{"name":%q,"namespace":"default","resourceVersion":%q},"data":{"password":"c2VjcmV0"}}`, name, rv))
+}
+
+func keyOf(t *testing.T, store storage.Store, resource, name string) storage.Key {
+	key, err := store.KeyFunc(storage.KeyBuildInfo{Component: "kubelet", Resources: resource, Namespace: "default", Name: name, Version: "v1"})
+	if err != nil {
+		t.Fatalf("failed to create key, %v", err)
+	}
+	return key
+}
+
+func TestParseKeys(t *testing.T) {
+	testcases := map[string]struct {
+		content string
+		ids     []string
+		err     bool
+	}{
+		"keys with comments": {
+			content: "# rotated at 2023-06-01\nk2:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + "\n\nk1:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + "\n",
+			ids:     []string{"k2", "k1"},
+		},
+		"key without id": {
+			content: base64.StdEncoding.EncodeToString(make([]byte, 32)),
+			err:     true,
+		},
+		"key not base64 encoded": {
+			content: "k1:not-base64!",
+			err:     true,
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			keys, err := parseKeys([]byte(tc.content))
+			if (err != nil) != tc.err {
+				t.Fatalf("expect error %v, but got %v", tc.err, err)
+			}
+			if len(keys) != len(tc.ids) {
+				t.Fatalf("expect keys %v, but got %d keys", tc.ids, len(keys))
+			}
+			for i := range keys {
+				if keys[i].ID != tc.ids[i] {
+					t.Errorf("expect key %s, but got %s", tc.ids[i], keys[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "keys")
+	oldKey := newKeyLine(t, "k1")
+	if err := os.WriteFile(keyFile, []byte(oldKey), 0600); err != nil {
+		t.Fatalf("failed to write keys, %v", err)
+	}
+	keyring, err := NewKeyring(NewKeyFileProvider(keyFile))
+	if err != nil {
+		t.Fatalf("failed to create keyring, %v", err)
+	}
+	ds, err := disk.NewDiskStorage(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	es := NewEncryptedStorage(ds, keyring, []string{"secrets"})
+
+	// secret cached before encryption is enabled
+	plainKey := keyOf(t, ds, "secrets", "plain")
+	if err := ds.Create(plainKey, secretContent("plain", "1")); err != nil {
+		t.Fatalf("failed to create secret, %v", err)
+	}
+	fooKey := keyOf(t, es, "secrets", "foo")
+	if err := es.Create(fooKey, secretContent("foo", "5")); err != nil {
+		t.Fatalf("failed to create secret, %v", err)
+	}
+	cmKey := keyOf(t, es, "configmaps", "bar")
+	cm := []byte(`{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"bar","resourceVersion":"1"}}`)
+	if err := es.Create(cmKey, cm); err != nil {
+		t.Fatalf("failed to create configmap, %v", err)
+	}
+
+	raw, _ := ds.Get(fooKey)
+	if bytes.Contains(raw, []byte("c2VjcmV0")) || !bytes.HasPrefix(raw, envelopePrefix) {
+		t.Errorf("secret is not encrypted on disk, %s", raw)
+	}
+	if raw, _ := ds.Get(cmKey); !bytes.Equal(raw, cm) {
+		t.Errorf("configmap should not be encrypted, %s", raw)
+	}
+
+	got, err := es.Get(fooKey)
+	if err != nil || !bytes.Equal(got, secretContent("foo", "5")) {
+		t.Errorf("failed to get decrypted secret, %s, %v", got, err)
+	}
+	contents, err := es.List(keyOf(t, es, "secrets", ""))
+	if err != nil || len(contents) != 2 {
+		t.Errorf("expect 2 secrets, but got %d, %v", len(contents), err)
+	}
+
+	// rv of encrypted secret is checked by disk storage
+	if _, err := es.Update(fooKey, secretContent("foo", "6"), 6); err != nil {
+		t.Fatalf("failed to update secret, %v", err)
+	}
+	old, err := es.Update(fooKey, secretContent("foo", "4"), 4)
+	if err != storage.ErrUpdateConflict || !bytes.Equal(old, secretContent("foo", "6")) {
+		t.Errorf("expect conflict with existing secret, but got %s, %v", old, err)
+	}
+
+	if err := es.ReplaceComponentList("kubelet", schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, "default",
+		map[storage.Key][]byte{fooKey: secretContent("foo", "7"), plainKey: secretContent("plain", "2")}); err != nil {
+		t.Fatalf("failed to replace secrets, %v", err)
+	}
+	for _, key := range []storage.Key{fooKey, plainKey} {
+		if raw, _ := ds.Get(key); !strings.Contains(string(raw), `"keyID":"k1"`) {
+			t.Errorf("%s is not encrypted by k1, %s", key.Key(), raw)
+		}
+	}
+
+	// rotate key, the secret is re-encrypted by new key when it's read
+	if err := os.WriteFile(keyFile, []byte(newKeyLine(t, "k2")+oldKey), 0600); err != nil {
+		t.Fatalf("failed to write keys, %v", err)
+	}
+	if err := keyring.reload(); err != nil {
+		t.Fatalf("failed to reload keys, %v", err)
+	}
+	if got, err := es.Get(fooKey); err != nil || !bytes.Equal(got, secretContent("foo", "7")) {
+		t.Errorf("failed to get secret encrypted by old key, %s, %v", got, err)
+	}
+	if raw, _ := ds.Get(fooKey); !strings.Contains(string(raw), `"keyID":"k2"`) {
+		t.Errorf("secret is not re-encrypted by k2, %s", raw)
+	}
+
+	// the secrets not read by get are re-encrypted when they are listed
+	if contents, err := es.List(keyOf(t, es, "secrets", "")); err != nil || len(contents) != 2 {
+		t.Fatalf("expect 2 secrets, but got %d, %v", len(contents), err)
+	}
+	if raw, _ := ds.Get(plainKey); !strings.Contains(string(raw), `"keyID":"k2"`) {
+		t.Errorf("listed secret is not re-encrypted by k2, %s", raw)
+	}
+
+	// the encrypted secret is bound to its key
+	raw, _ = ds.Get(fooKey)
+	movedKey := keyOf(t, ds, "secrets", "moved")
+	if err := ds.Create(movedKey, raw); err != nil {
+		t.Fatalf("failed to create secret, %v", err)
+	}
+	if _, err := es.Get(movedKey); err == nil {
+		t.Errorf("expect error for secret moved from %s", fooKey.Key())
+	}
+	if err := ds.Delete(movedKey); err != nil {
+		t.Fatalf("failed to delete secret, %v", err)
+	}
+
+	// old key is removed after secrets are re-encrypted
+	if err := os.WriteFile(keyFile, bytes.SplitAfter([]byte(mustRead(t, keyFile)), []byte("\n"))[0], 0600); err != nil {
+		t.Fatalf("failed to write keys, %v", err)
+	}
+	if err := keyring.reload(); err != nil {
+		t.Fatalf("failed to reload keys, %v", err)
+	}
+	for _, key := range []storage.Key{fooKey, plainKey} {
+		if _, err := es.Get(key); err != nil {
+			t.Errorf("failed to get %s after old key is removed, %v", key.Key(), err)
+		}
+	}
+}
+
+func mustRead(t *testing.T, file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read %s, %v", file, err)
+	}
+	return string(data)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TPMDevice is the tpm resource manager device which keys are unsealed from, it should be mounted into yurthub.
+const TPMDevice = "/dev/tpmrm0"
+
+// ParseTPMHandle parses the persistent handle in hex, like 0x81010002.
+func ParseTPMHandle(handle string) (uint32, error) {
+	h, err := strconv.ParseUint(strings.TrimPrefix(handle, "0x"), 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("tpm handle %s is invalid, %w", handle, err)
+	}
+	return uint32(h), nil
+}
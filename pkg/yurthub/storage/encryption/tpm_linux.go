@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+type tpmProvider struct {
+	handles []string
+	pcrs    []int
+}
+
+// NewTPMProvider creates a KeyProvider which unseals keys from the persistent handles of TPM, the key
+// of a handle is identified by the handle, and the first handle is the primary key. The keys are rotated
+// by sealing the new key in a new handle and adding it in the front of handles.
+// The keys should be sealed with the policy of sha256 pcrs, so they are only unsealed when the node
+// boots into the measured state, and they can't be unsealed by an auth value.
+func NewTPMProvider(handles []string, pcrs []int) KeyProvider {
+	return &tpmProvider{handles: handles, pcrs: pcrs}
+}
+
+func (p *tpmProvider) Name() string {
+	return fmt.Sprintf("tpm handles %s", strings.Join(p.handles, ","))
+}
+
+func (p *tpmProvider) Keys() ([]Key, error) {
+	rw, err := tpm2.OpenTPM(TPMDevice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tpm %s, %w", TPMDevice, err)
+	}
+	defer rw.Close()
+
+	keys := make([]Key, 0, len(p.handles))
+	for _, h := range p.handles {
+		handle, err := ParseTPMHandle(h)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := p.unseal(rw, tpmutil.Handle(handle))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unseal encryption key from tpm handle %s, %w", h, err)
+		}
+		keys = append(keys, Key{ID: h, Secret: secret})
+	}
+	return keys, nil
+}
+
+// unseal unseals the key of handle in a policy session which is satisfied by the current values of pcrs.
+func (p *tpmProvider) unseal(rw io.ReadWriter, handle tpmutil.Handle) ([]byte, error) {
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull, make([]byte, 16), nil,
+		tpm2.SessionPolicy, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start policy session, %w", err)
+	}
+	defer tpm2.FlushContext(rw, session)
+
+	if err := tpm2.PolicyPCR(rw, session, nil, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: p.pcrs}); err != nil {
+		return nil, fmt.Errorf("failed to apply pcr policy, %w", err)
+	}
+	return tpm2.UnsealWithSession(rw, session, handle, "")
+}
@@ -0,0 +1,43 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type tpmProvider struct {
+	handles []string
+}
+
+// NewTPMProvider creates a KeyProvider which unseals keys from tpm, it's supported on linux only.
+func NewTPMProvider(handles []string, pcrs []int) KeyProvider {
+	return &tpmProvider{handles: handles}
+}
+
+func (p *tpmProvider) Name() string {
+	return fmt.Sprintf("tpm handles %s", strings.Join(p.handles, ","))
+}
+
+func (p *tpmProvider) Keys() ([]Key, error) {
+	return nil, errors.New("encryption keys in tpm are not supported on this platform")
+}
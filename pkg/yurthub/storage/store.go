@@ -29,7 +29,12 @@ const (
 	Version          ClusterInfoType = "version"
 	APIsInfo         ClusterInfoType = "apis"
 	APIResourcesInfo ClusterInfoType = "api-resources"
+	DiscoveryInfo    ClusterInfoType = "discovery"
+	OpenAPIInfo      ClusterInfoType = "openapi"
 	Unknown          ClusterInfoType = "unknown"
+
+	// WildcardAgentsInfo is not a cluster info, it records the components cached because of cache agent "*"
+	WildcardAgentsInfo ClusterInfoType = "wildcard-agents"
 )
 
 // Store is an interface for caching data into store
@@ -17,9 +17,12 @@ package tenant
 
 import (
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
@@ -29,56 +32,132 @@ type Interface interface {
 
 	GetTenantToken() string
 
+	// GetTenantNsFor returns the tenant namespace of component, the components which are not
+	// mapped to a tenant belong to the default tenant.
+	GetTenantNsFor(component string) string
+
+	// GetTenantTokenOf returns the service account token of tenant namespace
+	GetTenantTokenOf(tenantNs string) string
+
 	WaitForCacheSync() bool
 
 	SetSecret(sec *v1.Secret)
 }
 
 type tenantManager struct {
-	secretSynced cache.InformerSynced
-
-	TenantSecret *v1.Secret
+	secretSynced []cache.InformerSynced
 
+	// TenantNs is the default tenant namespace
 	TenantNs string
 
+	// TenantNss are all tenant namespaces, including the default one
+	TenantNss []string
+
+	// componentTenants maps the components to their tenant namespaces
+	componentTenants map[string]string
+
 	StopCh <-chan struct{}
 
 	IsSynced bool
 
 	mutex sync.Mutex
+
+	secretLock sync.RWMutex
+	// secrets are the default service account token secrets of tenant namespaces
+	secrets map[string]*v1.Secret
+
+	// rotator requests the short-lived tokens of tenants, nil if the tokens of secrets are used only
+	rotator *tokenRotator
 }
 
 func (mgr *tenantManager) WaitForCacheSync() bool {
 
-	if mgr.IsSynced || mgr.TenantSecret != nil { //try to do sync for just one time， fast return
+	if mgr.IsSynced || mgr.allSecretsFound() { //try to do sync for just one time， fast return
 		return true
 	}
 
 	mgr.mutex.Lock()
 	defer mgr.mutex.Unlock()
 
-	mgr.IsSynced = cache.WaitForCacheSync(mgr.StopCh, mgr.secretSynced)
+	mgr.IsSynced = cache.WaitForCacheSync(mgr.StopCh, mgr.secretSynced...)
 
 	return mgr.IsSynced
 }
 
+func (mgr *tenantManager) allSecretsFound() bool {
+	mgr.secretLock.RLock()
+	defer mgr.secretLock.RUnlock()
+	return len(mgr.secrets) == len(mgr.TenantNss)
+}
+
+// Options are the settings of tenant manager
+type Options struct {
+	// TenantNss are the tenant namespaces, the first one is the default tenant.
+	TenantNss []string
+
+	// ComponentTenants maps the components to their tenant namespaces.
+	ComponentTenants map[string]string
+
+	// TokenExpiration is the expiration of tokens requested by TokenRequest API, the tokens of
+	// service account secrets are used only if it's zero.
+	TokenExpiration time.Duration
+
+	// TokenDir is the directory which the requested tokens are projected into.
+	TokenDir string
+}
+
 func New(tenantNs string, factory informers.SharedInformerFactory, stopCh <-chan struct{}) Interface {
-	klog.Infof("parse tenant ns: %s", tenantNs)
 	if tenantNs == "" {
+		return NewWithOptions(Options{}, nil, factory, stopCh)
+	}
+	return NewWithOptions(Options{TenantNss: []string{tenantNs}}, nil, factory, stopCh)
+}
+
+// NewWithOptions creates a tenant manager for multiple tenant namespaces. The secrets of default tenant
+// are watched by the informer registered in factory, and the secrets of other tenants are watched by
+// the informers created with client. The short-lived tokens of tenants are requested with client if
+// token expiration is set, and preferred to the tokens of secrets.
+func NewWithOptions(opts Options, client kubernetes.Interface, factory informers.SharedInformerFactory, stopCh <-chan struct{}) Interface {
+	tenantNss := opts.TenantNss
+	klog.Infof("parse tenant ns: %v", tenantNss)
+	if len(tenantNss) == 0 {
 		return nil
 	}
 
-	tenantMgr := &tenantManager{TenantNs: tenantNs, StopCh: stopCh}
+	tenantMgr := &tenantManager{
+		TenantNs:         tenantNss[0],
+		TenantNss:        tenantNss,
+		componentTenants: opts.ComponentTenants,
+		StopCh:           stopCh,
+		secrets:          make(map[string]*v1.Secret),
+	}
 
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    tenantMgr.addSecret,
+		UpdateFunc: tenantMgr.updateSecret,
+		DeleteFunc: tenantMgr.deleteSecret}
 	if factory != nil {
 		informer := factory.InformerFor(&v1.Secret{}, nil) //get registered secret informer
 
-		tenantMgr.secretSynced = informer.HasSynced
+		tenantMgr.secretSynced = append(tenantMgr.secretSynced, informer.HasSynced)
 		//add handlers
-		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-			AddFunc:    tenantMgr.addSecret,
-			UpdateFunc: tenantMgr.updateSecret,
-			DeleteFunc: tenantMgr.deleteSecret})
+		informer.AddEventHandler(handler)
+	}
+
+	if client != nil {
+		for _, ns := range tenantNss[1:] {
+			informer := coreinformers.NewSecretInformer(client, ns, 0, cache.Indexers{})
+			tenantMgr.secretSynced = append(tenantMgr.secretSynced, informer.HasSynced)
+			informer.AddEventHandler(handler)
+			go informer.Run(stopCh)
+		}
+
+		if opts.TokenExpiration > 0 {
+			tenantMgr.rotator = newTokenRotator(client, opts.TokenExpiration, opts.TokenDir)
+			for _, ns := range tenantNss {
+				go tenantMgr.rotator.run(ns, stopCh)
+			}
+		}
 	}
 
 	return tenantMgr
@@ -87,12 +166,30 @@ func New(tenantNs string, factory informers.SharedInformerFactory, stopCh <-chan
 
 func (mgr *tenantManager) GetTenantToken() string {
 
-	if mgr.TenantSecret == nil {
+	return mgr.GetTenantTokenOf(mgr.TenantNs)
+}
+
+// GetTenantTokenOf prefers the requested token to the token of secret, and the expired requested token
+// is still used if there is no secret, because the requests are served from local cache while offline.
+func (mgr *tenantManager) GetTenantTokenOf(tenantNs string) string {
+	var requested string
+	if mgr.rotator != nil {
+		token, valid := mgr.rotator.getToken(tenantNs)
+		if valid {
+			return token
+		}
+		requested = token
+	}
+
+	mgr.secretLock.RLock()
+	defer mgr.secretLock.RUnlock()
 
-		return ""
+	secret := mgr.secrets[tenantNs]
+	if secret == nil || len(secret.Data["token"]) == 0 {
+		return requested
 	}
 
-	return string(mgr.TenantSecret.Data["token"])
+	return string(secret.Data["token"])
 }
 
 func (mgr *tenantManager) GetTenantNs() string {
@@ -100,13 +197,29 @@ func (mgr *tenantManager) GetTenantNs() string {
 	return mgr.TenantNs
 }
 
-func (mgr *tenantManager) InformerSynced() cache.InformerSynced {
+func (mgr *tenantManager) GetTenantNsFor(component string) string {
+	if ns, ok := mgr.componentTenants[component]; ok {
+		return ns
+	}
+
+	return mgr.TenantNs
+}
+
+func (mgr *tenantManager) InformerSynced() []cache.InformerSynced {
 
 	return mgr.secretSynced
 }
 
+// SetSecret sets the secret of its namespace, the secret without namespace belongs to the default tenant.
 func (mgr *tenantManager) SetSecret(sec *v1.Secret) {
-	mgr.TenantSecret = sec
+	ns := sec.Namespace
+	if ns == "" {
+		ns = mgr.TenantNs
+	}
+
+	mgr.secretLock.Lock()
+	defer mgr.secretLock.Unlock()
+	mgr.secrets[ns] = sec
 }
 
 func (mgr *tenantManager) addSecret(sec interface{}) {
@@ -117,11 +230,11 @@ func (mgr *tenantManager) addSecret(sec interface{}) {
 		return
 	}
 
-	klog.Infof("secret added %s", secret.GetName())
+	klog.Infof("secret added %s/%s", secret.GetNamespace(), secret.GetName())
 
 	if IsDefaultSASecret(secret) { //found it
 
-		mgr.TenantSecret = secret
+		mgr.SetSecret(secret)
 	}
 
 }
@@ -129,14 +242,23 @@ func (mgr *tenantManager) addSecret(sec interface{}) {
 func (mgr *tenantManager) deleteSecret(sec interface{}) {
 	secret, ok := sec.(*v1.Secret)
 	if !ok {
-		klog.Errorf("failed to convert to *v1.Secret")
-		return
+		tombstone, ok := sec.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.Errorf("failed to convert to *v1.Secret")
+			return
+		}
+		if secret, ok = tombstone.Obj.(*v1.Secret); !ok {
+			klog.Errorf("failed to convert tombstone to *v1.Secret")
+			return
+		}
 	}
 
-	klog.Infof("secret deleted %s", secret.GetName())
+	klog.Infof("secret deleted %s/%s", secret.GetNamespace(), secret.GetName())
 
 	if IsDefaultSASecret(secret) { //found it
-		mgr.TenantSecret = nil
+		mgr.secretLock.Lock()
+		defer mgr.secretLock.Unlock()
+		delete(mgr.secrets, secret.Namespace)
 	}
 }
 
@@ -148,10 +270,10 @@ func (mgr *tenantManager) updateSecret(oldSec interface{}, newSec interface{}) {
 		return
 	}
 
-	klog.Infof("secret updated %s", secret.GetName())
+	klog.Infof("secret updated %s/%s", secret.GetNamespace(), secret.GetName())
 	if IsDefaultSASecret(secret) { //found it
 
-		mgr.TenantSecret = secret
+		mgr.SetSecret(secret)
 	}
 }
 
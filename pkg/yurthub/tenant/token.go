@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/webhook/util/writer/atomic"
+)
+
+const (
+	// TokenFileName is the name of token file projected in the directory of tenant
+	TokenFileName = "token"
+
+	tenantServiceAccount = "default"
+	tokenRequestTimeout  = 30 * time.Second
+	minRetryPeriod       = 5 * time.Second
+	maxRetryPeriod       = 5 * time.Minute
+)
+
+type tenantToken struct {
+	token     string
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// refreshTime is at 80% of the lifetime of token, the same as the projected tokens of kubelet.
+func (t *tenantToken) refreshTime() time.Time {
+	return t.issuedAt.Add(t.expiresAt.Sub(t.issuedAt) * 4 / 5)
+}
+
+// tokenRotator requests the short-lived tokens of tenants by TokenRequest API, rotates them before
+// expiration and projects them into <tokenDir>/<tenant namespace>/token for the local components.
+// The projected tokens are loaded when yurthub restarts, so the tokens are available even if the
+// node is offline.
+type tokenRotator struct {
+	client     kubernetes.Interface
+	expiration time.Duration
+	tokenDir   string
+	sync.RWMutex
+	tokens map[string]*tenantToken
+}
+
+func newTokenRotator(client kubernetes.Interface, expiration time.Duration, tokenDir string) *tokenRotator {
+	return &tokenRotator{
+		client:     client,
+		expiration: expiration,
+		tokenDir:   tokenDir,
+		tokens:     make(map[string]*tenantToken),
+	}
+}
+
+// run rotates the token of tenantNs until stopCh is closed, the rotation is retried with backoff
+// when kube-apiserver is unreachable, and the current token is kept in the meantime.
+func (r *tokenRotator) run(tenantNs string, stopCh <-chan struct{}) {
+	r.loadToken(tenantNs)
+
+	backoff := minRetryPeriod
+	for {
+		wait, err := r.rotate(tenantNs, time.Now())
+		if err != nil {
+			klog.Errorf("failed to rotate token of tenant %s, retry after %v, %v", tenantNs, backoff, err)
+			wait = backoff
+			backoff *= 2
+			if backoff > maxRetryPeriod {
+				backoff = maxRetryPeriod
+			}
+		} else {
+			backoff = minRetryPeriod
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rotate requests a new token if the current token should be refreshed, and returns the duration
+// until the next rotation.
+func (r *tokenRotator) rotate(tenantNs string, now time.Time) (time.Duration, error) {
+	r.RLock()
+	current := r.tokens[tenantNs]
+	r.RUnlock()
+	if current != nil && now.Before(current.refreshTime()) {
+		return current.refreshTime().Sub(now), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRequestTimeout)
+	defer cancel()
+	expirationSeconds := int64(r.expiration.Seconds())
+	tr, err := r.client.CoreV1().ServiceAccounts(tenantNs).CreateToken(ctx, tenantServiceAccount, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	t := &tenantToken{token: tr.Status.Token, issuedAt: now, expiresAt: tr.Status.ExpirationTimestamp.Time}
+	r.Lock()
+	r.tokens[tenantNs] = t
+	r.Unlock()
+	if err := r.projectToken(tenantNs, t.token); err != nil {
+		klog.Errorf("failed to project token of tenant %s, %v", tenantNs, err)
+	}
+	klog.Infof("token of tenant %s is rotated, it expires at %v", tenantNs, t.expiresAt)
+	return t.refreshTime().Sub(now), nil
+}
+
+// getToken returns the token of tenantNs and whether it's not expired.
+func (r *tokenRotator) getToken(tenantNs string) (string, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	t := r.tokens[tenantNs]
+	if t == nil {
+		return "", false
+	}
+	return t.token, time.Now().Before(t.expiresAt)
+}
+
+func (r *tokenRotator) projectToken(tenantNs, token string) error {
+	dir := filepath.Join(r.tokenDir, tenantNs)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	w, err := atomic.NewAtomicWriter(dir)
+	if err != nil {
+		return err
+	}
+	return w.Write(map[string]atomic.FileProjection{
+		TokenFileName: {Data: []byte(token), Mode: 0600},
+	})
+}
+
+// loadToken loads the token projected before yurthub restarts.
+func (r *tokenRotator) loadToken(tenantNs string) {
+	data, err := os.ReadFile(filepath.Join(r.tokenDir, tenantNs, TokenFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("failed to load token of tenant %s, %v", tenantNs, err)
+		}
+		return
+	}
+	t, err := parseTenantToken(string(data))
+	if err != nil {
+		klog.Errorf("projected token of tenant %s is invalid, %v", tenantNs, err)
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.tokens[tenantNs]; !ok {
+		r.tokens[tenantNs] = t
+	}
+}
+
+// parseTenantToken gets the lifetime of token from its claims, the signature is verified by kube-apiserver.
+func parseTenantToken(token string) (*tenantToken, error) {
+	jsonWebToken, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+	claims := jwt.Claims{}
+	if err := jsonWebToken.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt == nil || claims.Expiry == nil {
+		return nil, fmt.Errorf("token has no iat or exp claim")
+	}
+	return &tenantToken{token: token, issuedAt: claims.IssuedAt.Time(), expiresAt: claims.Expiry.Time()}, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenant
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestRotate(t *testing.T) {
+	now := time.Now()
+	requested := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		requested++
+		return true, &authenticationv1.TokenRequest{
+			Status: authenticationv1.TokenRequestStatus{
+				Token:               "token",
+				ExpirationTimestamp: metav1.NewTime(now.Add(time.Hour)),
+			},
+		}, nil
+	})
+
+	tokenDir := t.TempDir()
+	r := newTokenRotator(client, time.Hour, tokenDir)
+	wait, err := r.rotate("tenant-a", now)
+	if err != nil {
+		t.Fatalf("failed to rotate token, %v", err)
+	}
+	if wait != 48*time.Minute {
+		t.Errorf("expect next rotation after 48m, but got %v", wait)
+	}
+	if data, err := os.ReadFile(filepath.Join(tokenDir, "tenant-a", TokenFileName)); err != nil || string(data) != "token" {
+		t.Errorf("expect token projected, but got %q, %v", string(data), err)
+	}
+
+	if _, err := r.rotate("tenant-a", now.Add(30*time.Minute)); err != nil || requested != 1 {
+		t.Errorf("expect token not requested before refresh time, requested %d times, %v", requested, err)
+	}
+	if _, err := r.rotate("tenant-a", now.Add(50*time.Minute)); err != nil || requested != 2 {
+		t.Errorf("expect token requested after refresh time, requested %d times, %v", requested, err)
+	}
+}
+
+func TestGetTenantTokenOf(t *testing.T) {
+	expired := &tenantToken{token: "expired", issuedAt: time.Now().Add(-2 * time.Hour), expiresAt: time.Now().Add(-time.Hour)}
+	valid := &tenantToken{token: "valid", issuedAt: time.Now(), expiresAt: time.Now().Add(time.Hour)}
+	testcases := map[string]struct {
+		requested *tenantToken
+		secret    string
+		expect    string
+	}{
+		"requested token is preferred": {
+			requested: valid,
+			secret:    "secret",
+			expect:    "valid",
+		},
+		"secret token is used if requested token expired": {
+			requested: expired,
+			secret:    "secret",
+			expect:    "secret",
+		},
+		"expired token is used if there is no secret": {
+			requested: expired,
+			expect:    "expired",
+		},
+		"secret token is used if no token requested": {
+			secret: "secret",
+			expect: "secret",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			mgr := NewWithOptions(Options{TenantNss: []string{"tenant-a"}}, nil, nil, nil).(*tenantManager)
+			mgr.rotator = newTokenRotator(nil, time.Hour, t.TempDir())
+			if tc.requested != nil {
+				mgr.rotator.tokens["tenant-a"] = tc.requested
+			}
+			if tc.secret != "" {
+				mgr.SetSecret(&v1.Secret{Data: map[string][]byte{"token": []byte(tc.secret)}})
+			}
+			if token := mgr.GetTenantToken(); token != tc.expect {
+				t.Errorf("expect token %s, but got %s", tc.expect, token)
+			}
+		})
+	}
+}
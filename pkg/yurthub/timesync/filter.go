@@ -0,0 +1,46 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timesync
+
+import (
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+// WithCertificateRequestsBlocked rejects the certificate signing requests while the clock of node is
+// skewed, the certificates issued are not valid yet or expired too early for the node, and kubelet
+// retries the requests after the clock is synchronized.
+func WithCertificateRequestsBlocked(handler http.Handler, monitor *Monitor) http.Handler {
+	if monitor == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := apirequest.RequestInfoFrom(req.Context())
+		if ok && info.IsResourceRequest && info.Resource == "certificatesigningrequests" && info.Verb == "create" && monitor.Skewed() {
+			klog.Warningf("request %s is rejected because the clock of node is skewed", hubutil.ReqString(req))
+			util.Err(apierrors.NewServiceUnavailable("clock of node is skewed, certificate signing requests are rejected until it's synchronized"), w, req)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
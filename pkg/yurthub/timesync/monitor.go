@@ -0,0 +1,257 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timesync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/metrics"
+	"github.com/openyurtio/openyurt/pkg/yurthub/nodeproblem"
+)
+
+const (
+	// ClockSynchronizedCondition is the node condition which reports the clock of node is synchronized or not
+	ClockSynchronizedCondition corev1.NodeConditionType = "ClockSynchronized"
+
+	SourceAPIServer = "apiserver"
+	SourceNTP       = "ntp"
+
+	checkPeriod = time.Minute
+	// heartbeatPeriod is the period of refreshing the heartbeat of condition when it's not changed
+	heartbeatPeriod = 5 * time.Minute
+)
+
+// Status is the time synchronization status of node
+type Status struct {
+	// NTPSynchronized reports the clock is synchronized by chrony, ntpd or systemd-timesyncd, which
+	// is read from the kernel clock state. It's nil if the state can't be read.
+	NTPSynchronized *bool `json:"ntpSynchronized,omitempty"`
+	// NTPOffset is the remaining offset of kernel clock reported by the kernel
+	NTPOffset time.Duration `json:"ntpOffset"`
+	// APIServerOffset is the offset of node clock versus the time of kube-apiserver, the time of
+	// kube-apiserver is read from the Date header in second precision.
+	APIServerOffset time.Duration `json:"apiServerOffset"`
+	// APIServerCheckedAt is the last time the offset versus kube-apiserver is checked
+	APIServerCheckedAt time.Time `json:"apiServerCheckedAt,omitempty"`
+	Skewed             bool      `json:"skewed"`
+}
+
+// Monitor checks the clock of node against ntp and kube-apiserver periodically, the status is
+// exposed by metrics and the ClockSynchronized condition of node.
+type Monitor struct {
+	nodeName      string
+	skewThreshold time.Duration
+	ntpStatus     func() (bool, time.Duration, error)
+	// serverTime gets the time of kube-apiserver and the local time when the response is received
+	serverTime func(cfg *restclient.Config) (time.Time, time.Time, error)
+	sync.RWMutex
+	status   Status
+	reported *corev1.NodeCondition
+}
+
+// NewMonitor creates a Monitor for node, the clock is skewed if its offset exceeds skewThreshold.
+func NewMonitor(nodeName string, skewThreshold time.Duration) *Monitor {
+	return &Monitor{
+		nodeName:      nodeName,
+		skewThreshold: skewThreshold,
+		ntpStatus:     kernelNTPStatus,
+		serverTime:    apiServerTime,
+	}
+}
+
+// Run checks the clock until stopCh is closed, the offset versus kube-apiserver is checked by the
+// client of healthy cloud servers which is created by getRestConfig.
+func (m *Monitor) Run(getRestConfig func(needHealthyServer bool) *restclient.Config, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(checkPeriod)
+	defer ticker.Stop()
+	for {
+		m.check(getRestConfig(true))
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check detects the clock offsets without holding the lock, so Skewed isn't blocked by the request
+// to kube-apiserver, and then updates the status.
+func (m *Monitor) check(cfg *restclient.Config) {
+	synced, offset, ntpErr := m.ntpStatus()
+	if ntpErr != nil {
+		klog.V(4).Infof("failed to detect ntp status, %v", ntpErr)
+	} else {
+		metrics.Metrics.ObserveClockOffset(SourceNTP, offset.Seconds())
+	}
+
+	var serverTime, localTime time.Time
+	var serverErr error
+	if cfg != nil {
+		if serverTime, localTime, serverErr = m.serverTime(cfg); serverErr != nil {
+			klog.Errorf("failed to get time of kube-apiserver, %v", serverErr)
+		} else {
+			metrics.Metrics.ObserveClockOffset(SourceAPIServer, localTime.Sub(serverTime).Seconds())
+		}
+	}
+
+	m.Lock()
+	if ntpErr != nil {
+		m.status.NTPSynchronized = nil
+	} else {
+		m.status.NTPSynchronized, m.status.NTPOffset = &synced, offset
+	}
+	if cfg != nil && serverErr == nil {
+		m.status.APIServerOffset, m.status.APIServerCheckedAt = localTime.Sub(serverTime), localTime
+	}
+	m.status.Skewed = m.isSkewed()
+	skewed, condition := m.status.Skewed, m.condition()
+	m.Unlock()
+
+	if skewed {
+		klog.Warningf("clock of node %s is skewed, %s", m.nodeName, condition.Message)
+		metrics.Metrics.ObserveTimeSyncStatus(0)
+	} else {
+		metrics.Metrics.ObserveTimeSyncStatus(1)
+	}
+	if cfg != nil {
+		m.reportCondition(cfg, condition)
+	}
+}
+
+// isSkewed checks the offset versus kube-apiserver first, because certificates are verified by
+// kube-apiserver, and the ntp status is used if kube-apiserver is never reached.
+func (m *Monitor) isSkewed() bool {
+	if !m.status.APIServerCheckedAt.IsZero() {
+		return abs(m.status.APIServerOffset) > m.skewThreshold
+	}
+	if m.status.NTPSynchronized != nil {
+		return abs(m.status.NTPOffset) > m.skewThreshold
+	}
+	return false
+}
+
+func (m *Monitor) condition() corev1.NodeCondition {
+	condition := corev1.NodeCondition{
+		Type:    ClockSynchronizedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ClockSynchronized",
+		Message: fmt.Sprintf("offset versus kube-apiserver is %v", m.status.APIServerOffset),
+	}
+	if m.status.NTPSynchronized != nil && !*m.status.NTPSynchronized {
+		condition.Status, condition.Reason = corev1.ConditionFalse, "NTPUnsynchronized"
+		condition.Message = fmt.Sprintf("clock is not synchronized by ntp, %s", condition.Message)
+	}
+	if m.status.Skewed {
+		condition.Status, condition.Reason = corev1.ConditionFalse, "ClockSkewed"
+		condition.Message = fmt.Sprintf("%s, ntp offset is %v, exceeds %v", condition.Message, m.status.NTPOffset, m.skewThreshold)
+	}
+	return condition
+}
+
+// reportCondition patches the ClockSynchronized condition into node status when it's changed, and
+// refreshes the heartbeat of condition every heartbeatPeriod.
+func (m *Monitor) reportCondition(cfg *restclient.Config, condition corev1.NodeCondition) {
+	m.RLock()
+	reported := m.reported
+	m.RUnlock()
+	now := metav1.Now()
+	changed := reported == nil || reported.Status != condition.Status || reported.Reason != condition.Reason
+	if !changed && now.Sub(reported.LastHeartbeatTime.Time) < heartbeatPeriod {
+		return
+	}
+
+	condition.LastHeartbeatTime, condition.LastTransitionTime = now, now
+	if !changed {
+		condition.LastTransitionTime = reported.LastTransitionTime
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Errorf("failed to create client for reporting clock condition, %v", err)
+		return
+	}
+	if err := nodeproblem.PatchNodeConditions(client.CoreV1().Nodes(), m.nodeName, []corev1.NodeCondition{condition}); err != nil {
+		klog.Errorf("failed to report clock condition of node %s, %v", m.nodeName, err)
+		return
+	}
+	m.Lock()
+	m.reported = &condition
+	m.Unlock()
+}
+
+// Skewed checks the clock of node is skewed
+func (m *Monitor) Skewed() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.status.Skewed
+}
+
+// ServeHTTP returns the time synchronization status of node
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.RLock()
+	data, err := json.Marshal(&m.status)
+	m.RUnlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// apiServerTime reads the time of kube-apiserver from the Date header of /version, the time
+// of kube-apiserver is compared with the middle of request duration.
+func apiServerTime(cfg *restclient.Config) (time.Time, time.Time, error) {
+	rt, err := restclient.TransportFor(cfg)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(cfg.Host, "/")+"/version", nil)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start := time.Now()
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	end := time.Now()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse Date header, %w", err)
+	}
+	return serverTime, start.Add(end.Sub(start) / 2), nil
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timesync
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/filters"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	restclient "k8s.io/client-go/rest"
+)
+
+func TestCheck(t *testing.T) {
+	testcases := map[string]struct {
+		ntpSynced       bool
+		ntpOffset       time.Duration
+		apiServerOffset time.Duration
+		apiServerDown   bool
+		skewed          bool
+		status          corev1.ConditionStatus
+		reason          string
+	}{
+		"clock is synchronized": {
+			ntpSynced:       true,
+			ntpOffset:       time.Millisecond,
+			apiServerOffset: time.Second,
+			status:          corev1.ConditionTrue,
+			reason:          "ClockSynchronized",
+		},
+		"clock is skewed versus kube-apiserver": {
+			ntpSynced:       true,
+			ntpOffset:       time.Millisecond,
+			apiServerOffset: 5 * time.Minute,
+			skewed:          true,
+			status:          corev1.ConditionFalse,
+			reason:          "ClockSkewed",
+		},
+		"ntp is not synchronized": {
+			status: corev1.ConditionFalse,
+			reason: "NTPUnsynchronized",
+		},
+		"clock is skewed versus ntp while kube-apiserver is unreachable": {
+			ntpSynced:     true,
+			ntpOffset:     -10 * time.Minute,
+			apiServerDown: true,
+			skewed:        true,
+			status:        corev1.ConditionFalse,
+			reason:        "ClockSkewed",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			m := NewMonitor("foo", time.Minute)
+			m.ntpStatus = func() (bool, time.Duration, error) {
+				return tc.ntpSynced, tc.ntpOffset, nil
+			}
+			m.serverTime = func(*restclient.Config) (time.Time, time.Time, error) {
+				now := time.Now()
+				return now.Add(-tc.apiServerOffset), now, nil
+			}
+			var cfg *restclient.Config
+			if !tc.apiServerDown {
+				cfg = &restclient.Config{Host: "http://127.0.0.1:1"}
+			}
+			m.check(cfg)
+
+			if m.Skewed() != tc.skewed {
+				t.Errorf("expect skewed %v, but got %v", tc.skewed, m.Skewed())
+			}
+			m.RLock()
+			condition := m.condition()
+			m.RUnlock()
+			if condition.Status != tc.status || condition.Reason != tc.reason {
+				t.Errorf("expect condition %s/%s, but got %s/%s", tc.status, tc.reason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestReportConditionHeartbeat(t *testing.T) {
+	var patches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPatch {
+			patches++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"Node","apiVersion":"v1","metadata":{"name":"foo"}}`))
+	}))
+	defer server.Close()
+
+	m := NewMonitor("foo", time.Minute)
+	cfg := &restclient.Config{Host: server.URL}
+	condition := corev1.NodeCondition{Type: ClockSynchronizedCondition, Status: corev1.ConditionTrue, Reason: "ClockSynchronized"}
+	m.reportCondition(cfg, condition)
+	m.reportCondition(cfg, condition)
+	if patches != 1 {
+		t.Fatalf("expect condition is reported once when it's not changed, but got %d", patches)
+	}
+
+	transition := m.reported.LastTransitionTime
+	m.reported.LastHeartbeatTime.Time = m.reported.LastHeartbeatTime.Add(-heartbeatPeriod)
+	m.reportCondition(cfg, condition)
+	if patches != 2 {
+		t.Fatalf("expect heartbeat of condition is refreshed, but got %d patches", patches)
+	}
+	if !m.reported.LastTransitionTime.Equal(&transition) {
+		t.Errorf("expect transition time is kept when condition is not changed")
+	}
+}
+
+func TestWithCertificateRequestsBlocked(t *testing.T) {
+	m := NewMonitor("foo", time.Minute)
+	m.status.Skewed = true
+	resolver := &request.RequestInfoFactory{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler = filters.WithRequestInfo(WithCertificateRequestsBlocked(handler, m), resolver)
+
+	testcases := map[string]struct {
+		method     string
+		path       string
+		statusCode int
+	}{
+		"create csr is rejected": {
+			method:     http.MethodPost,
+			path:       "/apis/certificates.k8s.io/v1/certificatesigningrequests",
+			statusCode: http.StatusServiceUnavailable,
+		},
+		"get csr is passed": {
+			method:     http.MethodGet,
+			path:       "/apis/certificates.k8s.io/v1/certificatesigningrequests/csr-foo",
+			statusCode: http.StatusOK,
+		},
+		"list pods is passed": {
+			method:     http.MethodGet,
+			path:       "/api/v1/pods",
+			statusCode: http.StatusOK,
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.statusCode {
+				t.Errorf("expect status code %d, but got %d", tc.statusCode, rec.Code)
+			}
+		})
+	}
+}
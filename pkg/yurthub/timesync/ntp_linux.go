@@ -0,0 +1,41 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timesync
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// kernelNTPStatus reads the clock discipline state of kernel by adjtimex, which is kept by chrony,
+// ntpd or systemd-timesyncd on the host. It needs neither the ntp tools nor their sockets in the
+// container, and reading the state needs no privilege. The clock is unsynchronized if the kernel
+// reports TIME_ERROR or STA_UNSYNC, and the offset is the remaining offset of kernel clock.
+func kernelNTPStatus() (bool, time.Duration, error) {
+	var tx unix.Timex
+	state, err := unix.Adjtimex(&tx)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to read kernel clock state, %w", err)
+	}
+	offset := time.Duration(tx.Offset)
+	if tx.Status&unix.STA_NANO == 0 {
+		offset *= time.Microsecond
+	}
+	return state != unix.TIME_ERROR && tx.Status&unix.STA_UNSYNC == 0, offset, nil
+}
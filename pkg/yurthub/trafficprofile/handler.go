@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficprofile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+	hubutil "github.com/openyurtio/openyurt/pkg/yurthub/util"
+)
+
+// WithTrafficProfile shapes the resource requests of components by the settings of current profile,
+// canCacheFor checks the response of request is cached by yurthub or not.
+func WithTrafficProfile(handler http.Handler, m *Manager, canCacheFor func(req *http.Request) bool) http.Handler {
+	if m == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := apirequest.RequestInfoFrom(req.Context())
+		if !ok || !info.IsResourceRequest || httpstream.IsUpgradeRequest(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		component, _ := hubutil.ClientComponentFrom(req.Context())
+		settings, budget, ok := m.settingsFor(component)
+		if !ok {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		if info.Verb == "watch" {
+			handler.ServeHTTP(w, shapeWatch(req, &settings))
+			return
+		}
+
+		if budget != nil && budget.Tokens() < 1 {
+			klog.Warningf("retry budget of %s is used up by failed requests, reject %s", component, hubutil.ReqString(req))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter(settings.RetryBudget)))
+			util.Err(errors.NewTooManyRequestsError(fmt.Sprintf("retry budget of %s is used up, please try again later", component)), w, req)
+			return
+		}
+
+		if info.Verb == "list" && settings.ListPageSize > 0 && !canCacheFor(req) {
+			req = limitPageSize(req, settings.ListPageSize)
+		}
+		if settings.RequestTimeout != nil && settings.RequestTimeout.Duration > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), settings.RequestTimeout.Duration)
+			defer cancel()
+			req = req.WithContext(ctx)
+		}
+
+		if budget == nil {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		handler.ServeHTTP(sw, req)
+		if sw.statusCode >= http.StatusInternalServerError || sw.statusCode == http.StatusTooManyRequests {
+			budget.Allow()
+		}
+	})
+}
+
+// retryAfter returns the seconds for a token of budget to be refilled
+func retryAfter(budget int) int {
+	return int(math.Ceil(60 / float64(budget)))
+}
+
+// shapeWatch limits the timeout of watch, and requests the bookmark events if needed.
+func shapeWatch(req *http.Request, settings *Settings) *http.Request {
+	query := req.URL.Query()
+	changed := false
+	if settings.WatchTimeout != nil && settings.WatchTimeout.Duration > 0 {
+		maxSeconds := int64(settings.WatchTimeout.Seconds())
+		seconds, err := strconv.ParseInt(query.Get("timeoutSeconds"), 10, 64)
+		if err != nil || seconds <= 0 || seconds > maxSeconds {
+			query.Set("timeoutSeconds", strconv.FormatInt(maxSeconds, 10))
+			changed = true
+		}
+	}
+	if settings.WatchBookmarks != nil && *settings.WatchBookmarks && query.Get("allowWatchBookmarks") != "true" {
+		query.Set("allowWatchBookmarks", "true")
+		changed = true
+	}
+	if !changed {
+		return req
+	}
+	return withQuery(req, query.Encode())
+}
+
+// limitPageSize lowers the limit of the paged list to pageSize, the lists without limit are not
+// paged by yurthub, because the component doesn't continue them.
+func limitPageSize(req *http.Request, pageSize int64) *http.Request {
+	query := req.URL.Query()
+	limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil || limit <= pageSize {
+		return req
+	}
+	query.Set("limit", strconv.FormatInt(pageSize, 10))
+	return withQuery(req, query.Encode())
+}
+
+func withQuery(req *http.Request, rawQuery string) *http.Request {
+	req = req.WithContext(req.Context())
+	u := *req.URL
+	u.RawQuery = rawQuery
+	req.URL = &u
+	return req
+}
+
+// statusWriter records the status code of response
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	sw.statusCode = statusCode
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficprofile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/server"
+
+	"github.com/openyurtio/openyurt/pkg/yurthub/proxy/util"
+)
+
+func TestWithTrafficProfile(t *testing.T) {
+	m, err := NewManager("satellite", "", "node1", newTestStore(t))
+	if err != nil {
+		t.Fatalf("failed to create manager, %v", err)
+	}
+	resolver := server.NewRequestInfoResolver(&server.Config{LegacyAPIGroupPrefixes: sets.NewString(server.DefaultLegacyAPIPrefix)})
+
+	testcases := map[string]struct {
+		path        string
+		cached      bool
+		status      int
+		expectQuery url.Values
+		deadline    bool
+	}{
+		"watch without timeout": {
+			path:        "/api/v1/pods?watch=true",
+			status:      http.StatusOK,
+			expectQuery: url.Values{"watch": {"true"}, "timeoutSeconds": {"3600"}, "allowWatchBookmarks": {"true"}},
+		},
+		"watch with short timeout": {
+			path:        "/api/v1/pods?watch=true&timeoutSeconds=300",
+			status:      http.StatusOK,
+			expectQuery: url.Values{"watch": {"true"}, "timeoutSeconds": {"300"}, "allowWatchBookmarks": {"true"}},
+		},
+		"paged list not cached": {
+			path:        "/api/v1/configmaps?limit=500",
+			status:      http.StatusOK,
+			expectQuery: url.Values{"limit": {"100"}},
+			deadline:    true,
+		},
+		"paged list cached": {
+			path:        "/api/v1/pods?limit=500",
+			cached:      true,
+			status:      http.StatusOK,
+			expectQuery: url.Values{"limit": {"500"}},
+			deadline:    true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			var gotQuery url.Values
+			var gotDeadline bool
+			handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				gotQuery = req.URL.Query()
+				_, gotDeadline = req.Context().Deadline()
+				w.WriteHeader(tc.status)
+			})
+			h := WithTrafficProfile(handler, m, func(*http.Request) bool { return tc.cached })
+			h = util.WithRequestClientComponent(h)
+			h = util.WithRequestContentType(h)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("User-Agent", "kubelet")
+			info, _ := resolver.NewRequestInfo(req)
+			req = req.WithContext(apirequest.WithRequestInfo(req.Context(), info))
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(gotQuery) != len(tc.expectQuery) {
+				t.Errorf("expect query %v, but got %v", tc.expectQuery, gotQuery)
+			}
+			for key := range tc.expectQuery {
+				if gotQuery.Get(key) != tc.expectQuery.Get(key) {
+					t.Errorf("expect query %v, but got %v", tc.expectQuery, gotQuery)
+				}
+			}
+			if gotDeadline != tc.deadline {
+				t.Errorf("expect deadline %v, but got %v", tc.deadline, gotDeadline)
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	m, err := NewManager("satellite", "", "node1", newTestStore(t))
+	if err != nil {
+		t.Fatalf("failed to create manager, %v", err)
+	}
+	resolver := server.NewRequestInfoResolver(&server.Config{LegacyAPIGroupPrefixes: sets.NewString(server.DefaultLegacyAPIPrefix)})
+	served := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		served++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	h := WithTrafficProfile(handler, m, func(*http.Request) bool { return false })
+	h = util.WithRequestClientComponent(h)
+
+	var resp *httptest.ResponseRecorder
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/nodes/node1", nil)
+		req.Header.Set("User-Agent", "kubelet")
+		info, _ := resolver.NewRequestInfo(req)
+		req = req.WithContext(apirequest.WithRequestInfo(req.Context(), info))
+		resp = httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Skip("budget is refilled during the test")
+	}
+	if served != 10 {
+		t.Errorf("expect 10 requests served within retry budget, but got %d", served)
+	}
+	if resp.Code != http.StatusTooManyRequests || resp.Header().Get("Retry-After") != "6" {
+		t.Errorf("expect 429 with Retry-After 6, but got %d %q", resp.Code, resp.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficprofile
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+)
+
+const defaultSyncPeriod = 30 * time.Second
+
+// Manager selects the traffic profile of node, the profile of nodepool is published on the node
+// by annotation nodepool.openyurt.io/traffic-profile, and the default profile is used if the node
+// is not annotated. The node is got from the local cache of kubelet, so the profile is still
+// selected when the cloud is unreachable.
+type Manager struct {
+	profiles       map[string]*Profile
+	defaultProfile string
+	nodeName       string
+	store          cachemanager.StorageWrapper
+	syncPeriod     time.Duration
+
+	sync.RWMutex
+	name    string
+	current *Profile
+	// limiters are the retry budgets of components in current profile
+	limiters map[string]*rate.Limiter
+}
+
+// NewManager creates a Manager with the builtin profiles and the profiles in profilesFile, the requests
+// are not shaped if defaultProfile is empty and the node is not annotated.
+func NewManager(defaultProfile, profilesFile, nodeName string, store cachemanager.StorageWrapper) (*Manager, error) {
+	profiles, err := loadProfiles(profilesFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := profiles[defaultProfile]; len(defaultProfile) != 0 && !ok {
+		return nil, fmt.Errorf("traffic profile %s is not found", defaultProfile)
+	}
+
+	m := &Manager{
+		profiles:       profiles,
+		defaultProfile: defaultProfile,
+		nodeName:       nodeName,
+		store:          store,
+		syncPeriod:     defaultSyncPeriod,
+	}
+	m.use(defaultProfile)
+	return m, nil
+}
+
+// Run selects the profile of node periodically until stopCh is closed
+func (m *Manager) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(m.syncPeriod)
+	defer ticker.Stop()
+	for {
+		m.sync()
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) sync() {
+	name := m.defaultProfile
+	if annotated := m.nodeProfile(); len(annotated) != 0 {
+		if _, ok := m.profiles[annotated]; ok {
+			name = annotated
+		} else {
+			klog.Errorf("traffic profile %s of node %s is not found, use profile %q", annotated, m.nodeName, name)
+		}
+	}
+	m.use(name)
+}
+
+// nodeProfile returns the traffic profile in the annotation of node in local cache
+func (m *Manager) nodeProfile() string {
+	key, err := m.store.KeyFunc(storage.KeyBuildInfo{
+		Component: "kubelet",
+		Resources: "nodes",
+		Version:   "v1",
+		Name:      m.nodeName,
+	})
+	if err != nil {
+		return ""
+	}
+	obj, err := m.store.Get(key)
+	if err != nil {
+		if err != storage.ErrStorageNotFound {
+			klog.Errorf("failed to get node %s from local cache for traffic profile, %v", m.nodeName, err)
+		}
+		return ""
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return ""
+	}
+	return node.Annotations[apps.AnnotationTrafficProfile]
+}
+
+// use switches to the profile of name, the retry budgets are reset when the profile is switched.
+func (m *Manager) use(name string) {
+	m.Lock()
+	defer m.Unlock()
+	if m.current != nil && m.name == name {
+		return
+	}
+	if len(name) == 0 {
+		if m.current != nil {
+			klog.Infof("requests of components are not shaped by traffic profile %s any more", m.name)
+		}
+		m.name, m.current = "", nil
+	} else {
+		klog.Infof("requests of components are shaped by traffic profile %s", name)
+		m.name, m.current = name, m.profiles[name]
+	}
+	m.limiters = make(map[string]*rate.Limiter)
+}
+
+// Current returns the name of profile in use, it's empty if the requests are not shaped.
+func (m *Manager) Current() string {
+	m.RLock()
+	defer m.RUnlock()
+	return m.name
+}
+
+// settingsFor returns the settings and the retry budget of component, and false if
+// the requests are not shaped.
+func (m *Manager) settingsFor(component string) (Settings, *rate.Limiter, bool) {
+	m.Lock()
+	defer m.Unlock()
+	if m.current == nil {
+		return Settings{}, nil, false
+	}
+	s := m.current.settingsFor(component)
+	if s.RetryBudget <= 0 {
+		return s, nil, true
+	}
+	limiter, ok := m.limiters[component]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(float64(s.RetryBudget)/60), s.RetryBudget)
+		m.limiters[component] = limiter
+	}
+	return s, limiter, true
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficprofile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/yurthub/cachemanager"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage"
+	"github.com/openyurtio/openyurt/pkg/yurthub/storage/disk"
+)
+
+func newTestStore(t *testing.T) cachemanager.StorageWrapper {
+	store, err := disk.NewDiskStorage(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("failed to create disk storage, %v", err)
+	}
+	return cachemanager.NewStorageWrapper(store)
+}
+
+func cacheNode(t *testing.T, store cachemanager.StorageWrapper, profile string) {
+	node := &corev1.Node{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Annotations: map[string]string{apps.AnnotationTrafficProfile: profile}},
+	}
+	key, _ := store.KeyFunc(storage.KeyBuildInfo{Component: "kubelet", Resources: "nodes", Version: "v1", Name: "node1"})
+	_ = store.Delete(key)
+	if err := store.Create(key, node); err != nil {
+		t.Fatalf("failed to cache node, %v", err)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	testcases := map[string]struct {
+		content string
+		check   func(t *testing.T, profiles map[string]*Profile)
+		err     bool
+	}{
+		"builtin profiles only": {
+			check: func(t *testing.T, profiles map[string]*Profile) {
+				for _, name := range []string{"lan", "metered", "satellite"} {
+					if profiles[name] == nil {
+						t.Errorf("builtin profile %s is not found", name)
+					}
+				}
+			},
+		},
+		"override builtin profile and add new one": {
+			content: `
+metered:
+  requestTimeout: 30s
+  components:
+    kubelet:
+      listPageSize: 50
+lte:
+  retryBudget: 5
+`,
+			check: func(t *testing.T, profiles map[string]*Profile) {
+				metered := profiles["metered"]
+				if metered.RequestTimeout.Duration != 30*time.Second || metered.ListPageSize != 0 {
+					t.Errorf("metered profile is not overridden, %+v", metered.Settings)
+				}
+				if s := metered.settingsFor("kubelet"); s.ListPageSize != 50 || s.RequestTimeout.Duration != 30*time.Second {
+					t.Errorf("settings of kubelet are not merged, %+v", s)
+				}
+				if profiles["lte"] == nil || profiles["lte"].RetryBudget != 5 {
+					t.Errorf("profile lte is not loaded")
+				}
+			},
+		},
+		"unknown field": {
+			content: "lte:\n  retries: 5\n",
+			err:     true,
+		},
+		"negative page size": {
+			content: "lte:\n  components:\n    kubelet:\n      listPageSize: -1\n",
+			err:     true,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			file := ""
+			if len(tc.content) != 0 {
+				file = filepath.Join(dir, filepath.Base(t.Name())+".yaml")
+				if err := os.WriteFile(file, []byte(tc.content), 0600); err != nil {
+					t.Fatalf("failed to write profiles, %v", err)
+				}
+			}
+			profiles, err := loadProfiles(file)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expect error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load profiles, %v", err)
+			}
+			tc.check(t, profiles)
+		})
+	}
+}
+
+func TestManagerSync(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := NewManager("unknown", "", "node1", store); err == nil {
+		t.Errorf("expect error for unknown default profile, but got nil")
+	}
+
+	m, err := NewManager("lan", "", "node1", store)
+	if err != nil {
+		t.Fatalf("failed to create manager, %v", err)
+	}
+	m.sync()
+	if m.Current() != "lan" {
+		t.Errorf("expect default profile lan when node is not cached, but got %q", m.Current())
+	}
+
+	cacheNode(t, store, "satellite")
+	m.sync()
+	if m.Current() != "satellite" {
+		t.Errorf("expect profile satellite of nodepool, but got %q", m.Current())
+	}
+	s, budget, ok := m.settingsFor("kubelet")
+	if !ok || s.ListPageSize != 100 || budget == nil {
+		t.Errorf("unexpected settings of kubelet, %+v", s)
+	}
+
+	cacheNode(t, store, "unknown")
+	m.sync()
+	if m.Current() != "lan" {
+		t.Errorf("expect default profile lan for unknown profile of nodepool, but got %q", m.Current())
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trafficprofile
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Settings are the knobs of shaping the requests of a component, the zero value of a knob means
+// the requests are not shaped by it.
+type Settings struct {
+	// RequestTimeout is the timeout of the requests except watch, the requests are served from
+	// local cache if possible when they time out.
+	RequestTimeout *metav1.Duration `json:"requestTimeout,omitempty"`
+	// WatchTimeout is the max timeout of watch requests, it's used if the watch has no timeout.
+	WatchTimeout *metav1.Duration `json:"watchTimeout,omitempty"`
+	// WatchBookmarks requests the bookmark events for watch requests, so the watches are resumed
+	// from the latest resource version instead of relisting after they are closed.
+	WatchBookmarks *bool `json:"watchBookmarks,omitempty"`
+	// ListPageSize is the max limit of the paged list requests, it's not applied to the lists which
+	// are cached by yurthub, because the cache is replaced by the list.
+	ListPageSize int64 `json:"listPageSize,omitempty"`
+	// RetryBudget is the number of failed requests per minute, the requests are rejected with 429 and
+	// Retry-After when the budget is used up, so the retries of component are slowed down.
+	RetryBudget int `json:"retryBudget,omitempty"`
+}
+
+// Profile is a named bundle of settings, Settings are applied to all components, and Components
+// override them for the components specified by user agent.
+type Profile struct {
+	Settings   `json:",inline"`
+	Components map[string]Settings `json:"components,omitempty"`
+}
+
+// settingsFor returns the settings of component, which are the settings of profile overridden
+// by the settings of component.
+func (p *Profile) settingsFor(component string) Settings {
+	s := p.Settings
+	override, ok := p.Components[component]
+	if !ok {
+		return s
+	}
+	if override.RequestTimeout != nil {
+		s.RequestTimeout = override.RequestTimeout
+	}
+	if override.WatchTimeout != nil {
+		s.WatchTimeout = override.WatchTimeout
+	}
+	if override.WatchBookmarks != nil {
+		s.WatchBookmarks = override.WatchBookmarks
+	}
+	if override.ListPageSize != 0 {
+		s.ListPageSize = override.ListPageSize
+	}
+	if override.RetryBudget != 0 {
+		s.RetryBudget = override.RetryBudget
+	}
+	return s
+}
+
+func duration(d time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: d}
+}
+
+func enabled() *bool {
+	b := true
+	return &b
+}
+
+// builtinProfiles returns the profiles for the common links between the sites and the cloud.
+func builtinProfiles() map[string]*Profile {
+	return map[string]*Profile{
+		// lan is for the sites with low latency links, failing fast makes the requests served from
+		// local cache without waiting for the lost cloud.
+		"lan": {
+			Settings: Settings{
+				RequestTimeout: duration(15 * time.Second),
+			},
+		},
+		// metered is for the sites that pay for traffic, the watches are kept long and resumed by
+		// bookmarks, so the expensive relists are avoided.
+		"metered": {
+			Settings: Settings{
+				RequestTimeout: duration(60 * time.Second),
+				WatchTimeout:   duration(60 * time.Minute),
+				WatchBookmarks: enabled(),
+				ListPageSize:   500,
+				RetryBudget:    30,
+			},
+		},
+		// satellite is for the sites with high latency and low bandwidth links, the requests wait
+		// longer, and large lists are paged to avoid timing out.
+		"satellite": {
+			Settings: Settings{
+				RequestTimeout: duration(120 * time.Second),
+				WatchTimeout:   duration(60 * time.Minute),
+				WatchBookmarks: enabled(),
+				ListPageSize:   100,
+				RetryBudget:    10,
+			},
+		},
+	}
+}
+
+// loadProfiles returns the builtin profiles and the profiles in file, which is a yaml map of
+// profiles keyed by name, the builtin profiles are replaced by the ones with the same name in file.
+func loadProfiles(file string) (map[string]*Profile, error) {
+	profiles := builtinProfiles()
+	if len(file) == 0 {
+		return profiles, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic profiles, %w", err)
+	}
+	custom := make(map[string]*Profile)
+	if err := yaml.UnmarshalStrict(data, &custom); err != nil {
+		return nil, fmt.Errorf("failed to parse traffic profiles in %s, %w", file, err)
+	}
+	for name, p := range custom {
+		if p == nil {
+			p = &Profile{}
+		}
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("traffic profile %s is invalid, %w", name, err)
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+func (p *Profile) validate() error {
+	settings := []Settings{p.Settings}
+	for _, s := range p.Components {
+		settings = append(settings, s)
+	}
+	for _, s := range settings {
+		if s.RequestTimeout != nil && s.RequestTimeout.Duration < 0 {
+			return fmt.Errorf("request timeout %v should not be negative", s.RequestTimeout.Duration)
+		}
+		if s.WatchTimeout != nil && s.WatchTimeout.Duration < 0 {
+			return fmt.Errorf("watch timeout %v should not be negative", s.WatchTimeout.Duration)
+		}
+		if s.ListPageSize < 0 {
+			return fmt.Errorf("list page size %d should not be negative", s.ListPageSize)
+		}
+		if s.RetryBudget < 0 {
+			return fmt.Errorf("retry budget %d should not be negative", s.RetryBudget)
+		}
+	}
+	return nil
+}
@@ -78,6 +78,8 @@ const (
 	YurtHubProxyPort       = 10261
 	YurtHubPort            = 10267
 	YurtHubProxySecurePort = 10268
+	// YurtHubProxyRedirectSecurePort is the port which the https connections to kube-apiserver are redirected to in ebpf firewall mode
+	YurtHubProxyRedirectSecurePort = 10271
 )
 
 var (
@@ -460,6 +462,22 @@ func ParseTenantNsFromOrgs(orgs []string) string {
 	return ns
 }
 
+// ParseTenantNamespacesFromOrgs returns all tenant namespaces in orgs without duplication, the first
+// one is the default tenant.
+func ParseTenantNamespacesFromOrgs(orgs []string) []string {
+	var nss []string
+	seen := make(map[string]bool)
+	for _, v := range orgs {
+		ns := ParseTenantNs(v)
+		if len(ns) != 0 && !seen[ns] {
+			seen[ns] = true
+			nss = append(nss, ns)
+		}
+	}
+
+	return nss
+}
+
 func ParseBearerToken(token string) string {
 	if token == "" {
 		return ""
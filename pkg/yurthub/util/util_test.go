@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
 	"testing"
 
 	apirequest "k8s.io/apiserver/pkg/endpoints/request"
@@ -351,6 +352,25 @@ func TestParseTenantNsFromOrgs(t *testing.T) {
 	}
 }
 
+func TestParseTenantNamespacesFromOrgs(t *testing.T) {
+	tests := []struct {
+		name string
+		orgs []string
+		want []string
+	}{
+		{"no orgs", nil, nil},
+		{"no tenant in orgs", []string{"org1", "org2"}, nil},
+		{"multiple tenants in orgs", []string{"openyurt:tenant:tenant-b", "org1", "openyurt:tenant:tenant-a", "openyurt:tenant:tenant-b"}, []string{"tenant-b", "tenant-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTenantNamespacesFromOrgs(tt.orgs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTenantNamespacesFromOrgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseBearerToken(t *testing.T) {
 	type args struct {
 		token string
@@ -0,0 +1,302 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const (
+	syncPeriod         = time.Minute
+	webhooksFileName   = "webhooks.json"
+	namespacesFileName = "namespaces.json"
+)
+
+var namespacesGVR = corev1.SchemeGroupVersion.WithResource("namespaces")
+
+// Webhook is an admission webhook of the cloud, only the fields for matching writes are kept.
+type Webhook struct {
+	Name              string                                       `json:"name"`
+	Rules             []admissionregistrationv1.RuleWithOperations `json:"rules,omitempty"`
+	MatchPolicy       *admissionregistrationv1.MatchPolicyType     `json:"matchPolicy,omitempty"`
+	NamespaceSelector *metav1.LabelSelector                        `json:"namespaceSelector,omitempty"`
+	ObjectSelector    *metav1.LabelSelector                        `json:"objectSelector,omitempty"`
+}
+
+// Matcher keeps the admission webhooks of the cloud, so the writes accepted by yurthub while the
+// cloud is unreachable can record which webhooks are deferred until the writes are replayed. The
+// webhooks and the labels of namespaces for evaluating namespace selectors are saved into dir, so
+// they are still known when yurthub restarts during an outage.
+type Matcher struct {
+	dir string
+	sync.RWMutex
+	webhooks   []Webhook
+	namespaces map[string]map[string]string
+}
+
+// NewMatcher creates a Matcher and loads the webhooks in dir
+func NewMatcher(dir string) *Matcher {
+	m := &Matcher{dir: dir}
+	m.load()
+	return m
+}
+
+// Run syncs the webhooks from the cloud until stopCh is closed, the webhooks are listed by the
+// client of healthy cloud servers which is created by getRestConfig.
+func (m *Matcher) Run(getRestConfig func(needHealthyServer bool) *restclient.Config, stopCh <-chan struct{}) {
+	m.sync(getRestConfig)
+
+	ticker := time.NewTicker(syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			m.sync(getRestConfig)
+		}
+	}
+}
+
+// sync keeps the last webhooks if the cloud is unreachable or the webhooks can't be listed.
+func (m *Matcher) sync(getRestConfig func(needHealthyServer bool) *restclient.Config) {
+	cfg := getRestConfig(true)
+	if cfg == nil {
+		return
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Errorf("failed to create client for listing admission webhooks, %v", err)
+		return
+	}
+	webhooks, err := listWebhooks(client)
+	if err != nil {
+		klog.Errorf("failed to list admission webhooks, %v", err)
+		return
+	}
+
+	m.Lock()
+	m.webhooks = webhooks
+	m.Unlock()
+	m.save(webhooksFileName, webhooks)
+
+	namespaces, err := listNamespaceLabels(client)
+	if err != nil {
+		klog.Errorf("failed to list namespaces for admission webhooks, %v", err)
+		return
+	}
+	m.Lock()
+	m.namespaces = namespaces
+	m.Unlock()
+	m.save(namespacesFileName, namespaces)
+}
+
+func listWebhooks(client kubernetes.Interface) ([]Webhook, error) {
+	mutating, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	validating, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []Webhook
+	for i := range mutating.Items {
+		for _, wh := range mutating.Items[i].Webhooks {
+			webhooks = append(webhooks, Webhook{Name: wh.Name, Rules: wh.Rules, MatchPolicy: wh.MatchPolicy,
+				NamespaceSelector: wh.NamespaceSelector, ObjectSelector: wh.ObjectSelector})
+		}
+	}
+	for i := range validating.Items {
+		for _, wh := range validating.Items[i].Webhooks {
+			webhooks = append(webhooks, Webhook{Name: wh.Name, Rules: wh.Rules, MatchPolicy: wh.MatchPolicy,
+				NamespaceSelector: wh.NamespaceSelector, ObjectSelector: wh.ObjectSelector})
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].Name < webhooks[j].Name
+	})
+	return webhooks, nil
+}
+
+func listNamespaceLabels(client kubernetes.Interface) (map[string]map[string]string, error) {
+	namespaces, err := client.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nsLabels := make(map[string]map[string]string, len(namespaces.Items))
+	for i := range namespaces.Items {
+		nsLabels[namespaces.Items[i].Name] = namespaces.Items[i].Labels
+	}
+	return nsLabels, nil
+}
+
+// Match returns the names of webhooks which would be called by the cloud for the write of object
+// in namespace with objLabels, namespace is empty for cluster scoped object. The result is an upper
+// bound of the webhooks called by the cloud: the namespace selector is treated as matched when
+// the namespace is not known yet, and only the other versions of the same group are treated as
+// equivalent resources for the Equivalent match policy.
+func (m *Matcher) Match(gvr schema.GroupVersionResource, namespace string, operation admissionregistrationv1.OperationType, objLabels map[string]string) []string {
+	m.RLock()
+	defer m.RUnlock()
+	nsLabels, nsKnown := m.namespaceLabels(gvr, namespace, objLabels)
+	var names []string
+	for i := range m.webhooks {
+		if m.webhooks[i].matches(gvr, len(namespace) != 0, operation, objLabels, nsLabels, nsKnown) {
+			names = append(names, m.webhooks[i].Name)
+		}
+	}
+	return names
+}
+
+// namespaceLabels returns the labels for evaluating namespace selectors like kube-apiserver: the labels
+// of namespace object itself for namespaces, and the labels of its namespace for namespaced object. False
+// is returned if the namespace selectors are not evaluated for the object.
+func (m *Matcher) namespaceLabels(gvr schema.GroupVersionResource, namespace string, objLabels map[string]string) (labels.Set, bool) {
+	if gvr.GroupResource() == namespacesGVR.GroupResource() {
+		return labels.Set(objLabels), true
+	}
+	if len(namespace) == 0 {
+		return nil, false
+	}
+	nsLabels, ok := m.namespaces[namespace]
+	return labels.Set(nsLabels), ok
+}
+
+func (wh *Webhook) matches(gvr schema.GroupVersionResource, namespaced bool, operation admissionregistrationv1.OperationType, objLabels map[string]string, nsLabels labels.Set, nsKnown bool) bool {
+	// the invalid selector is treated as matched too, the webhook is never skipped by mistake
+	if wh.NamespaceSelector != nil && nsKnown {
+		if selector, err := metav1.LabelSelectorAsSelector(wh.NamespaceSelector); err == nil && !selector.Matches(nsLabels) {
+			return false
+		}
+	}
+	if wh.ObjectSelector != nil {
+		if selector, err := metav1.LabelSelectorAsSelector(wh.ObjectSelector); err == nil && !selector.Matches(labels.Set(objLabels)) {
+			return false
+		}
+	}
+	exact := wh.MatchPolicy != nil && *wh.MatchPolicy == admissionregistrationv1.Exact
+	for i := range wh.Rules {
+		if ruleMatches(&wh.Rules[i], gvr, namespaced, operation, exact) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleMatches returns true if the rule matches the write, the version is not compared unless exact is
+// true, because the write of other versions is converted to the version of rule by kube-apiserver.
+func ruleMatches(rule *admissionregistrationv1.RuleWithOperations, gvr schema.GroupVersionResource, namespaced bool, operation admissionregistrationv1.OperationType, exact bool) bool {
+	if !containsOperation(rule.Operations, operation) ||
+		!contains(rule.APIGroups, gvr.Group) ||
+		(exact && !contains(rule.APIVersions, gvr.Version)) ||
+		!(contains(rule.Resources, gvr.Resource) || contains(rule.Resources, "*/*")) {
+		return false
+	}
+	if rule.Scope == nil {
+		return true
+	}
+	switch *rule.Scope {
+	case admissionregistrationv1.ClusterScope:
+		return !namespaced
+	case admissionregistrationv1.NamespacedScope:
+		return namespaced
+	default:
+		return true
+	}
+}
+
+func containsOperation(operations []admissionregistrationv1.OperationType, operation admissionregistrationv1.OperationType) bool {
+	for _, op := range operations {
+		if op == admissionregistrationv1.OperationAll || op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == "*" || i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) save(name string, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		klog.Errorf("failed to encode %s for admission webhooks, %v", name, err)
+		return
+	}
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		klog.Errorf("failed to create dir for admission webhooks, %v", err)
+		return
+	}
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		klog.Errorf("failed to save admission webhooks, %v", err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		klog.Errorf("failed to save admission webhooks, %v", err)
+	}
+}
+
+func (m *Matcher) load() {
+	var webhooks []Webhook
+	if m.loadFile(webhooksFileName, &webhooks) {
+		m.webhooks = webhooks
+		klog.Infof("%d admission webhooks are loaded", len(webhooks))
+	}
+	var namespaces map[string]map[string]string
+	if m.loadFile(namespacesFileName, &namespaces) {
+		m.namespaces = namespaces
+	}
+}
+
+func (m *Matcher) loadFile(name string, obj interface{}) bool {
+	data, err := os.ReadFile(filepath.Join(m.dir, name))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Errorf("failed to load %s for admission webhooks, %v", name, err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		klog.Errorf("failed to decode %s for admission webhooks, %v", name, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"reflect"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newRule(operation admissionregistrationv1.OperationType, group, resource string, scope admissionregistrationv1.ScopeType) admissionregistrationv1.RuleWithOperations {
+	return admissionregistrationv1.RuleWithOperations{
+		Operations: []admissionregistrationv1.OperationType{operation},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{group},
+			APIVersions: []string{"*"},
+			Resources:   []string{resource},
+			Scope:       &scope,
+		},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	exact := admissionregistrationv1.Exact
+	exactRule := newRule(admissionregistrationv1.Create, "apps", "deployments", admissionregistrationv1.AllScopes)
+	exactRule.APIVersions = []string{"v1"}
+	client := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: map[string]string{"control-plane": "true"}}},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{
+				{
+					Name:  "mutate.policy.io",
+					Rules: []admissionregistrationv1.RuleWithOperations{newRule(admissionregistrationv1.OperationAll, "*", "*/*", admissionregistrationv1.AllScopes)},
+					ObjectSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "policy.io/ignore", Operator: metav1.LabelSelectorOpDoesNotExist}},
+					},
+				},
+			},
+		},
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "events"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{
+					Name:  "create-events.policy.io",
+					Rules: []admissionregistrationv1.RuleWithOperations{newRule(admissionregistrationv1.Create, "", "events", admissionregistrationv1.NamespacedScope)},
+				},
+				{
+					Name:  "cluster-events.policy.io",
+					Rules: []admissionregistrationv1.RuleWithOperations{newRule(admissionregistrationv1.Create, "", "events", admissionregistrationv1.ClusterScope)},
+				},
+				{
+					Name:  "control-plane.policy.io",
+					Rules: []admissionregistrationv1.RuleWithOperations{newRule(admissionregistrationv1.Delete, "*", "*", admissionregistrationv1.AllScopes)},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "control-plane", Operator: metav1.LabelSelectorOpExists}},
+					},
+				},
+				{
+					Name:        "exact-deployments.policy.io",
+					Rules:       []admissionregistrationv1.RuleWithOperations{exactRule},
+					MatchPolicy: &exact,
+				},
+				{
+					Name:  "equivalent-deployments.policy.io",
+					Rules: []admissionregistrationv1.RuleWithOperations{exactRule},
+				},
+			},
+		},
+	)
+	webhooks, err := listWebhooks(client)
+	if err != nil {
+		t.Fatalf("failed to list webhooks, %v", err)
+	}
+	namespaces, err := listNamespaceLabels(client)
+	if err != nil {
+		t.Fatalf("failed to list namespaces, %v", err)
+	}
+
+	dir := t.TempDir()
+	m := &Matcher{dir: dir, webhooks: webhooks, namespaces: namespaces}
+	m.save(webhooksFileName, webhooks)
+	m.save(namespacesFileName, namespaces)
+	// the matcher loaded from dir is used, so the webhooks are known when yurthub restarts during an outage
+	m = NewMatcher(dir)
+
+	events := schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	testcases := map[string]struct {
+		gvr       schema.GroupVersionResource
+		namespace string
+		operation admissionregistrationv1.OperationType
+		labels    map[string]string
+		expect    []string
+	}{
+		"create event": {
+			gvr:       events,
+			namespace: "default",
+			operation: admissionregistrationv1.Create,
+			expect:    []string{"create-events.policy.io", "mutate.policy.io"},
+		},
+		"update event": {
+			gvr:       events,
+			namespace: "default",
+			operation: admissionregistrationv1.Update,
+			expect:    []string{"mutate.policy.io"},
+		},
+		"event is not selected by object selector": {
+			gvr:       events,
+			namespace: "default",
+			operation: admissionregistrationv1.Create,
+			labels:    map[string]string{"policy.io/ignore": "true"},
+			expect:    []string{"create-events.policy.io"},
+		},
+		"other resource": {
+			gvr:       schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			namespace: "default",
+			operation: admissionregistrationv1.Delete,
+			expect:    []string{"mutate.policy.io"},
+		},
+		"namespace is selected by namespace selector": {
+			gvr:       events,
+			namespace: "kube-system",
+			operation: admissionregistrationv1.Delete,
+			expect:    []string{"control-plane.policy.io", "mutate.policy.io"},
+		},
+		"unknown namespace is treated as selected": {
+			gvr:       events,
+			namespace: "created-offline",
+			operation: admissionregistrationv1.Delete,
+			expect:    []string{"control-plane.policy.io", "mutate.policy.io"},
+		},
+		"namespace object is selected by its labels": {
+			gvr:       schema.GroupVersionResource{Version: "v1", Resource: "namespaces"},
+			operation: admissionregistrationv1.Delete,
+			labels:    map[string]string{"control-plane": "true"},
+			expect:    []string{"control-plane.policy.io", "mutate.policy.io"},
+		},
+		"cluster scoped object ignores namespace selector": {
+			gvr:       schema.GroupVersionResource{Version: "v1", Resource: "nodes"},
+			operation: admissionregistrationv1.Delete,
+			expect:    []string{"control-plane.policy.io", "mutate.policy.io"},
+		},
+		"exact version": {
+			gvr:       schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			namespace: "default",
+			operation: admissionregistrationv1.Create,
+			expect:    []string{"equivalent-deployments.policy.io", "exact-deployments.policy.io", "mutate.policy.io"},
+		},
+		"equivalent version": {
+			gvr:       schema.GroupVersionResource{Group: "apps", Version: "v1beta2", Resource: "deployments"},
+			namespace: "default",
+			operation: admissionregistrationv1.Create,
+			expect:    []string{"equivalent-deployments.policy.io", "mutate.policy.io"},
+		},
+	}
+
+	for k, tt := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if got := m.Match(tt.gvr, tt.namespace, tt.operation, tt.labels); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expect webhooks %v, but got %v", tt.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesspolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+const defaultSyncPeriod = 30 * time.Second
+
+// tunnelAccessPoliciesPath is the path for listing TunnelAccessPolicy, the policies are listed by
+// the rest client of clientset, so no extra client is needed by tunnel server and agent.
+var tunnelAccessPoliciesPath = fmt.Sprintf("/apis/%s/tunnelaccesspolicies", ravenv1alpha1.GroupVersion.String())
+
+// Enforcer checks the destinations dialed through the tunnel against the ingress rules of
+// TunnelAccessPolicy. The destinations of a node are not restricted if the node is not selected
+// by any policy, otherwise the destination must be matched by a rule of the selecting policies.
+type Enforcer interface {
+	Run(stopCh <-chan struct{}, wg *sync.WaitGroup)
+	// Check returns an error if dialing addr on the node with protocol is not allowed.
+	Check(node *corev1.Node, protocol, addr string) error
+}
+
+type policyEnforcer struct {
+	listPolicies func() ([]ravenv1alpha1.TunnelAccessPolicy, error)
+	syncPeriod   time.Duration
+	sync.RWMutex
+	synced   bool
+	policies []policy
+}
+
+type policy struct {
+	name     string
+	selector labels.Selector
+	rules    []rule
+}
+
+type rule struct {
+	cidrs []*net.IPNet
+	ports []ravenv1alpha1.AccessPort
+}
+
+// NewEnforcer creates an Enforcer which lists the policies periodically, all destinations are
+// rejected until the policies are listed successfully.
+func NewEnforcer(client clientset.Interface) Enforcer {
+	return &policyEnforcer{
+		listPolicies: func() ([]ravenv1alpha1.TunnelAccessPolicy, error) {
+			return listTunnelAccessPolicies(client)
+		},
+		syncPeriod: defaultSyncPeriod,
+	}
+}
+
+func listTunnelAccessPolicies(client clientset.Interface) ([]ravenv1alpha1.TunnelAccessPolicy, error) {
+	data, err := client.Discovery().RESTClient().Get().AbsPath(tunnelAccessPoliciesPath).DoRaw(context.Background())
+	if err != nil {
+		// no destination is restricted if the crd is not installed
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list ravenv1alpha1.TunnelAccessPolicyList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode tunnel access policies, %w", err)
+	}
+	return list.Items, nil
+}
+
+// Run syncs the policies until stopCh is closed
+func (e *policyEnforcer) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	e.syncPolicies()
+
+	ticker := time.NewTicker(e.syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("stop syncing tunnel access policies")
+			return
+		case <-ticker.C:
+			e.syncPolicies()
+		}
+	}
+}
+
+// syncPolicies keeps the last synced policies if the policies can't be listed.
+func (e *policyEnforcer) syncPolicies() {
+	items, err := e.listPolicies()
+	if err != nil {
+		klog.Errorf("failed to list tunnel access policies, %v", err)
+		return
+	}
+
+	policies := make([]policy, 0, len(items))
+	for i := range items {
+		policies = append(policies, compilePolicy(&items[i]))
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	if !e.synced || len(e.policies) != len(policies) {
+		klog.Infof("%d tunnel access policies are enforced", len(policies))
+	}
+	e.policies = policies
+	e.synced = true
+}
+
+// compilePolicy parses the node selector and cidrs of policy. the invalid parts never make the
+// policy more permissive: a policy with invalid node selector selects all nodes, and the rules
+// with invalid cidrs are skipped.
+func compilePolicy(tap *ravenv1alpha1.TunnelAccessPolicy) policy {
+	p := policy{name: tap.Name, selector: labels.Everything()}
+	if tap.Spec.NodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(tap.Spec.NodeSelector)
+		if err != nil {
+			klog.Errorf("node selector of tunnel access policy %s is invalid, all nodes are selected, %v", tap.Name, err)
+		} else {
+			p.selector = selector
+		}
+	}
+
+	for i, ar := range tap.Spec.Ingress {
+		r, err := compileRule(ar)
+		if err != nil {
+			klog.Errorf("ingress rule %d of tunnel access policy %s is skipped, %v", i, tap.Name, err)
+			continue
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+func compileRule(ar ravenv1alpha1.AccessRule) (rule, error) {
+	r := rule{ports: ar.Ports}
+	for _, cidr := range ar.CIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return r, fmt.Errorf("cidr %q is invalid, %w", cidr, err)
+		}
+		r.cidrs = append(r.cidrs, ipNet)
+	}
+	return r, nil
+}
+
+func (e *policyEnforcer) Check(node *corev1.Node, protocol, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("destination %s is invalid, %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("port of destination %s is invalid, %w", addr, err)
+	}
+
+	e.RLock()
+	defer e.RUnlock()
+	if !e.synced {
+		return errors.New("tunnel access policies are not synced")
+	}
+
+	selected := false
+	nodeLabels := labels.Set(node.Labels)
+	for _, p := range e.policies {
+		if !p.selector.Matches(nodeLabels) {
+			continue
+		}
+		selected = true
+		for _, r := range p.rules {
+			if r.matches(node, protocol, host, int32(port)) {
+				return nil
+			}
+		}
+	}
+	if !selected {
+		return nil
+	}
+	return fmt.Errorf("%s %s on node %s is not allowed by tunnel access policies", protocol, addr, node.Name)
+}
+
+func (r *rule) matches(node *corev1.Node, protocol, host string, port int32) bool {
+	if !r.matchesPort(protocol, port) {
+		return false
+	}
+	if len(r.cidrs) == 0 {
+		return isNodeAddress(node, host)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range r.cidrs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) matchesPort(protocol string, port int32) bool {
+	if len(r.ports) == 0 {
+		return true
+	}
+	for _, ap := range r.ports {
+		apProtocol := string(ap.Protocol)
+		if len(apProtocol) == 0 {
+			apProtocol = string(corev1.ProtocolTCP)
+		}
+		if !strings.EqualFold(apProtocol, protocol) {
+			continue
+		}
+		endPort := ap.EndPort
+		if endPort < ap.Port {
+			endPort = ap.Port
+		}
+		if port >= ap.Port && port <= endPort {
+			return true
+		}
+	}
+	return false
+}
+
+// isNodeAddress checks the host is the node itself, including its name, addresses and loopback addresses.
+func isNodeAddress(node *corev1.Node, host string) bool {
+	if strings.EqualFold(host, node.Name) || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, addr := range node.Status.Addresses {
+		if strings.EqualFold(host, addr.Address) {
+			return true
+		}
+	}
+	return false
+}
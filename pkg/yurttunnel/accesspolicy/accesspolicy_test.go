@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesspolicy
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ravenv1alpha1 "github.com/openyurtio/openyurt/pkg/apis/raven/v1alpha1"
+)
+
+func newTestNode(name, ip string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policies := []ravenv1alpha1.TunnelAccessPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubelet"},
+			Spec: ravenv1alpha1.TunnelAccessPolicySpec{
+				Ingress: []ravenv1alpha1.AccessRule{
+					{Ports: []ravenv1alpha1.AccessPort{{Port: 10250}, {Port: 10255}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "hangzhou"},
+			Spec: ravenv1alpha1.TunnelAccessPolicySpec{
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "hangzhou"}},
+				Ingress: []ravenv1alpha1.AccessRule{
+					{CIDRs: []string{"192.168.0.0/16"}, Ports: []ravenv1alpha1.AccessPort{{Port: 8000, EndPort: 8080}}},
+					{CIDRs: []string{"10.0.0.0/8"}, Ports: []ravenv1alpha1.AccessPort{{Protocol: corev1.ProtocolUDP, Port: 53}}},
+					{CIDRs: []string{"invalid"}},
+				},
+			},
+		},
+	}
+	e := &policyEnforcer{listPolicies: func() ([]ravenv1alpha1.TunnelAccessPolicy, error) {
+		return policies, nil
+	}}
+
+	hz := newTestNode("edge-node-1", "172.16.0.1", map[string]string{"pool": "hangzhou"})
+	if err := e.Check(hz, "tcp", "172.16.0.1:10250"); err == nil {
+		t.Errorf("expect destinations are rejected before policies are synced")
+	}
+	e.syncPolicies()
+
+	testcases := map[string]struct {
+		node     *corev1.Node
+		protocol string
+		addr     string
+		allowed  bool
+	}{
+		"kubelet port of node address": {
+			node: hz, protocol: "tcp", addr: "172.16.0.1:10250", allowed: true,
+		},
+		"kubelet port of loopback address": {
+			node: hz, protocol: "tcp", addr: "127.0.0.1:10255", allowed: true,
+		},
+		"kubelet port of node name": {
+			node: hz, protocol: "tcp", addr: "edge-node-1:10250", allowed: true,
+		},
+		"kubelet port of other address": {
+			node: hz, protocol: "tcp", addr: "172.16.0.2:10250",
+		},
+		"other port of node address": {
+			node: hz, protocol: "tcp", addr: "172.16.0.1:22",
+		},
+		"port in range of cidr": {
+			node: hz, protocol: "tcp", addr: "192.168.1.10:8080", allowed: true,
+		},
+		"port out of range of cidr": {
+			node: hz, protocol: "tcp", addr: "192.168.1.10:8081",
+		},
+		"udp port of cidr": {
+			node: hz, protocol: "udp", addr: "10.0.0.10:53", allowed: true,
+		},
+		"tcp port of udp rule": {
+			node: hz, protocol: "tcp", addr: "10.0.0.10:53",
+		},
+		"hostname is not matched by cidr": {
+			node: hz, protocol: "tcp", addr: "example.com:8000",
+		},
+		"rules of other pool are not applied": {
+			node: newTestNode("edge-node-2", "172.16.0.2", map[string]string{"pool": "beijing"}), protocol: "tcp", addr: "192.168.1.10:8080",
+		},
+		"invalid destination": {
+			node: hz, protocol: "tcp", addr: "172.16.0.1",
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			err := e.Check(tc.node, tc.protocol, tc.addr)
+			if tc.allowed && err != nil {
+				t.Errorf("expect %s %s is allowed, but got %v", tc.protocol, tc.addr, err)
+			} else if !tc.allowed && err == nil {
+				t.Errorf("expect %s %s is rejected", tc.protocol, tc.addr)
+			}
+		})
+	}
+}
+
+func TestCheckWithoutPolicies(t *testing.T) {
+	policies := []ravenv1alpha1.TunnelAccessPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "hangzhou"},
+			Spec: ravenv1alpha1.TunnelAccessPolicySpec{
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "hangzhou"}},
+			},
+		},
+	}
+	listErr := errors.New("connection refused")
+	var err error
+	e := &policyEnforcer{listPolicies: func() ([]ravenv1alpha1.TunnelAccessPolicy, error) {
+		return policies, err
+	}}
+	e.syncPolicies()
+
+	if err := e.Check(newTestNode("edge-node-2", "172.16.0.2", nil), "tcp", "172.16.0.2:22"); err != nil {
+		t.Errorf("expect destinations of node not selected by any policy are allowed, but got %v", err)
+	}
+	if err := e.Check(newTestNode("edge-node-1", "172.16.0.1", map[string]string{"pool": "hangzhou"}), "tcp", "172.16.0.1:10250"); err == nil {
+		t.Errorf("expect destinations of node selected by policy without rules are rejected")
+	}
+
+	// the last synced policies are kept if policies can't be listed
+	policies, err = nil, listErr
+	e.syncPolicies()
+	if err := e.Check(newTestNode("edge-node-1", "172.16.0.1", map[string]string{"pool": "hangzhou"}), "tcp", "172.16.0.1:10250"); err == nil {
+		t.Errorf("expect the last synced policies are kept")
+	}
+}
+
+func TestNodeChecker(t *testing.T) {
+	e := &policyEnforcer{listPolicies: func() ([]ravenv1alpha1.TunnelAccessPolicy, error) {
+		return nil, nil
+	}}
+	e.syncPolicies()
+
+	c := &NodeChecker{
+		enforcer: e,
+		getNode: func() (*corev1.Node, error) {
+			return newTestNode("edge-node-1", "172.16.0.1", nil), nil
+		},
+	}
+	if err := c.Check("tcp", "172.16.0.1:10250"); err == nil {
+		t.Errorf("expect destinations are rejected before node is synced")
+	}
+	c.syncNode()
+	if err := c.Check("tcp", "172.16.0.1:10250"); err != nil {
+		t.Errorf("expect destination is allowed, but got %v", err)
+	}
+}
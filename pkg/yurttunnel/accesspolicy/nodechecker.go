@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accesspolicy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// NodeChecker checks the destinations dialed on the node which the tunnel agent is running on,
+// the node is refreshed periodically, so the checks never wait for kube-apiserver.
+type NodeChecker struct {
+	enforcer   Enforcer
+	getNode    func() (*corev1.Node, error)
+	syncPeriod time.Duration
+	sync.RWMutex
+	node *corev1.Node
+}
+
+// NewNodeChecker creates a NodeChecker for node, all destinations are rejected until the node
+// is got from kube-apiserver, because the policies are selected by the labels of node.
+func NewNodeChecker(enforcer Enforcer, client clientset.Interface, nodeName string) *NodeChecker {
+	return &NodeChecker{
+		enforcer: enforcer,
+		getNode: func() (*corev1.Node, error) {
+			return client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+		},
+		syncPeriod: defaultSyncPeriod,
+	}
+}
+
+// Run refreshes the node until stopCh is closed
+func (c *NodeChecker) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	c.syncNode()
+
+	ticker := time.NewTicker(c.syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			c.syncNode()
+		}
+	}
+}
+
+// syncNode keeps the last node if the node can't be got.
+func (c *NodeChecker) syncNode() {
+	node, err := c.getNode()
+	if err != nil {
+		klog.Errorf("failed to get node for checking tunnel access policies, %v", err)
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.node = node
+}
+
+// Check returns an error if dialing addr on the node with protocol is not allowed.
+func (c *NodeChecker) Check(protocol, addr string) error {
+	c.RLock()
+	node := c.node
+	c.RUnlock()
+	if node == nil {
+		return errors.New("node is not synced for checking tunnel access policies")
+	}
+	return c.enforcer.Check(node, protocol, addr)
+}
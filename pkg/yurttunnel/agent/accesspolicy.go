@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/apiserver-network-proxy/konnectivity-client/proto/client"
+)
+
+// deniedProtocol replaces the protocol of dial requests which are not allowed by the tunnel
+// access policies, so the dial of agent fails at once and the error is sent back to the server.
+const deniedProtocol = "denied-by-tunnel-access-policy"
+
+// accessPolicyStreamInterceptor checks the dial requests received from tunnel server, because
+// the agent of apiserver-network-proxy dials the requested addresses directly.
+func accessPolicyStreamInterceptor(check func(protocol, addr string) error) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &accessPolicyStream{ClientStream: stream, check: check}, nil
+	}
+}
+
+type accessPolicyStream struct {
+	grpc.ClientStream
+	check func(protocol, addr string) error
+}
+
+func (s *accessPolicyStream) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return err
+	}
+	pkt, ok := m.(*client.Packet)
+	if !ok || pkt.Type != client.PacketType_DIAL_REQ {
+		return nil
+	}
+	dialReq := pkt.GetDialRequest()
+	if dialReq == nil {
+		return nil
+	}
+	if err := s.check(dialReq.Protocol, dialReq.Address); err != nil {
+		klog.Errorf("dial request to %s %s is rejected, %v", dialReq.Protocol, dialReq.Address, err)
+		dialReq.Protocol = deniedProtocol
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"sigs.k8s.io/apiserver-network-proxy/konnectivity-client/proto/client"
+)
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	pkt *client.Packet
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	*m.(*client.Packet) = *s.pkt
+	return nil
+}
+
+func TestAccessPolicyStream(t *testing.T) {
+	check := func(protocol, addr string) error {
+		if addr == "127.0.0.1:10250" {
+			return nil
+		}
+		return errors.New("not allowed")
+	}
+
+	testcases := map[string]struct {
+		addr     string
+		protocol string
+	}{
+		"allowed destination": {
+			addr: "127.0.0.1:10250", protocol: "tcp",
+		},
+		"rejected destination": {
+			addr: "127.0.0.1:22", protocol: deniedProtocol,
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			stream := &accessPolicyStream{
+				ClientStream: &fakeClientStream{pkt: &client.Packet{
+					Type:    client.PacketType_DIAL_REQ,
+					Payload: &client.Packet_DialRequest{DialRequest: &client.DialRequest{Protocol: "tcp", Address: tc.addr}},
+				}},
+				check: check,
+			}
+			pkt := &client.Packet{}
+			if err := stream.RecvMsg(pkt); err != nil {
+				t.Fatalf("failed to receive packet, %v", err)
+			}
+			if protocol := pkt.GetDialRequest().Protocol; protocol != tc.protocol {
+				t.Errorf("expect protocol %s, but got %s", tc.protocol, protocol)
+			}
+		})
+	}
+
+	// the dial of rejected destination fails at once
+	if _, err := net.Dial(deniedProtocol, "127.0.0.1:22"); err == nil || !strings.Contains(err.Error(), deniedProtocol) {
+		t.Errorf("expect dial with denied protocol fails, but got %v", err)
+	}
+}
@@ -18,6 +18,8 @@ package agent
 
 import (
 	"crypto/tls"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
 )
 
 // TunnelAgent sets up tunnel to TunnelServer, receive requests
@@ -26,14 +28,23 @@ type TunnelAgent interface {
 	Run(<-chan struct{})
 }
 
-// NewTunnelAgent generates a new TunnelAgent
+// NewTunnelAgent generates a new TunnelAgent, the bandwidth of tunnel is limited
+// to maxUploadBandwidth and maxDownloadBandwidth bytes per second if they are positive, and
+// the dial requests from server are checked by accessChecker if it's not nil. The agent
+// connects to one of tunnelServerAddrs at a time and fails over to the next one if the
+// current server is unhealthy.
 func NewTunnelAgent(tlsCfg *tls.Config,
-	tunnelServerAddr, nodeName, agentIdentifiers string) TunnelAgent {
+	tunnelServerAddrs []string, nodeName, agentIdentifiers string,
+	maxUploadBandwidth, maxDownloadBandwidth int,
+	accessChecker *accesspolicy.NodeChecker) TunnelAgent {
 	ata := anpTunnelAgent{
-		tlsCfg:           tlsCfg,
-		tunnelServerAddr: tunnelServerAddr,
-		nodeName:         nodeName,
-		agentIdentifiers: agentIdentifiers,
+		tlsCfg:               tlsCfg,
+		tunnelServerAddrs:    tunnelServerAddrs,
+		nodeName:             nodeName,
+		agentIdentifiers:     agentIdentifiers,
+		maxUploadBandwidth:   maxUploadBandwidth,
+		maxDownloadBandwidth: maxDownloadBandwidth,
+		accessChecker:        accessChecker,
 	}
 
 	return &ata
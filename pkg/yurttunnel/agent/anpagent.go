@@ -18,6 +18,7 @@ package agent
 
 import (
 	"crypto/tls"
+	"net"
 	"time"
 
 	"google.golang.org/grpc"
@@ -26,34 +27,78 @@ import (
 	anpagent "sigs.k8s.io/apiserver-network-proxy/pkg/agent"
 
 	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
 )
 
 // anpTunnelAgent implements the TunnelAgent using the
 // apiserver-network-proxy package
 type anpTunnelAgent struct {
-	tlsCfg           *tls.Config
-	tunnelServerAddr string
-	nodeName         string
-	agentIdentifiers string
+	tlsCfg               *tls.Config
+	tunnelServerAddrs    []string
+	nodeName             string
+	agentIdentifiers     string
+	maxUploadBandwidth   int
+	maxDownloadBandwidth int
+	accessChecker        *accesspolicy.NodeChecker
 }
 
 var _ TunnelAgent = &anpTunnelAgent{}
 
-// RunAgent runs the yurttunnel-agent which will try to connect yurttunnel-server
+// RunAgent runs the yurttunnel-agent which will try to connect yurttunnel-server, the agent
+// fails over among the servers if more than one server address is configured, and re-establishes
+// the connections gracefully when the certificates are rotated.
 func (ata *anpTunnelAgent) Run(stopChan <-chan struct{}) {
-	dialOption := grpc.WithTransportCredentials(credentials.NewTLS(ata.tlsCfg))
+	var f *serverFailover
+	watcher := newCertRotationWatcher(ata.tlsCfg, func() {
+		f.notifyRotated()
+	})
+	f = newServerFailover(ata.tunnelServerAddrs, func(addr string, stopCh <-chan struct{}) serverConnection {
+		return ata.connect(addr, tlsConfigFor(ata.tlsCfg, addr, watcher.verifyConnection), stopCh)
+	})
+	go f.run(stopChan)
+	go watcher.run(stopChan)
+}
+
+// connect serves the grpc requests from the tunnel server at addr until stopCh is closed.
+func (ata *anpTunnelAgent) connect(addr string, tlsCfg *tls.Config, stopCh <-chan struct{}) *anpagent.ClientSet {
+	dialOption := grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))
+	dialerOption := grpc.WithContextDialer(bandwidthLimitedDialer(ata.maxUploadBandwidth, ata.maxDownloadBandwidth))
+	dialOptions := []grpc.DialOption{dialOption, dialerOption}
+	if ata.accessChecker != nil {
+		dialOptions = append(dialOptions, grpc.WithStreamInterceptor(accessPolicyStreamInterceptor(ata.accessChecker.Check)))
+	}
 	cc := &anpagent.ClientSetConfig{
-		Address:                 ata.tunnelServerAddr,
+		Address:                 addr,
 		AgentID:                 ata.nodeName,
 		AgentIdentifiers:        ata.agentIdentifiers,
 		SyncInterval:            5 * time.Second,
 		ProbeInterval:           5 * time.Second,
-		DialOptions:             []grpc.DialOption{dialOption},
+		DialOptions:             dialOptions,
 		ServiceAccountTokenPath: "",
 	}
 
-	cs := cc.NewAgentClientSet(stopChan)
+	cs := cc.NewAgentClientSet(stopCh)
 	cs.Serve()
 	klog.Infof("start serving grpc request redirected from %s: %s",
-		projectinfo.GetServerName(), ata.tunnelServerAddr)
+		projectinfo.GetServerName(), addr)
+	return cs
+}
+
+// tlsConfigFor verifies the certificate of tunnel server at addr by the host of addr, as the
+// servers may be published by different hosts, and the verified connections are passed to
+// verifyConnection.
+func tlsConfigFor(tlsCfg *tls.Config, addr string, verifyConnection func(tls.ConnectionState) error) *tls.Config {
+	cfg := tlsCfg.Clone()
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		cfg.ServerName = host
+	}
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if tlsCfg.VerifyConnection != nil {
+			if err := tlsCfg.VerifyConnection(cs); err != nil {
+				return err
+			}
+		}
+		return verifyConnection(cs)
+	}
+	return cfg
 }
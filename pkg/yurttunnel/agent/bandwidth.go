@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"context"
+	"net"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+// bandwidthLimitedDialer returns a dialer for the grpc connection to tunnel server, all of
+// the connections share the same limiters, so the limits are applied to the node as a whole.
+func bandwidthLimitedDialer(maxUploadBandwidth, maxDownloadBandwidth int) func(ctx context.Context, addr string) (net.Conn, error) {
+	upload := util.NewBandwidthLimiter(maxUploadBandwidth)
+	download := util.NewBandwidthLimiter(maxDownloadBandwidth)
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		if upload == nil && download == nil {
+			return conn, nil
+		}
+		return &util.BandwidthLimitedConn{Conn: conn, ReadLimiter: download, WriteLimiter: upload}, nil
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultHealthCheckInterval = 5 * time.Second
+	// defaultFailureThreshold is the number of consecutive failed health checks before
+	// switching to the next tunnel server.
+	defaultFailureThreshold = 3
+	// defaultDrainTimeout is how long the connections to the previous tunnel server are kept
+	// after the connections to the next one are healthy, so the in-flight requests are finished.
+	defaultDrainTimeout = 30 * time.Second
+)
+
+// defaultFailoverBackoff is used for waiting before trying the tunnel servers again
+// when none of them is healthy.
+var defaultFailoverBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    6,
+	Cap:      time.Minute,
+}
+
+// serverConnection is the connections to a tunnel server, it is healthy if any of its
+// connections is ready.
+type serverConnection interface {
+	HealthyClientsCount() int
+}
+
+// connectFunc connects to the tunnel server at addr until stopCh is closed.
+type connectFunc func(addr string, stopCh <-chan struct{}) serverConnection
+
+type activeServer struct {
+	addr    string
+	conn    serverConnection
+	stopCh  chan struct{}
+	healthy bool
+}
+
+func (s *activeServer) close() {
+	klog.Infof("close connections to tunnel server %s", s.addr)
+	close(s.stopCh)
+}
+
+// serverFailover connects to one of the tunnel servers at a time, and switches to the next
+// one when the current server is unhealthy for failureThreshold consecutive checks. The
+// connections to a server which has been healthy are drained instead of being closed at once,
+// they are closed drainTimeout after the next server is healthy. The connections to the current
+// server are re-established in the same way when the certificates are rotated.
+type serverFailover struct {
+	addrs            []string
+	connect          connectFunc
+	checkInterval    time.Duration
+	failureThreshold int
+	drainTimeout     time.Duration
+	backoff          wait.Backoff
+	rotated          chan struct{}
+}
+
+func newServerFailover(addrs []string, connect connectFunc) *serverFailover {
+	return &serverFailover{
+		addrs:            addrs,
+		connect:          connect,
+		checkInterval:    defaultHealthCheckInterval,
+		failureThreshold: defaultFailureThreshold,
+		drainTimeout:     defaultDrainTimeout,
+		backoff:          defaultFailoverBackoff,
+		rotated:          make(chan struct{}, 1),
+	}
+}
+
+// notifyRotated never blocks, the pending notifications are merged into one re-establishment.
+func (f *serverFailover) notifyRotated() {
+	select {
+	case f.rotated <- struct{}{}:
+	default:
+	}
+}
+
+func (f *serverFailover) connectTo(addr string) *activeServer {
+	klog.Infof("connect to tunnel server %s", addr)
+	s := &activeServer{addr: addr, stopCh: make(chan struct{})}
+	s.conn = f.connect(addr, s.stopCh)
+	return s
+}
+
+// run checks the health of current server and fails over until stopCh is closed.
+func (f *serverFailover) run(stopCh <-chan struct{}) {
+	var (
+		idx        int
+		current    = f.connectTo(f.addrs[idx])
+		draining   *activeServer
+		drainTimer <-chan time.Time
+		failures   int
+		// unhealthyServers is the number of servers which are switched over one after another
+		// without any of them being healthy.
+		unhealthyServers int
+		backoff          = f.backoff
+	)
+	ticker := time.NewTicker(f.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			current.close()
+			if draining != nil {
+				draining.close()
+			}
+			return
+		case <-drainTimer:
+			draining.close()
+			draining, drainTimer = nil, nil
+		case <-f.rotated:
+			// the new connections are handshaked with the rotated certificates, and the current
+			// connections keep serving until the new ones are healthy.
+			klog.Infof("certificates are rotated, re-establish connections to tunnel server %s", current.addr)
+			if draining != nil {
+				draining.close()
+			}
+			current.healthy = false
+			draining, drainTimer = current, nil
+			current = f.connectTo(current.addr)
+			failures = 0
+		case <-ticker.C:
+			if current.conn.HealthyClientsCount() > 0 {
+				failures = 0
+				if !current.healthy {
+					klog.Infof("tunnel server %s is healthy", current.addr)
+					current.healthy = true
+					unhealthyServers = 0
+					backoff = f.backoff
+					if draining != nil {
+						drainTimer = time.After(f.drainTimeout)
+					}
+				}
+				continue
+			}
+
+			failures++
+			if failures < f.failureThreshold {
+				continue
+			}
+			failures = 0
+			klog.Warningf("tunnel server %s is unhealthy, fail over to the next tunnel server", current.addr)
+			if current.healthy {
+				// the previous draining server is replaced, as only the latest healthy server is kept
+				if draining != nil {
+					draining.close()
+				}
+				current.healthy = false
+				draining, drainTimer = current, nil
+			} else {
+				current.close()
+			}
+
+			unhealthyServers++
+			if unhealthyServers >= len(f.addrs) {
+				delay := backoff.Step()
+				klog.Warningf("none of tunnel servers is healthy, try again after %v", delay)
+				select {
+				case <-stopCh:
+					if draining != nil {
+						draining.close()
+					}
+					return
+				case <-time.After(delay):
+				}
+				unhealthyServers = 0
+			}
+
+			idx = (idx + 1) % len(f.addrs)
+			if draining != nil && draining.addr == f.addrs[idx] {
+				// the connections to the draining server are reused instead of connecting again
+				current, draining, drainTimer = draining, nil, nil
+			} else {
+				current = f.connectTo(f.addrs[idx])
+			}
+		}
+	}
+}
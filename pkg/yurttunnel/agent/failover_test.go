@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+type fakeServerConnection struct {
+	healthy *int32
+}
+
+func (c *fakeServerConnection) HealthyClientsCount() int {
+	return int(atomic.LoadInt32(c.healthy))
+}
+
+type fakeServers struct {
+	sync.Mutex
+	healthy   map[string]*int32
+	connected []string
+	stopped   map[string]int
+}
+
+func newFakeServers(addrs ...string) *fakeServers {
+	fs := &fakeServers{healthy: make(map[string]*int32), stopped: make(map[string]int)}
+	for _, addr := range addrs {
+		fs.healthy[addr] = new(int32)
+	}
+	return fs
+}
+
+func (fs *fakeServers) setHealthy(addr string, healthy bool) {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	atomic.StoreInt32(fs.healthy[addr], v)
+}
+
+func (fs *fakeServers) connect(addr string, stopCh <-chan struct{}) serverConnection {
+	fs.Lock()
+	fs.connected = append(fs.connected, addr)
+	fs.Unlock()
+	go func() {
+		<-stopCh
+		fs.Lock()
+		fs.stopped[addr]++
+		fs.Unlock()
+	}()
+	return &fakeServerConnection{healthy: fs.healthy[addr]}
+}
+
+func (fs *fakeServers) state() ([]string, map[string]int) {
+	fs.Lock()
+	defer fs.Unlock()
+	stopped := make(map[string]int, len(fs.stopped))
+	for k, v := range fs.stopped {
+		stopped[k] = v
+	}
+	return append([]string(nil), fs.connected...), stopped
+}
+
+func newTestFailover(fs *fakeServers, addrs ...string) *serverFailover {
+	f := newServerFailover(addrs, fs.connect)
+	f.checkInterval = 10 * time.Millisecond
+	f.failureThreshold = 2
+	f.drainTimeout = 100 * time.Millisecond
+	f.backoff = wait.Backoff{Duration: 10 * time.Millisecond, Factor: 2, Steps: 3, Cap: 50 * time.Millisecond}
+	return f
+}
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	if err := wait.PollImmediate(5*time.Millisecond, 3*time.Second, func() (bool, error) {
+		return cond(), nil
+	}); err != nil {
+		t.Fatalf("timeout waiting for %s", desc)
+	}
+}
+
+func TestServerFailoverDrainsUnhealthyServer(t *testing.T) {
+	fs := newFakeServers("a:1", "b:1")
+	fs.setHealthy("a:1", true)
+	f := newTestFailover(fs, "a:1", "b:1")
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		f.run(stopCh)
+		close(done)
+	}()
+
+	// stays on the healthy server
+	time.Sleep(50 * time.Millisecond)
+	if connected, _ := fs.state(); len(connected) != 1 || connected[0] != "a:1" {
+		t.Fatalf("expect only a:1 connected, but got %v", connected)
+	}
+
+	fs.setHealthy("b:1", true)
+	fs.setHealthy("a:1", false)
+	waitFor(t, "failover to b:1", func() bool {
+		connected, _ := fs.state()
+		return len(connected) == 2 && connected[1] == "b:1"
+	})
+	// the previous server is drained for a while after the next server is healthy
+	time.Sleep(30 * time.Millisecond)
+	if _, stopped := fs.state(); stopped["a:1"] != 0 {
+		t.Fatalf("expect a:1 not closed before draining timeout")
+	}
+
+	waitFor(t, "a:1 closed after draining", func() bool {
+		_, stopped := fs.state()
+		return stopped["a:1"] == 1
+	})
+
+	close(stopCh)
+	<-done
+	waitFor(t, "b:1 closed after stopped", func() bool {
+		_, stopped := fs.state()
+		return stopped["b:1"] == 1
+	})
+}
+
+func TestServerFailoverReusesDrainingServer(t *testing.T) {
+	fs := newFakeServers("a:1", "b:1")
+	fs.setHealthy("a:1", true)
+	f := newTestFailover(fs, "a:1", "b:1")
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go f.run(stopCh)
+
+	waitFor(t, "a:1 connected", func() bool {
+		connected, _ := fs.state()
+		return len(connected) == 1
+	})
+	time.Sleep(30 * time.Millisecond)
+	fs.setHealthy("a:1", false)
+	waitFor(t, "failover to b:1", func() bool {
+		connected, _ := fs.state()
+		return len(connected) == 2
+	})
+
+	// b:1 never becomes healthy, so the agent switches back to the draining a:1
+	fs.setHealthy("a:1", true)
+	waitFor(t, "b:1 closed", func() bool {
+		_, stopped := fs.state()
+		return stopped["b:1"] == 1
+	})
+	time.Sleep(50 * time.Millisecond)
+	connected, stopped := fs.state()
+	if len(connected) != 2 || stopped["a:1"] != 0 {
+		t.Fatalf("expect connections to a:1 reused, but got connected %v, stopped %v", connected, stopped)
+	}
+}
+
+func TestServerFailoverBacksOffWhenAllUnhealthy(t *testing.T) {
+	fs := newFakeServers("a:1", "b:1")
+	f := newTestFailover(fs, "a:1", "b:1")
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go f.run(stopCh)
+
+	waitFor(t, "servers tried again", func() bool {
+		connected, _ := fs.state()
+		return len(connected) >= 4
+	})
+	connected, _ := fs.state()
+	for i, addr := range connected {
+		if expected := f.addrs[i%2]; addr != expected {
+			t.Fatalf("expect server %s connected at %d, but got %s", expected, i, addr)
+		}
+	}
+}
+
+func TestServerFailoverReconnectsWhenRotated(t *testing.T) {
+	fs := newFakeServers("a:1")
+	fs.setHealthy("a:1", true)
+	f := newTestFailover(fs, "a:1")
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go f.run(stopCh)
+
+	waitFor(t, "a:1 connected", func() bool {
+		connected, _ := fs.state()
+		return len(connected) == 1
+	})
+	f.notifyRotated()
+	waitFor(t, "a:1 reconnected", func() bool {
+		connected, _ := fs.state()
+		return len(connected) == 2
+	})
+	// the previous connections are drained after the new ones are healthy
+	if _, stopped := fs.state(); stopped["a:1"] != 0 {
+		t.Fatalf("expect previous connections not closed before draining timeout")
+	}
+	waitFor(t, "previous connections closed", func() bool {
+		_, stopped := fs.state()
+		return stopped["a:1"] == 1
+	})
+}
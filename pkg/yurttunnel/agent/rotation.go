@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"bytes"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultRotationCheckInterval = time.Minute
+	// defaultServerCertExpiryThreshold is how long before the certificate of tunnel server expires
+	// the connections are re-established, so the rotated certificate of server is handshaked.
+	defaultServerCertExpiryThreshold = time.Hour
+)
+
+// certRotationWatcher notifies when the client certificate of agent is rotated, or the certificate
+// of tunnel server handshaked by the current connections is going to expire, then the connections
+// can be re-established with the new certificates before the old ones expire.
+type certRotationWatcher struct {
+	clientCert      func() *tls.Certificate
+	checkInterval   time.Duration
+	expiryThreshold time.Duration
+	notify          func()
+	sync.Mutex
+	serverCertNotAfter time.Time
+}
+
+func newCertRotationWatcher(tlsCfg *tls.Config, notify func()) *certRotationWatcher {
+	return &certRotationWatcher{
+		clientCert:      clientCertificateOf(tlsCfg),
+		checkInterval:   defaultRotationCheckInterval,
+		expiryThreshold: defaultServerCertExpiryThreshold,
+		notify:          notify,
+	}
+}
+
+// clientCertificateOf gets the client certificate in the same way as the handshakes.
+func clientCertificateOf(tlsCfg *tls.Config) func() *tls.Certificate {
+	return func() *tls.Certificate {
+		if tlsCfg.GetClientCertificate != nil {
+			cert, err := tlsCfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+			if err != nil {
+				return nil
+			}
+			return cert
+		}
+		if len(tlsCfg.Certificates) != 0 {
+			return &tlsCfg.Certificates[0]
+		}
+		return nil
+	}
+}
+
+// verifyConnection records the expiry of the certificate of tunnel server in the latest handshake,
+// it's set as tls.Config.VerifyConnection and never rejects the connection.
+func (w *certRotationWatcher) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	w.Lock()
+	defer w.Unlock()
+	w.serverCertNotAfter = cs.PeerCertificates[0].NotAfter
+	return nil
+}
+
+// run checks the certificates periodically until stopCh is closed.
+func (w *certRotationWatcher) run(stopCh <-chan struct{}) {
+	lastClientCert := leafOf(w.clientCert())
+	var notifiedNotAfter time.Time
+
+	ticker := time.NewTicker(w.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		if clientCert := leafOf(w.clientCert()); clientCert != nil && !bytes.Equal(clientCert, lastClientCert) {
+			if lastClientCert != nil {
+				klog.Info("certificate of tunnel agent is rotated")
+				w.notify()
+			}
+			lastClientCert = clientCert
+			continue
+		}
+
+		w.Lock()
+		notAfter := w.serverCertNotAfter
+		w.Unlock()
+		// notified only once for each certificate, as the server may not rotate its certificate in time
+		if !notAfter.IsZero() && !notAfter.Equal(notifiedNotAfter) && time.Until(notAfter) < w.expiryThreshold {
+			klog.Infof("certificate of tunnel server expires at %v, re-establish connections for the rotated certificate", notAfter)
+			notifiedNotAfter = notAfter
+			w.notify()
+		}
+	}
+}
+
+func leafOf(cert *tls.Certificate) []byte {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return nil
+	}
+	return cert.Certificate[0]
+}
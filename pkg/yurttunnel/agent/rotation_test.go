@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCertRotationWatcher(t *testing.T) {
+	var lock sync.Mutex
+	cert := &tls.Certificate{Certificate: [][]byte{[]byte("cert-1")}}
+	var notified int32
+	w := newCertRotationWatcher(&tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return cert, nil
+		},
+	}, func() {
+		atomic.AddInt32(&notified, 1)
+	})
+	w.checkInterval = 10 * time.Millisecond
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go w.run(stopCh)
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&notified); n != 0 {
+		t.Fatalf("expect no notification before rotation, but got %d", n)
+	}
+
+	lock.Lock()
+	cert = &tls.Certificate{Certificate: [][]byte{[]byte("cert-2")}}
+	lock.Unlock()
+	waitFor(t, "client certificate rotation notified", func() bool {
+		return atomic.LoadInt32(&notified) == 1
+	})
+
+	// the expiring certificate of server is notified only once
+	if err := w.verifyConnection(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{NotAfter: time.Now().Add(time.Minute)}},
+	}); err != nil {
+		t.Fatalf("expect connection not rejected, but got %v", err)
+	}
+	waitFor(t, "server certificate expiry notified", func() bool {
+		return atomic.LoadInt32(&notified) == 2
+	})
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&notified); n != 2 {
+		t.Fatalf("expect notified twice, but got %d", n)
+	}
+
+	// the certificate of server which is not going to expire is not notified
+	if err := w.verifyConnection(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{NotAfter: time.Now().Add(24 * time.Hour)}},
+	}); err != nil {
+		t.Fatalf("expect connection not rejected, but got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&notified); n != 2 {
+		t.Fatalf("expect notified twice, but got %d", n)
+	}
+}
+
+func TestTLSConfigFor(t *testing.T) {
+	var verified int32
+	base := &tls.Config{ServerName: "127.0.0.1"}
+	cfg := tlsConfigFor(base, "tunnel.example.com:10262", func(tls.ConnectionState) error {
+		atomic.AddInt32(&verified, 1)
+		return nil
+	})
+	if cfg.ServerName != "tunnel.example.com" || base.ServerName != "127.0.0.1" {
+		t.Errorf("expect server name tunnel.example.com without changing base config, but got %s and %s", cfg.ServerName, base.ServerName)
+	}
+	if err := cfg.VerifyConnection(tls.ConnectionState{}); err != nil || atomic.LoadInt32(&verified) != 1 {
+		t.Errorf("expect connection verified, but got %v", err)
+	}
+
+	if cfg := tlsConfigFor(base, ":10262", func(tls.ConnectionState) error { return nil }); cfg.ServerName != "127.0.0.1" {
+		t.Errorf("expect server name 127.0.0.1 for address without host, but got %s", cfg.ServerName)
+	}
+}
@@ -23,6 +23,8 @@ const (
 	YurttunnelServerMetaPort            = "10265"
 	YurtTunnelServerNodeName            = "tunnel-server"
 	YurttunnelAgentMetaPort             = "10266"
+	YurttunnelAgentUDPRelayPort         = "10267"
+	YurttunnelServerEgressSelectorPort  = "10269"
 	YurttunnelServerServiceNs           = "kube-system"
 	YurttunnelServerInternalServiceName = "x-tunnel-server-internal-svc"
 	YurttunnelServerServiceName         = "x-tunnel-server-svc"
@@ -52,6 +54,10 @@ const (
 	ProxyHostHeaderKey = "X-Tunnel-Proxy-Host"
 	ProxyDestHeaderKey = "X-Tunnel-Proxy-Dest"
 
+	// the proxy protocols of the egress selector of kube-apiserver
+	EgressSelectorModeHTTPConnect = "http-connect"
+	EgressSelectorModeGRPC        = "grpc"
+
 	// The timeout seconds of reading a complete request from the apiserver
 	YurttunnelANPInterceptorReadTimeoutSec = 10
 	// The period between two keep-alive probes
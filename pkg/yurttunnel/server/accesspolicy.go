@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
+)
+
+// accessPolicyFilter rejects the streams whose destinations are not allowed by the tunnel
+// access policies of node, so the cloud components can only reach the whitelisted
+// destinations on edge nodes. the streams are always tcp connections.
+type accessPolicyFilter struct {
+	enforcer   accesspolicy.Enforcer
+	nodeLister corelisters.NodeLister
+}
+
+// nodeOf returns the node of agent, the agent is identified by node name or node address.
+func (f *accessPolicyFilter) nodeOf(agent string) *corev1.Node {
+	if node, err := f.nodeLister.Get(agent); err == nil {
+		return node
+	}
+	if ip := net.ParseIP(agent); ip != nil {
+		nodes, err := f.nodeLister.List(labels.Everything())
+		if err != nil {
+			klog.Errorf("failed to list nodes, %v", err)
+		}
+		for _, node := range nodes {
+			for _, addr := range node.Status.Addresses {
+				if addr.Address == agent {
+					return node
+				}
+			}
+		}
+	}
+	// the labels of node are unknown, only the policies for all nodes are applied
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: agent}}
+}
+
+// WrapHandler wraps the proxier handler with the check of tunnel access policies
+func (f *accessPolicyFilter) WrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent := agentOf(r)
+		if err := f.enforcer.Check(f.nodeOf(agent), "tcp", r.Host); err != nil {
+			klog.Errorf("stream to %s through agent %s is rejected, %v", r.Host, agent, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+type fakeEnforcer struct {
+	allowed map[string]string
+}
+
+func (e *fakeEnforcer) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	wg.Done()
+}
+
+func (e *fakeEnforcer) Check(node *corev1.Node, protocol, addr string) error {
+	if e.allowed[addr] == node.Labels["pool"] {
+		return nil
+	}
+	return errors.New("not allowed")
+}
+
+func TestAccessPolicyFilter(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge-node-1", Labels: map[string]string{"pool": "hangzhou"}},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "172.16.0.1"}},
+		},
+	}
+	factory := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	factory.Core().V1().Nodes().Informer().GetStore().Add(node)
+	f := &accessPolicyFilter{
+		enforcer: &fakeEnforcer{allowed: map[string]string{
+			"172.16.0.1:10250": "hangzhou",
+			"172.16.0.9:10250": "",
+		}},
+		nodeLister: factory.Core().V1().Nodes().Lister(),
+	}
+	handler := f.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testcases := map[string]struct {
+		addr      string
+		proxyHost string
+		code      int
+	}{
+		"node is identified by name": {
+			addr: "172.16.0.1:10250", proxyHost: "edge-node-1", code: http.StatusOK,
+		},
+		"node is identified by address": {
+			addr: "172.16.0.1:10250", code: http.StatusOK,
+		},
+		"destination is not allowed": {
+			addr: "172.16.0.1:22", proxyHost: "edge-node-1", code: http.StatusForbidden,
+		},
+		"unknown node has no labels": {
+			addr: "172.16.0.9:10250", code: http.StatusOK,
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodConnect, "http://"+tc.addr, nil)
+			req.Host = tc.addr
+			if len(tc.proxyHost) != 0 {
+				req.Header.Set(constants.ProxyHostHeaderKey, tc.proxyHost)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tc.code {
+				t.Errorf("expect code %d, but got %d", tc.code, w.Code)
+			}
+		})
+	}
+}
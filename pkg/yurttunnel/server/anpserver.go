@@ -18,7 +18,6 @@ package server
 
 import (
 	"crypto/tls"
-	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -28,10 +27,14 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 	anpserver "sigs.k8s.io/apiserver-network-proxy/pkg/server"
 	anpagent "sigs.k8s.io/apiserver-network-proxy/proto/agent"
 
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
 	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
 	hw "github.com/openyurtio/openyurt/pkg/yurttunnel/handlerwrapper"
 	wh "github.com/openyurtio/openyurt/pkg/yurttunnel/handlerwrapper/wraphandler"
@@ -40,7 +43,7 @@ import (
 // anpTunnelServer implements the TunnelServer interface using the
 // apiserver-network-proxy package
 type anpTunnelServer struct {
-	egressSelectorEnabled    bool
+	egressSelector           *EgressSelectorConfig
 	interceptorServerUDSFile string
 	serverMasterAddr         string
 	serverMasterInsecureAddr string
@@ -50,28 +53,69 @@ type anpTunnelServer struct {
 	proxyClientTlsCfg        *tls.Config
 	wrappers                 hw.HandlerWrappers
 	proxyStrategy            string
+	maxStreamsPerAgent       int
+	streamWaitTimeout        time.Duration
+	streamBandwidthLimits    map[string]int
+	peerAddr                 string
+	kubeClient               clientset.Interface
+	accessEnforcer           accesspolicy.Enforcer
+	nodeLister               corelisters.NodeLister
 }
 
 var _ TunnelServer = &anpTunnelServer{}
 
 // Run runs the yurttunnel-server
 func (ats *anpTunnelServer) Run() error {
+	serverCount := ats.serverCount
+	var registry *agentRegistry
+	if len(ats.peerAddr) != 0 {
+		// every agent only connects to one of the tunnel servers, and the requests
+		// are forwarded to the tunnel server which holds the agent.
+		klog.Infof("agents are shared by tunnel servers, and %s is registered as the peer address", ats.peerAddr)
+		serverCount = 1
+		registry = newAgentRegistry(ats.kubeClient, ats.peerAddr)
+		registry.run(wait.NeverStop)
+	}
+
 	proxyServer := anpserver.NewProxyServer(uuid.New().String(),
 		[]anpserver.ProxyStrategy{anpserver.ProxyStrategy(ats.proxyStrategy)},
-		ats.serverCount,
+		serverCount,
 		&anpserver.AgentTokenAuthenticationOptions{})
 	// 1. start the proxier
+	// the streams to every agent are limited and metered by streamLimiter
+	tunnelHandler := newStreamLimiter(ats.maxStreamsPerAgent, ats.streamWaitTimeout).WrapHandler(&anpserver.Tunnel{Server: proxyServer})
+	// the destinations are checked by the tunnel server which holds the agent, including
+	// the streams forwarded by peers.
+	if ats.accessEnforcer != nil {
+		tunnelHandler = (&accessPolicyFilter{enforcer: ats.accessEnforcer, nodeLister: ats.nodeLister}).WrapHandler(tunnelHandler)
+	}
+	proxierHandler := tunnelHandler
+	if registry != nil {
+		proxierHandler = &peerForwarder{registry: registry, next: tunnelHandler, tlsCfg: ats.proxyClientTlsCfg}
+		runPeerServer(tunnelHandler, ats.peerAddr, ats.tlsCfg)
+	}
 	proxierErr := runProxier(
-		&anpserver.Tunnel{Server: proxyServer},
-		ats.egressSelectorEnabled,
+		proxierHandler,
 		ats.interceptorServerUDSFile,
 		ats.tlsCfg)
 	if proxierErr != nil {
 		return fmt.Errorf("fail to run the proxier: %w", proxierErr)
 	}
 
+	// 1.1. serve the egress selector of kube-apiserver with the konnectivity proxy protocol
+	if ats.egressSelector != nil {
+		if err := runEgressSelectorServer(ats.egressSelector, proxierHandler, ats.interceptorServerUDSFile, ats.tlsCfg); err != nil {
+			return fmt.Errorf("fail to run the egress selector server: %w", err)
+		}
+	}
+
+	// the requests from the cloud are limited by the bandwidth limits of stream classes
+	var interceptor http.Handler = NewRequestInterceptor(ats.interceptorServerUDSFile, ats.proxyClientTlsCfg)
+	if len(ats.streamBandwidthLimits) != 0 {
+		interceptor = newClassBandwidthLimiter(ats.streamBandwidthLimits).WrapHandler(interceptor)
+	}
 	wrappedHandler, err := wh.WrapHandler(
-		NewRequestInterceptor(ats.interceptorServerUDSFile, ats.proxyClientTlsCfg),
+		interceptor,
 		ats.wrappers,
 	)
 	if err != nil {
@@ -81,7 +125,6 @@ func (ats *anpTunnelServer) Run() error {
 	// 2. start the master server
 	masterServerErr := runMasterServer(
 		wrappedHandler,
-		ats.egressSelectorEnabled,
 		ats.serverMasterAddr,
 		ats.serverMasterInsecureAddr,
 		ats.tlsCfg)
@@ -90,7 +133,11 @@ func (ats *anpTunnelServer) Run() error {
 	}
 
 	// 3. start the agent server
-	agentServerErr := runAgentServer(ats.tlsCfg, ats.serverAgentAddr, proxyServer)
+	var agentServerOptions []grpc.ServerOption
+	if registry != nil {
+		agentServerOptions = append(agentServerOptions, grpc.StreamInterceptor(registry.streamInterceptor))
+	}
+	agentServerErr := runAgentServer(ats.tlsCfg, ats.serverAgentAddr, proxyServer, agentServerOptions...)
 	if agentServerErr != nil {
 		return fmt.Errorf("fail to run agent server: %w", agentServerErr)
 	}
@@ -101,14 +148,9 @@ func (ats *anpTunnelServer) Run() error {
 // runProxier starts a proxy server that redirects requests received from
 // apiserver to corresponding yurttunel-agent
 func runProxier(handler http.Handler,
-	egressSelectorEnabled bool,
 	udsSockFile string,
 	tlsConfig *tls.Config) error {
 	klog.Info("start handling request from interceptor")
-	if egressSelectorEnabled {
-		// TODO will support egress selector for apiserver version > 1.18
-		return errors.New("DOESN'T SUPPROT EGRESS SELECTOR YET")
-	}
 	// request will be sent from request interceptor on the same host,
 	// so we use UDS protocol to avoide sending request through kernel
 	// network stack.
@@ -132,13 +174,9 @@ func runProxier(handler http.Handler,
 
 // runMasterServer runs an https server to handle requests from apiserver
 func runMasterServer(handler http.Handler,
-	egressSelectorEnabled bool,
 	masterServerAddr,
 	masterServerInsecureAddr string,
 	tlsCfg *tls.Config) error {
-	if egressSelectorEnabled {
-		return errors.New("DOESN'T SUPPORT EGRESS SELECTOR YET")
-	}
 	go func() {
 		klog.Infof("start handling https request from master at %s", masterServerAddr)
 		server := http.Server{
@@ -175,7 +213,8 @@ func runMasterServer(handler http.Handler,
 // to corresponding yurttunel-agent
 func runAgentServer(tlsCfg *tls.Config,
 	agentServerAddr string,
-	proxyServer *anpserver.ProxyServer) error {
+	proxyServer *anpserver.ProxyServer,
+	opts ...grpc.ServerOption) error {
 	serverOption := grpc.Creds(credentials.NewTLS(tlsCfg))
 
 	ka := keepalive.ServerParameters{
@@ -187,8 +226,8 @@ func runAgentServer(tlsCfg *tls.Config,
 		Timeout: constants.YurttunnelANPGrpcKeepAliveTimeoutSec * time.Second,
 	}
 
-	grpcServer := grpc.NewServer(serverOption,
-		grpc.KeepaliveParams(ka))
+	grpcServer := grpc.NewServer(append([]grpc.ServerOption{serverOption,
+		grpc.KeepaliveParams(ka)}, opts...)...)
 
 	anpagent.RegisterAgentServiceServer(grpcServer, proxyServer)
 	listener, err := net.Listen("tcp", agentServerAddr)
@@ -0,0 +1,202 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+// the classes of streams to kubelet, which are limited by the bandwidth limits of class
+const (
+	StreamClassExec        = "exec"
+	StreamClassLogs        = "logs"
+	StreamClassPortForward = "port-forward"
+	StreamClassMetrics     = "metrics"
+)
+
+// StreamClasses are the classes of streams which can be limited
+var StreamClasses = []string{StreamClassExec, StreamClassLogs, StreamClassPortForward, StreamClassMetrics}
+
+// streamClassOf returns the class of request by the path of kubelet api, empty if the
+// request is not in any class.
+func streamClassOf(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/exec/"), strings.HasPrefix(path, "/attach/"), strings.HasPrefix(path, "/run/"):
+		return StreamClassExec
+	case strings.HasPrefix(path, "/containerLogs/"), strings.HasPrefix(path, "/logs/"):
+		return StreamClassLogs
+	case strings.HasPrefix(path, "/portForward/"):
+		return StreamClassPortForward
+	case path == "/metrics", strings.HasPrefix(path, "/metrics/"), strings.HasPrefix(path, "/stats/"):
+		return StreamClassMetrics
+	}
+	return ""
+}
+
+// classBandwidthLimiter limits the bandwidth of the requests intercepted from the cloud by the
+// class of stream. Each stream is shaped by its own limiters, and the limit of a class to an agent
+// is shared equally by the active streams of the class in each direction, so a large stream only
+// waits for its own share and doesn't hold back the other streams of the class.
+type classBandwidthLimiter struct {
+	limits map[string]int
+	sync.Mutex
+	streams map[string]map[*streamLimiters]struct{}
+}
+
+// streamLimiters limit the data sent(tx) to and received(rx) from the agent of a stream
+type streamLimiters struct {
+	tx *rate.Limiter
+	rx *rate.Limiter
+}
+
+func newClassBandwidthLimiter(limits map[string]int) *classBandwidthLimiter {
+	return &classBandwidthLimiter{
+		limits:  limits,
+		streams: make(map[string]map[*streamLimiters]struct{}),
+	}
+}
+
+// acquire adds a stream of class to agent, and shares the limit of class among the streams.
+func (bl *classBandwidthLimiter) acquire(agent, class string) *streamLimiters {
+	key := agent + "/" + class
+	stream := &streamLimiters{
+		tx: util.NewBandwidthLimiter(bl.limits[class]),
+		rx: util.NewBandwidthLimiter(bl.limits[class]),
+	}
+	bl.Lock()
+	defer bl.Unlock()
+	if bl.streams[key] == nil {
+		bl.streams[key] = make(map[*streamLimiters]struct{})
+	}
+	bl.streams[key][stream] = struct{}{}
+	bl.share(key, class)
+	return stream
+}
+
+// release removes the stream when it's finished, and its share is given back to the other streams.
+func (bl *classBandwidthLimiter) release(agent, class string, stream *streamLimiters) {
+	key := agent + "/" + class
+	bl.Lock()
+	defer bl.Unlock()
+	delete(bl.streams[key], stream)
+	if len(bl.streams[key]) == 0 {
+		delete(bl.streams, key)
+		return
+	}
+	bl.share(key, class)
+}
+
+// share divides the limit of class equally among the streams of key, the caller should hold the lock.
+func (bl *classBandwidthLimiter) share(key, class string) {
+	streams := bl.streams[key]
+	limit := rate.Limit(bl.limits[class] / len(streams))
+	if limit < 1 {
+		limit = 1
+	}
+	for stream := range streams {
+		stream.tx.SetLimit(limit)
+		stream.rx.SetLimit(limit)
+	}
+}
+
+// WrapHandler wraps the request interceptor with the bandwidth limits of stream classes
+func (bl *classBandwidthLimiter) WrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := streamClassOf(r)
+		if bl.limits[class] <= 0 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// the upgraded connection is served until the stream is finished, so the stream is released
+		// after the handler returns.
+		agent := agentOf(r)
+		stream := bl.acquire(agent, class)
+		defer bl.release(agent, class, stream)
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{ReadCloser: r.Body, limiter: stream.tx}
+		}
+		handler.ServeHTTP(&limitedResponseWriter{ResponseWriter: w, tx: stream.tx, rx: stream.rx}, r)
+	})
+}
+
+// limitedBody limits the body of request sent(tx) to the agent
+type limitedBody struct {
+	io.ReadCloser
+	limiter *rate.Limiter
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	return util.LimitedRead(b.limiter, b.ReadCloser.Read, p)
+}
+
+// limitedResponseWriter limits the response received(rx) from the agent, and the hijacked
+// connection of upgrade requests in both directions.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	tx *rate.Limiter
+	rx *rate.Limiter
+}
+
+func (lw *limitedResponseWriter) Write(b []byte) (int, error) {
+	return util.LimitedWrite(lw.rx, lw.ResponseWriter.Write, b)
+}
+
+func (lw *limitedResponseWriter) Flush() {
+	if flusher, ok := lw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (lw *limitedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := lw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// data buffered by the http server should be read first
+	limitedConn := &util.BandwidthLimitedConn{
+		Conn:         &bufferedConn{Conn: conn, reader: rw.Reader},
+		ReadLimiter:  lw.tx,
+		WriteLimiter: lw.rx,
+	}
+	return limitedConn, bufio.NewReadWriter(bufio.NewReader(limitedConn), bufio.NewWriter(limitedConn)), nil
+}
+
+// bufferedConn reads the data buffered by the http server before the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamClassOf(t *testing.T) {
+	testcases := map[string]string{
+		"/exec/default/foo/app?command=sh":      StreamClassExec,
+		"/attach/default/foo/app":               StreamClassExec,
+		"/containerLogs/default/foo/app?follow": StreamClassLogs,
+		"/portForward/default/foo":              StreamClassPortForward,
+		"/metrics":                              StreamClassMetrics,
+		"/metrics/cadvisor":                     StreamClassMetrics,
+		"/stats/summary":                        StreamClassMetrics,
+		"/pods":                                 "",
+		"/metricsfoo":                           "",
+	}
+
+	for path, class := range testcases {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://192.168.1.10:10250"+path, nil)
+			if got := streamClassOf(req); got != class {
+				t.Errorf("expect class %q, but got %q", class, got)
+			}
+		})
+	}
+}
+
+func TestClassBandwidthLimiterShare(t *testing.T) {
+	bl := newClassBandwidthLimiter(map[string]int{StreamClassLogs: 64 * 1024})
+	first := bl.acquire("node-1", StreamClassLogs)
+	if first.rx.Limit() != 64*1024 {
+		t.Errorf("expect the only stream gets the whole limit, but got %v", first.rx.Limit())
+	}
+	second := bl.acquire("node-1", StreamClassLogs)
+	other := bl.acquire("node-2", StreamClassLogs)
+	for _, s := range []*streamLimiters{first, second} {
+		if s.tx.Limit() != 32*1024 || s.rx.Limit() != 32*1024 {
+			t.Errorf("expect streams to an agent share the limit, but got %v, %v", s.tx.Limit(), s.rx.Limit())
+		}
+	}
+	if other.rx.Limit() != 64*1024 {
+		t.Errorf("expect streams to other agents are not affected, but got %v", other.rx.Limit())
+	}
+
+	bl.release("node-1", StreamClassLogs, first)
+	if second.rx.Limit() != 64*1024 {
+		t.Errorf("expect the share is given back after a stream is released, but got %v", second.rx.Limit())
+	}
+	bl.release("node-1", StreamClassLogs, second)
+	bl.release("node-2", StreamClassLogs, other)
+	if len(bl.streams) != 0 {
+		t.Errorf("expect no stream left, but got %d", len(bl.streams))
+	}
+}
+
+func TestClassBandwidthLimiter(t *testing.T) {
+	data := make([]byte, 24*1024)
+	handler := newClassBandwidthLimiter(map[string]int{StreamClassLogs: 16 * 1024}).WrapHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(data)
+		}))
+
+	testcases := map[string]struct {
+		path    string
+		limited bool
+	}{
+		"logs are limited": {
+			path:    "/containerLogs/default/foo/app",
+			limited: true,
+		},
+		"exec is not limited": {
+			path:    "/exec/default/foo/app",
+			limited: false,
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://192.168.1.10:10250"+tc.path, nil)
+			rec := httptest.NewRecorder()
+			// the first burst is written immediately, and the rest waits for about half a second
+			start := time.Now()
+			handler.ServeHTTP(rec, req)
+			elapsed := time.Since(start)
+			if rec.Body.Len() != len(data) {
+				t.Errorf("expect %d bytes, but got %d", len(data), rec.Body.Len())
+			}
+			if limited := elapsed >= 400*time.Millisecond; limited != tc.limited {
+				t.Errorf("expect limited %v, but response is written in %v", tc.limited, elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+const (
+	maxIdleTunnelConnsPerHost = 4
+	idleTunnelConnTimeout     = 90 * time.Second
+)
+
+type idleTunnelConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+// tunnelConnPool keeps the tunnel connections whose responses are completed, so the following
+// requests to the same host can be sent without setting up the tunnel and tls handshake again.
+// every tunnel connection is a multiplexed stream of the grpc connection between tunnel server and agent.
+type tunnelConnPool struct {
+	sync.Mutex
+	maxIdlePerHost int
+	idleTimeout    time.Duration
+	idle           map[string][]idleTunnelConn
+}
+
+func newTunnelConnPool(maxIdlePerHost int, idleTimeout time.Duration) *tunnelConnPool {
+	return &tunnelConnPool{
+		maxIdlePerHost: maxIdlePerHost,
+		idleTimeout:    idleTimeout,
+		idle:           make(map[string][]idleTunnelConn),
+	}
+}
+
+// poolKey returns the key of tunnel connections which can be shared by the request
+func poolKey(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/" + r.Header.Get(constants.ProxyHostHeaderKey)
+}
+
+// get returns the most recently used idle connection of key, nil is returned if no connection is idle.
+func (p *tunnelConnPool) get(key string) net.Conn {
+	p.Lock()
+	defer p.Unlock()
+	conns := p.idle[key]
+	for len(conns) > 0 {
+		c := conns[len(conns)-1]
+		conns = conns[:len(conns)-1]
+		if time.Since(c.since) < p.idleTimeout {
+			p.setIdle(key, conns)
+			return c.conn
+		}
+		c.conn.Close()
+	}
+	p.setIdle(key, conns)
+	return nil
+}
+
+// put keeps the connection as idle, the expired connections are closed at the same time.
+func (p *tunnelConnPool) put(key string, conn net.Conn) {
+	p.Lock()
+	defer p.Unlock()
+	now := time.Now()
+	for k, conns := range p.idle {
+		active := conns[:0]
+		for _, c := range conns {
+			if now.Sub(c.since) < p.idleTimeout {
+				active = append(active, c)
+			} else {
+				c.conn.Close()
+			}
+		}
+		p.setIdle(k, active)
+	}
+
+	if len(p.idle[key]) >= p.maxIdlePerHost {
+		conn.Close()
+		return
+	}
+	p.idle[key] = append(p.idle[key], idleTunnelConn{conn: conn, since: now})
+}
+
+func (p *tunnelConnPool) setIdle(key string, conns []idleTunnelConn) {
+	if len(conns) == 0 {
+		delete(p.idle, key)
+		return
+	}
+	p.idle[key] = conns
+}
+
+// isReusableRequest checks the request can be sent through an idle tunnel connection, only the
+// requests without body are supported, so they can be sent again when the idle connection is broken.
+func isReusableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.ContentLength != 0 || len(r.TransferEncoding) != 0 || r.Close {
+		return false
+	}
+	return !httpstream.IsUpgradeRequest(r)
+}
+
+// serveReusableRequest sends the request through an idle tunnel connection or a new one, and
+// keeps the connection as idle if the response is completed.
+func (ri *RequestInterceptor) serveReusableRequest(w http.ResponseWriter, r *http.Request) {
+	key := poolKey(r)
+	tunnelConn := ri.pool.get(key)
+	reused := tunnelConn != nil
+	for {
+		if tunnelConn == nil {
+			var err error
+			tunnelConn, err = ri.contextDialer(r.Host, r.Header, r.TLS != nil)
+			if err != nil {
+				klogAndHTTPError(w, http.StatusServiceUnavailable,
+					"fail to setup the tunnel: %s", err)
+				return
+			}
+		}
+
+		br := newBufioReader(tunnelConn)
+		tunnelHTTPResp, err := roundTrip(tunnelConn, br, r)
+		if err != nil {
+			putBufioReader(br)
+			tunnelConn.Close()
+			tunnelConn = nil
+			// the idle connection may be closed by the peer, retry with a new connection
+			if reused {
+				klog.V(4).Infof("interceptor: idle tunnel connection for %s is broken, %v", key, err)
+				reused = false
+				continue
+			}
+			klogAndHTTPError(w, http.StatusServiceUnavailable, "fail to read response from the tunnel: %v", err)
+			return
+		}
+
+		completed := copyResponse(tunnelConn, w, r, tunnelHTTPResp)
+		putBufioReader(br)
+		if completed && !tunnelHTTPResp.Close {
+			ri.pool.put(key, tunnelConn)
+		} else {
+			tunnelConn.Close()
+		}
+		return
+	}
+}
+
+// roundTrip writes the request to the tunnel connection and reads the response.
+func roundTrip(tunnelConn net.Conn, br *bufio.Reader, r *http.Request) (*http.Response, error) {
+	if err := r.Write(tunnelConn); err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(br, r)
+}
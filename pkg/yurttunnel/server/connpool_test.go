@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsReusableRequest(t *testing.T) {
+	testcases := map[string]struct {
+		req    func() *http.Request
+		expect bool
+	}{
+		"get request": {
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "https://10.0.0.1:10250/metrics", nil)
+			},
+			expect: true,
+		},
+		"post request": {
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "https://10.0.0.1:10250/run", strings.NewReader("ls"))
+			},
+		},
+		"close request": {
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "https://10.0.0.1:10250/metrics", nil)
+				req.Close = true
+				return req
+			},
+		},
+		"upgrade request": {
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "https://10.0.0.1:10250/exec/default/nginx/nginx", nil)
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "SPDY/3.1")
+				return req
+			},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if got := isReusableRequest(tc.req()); got != tc.expect {
+				t.Errorf("expect %v, but got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestTunnelConnPool(t *testing.T) {
+	pool := newTunnelConnPool(1, 50*time.Millisecond)
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	if conn := pool.get("key"); conn != nil {
+		t.Fatalf("expect no idle connection")
+	}
+	pool.put("key", c1)
+	if conn := pool.get("key"); conn != c1 {
+		t.Fatalf("expect the idle connection is returned")
+	}
+	if conn := pool.get("key"); conn != nil {
+		t.Fatalf("expect idle connection is taken only once")
+	}
+
+	// connections exceed the limit are closed
+	pool.put("key", c1)
+	c3, c4 := net.Pipe()
+	defer c4.Close()
+	pool.put("key", c3)
+	if _, err := c3.Write([]byte("x")); err == nil {
+		t.Errorf("expect connection exceeds the limit is closed")
+	}
+
+	// expired connections are closed
+	time.Sleep(60 * time.Millisecond)
+	if conn := pool.get("key"); conn != nil {
+		t.Errorf("expect expired connection is not returned")
+	}
+}
+
+func TestServeReusableRequest(t *testing.T) {
+	var accepted int32
+	kubelet := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "metrics of "+r.URL.Path)
+	}))
+	kubelet.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&accepted, 1)
+		}
+	}
+	kubelet.Start()
+	defer kubelet.Close()
+
+	ri := &RequestInterceptor{
+		contextDialer: func(addr string, header http.Header, isTLS bool) (net.Conn, error) {
+			return net.Dial("tcp", kubelet.Listener.Addr().String())
+		},
+		pool: newTunnelConnPool(maxIdleTunnelConnsPerHost, idleTunnelConnTimeout),
+	}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://edge-node-1:10255/metrics", nil)
+		w := httptest.NewRecorder()
+		ri.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "metrics of /metrics" {
+			t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if n := atomic.LoadInt32(&accepted); n != 1 {
+		t.Errorf("expect requests are sent through one tunnel connection, but got %d connections", n)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/apiserver-network-proxy/konnectivity-client/proto/client"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+// EgressSelectorConfig is the configuration for serving the egress selector of kube-apiserver
+// with the konnectivity proxy protocol.
+type EgressSelectorConfig struct {
+	// Mode is the proxy protocol, http-connect or grpc.
+	Mode string
+	// ListenAddr is the address for serving kube-apiserver with mTLS, it's ignored if UDSFile is set.
+	// kube-apiserver must present the tunnel proxy client certificate.
+	ListenAddr string
+	// UDSFile is the unix socket for serving kube-apiserver on the same host.
+	UDSFile string
+}
+
+// runEgressSelectorServer serves the egress selector of kube-apiserver, the streams of both
+// protocols are proxied through the proxier like the requests intercepted from cloud clients,
+// so they are limited, checked by access policies and forwarded to peers as well.
+func runEgressSelectorServer(cfg *EgressSelectorConfig, proxierHandler http.Handler, udsSockFile string, tlsCfg *tls.Config) error {
+	var listener net.Listener
+	var err error
+	var grpcOpts []grpc.ServerOption
+	if len(cfg.UDSFile) != 0 {
+		if err := os.Remove(cfg.UDSFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("fail to remove the stale socket %s: %w", cfg.UDSFile, err)
+		}
+		listener, err = net.Listen("unix", cfg.UDSFile)
+	} else {
+		// kube-apiserver is authenticated by the tunnel proxy client certificate
+		mtlsCfg := tlsCfg.Clone()
+		mtlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		mtlsCfg.VerifyPeerCertificate = verifyEgressClient
+		if cfg.Mode == constants.EgressSelectorModeGRPC {
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(mtlsCfg)))
+			listener, err = net.Listen("tcp", cfg.ListenAddr)
+		} else {
+			listener, err = tls.Listen("tcp", cfg.ListenAddr, mtlsCfg)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("fail to listen for egress selector: %w", err)
+	}
+
+	klog.Infof("start handling %s streams from egress selector at %s", cfg.Mode, listener.Addr())
+	if cfg.Mode == constants.EgressSelectorModeGRPC {
+		grpcServer := grpc.NewServer(grpcOpts...)
+		client.RegisterProxyServiceServer(grpcServer, &grpcFrontend{
+			dial: func(proxyHost, addr string) (net.Conn, error) {
+				return util.DialTunnel(udsSockFile, proxyHost, addr)
+			},
+		})
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				klog.Errorf("failed to serve grpc streams from egress selector: %v", err)
+			}
+		}()
+		return nil
+	}
+
+	go func() {
+		server := &http.Server{
+			Handler:           proxierHandler,
+			ReadHeaderTimeout: constants.YurttunnelANPProxierReadTimeoutSec * time.Second,
+			TLSNextProto:      make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+		}
+		if err := server.Serve(listener); err != nil {
+			klog.Errorf("failed to serve http-connect streams from egress selector: %v", err)
+		}
+	}()
+	return nil
+}
+
+// verifyEgressClient only accepts the tunnel proxy client certificate, which kube-apiserver is configured
+// with in the egress selector configuration. The other certificates signed by the cluster CA, e.g. the
+// kubelet client certificates held by every node, are rejected.
+func verifyEgressClient(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return errors.New("client certificate is not verified")
+	}
+	subject := verifiedChains[0][0].Subject
+	if subject.CommonName != constants.YurtTunnelProxyClientCSRCN ||
+		len(subject.Organization) != 1 || subject.Organization[0] != constants.YurtTunnelCSROrg {
+		return fmt.Errorf("client %s is not allowed to open egress streams", subject.String())
+	}
+	return nil
+}
+
+// grpcFrontend serves the streams of konnectivity grpc protocol, every dial request is
+// proxied through the tunnel of the node which is the host of destination.
+type grpcFrontend struct {
+	dial func(proxyHost, addr string) (net.Conn, error)
+}
+
+func (f *grpcFrontend) Proxy(stream client.ProxyService_ProxyServer) error {
+	ps := &proxyStream{stream: stream, dial: f.dial, conns: make(map[int64]net.Conn)}
+	defer ps.closeAll()
+	for {
+		pkt, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch pkt.Type {
+		case client.PacketType_DIAL_REQ:
+			go ps.handleDial(pkt.GetDialRequest())
+		case client.PacketType_DATA:
+			ps.handleData(pkt.GetData())
+		case client.PacketType_CLOSE_REQ:
+			ps.closeConn(pkt.GetCloseRequest().ConnectID, "")
+		default:
+			klog.V(2).Infof("ignore packet of type %s from egress selector", pkt.Type)
+		}
+	}
+}
+
+// proxyStream holds the connections dialed by a grpc stream, the connect IDs are scoped in
+// the stream.
+type proxyStream struct {
+	stream client.ProxyService_ProxyServer
+	dial   func(proxyHost, addr string) (net.Conn, error)
+	sendMu sync.Mutex
+	sync.Mutex
+	nextID int64
+	conns  map[int64]net.Conn
+	closed bool
+}
+
+func (ps *proxyStream) send(pkt *client.Packet) {
+	ps.sendMu.Lock()
+	defer ps.sendMu.Unlock()
+	if err := ps.stream.Send(pkt); err != nil {
+		klog.Errorf("failed to send %s packet to egress selector, %v", pkt.Type, err)
+	}
+}
+
+func (ps *proxyStream) handleDial(req *client.DialRequest) {
+	dialRsp := func(connID int64, errMsg string) *client.Packet {
+		return &client.Packet{
+			Type: client.PacketType_DIAL_RSP,
+			Payload: &client.Packet_DialResponse{DialResponse: &client.DialResponse{
+				Random:    req.Random,
+				ConnectID: connID,
+				Error:     errMsg,
+			}},
+		}
+	}
+	if req.Protocol != "tcp" {
+		ps.send(dialRsp(0, fmt.Sprintf("protocol %s is not supported", req.Protocol)))
+		return
+	}
+	host, _, err := net.SplitHostPort(req.Address)
+	if err != nil {
+		ps.send(dialRsp(0, fmt.Sprintf("address %s is invalid, %v", req.Address, err)))
+		return
+	}
+	conn, err := ps.dial(host, req.Address)
+	if err != nil {
+		klog.Errorf("failed to dial %s for egress selector, %v", req.Address, err)
+		ps.send(dialRsp(0, err.Error()))
+		return
+	}
+
+	ps.Lock()
+	if ps.closed {
+		ps.Unlock()
+		conn.Close()
+		return
+	}
+	ps.nextID++
+	connID := ps.nextID
+	ps.conns[connID] = conn
+	ps.Unlock()
+	klog.V(4).Infof("dialed %s for egress selector, connectID %d", req.Address, connID)
+	ps.send(dialRsp(connID, ""))
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			ps.send(&client.Packet{
+				Type:    client.PacketType_DATA,
+				Payload: &client.Packet_Data{Data: &client.Data{ConnectID: connID, Data: append([]byte(nil), buf[:n]...)}},
+			})
+		}
+		if err != nil {
+			errMsg := ""
+			if err != io.EOF {
+				errMsg = err.Error()
+			}
+			ps.closeConn(connID, errMsg)
+			return
+		}
+	}
+}
+
+func (ps *proxyStream) handleData(data *client.Data) {
+	ps.Lock()
+	conn := ps.conns[data.ConnectID]
+	ps.Unlock()
+	if conn == nil {
+		klog.V(2).Infof("connectID %d of egress selector is not found", data.ConnectID)
+		return
+	}
+	if _, err := conn.Write(data.Data); err != nil {
+		ps.closeConn(data.ConnectID, err.Error())
+	}
+}
+
+// closeConn closes the connection and responds to egress selector, only the first close of
+// the connection is responded.
+func (ps *proxyStream) closeConn(connID int64, errMsg string) {
+	ps.Lock()
+	conn, ok := ps.conns[connID]
+	delete(ps.conns, connID)
+	ps.Unlock()
+	if !ok {
+		return
+	}
+	conn.Close()
+	ps.send(&client.Packet{
+		Type:    client.PacketType_CLOSE_RSP,
+		Payload: &client.Packet_CloseResponse{CloseResponse: &client.CloseResponse{ConnectID: connID, Error: errMsg}},
+	})
+}
+
+func (ps *proxyStream) closeAll() {
+	ps.Lock()
+	defer ps.Unlock()
+	ps.closed = true
+	for connID, conn := range ps.conns {
+		conn.Close()
+		delete(ps.conns, connID)
+	}
+}
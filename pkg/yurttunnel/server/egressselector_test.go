@@ -0,0 +1,224 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"sigs.k8s.io/apiserver-network-proxy/konnectivity-client/proto/client"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+func TestGRPCFrontend(t *testing.T) {
+	var proxyHosts []string
+	frontend := &grpcFrontend{
+		dial: func(proxyHost, addr string) (net.Conn, error) {
+			proxyHosts = append(proxyHosts, proxyHost)
+			if addr != "10.0.0.1:10250" {
+				return nil, errors.New("no agent")
+			}
+			// the destination echoes the data in upper case
+			conn, dest := net.Pipe()
+			go func() {
+				defer dest.Close()
+				buf := make([]byte, 1024)
+				n, err := dest.Read(buf)
+				if err != nil {
+					return
+				}
+				dest.Write([]byte(strings.ToUpper(string(buf[:n]))))
+			}()
+			return conn, nil
+		},
+	}
+	grpcServer := grpc.NewServer()
+	client.RegisterProxyServiceServer(grpcServer, frontend)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen, %v", err)
+	}
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial frontend, %v", err)
+	}
+	defer conn.Close()
+	stream, err := client.NewProxyServiceClient(conn).Proxy(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create proxy stream, %v", err)
+	}
+	dialReq := func(addr string, random int64) *client.Packet {
+		return &client.Packet{
+			Type:    client.PacketType_DIAL_REQ,
+			Payload: &client.Packet_DialRequest{DialRequest: &client.DialRequest{Protocol: "tcp", Address: addr, Random: random}},
+		}
+	}
+	recv := func(expect client.PacketType) *client.Packet {
+		pkt, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("failed to receive %s, %v", expect, err)
+		}
+		if pkt.Type != expect {
+			t.Fatalf("expect packet %s, but got %s", expect, pkt.Type)
+		}
+		return pkt
+	}
+
+	// the dial failure is responded with the random of request
+	stream.Send(dialReq("10.0.0.2:10250", 1))
+	if rsp := recv(client.PacketType_DIAL_RSP).GetDialResponse(); rsp.Random != 1 || rsp.Error == "" {
+		t.Errorf("expect dial error for random 1, but got %v", rsp)
+	}
+
+	stream.Send(dialReq("10.0.0.1:10250", 2))
+	rsp := recv(client.PacketType_DIAL_RSP).GetDialResponse()
+	if rsp.Random != 2 || rsp.Error != "" || rsp.ConnectID == 0 {
+		t.Fatalf("expect connectID for random 2, but got %v", rsp)
+	}
+	stream.Send(&client.Packet{
+		Type:    client.PacketType_DATA,
+		Payload: &client.Packet_Data{Data: &client.Data{ConnectID: rsp.ConnectID, Data: []byte("hello")}},
+	})
+	if data := recv(client.PacketType_DATA).GetData(); data.ConnectID != rsp.ConnectID || string(data.Data) != "HELLO" {
+		t.Errorf("expect HELLO for connectID %d, but got %v", rsp.ConnectID, data)
+	}
+	// the connection is closed by the destination
+	if closeRsp := recv(client.PacketType_CLOSE_RSP).GetCloseResponse(); closeRsp.ConnectID != rsp.ConnectID {
+		t.Errorf("expect close response for connectID %d, but got %v", rsp.ConnectID, closeRsp)
+	}
+
+	if strings.Join(proxyHosts, ",") != "10.0.0.2,10.0.0.1" {
+		t.Errorf("expect the hosts of destinations are the proxy hosts, but got %v", proxyHosts)
+	}
+}
+
+func TestEgressSelectorClientIdentity(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cluster-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("failed to create ca, %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	// newCert signs a certificate by the cluster ca
+	newCert := func(serial int64, cn string, orgs []string, usage x509.ExtKeyUsage) tls.Certificate {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn, Organization: orgs},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, key.Public(), caKey)
+		if err != nil {
+			t.Fatalf("failed to create cert, %v", err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen, %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{newCert(2, "tunnel-server", nil, x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    pool,
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	cfg := &EgressSelectorConfig{Mode: constants.EgressSelectorModeHTTPConnect, ListenAddr: addr}
+	if err := runEgressSelectorServer(cfg, handler, "", tlsCfg); err != nil {
+		t.Fatalf("failed to run egress selector server, %v", err)
+	}
+
+	testcases := map[string]struct {
+		cert    tls.Certificate
+		allowed bool
+	}{
+		"tunnel proxy client is allowed": {
+			cert:    newCert(3, constants.YurtTunnelProxyClientCSRCN, []string{constants.YurtTunnelCSROrg}, x509.ExtKeyUsageClientAuth),
+			allowed: true,
+		},
+		"kubelet client of node is rejected": {
+			cert: newCert(4, "system:node:edge-node-1", []string{"system:nodes"}, x509.ExtKeyUsageClientAuth),
+		},
+		"proxy client cn with other organization is rejected": {
+			cert: newCert(5, constants.YurtTunnelProxyClientCSRCN, []string{"system:masters"}, x509.ExtKeyUsageClientAuth),
+		},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      pool,
+				Certificates: []tls.Certificate{tc.cert},
+			}}}
+			var resp *http.Response
+			var err error
+			// the server is started asynchronously
+			for i := 0; i < 50; i++ {
+				resp, err = client.Get("https://" + addr)
+				if err == nil || !strings.Contains(err.Error(), "connection refused") {
+					break
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			if tc.allowed {
+				if err != nil {
+					t.Fatalf("expect client is allowed, but got %v", err)
+				}
+				resp.Body.Close()
+			} else if err == nil {
+				resp.Body.Close()
+				t.Errorf("expect client is rejected, but got %s", resp.Status)
+			}
+		})
+	}
+}
@@ -65,6 +65,7 @@ func putBufioReader(br *bufio.Reader) {
 // through the tunnel and sends responses back to the master
 type RequestInterceptor struct {
 	contextDialer func(addr string, header http.Header, isTLS bool) (net.Conn, error)
+	pool          *tunnelConnPool
 }
 
 // NewRequestInterceptor creates a interceptor object that intercept request from kube-apiserver
@@ -118,6 +119,7 @@ func NewRequestInterceptor(udsSockFile string, cfg *tls.Config) *RequestIntercep
 
 	return &RequestInterceptor{
 		contextDialer: contextDialer,
+		pool:          newTunnelConnPool(maxIdleTunnelConnsPerHost, idleTunnelConnTimeout),
 	}
 }
 
@@ -140,6 +142,13 @@ func klogAndHTTPError(w http.ResponseWriter, errCode int, format string, i ...in
 // ServeHTTP will proxy the request to the tunnel and return response from tunnel back
 // to the client
 func (ri *RequestInterceptor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 0. requests without body, like metrics scraping and kubectl logs, are sent
+	// through the idle tunnel connections to avoid setting up tunnel for every request
+	if ri.pool != nil && isReusableRequest(r) {
+		ri.serveReusableRequest(w, r)
+		return
+	}
+
 	// 1. setup the tunnel
 	tunnelConn, err := ri.contextDialer(r.Host, r.Header, r.TLS != nil)
 	if err != nil {
@@ -280,6 +289,12 @@ func serveRequest(tunnelConn net.Conn, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	klog.V(4).Infof("interceptor: successfully read the http response from the proxy tunnel for request %s", r.URL.String())
+	copyResponse(tunnelConn, w, r, tunnelHTTPResp)
+}
+
+// copyResponse copies the response from tunnel back to the client, it returns true
+// if the response body is copied completely.
+func copyResponse(tunnelConn net.Conn, w http.ResponseWriter, r *http.Request, tunnelHTTPResp *http.Response) bool {
 	defer tunnelHTTPResp.Body.Close()
 
 	if wsstream.IsWebSocketRequest(r) {
@@ -287,7 +302,7 @@ func serveRequest(tunnelConn net.Conn, w http.ResponseWriter, r *http.Request) {
 		if err := wsReader.Copy(w, r); err != nil {
 			klog.ErrorS(err, "error encountered while streaming results via websocket")
 		}
-		return
+		return false
 	}
 
 	copyHeader(w.Header(), tunnelHTTPResp.Header)
@@ -315,11 +330,11 @@ func serveRequest(tunnelConn net.Conn, w http.ResponseWriter, r *http.Request) {
 		writer = flushwriter.Wrap(w)
 	}
 
-	_, err = io.Copy(writer, tunnelHTTPResp.Body)
+	_, err := io.Copy(writer, tunnelHTTPResp.Body)
 	if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
 		klog.ErrorS(err, "fail to copy response from the tunnel back to the client")
 	}
 
 	klog.V(4).Infof("interceptor: stop serving request %s with headers: %v", r.URL.String(), r.Header)
-
+	return err == nil && r.Context().Err() == nil
 }
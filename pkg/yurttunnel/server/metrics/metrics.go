@@ -38,6 +38,10 @@ type TunnelServerMetrics struct {
 	proxyingRequestsCollector *prometheus.GaugeVec
 	proxyingRequestsGauge     prometheus.Gauge
 	cloudNodeGauge            prometheus.Gauge
+	agentStreamsCollector     *prometheus.GaugeVec
+	agentStreamBytesCounter   *prometheus.CounterVec
+	agentDialFailuresCounter  *prometheus.CounterVec
+	agentRejectedStreams      *prometheus.CounterVec
 }
 
 func newTunnelServerMetrics() *TunnelServerMetrics {
@@ -63,14 +67,54 @@ func newTunnelServerMetrics() *TunnelServerMetrics {
 			Name:      "cloud_nodes_counter",
 			Help:      "counter of cloud nodes that do not run tunnel agent",
 		})
+	agentStreamsCollector := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "agent_active_streams",
+			Help:      "how many streams are proxying through the tunnel to the agent of node",
+		},
+		[]string{"node"})
+	agentStreamBytesCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "agent_stream_bytes_total",
+			Help:      "bytes transferred through the tunnel to(tx) or from(rx) the agent of node",
+		},
+		[]string{"node", "direction"})
+	agentDialFailuresCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "agent_dial_failures_total",
+			Help:      "counter of streams closed without any data from the agent of node, e.g. agent is not connected or failed to dial",
+		},
+		[]string{"node"})
+	agentRejectedStreams := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "agent_rejected_streams_total",
+			Help:      "counter of streams rejected because the agent of node reaches the stream limit",
+		},
+		[]string{"node"})
 
 	prometheus.MustRegister(proxyingRequestsCollector)
 	prometheus.MustRegister(proxyingRequestsGauge)
 	prometheus.MustRegister(cloudNodeGauge)
+	prometheus.MustRegister(agentStreamsCollector)
+	prometheus.MustRegister(agentStreamBytesCounter)
+	prometheus.MustRegister(agentDialFailuresCounter)
+	prometheus.MustRegister(agentRejectedStreams)
 	return &TunnelServerMetrics{
 		proxyingRequestsCollector: proxyingRequestsCollector,
 		proxyingRequestsGauge:     proxyingRequestsGauge,
 		cloudNodeGauge:            cloudNodeGauge,
+		agentStreamsCollector:     agentStreamsCollector,
+		agentStreamBytesCounter:   agentStreamBytesCounter,
+		agentDialFailuresCounter:  agentDialFailuresCounter,
+		agentRejectedStreams:      agentRejectedStreams,
 	}
 }
 
@@ -78,6 +122,10 @@ func (tsm *TunnelServerMetrics) Reset() {
 	tsm.proxyingRequestsCollector.Reset()
 	tsm.proxyingRequestsGauge.Set(float64(0))
 	tsm.cloudNodeGauge.Set(float64(0))
+	tsm.agentStreamsCollector.Reset()
+	tsm.agentStreamBytesCounter.Reset()
+	tsm.agentDialFailuresCounter.Reset()
+	tsm.agentRejectedStreams.Reset()
 }
 
 func (tsm *TunnelServerMetrics) IncInFlightRequests(verb, path string) {
@@ -93,3 +141,23 @@ func (tsm *TunnelServerMetrics) DecInFlightRequests(verb, path string) {
 func (tsm *TunnelServerMetrics) ObserveCloudNodes(cnt int) {
 	tsm.cloudNodeGauge.Set(float64(cnt))
 }
+
+func (tsm *TunnelServerMetrics) IncAgentStreams(node string) {
+	tsm.agentStreamsCollector.WithLabelValues(node).Inc()
+}
+
+func (tsm *TunnelServerMetrics) DecAgentStreams(node string) {
+	tsm.agentStreamsCollector.WithLabelValues(node).Dec()
+}
+
+func (tsm *TunnelServerMetrics) AddAgentStreamBytes(node, direction string, n int) {
+	tsm.agentStreamBytesCounter.WithLabelValues(node, direction).Add(float64(n))
+}
+
+func (tsm *TunnelServerMetrics) IncAgentDialFailures(node string) {
+	tsm.agentDialFailuresCounter.WithLabelValues(node).Inc()
+}
+
+func (tsm *TunnelServerMetrics) IncAgentRejectedStreams(node string) {
+	tsm.agentRejectedStreams.WithLabelValues(node).Inc()
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	anpserver "sigs.k8s.io/apiserver-network-proxy/pkg/server"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+const peerDialTimeout = 5 * time.Second
+
+// peerForwarder forwards the CONNECT requests to the peer tunnel server when the agent
+// is not connected to this tunnel server but registered by the peer.
+type peerForwarder struct {
+	registry *agentRegistry
+	next     http.Handler
+	// tlsCfg is the client tls config for connecting the peer tunnel servers
+	tlsCfg *tls.Config
+}
+
+func (pf *peerForwarder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := hostOf(anpserver.GenIndexInfoForBackend(r))
+	if r.Method != http.MethodConnect || pf.registry.isLocal(host) {
+		pf.next.ServeHTTP(w, r)
+		return
+	}
+	peer := pf.registry.peerOf(host)
+	if len(peer) == 0 {
+		pf.next.ServeHTTP(w, r)
+		return
+	}
+
+	klog.V(4).Infof("agent of %s is held by tunnel server %s, forward the request to it", host, peer)
+	peerConn, br, err := pf.connectPeer(peer, r)
+	if err != nil {
+		klogAndHTTPError(w, http.StatusServiceUnavailable, "fail to forward the request to tunnel server %s: %v", peer, err)
+		return
+	}
+	defer peerConn.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		klogAndHTTPError(w, http.StatusInternalServerError, "hijacking not supported")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	clientConn, bufrw, err := hijacker.Hijack()
+	if err != nil {
+		klog.Errorf("failed to hijack the connection for %s, %v", host, err)
+		return
+	}
+	defer clientConn.Close()
+
+	readerComplete, writerComplete := make(chan struct{}), make(chan struct{})
+	go func() {
+		if _, err := io.Copy(peerConn, bufrw); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			klog.Errorf("error forwarding data to tunnel server %s: %v", peer, err)
+		}
+		close(writerComplete)
+	}()
+	go func() {
+		if _, err := io.Copy(clientConn, br); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			klog.Errorf("error forwarding data from tunnel server %s: %v", peer, err)
+		}
+		close(readerComplete)
+	}()
+
+	select {
+	case <-writerComplete:
+	case <-readerComplete:
+	}
+}
+
+// connectPeer sends the CONNECT request to the peer tunnel server, and returns the connection
+// and its reader which may have buffered the data sent by the agent.
+func (pf *peerForwarder) connectPeer(peer string, r *http.Request) (net.Conn, *bufio.Reader, error) {
+	// the certificate of peer is verified, though the proxy client config skips verifying kubelet
+	cfg := pf.tlsCfg.Clone()
+	cfg.InsecureSkipVerify = false
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: peerDialTimeout}, "tcp", peer, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var connectHeaders string
+	for _, h := range supportedHeaders {
+		if v := r.Header.Get(h); len(v) != 0 {
+			connectHeaders = fmt.Sprintf("%s\r\n%s: %s", connectHeaders, h, v)
+		}
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: localhost%s\r\n\r\n", r.Host, connectHeaders); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return conn, br, nil
+}
+
+// peerServerHandler only accepts the requests from the peer tunnel servers, whose client
+// certificates are signed for the tunnel proxy client.
+func peerServerHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 ||
+			r.TLS.PeerCertificates[0].Subject.CommonName != constants.YurtTunnelProxyClientCSRCN {
+			http.Error(w, "only tunnel servers are allowed", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// runPeerServer runs an https server to handle the requests forwarded by peer tunnel servers
+func runPeerServer(handler http.Handler, peerAddr string, tlsCfg *tls.Config) {
+	go func() {
+		klog.Infof("start handling requests from peer tunnel servers at %s", peerAddr)
+		server := http.Server{
+			Addr:              peerAddr,
+			Handler:           peerServerHandler(handler),
+			ReadHeaderTimeout: constants.YurttunnelANPProxierReadTimeoutSec * time.Second,
+			TLSConfig:         tlsCfg,
+			TLSNextProto:      make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+		}
+		if err := server.ListenAndServeTLS("", ""); err != nil {
+			klog.Errorf("failed to serve requests from peer tunnel servers: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+// echoTunnel emulates the tunnel handler which echoes the data sent through the tunnel
+var echoTunnel = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	conn, bufrw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "%s/%s:", r.Host, r.Header.Get(constants.ProxyHostHeaderKey))
+	line, _ := bufrw.ReadString('\n')
+	io.WriteString(conn, line)
+})
+
+func TestPeerForwarder(t *testing.T) {
+	peer := httptest.NewTLSServer(echoTunnel)
+	defer peer.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(peer.Certificate())
+
+	registry := newAgentRegistry(fake.NewSimpleClientset(), "192.168.0.1:10261")
+	peerAddr := peer.Listener.Addr().String()
+	duration := int32(agentLeaseDurationSeconds)
+	now := metav1.NewMicroTime(time.Now())
+	registry.indexer.Add(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        agentLeaseNamePrefix + "edge-node-1",
+			Namespace:   constants.YurttunnelServerServiceNs,
+			Annotations: map[string]string{agentIdentifiersAnnotation: "ipv4=10.0.0.1&host=edge-node-1"},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &peerAddr,
+			LeaseDurationSeconds: &duration,
+			RenewTime:            &now,
+		},
+	})
+
+	var localRequests int
+	pf := &peerForwarder{
+		registry: registry,
+		next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			localRequests++
+			w.WriteHeader(http.StatusOK)
+		}),
+		tlsCfg: &tls.Config{RootCAs: pool, InsecureSkipVerify: true},
+	}
+	proxier := httptest.NewServer(pf)
+	defer proxier.Close()
+
+	// the agent is held by the peer, so the request is forwarded
+	conn, err := net.Dial("tcp", proxier.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect the proxier, %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "CONNECT 10.0.0.1:10250 HTTP/1.1\r\nHost: localhost\r\n%s: edge-node-1\r\n\r\n", constants.ProxyHostHeaderKey)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to connect through the peer, %v", err)
+	}
+	io.WriteString(conn, "hello\n")
+	if got, _ := br.ReadString('\n'); got != "10.0.0.1:10250/edge-node-1:hello\n" {
+		t.Errorf("unexpected data from peer: %q", got)
+	}
+
+	// the agent is not registered, so the request is handled locally
+	req := httptest.NewRequest(http.MethodConnect, "http://10.0.0.2:10250", nil)
+	req.Host = "10.0.0.2:10250"
+	pf.ServeHTTP(httptest.NewRecorder(), req)
+	if localRequests != 1 {
+		t.Errorf("expect the request for unregistered agent is handled locally")
+	}
+}
+
+func TestPeerServerHandler(t *testing.T) {
+	handler := peerServerHandler(echoTunnel)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodConnect, "http://10.0.0.1:10250", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expect requests without client certificate are forbidden, but got %d", w.Code)
+	}
+}
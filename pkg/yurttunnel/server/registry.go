@@ -0,0 +1,312 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	anpagent "sigs.k8s.io/apiserver-network-proxy/pkg/agent"
+	"sigs.k8s.io/apiserver-network-proxy/proto/header"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+const (
+	// agentRegistryLabel is the label of leases which record the tunnel server connected by the agent
+	agentRegistryLabel = "openyurt.io/yurt-tunnel-agent-registry"
+	// agentIdentifiersAnnotation records the identifiers of agent, so the lease can be found by node ip
+	agentIdentifiersAnnotation = "openyurt.io/yurt-tunnel-agent-identifiers"
+	agentLeaseNamePrefix       = "yurt-tunnel-agent-"
+	agentIdentifierIndex       = "agentIdentifier"
+	// agentLeaseDurationSeconds is the duration after which the registry of agent is considered
+	// stale if it is not renewed, e.g. the tunnel server holding the agent has crashed.
+	agentLeaseDurationSeconds = 40
+	agentLeaseRenewInterval   = 10 * time.Second
+)
+
+// agentRegistry records the agents connected to this tunnel server in the local map, and
+// the tunnel server of every agent in the shared registry, which is a lease per agent whose
+// holder is the peer address of tunnel server. so when the tunnel servers are scaled out and
+// every agent only connects to one of them, requests for the agent can be forwarded to the
+// tunnel server which holds the agent.
+type agentRegistry struct {
+	kubeClient clientset.Interface
+	peerAddr   string
+	indexer    cache.Indexer
+	informer   cache.SharedIndexInformer
+	sync.RWMutex
+	// local records the number of connections for each identifier of agents
+	local map[string]int
+	// agents records the identifiers of agents held by this tunnel server, whose leases are renewed
+	agents map[string]string
+	now    func() time.Time
+}
+
+func newAgentRegistry(client clientset.Interface, peerAddr string) *agentRegistry {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 24*time.Hour,
+		informers.WithNamespace(constants.YurttunnelServerServiceNs),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = labels.Set{agentRegistryLabel: "true"}.String()
+		}))
+	informer := factory.Coordination().V1().Leases().Informer()
+	informer.AddIndexers(cache.Indexers{agentIdentifierIndex: indexByAgentIdentifiers})
+
+	return &agentRegistry{
+		kubeClient: client,
+		peerAddr:   peerAddr,
+		indexer:    informer.GetIndexer(),
+		informer:   informer,
+		local:      make(map[string]int),
+		agents:     make(map[string]string),
+		now:        time.Now,
+	}
+}
+
+func (ar *agentRegistry) run(stopCh <-chan struct{}) {
+	go ar.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, ar.informer.HasSynced) {
+		klog.Error("failed to sync the cache of agent registry")
+	}
+	go wait.Until(ar.renew, agentLeaseRenewInterval, stopCh)
+}
+
+func indexByAgentIdentifiers(obj interface{}) ([]string, error) {
+	lease, ok := obj.(*coordinationv1.Lease)
+	if !ok {
+		return []string{}, nil
+	}
+	return identifiersOf(lease.Annotations[agentIdentifiersAnnotation]), nil
+}
+
+// identifiersOf returns the addresses of agent identifiers which can be used for selecting the agent
+func identifiersOf(agentIdentifiers string) []string {
+	ids, err := anpagent.GenAgentIdentifiers(agentIdentifiers)
+	if err != nil {
+		klog.Errorf("failed to parse agent identifiers %s, %v", agentIdentifiers, err)
+		return []string{}
+	}
+	addrs := make([]string, 0, len(ids.IPv4)+len(ids.IPv6)+len(ids.Host))
+	addrs = append(addrs, ids.IPv4...)
+	addrs = append(addrs, ids.IPv6...)
+	return append(addrs, ids.Host...)
+}
+
+// isLocal checks the agent of host is connected to this tunnel server or not
+func (ar *agentRegistry) isLocal(host string) bool {
+	ar.RLock()
+	defer ar.RUnlock()
+	return ar.local[host] > 0
+}
+
+// peerOf returns the peer address of tunnel server which holds the agent of host,
+// empty string is returned if the agent is not registered by other tunnel servers.
+func (ar *agentRegistry) peerOf(host string) string {
+	objs, err := ar.indexer.ByIndex(agentIdentifierIndex, host)
+	if err != nil || len(objs) == 0 {
+		return ""
+	}
+	lease, ok := objs[0].(*coordinationv1.Lease)
+	if !ok || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == ar.peerAddr || ar.isExpired(lease) {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// isExpired checks the lease is not renewed in its duration by the holder
+func (ar *agentRegistry) isExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expireTime := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+	return !ar.now().Before(expireTime)
+}
+
+// streamInterceptor registers the agent when its connect stream is set up, and unregisters
+// the agent when the stream is closed.
+func (ar *agentRegistry) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, ok := metadata.FromIncomingContext(ss.Context())
+	if !ok || len(md.Get(header.AgentID)) != 1 {
+		return handler(srv, ss)
+	}
+	agentID := md.Get(header.AgentID)[0]
+	agentIdentifiers := ""
+	if v := md.Get(header.AgentIdentifiers); len(v) == 1 {
+		agentIdentifiers = v[0]
+	}
+	// agent id is the node name of agent, and it can also be used for selecting the agent
+	ids := sets.NewString(append(identifiersOf(agentIdentifiers), agentID)...).List()
+
+	ar.addLocal(agentID, agentIdentifiers, ids)
+	ar.register(agentID, agentIdentifiers)
+	defer func() {
+		if ar.removeLocal(agentID, ids) {
+			ar.unregister(agentID)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func (ar *agentRegistry) addLocal(agentID, agentIdentifiers string, ids []string) {
+	ar.Lock()
+	defer ar.Unlock()
+	ar.agents[agentID] = agentIdentifiers
+	for _, id := range ids {
+		ar.local[id]++
+	}
+}
+
+// removeLocal returns true if the agent has no connections to this tunnel server
+func (ar *agentRegistry) removeLocal(agentID string, ids []string) bool {
+	ar.Lock()
+	defer ar.Unlock()
+	disconnected := true
+	for _, id := range ids {
+		ar.local[id]--
+		if ar.local[id] <= 0 {
+			delete(ar.local, id)
+		} else {
+			disconnected = false
+		}
+	}
+	if disconnected {
+		delete(ar.agents, agentID)
+	}
+	return disconnected
+}
+
+// register records this tunnel server as the holder of agent in the shared registry
+func (ar *agentRegistry) register(agentID, agentIdentifiers string) {
+	leaseClient := ar.kubeClient.CoordinationV1().Leases(constants.YurttunnelServerServiceNs)
+	now := metav1.NewMicroTime(ar.now())
+	duration := int32(agentLeaseDurationSeconds)
+	lease, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+agentID, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        agentLeaseNamePrefix + agentID,
+				Namespace:   constants.YurttunnelServerServiceNs,
+				Labels:      map[string]string{agentRegistryLabel: "true"},
+				Annotations: map[string]string{agentIdentifiersAnnotation: agentIdentifiers},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &ar.peerAddr,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if _, err := leaseClient.Create(context.Background(), lease, metav1.CreateOptions{}); err != nil {
+			klog.Errorf("failed to register agent %s, %v", agentID, err)
+		}
+		return
+	} else if err != nil {
+		klog.Errorf("failed to get the registry of agent %s, %v", agentID, err)
+		return
+	}
+
+	lease = lease.DeepCopy()
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Annotations[agentIdentifiersAnnotation] = agentIdentifiers
+	lease.Spec.HolderIdentity = &ar.peerAddr
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	if _, err := leaseClient.Update(context.Background(), lease, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("failed to register agent %s, %v", agentID, err)
+	}
+}
+
+// renew updates the renew time of leases for agents held by this tunnel server, so other
+// tunnel servers don't consider these agents are held by a dead tunnel server.
+func (ar *agentRegistry) renew() {
+	ar.RLock()
+	agents := make(map[string]string, len(ar.agents))
+	for agentID, agentIdentifiers := range ar.agents {
+		agents[agentID] = agentIdentifiers
+	}
+	ar.RUnlock()
+
+	leaseClient := ar.kubeClient.CoordinationV1().Leases(constants.YurttunnelServerServiceNs)
+	for agentID, agentIdentifiers := range agents {
+		lease, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+agentID, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// the registry is lost while the agent is still connected to this tunnel server
+			ar.register(agentID, agentIdentifiers)
+			continue
+		} else if err != nil {
+			klog.Errorf("failed to get the registry of agent %s, %v", agentID, err)
+			continue
+		}
+		// the agent has been registered by another tunnel server
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != ar.peerAddr {
+			continue
+		}
+
+		lease = lease.DeepCopy()
+		now := metav1.NewMicroTime(ar.now())
+		lease.Spec.RenewTime = &now
+		if _, err := leaseClient.Update(context.Background(), lease, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("failed to renew the registry of agent %s, %v", agentID, err)
+		}
+	}
+}
+
+// unregister removes the registry of agent, unless the agent has been registered by other tunnel server
+func (ar *agentRegistry) unregister(agentID string) {
+	leaseClient := ar.kubeClient.CoordinationV1().Leases(constants.YurttunnelServerServiceNs)
+	lease, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+agentID, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("failed to get the registry of agent %s, %v", agentID, err)
+		}
+		return
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != ar.peerAddr {
+		return
+	}
+	err = leaseClient.Delete(context.Background(), lease.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+	})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		klog.Errorf("failed to unregister agent %s, %v", agentID, err)
+	}
+}
+
+// hostOf returns the host of address without port
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/apiserver-network-proxy/proto/header"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestAgentRegistryStreamInterceptor(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	registry := newAgentRegistry(client, "192.168.0.1:10261")
+	leaseClient := client.CoordinationV1().Leases(constants.YurttunnelServerServiceNs)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		header.AgentID, "edge-node-1",
+		header.AgentIdentifiers, "ipv4=10.0.0.1&host=edge-node-1"))
+	err := registry.streamInterceptor(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			for _, host := range []string{"edge-node-1", "10.0.0.1"} {
+				if !registry.isLocal(host) {
+					t.Errorf("expect agent of %s is connected", host)
+				}
+			}
+			lease, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+"edge-node-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("expect agent is registered, %v", err)
+			}
+			if *lease.Spec.HolderIdentity != "192.168.0.1:10261" {
+				t.Errorf("expect agent is held by 192.168.0.1:10261, but got %s", *lease.Spec.HolderIdentity)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if registry.isLocal("10.0.0.1") {
+		t.Errorf("expect agent is disconnected")
+	}
+	if _, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+"edge-node-1", metav1.GetOptions{}); err == nil {
+		t.Errorf("expect agent is unregistered")
+	}
+}
+
+func TestAgentRegistryPeerOf(t *testing.T) {
+	registry := newAgentRegistry(fake.NewSimpleClientset(), "192.168.0.1:10261")
+	duration := int32(agentLeaseDurationSeconds)
+	now := metav1.NewMicroTime(time.Now())
+	for name, holder := range map[string]string{
+		"edge-node-1": "192.168.0.2:10261",
+		"edge-node-2": "192.168.0.1:10261",
+	} {
+		holder := holder
+		registry.indexer.Add(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        agentLeaseNamePrefix + name,
+				Namespace:   constants.YurttunnelServerServiceNs,
+				Annotations: map[string]string{agentIdentifiersAnnotation: "host=" + name},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &now,
+			},
+		})
+	}
+
+	testcases := map[string]string{
+		"edge-node-1": "192.168.0.2:10261",
+		// the agent is held by the tunnel server itself
+		"edge-node-2": "",
+		"edge-node-3": "",
+	}
+	for host, expect := range testcases {
+		if got := registry.peerOf(host); got != expect {
+			t.Errorf("expect peer %q for %s, but got %q", expect, host, got)
+		}
+	}
+}
+
+func TestAgentRegistryLeaseExpiry(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	other := newAgentRegistry(client, "192.168.0.2:10261")
+	registry := newAgentRegistry(client, "192.168.0.1:10261")
+	leaseClient := client.CoordinationV1().Leases(constants.YurttunnelServerServiceNs)
+
+	start := time.Now()
+	other.now = func() time.Time { return start }
+	other.addLocal("edge-node-1", "host=edge-node-1", []string{"edge-node-1"})
+	other.register("edge-node-1", "host=edge-node-1")
+
+	syncLease := func() {
+		lease, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+"edge-node-1", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get lease, %v", err)
+		}
+		registry.indexer.Update(lease)
+	}
+	syncLease()
+
+	testcases := []struct {
+		name   string
+		renew  bool
+		now    time.Time
+		expect string
+	}{
+		{
+			name:   "lease is in its duration",
+			now:    start.Add(agentLeaseDurationSeconds * time.Second / 2),
+			expect: "192.168.0.2:10261",
+		},
+		{
+			name:   "lease is not renewed in its duration",
+			now:    start.Add(agentLeaseDurationSeconds * time.Second),
+			expect: "",
+		},
+		{
+			name:   "lease is renewed by the holder",
+			renew:  true,
+			now:    start.Add(agentLeaseDurationSeconds * time.Second),
+			expect: "192.168.0.2:10261",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.renew {
+				other.now = func() time.Time { return tc.now.Add(-agentLeaseRenewInterval) }
+				other.renew()
+				syncLease()
+			}
+			registry.now = func() time.Time { return tc.now }
+			if got := registry.peerOf("edge-node-1"); got != tc.expect {
+				t.Errorf("expect peer %q, but got %q", tc.expect, got)
+			}
+		})
+	}
+
+	// the lease is registered again if it is lost while the agent is still connected
+	if err := leaseClient.Delete(context.Background(), agentLeaseNamePrefix+"edge-node-1", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete lease, %v", err)
+	}
+	other.renew()
+	if _, err := leaseClient.Get(context.Background(), agentLeaseNamePrefix+"edge-node-1", metav1.GetOptions{}); err != nil {
+		t.Errorf("expect agent is registered again, %v", err)
+	}
+}
@@ -18,7 +18,12 @@ package server
 
 import (
 	"crypto/tls"
+	"time"
 
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/accesspolicy"
 	hw "github.com/openyurtio/openyurt/pkg/yurttunnel/handlerwrapper"
 )
 
@@ -28,9 +33,12 @@ type TunnelServer interface {
 	Run() error
 }
 
-// NewTunnelServer returns a new TunnelServer
+// NewTunnelServer returns a new TunnelServer, the destinations of streams are checked by
+// accessEnforcer if it's not nil, and the egress selector of kube-apiserver is served if
+// egressSelector is not nil. streamBandwidthLimits are the bandwidth limits in bytes per
+// second of the stream classes to every agent.
 func NewTunnelServer(
-	egressSelectorEnabled bool,
+	egressSelector *EgressSelectorConfig,
 	interceptorServerUDSFile,
 	serverMasterAddr,
 	serverMasterInsecureAddr,
@@ -39,9 +47,16 @@ func NewTunnelServer(
 	tlsCfg *tls.Config,
 	proxyClientTlsCfg *tls.Config,
 	wrappers hw.HandlerWrappers,
-	proxyStrategy string) TunnelServer {
+	proxyStrategy string,
+	maxStreamsPerAgent int,
+	streamWaitTimeout time.Duration,
+	streamBandwidthLimits map[string]int,
+	peerAddr string,
+	kubeClient clientset.Interface,
+	accessEnforcer accesspolicy.Enforcer,
+	nodeLister corelisters.NodeLister) TunnelServer {
 	ats := anpTunnelServer{
-		egressSelectorEnabled:    egressSelectorEnabled,
+		egressSelector:           egressSelector,
 		interceptorServerUDSFile: interceptorServerUDSFile,
 		serverMasterAddr:         serverMasterAddr,
 		serverMasterInsecureAddr: serverMasterInsecureAddr,
@@ -51,6 +66,13 @@ func NewTunnelServer(
 		proxyClientTlsCfg:        proxyClientTlsCfg,
 		wrappers:                 wrappers,
 		proxyStrategy:            proxyStrategy,
+		maxStreamsPerAgent:       maxStreamsPerAgent,
+		streamWaitTimeout:        streamWaitTimeout,
+		streamBandwidthLimits:    streamBandwidthLimits,
+		peerAddr:                 peerAddr,
+		kubeClient:               kubeClient,
+		accessEnforcer:           accessEnforcer,
+		nodeLister:               nodeLister,
 	}
 	return &ats
 }
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/server/metrics"
+)
+
+const (
+	directionTx = "tx"
+	directionRx = "rx"
+)
+
+// streamLimiter limits the number of concurrent streams to every agent, and records
+// the metrics of streams for every agent. a new stream waits for at most waitTimeout
+// when the agent reaches the limit, so the clients are slowed down instead of failed
+// at once during bursts.
+type streamLimiter struct {
+	maxStreams  int
+	waitTimeout time.Duration
+	sync.Mutex
+	slots map[string]*agentSlots
+}
+
+// agentSlots are the stream slots of an agent, they are removed when no stream holds
+// or waits for them, so the slots of agents that went away are not kept.
+type agentSlots struct {
+	slot chan struct{}
+	// users is the number of streams holding or waiting for the slots
+	users int
+}
+
+func newStreamLimiter(maxStreams int, waitTimeout time.Duration) *streamLimiter {
+	return &streamLimiter{
+		maxStreams:  maxStreams,
+		waitTimeout: waitTimeout,
+		slots:       make(map[string]*agentSlots),
+	}
+}
+
+// agentOf returns the identifier of agent which the stream is proxied to, it's the same
+// as the backend index used by proxy server.
+func agentOf(r *http.Request) string {
+	if agent := r.Header.Get(constants.ProxyHostHeaderKey); len(agent) != 0 {
+		return agent
+	}
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return r.Host
+}
+
+// acquire takes a stream slot of the agent, false is returned if no slot is released
+// before waitTimeout or the request is canceled.
+func (sl *streamLimiter) acquire(r *http.Request, agent string) bool {
+	if sl.maxStreams <= 0 {
+		return true
+	}
+	sl.Lock()
+	slots, ok := sl.slots[agent]
+	if !ok {
+		slots = &agentSlots{slot: make(chan struct{}, sl.maxStreams)}
+		sl.slots[agent] = slots
+	}
+	slots.users++
+	sl.Unlock()
+
+	select {
+	case slots.slot <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(sl.waitTimeout)
+	defer timer.Stop()
+	select {
+	case slots.slot <- struct{}{}:
+		return true
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+	sl.put(agent)
+	return false
+}
+
+func (sl *streamLimiter) release(agent string) {
+	if sl.maxStreams <= 0 {
+		return
+	}
+	sl.Lock()
+	slots := sl.slots[agent]
+	sl.Unlock()
+	<-slots.slot
+	sl.put(agent)
+}
+
+// put drops a user of the slots of agent, and removes the slots if they are not used any more.
+func (sl *streamLimiter) put(agent string) {
+	sl.Lock()
+	defer sl.Unlock()
+	slots := sl.slots[agent]
+	slots.users--
+	if slots.users == 0 {
+		delete(sl.slots, agent)
+	}
+}
+
+// WrapHandler wraps the proxier handler with stream limitation and metrics
+func (sl *streamLimiter) WrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent := agentOf(r)
+		if !sl.acquire(r, agent) {
+			metrics.Metrics.IncAgentRejectedStreams(agent)
+			klog.Errorf("stream to %s is rejected, agent %s reaches the limit of %d streams", r.Host, agent, sl.maxStreams)
+			http.Error(w, "too many streams to agent "+agent, http.StatusTooManyRequests)
+			return
+		}
+		defer sl.release(agent)
+
+		metrics.Metrics.IncAgentStreams(agent)
+		defer metrics.Metrics.DecAgentStreams(agent)
+
+		mw := &meteredResponseWriter{ResponseWriter: w, agent: agent}
+		handler.ServeHTTP(mw, r)
+		if mw.conn != nil && atomic.LoadInt64(&mw.conn.rxBytes) == 0 {
+			metrics.Metrics.IncAgentDialFailures(agent)
+		}
+	})
+}
+
+// meteredResponseWriter returns a connection which records the transferred bytes when hijacked
+type meteredResponseWriter struct {
+	http.ResponseWriter
+	agent string
+	conn  *meteredConn
+}
+
+func (mw *meteredResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := mw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// data buffered by the http server should be read first
+	mw.conn = &meteredConn{Conn: conn, reader: rw.Reader, agent: mw.agent}
+	return mw.conn, bufio.NewReadWriter(bufio.NewReader(mw.conn), bufio.NewWriter(mw.conn)), nil
+}
+
+// meteredConn is the connection between the proxier and its client, the data read from
+// the connection is sent(tx) to the agent, and the data written is received(rx) from the agent.
+type meteredConn struct {
+	net.Conn
+	reader  *bufio.Reader
+	agent   string
+	rxBytes int64
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.reader.Read(b)
+	if n > 0 {
+		metrics.Metrics.AddAgentStreamBytes(c.agent, directionTx, n)
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		atomic.AddInt64(&c.rxBytes, int64(n))
+		metrics.Metrics.AddAgentStreamBytes(c.agent, directionRx, n)
+	}
+	return n, err
+}
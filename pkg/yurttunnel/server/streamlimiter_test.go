@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+func TestAgentOf(t *testing.T) {
+	testcases := map[string]struct {
+		host   string
+		header string
+		expect string
+	}{
+		"proxy host header": {
+			host:   "192.168.1.10:10250",
+			header: "edge-node-1",
+			expect: "edge-node-1",
+		},
+		"host with port": {
+			host:   "192.168.1.10:10250",
+			expect: "192.168.1.10",
+		},
+		"host without port": {
+			host:   "192.168.1.10",
+			expect: "192.168.1.10",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodConnect, "http://localhost", nil)
+			req.Host = tc.host
+			if tc.header != "" {
+				req.Header.Set(constants.ProxyHostHeaderKey, tc.header)
+			}
+			if agent := agentOf(req); agent != tc.expect {
+				t.Errorf("expect agent %s, but got %s", tc.expect, agent)
+			}
+		})
+	}
+}
+
+func TestStreamLimiter(t *testing.T) {
+	sl := newStreamLimiter(2, 50*time.Millisecond)
+	req := httptest.NewRequest(http.MethodConnect, "http://localhost", nil)
+
+	for i := 0; i < 2; i++ {
+		if !sl.acquire(req, "edge-node-1") {
+			t.Fatalf("expect stream %d is acquired", i)
+		}
+	}
+	if sl.acquire(req, "edge-node-1") {
+		t.Errorf("expect stream is rejected when agent reaches the limit")
+	}
+	if !sl.acquire(req, "edge-node-2") {
+		t.Errorf("expect streams of other agents are not limited")
+	}
+
+	// the waiting stream is accepted once a stream is released
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		sl.release("edge-node-1")
+	}()
+	if !sl.acquire(req, "edge-node-1") {
+		t.Errorf("expect stream is acquired after a stream is released")
+	}
+
+	// the slots of agents are removed after all of their streams are finished
+	sl.release("edge-node-1")
+	sl.release("edge-node-1")
+	sl.release("edge-node-2")
+	if len(sl.slots) != 0 {
+		t.Errorf("expect slots of agents are removed, but got %d", len(sl.slots))
+	}
+
+	unlimited := newStreamLimiter(0, 0)
+	for i := 0; i < 10; i++ {
+		if !unlimited.acquire(req, "edge-node-1") {
+			t.Fatalf("expect stream is not limited")
+		}
+	}
+}
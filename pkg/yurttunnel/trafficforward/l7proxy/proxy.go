@@ -0,0 +1,222 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"reflect"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+const (
+	defaultSyncPeriod = 30 * time.Second
+	// ForwardedUserHeader is the header for telling the backend who is accessing the route
+	ForwardedUserHeader = "X-Forwarded-User"
+	// TokenAudience is the audience the bearer tokens must be issued for, e.g.
+	// kubectl create token {serviceaccount} --audience openyurt.io/l7-proxy
+	TokenAudience = "openyurt.io/l7-proxy"
+)
+
+// L7Proxy interface defines the method for proxying http requests from cloud clients
+// to the http services on edge nodes.
+type L7Proxy interface {
+	Run(stopCh <-chan struct{}, wg *sync.WaitGroup)
+}
+
+// l7Proxy routes the http requests by hostname to the backends configured in l7 proxy routes
+// of yurt-tunnel-server-cfg configmap, the clients are authenticated by client certificates
+// signed by cluster CA or bearer tokens verified by kube-apiserver, and authorized by the
+// policy of route.
+type l7Proxy struct {
+	kubeClient    clientset.Interface
+	listenAddr    string
+	tlsCfg        *tls.Config
+	syncPeriod    time.Duration
+	authenticator authenticator.Request
+	dialTunnelFor func(nodeName, addr string) (net.Conn, error)
+	sync.RWMutex
+	routes map[string]*routeProxy
+}
+
+type routeProxy struct {
+	route     Route
+	transport *http.Transport
+	proxy     *httputil.ReverseProxy
+}
+
+// NewL7Proxy creates a L7Proxy which serves https requests on listenAddr.
+func NewL7Proxy(client clientset.Interface, listenAddr, udsSockFile string, tlsCfg *tls.Config) L7Proxy {
+	return &l7Proxy{
+		kubeClient:    client,
+		listenAddr:    listenAddr,
+		tlsCfg:        tlsCfg,
+		syncPeriod:    defaultSyncPeriod,
+		authenticator: util.NewClientAuthenticator(client, tlsCfg.ClientCAs, TokenAudience),
+		dialTunnelFor: func(nodeName, addr string) (net.Conn, error) {
+			return util.DialTunnel(udsSockFile, nodeName, addr)
+		},
+		routes: make(map[string]*routeProxy),
+	}
+}
+
+// Run starts the l7 proxy server and updates the routes periodically
+func (p *l7Proxy) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	p.syncRoutes()
+
+	server := &http.Server{
+		Addr:      p.listenAddr,
+		Handler:   p,
+		TLSConfig: p.tlsCfg,
+	}
+	go func() {
+		klog.Infof("start handling l7 proxy requests at %s", p.listenAddr)
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("failed to serve l7 proxy requests: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(p.syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("stop the l7 proxy")
+			server.Close()
+			p.Lock()
+			for host, rp := range p.routes {
+				rp.transport.CloseIdleConnections()
+				delete(p.routes, host)
+			}
+			p.Unlock()
+			return
+		case <-ticker.C:
+			p.syncRoutes()
+		}
+	}
+}
+
+func (p *l7Proxy) getConfiguredRoutes() (map[string]Route, error) {
+	cm, err := p.kubeClient.CoreV1().
+		ConfigMaps(util.YurttunnelServerDnatConfigMapNs).
+		Get(context.Background(), util.YurttunnelServerDnatConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string]Route{}, nil
+		}
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w",
+			util.YurttunnelServerDnatConfigMapNs,
+			util.YurttunnelServerDnatConfigMapName, err)
+	}
+	return resolveRoutes(cm.Data[l7ProxyRoutesKey]), nil
+}
+
+// syncRoutes updates the proxies of changed routes, the unchanged routes keep their idle connections.
+func (p *l7Proxy) syncRoutes() {
+	routes, err := p.getConfiguredRoutes()
+	if err != nil {
+		klog.Errorf("failed to get l7 proxy routes, %v", err)
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	for host, rp := range p.routes {
+		if route, ok := routes[host]; !ok || !reflect.DeepEqual(route, rp.route) {
+			klog.Infof("remove l7 proxy route for host %s", host)
+			rp.transport.CloseIdleConnections()
+			delete(p.routes, host)
+		}
+	}
+	for host, route := range routes {
+		if _, ok := p.routes[host]; !ok {
+			klog.Infof("add l7 proxy route for host %s to %s/%s", host, route.NodeName, route.Backend)
+			p.routes[host] = p.newRouteProxy(route)
+		}
+	}
+}
+
+func (p *l7Proxy) newRouteProxy(route Route) *routeProxy {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return p.dialTunnelFor(route.NodeName, route.Backend)
+		},
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			// the original host is kept in req.Host for the virtual hosts of backend
+			req.URL.Scheme = "http"
+			req.URL.Host = route.Backend
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			klog.Errorf("failed to proxy request %s %s to %s/%s, %v", req.Method, req.URL.Path, route.NodeName, route.Backend, err)
+			http.Error(w, "failed to proxy request to backend", http.StatusBadGateway)
+		},
+	}
+	return &routeProxy{route: route, transport: transport, proxy: proxy}
+}
+
+func (p *l7Proxy) routeFor(host string) *routeProxy {
+	p.RLock()
+	defer p.RUnlock()
+	return p.routes[host]
+}
+
+func (p *l7Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rp := p.routeFor(hostOf(r.Host))
+	if rp == nil {
+		http.Error(w, fmt.Sprintf("no route for host %s", r.Host), http.StatusNotFound)
+		return
+	}
+
+	resp, ok, err := p.authenticator.AuthenticateRequest(r)
+	if err != nil || !ok {
+		if err != nil {
+			klog.Errorf("failed to authenticate request for host %s, %v", r.Host, err)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !rp.route.allows(resp.User) {
+		klog.V(2).Infof("user %s is not allowed to access host %s", resp.User.GetName(), r.Host)
+		http.Error(w, fmt.Sprintf("user %s is not allowed to access host %s", resp.User.GetName(), r.Host), http.StatusForbidden)
+		return
+	}
+
+	// credentials of cluster are not sent to the backend
+	r.Header.Del("Authorization")
+	r.Header.Set(ForwardedUserHeader, resp.User.GetName())
+	klog.V(4).Infof("proxy request %s %s for user %s to %s/%s", r.Method, r.URL.Path, resp.User.GetName(), rp.route.NodeName, rp.route.Backend)
+	rp.proxy.ServeHTTP(w, r)
+}
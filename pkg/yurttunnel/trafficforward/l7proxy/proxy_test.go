@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestServeHTTP(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get("Authorization")) != 0 {
+			t.Errorf("expect credentials are not sent to backend")
+		}
+		io.WriteString(w, r.Host+" "+r.Header.Get(ForwardedUserHeader))
+	}))
+	defer backend.Close()
+
+	var dialedNode, dialedAddr string
+	p := &l7Proxy{
+		authenticator: authenticator.RequestFunc(func(r *http.Request) (*authenticator.Response, bool, error) {
+			switch r.Header.Get("Authorization") {
+			case "Bearer alice":
+				return &authenticator.Response{User: &user.DefaultInfo{Name: "alice"}}, true, nil
+			case "Bearer bob":
+				return &authenticator.Response{User: &user.DefaultInfo{Name: "bob"}}, true, nil
+			}
+			return nil, false, nil
+		}),
+		dialTunnelFor: func(nodeName, addr string) (net.Conn, error) {
+			dialedNode, dialedAddr = nodeName, addr
+			return net.Dial("tcp", backend.Listener.Addr().String())
+		},
+		routes: make(map[string]*routeProxy),
+	}
+	route := Route{
+		Host:         "grafana.example.com",
+		NodeName:     "edge-node-1",
+		Backend:      "127.0.0.1:3000",
+		AllowedUsers: []string{"alice"},
+	}
+	p.routes[route.Host] = p.newRouteProxy(route)
+
+	testcases := map[string]struct {
+		host       string
+		token      string
+		expectCode int
+		expectBody string
+	}{
+		"no route": {
+			host:       "unknown.example.com",
+			token:      "alice",
+			expectCode: http.StatusNotFound,
+		},
+		"unauthenticated": {
+			host:       "grafana.example.com",
+			expectCode: http.StatusUnauthorized,
+		},
+		"forbidden": {
+			host:       "grafana.example.com",
+			token:      "bob",
+			expectCode: http.StatusForbidden,
+		},
+		"proxied": {
+			host:       "grafana.example.com:8443",
+			token:      "alice",
+			expectCode: http.StatusOK,
+			expectBody: "grafana.example.com:8443 alice",
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://"+tc.host+"/api/health", nil)
+			if len(tc.token) != 0 {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, req)
+			if w.Code != tc.expectCode {
+				t.Fatalf("expect status %d, but got %d: %s", tc.expectCode, w.Code, w.Body.String())
+			}
+			if len(tc.expectBody) != 0 && w.Body.String() != tc.expectBody {
+				t.Errorf("expect body %q, but got %q", tc.expectBody, w.Body.String())
+			}
+		})
+	}
+
+	if dialedNode != "edge-node-1" || dialedAddr != "127.0.0.1:3000" {
+		t.Errorf("expect tunnel to edge-node-1/127.0.0.1:3000, but got %s/%s", dialedNode, dialedAddr)
+	}
+}
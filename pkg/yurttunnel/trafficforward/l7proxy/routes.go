@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// l7ProxyRoutesKey is the field of yurt-tunnel-server-cfg configmap for l7 proxy routes, the value
+// is a yaml list of routes, e.g.
+//
+//   - host: grafana.hangzhou.example.com
+//     nodeName: edge-node-1
+//     backend: 127.0.0.1:3000
+//     allowedGroups: ["ops"]
+const l7ProxyRoutesKey = "l7-proxy-routes"
+
+// Route proxies the http requests for Host to the Backend address through the tunnel agent on
+// the node. Only the authenticated users in AllowedUsers or AllowedGroups can access the route,
+// and nobody can access the route if both of them are empty.
+type Route struct {
+	Host          string   `json:"host"`
+	NodeName      string   `json:"nodeName"`
+	Backend       string   `json:"backend"`
+	AllowedUsers  []string `json:"allowedUsers,omitempty"`
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+}
+
+// allows checks the user can access the route or not
+func (r *Route) allows(u user.Info) bool {
+	if sets.NewString(r.AllowedUsers...).Has(u.GetName()) {
+		return true
+	}
+	return sets.NewString(r.AllowedGroups...).HasAny(u.GetGroups()...)
+}
+
+func (r *Route) validate() error {
+	if len(r.Host) == 0 {
+		return fmt.Errorf("host is empty")
+	}
+	if len(r.NodeName) == 0 {
+		return fmt.Errorf("nodeName is empty")
+	}
+	host, port, err := net.SplitHostPort(r.Backend)
+	if err != nil {
+		return fmt.Errorf("backend %q is invalid, %w", r.Backend, err)
+	}
+	if len(host) == 0 || len(port) == 0 {
+		return fmt.Errorf("backend %q should be in format {host}:{port}", r.Backend)
+	}
+	return nil
+}
+
+// resolveRoutes parses the l7 proxy routes and indexes them by host, invalid routes and
+// routes with duplicated host are skipped.
+func resolveRoutes(data string) map[string]Route {
+	routes := make(map[string]Route)
+	if len(strings.TrimSpace(data)) == 0 {
+		return routes
+	}
+
+	var list []Route
+	if err := yaml.Unmarshal([]byte(data), &list); err != nil {
+		klog.Errorf("failed to parse l7 proxy routes, %v", err)
+		return routes
+	}
+	for i := range list {
+		route := list[i]
+		route.Host = strings.ToLower(route.Host)
+		if err := route.validate(); err != nil {
+			klog.Errorf("l7 proxy route %d is skipped, %v", i, err)
+			continue
+		}
+		if _, ok := routes[route.Host]; ok {
+			klog.Errorf("l7 proxy route %d is skipped, host %s is used by other route", i, route.Host)
+			continue
+		}
+		routes[route.Host] = route
+	}
+	return routes
+}
+
+// hostOf returns the host of request without port
+func hostOf(reqHost string) string {
+	if host, _, err := net.SplitHostPort(reqHost); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(reqHost)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l7proxy
+
+import (
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func TestResolveRoutes(t *testing.T) {
+	data := `
+- host: Grafana.example.com
+  nodeName: edge-node-1
+  backend: 127.0.0.1:3000
+- host: grafana.example.com
+  nodeName: edge-node-2
+  backend: 127.0.0.1:3000
+- host: invalid.example.com
+  nodeName: edge-node-1
+  backend: 127.0.0.1
+- host: no-node.example.com
+  backend: 127.0.0.1:8080
+`
+	routes := resolveRoutes(data)
+	if len(routes) != 1 {
+		t.Fatalf("expect 1 route, but got %d: %v", len(routes), routes)
+	}
+	route, ok := routes["grafana.example.com"]
+	if !ok {
+		t.Fatalf("expect route for grafana.example.com, but got %v", routes)
+	}
+	if route.NodeName != "edge-node-1" || route.Backend != "127.0.0.1:3000" {
+		t.Errorf("unexpected route %v", route)
+	}
+
+	if routes := resolveRoutes("not a list"); len(routes) != 0 {
+		t.Errorf("expect no routes for invalid data, but got %v", routes)
+	}
+	if routes := resolveRoutes(""); len(routes) != 0 {
+		t.Errorf("expect no routes for empty data, but got %v", routes)
+	}
+}
+
+func TestRouteAllows(t *testing.T) {
+	testcases := map[string]struct {
+		route  Route
+		user   user.Info
+		expect bool
+	}{
+		"no policy": {
+			route: Route{},
+			user:  &user.DefaultInfo{Name: "alice", Groups: []string{"system:authenticated"}},
+		},
+		"allowed user": {
+			route:  Route{AllowedUsers: []string{"alice"}},
+			user:   &user.DefaultInfo{Name: "alice"},
+			expect: true,
+		},
+		"allowed group": {
+			route:  Route{AllowedUsers: []string{"alice"}, AllowedGroups: []string{"ops"}},
+			user:   &user.DefaultInfo{Name: "bob", Groups: []string{"dev", "ops"}},
+			expect: true,
+		},
+		"denied user": {
+			route: Route{AllowedUsers: []string{"alice"}, AllowedGroups: []string{"ops"}},
+			user:  &user.DefaultInfo{Name: "bob", Groups: []string{"dev"}},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			if got := tc.route.allows(tc.user); got != tc.expect {
+				t.Errorf("expect %v, but got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestHostOf(t *testing.T) {
+	testcases := map[string]string{
+		"Grafana.example.com":      "grafana.example.com",
+		"grafana.example.com:8443": "grafana.example.com",
+	}
+	for reqHost, expect := range testcases {
+		if got := hostOf(reqHost); got != expect {
+			t.Errorf("expect %s for %s, but got %s", expect, reqHost, got)
+		}
+	}
+}
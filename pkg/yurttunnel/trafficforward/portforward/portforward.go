@@ -0,0 +1,392 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+const (
+	defaultSyncPeriod = 30 * time.Second
+
+	// PortForwardingGroup is the group of client certificates which are allowed to connect
+	// the tcp ports of port forwarding rules.
+	PortForwardingGroup = "openyurt:port-forwarding"
+)
+
+// PortForwardManager interface defines the method for forwarding tcp/udp ports of
+// tunnel server to the addresses in edge nodes.
+type PortForwardManager interface {
+	Run(stopCh <-chan struct{}, wg *sync.WaitGroup)
+}
+
+// portForwardManager listens on the ports configured in port forwarding rules of
+// yurt-tunnel-server-cfg configmap, and forwards the traffic through the tunnel.
+// only the addresses in rules can be accessed, so the cluster administrator controls
+// which edge addresses are exposed to the cloud. The tcp ports are served with mTLS and
+// only the clients in PortForwardingGroup are accepted, udp clients can't be authenticated,
+// so only the datagrams from udpAllowedSources are forwarded.
+type portForwardManager struct {
+	kubeClient        clientset.Interface
+	listenHost        string
+	udsSockFile       string
+	udpRelayAddr      string
+	tlsConfig         *tls.Config
+	udpAllowedSources []*net.IPNet
+	syncPeriod        time.Duration
+	forwarders        map[string]*forwarder
+	dialTunnelFor     func(nodeName, addr string) (net.Conn, error)
+}
+
+// NewPortForwardManager creates a PortForwardManager, the udp datagrams are sent to
+// the udp relay of tunnel agent, which is listening on udpRelayAddr of edge node. The
+// clients of tcp ports are verified by the client CAs of tlsCfg.
+func NewPortForwardManager(client clientset.Interface,
+	listenHost, udsSockFile, udpRelayAddr string, tlsCfg *tls.Config, udpAllowedSources []*net.IPNet) PortForwardManager {
+	tlsConfig := tlsCfg.Clone()
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	pm := &portForwardManager{
+		kubeClient:        client,
+		listenHost:        listenHost,
+		udsSockFile:       udsSockFile,
+		udpRelayAddr:      udpRelayAddr,
+		tlsConfig:         tlsConfig,
+		udpAllowedSources: udpAllowedSources,
+		syncPeriod:        defaultSyncPeriod,
+		forwarders:        make(map[string]*forwarder),
+	}
+	pm.dialTunnelFor = pm.dialTunnel
+	return pm
+}
+
+// Run starts the portForwardManager that will updates the forwarders periodically
+func (pm *portForwardManager) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	pm.syncForwarders()
+
+	ticker := time.NewTicker(pm.syncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("stop the portForwardManager")
+			for key, f := range pm.forwarders {
+				f.stop()
+				delete(pm.forwarders, key)
+			}
+			return
+		case <-ticker.C:
+			pm.syncForwarders()
+		}
+	}
+}
+
+func (pm *portForwardManager) getConfiguredRules() ([]Rule, error) {
+	cm, err := pm.kubeClient.CoreV1().
+		ConfigMaps(util.YurttunnelServerDnatConfigMapNs).
+		Get(context.Background(), util.YurttunnelServerDnatConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return []Rule{}, nil
+		}
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w",
+			util.YurttunnelServerDnatConfigMapNs,
+			util.YurttunnelServerDnatConfigMapName, err)
+	}
+	return resolveRules(cm.Data[portForwardingRulesKey]), nil
+}
+
+// syncForwarders stops the forwarders of removed or changed rules, and starts forwarders
+// for the new rules.
+func (pm *portForwardManager) syncForwarders() {
+	rules, err := pm.getConfiguredRules()
+	if err != nil {
+		klog.Errorf("failed to get port forwarding rules, %v", err)
+		return
+	}
+
+	desired := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		desired[rule.key()] = rule
+	}
+	for key, f := range pm.forwarders {
+		if rule, ok := desired[key]; !ok || rule != f.rule {
+			klog.Infof("stop forwarding for rule %s", f.rule.String())
+			f.stop()
+			delete(pm.forwarders, key)
+		}
+	}
+
+	for _, rule := range rules {
+		if _, ok := pm.forwarders[rule.key()]; ok {
+			continue
+		}
+		f, err := pm.startForwarder(rule)
+		if err != nil {
+			klog.Errorf("failed to start forwarding for rule %s, %v", rule.String(), err)
+			continue
+		}
+		klog.Infof("start forwarding for rule %s", rule.String())
+		pm.forwarders[rule.key()] = f
+	}
+}
+
+// dialTunnel sets up a connection to the addr through the tunnel agent on the node
+func (pm *portForwardManager) dialTunnel(nodeName, addr string) (net.Conn, error) {
+	return util.DialTunnel(pm.udsSockFile, nodeName, addr)
+}
+
+type forwarder struct {
+	rule     Rule
+	listener io.Closer
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func (f *forwarder) stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopCh)
+		f.listener.Close()
+	})
+}
+
+func (pm *portForwardManager) startForwarder(rule Rule) (*forwarder, error) {
+	addr := net.JoinHostPort(pm.listenHost, rule.ListenPort)
+	f := &forwarder{rule: rule, stopCh: make(chan struct{})}
+	switch rule.Protocol {
+	case ProtocolTCP:
+		listener, err := net.Listen(ProtocolTCP, addr)
+		if err != nil {
+			return nil, err
+		}
+		f.listener = listener
+		go pm.serveTCP(rule, tls.NewListener(listener, pm.tlsConfig))
+	case ProtocolUDP:
+		if len(pm.udpAllowedSources) == 0 {
+			return nil, fmt.Errorf("udp clients can't be authenticated, the allowed sources of udp datagrams should be configured")
+		}
+		conn, err := net.ListenPacket(ProtocolUDP, addr)
+		if err != nil {
+			return nil, err
+		}
+		f.listener = conn
+		go pm.serveUDP(rule, conn, f.stopCh)
+	default:
+		return nil, fmt.Errorf("protocol %s is not supported", rule.Protocol)
+	}
+	return f, nil
+}
+
+func (pm *portForwardManager) serveTCP(rule Rule, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				klog.Errorf("failed to accept connection for rule %s, %v", rule.String(), err)
+			}
+			return
+		}
+		go pm.forwardTCP(rule, conn)
+	}
+}
+
+func (pm *portForwardManager) forwardTCP(rule Rule, clientConn net.Conn) {
+	defer clientConn.Close()
+	if err := authorizeClient(clientConn); err != nil {
+		klog.Errorf("connection from %s for rule %s is rejected, %v", clientConn.RemoteAddr().String(), rule.String(), err)
+		return
+	}
+	tunnelConn, err := pm.dialTunnelFor(rule.NodeName, rule.TargetAddr)
+	if err != nil {
+		klog.Errorf("failed to setup the tunnel for rule %s, %v", rule.String(), err)
+		return
+	}
+	defer tunnelConn.Close()
+	klog.V(4).Infof("forwarding connection from %s for rule %s", clientConn.RemoteAddr().String(), rule.String())
+
+	// start bidirectional connection proxy, so start two goroutines
+	// to copy in each direction.
+	readerComplete, writerComplete := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, err := io.Copy(tunnelConn, clientConn)
+		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			klog.Errorf("error forwarding data from client to tunnel: %v", err)
+		}
+		close(writerComplete)
+	}()
+
+	go func() {
+		_, err := io.Copy(clientConn, tunnelConn)
+		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			klog.Errorf("error forwarding data from tunnel to client: %v", err)
+		}
+		close(readerComplete)
+	}()
+
+	select {
+	case <-writerComplete:
+	case <-readerComplete:
+	}
+}
+
+// authorizeClient verifies the client certificate of connection is in PortForwardingGroup
+func authorizeClient(conn net.Conn) error {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return fmt.Errorf("connection is not tls")
+	}
+	tlsConn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return fmt.Errorf("no client certificate")
+	}
+	for _, org := range certs[0].Subject.Organization {
+		if org == PortForwardingGroup {
+			return nil
+		}
+	}
+	return fmt.Errorf("client %s is not in group %s", certs[0].Subject.CommonName, PortForwardingGroup)
+}
+
+// allowsSource checks the udp datagrams from addr can be forwarded or not
+func (pm *portForwardManager) allowsSource(addr net.Addr) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	for _, cidr := range pm.udpAllowedSources {
+		if cidr.Contains(udpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveUDP forwards the datagrams of every client address through its own udp session,
+// the session is closed after it is idle in both directions for udpSessionIdleTimeout.
+func (pm *portForwardManager) serveUDP(rule Rule, conn net.PacketConn, stopCh <-chan struct{}) {
+	var mu sync.Mutex
+	sessions := make(map[string]*udpSession)
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, session := range sessions {
+			session.Close()
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, clientAddr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-stopCh:
+			default:
+				klog.Errorf("failed to read datagram for rule %s, %v", rule.String(), err)
+			}
+			return
+		}
+		if !pm.allowsSource(clientAddr) {
+			klog.V(4).Infof("drop datagram from %s for rule %s, the source is not allowed", clientAddr.String(), rule.String())
+			continue
+		}
+
+		mu.Lock()
+		s, ok := sessions[clientAddr.String()]
+		if !ok {
+			session, err := pm.newUDPSession(rule)
+			if err != nil {
+				mu.Unlock()
+				klog.Errorf("failed to setup udp session for rule %s, %v", rule.String(), err)
+				continue
+			}
+			s = &udpSession{Conn: session, idle: newIdleTimer(func() { session.Close() })}
+			sessions[clientAddr.String()] = s
+			go func(s *udpSession, clientAddr net.Addr) {
+				pm.replyUDP(s, conn, clientAddr)
+				mu.Lock()
+				delete(sessions, clientAddr.String())
+				mu.Unlock()
+				s.Close()
+			}(s, clientAddr)
+		}
+		mu.Unlock()
+
+		s.idle.touch()
+		if err := writeDatagram(s, buf[:n]); err != nil {
+			klog.Errorf("failed to forward datagram from %s for rule %s, %v", clientAddr.String(), rule.String(), err)
+			s.Close()
+		}
+	}
+}
+
+// udpSession is the tunnel connection to the udp relay for a client address
+type udpSession struct {
+	net.Conn
+	idle *idleTimer
+}
+
+func (s *udpSession) Close() error {
+	s.idle.stop()
+	return s.Conn.Close()
+}
+
+func (pm *portForwardManager) newUDPSession(rule Rule) (net.Conn, error) {
+	session, err := pm.dialTunnelFor(rule.NodeName, pm.udpRelayAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeRelayHeader(session, rule.TargetAddr); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// replyUDP sends the datagrams from udp session back to client until the session is closed or idle.
+func (pm *portForwardManager) replyUDP(s *udpSession, conn net.PacketConn, clientAddr net.Addr) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := readDatagram(s, buf)
+		if err != nil {
+			return
+		}
+		s.idle.touch()
+		if _, err := conn.WriteTo(buf[:n], clientAddr); err != nil {
+			return
+		}
+	}
+}
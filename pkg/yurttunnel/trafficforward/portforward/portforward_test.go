@@ -0,0 +1,261 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T, network string) string {
+	var addr net.Addr
+	switch network {
+	case ProtocolTCP:
+		l, err := net.Listen(network, "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen, %v", err)
+		}
+		addr = l.Addr()
+		l.Close()
+	default:
+		c, err := net.ListenPacket(network, "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen, %v", err)
+		}
+		addr = c.LocalAddr()
+		c.Close()
+	}
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
+
+// newTestCert returns a certificate issued by parent, or a self-signed CA if parent is nil.
+func newTestCert(t *testing.T, cn string, orgs []string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: orgs},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// newTestTLSConfigs returns the tls config of server and the client tls config with organizations
+func newTestTLSConfigs(t *testing.T, orgs []string) (*tls.Config, *tls.Config) {
+	ca, caKey := newTestCert(t, "kubernetes", nil, nil, nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	serverCert, serverKey := newTestCert(t, "tunnel-server", nil, ca, caKey)
+	clientCert, clientKey := newTestCert(t, "alice", orgs, ca, caKey)
+	serverCfg := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientCAs:    pool,
+	}
+	clientCfg := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}},
+		RootCAs:      pool,
+	}
+	return serverCfg, clientCfg
+}
+
+// newTestManager creates a manager which dials the address directly instead of through the tunnel
+func newTestManager(udpRelayAddr string, tlsCfg *tls.Config) *portForwardManager {
+	pm := NewPortForwardManager(nil, "127.0.0.1", "", udpRelayAddr, tlsCfg, []*net.IPNet{{IP: net.ParseIP("127.0.0.0"), Mask: net.CIDRMask(8, 32)}}).(*portForwardManager)
+	pm.dialTunnelFor = func(nodeName, addr string) (net.Conn, error) {
+		return net.Dial(ProtocolTCP, addr)
+	}
+	return pm
+}
+
+func TestForwardTCP(t *testing.T) {
+	target, err := net.Listen(ProtocolTCP, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen, %v", err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	testcases := map[string]struct {
+		orgs   []string
+		expect bool
+	}{
+		"client in port forwarding group": {
+			orgs:   []string{PortForwardingGroup},
+			expect: true,
+		},
+		"client in other group": {
+			orgs: []string{"system:nodes"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			serverCfg, clientCfg := newTestTLSConfigs(t, tc.orgs)
+			pm := newTestManager("", serverCfg)
+			rule := Rule{Protocol: ProtocolTCP, ListenPort: freePort(t, ProtocolTCP), NodeName: "edge-node-1", TargetAddr: target.Addr().String()}
+			f, err := pm.startForwarder(rule)
+			if err != nil {
+				t.Fatalf("failed to start forwarder, %v", err)
+			}
+			defer f.stop()
+
+			// plain tcp clients are not accepted
+			plain, err := net.Dial(ProtocolTCP, net.JoinHostPort("127.0.0.1", rule.ListenPort))
+			if err != nil {
+				t.Fatalf("failed to dial forwarder, %v", err)
+			}
+			plain.SetDeadline(time.Now().Add(10 * time.Second))
+			plain.Write([]byte("plain text"))
+			if _, err := io.ReadFull(plain, make([]byte, 10)); err == nil {
+				t.Errorf("expect plain tcp client is rejected")
+			}
+			plain.Close()
+
+			conn, err := tls.Dial(ProtocolTCP, net.JoinHostPort("127.0.0.1", rule.ListenPort), clientCfg)
+			if err != nil {
+				t.Fatalf("failed to dial forwarder, %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(10 * time.Second))
+			msg := []byte("read holding registers")
+			if _, err := conn.Write(msg); err != nil {
+				t.Fatalf("failed to write, %v", err)
+			}
+			buf := make([]byte, len(msg))
+			_, err = io.ReadFull(conn, buf)
+			if !tc.expect {
+				if err == nil {
+					t.Errorf("expect client is rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to read, %v", err)
+			}
+			if string(buf) != string(msg) {
+				t.Errorf("expect %q, but got %q", msg, buf)
+			}
+		})
+	}
+}
+
+func TestForwardUDP(t *testing.T) {
+	echo, err := net.ListenPacket(ProtocolUDP, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen udp, %v", err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	relayAddr := net.JoinHostPort("127.0.0.1", freePort(t, ProtocolTCP))
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := RunUDPRelay(relayAddr, nil, stopCh); err != nil {
+		t.Fatalf("failed to run udp relay, %v", err)
+	}
+
+	serverCfg, _ := newTestTLSConfigs(t, nil)
+	pm := newTestManager(relayAddr, serverCfg)
+	rule := Rule{Protocol: ProtocolUDP, ListenPort: freePort(t, ProtocolUDP), NodeName: "edge-node-1", TargetAddr: echo.LocalAddr().String()}
+	f, err := pm.startForwarder(rule)
+	if err != nil {
+		t.Fatalf("failed to start forwarder, %v", err)
+	}
+	defer f.stop()
+
+	conn, err := net.Dial(ProtocolUDP, net.JoinHostPort("127.0.0.1", rule.ListenPort))
+	if err != nil {
+		t.Fatalf("failed to dial forwarder, %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	buf := make([]byte, maxDatagramSize)
+	for i := 0; i < 3; i++ {
+		msg := "trap " + strconv.Itoa(i)
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatalf("failed to write, %v", err)
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("failed to read, %v", err)
+		}
+		if string(buf[:n]) != msg {
+			t.Errorf("expect %q, but got %q", msg, buf[:n])
+		}
+	}
+}
+
+func TestForwardUDPWithoutAllowedSources(t *testing.T) {
+	serverCfg, _ := newTestTLSConfigs(t, nil)
+	pm := newTestManager("", serverCfg)
+	pm.udpAllowedSources = nil
+	rule := Rule{Protocol: ProtocolUDP, ListenPort: freePort(t, ProtocolUDP), NodeName: "edge-node-1", TargetAddr: "127.0.0.1:161"}
+	if f, err := pm.startForwarder(rule); err == nil {
+		f.stop()
+		t.Errorf("expect udp rule is not served without allowed sources")
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+const (
+	// portForwardingRulesKey is the field of yurt-tunnel-server-cfg configmap for port forwarding rules,
+	// the rules are separated by comma, and the format of rule is {protocol}:{listen port}={node name}/{target address},
+	// e.g. udp:10161=edge-node-1/192.168.1.10:161,tcp:10502=edge-node-1/127.0.0.1:502
+	portForwardingRulesKey = "port-forwarding-rules"
+
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// Rule forwards the traffic received on the listen port of tunnel server to the
+// target address through the tunnel agent on the node.
+type Rule struct {
+	Protocol   string
+	ListenPort string
+	NodeName   string
+	TargetAddr string
+}
+
+// String returns the rule in the same format of configmap
+func (r Rule) String() string {
+	return fmt.Sprintf("%s:%s=%s/%s", r.Protocol, r.ListenPort, r.NodeName, r.TargetAddr)
+}
+
+// key returns the identity of listener used by the rule
+func (r Rule) key() string {
+	return r.Protocol + ":" + r.ListenPort
+}
+
+// resolveRules parses the port forwarding rules, invalid rules and rules conflicted
+// with former rules are skipped.
+func resolveRules(rulesStr string) []Rule {
+	rules := make([]Rule, 0)
+	if len(strings.TrimSpace(rulesStr)) == 0 {
+		return rules
+	}
+
+	listeners := make(map[string]struct{})
+	for _, ruleStr := range strings.Split(rulesStr, util.PortsSeparator) {
+		ruleStr = strings.TrimSpace(ruleStr)
+		if len(ruleStr) == 0 {
+			continue
+		}
+		rule, err := parseRule(ruleStr)
+		if err != nil {
+			klog.Errorf("failed to parse port forwarding rule %s, %v", ruleStr, err)
+			continue
+		}
+		if _, ok := listeners[rule.key()]; ok {
+			klog.Errorf("port forwarding rule %s is skipped, %s port %s is used by other rule", ruleStr, rule.Protocol, rule.ListenPort)
+			continue
+		}
+		listeners[rule.key()] = struct{}{}
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].key() < rules[j].key()
+	})
+	return rules
+}
+
+func parseRule(ruleStr string) (Rule, error) {
+	var rule Rule
+	parts := strings.Split(ruleStr, util.PortPairSeparator)
+	if len(parts) != 2 {
+		return rule, fmt.Errorf("rule should be in format {protocol}:{listen port}={node name}/{target address}")
+	}
+
+	listen := strings.Split(strings.TrimSpace(parts[0]), ":")
+	if len(listen) != 2 {
+		return rule, fmt.Errorf("listen part %q should be in format {protocol}:{listen port}", parts[0])
+	}
+	rule.Protocol = strings.ToLower(strings.TrimSpace(listen[0]))
+	if rule.Protocol != ProtocolTCP && rule.Protocol != ProtocolUDP {
+		return rule, fmt.Errorf("protocol %q is not supported, only tcp and udp are supported", listen[0])
+	}
+	rule.ListenPort = strings.TrimSpace(listen[1])
+	if err := validatePort(rule.ListenPort); err != nil {
+		return rule, err
+	}
+
+	target := strings.SplitN(strings.TrimSpace(parts[1]), "/", 2)
+	if len(target) != 2 || len(target[0]) == 0 {
+		return rule, fmt.Errorf("target part %q should be in format {node name}/{target address}", parts[1])
+	}
+	rule.NodeName = target[0]
+	rule.TargetAddr = target[1]
+	host, port, err := net.SplitHostPort(rule.TargetAddr)
+	if err != nil {
+		return rule, fmt.Errorf("target address %q is invalid, %w", rule.TargetAddr, err)
+	}
+	if len(host) == 0 {
+		return rule, fmt.Errorf("host of target address %q is empty", rule.TargetAddr)
+	}
+	if err := validatePort(port); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func validatePort(port string) error {
+	portInt, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("failed to parse port %s, %w", port, err)
+	}
+	if portInt < util.MinPort || portInt > util.MaxPort {
+		return fmt.Errorf("port %s is an invalid port(should be range 1~65535)", port)
+	}
+	return nil
+}
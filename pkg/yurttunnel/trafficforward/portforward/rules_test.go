@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveRules(t *testing.T) {
+	testcases := map[string]struct {
+		rules  string
+		expect []Rule
+	}{
+		"empty rules": {
+			rules:  " ",
+			expect: []Rule{},
+		},
+		"tcp and udp rules": {
+			rules: "udp:10161=edge-node-1/192.168.1.10:161, TCP:10502=edge-node-2/127.0.0.1:502",
+			expect: []Rule{
+				{Protocol: ProtocolTCP, ListenPort: "10502", NodeName: "edge-node-2", TargetAddr: "127.0.0.1:502"},
+				{Protocol: ProtocolUDP, ListenPort: "10161", NodeName: "edge-node-1", TargetAddr: "192.168.1.10:161"},
+			},
+		},
+		"ipv6 target address": {
+			rules: "tcp:10502=edge-node-1/[fd00::10]:502",
+			expect: []Rule{
+				{Protocol: ProtocolTCP, ListenPort: "10502", NodeName: "edge-node-1", TargetAddr: "[fd00::10]:502"},
+			},
+		},
+		"same port with different protocols": {
+			rules: "udp:10514=edge-node-1/127.0.0.1:514,tcp:10514=edge-node-1/127.0.0.1:514",
+			expect: []Rule{
+				{Protocol: ProtocolTCP, ListenPort: "10514", NodeName: "edge-node-1", TargetAddr: "127.0.0.1:514"},
+				{Protocol: ProtocolUDP, ListenPort: "10514", NodeName: "edge-node-1", TargetAddr: "127.0.0.1:514"},
+			},
+		},
+		"conflicted rules": {
+			rules: "udp:10161=edge-node-1/192.168.1.10:161,udp:10161=edge-node-2/192.168.1.11:161",
+			expect: []Rule{
+				{Protocol: ProtocolUDP, ListenPort: "10161", NodeName: "edge-node-1", TargetAddr: "192.168.1.10:161"},
+			},
+		},
+		"invalid rules": {
+			rules: "sctp:10161=edge-node-1/192.168.1.10:161,udp:70000=edge-node-1/192.168.1.10:161," +
+				"udp:10161=192.168.1.10:161,udp:10162=edge-node-1/192.168.1.10,tcp:10502=edge-node-1/:502,tcp:10503",
+			expect: []Rule{},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			rules := resolveRules(tc.rules)
+			if !reflect.DeepEqual(rules, tc.expect) {
+				t.Errorf("expect rules %v, but got %v", tc.expect, rules)
+			}
+		})
+	}
+}
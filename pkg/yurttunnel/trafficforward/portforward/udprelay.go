@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const maxDatagramSize = 65535
+
+// udpSessionIdleTimeout is the timeout of udp session without any datagram in either direction
+var udpSessionIdleTimeout = 2 * time.Minute
+
+// idleTimer calls onIdle when the session has no datagram in either direction for udpSessionIdleTimeout,
+// so the sessions with datagrams in one direction only, like snmp traps, are kept.
+type idleTimer struct {
+	sync.Mutex
+	lastActive time.Time
+	timer      *time.Timer
+}
+
+func newIdleTimer(onIdle func()) *idleTimer {
+	t := &idleTimer{lastActive: time.Now()}
+	t.Lock()
+	defer t.Unlock()
+	t.timer = time.AfterFunc(udpSessionIdleTimeout, func() {
+		t.Lock()
+		defer t.Unlock()
+		if idle := time.Since(t.lastActive); idle < udpSessionIdleTimeout {
+			t.timer.Reset(udpSessionIdleTimeout - idle)
+			return
+		}
+		go onIdle()
+	})
+	return t
+}
+
+// touch records a datagram of the session
+func (t *idleTimer) touch() {
+	t.Lock()
+	defer t.Unlock()
+	t.lastActive = time.Now()
+}
+
+func (t *idleTimer) stop() {
+	t.Lock()
+	defer t.Unlock()
+	t.timer.Stop()
+}
+
+// writeDatagram writes the datagram into the stream with a 2 bytes length prefix,
+// so the datagram boundaries are kept in the tunnel.
+func writeDatagram(w io.Writer, datagram []byte) error {
+	if len(datagram) > maxDatagramSize {
+		return fmt.Errorf("datagram size %d exceeds %d", len(datagram), maxDatagramSize)
+	}
+	buf := make([]byte, 2+len(datagram))
+	binary.BigEndian.PutUint16(buf, uint16(len(datagram)))
+	copy(buf[2:], datagram)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readDatagram reads a datagram written by writeDatagram from the stream.
+func readDatagram(r io.Reader, buf []byte) (int, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(size[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("datagram size %d exceeds buffer size %d", n, len(buf))
+	}
+	return io.ReadFull(r, buf[:n])
+}
+
+// writeRelayHeader tells the udp relay the target address of session.
+func writeRelayHeader(w io.Writer, targetAddr string) error {
+	_, err := fmt.Fprintf(w, "%s\n", targetAddr)
+	return err
+}
+
+// RunUDPRelay starts the udp relay of tunnel agent, which receives the udp sessions
+// from tunnel server through the tunnel and sends the datagrams to target addresses,
+// because only tcp connections are supported by the tunnel. the target addresses are
+// checked by checkTarget if it's not nil.
+func RunUDPRelay(addr string, checkTarget func(protocol, addr string) error, stopCh <-chan struct{}) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("fail to listen udp relay on %s: %w", addr, err)
+	}
+	klog.Infof("start handling udp relay sessions at %s", addr)
+
+	go func() {
+		<-stopCh
+		listener.Close()
+	}()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !strings.Contains(err.Error(), "use of closed network connection") {
+					klog.Errorf("udp relay fail to accept connection, %v", err)
+				}
+				return
+			}
+			go serveRelaySession(conn, checkTarget)
+		}
+	}()
+	return nil
+}
+
+// serveRelaySession relays the datagrams between the session and target address until
+// the session is closed or idle for udpSessionIdleTimeout.
+func serveRelaySession(conn net.Conn, checkTarget func(protocol, addr string) error) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(udpSessionIdleTimeout))
+	targetAddr, err := br.ReadString('\n')
+	if err != nil {
+		klog.Errorf("udp relay fail to read target address, %v", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	targetAddr = strings.TrimSpace(targetAddr)
+	if checkTarget != nil {
+		if err := checkTarget(ProtocolUDP, targetAddr); err != nil {
+			klog.Errorf("udp relay session to %s is rejected, %v", targetAddr, err)
+			return
+		}
+	}
+
+	udpConn, err := net.Dial(ProtocolUDP, targetAddr)
+	if err != nil {
+		klog.Errorf("udp relay fail to dial %s, %v", targetAddr, err)
+		return
+	}
+	defer udpConn.Close()
+	klog.V(2).Infof("udp relay session to %s is started", targetAddr)
+	idle := newIdleTimer(func() {
+		conn.Close()
+		udpConn.Close()
+	})
+	defer idle.stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, err := udpConn.Read(buf)
+			if err != nil {
+				return
+			}
+			idle.touch()
+			if err := writeDatagram(conn, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := readDatagram(br, buf)
+		if err != nil {
+			break
+		}
+		idle.touch()
+		if _, err := udpConn.Write(buf[:n]); err != nil {
+			klog.Errorf("udp relay fail to send datagram to %s, %v", targetAddr, err)
+			break
+		}
+	}
+	udpConn.Close()
+	<-done
+	klog.V(2).Infof("udp relay session to %s is stopped", targetAddr)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portforward
+
+import (
+	"bytes"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDatagramFraming(t *testing.T) {
+	var stream bytes.Buffer
+	datagrams := [][]byte{[]byte("first"), {}, bytes.Repeat([]byte("x"), 1500)}
+	for _, d := range datagrams {
+		if err := writeDatagram(&stream, d); err != nil {
+			t.Fatalf("failed to write datagram, %v", err)
+		}
+	}
+	if err := writeDatagram(&stream, make([]byte, maxDatagramSize+1)); err == nil {
+		t.Errorf("expect error for oversized datagram")
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	for _, d := range datagrams {
+		n, err := readDatagram(&stream, buf)
+		if err != nil {
+			t.Fatalf("failed to read datagram, %v", err)
+		}
+		if !bytes.Equal(buf[:n], d) {
+			t.Errorf("expect datagram %q, but got %q", d, buf[:n])
+		}
+	}
+}
+
+func TestUDPRelay(t *testing.T) {
+	// udp echo server as the edge address
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen udp, %v", err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen tcp, %v", err)
+	}
+	relayAddr := listener.Addr().String()
+	listener.Close()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := RunUDPRelay(relayAddr, nil, stopCh); err != nil {
+		t.Fatalf("failed to run udp relay, %v", err)
+	}
+
+	session, err := net.Dial("tcp", relayAddr)
+	if err != nil {
+		t.Fatalf("failed to dial udp relay, %v", err)
+	}
+	defer session.Close()
+	session.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := writeRelayHeader(session, echo.LocalAddr().String()); err != nil {
+		t.Fatalf("failed to write relay header, %v", err)
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	for _, msg := range []string{"get sysName", "get sysUpTime"} {
+		if err := writeDatagram(session, []byte(msg)); err != nil {
+			t.Fatalf("failed to write datagram, %v", err)
+		}
+		n, err := readDatagram(session, buf)
+		if err != nil {
+			t.Fatalf("failed to read datagram, %v", err)
+		}
+		if string(buf[:n]) != msg {
+			t.Errorf("expect datagram %q, but got %q", msg, buf[:n])
+		}
+	}
+}
+
+func TestIdleTimer(t *testing.T) {
+	defer func(timeout time.Duration) {
+		udpSessionIdleTimeout = timeout
+	}(udpSessionIdleTimeout)
+	udpSessionIdleTimeout = 200 * time.Millisecond
+
+	var idled int32
+	timer := newIdleTimer(func() {
+		atomic.StoreInt32(&idled, 1)
+	})
+	defer timer.stop()
+
+	// datagrams in one direction keep the session active
+	for i := 0; i < 10; i++ {
+		time.Sleep(50 * time.Millisecond)
+		timer.touch()
+	}
+	if atomic.LoadInt32(&idled) != 0 {
+		t.Fatalf("expect active session is not idle")
+	}
+
+	time.Sleep(time.Second)
+	if atomic.LoadInt32(&idled) != 1 {
+		t.Errorf("expect session is idle after no datagram for the timeout")
+	}
+}
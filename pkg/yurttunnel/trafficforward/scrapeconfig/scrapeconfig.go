@@ -0,0 +1,340 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scrapeconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	corelister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+const (
+	defaultSyncPeriod = 30 * time.Second
+
+	// the annotations of services which are scraped by prometheus
+	prometheusScrapeAnnotation = "prometheus.io/scrape"
+	prometheusPortAnnotation   = "prometheus.io/port"
+	prometheusSchemeAnnotation = "prometheus.io/scheme"
+
+	// scrapedServiceIndex indexes the services annotated with prometheus.io/scrape=true
+	scrapedServiceIndex = "scraped"
+)
+
+// ScrapeConfigManager interface defines the method for maintaining the proxy ports which are
+// required by metrics-server and prometheus to scrape the edge nodes through the tunnel.
+type ScrapeConfigManager interface {
+	Run(stopCh <-chan struct{}, wg *sync.WaitGroup)
+}
+
+// scrapeConfigManager resolves the metrics ports served by the edge nodes, including the kubelet
+// ports which are not the default one and the ports of host network exporters(like node-exporter)
+// behind the services annotated with prometheus.io/scrape, and records them in the scrape proxy
+// ports of yurt-tunnel-server-cfg configmap. then the dnat rules and dns records for these ports
+// are set up by iptables manager and dns controller as the manually configured proxy ports.
+type scrapeConfigManager struct {
+	kubeClient  clientset.Interface
+	nodeLister  corelister.NodeLister
+	nodeSynced  cache.InformerSynced
+	svcInformer cache.SharedIndexInformer
+	svcSynced   cache.InformerSynced
+	syncPeriod  time.Duration
+	changed     chan struct{}
+}
+
+// NewScrapeConfigManager creates a ScrapeConfigManager, the proxy ports are synced periodically
+// and whenever an edge node joins or leaves the cluster or a scraped service is changed.
+func NewScrapeConfigManager(client clientset.Interface, nodeInformer coreinformers.NodeInformer) ScrapeConfigManager {
+	// the service informer of the shared informer factory only watches the services of tunnel server,
+	// so the scraped services are indexed by an informer of their own.
+	svcInformer := coreinformers.NewServiceInformer(client, metav1.NamespaceAll, 0, cache.Indexers{
+		scrapedServiceIndex: indexScrapedService,
+	})
+	sm := &scrapeConfigManager{
+		kubeClient:  client,
+		nodeLister:  nodeInformer.Lister(),
+		nodeSynced:  nodeInformer.Informer().HasSynced,
+		svcInformer: svcInformer,
+		svcSynced:   svcInformer.HasSynced,
+		syncPeriod:  defaultSyncPeriod,
+		changed:     make(chan struct{}, 1),
+	}
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*corev1.Node); ok && isEdgeNode(node) {
+				sm.notifyChanged()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+			if isEdgeNode(oldNode) != isEdgeNode(newNode) ||
+				kubeletPort(oldNode) != kubeletPort(newNode) {
+				sm.notifyChanged()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			sm.notifyChanged()
+		},
+	})
+	svcInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if isScrapedService(obj) {
+				sm.notifyChanged()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSvc, ok := oldObj.(*corev1.Service)
+			if !ok {
+				return
+			}
+			newSvc, ok := newObj.(*corev1.Service)
+			if !ok {
+				return
+			}
+			if (isScrapedService(oldSvc) || isScrapedService(newSvc)) &&
+				!reflect.DeepEqual(oldSvc.Annotations, newSvc.Annotations) {
+				sm.notifyChanged()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if isScrapedService(obj) {
+				sm.notifyChanged()
+			}
+		},
+	})
+	return sm
+}
+
+// notifyChanged never blocks the informer, the pending notifications are merged into one sync.
+func (sm *scrapeConfigManager) notifyChanged() {
+	select {
+	case sm.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs the scrape proxy ports until stopCh is closed
+func (sm *scrapeConfigManager) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	go sm.svcInformer.Run(stopCh)
+	if !cache.WaitForNamedCacheSync("tunnel-scrape-config", stopCh, sm.nodeSynced, sm.svcSynced) {
+		return
+	}
+	sm.syncScrapePorts()
+
+	ticker := time.NewTicker(sm.syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("stop the scrape config manager")
+			return
+		case <-ticker.C:
+			sm.syncScrapePorts()
+		case <-sm.changed:
+			sm.syncScrapePorts()
+		}
+	}
+}
+
+func (sm *scrapeConfigManager) syncScrapePorts() {
+	httpPorts, httpsPorts, err := sm.resolveScrapePorts()
+	if err != nil {
+		klog.Errorf("failed to resolve scrape ports of edge nodes, %v", err)
+		return
+	}
+	if err := sm.updateScrapePorts(httpPorts, httpsPorts); err != nil {
+		klog.Errorf("failed to update scrape ports, %v", err)
+	}
+}
+
+// resolveScrapePorts returns the http and https metrics ports served by the edge nodes.
+func (sm *scrapeConfigManager) resolveScrapePorts() (sets.String, sets.String, error) {
+	httpPorts, httpsPorts := sets.NewString(), sets.NewString()
+	nodes, err := sm.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	edgeNodes := make(map[string]*corev1.Node)
+	for _, node := range nodes {
+		if !isEdgeNode(node) {
+			continue
+		}
+		edgeNodes[node.Name] = node
+		// metrics-server scrapes the kubelet port in node status
+		if port := kubeletPort(node); port != 0 {
+			httpsPorts.Insert(strconv.Itoa(int(port)))
+		}
+	}
+	if len(edgeNodes) == 0 {
+		return httpPorts, httpsPorts, nil
+	}
+
+	svcs, err := sm.svcInformer.GetIndexer().ByIndex(scrapedServiceIndex, "true")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list scraped services, %w", err)
+	}
+	for _, obj := range svcs {
+		svc, ok := obj.(*corev1.Service)
+		if !ok {
+			continue
+		}
+		eps, err := sm.kubeClient.CoreV1().Endpoints(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to get endpoints %s/%s, %w", svc.Namespace, svc.Name, err)
+		}
+		ports := httpPorts
+		if strings.EqualFold(svc.Annotations[prometheusSchemeAnnotation], "https") {
+			ports = httpsPorts
+		}
+		ports.Insert(scrapePortsOnEdge(svc, eps, edgeNodes)...)
+	}
+	return httpPorts, httpsPorts, nil
+}
+
+// scrapePortsOnEdge returns the scraped ports of the endpoints which are served by the host network
+// of edge nodes, the endpoints with pod ips are reached through raven instead of the tunnel.
+func scrapePortsOnEdge(svc *corev1.Service, eps *corev1.Endpoints, edgeNodes map[string]*corev1.Node) []string {
+	onEdge := false
+	var endpointPorts []string
+	for _, subset := range eps.Subsets {
+		for _, addr := range subset.Addresses {
+			if addr.NodeName == nil {
+				continue
+			}
+			if node, ok := edgeNodes[*addr.NodeName]; ok && isNodeAddress(node, addr.IP) {
+				onEdge = true
+				break
+			}
+		}
+		if !onEdge {
+			continue
+		}
+		for _, port := range subset.Ports {
+			if port.Protocol == "" || port.Protocol == corev1.ProtocolTCP {
+				endpointPorts = append(endpointPorts, strconv.Itoa(int(port.Port)))
+			}
+		}
+		break
+	}
+	if !onEdge {
+		return nil
+	}
+
+	if annotated := svc.Annotations[prometheusPortAnnotation]; annotated != "" {
+		if port, err := strconv.Atoi(annotated); err != nil || port < util.MinPort || port > util.MaxPort {
+			klog.Errorf("annotation %s=%s of service %s/%s is invalid", prometheusPortAnnotation, annotated, svc.Namespace, svc.Name)
+			return nil
+		}
+		return []string{annotated}
+	}
+	return endpointPorts
+}
+
+// updateScrapePorts records the ports in yurt-tunnel-server-cfg configmap if they are changed.
+func (sm *scrapeConfigManager) updateScrapePorts(httpPorts, httpsPorts sets.String) error {
+	// the kubelet ports are always proxied
+	httpsPorts.Delete(util.KubeletHTTPSPort, util.KubeletHTTPPort)
+	httpPorts.Delete(util.KubeletHTTPSPort, util.KubeletHTTPPort)
+	httpValue := strings.Join(httpPorts.List(), util.PortsSeparator)
+	httpsValue := strings.Join(httpsPorts.List(), util.PortsSeparator)
+
+	cm, err := sm.kubeClient.CoreV1().
+		ConfigMaps(util.YurttunnelServerDnatConfigMapNs).
+		Get(context.Background(), util.YurttunnelServerDnatConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get configmap %s/%s: %w",
+			util.YurttunnelServerDnatConfigMapNs,
+			util.YurttunnelServerDnatConfigMapName, err)
+	}
+	if cm.Data[util.ScrapeHTTPProxyPorts] == httpValue && cm.Data[util.ScrapeHTTPSProxyPorts] == httpsValue {
+		return nil
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[util.ScrapeHTTPProxyPorts] = httpValue
+	cm.Data[util.ScrapeHTTPSProxyPorts] = httpsValue
+	if _, err := sm.kubeClient.CoreV1().ConfigMaps(util.YurttunnelServerDnatConfigMapNs).Update(context.Background(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update configmap %s/%s: %w",
+			util.YurttunnelServerDnatConfigMapNs,
+			util.YurttunnelServerDnatConfigMapName, err)
+	}
+	klog.Infof("scrape ports are updated, http: %q, https: %q", httpValue, httpsValue)
+	return nil
+}
+
+// indexScrapedService indexes the services annotated with prometheus.io/scrape=true by "true".
+func indexScrapedService(obj interface{}) ([]string, error) {
+	if !isScrapedService(obj) {
+		return nil, nil
+	}
+	return []string{"true"}, nil
+}
+
+func isScrapedService(obj interface{}) bool {
+	svc, ok := obj.(*corev1.Service)
+	return ok && svc.Annotations[prometheusScrapeAnnotation] == "true"
+}
+
+func isEdgeNode(node *corev1.Node) bool {
+	return node.Labels[projectinfo.GetEdgeWorkerLabelKey()] == "true"
+}
+
+func kubeletPort(node *corev1.Node) int32 {
+	return node.Status.DaemonEndpoints.KubeletEndpoint.Port
+}
+
+func isNodeAddress(node *corev1.Node, ip string) bool {
+	for _, addr := range node.Status.Addresses {
+		if addr.Address == ip {
+			return true
+		}
+	}
+	return false
+}
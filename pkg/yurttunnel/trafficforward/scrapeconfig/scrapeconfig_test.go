@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scrapeconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openyurtio/openyurt/pkg/projectinfo"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+func newNode(name, ip string, edge bool, kubeletPort int32) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{},
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{Port: kubeletPort},
+			},
+		},
+	}
+	if edge {
+		node.Labels[projectinfo.GetEdgeWorkerLabelKey()] = "true"
+	}
+	return node
+}
+
+func newScrapedService(name string, annotations map[string]string, port int32, addrs map[string]string) (*corev1.Service, *corev1.Endpoints) {
+	annotations[prometheusScrapeAnnotation] = "true"
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "monitoring", Annotations: annotations},
+	}
+	subset := corev1.EndpointSubset{
+		Ports: []corev1.EndpointPort{{Port: port, Protocol: corev1.ProtocolTCP}},
+	}
+	for ip, nodeName := range addrs {
+		nodeName := nodeName
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: ip, NodeName: &nodeName})
+	}
+	eps := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "monitoring"},
+		Subsets:    []corev1.EndpointSubset{subset},
+	}
+	return svc, eps
+}
+
+func TestSyncScrapePorts(t *testing.T) {
+	nodeExporterSvc, nodeExporterEps := newScrapedService("node-exporter", map[string]string{}, 9100,
+		map[string]string{"192.168.1.1": "edge-node", "10.0.0.1": "cloud-node"})
+	httpsExporterSvc, httpsExporterEps := newScrapedService("https-exporter", map[string]string{
+		prometheusSchemeAnnotation: "https",
+		prometheusPortAnnotation:   "9443",
+	}, 9443, map[string]string{"192.168.1.1": "edge-node"})
+	// pod ips on edge nodes are not reached through the tunnel
+	podSvc, podEps := newScrapedService("pod-exporter", map[string]string{}, 8080,
+		map[string]string{"172.16.0.5": "edge-node"})
+	cloudSvc, cloudEps := newScrapedService("cloud-exporter", map[string]string{}, 9200,
+		map[string]string{"10.0.0.1": "cloud-node"})
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.YurttunnelServerDnatConfigMapName,
+			Namespace: util.YurttunnelServerDnatConfigMapNs,
+		},
+		Data: map[string]string{"http-proxy-ports": "9300"},
+	}
+
+	client := fake.NewSimpleClientset(
+		newNode("edge-node", "192.168.1.1", true, 10350),
+		newNode("edge-node-default", "192.168.1.2", true, 10250),
+		newNode("cloud-node", "10.0.0.1", false, 10351),
+		nodeExporterSvc, nodeExporterEps,
+		httpsExporterSvc, httpsExporterEps,
+		podSvc, podEps,
+		cloudSvc, cloudEps,
+		cm,
+	)
+	factory := informers.NewSharedInformerFactory(client, 0)
+	sm := NewScrapeConfigManager(client, factory.Core().V1().Nodes()).(*scrapeConfigManager)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	go sm.svcInformer.Run(stopCh)
+	cache.WaitForCacheSync(stopCh, sm.svcSynced)
+
+	sm.syncScrapePorts()
+	got, err := client.CoreV1().ConfigMaps(cm.Namespace).Get(context.Background(), cm.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get configmap, %v", err)
+	}
+	if got.Data[util.ScrapeHTTPProxyPorts] != "9100" {
+		t.Errorf("expect scrape http ports 9100, but got %q", got.Data[util.ScrapeHTTPProxyPorts])
+	}
+	if got.Data[util.ScrapeHTTPSProxyPorts] != "10350,9443" {
+		t.Errorf("expect scrape https ports 10350,9443, but got %q", got.Data[util.ScrapeHTTPSProxyPorts])
+	}
+	if got.Data["http-proxy-ports"] != "9300" {
+		t.Errorf("expect manually configured ports kept, but got %q", got.Data["http-proxy-ports"])
+	}
+
+	// a sync is triggered when the scrape annotation of service is removed
+	select {
+	case <-sm.changed:
+	default:
+	}
+	cloudSvc.Annotations = nil
+	if _, err := client.CoreV1().Services(cloudSvc.Namespace).Update(context.Background(), cloudSvc, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update service, %v", err)
+	}
+	select {
+	case <-sm.changed:
+	case <-time.After(time.Second):
+		t.Errorf("expect a sync is triggered by the change of scraped service")
+	}
+
+	// the ports are removed after the edge node leaves the cluster
+	if err := client.CoreV1().Nodes().Delete(context.Background(), "edge-node", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete node, %v", err)
+	}
+	if err := factory.Core().V1().Nodes().Informer().GetIndexer().Delete(newNode("edge-node", "192.168.1.1", true, 10350)); err != nil {
+		t.Fatalf("failed to delete node from cache, %v", err)
+	}
+	sm.syncScrapePorts()
+	got, err = client.CoreV1().ConfigMaps(cm.Namespace).Get(context.Background(), cm.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get configmap, %v", err)
+	}
+	if got.Data[util.ScrapeHTTPProxyPorts] != "" || got.Data[util.ScrapeHTTPSProxyPorts] != "" {
+		t.Errorf("expect no scrape ports, but got http %q, https %q",
+			got.Data[util.ScrapeHTTPProxyPorts], got.Data[util.ScrapeHTTPSProxyPorts])
+	}
+}
+
+func TestScrapePortsOnEdgeWithInvalidPortAnnotation(t *testing.T) {
+	svc, eps := newScrapedService("invalid", map[string]string{prometheusPortAnnotation: "http"}, 9100,
+		map[string]string{"192.168.1.1": "edge-node"})
+	edgeNodes := map[string]*corev1.Node{"edge-node": newNode("edge-node", "192.168.1.1", true, 10250)}
+	if ports := scrapePortsOnEdge(svc, eps, edgeNodes); len(ports) != 0 {
+		t.Errorf("expect no ports for invalid port annotation, but got %v", ports)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshjump
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Session is the information of a ssh session opened through the ssh jump
+type Session struct {
+	ID         string
+	User       string
+	Groups     []string
+	NodeName   string
+	RemoteAddr string
+	StartTime  time.Time
+	EndTime    time.Time
+	// BytesSent is the number of bytes sent to the sshd of node, and BytesReceived
+	// is the number of bytes received from it.
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// SessionHook is notified when the ssh sessions are started and ended, it can be used
+// for recording the sessions into an audit system.
+type SessionHook interface {
+	SessionStarted(s *Session)
+	SessionEnded(s *Session)
+}
+
+// logSessionHook records the sessions in the log of tunnel server
+type logSessionHook struct{}
+
+func (logSessionHook) SessionStarted(s *Session) {
+	klog.Infof("ssh session %s started, user: %s, groups: %v, node: %s, remote addr: %s",
+		s.ID, s.User, s.Groups, s.NodeName, s.RemoteAddr)
+}
+
+func (logSessionHook) SessionEnded(s *Session) {
+	klog.Infof("ssh session %s ended, user: %s, node: %s, duration: %v, bytes sent: %d, bytes received: %d",
+		s.ID, s.User, s.NodeName, s.EndTime.Sub(s.StartTime), s.BytesSent, s.BytesReceived)
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshjump
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+const (
+	defaultSyncPeriod = 30 * time.Second
+	// sshJumpNodePoolsKey is the field of yurt-tunnel-server-cfg configmap for the nodepools
+	// whose nodes can be accessed by ssh jump, the value is a comma-separated list of nodepools,
+	// and "*" means all nodepools. ssh jump is disabled for all nodes if it is not set.
+	sshJumpNodePoolsKey = "ssh-jump-nodepools"
+	// sshdAddr is the address of sshd which is dialed by the tunnel agent on the node
+	sshdAddr = "127.0.0.1:22"
+	// sshSubresource is the subresource of node which is checked by SubjectAccessReview,
+	// e.g. the users can open ssh sessions if they are allowed to create nodes/ssh.
+	sshSubresource = "ssh"
+)
+
+// SSHJump interface defines the method for opening ssh sessions to the sshd
+// of edge nodes through the tunnel.
+type SSHJump interface {
+	Run(stopCh <-chan struct{}, wg *sync.WaitGroup)
+}
+
+// sshJump serves CONNECT requests for {nodeName}:22 from the cloud clients, e.g.
+//
+//	ssh -o ProxyCommand="proxytunnel -E -p {tunnel-server}:{ssh-jump-port} -d %h:%p" root@edge-node-1
+//
+// the clients are authenticated by client certificates or bearer tokens, and authorized
+// by SubjectAccessReview for creating nodes/ssh, and the node must belong to one of the
+// nodepools in ssh-jump-nodepools.
+type sshJump struct {
+	kubeClient    clientset.Interface
+	listenAddr    string
+	tlsCfg        *tls.Config
+	syncPeriod    time.Duration
+	authenticator authenticator.Request
+	authorize     func(u user.Info, nodeName string) (bool, string, error)
+	dialTunnelFor func(nodeName, addr string) (net.Conn, error)
+	hooks         []SessionHook
+	sync.RWMutex
+	enabledPools sets.String
+}
+
+// NewSSHJump creates a SSHJump which serves https requests on listenAddr, the sessions
+// are logged and passed to the hooks.
+func NewSSHJump(client clientset.Interface, listenAddr, udsSockFile string, tlsCfg *tls.Config, hooks ...SessionHook) SSHJump {
+	sj := &sshJump{
+		kubeClient:    client,
+		listenAddr:    listenAddr,
+		tlsCfg:        tlsCfg,
+		syncPeriod:    defaultSyncPeriod,
+		authenticator: util.NewClientAuthenticator(client, tlsCfg.ClientCAs),
+		dialTunnelFor: func(nodeName, addr string) (net.Conn, error) {
+			return util.DialTunnel(udsSockFile, nodeName, addr)
+		},
+		hooks:        append([]SessionHook{logSessionHook{}}, hooks...),
+		enabledPools: sets.NewString(),
+	}
+	sj.authorize = sj.subjectAccessReview
+	return sj
+}
+
+// Run starts the ssh jump server and updates the enabled nodepools periodically
+func (sj *sshJump) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	sj.syncEnabledPools()
+
+	server := &http.Server{
+		Addr:         sj.listenAddr,
+		Handler:      sj,
+		TLSConfig:    sj.tlsCfg,
+		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+	}
+	go func() {
+		klog.Infof("start handling ssh jump requests at %s", sj.listenAddr)
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("failed to serve ssh jump requests: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(sj.syncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("stop the ssh jump")
+			server.Close()
+			return
+		case <-ticker.C:
+			sj.syncEnabledPools()
+		}
+	}
+}
+
+func (sj *sshJump) syncEnabledPools() {
+	cm, err := sj.kubeClient.CoreV1().
+		ConfigMaps(util.YurttunnelServerDnatConfigMapNs).
+		Get(context.Background(), util.YurttunnelServerDnatConfigMapName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("failed to get configmap %s/%s, %v", util.YurttunnelServerDnatConfigMapNs, util.YurttunnelServerDnatConfigMapName, err)
+		return
+	}
+
+	pools := sets.NewString()
+	if cm != nil {
+		for _, pool := range strings.Split(cm.Data[sshJumpNodePoolsKey], ",") {
+			if pool = strings.TrimSpace(pool); len(pool) != 0 {
+				pools.Insert(pool)
+			}
+		}
+	}
+	sj.Lock()
+	defer sj.Unlock()
+	if !pools.Equal(sj.enabledPools) {
+		klog.Infof("ssh jump is enabled for nodepools %v", pools.List())
+		sj.enabledPools = pools
+	}
+}
+
+func (sj *sshJump) isEnabled(pool string) bool {
+	sj.RLock()
+	defer sj.RUnlock()
+	return sj.enabledPools.Has("*") || (len(pool) != 0 && sj.enabledPools.Has(pool))
+}
+
+func (sj *sshJump) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "ssh jump only supports CONNECT", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp, ok, err := sj.authenticator.AuthenticateRequest(r)
+	if err != nil || !ok {
+		if err != nil {
+			klog.Errorf("failed to authenticate ssh jump request for %s, %v", r.Host, err)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	nodeName := r.Host
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		nodeName = host
+	}
+	node, err := sj.kubeClient.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("node %s is not found", nodeName), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to get node %s", nodeName), http.StatusInternalServerError)
+		return
+	}
+	if pool := node.Labels[apps.LabelCurrentNodePool]; !sj.isEnabled(pool) {
+		http.Error(w, fmt.Sprintf("ssh jump is not enabled for nodepool %q of node %s", pool, nodeName), http.StatusForbidden)
+		return
+	}
+
+	allowed, reason, err := sj.authorize(resp.User, nodeName)
+	if err != nil {
+		klog.Errorf("failed to authorize ssh jump request of user %s for node %s, %v", resp.User.GetName(), nodeName, err)
+		http.Error(w, "failed to authorize the request", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		klog.Infof("user %s is not allowed to ssh node %s, %s", resp.User.GetName(), nodeName, reason)
+		http.Error(w, fmt.Sprintf("user %s is not allowed to create nodes/%s for node %s", resp.User.GetName(), sshSubresource, nodeName), http.StatusForbidden)
+		return
+	}
+
+	sshConn, err := sj.dialTunnelFor(nodeName, sshdAddr)
+	if err != nil {
+		klog.Errorf("failed to setup the tunnel to sshd of node %s, %v", nodeName, err)
+		http.Error(w, fmt.Sprintf("failed to connect sshd of node %s", nodeName), http.StatusBadGateway)
+		return
+	}
+	defer sshConn.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	clientConn, bufrw, err := hijacker.Hijack()
+	if err != nil {
+		klog.Errorf("failed to hijack the connection of ssh jump, %v", err)
+		return
+	}
+	defer clientConn.Close()
+
+	session := &Session{
+		ID:         uuid.New().String(),
+		User:       resp.User.GetName(),
+		Groups:     resp.User.GetGroups(),
+		NodeName:   nodeName,
+		RemoteAddr: r.RemoteAddr,
+		StartTime:  time.Now(),
+	}
+	for _, hook := range sj.hooks {
+		hook.SessionStarted(session)
+	}
+	sj.serveSession(session, bufrw, clientConn, sshConn)
+	session.EndTime = time.Now()
+	for _, hook := range sj.hooks {
+		hook.SessionEnded(session)
+	}
+}
+
+// serveSession copies the data between client and sshd until one of them is closed
+func (sj *sshJump) serveSession(session *Session, clientReader io.Reader, clientConn, sshConn net.Conn) {
+	readerComplete, writerComplete := make(chan struct{}), make(chan struct{})
+	go func() {
+		n, _ := io.Copy(sshConn, clientReader)
+		atomic.AddInt64(&session.BytesSent, n)
+		close(writerComplete)
+	}()
+	go func() {
+		n, _ := io.Copy(clientConn, sshConn)
+		atomic.AddInt64(&session.BytesReceived, n)
+		close(readerComplete)
+	}()
+
+	select {
+	case <-writerComplete:
+		// wait for the other direction, so the counters are completed
+		sshConn.Close()
+		<-readerComplete
+	case <-readerComplete:
+		clientConn.Close()
+		<-writerComplete
+	}
+}
+
+// subjectAccessReview checks the user is allowed to create nodes/ssh for the node
+func (sj *sshJump) subjectAccessReview(u user.Info, nodeName string) (bool, string, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(u.GetExtra()))
+	for k, v := range u.GetExtra() {
+		extra[k] = v
+	}
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        "create",
+				Resource:    "nodes",
+				Subresource: sshSubresource,
+				Name:        nodeName,
+			},
+			User:   u.GetName(),
+			Groups: u.GetGroups(),
+			UID:    u.GetUID(),
+			Extra:  extra,
+		},
+	}
+	result, err := sj.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.Background(), sar, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", err
+	}
+	return result.Status.Allowed, result.Status.Reason, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sshjump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openyurtio/openyurt/pkg/apis/apps"
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/util"
+)
+
+type fakeHook struct {
+	ended chan *Session
+}
+
+func (h *fakeHook) SessionStarted(s *Session) {}
+
+func (h *fakeHook) SessionEnded(s *Session) {
+	h.ended <- s
+}
+
+func newTestSSHJump(t *testing.T, hook SessionHook) *sshJump {
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "edge-node-1", Labels: map[string]string{apps.LabelCurrentNodePool: "hangzhou"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "edge-node-2", Labels: map[string]string{apps.LabelCurrentNodePool: "beijing"}}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: util.YurttunnelServerDnatConfigMapName, Namespace: util.YurttunnelServerDnatConfigMapNs},
+			Data:       map[string]string{sshJumpNodePoolsKey: "hangzhou, shanghai"},
+		},
+	)
+	sj := &sshJump{
+		kubeClient: client,
+		authenticator: authenticator.RequestFunc(func(r *http.Request) (*authenticator.Response, bool, error) {
+			switch r.Header.Get("Authorization") {
+			case "Bearer admin":
+				return &authenticator.Response{User: &user.DefaultInfo{Name: "admin"}}, true, nil
+			case "Bearer dev":
+				return &authenticator.Response{User: &user.DefaultInfo{Name: "dev"}}, true, nil
+			}
+			return nil, false, nil
+		}),
+		authorize: func(u user.Info, nodeName string) (bool, string, error) {
+			return u.GetName() == "admin", "", nil
+		},
+		dialTunnelFor: func(nodeName, addr string) (net.Conn, error) {
+			if addr != sshdAddr {
+				t.Errorf("expect sshd address %s is dialed, but got %s", sshdAddr, addr)
+			}
+			// the fake sshd echoes the data with node name
+			server, client := net.Pipe()
+			go func() {
+				defer server.Close()
+				line, _ := bufio.NewReader(server).ReadString('\n')
+				fmt.Fprintf(server, "%s:%s", nodeName, line)
+			}()
+			return client, nil
+		},
+		hooks:        []SessionHook{logSessionHook{}, hook},
+		enabledPools: sets.NewString(),
+	}
+	sj.syncEnabledPools()
+	return sj
+}
+
+func TestSyncEnabledPools(t *testing.T) {
+	sj := newTestSSHJump(t, &fakeHook{ended: make(chan *Session, 1)})
+	if !sj.enabledPools.Equal(sets.NewString("hangzhou", "shanghai")) {
+		t.Errorf("unexpected enabled pools %v", sj.enabledPools.List())
+	}
+	if sj.isEnabled("") || sj.isEnabled("beijing") || !sj.isEnabled("hangzhou") {
+		t.Errorf("unexpected enabled result")
+	}
+	sj.enabledPools = sets.NewString("*")
+	if !sj.isEnabled("") {
+		t.Errorf("expect all nodes are enabled by *")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	hook := &fakeHook{ended: make(chan *Session, 1)}
+	sj := newTestSSHJump(t, hook)
+	server := httptest.NewServer(sj)
+	defer server.Close()
+
+	connect := func(target, token string) (*http.Response, net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("failed to connect ssh jump, %v", err)
+		}
+		fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\nAuthorization: Bearer %s\r\n\r\n", target, target, token)
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("failed to read response, %v", err)
+		}
+		return resp, conn, br
+	}
+
+	testcases := map[string]struct {
+		target     string
+		token      string
+		expectCode int
+	}{
+		"unauthenticated": {target: "edge-node-1:22", token: "unknown", expectCode: http.StatusUnauthorized},
+		"node not found":  {target: "edge-node-3:22", token: "admin", expectCode: http.StatusNotFound},
+		"pool disabled":   {target: "edge-node-2:22", token: "admin", expectCode: http.StatusForbidden},
+		"not authorized":  {target: "edge-node-1:22", token: "dev", expectCode: http.StatusForbidden},
+	}
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			resp, conn, _ := connect(tc.target, tc.token)
+			defer conn.Close()
+			if resp.StatusCode != tc.expectCode {
+				t.Errorf("expect status %d, but got %d", tc.expectCode, resp.StatusCode)
+			}
+		})
+	}
+
+	resp, conn, br := connect("edge-node-1:22", "admin")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expect session is opened, but got %d", resp.StatusCode)
+	}
+	io.WriteString(conn, "SSH-2.0-OpenSSH\n")
+	if got, _ := br.ReadString('\n'); got != "edge-node-1:SSH-2.0-OpenSSH\n" {
+		t.Errorf("unexpected data from sshd: %q", got)
+	}
+	io.ReadAll(br)
+	conn.Close()
+
+	session := <-hook.ended
+	if session.User != "admin" || session.NodeName != "edge-node-1" || session.BytesSent != 16 {
+		t.Errorf("unexpected session %+v", session)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/request/union"
+	x509request "k8s.io/apiserver/pkg/authentication/request/x509"
+	"k8s.io/apiserver/pkg/authentication/token/cache"
+	"k8s.io/apiserver/pkg/authentication/user"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// NewClientAuthenticator authenticates the clients by the certificates signed by clientCAs or by the
+// bearer tokens, the results of tokens are cached to reduce TokenReview requests. If audiences are
+// specified, only the tokens issued for one of them are accepted.
+func NewClientAuthenticator(client clientset.Interface, clientCAs *x509.CertPool, audiences ...string) authenticator.Request {
+	opts := x509request.DefaultVerifyOptions()
+	opts.Roots = clientCAs
+	tokenAuth := cache.New(&tokenReviewAuthenticator{kubeClient: client, audiences: audiences}, false, 2*time.Minute, 10*time.Second)
+	return union.New(x509request.New(opts, x509request.CommonNameUserConversion), bearertoken.New(tokenAuth))
+}
+
+// tokenReviewAuthenticator verifies the bearer tokens by kube-apiserver
+type tokenReviewAuthenticator struct {
+	kubeClient clientset.Interface
+	audiences  []string
+}
+
+func (a *tokenReviewAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	review, err := a.kubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: a.audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, false, err
+	}
+	if !review.Status.Authenticated {
+		return nil, false, nil
+	}
+	if len(a.audiences) != 0 && !sets.NewString(a.audiences...).HasAny(review.Status.Audiences...) {
+		return nil, false, nil
+	}
+
+	extra := make(map[string][]string, len(review.Status.User.Extra))
+	for k, v := range review.Status.User.Extra {
+		extra[k] = v
+	}
+	return &authenticator.Response{
+		User: &user.DefaultInfo{
+			Name:   review.Status.User.Username,
+			UID:    review.Status.User.UID,
+			Groups: review.Status.User.Groups,
+			Extra:  extra,
+		},
+	}, true, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestTokenReviewAuthenticatorAudiences(t *testing.T) {
+	testcases := map[string]struct {
+		audiences       []string
+		statusAudiences []string
+		expect          bool
+	}{
+		"no audiences": {
+			expect: true,
+		},
+		"token issued for the audience": {
+			audiences:       []string{"openyurt.io/l7-proxy"},
+			statusAudiences: []string{"openyurt.io/l7-proxy"},
+			expect:          true,
+		},
+		"token issued for other audiences": {
+			audiences:       []string{"openyurt.io/l7-proxy"},
+			statusAudiences: []string{"https://kubernetes.default.svc"},
+		},
+	}
+
+	for k, tc := range testcases {
+		t.Run(k, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			var requested []string
+			client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				requested = review.Spec.Audiences
+				review.Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User:          authenticationv1.UserInfo{Username: "alice"},
+					Audiences:     tc.statusAudiences,
+				}
+				return true, review, nil
+			})
+
+			a := &tokenReviewAuthenticator{kubeClient: client, audiences: tc.audiences}
+			resp, ok, err := a.AuthenticateToken(context.Background(), "token")
+			if err != nil {
+				t.Fatalf("unexpected error, %v", err)
+			}
+			if len(requested) != len(tc.audiences) {
+				t.Errorf("expect audiences %v in token review, but got %v", tc.audiences, requested)
+			}
+			if ok != tc.expect {
+				t.Fatalf("expect authenticated %v, but got %v", tc.expect, ok)
+			}
+			if ok && resp.User.GetName() != "alice" {
+				t.Errorf("expect user alice, but got %s", resp.User.GetName())
+			}
+		})
+	}
+}
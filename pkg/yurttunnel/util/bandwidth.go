@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/time/rate"
+)
+
+// minBandwidthBurst is the minimum burst of bandwidth limiter, so a tls record
+// can be sent or received without being split into many small pieces.
+const minBandwidthBurst = 16 * 1024
+
+// NewBandwidthLimiter creates a limiter which allows bytesPerSecond bytes per second,
+// nil is returned if bytesPerSecond is not positive, which means unlimited.
+func NewBandwidthLimiter(bytesPerSecond int) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := bytesPerSecond
+	if burst < minBandwidthBurst {
+		burst = minBandwidthBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// LimitedRead reads into b by read, and waits until the read bytes are allowed by limiter,
+// so the sender is slowed down by the flow control of the underlying connection.
+func LimitedRead(limiter *rate.Limiter, read func([]byte) (int, error), b []byte) (int, error) {
+	if limiter == nil {
+		return read(b)
+	}
+	if len(b) > limiter.Burst() {
+		b = b[:limiter.Burst()]
+	}
+	n, err := read(b)
+	if n > 0 {
+		if waitErr := limiter.WaitN(context.Background(), n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// LimitedWrite writes b by write in pieces of at most the burst of limiter, every piece
+// is written after it's allowed by limiter.
+func LimitedWrite(limiter *rate.Limiter, write func([]byte) (int, error), b []byte) (int, error) {
+	if limiter == nil {
+		return write(b)
+	}
+	written := 0
+	for written < len(b) {
+		size := len(b) - written
+		if size > limiter.Burst() {
+			size = limiter.Burst()
+		}
+		if err := limiter.WaitN(context.Background(), size); err != nil {
+			return written, err
+		}
+		n, err := write(b[written : written+size])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// BandwidthLimitedConn limits the bandwidth of connection, ReadLimiter is applied to the
+// data received and WriteLimiter to the data sent, nil limiter means unlimited.
+type BandwidthLimitedConn struct {
+	net.Conn
+	ReadLimiter  *rate.Limiter
+	WriteLimiter *rate.Limiter
+}
+
+func (c *BandwidthLimitedConn) Read(b []byte) (int, error) {
+	return LimitedRead(c.ReadLimiter, c.Conn.Read, b)
+}
+
+func (c *BandwidthLimitedConn) Write(b []byte) (int, error) {
+	return LimitedWrite(c.WriteLimiter, c.Conn.Write, b)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNewBandwidthLimiter(t *testing.T) {
+	if l := NewBandwidthLimiter(0); l != nil {
+		t.Errorf("expect no limiter for 0 bandwidth")
+	}
+	if l := NewBandwidthLimiter(1024); l == nil || l.Burst() != minBandwidthBurst {
+		t.Errorf("expect limiter with burst %d", minBandwidthBurst)
+	}
+	if l := NewBandwidthLimiter(1 << 20); l == nil || l.Burst() != 1<<20 {
+		t.Errorf("expect limiter with burst %d", 1<<20)
+	}
+}
+
+func TestBandwidthLimitedConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := &BandwidthLimitedConn{
+		Conn:         client,
+		WriteLimiter: NewBandwidthLimiter(minBandwidthBurst),
+	}
+	defer conn.Close()
+
+	// the first burst is sent immediately, and the next one waits for about one second
+	data := make([]byte, minBandwidthBurst+minBandwidthBurst/2)
+	go func() {
+		if _, err := conn.Write(data); err != nil {
+			t.Errorf("failed to write data, %v", err)
+		}
+	}()
+
+	start := time.Now()
+	if _, err := io.ReadFull(server, make([]byte, len(data))); err != nil {
+		t.Fatalf("failed to read data, %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expect upload is limited, but data is sent in %v", elapsed)
+	}
+}
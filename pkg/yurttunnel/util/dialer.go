@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The OpenYurt Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/openyurtio/openyurt/pkg/yurttunnel/constants"
+)
+
+// DialTunnel sets up a connection to the addr through the tunnel agent of proxyHost(node name or ip),
+// the connection is proxied by the proxier of tunnel server which is listening on udsSockFile.
+func DialTunnel(udsSockFile, proxyHost, addr string) (net.Conn, error) {
+	proxyConn, err := net.Dial("unix", udsSockFile)
+	if err != nil {
+		return nil, fmt.Errorf("dialing proxy %q failed: %w", udsSockFile, err)
+	}
+
+	fmt.Fprintf(proxyConn, "CONNECT %s HTTP/1.1\r\nHost: localhost\r\n%s: %s\r\n\r\n", addr, constants.ProxyHostHeaderKey, proxyHost)
+	br := bufio.NewReader(proxyConn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		proxyConn.Close()
+		return nil, fmt.Errorf("reading HTTP response from CONNECT to %s via proxy %s failed: %w", addr, udsSockFile, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		proxyConn.Close()
+		return nil, fmt.Errorf("proxy error from %s while dialing %s, code %d: %v", udsSockFile, addr, res.StatusCode, res.Status)
+	}
+	return &bufferedConn{Conn: proxyConn, reader: br}, nil
+}
+
+// bufferedConn reads the data buffered while reading the CONNECT response first
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -43,8 +43,12 @@ const (
 	YurtTunnelLocalHostProxyPorts   = "localhost-proxy-ports"
 	yurttunnelServerHTTPProxyPorts  = "http-proxy-ports"
 	yurttunnelServerHTTPSProxyPorts = "https-proxy-ports"
-	PortsSeparator                  = ","
-	PortPairSeparator               = "="
+	// ScrapeHTTPProxyPorts and ScrapeHTTPSProxyPorts are maintained by the scrape config manager
+	// for the metrics endpoints on edge nodes, they should not be edited manually.
+	ScrapeHTTPProxyPorts  = "scrape-http-proxy-ports"
+	ScrapeHTTPSProxyPorts = "scrape-https-proxy-ports"
+	PortsSeparator        = ","
+	PortPairSeparator     = "="
 
 	KubeletHTTPSPort = "10250"
 	KubeletHTTPPort  = "10255"
@@ -126,6 +130,18 @@ func resolveProxyPortsAndMappings(cm *v1.ConfigMap, insecureListenAddr, secureLi
 		portMappings[port] = secureListenAddr
 	}
 
+	// resolve the ports maintained for scraping metrics, the manually configured ports take precedence
+	for _, port := range resolvePorts(cm.Data[ScrapeHTTPProxyPorts], "") {
+		if _, ok := portMappings[port]; !ok {
+			portMappings[port] = insecureListenAddr
+		}
+	}
+	for _, port := range resolvePorts(cm.Data[ScrapeHTTPSProxyPorts], "") {
+		if _, ok := portMappings[port]; !ok {
+			portMappings[port] = secureListenAddr
+		}
+	}
+
 	// cleanup 10250/10255 mappings
 	delete(portMappings, KubeletHTTPSPort)
 	delete(portMappings, KubeletHTTPPort)
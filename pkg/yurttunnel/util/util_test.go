@@ -152,6 +152,36 @@ func TestResolveProxyPortsAndMappings(t *testing.T) {
 				err: nil,
 			},
 		},
+		"setting ports on scrape proxy ports": {
+			configMap: &corev1.ConfigMap{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "yurt-tunnel-server-cfg",
+					Namespace: "kube-system",
+				},
+				Data: map[string]string{
+					"https-proxy-ports":        "9100",
+					"scrape-http-proxy-ports":  "9100,9200",
+					"scrape-https-proxy-ports": "10350",
+				},
+			},
+			expectResult: struct {
+				ports        []string
+				portMappings map[string]string
+				err          error
+			}{
+				ports: []string{"9100", "9200", "10350"},
+				portMappings: map[string]string{
+					"9100":  secureListenAddr,
+					"9200":  insecureListenAddr,
+					"10350": secureListenAddr,
+				},
+				err: nil,
+			},
+		},
 	}
 
 	for k, tt := range testcases {
@@ -17,6 +17,33 @@ limitations under the License.
 package constants
 
 const (
+	YurttunnelAgentClusterRole = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: yurt-tunnel-agent
+rules:
+- apiGroups:
+  - "raven.openyurt.io"
+  resources:
+  - tunnelaccesspolicies
+  verbs:
+  - list
+`
+	YurttunnelAgentClusterRolebinding = `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: yurt-tunnel-agent
+subjects:
+  - kind: Group
+    name: system:nodes
+    apiGroup: rbac.authorization.k8s.io
+roleRef:
+  kind: ClusterRole
+  name: yurt-tunnel-agent
+  apiGroup: rbac.authorization.k8s.io
+`
 	YurttunnelAgentDaemonSet = `
 apiVersion: apps/v1
 kind: DaemonSet
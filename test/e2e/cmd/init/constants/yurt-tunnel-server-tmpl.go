@@ -90,6 +90,12 @@ rules:
   verbs:
   - list
   - watch
+- apiGroups:
+  - ""
+  resources:
+  - nodes
+  verbs:
+  - get
 - apiGroups:
   - ""
   resources:
@@ -116,7 +122,28 @@ rules:
   verbs:
   - create
   - get
+  - list
+  - watch
   - update
+  - delete
+- apiGroups:
+  - "raven.openyurt.io"
+  resources:
+  - tunnelaccesspolicies
+  verbs:
+  - list
+- apiGroups:
+  - "authentication.k8s.io"
+  resources:
+  - tokenreviews
+  verbs:
+  - create
+- apiGroups:
+  - "authorization.k8s.io"
+  resources:
+  - subjectaccessreviews
+  verbs:
+  - create
 `
 	YurttunnelServerServiceAccount = `
 apiVersion: v1
@@ -127,7 +127,17 @@ func DeployYurttunnelAgent(
 	client kubeclientset.Interface,
 	tunnelServerAddress string,
 	yurttunnelAgentImage string) error {
-	// 1. Deploy the yurt-tunnel-agent DaemonSet
+	// 1. create the ClusterRole and ClusterRoleBinding for listing tunnel access policies
+	if err := CreateClusterRoleFromYaml(client,
+		constants.YurttunnelAgentClusterRole); err != nil {
+		return err
+	}
+	if err := CreateClusterRoleBindingFromYaml(client,
+		constants.YurttunnelAgentClusterRolebinding); err != nil {
+		return err
+	}
+
+	// 2. Deploy the yurt-tunnel-agent DaemonSet
 	if err := CreateDaemonSetFromYaml(client,
 		SystemNamespace,
 		constants.YurttunnelAgentDaemonSet,
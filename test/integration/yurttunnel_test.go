@@ -163,7 +163,7 @@ func startTunnelServer(t *testing.T) {
 	}
 
 	tunnelServer := ts.NewTunnelServer(
-		false,                                /*egressSelectorEnabled*/
+		nil,                                  /* egressSelector */
 		InterceptorServerUDSFile,             /* interceptorServerUDSFile*/
 		fmt.Sprintf(":%d", ServerMasterPort), /* serverMasterAddr */
 		fmt.Sprintf(":%d", ServerMasterInsecurePort), /* serverMasterInsecureAddr */
@@ -173,6 +173,13 @@ func startTunnelServer(t *testing.T) {
 		&tlsCfg,
 		wrappers,                                /* hw.HandlerWrappers */
 		string(anpserver.ProxyStrategyDestHost), /* proxyStrategy */
+		0,                                       /* maxStreamsPerAgent */
+		0,                                       /* streamWaitTimeout */
+		nil,                                     /* streamBandwidthLimits */
+		"",                                      /* peerAddr */
+		nil,                                     /* kubeClient */
+		nil,                                     /* accessEnforcer */
+		nil,                                     /* nodeLister */
 	)
 	tunnelServer.Run()
 	klog.Info("[TEST] Yurttunnel Server is running")
@@ -186,11 +193,15 @@ func startTunnelAgent(t *testing.T) {
 		RootCAs:    genCAPool(t, RootCAFile),
 		ServerName: "127.0.0.1",
 	}
+	tunnelServerAddrs := []string{fmt.Sprintf(":%d", ServerAgentPort)}
 	tunnelAgent := ta.NewTunnelAgent(
-		&tlsCfg,                             /* tlsCfg */
-		fmt.Sprintf(":%d", ServerAgentPort), /* tunnelServerAddr */
-		"dummy-agent",                       /* nodeName */
-		"ipv4=127.0.0.1&host=localhost",     /* agentIdentifiers */
+		&tlsCfg,                         /* tlsCfg */
+		tunnelServerAddrs,               /* tunnelServerAddrs */
+		"dummy-agent",                   /* nodeName */
+		"ipv4=127.0.0.1&host=localhost", /* agentIdentifiers */
+		0,                               /* maxUploadBandwidth */
+		0,                               /* maxDownloadBandwidth */
+		nil,                             /* accessChecker */
 	)
 	tunnelAgent.Run(wait.NeverStop)
 	klog.Info("[TEST] Yurttunnel Agent is running")